@@ -25,8 +25,12 @@ export function updateEntity(
 
 export function deleteEntity(
   name: string,
-): Promise<ApiResponse<{ name: string; deleted: boolean }>> {
-  return del<ApiResponse<{ name: string; deleted: boolean }>>(
+): Promise<
+  ApiResponse<{ name: string; deleted: boolean; dropped_tables: string[] }>
+> {
+  return del<
+    ApiResponse<{ name: string; deleted: boolean; dropped_tables: string[] }>
+  >(
     `/_admin/entities/${name}`,
   );
 }
@@ -10,8 +10,15 @@ export function createInvite(data: InvitePayload): Promise<ApiResponse<InviteRow
   return post<ApiResponse<InviteRow>>("/_admin/invites", data);
 }
 
-export function bulkCreateInvites(data: BulkInvitePayload): Promise<ApiResponse<BulkInviteResult>> {
-  return post<ApiResponse<BulkInviteResult>>("/_admin/invites/bulk", data);
+export async function bulkCreateInvites(data: BulkInvitePayload): Promise<ApiResponse<BulkInviteResult>> {
+  try {
+    return await post<ApiResponse<BulkInviteResult>>("/_admin/invites/bulk", data);
+  } catch (err) {
+    // 422 means every invite failed; the body still lists why
+    const body = err as Partial<ApiResponse<BulkInviteResult>>;
+    if (body?.data) return body as ApiResponse<BulkInviteResult>;
+    throw err;
+  }
 }
 
 export function deleteInvite(
@@ -0,0 +1,11 @@
+import { get, post } from "./client";
+import type { ApiResponse } from "../types/api";
+import type { RoleRow } from "../types/role";
+
+export function listRoles(): Promise<ApiResponse<RoleRow[]>> {
+  return get<ApiResponse<RoleRow[]>>("/_admin/roles");
+}
+
+export function createRole(name: string): Promise<ApiResponse<RoleRow>> {
+  return post<ApiResponse<RoleRow>>("/_admin/roles", { name });
+}
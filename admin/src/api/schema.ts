@@ -10,12 +10,16 @@ export interface SchemaExport {
   workflows: any[];
   permissions: any[];
   webhooks: any[];
+  // signed webhooks whose secrets were left out
+  warnings?: string[];
 }
 
 export interface ImportResult {
   message: string;
+  committed: boolean;
   summary: Record<string, number>;
   errors?: string[];
+  warnings?: string[];
 }
 
 export async function exportSchema(): Promise<SchemaExport> {
@@ -24,6 +28,14 @@ export async function exportSchema(): Promise<SchemaExport> {
 }
 
 export async function importSchema(data: SchemaExport): Promise<ImportResult> {
-  const res = await post<{ data: ImportResult }>("/_admin/import", data);
-  return res.data;
+  try {
+    const res = await post<{ data: ImportResult }>("/_admin/import", data);
+    return res.data;
+  } catch (err) {
+    // 422 means nothing imported (or, with atomic, rolled back); the body
+    // still carries the summary and errors
+    const body = err as { data?: ImportResult };
+    if (body?.data) return body.data;
+    throw err;
+  }
 }
@@ -146,7 +146,7 @@ export function AISchemaGenerator() {
       const result = await importSchema(data);
       setImportResult(result);
       setPhase("applied");
-      addToast("success", "Schema applied successfully");
+      addToast(result.errors?.length ? "error" : "success", result.errors?.length ? result.message : "Schema applied successfully");
     } catch (err) {
       if (isApiError(err)) {
         setError(err.error.message);
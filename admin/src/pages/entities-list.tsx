@@ -55,7 +55,11 @@ export function EntitiesList() {
       a.download = `schema-export-${date}.json`;
       a.click();
       URL.revokeObjectURL(url);
-      addToast("success", "Schema exported successfully");
+      if (data.warnings?.length) {
+        addToast("error", `Schema exported, but ${data.warnings.length} webhook secret(s) were left out`);
+      } else {
+        addToast("success", "Schema exported successfully");
+      }
     } catch (err) {
       if (isApiError(err)) {
         addToast("error", err.error.message);
@@ -80,7 +84,7 @@ export function EntitiesList() {
       const data = JSON.parse(text);
       const result = await importSchema(data);
       setImportResult(result);
-      addToast("success", result.message);
+      addToast(result.errors?.length ? "error" : "success", result.message);
       await load(); // reload entities list
     } catch (err) {
       if (isApiError(err)) {
@@ -250,6 +254,16 @@ export function EntitiesList() {
                       </ul>
                     </div>
                   </Show>
+                  <Show when={result().warnings && result().warnings!.length > 0}>
+                    <div style={{ "margin-top": "0.5rem", "font-size": "0.85rem", color: "var(--color-warning, #d97706)" }}>
+                      <strong>Warnings:</strong>
+                      <ul style={{ margin: "0.25rem 0", "padding-left": "1.25rem" }}>
+                        <For each={result().warnings}>
+                          {(w) => <li>{w}</li>}
+                        </For>
+                      </ul>
+                    </div>
+                  </Show>
                 </div>
               )}
             </Show>
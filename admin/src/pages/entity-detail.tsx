@@ -1,7 +1,7 @@
 import { createSignal, onMount, onCleanup, For, Show } from "solid-js";
 import { useParams, useNavigate } from "@solidjs/router";
 import { getEntity, createEntity, updateEntity } from "../api/entities";
-import { parseDefinition, type EntityDefinition, type Field, type FieldType } from "../types/entity";
+import { ID_STRATEGIES, parseDefinition, type EntityDefinition, type Field, type FieldType } from "../types/entity";
 import { isApiError } from "../types/api";
 import { FieldRow } from "../components/entity/field-row";
 import { Toggle } from "../components/form/toggle";
@@ -303,6 +303,18 @@ export function EntityDetail() {
               }
             />
           </div>
+          <Show when={definition().primary_key.generated}>
+            <div class="mt-4">
+              <SelectInput
+                label="ID Strategy"
+                value={definition().id_strategy ?? "uuid"}
+                onChange={(val) =>
+                  updateDef({ id_strategy: val as EntityDefinition["id_strategy"] })
+                }
+                options={ID_STRATEGIES.map((s) => ({ value: s, label: s }))}
+              />
+            </div>
+          </Show>
         </div>
 
         {/* Slug Section */}
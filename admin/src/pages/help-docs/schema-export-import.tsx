@@ -129,7 +129,9 @@ export default function SchemaExportImport() {
         <p>
           If any individual items fail to import (e.g., a relation references a non-existent
           entity), the error is captured in the <C>errors</C> array and processing continues
-          with the remaining items.
+          with the remaining items. The status code tells the outcome apart: <C>200</C> when
+          nothing failed, <C>207</C> when some items imported and others failed, and <C>422</C> when
+          every item failed. The body has the same shape in all three cases.
         </p>
         <CodeBlock language="json" title="Import response with partial errors">{`{
   "data": {
@@ -146,6 +148,30 @@ export default function SchemaExportImport() {
       "relation 'items': target entity 'line_item' not found"
     ]
   }
+}`}</CodeBlock>
+        <p>
+          Add <C>?validate_only=true</C> to check a payload before importing it. Every definition
+          is run through the same validation as the individual create endpoints, nothing is
+          written, and the response lists the problems per section along with a <C>valid</C> flag.
+          Relations, rules and workflows are checked against the existing entities plus those in
+          the payload.
+        </p>
+        <CodeBlock language="json" title="Validation report">{`{
+  "data": {
+    "valid": false,
+    "errors": {
+      "entities": [],
+      "relations": [],
+      "rules": [],
+      "state_machines": [],
+      "workflows": [
+        { "index": 0, "name": "escalate", "message": "invalid step type: teleport (must be action, condition, approval, or callback)" }
+      ],
+      "permissions": [],
+      "webhooks": [],
+      "ui_configs": []
+    }
+  }
 }`}</CodeBlock>
       </Section>
 
@@ -349,7 +349,7 @@ export default function ValidationRules() {
           columns={["Property", "Type", "Required", "Description"]}
           rows={[
             [<C>entity</C>, "string", "Yes", "The entity this rule applies to."],
-            [<C>hook</C>, "string", "Yes", <><C>"before_write"</C> or <C>"before_delete"</C>. When this rule is evaluated.</>],
+            [<C>hook</C>, "string", "Yes", <><C>"before_write"</C> (creates and updates), <C>"before_create"</C>, <C>"before_update"</C>, <C>"before_delete"</C>, or <C>"after_write"</C>/<C>"after_delete"</C> (computed rules only, run after commit). When this rule is evaluated.</>],
             [<C>type</C>, "string", "Yes", <><C>"field"</C>, <C>"expression"</C>, or <C>"computed"</C>. Determines how the rule is evaluated.</>],
             [<C>definition</C>, "object", "Yes", "The rule logic. Contents vary by type (see below)."],
             [<C>priority</C>, "number", "No", "Execution order (lower runs first). Defaults to 0."],
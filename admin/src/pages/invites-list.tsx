@@ -3,6 +3,7 @@ import { listInvites, createInvite, deleteInvite, bulkCreateInvites } from "../a
 import type { InviteRow, InvitePayload } from "../types/invite";
 import type { BulkInviteResult, BulkInviteCreated } from "../types/invite";
 import { isApiError } from "../types/api";
+import { listRoles } from "../api/roles";
 import { DataTable, type Column } from "../components/data-table";
 import { Modal } from "../components/modal";
 import { ConfirmDialog } from "../components/confirm-dialog";
@@ -26,6 +27,7 @@ export function InvitesList() {
   const [deleteTarget, setDeleteTarget] = createSignal<string | null>(null);
   const [email, setEmail] = createSignal("");
   const [rolesInput, setRolesInput] = createSignal("");
+  const [knownRoles, setKnownRoles] = createSignal<string[]>([]);
   const [createdToken, setCreatedToken] = createSignal<string | null>(null);
   const [copied, setCopied] = createSignal(false);
 
@@ -50,6 +52,7 @@ export function InvitesList() {
 
   onMount(() => {
     loadInvites();
+    listRoles().then((res) => setKnownRoles(res.data.map((r) => r.name)));
   });
 
   const openCreate = () => {
@@ -130,7 +133,7 @@ export function InvitesList() {
     try {
       const res = await bulkCreateInvites({ emails, roles });
       setBulkResult(res.data);
-      addToast("success", `Created ${res.data.summary.created} of ${res.data.summary.total} invites`);
+      addToast(res.data.summary.failed === 0 ? "success" : "error", `Created ${res.data.summary.created} of ${res.data.summary.total} invites`);
       await loadInvites();
     } catch (err) {
       if (isApiError(err)) {
@@ -355,6 +358,9 @@ export function InvitesList() {
                   onInput={(e) => setRolesInput(e.currentTarget.value)}
                   placeholder="editor, viewer"
                 />
+                <p class="form-help-text">
+                  Known roles: {knownRoles().join(", ") || "none"}. Other names are rejected.
+                </p>
               </div>
 
               <div class="flex justify-end gap-2 mt-4">
@@ -410,6 +416,9 @@ export function InvitesList() {
                   onInput={(e) => setBulkRoles(e.currentTarget.value)}
                   placeholder="editor, viewer"
                 />
+                <p class="form-help-text">
+                  Known roles: {knownRoles().join(", ") || "none"}. Other names are rejected.
+                </p>
               </div>
 
               <div class="flex justify-end gap-2 mt-4">
@@ -433,10 +442,12 @@ export function InvitesList() {
               <div>
                 {/* Summary */}
                 <div class={`text-sm font-medium mb-3 ${
-                  result().summary.skipped === 0 ? "text-green-600 dark:text-green-400" : "text-yellow-600 dark:text-yellow-400"
+                  result().summary.failed > 0 ? "text-red-600 dark:text-red-400"
+                    : result().summary.skipped === 0 ? "text-green-600 dark:text-green-400" : "text-yellow-600 dark:text-yellow-400"
                 }`}>
                   Created {result().summary.created} of {result().summary.total} invites
                   {result().summary.skipped > 0 && ` (${result().summary.skipped} skipped)`}
+                  {result().summary.failed > 0 && ` (${result().summary.failed} failed)`}
                 </div>
 
                 {/* Created list */}
@@ -511,6 +522,33 @@ export function InvitesList() {
                   </div>
                 </Show>
 
+                {/* Failed list */}
+                <Show when={result().failed.length > 0}>
+                  <div class="mb-4">
+                    <span class="text-sm font-medium text-gray-700 dark:text-gray-300 mb-2 block">Failed</span>
+                    <div class="border border-red-200 dark:border-red-800 rounded-lg overflow-hidden">
+                      <table class="w-full text-sm">
+                        <thead>
+                          <tr class="bg-red-50 dark:bg-red-900/30">
+                            <th class="text-left px-3 py-2 font-medium text-gray-600 dark:text-gray-400">Email</th>
+                            <th class="text-left px-3 py-2 font-medium text-gray-600 dark:text-gray-400">Error</th>
+                          </tr>
+                        </thead>
+                        <tbody>
+                          <For each={result().failed}>
+                            {(item) => (
+                              <tr class="border-t border-red-200 dark:border-red-800">
+                                <td class="px-3 py-2 text-gray-900 dark:text-gray-100">{item.email}</td>
+                                <td class="px-3 py-2 text-red-700 dark:text-red-400 text-xs">{item.reason}</td>
+                              </tr>
+                            )}
+                          </For>
+                        </tbody>
+                      </table>
+                    </div>
+                  </div>
+                </Show>
+
                 <div class="flex justify-end gap-2 mt-4">
                   <button
                     class="btn-primary"
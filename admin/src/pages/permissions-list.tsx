@@ -12,6 +12,7 @@ import {
   type PermissionPayload,
 } from "../types/permission";
 import { isApiError } from "../types/api";
+import { listRoles } from "../api/roles";
 import { useEntities } from "../stores/entities";
 import { DataTable, type Column } from "../components/data-table";
 import { Modal } from "../components/modal";
@@ -38,6 +39,7 @@ export function PermissionsList() {
   const [editorError, setEditorError] = createSignal<string | null>(null);
   const [deleteTarget, setDeleteTarget] = createSignal<string | null>(null);
   const [rolesInput, setRolesInput] = createSignal("");
+  const [knownRoles, setKnownRoles] = createSignal<string[]>([]);
 
   const entityFields = () => {
     const ent = parsed().find((e) => e.name === editingPerm().entity);
@@ -56,6 +58,7 @@ export function PermissionsList() {
 
   onMount(() => {
     loadPerms();
+    listRoles().then((res) => setKnownRoles(res.data.map((r) => r.name)));
     loadEntities();
   });
 
@@ -294,6 +297,10 @@ export function PermissionsList() {
               onInput={(e) => setRolesInput(e.currentTarget.value)}
               placeholder="viewer, editor, manager"
             />
+            <p class="form-help-text">
+              Known roles: {knownRoles().join(", ") || "none"}. Other names are rejected; add
+              them with POST /api/_admin/roles or the bootstrap.roles config.
+            </p>
           </div>
 
           <div class="form-group">
@@ -18,7 +18,11 @@ const typeColor: Record<string, "blue" | "purple" | "green"> = {
 
 const hookColor: Record<string, "blue" | "gray"> = {
   before_write: "blue",
+  before_create: "blue",
+  before_update: "blue",
   before_delete: "gray",
+  after_write: "gray",
+  after_delete: "gray",
 };
 
 export function RulesList() {
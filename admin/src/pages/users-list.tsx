@@ -2,6 +2,7 @@ import { createSignal, onMount, type JSX } from "solid-js";
 import { listUsers, createUser, updateUser, deleteUser } from "../api/users";
 import { emptyUser, type UserRow, type UserPayload } from "../types/user";
 import { isApiError } from "../types/api";
+import { listRoles } from "../api/roles";
 import { DataTable, type Column } from "../components/data-table";
 import { Modal } from "../components/modal";
 import { ConfirmDialog } from "../components/confirm-dialog";
@@ -18,6 +19,7 @@ export function UsersList() {
   const [editorError, setEditorError] = createSignal<string | null>(null);
   const [deleteTarget, setDeleteTarget] = createSignal<string | null>(null);
   const [rolesInput, setRolesInput] = createSignal("");
+  const [knownRoles, setKnownRoles] = createSignal<string[]>([]);
 
   async function loadUsers() {
     setLoading(true);
@@ -31,6 +33,7 @@ export function UsersList() {
 
   onMount(() => {
     loadUsers();
+    listRoles().then((res) => setKnownRoles(res.data.map((r) => r.name)));
   });
 
   const openCreate = () => {
@@ -231,6 +234,10 @@ export function UsersList() {
               onInput={(e) => setRolesInput(e.currentTarget.value)}
               placeholder="admin, editor, viewer"
             />
+            <p class="form-help-text">
+              Known roles: {knownRoles().join(", ") || "none"}. Other names are rejected; add
+              them with POST /api/_admin/roles or the bootstrap.roles config.
+            </p>
           </div>
 
           <div class="form-group">
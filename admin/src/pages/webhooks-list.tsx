@@ -47,6 +47,7 @@ export function WebhooksList() {
   const [editorError, setEditorError] = createSignal<string | null>(null);
   const [deleteTarget, setDeleteTarget] = createSignal<string | null>(null);
   const [headersJson, setHeadersJson] = createSignal("{}");
+  const [editingHasSecret, setEditingHasSecret] = createSignal(false);
 
   const entityFields = () => {
     const ent = parsed().find((e) => e.name === editingWH().entity);
@@ -71,6 +72,7 @@ export function WebhooksList() {
   const openCreate = () => {
     setEditingWH(emptyWebhook());
     setEditingId(null);
+    setEditingHasSecret(false);
     setEditorError(null);
     setHeadersJson("{}");
     setEditorOpen(true);
@@ -91,6 +93,7 @@ export function WebhooksList() {
       retry,
       active: row.active,
     });
+    setEditingHasSecret(row.has_secret ?? false);
     setEditingId(row.id);
     setEditorError(null);
     setHeadersJson(JSON.stringify(headers, null, 2));
@@ -131,6 +134,9 @@ export function WebhooksList() {
       retry: wh.retry,
       active: wh.active,
     };
+    if (wh.secret) {
+      payload.secret = wh.secret;
+    }
 
     setSaving(true);
     setEditorError(null);
@@ -354,6 +360,23 @@ export function WebhooksList() {
             />
           </div>
 
+          <div class="form-group">
+            <label class="form-label">Signing Secret</label>
+            <input
+              type="password"
+              class="form-input"
+              autocomplete="new-password"
+              value={editingWH().secret ?? ""}
+              onInput={(e) =>
+                setEditingWH({ ...editingWH(), secret: e.currentTarget.value })
+              }
+              placeholder={editingHasSecret() ? "Secret set (leave empty to keep it)" : "Optional"}
+            />
+            <p class="text-xs text-gray-500 dark:text-gray-400 mt-1">
+              Deliveries carry X-Rocket-Signature: sha256=&lt;HMAC of the body&gt;
+            </p>
+          </div>
+
           <div class="form-group">
             <label class="form-label">Condition (empty = always fire)</label>
             <ExpressionBuilder
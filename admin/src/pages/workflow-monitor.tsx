@@ -229,6 +229,22 @@ export function WorkflowMonitor() {
                 </div>
               </Show>
 
+              <Show when={inst().last_error}>
+                <div>
+                  <span class="text-xs text-gray-500 dark:text-gray-400">Last Error</span>
+                  <p class="text-sm text-red-600 dark:text-red-400">{inst().last_error}</p>
+                </div>
+              </Show>
+
+              <Show when={inst().next_retry_at}>
+                <div>
+                  <span class="text-xs text-gray-500 dark:text-gray-400">Next Retry</span>
+                  <p class="text-sm">
+                    {new Date(inst().next_retry_at!).toLocaleString()} (attempt {(inst().attempts ?? 0) + 1} of {inst().max_attempts})
+                  </p>
+                </div>
+              </Show>
+
               <div>
                 <span class="text-xs text-gray-500 dark:text-gray-400">Context</span>
                 <pre class="text-xs bg-gray-50 dark:bg-gray-800/50 p-2 rounded mt-1 overflow-auto" style="max-height: 150px;">
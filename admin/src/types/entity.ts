@@ -27,6 +27,8 @@ export const FIELD_TYPES: FieldType[] = [
 
 export const PK_TYPES = ["uuid", "int", "bigint", "string"] as const;
 
+export const ID_STRATEGIES = ["uuid", "uuidv7", "nanoid", "sequence"] as const;
+
 export interface Field {
   name: string;
   type: FieldType;
@@ -55,6 +57,7 @@ export interface EntityDefinition {
   name: string;
   table: string;
   primary_key: PrimaryKey;
+  id_strategy?: (typeof ID_STRATEGIES)[number];
   soft_delete: boolean;
   slug?: SlugConfig;
   fields: Field[];
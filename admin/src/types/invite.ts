@@ -34,5 +34,6 @@ export interface BulkInviteSkipped {
 export interface BulkInviteResult {
   created: BulkInviteCreated[];
   skipped: BulkInviteSkipped[];
-  summary: { total: number; created: number; skipped: number };
+  failed: BulkInviteSkipped[];
+  summary: { total: number; created: number; skipped: number; failed: number };
 }
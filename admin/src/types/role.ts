@@ -0,0 +1,4 @@
+export interface RoleRow {
+  name: string;
+  created_at: string;
+}
@@ -1,9 +1,22 @@
 export type RuleType = "field" | "expression" | "computed";
-export type RuleHook = "before_write" | "before_delete";
+export type RuleHook =
+  | "before_write"
+  | "before_create"
+  | "before_update"
+  | "before_delete"
+  | "after_write"
+  | "after_delete";
 export type FieldOperator = "min" | "max" | "min_length" | "max_length" | "pattern";
 
 export const RULE_TYPES: RuleType[] = ["field", "expression", "computed"];
-export const RULE_HOOKS: RuleHook[] = ["before_write", "before_delete"];
+export const RULE_HOOKS: RuleHook[] = [
+  "before_write",
+  "before_create",
+  "before_update",
+  "before_delete",
+  "after_write",
+  "after_delete",
+];
 export const FIELD_OPERATORS: FieldOperator[] = [
   "min",
   "max",
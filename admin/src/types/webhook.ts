@@ -14,6 +14,7 @@ export interface WebhookRow {
   async: boolean;
   retry: WebhookRetry | string;
   active: boolean;
+  has_secret?: boolean;
   created_at: string;
   updated_at: string;
 }
@@ -29,6 +30,8 @@ export interface WebhookPayload {
   async: boolean;
   retry: WebhookRetry;
   active: boolean;
+  // Write-only: omitted keeps the stored secret, "" removes it
+  secret?: string;
 }
 
 export interface WebhookLogRow {
@@ -92,6 +92,10 @@ export interface WorkflowInstance {
   status: string;
   current_step: string;
   current_step_deadline?: string | null;
+  attempts?: number;
+  max_attempts?: number;
+  last_error?: string;
+  next_retry_at?: string | null;
   context: Record<string, any>;
   history: WorkflowHistoryEntry[];
   created_at?: string;
@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"rocket-backend/internal/admin"
+	"rocket-backend/internal/auth"
 	"rocket-backend/internal/config"
 	"rocket-backend/internal/engine"
 	"rocket-backend/internal/multiapp"
@@ -41,11 +45,38 @@ func main() {
 	}
 	log.Println("Platform tables ready")
 
+	engine.MaxWorkflowSteps = cfg.Workflows.MaxSteps
+	engine.WorkflowRetryMaxAttempts = cfg.Workflows.RetryMaxAttempts
+	engine.WorkflowRetryBackoff = time.Duration(cfg.Workflows.RetryBackoffSeconds) * time.Second
+	engine.WebhookDedupWindow = time.Duration(cfg.Webhooks.DedupWindowSeconds) * time.Second
+	if engine.WebhookTargets, err = engine.NewWebhookTargetPolicy(cfg.Webhooks.AllowedTargets, cfg.Webhooks.DeniedTargets, cfg.Webhooks.AllowInternalTargets); err != nil {
+		log.Fatalf("Invalid webhooks config: %v", err)
+	}
+	engine.WebhookSecretKey = cfg.Webhooks.SecretKey
+	engine.MaxIncludeDepth = cfg.Query.MaxIncludeDepth
+	engine.MaxIncludeRecords = cfg.Query.MaxIncludeRecords
+	engine.MaxCacheEntries = cfg.Query.MaxCacheEntries
+	engine.DefaultPerPage = cfg.Pagination.API.DefaultPerPage
+	engine.MaxPerPage = cfg.Pagination.API.MaxPerPage
+	admin.DefaultPerPage = cfg.Pagination.Admin.DefaultPerPage
+	admin.MaxPerPage = cfg.Pagination.Admin.MaxPerPage
+	admin.AllowTruncate = cfg.Admin.AllowTruncate
+	admin.ImportMigrationConcurrency = cfg.Admin.ImportMigrationConcurrency
+	engine.StrictFields = cfg.Writes.StrictFields
+	engine.RuleOrder = cfg.Writes.RuleOrder
+	engine.ExprTimeout = time.Duration(cfg.Writes.ExprTimeoutMs) * time.Millisecond
+	engine.EmptyStrings = cfg.Writes.EmptyStrings
+	engine.MaxBulkRecords = cfg.Writes.MaxBulkRecords
+	store.NormalizeEmails = cfg.Auth.NormalizeEmails
+	if auth.SigningKeys, err = auth.LoadKeyRing(cfg.JWT); err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
 	// 4. Create file storage
 	fileStorage := storage.NewLocalStorage(cfg.Storage.LocalPath)
 
 	// 5. Create AppManager and load all existing apps
-	manager := multiapp.NewAppManager(mgmtStore, cfg.Database, cfg.AppPoolSize, fileStorage, cfg.Storage.MaxFileSize, cfg.Instrumentation, cfg.AI)
+	manager := multiapp.NewAppManager(mgmtStore, cfg.Database, cfg.AppPoolSize, fileStorage, cfg.Storage.MaxFileSize, cfg.Instrumentation, cfg.AI, cfg.Bootstrap)
 	defer manager.Close()
 
 	if err := manager.LoadAll(ctx); err != nil {
@@ -62,12 +93,37 @@ func main() {
 	app.Use(logger.New(logger.Config{
 		Format: "${time} ${status} ${method} ${path} ${latency}\n",
 	}))
+	if cfg.CORS.Enabled {
+		app.Use(cors.New(cors.Config{
+			AllowOrigins:  cfg.CORS.AllowOrigins,
+			MaxAge:        cfg.CORS.MaxAge,
+			ExposeHeaders: "Location, Preference-Applied", // Prefer: return=minimal responses
+		}))
+	}
+	if cfg.Security.Headers {
+		app.Use(multiapp.SecurityHeadersMiddleware(cfg.Security))
+	}
+	if cfg.Database.QueryTags {
+		app.Use(multiapp.QueryTagMiddleware())
+	}
+	if cfg.Server.RequestTimeoutMs > 0 {
+		app.Use(multiapp.RequestTimeoutMiddleware(time.Duration(cfg.Server.RequestTimeoutMs)*time.Millisecond, cfg.Server.RequestTimeoutExclude))
+	}
 
-	// 6. Health check
+	// 6. Health check — degraded (503) when a scheduler has stopped ticking
 	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{"status": "ok"})
+		schedulers := engine.SchedulerStatuses()
+		for _, s := range schedulers {
+			if !s.Healthy {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "degraded", "schedulers": schedulers})
+			}
+		}
+		return c.JSON(fiber.Map{"status": "ok", "schedulers": schedulers})
 	})
 
+	// JSON Web Key Set for external token verification (RS256/ES256 only)
+	app.Get("/.well-known/jwks.json", auth.JWKSHandler)
+
 	// 7. Platform routes (auth + app CRUD)
 	platformHandler := multiapp.NewPlatformHandler(mgmtStore, cfg.PlatformJWTSecret, manager, cfg.AI)
 	platformAuthMW := multiapp.PlatformAuthMiddleware(cfg.PlatformJWTSecret)
@@ -77,7 +133,7 @@ func main() {
 	multiapp.RegisterAppRoutes(app, manager, cfg.PlatformJWTSecret, cfg.Instrumentation)
 
 	// 9. Start multi-app schedulers
-	scheduler := multiapp.NewMultiAppScheduler(manager, cfg.Instrumentation)
+	scheduler := multiapp.NewMultiAppScheduler(manager, cfg.Instrumentation, cfg.Webhooks)
 	scheduler.Start()
 	defer scheduler.Stop()
 
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/store"
+)
+
+// MaxCountByGroups caps the groups GET /_admin/entities/:name/records/count-by/:field
+// returns; a request's ?limit can only lower it.
+var MaxCountByGroups = 100
+
+// CountBy handles GET /_admin/entities/:name/records/count-by/:field — how
+// many records hold each value of a field, largest groups first, for
+// dashboard breakdowns like orders by status. ?from= and ?to= (a date or
+// RFC 3339 timestamp) keep records whose time_field (default created_at) is
+// in [from, to). Soft-deleted records are not counted. meta.truncated
+// reports groups cut off by the limit.
+func (h *Handler) CountBy(c *fiber.Ctx) error {
+	name := c.Params("name")
+	entity := h.registry.GetEntity(name)
+	if entity == nil {
+		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Entity not found: " + name}})
+	}
+	fieldName := c.Params("field")
+	field := entity.GetField(fieldName)
+	if field == nil {
+		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "UNKNOWN_FIELD", "message": "Unknown field: " + fieldName}})
+	}
+	switch field.Type {
+	case "json", "file", "array", "geopoint":
+		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": fmt.Sprintf("count-by is not supported on %s field %s", field.Type, fieldName)}})
+	}
+
+	plan := &engine.QueryPlan{Entity: entity}
+	if from, to := c.Query("from"), c.Query("to"); from != "" || to != "" {
+		timeField := c.Query("time_field", "created_at")
+		if f := entity.GetField(timeField); f == nil || (f.Type != "timestamp" && f.Type != "date") {
+			return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": fmt.Sprintf("time_field %s must be a timestamp or date field of %s", timeField, name)}})
+		}
+		for _, bound := range []struct{ param, value, op string }{{"from", from, "gte"}, {"to", to, "lt"}} {
+			if bound.value == "" {
+				continue
+			}
+			t, err := parseCutoff(bound.value)
+			if err != nil {
+				return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": bound.param + " must be a date (2006-01-02) or RFC 3339 timestamp"}})
+			}
+			plan.Filters = append(plan.Filters, engine.WhereClause{Field: timeField, Operator: bound.op, Value: h.store.Dialect.TimeParam(t)})
+		}
+	}
+
+	limit := MaxCountByGroups
+	if l := c.QueryInt("limit"); l > 0 && l < limit {
+		limit = l
+	}
+
+	// One extra group is fetched to tell whether any were cut off.
+	qr := engine.BuildCountBySQL(plan, fieldName, limit+1, h.store.Dialect)
+	rows, err := store.QueryRows(c.Context(), h.store.DB, qr.SQL, qr.Params...)
+	if err != nil {
+		return fmt.Errorf("count %s by %s: %w", name, fieldName, err)
+	}
+	truncated := len(rows) > limit
+	if truncated {
+		rows = rows[:limit]
+	}
+	if rows == nil {
+		rows = []map[string]any{}
+	}
+	if field.Type == "boolean" && h.store.Dialect.NeedsBoolFix() {
+		store.NormalizeBooleans(rows, []string{"value"})
+	}
+	return c.JSON(fiber.Map{"data": rows, "meta": fiber.Map{"limit": limit, "truncated": truncated}})
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rocket-backend/internal/auth"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// exportUsers returns the users and pending invites written by an export with
+// ?include_users=true. Only email, roles and the active flag are exported:
+// password hashes, invite tokens and ids never leave the database.
+func (h *Handler) exportUsers(ctx context.Context) ([]map[string]any, []map[string]any, error) {
+	userRows, err := store.QueryRows(ctx, h.store.DB,
+		"SELECT email, roles, active FROM _users ORDER BY email")
+	if err != nil {
+		return nil, nil, fmt.Errorf("export users: %w", err)
+	}
+	if h.store.Dialect.NeedsBoolFix() {
+		store.NormalizeBooleans(userRows, []string{"active"})
+	}
+	users := make([]map[string]any, 0, len(userRows))
+	for _, row := range userRows {
+		users = append(users, map[string]any{
+			"email": row["email"], "roles": metadata.ParseStringArray(row["roles"]), "active": row["active"],
+		})
+	}
+
+	inviteRows, err := store.QueryRows(ctx, h.store.DB,
+		fmt.Sprintf("SELECT email, roles FROM _invites WHERE accepted_at IS NULL AND expires_at > %s ORDER BY email",
+			h.store.Dialect.NowExpr()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("export invites: %w", err)
+	}
+	invites := make([]map[string]any, 0, len(inviteRows))
+	for _, row := range inviteRows {
+		invites = append(invites, map[string]any{
+			"email": row["email"], "roles": metadata.ParseStringArray(row["roles"]),
+		})
+	}
+	return users, invites, nil
+}
+
+// importUsers creates the payload's users and pending invites, skipping emails
+// that already belong to a user (or, for invites, have a pending invite). Roles
+// they name that aren't in _roles yet are added.
+// Exports carry no passwords, so imported users get an unusable random one and
+// are flagged password_reset_required: they cannot sign in until an admin sets
+// a password. Invites are reissued with a new token and a fresh expiry. The
+// rows are written on q, the import's transaction.
+func (h *Handler) importUsers(ctx context.Context, q store.Querier, payload *importPayload, summary map[string]int) []string {
+	var errs []string
+	summary["users"] = 0
+	summary["invites"] = 0
+
+	for _, raw := range payload.Users {
+		email, _ := raw["email"].(string)
+		email = store.NormalizeEmail(email)
+		if email == "" {
+			continue
+		}
+		if h.userExists(ctx, q, email) {
+			continue
+		}
+		hash, err := auth.HashPassword(store.GenerateUUID())
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("User %s: %v", email, err))
+			continue
+		}
+		active := true
+		if v, ok := raw["active"].(bool); ok {
+			active = v
+		}
+		roles := metadata.ParseStringArray(raw["roles"])
+		if roles == nil {
+			roles = []string{}
+		}
+		id := store.GenerateUUID()
+		if err := importSavepoint(ctx, q, func() error {
+			if err := h.addRoles(ctx, q, roles); err != nil {
+				return err
+			}
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.Exec(ctx, q,
+				fmt.Sprintf("INSERT INTO _users (id, email, password_hash, roles, active, password_reset_required) VALUES (%s, %s, %s, %s, %s, %s)",
+					pb.Add(id), pb.Add(email), pb.Add(hash), pb.Add(h.store.Dialect.ArrayParam(roles)), pb.Add(active), pb.Add(true)),
+				pb.Params()...)
+			return err
+		}); err != nil {
+			errs = append(errs, fmt.Sprintf("User %s: %v", email, err))
+			continue
+		}
+		summary["users"]++
+	}
+
+	for _, raw := range payload.Invites {
+		email, _ := raw["email"].(string)
+		email = store.NormalizeEmail(email)
+		if email == "" {
+			continue
+		}
+		if h.userExists(ctx, q, email) {
+			continue
+		}
+		if h.pendingInviteExists(ctx, q, email) {
+			continue
+		}
+		roles := metadata.ParseStringArray(raw["roles"])
+		if roles == nil {
+			roles = []string{}
+		}
+		id := store.GenerateUUID()
+		if err := importSavepoint(ctx, q, func() error {
+			if err := h.addRoles(ctx, q, roles); err != nil {
+				return err
+			}
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.Exec(ctx, q,
+				fmt.Sprintf("INSERT INTO _invites (id, email, roles, token, expires_at) VALUES (%s, %s, %s, %s, %s)",
+					pb.Add(id), pb.Add(email), pb.Add(h.store.Dialect.ArrayParam(roles)),
+					pb.Add(store.GenerateUUID()), pb.Add(time.Now().Add(72*time.Hour))),
+				pb.Params()...)
+			return err
+		}); err != nil {
+			errs = append(errs, fmt.Sprintf("Invite %s: %v", email, err))
+			continue
+		}
+		summary["invites"]++
+	}
+	return errs
+}
+
+// userExists reports whether a user with the given email exists.
+func (h *Handler) userExists(ctx context.Context, q store.Querier, email string) bool {
+	pb := h.store.Dialect.NewParamBuilder()
+	_, err := store.QueryRow(ctx, q,
+		fmt.Sprintf("SELECT id FROM _users WHERE LOWER(email) = LOWER(%s)", pb.Add(email)),
+		pb.Params()...)
+	return err == nil
+}
+
+// pendingInviteExists reports whether an unaccepted, unexpired invite for the
+// given email exists.
+func (h *Handler) pendingInviteExists(ctx context.Context, q store.Querier, email string) bool {
+	pb := h.store.Dialect.NewParamBuilder()
+	_, err := store.QueryRow(ctx, q,
+		fmt.Sprintf("SELECT id FROM _invites WHERE LOWER(email) = LOWER(%s) AND accepted_at IS NULL AND expires_at > %s",
+			pb.Add(email), h.store.Dialect.NowExpr()),
+		pb.Params()...)
+	return err == nil
+}
@@ -1,29 +1,60 @@
 package admin
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"rocket-backend/internal/auth"
+	"rocket-backend/internal/engine"
 	"rocket-backend/internal/metadata"
 	"rocket-backend/internal/store"
 )
 
+// DefaultPerPage is the page size of the admin log lists (webhook logs and
+// the audit log) when a request has no per_page; MaxPerPage caps per_page.
+// They are separate from the dynamic API's engine.DefaultPerPage and
+// engine.MaxPerPage.
+var (
+	DefaultPerPage = 200
+	MaxPerPage     = 1000
+)
+
+// AllowTruncate enables POST /_admin/entities/:name/truncate. It is off by
+// default so production data can't be wiped by one admin call; enable it on
+// test and staging servers.
+var AllowTruncate = false
+
+// ImportMigrationConcurrency caps how many entity tables POST /_admin/import
+// creates at once.
+var ImportMigrationConcurrency = 4
+
 type Handler struct {
 	store    *store.Store
 	registry *metadata.Registry
 	migrator *store.Migrator
+	settings *settingsCache
+	reindex  sync.Mutex // one index rebuild at a time
 }
 
 func NewHandler(s *store.Store, reg *metadata.Registry, mig *store.Migrator) *Handler {
-	return &Handler{store: s, registry: reg, migrator: mig}
+	return &Handler{store: s, registry: reg, migrator: mig, settings: &settingsCache{}}
 }
 
 func RegisterAdminRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handler) {
+	// Public settings need no auth, so they sit outside the admin group
+	app.Get("/api/settings/public", h.PublicSettings)
+
 	admin := app.Group("/api/_admin", middleware...)
 
 	admin.Get("/entities", h.ListEntities)
@@ -31,8 +62,12 @@ func RegisterAdminRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handler
 	admin.Post("/entities", h.CreateEntity)
 	admin.Put("/entities/:name", h.UpdateEntity)
 	admin.Delete("/entities/:name", h.DeleteEntity)
+	admin.Post("/entities/:name/reindex", h.ReindexEntity)
+	admin.Post("/entities/:name/truncate", h.TruncateEntity)
+	admin.Get("/entities/:name/records/count-by/:field", h.CountBy)
 
 	admin.Get("/relations", h.ListRelations)
+	admin.Get("/relations/graph", h.RelationGraph)
 	admin.Get("/relations/:name", h.GetRelation)
 	admin.Post("/relations", h.CreateRelation)
 	admin.Put("/relations/:name", h.UpdateRelation)
@@ -42,6 +77,7 @@ func RegisterAdminRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handler
 	admin.Get("/rules/:id", h.GetRule)
 	admin.Post("/rules", h.CreateRule)
 	admin.Put("/rules/:id", h.UpdateRule)
+	admin.Post("/rules/:id/toggle", h.ToggleRule)
 	admin.Delete("/rules/:id", h.DeleteRule)
 
 	admin.Get("/state-machines", h.ListStateMachines)
@@ -62,9 +98,13 @@ func RegisterAdminRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handler
 	admin.Put("/users/:id", h.UpdateUser)
 	admin.Delete("/users/:id", h.DeleteUser)
 
+	admin.Get("/roles", h.ListRoles)
+	admin.Post("/roles", h.CreateRole)
+
 	admin.Get("/permissions", h.ListPermissions)
 	admin.Get("/permissions/:id", h.GetPermission)
 	admin.Post("/permissions", h.CreatePermission)
+	admin.Post("/permissions/bulk", h.BulkCreatePermissions)
 	admin.Put("/permissions/:id", h.UpdatePermission)
 	admin.Delete("/permissions/:id", h.DeletePermission)
 
@@ -72,12 +112,16 @@ func RegisterAdminRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handler
 	admin.Get("/webhooks/:id", h.GetWebhook)
 	admin.Post("/webhooks", h.CreateWebhook)
 	admin.Put("/webhooks/:id", h.UpdateWebhook)
+	admin.Post("/webhooks/:id/toggle", h.ToggleWebhook)
 	admin.Delete("/webhooks/:id", h.DeleteWebhook)
 
 	admin.Get("/webhook-logs", h.ListWebhookLogs)
+	admin.Get("/webhook-logs/export.csv", h.ExportWebhookLogs)
 	admin.Get("/webhook-logs/:id", h.GetWebhookLog)
 	admin.Post("/webhook-logs/:id/retry", h.RetryWebhookLog)
 
+	admin.Get("/audit-log", h.ListAuditLog)
+
 	admin.Post("/invites/bulk", h.BulkCreateInvites)
 	admin.Get("/invites", h.ListInvites)
 	admin.Post("/invites", h.CreateInvite)
@@ -85,6 +129,13 @@ func RegisterAdminRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handler
 
 	admin.Get("/export", h.Export)
 	admin.Post("/import", h.Import)
+
+	admin.Get("/settings", h.ListSettings)
+	admin.Get("/settings/:key", h.GetSetting)
+	admin.Put("/settings/:key", h.PutSetting)
+	admin.Delete("/settings/:key", h.DeleteSetting)
+
+	admin.Get("/cache", h.CacheStats)
 }
 
 // --- Entity Endpoints ---
@@ -122,6 +173,9 @@ func (h *Handler) CreateEntity(c *fiber.Ctx) error {
 	if err := validateEntity(&entity); err != nil {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
 	}
+	if err := validateRollupTargets(&entity, h.registry); err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
+	}
 
 	// Check for duplicate
 	existing := h.registry.GetEntity(entity.Name)
@@ -152,6 +206,9 @@ func (h *Handler) CreateEntity(c *fiber.Ctx) error {
 	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
 		return fmt.Errorf("reload registry: %w", err)
 	}
+	if err := h.recomputeRollups(c, entity.Name); err != nil {
+		return err
+	}
 
 	return c.Status(201).JSON(fiber.Map{"data": entity})
 }
@@ -172,6 +229,41 @@ func (h *Handler) UpdateEntity(c *fiber.Ctx) error {
 	if err := validateEntity(&entity); err != nil {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
 	}
+	if err := validateRollupTargets(&entity, h.registry); err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
+	}
+	if err := h.migrator.ValidateRenames(c.Context(), &entity); err != nil {
+		if errors.Is(err, store.ErrInvalidRename) {
+			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
+		}
+		return fmt.Errorf("validate renames for %s: %w", name, err)
+	}
+	tightened, loosened := store.RequiredChanges(existing, &entity)
+	violations, err := h.migrator.CheckRequired(c.Context(), &entity, tightened)
+	if err != nil {
+		return fmt.Errorf("check required fields for %s: %w", name, err)
+	}
+	if len(violations) > 0 {
+		details := make([]fiber.Map, 0, len(violations))
+		for _, v := range violations {
+			details = append(details, fiber.Map{
+				"field":   v.Field,
+				"rule":    "required",
+				"message": fmt.Sprintf("%d existing rows have no value; set backfill or update them first", v.Rows),
+				"rows":    v.Rows,
+			})
+		}
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{
+			"code":    "VALIDATION_FAILED",
+			"message": "Existing rows would violate NOT NULL on fields becoming required",
+			"details": details,
+		}})
+	}
+	// Backfill and NOT NULL changes go first, in their own transaction: if
+	// they fail, neither the definition nor the table has changed yet
+	if err := h.migrator.ApplyRequiredChanges(c.Context(), &entity, tightened, loosened); err != nil {
+		return fmt.Errorf("apply required fields for %s: %w", entity.Name, err)
+	}
 
 	defJSON, err := json.Marshal(entity)
 	if err != nil {
@@ -194,39 +286,171 @@ func (h *Handler) UpdateEntity(c *fiber.Ctx) error {
 	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
 		return fmt.Errorf("reload registry: %w", err)
 	}
+	if err := h.recomputeRollups(c, entity.Name); err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{"data": entity})
 }
 
+// DeleteEntity handles DELETE /_admin/entities/:name. In one transaction it
+// removes the entity with its relations, rules, state machines, permissions,
+// webhooks and UI configs, and drops its table and the join tables of its
+// many_to_many relations. ?drop_table=false keeps the tables and their data.
 func (h *Handler) DeleteEntity(c *fiber.Ctx) error {
 	name := c.Params("name")
 	existing := h.registry.GetEntity(name)
 	if existing == nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Entity not found: " + name}})
 	}
+	dropTable := c.QueryBool("drop_table", true)
 
-	// Delete relations first (FK constraint)
-	pb := h.store.Dialect.NewParamBuilder()
-	_, err := store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("DELETE FROM _relations WHERE source = %s OR target = %s", pb.Add(name), pb.Add(name)),
-		pb.Params()...)
-	if err != nil {
-		return fmt.Errorf("delete relations for entity %s: %w", name, err)
+	// Join tables of the many_to_many relations that go with the entity
+	joinTables := []string{}
+	for _, rel := range h.registry.AllRelations() {
+		if rel.IsManyToMany() && (rel.Source == name || rel.Target == name) && !slices.Contains(joinTables, rel.PhysicalJoinTable(h.store.TablePrefix)) {
+			joinTables = append(joinTables, rel.PhysicalJoinTable(h.store.TablePrefix))
+		}
 	}
 
-	pb2 := h.store.Dialect.NewParamBuilder()
-	_, err = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("DELETE FROM _entities WHERE name = %s", pb2.Add(name)),
-		pb2.Params()...)
+	err := h.store.Tx(c.Context(), func(tx store.Querier) error {
+		// Relations first (FK constraint), then the metadata that names the
+		// entity, then the entity itself
+		pb := h.store.Dialect.NewParamBuilder()
+		if _, err := store.Exec(c.Context(), tx,
+			fmt.Sprintf("DELETE FROM _relations WHERE source = %s OR target = %s", pb.Add(name), pb.Add(name)),
+			pb.Params()...); err != nil {
+			return fmt.Errorf("delete relations for entity %s: %w", name, err)
+		}
+		for _, table := range []string{"_rules", "_state_machines", "_permissions", "_webhooks", "_ui_configs", "_entities"} {
+			column := "entity"
+			if table == "_entities" {
+				column = "name"
+			}
+			pb := h.store.Dialect.NewParamBuilder()
+			if _, err := store.Exec(c.Context(), tx,
+				fmt.Sprintf("DELETE FROM %s WHERE %s = %s", table, column, pb.Add(name)),
+				pb.Params()...); err != nil {
+				return fmt.Errorf("delete %s for entity %s: %w", table, name, err)
+			}
+		}
+		if dropTable {
+			return h.migrator.Drop(c.Context(), tx, existing, joinTables)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("delete entity %s: %w", name, err)
+		return err
 	}
+	engine.InvalidateEntityCache(h.registry, name)
 
 	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
 		return fmt.Errorf("reload registry: %w", err)
 	}
 
-	return c.JSON(fiber.Map{"data": fiber.Map{"name": name, "deleted": true}})
+	dropped := []string{}
+	if dropTable {
+		dropped = append([]string{existing.Table}, joinTables...)
+	}
+	return c.JSON(fiber.Map{"data": fiber.Map{"name": name, "deleted": true, "dropped_tables": dropped}})
+}
+
+// ReindexEntity handles POST /_admin/entities/:name/reindex. It drops and
+// recreates the entity's declared indexes, then with ?analyze=true (or
+// ?vacuum=true) runs the dialect's table maintenance. Rebuilding locks the
+// table, so only one reindex runs at a time; a second gets 409.
+func (h *Handler) ReindexEntity(c *fiber.Ctx) error {
+	name := c.Params("name")
+	entity := h.registry.GetEntity(name)
+	if entity == nil {
+		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Entity not found: " + name}})
+	}
+	if !h.reindex.TryLock() {
+		return c.Status(409).JSON(fiber.Map{"error": fiber.Map{"code": "CONFLICT", "message": "A reindex is already running"}})
+	}
+	defer h.reindex.Unlock()
+
+	start := time.Now()
+	rebuilt, err := h.migrator.Reindex(c.Context(), entity)
+	if err != nil {
+		return fmt.Errorf("reindex %s: %w", name, err)
+	}
+	maintenance := []string{}
+	if vacuum := c.QueryBool("vacuum"); vacuum || c.QueryBool("analyze") {
+		ran, err := h.migrator.Maintain(c.Context(), entity, vacuum)
+		if err != nil {
+			return fmt.Errorf("maintain %s: %w", name, err)
+		}
+		maintenance = append(maintenance, ran...)
+	}
+
+	return c.JSON(fiber.Map{"data": fiber.Map{
+		"entity":      name,
+		"indexes":     rebuilt,
+		"maintenance": maintenance,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}})
+}
+
+// TruncateEntity handles POST /_admin/entities/:name/truncate?confirm=true.
+// It removes every record of the entity and keeps the schema, for resetting
+// test and staging data. ?join_tables=true also empties the join tables of
+// its many_to_many relations, and ?restart_identity=true restarts sequence
+// keys. No hooks, rules or webhooks run; one audit entry records the truncate.
+func (h *Handler) TruncateEntity(c *fiber.Ctx) error {
+	if !AllowTruncate {
+		return c.Status(403).JSON(fiber.Map{"error": fiber.Map{"code": "FORBIDDEN", "message": "Truncate is disabled on this server (admin.allow_truncate)"}})
+	}
+	name := c.Params("name")
+	entity := h.registry.GetEntity(name)
+	if entity == nil {
+		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Entity not found: " + name}})
+	}
+	if !c.QueryBool("confirm") {
+		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "CONFIRMATION_REQUIRED", "message": "Truncate removes every " + name + " record; repeat the request with confirm=true"}})
+	}
+
+	joinTables := []string{}
+	if c.QueryBool("join_tables") {
+		for _, rel := range h.registry.AllRelations() {
+			if rel.IsManyToMany() && (rel.Source == name || rel.Target == name) && !slices.Contains(joinTables, rel.PhysicalJoinTable(h.store.TablePrefix)) {
+				joinTables = append(joinTables, rel.PhysicalJoinTable(h.store.TablePrefix))
+			}
+		}
+	}
+	restart := c.QueryBool("restart_identity")
+	user, _ := c.Locals("user").(*metadata.UserContext)
+	var removed int64
+	err := h.store.Tx(c.Context(), func(tx store.Querier) error {
+		var err error
+		if removed, err = h.migrator.Truncate(c.Context(), tx, entity, joinTables, restart); err != nil {
+			return err
+		}
+		if err := engine.WriteAuditEntry(c.Context(), tx, h.store.Dialect, entity, "*", "truncate", user, map[string]engine.AuditChange{}); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("truncate %s: %w", name, err)
+	}
+	engine.InvalidateEntityCache(h.registry, name)
+
+	// Rollups of parent entities aggregated the removed records
+	for _, rel := range h.registry.AllRelations() {
+		if rel.Target == name && rel.Source != name {
+			if err := h.recomputeRollups(c, rel.Source); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.JSON(fiber.Map{"data": fiber.Map{
+		"entity":           name,
+		"removed":          removed,
+		"join_tables":      joinTables,
+		"restart_identity": restart,
+	}})
 }
 
 // --- Relation Endpoints ---
@@ -275,29 +499,38 @@ func (h *Handler) CreateRelation(c *fiber.Ctx) error {
 		return fmt.Errorf("marshal relation: %w", err)
 	}
 
-	pb := h.store.Dialect.NewParamBuilder()
-	_, err = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("INSERT INTO _relations (name, source, target, definition) VALUES (%s, %s, %s, %s)",
-			pb.Add(rel.Name), pb.Add(rel.Source), pb.Add(rel.Target), pb.Add(defJSON)),
-		pb.Params()...)
-	if err != nil {
-		return fmt.Errorf("insert relation: %w", err)
-	}
+	// The definition row and the join table land together or not at all
+	err = h.store.Tx(c.Context(), func(tx store.Querier) error {
+		pb := h.store.Dialect.NewParamBuilder()
+		if _, err := store.Exec(c.Context(), tx,
+			fmt.Sprintf("INSERT INTO _relations (name, source, target, definition) VALUES (%s, %s, %s, %s)",
+				pb.Add(rel.Name), pb.Add(rel.Source), pb.Add(rel.Target), pb.Add(defJSON)),
+			pb.Params()...); err != nil {
+			return fmt.Errorf("insert relation: %w", err)
+		}
 
-	// Create join table for many-to-many
-	if rel.IsManyToMany() {
-		sourceEntity := h.registry.GetEntity(rel.Source)
-		targetEntity := h.registry.GetEntity(rel.Target)
-		if sourceEntity != nil && targetEntity != nil {
-			if err := h.migrator.MigrateJoinTable(c.Context(), &rel, sourceEntity, targetEntity); err != nil {
-				return fmt.Errorf("create join table: %w", err)
+		// Create join table for many-to-many
+		if rel.IsManyToMany() {
+			sourceEntity := h.registry.GetEntity(rel.Source)
+			targetEntity := h.registry.GetEntity(rel.Target)
+			if sourceEntity != nil && targetEntity != nil {
+				if err := h.migrator.MigrateJoinTable(c.Context(), tx, &rel, sourceEntity, targetEntity); err != nil {
+					return fmt.Errorf("create join table: %w", err)
+				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
 		return fmt.Errorf("reload registry: %w", err)
 	}
+	if err := h.recomputeRollups(c, rel.Source); err != nil {
+		return err
+	}
 
 	return c.Status(201).JSON(fiber.Map{"data": rel})
 }
@@ -336,10 +569,26 @@ func (h *Handler) UpdateRelation(c *fiber.Ctx) error {
 	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
 		return fmt.Errorf("reload registry: %w", err)
 	}
+	if err := h.recomputeRollups(c, rel.Source); err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{"data": rel})
 }
 
+// recomputeRollups brings the entity's rollup fields up to date after its
+// definition or one of its relations changed.
+func (h *Handler) recomputeRollups(c *fiber.Ctx, entityName string) error {
+	entity := h.registry.GetEntity(entityName)
+	if entity == nil {
+		return nil
+	}
+	if err := engine.RecomputeRollups(c.Context(), h.store.DB, h.store.Dialect, h.registry, entity); err != nil {
+		return fmt.Errorf("recompute rollups for %s: %w", entityName, err)
+	}
+	return nil
+}
+
 func (h *Handler) DeleteRelation(c *fiber.Ctx) error {
 	name := c.Params("name")
 	existing := h.registry.GetRelation(name)
@@ -362,11 +611,136 @@ func (h *Handler) DeleteRelation(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"data": fiber.Map{"name": name, "deleted": true}})
 }
 
+type graphField struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	Required   bool   `json:"required,omitempty"`
+	Unique     bool   `json:"unique,omitempty"`
+	Nullable   bool   `json:"nullable,omitempty"`
+}
+
+type graphNode struct {
+	ID         string       `json:"id"`
+	Table      string       `json:"table"`
+	SoftDelete bool         `json:"soft_delete"`
+	Fields     []graphField `json:"fields"`
+}
+
+type graphEdge struct {
+	ID            string `json:"id"`
+	Source        string `json:"source"`
+	Target        string `json:"target"`
+	Type          string `json:"type"`
+	SourceKey     string `json:"source_key"`
+	TargetKey     string `json:"target_key,omitempty"`
+	JoinTable     string `json:"join_table,omitempty"`
+	SourceJoinKey string `json:"source_join_key,omitempty"`
+	TargetJoinKey string `json:"target_join_key,omitempty"`
+	Ownership     string `json:"ownership"`
+	OnDelete      string `json:"on_delete"`
+	SelfReference bool   `json:"self_reference,omitempty"`
+}
+
+// RelationGraph returns the entity-relationship graph built from the registry:
+// one node per entity and one edge per relation, sorted by name.
+func (h *Handler) RelationGraph(c *fiber.Ctx) error {
+	entities := h.registry.AllEntities()
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Name < entities[j].Name })
+
+	nodes := make([]graphNode, 0, len(entities))
+	for _, e := range entities {
+		fields := make([]graphField, 0, len(e.Fields))
+		for _, f := range e.Fields {
+			fields = append(fields, graphField{
+				Name:       f.Name,
+				Type:       f.Type,
+				PrimaryKey: f.Name == e.PrimaryKey.Field,
+				Required:   f.Required,
+				Unique:     f.Unique,
+				Nullable:   f.Nullable,
+			})
+		}
+		nodes = append(nodes, graphNode{ID: e.Name, Table: e.Table, SoftDelete: e.SoftDelete, Fields: fields})
+	}
+
+	relations := h.registry.AllRelations()
+	sort.Slice(relations, func(i, j int) bool { return relations[i].Name < relations[j].Name })
+
+	edges := make([]graphEdge, 0, len(relations))
+	for _, rel := range relations {
+		edge := graphEdge{
+			ID:            rel.Name,
+			Source:        rel.Source,
+			Target:        rel.Target,
+			Type:          rel.Type,
+			SourceKey:     rel.SourceKey,
+			TargetKey:     rel.TargetKey,
+			Ownership:     rel.Ownership,
+			OnDelete:      rel.OnDelete,
+			SelfReference: rel.Source == rel.Target,
+		}
+		if rel.IsManyToMany() {
+			edge.JoinTable = rel.JoinTable
+			edge.SourceJoinKey = rel.SourceJoinKey
+			edge.TargetJoinKey = rel.TargetJoinKey
+		}
+		edges = append(edges, edge)
+	}
+
+	return c.JSON(fiber.Map{"data": fiber.Map{"nodes": nodes, "edges": edges}})
+}
+
+// CacheStats reports read cache hits, misses and live entries per cacheable entity.
+func (h *Handler) CacheStats(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"data": engine.EntityCacheStats(h.registry)})
+}
+
+// ListSchedulers reports the last run, last error and processed count of each
+// background scheduler. Schedulers serve every app, so the list is process-wide.
+func (h *Handler) ListSchedulers(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"data": engine.SchedulerStatuses()})
+}
+
+// tagFilter returns a WHERE clause keeping rows tagged with ?tag=, or "" when
+// the list isn't filtered.
+func (h *Handler) tagFilter(c *fiber.Ctx, pb store.ParamBuilder) string {
+	tag := strings.TrimSpace(c.Query("tag"))
+	if tag == "" {
+		return ""
+	}
+	return " WHERE " + h.store.Dialect.ArrayContainsExpr("tags", pb, tag)
+}
+
+// normalizeTags trims tags and drops blank and repeated ones, keeping order.
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// parseTags turns the tags column of each row from TEXT[]/JSON text into []string.
+func parseTags(rows ...map[string]any) {
+	for _, row := range rows {
+		row["tags"] = metadata.ParseStringArray(row["tags"])
+	}
+}
+
 // --- Rule Endpoints ---
 
 func (h *Handler) ListRules(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
 	rows, err := store.QueryRows(c.Context(), h.store.DB,
-		"SELECT id, entity, hook, type, definition, priority, active, created_at, updated_at FROM _rules ORDER BY entity, priority")
+		"SELECT id, entity, hook, type, definition, priority, active, tags, created_at, updated_at FROM _rules"+h.tagFilter(c, pb)+" ORDER BY entity, priority, created_at, id",
+		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("list rules: %w", err)
 	}
@@ -376,6 +750,7 @@ func (h *Handler) ListRules(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans(rows, []string{"active"})
 	}
+	parseTags(rows...)
 	return c.JSON(fiber.Map{"data": rows})
 }
 
@@ -383,7 +758,7 @@ func (h *Handler) GetRule(c *fiber.Ctx) error {
 	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, entity, hook, type, definition, priority, active, created_at, updated_at FROM _rules WHERE id = %s", pb.Add(id)),
+		fmt.Sprintf("SELECT id, entity, hook, type, definition, priority, active, tags, created_at, updated_at FROM _rules WHERE id = %s", pb.Add(id)),
 		pb.Params()...)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Rule not found: " + id}})
@@ -391,6 +766,7 @@ func (h *Handler) GetRule(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans([]map[string]any{row}, []string{"active"})
 	}
+	parseTags(row)
 	return c.JSON(fiber.Map{"data": row})
 }
 
@@ -403,6 +779,7 @@ func (h *Handler) CreateRule(c *fiber.Ctx) error {
 	if err := validateRule(&rule, h.registry); err != nil {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
 	}
+	rule.Tags = normalizeTags(rule.Tags)
 
 	defJSON, err := json.Marshal(rule.Definition)
 	if err != nil {
@@ -412,8 +789,9 @@ func (h *Handler) CreateRule(c *fiber.Ctx) error {
 	id := store.GenerateUUID()
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("INSERT INTO _rules (id, entity, hook, type, definition, priority, active) VALUES (%s, %s, %s, %s, %s, %s, %s) RETURNING id",
-			pb.Add(id), pb.Add(rule.Entity), pb.Add(rule.Hook), pb.Add(rule.Type), pb.Add(defJSON), pb.Add(rule.Priority), pb.Add(rule.Active)),
+		fmt.Sprintf("INSERT INTO _rules (id, entity, hook, type, definition, priority, active, tags) VALUES (%s, %s, %s, %s, %s, %s, %s, %s) RETURNING id",
+			pb.Add(id), pb.Add(rule.Entity), pb.Add(rule.Hook), pb.Add(rule.Type), pb.Add(defJSON), pb.Add(rule.Priority), pb.Add(rule.Active),
+			pb.Add(h.store.Dialect.ArrayParam(rule.Tags))),
 		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("insert rule: %w", err)
@@ -446,6 +824,7 @@ func (h *Handler) UpdateRule(c *fiber.Ctx) error {
 	if err := validateRule(&rule, h.registry); err != nil {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
 	}
+	rule.Tags = normalizeTags(rule.Tags)
 
 	defJSON, err := json.Marshal(rule.Definition)
 	if err != nil {
@@ -454,8 +833,9 @@ func (h *Handler) UpdateRule(c *fiber.Ctx) error {
 
 	pb2 := h.store.Dialect.NewParamBuilder()
 	_, err = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("UPDATE _rules SET entity = %s, hook = %s, type = %s, definition = %s, priority = %s, active = %s, updated_at = %s WHERE id = %s",
-			pb2.Add(rule.Entity), pb2.Add(rule.Hook), pb2.Add(rule.Type), pb2.Add(defJSON), pb2.Add(rule.Priority), pb2.Add(rule.Active), h.store.Dialect.NowExpr(), pb2.Add(id)),
+		fmt.Sprintf("UPDATE _rules SET entity = %s, hook = %s, type = %s, definition = %s, priority = %s, active = %s, tags = %s, updated_at = %s WHERE id = %s",
+			pb2.Add(rule.Entity), pb2.Add(rule.Hook), pb2.Add(rule.Type), pb2.Add(defJSON), pb2.Add(rule.Priority), pb2.Add(rule.Active),
+			pb2.Add(h.store.Dialect.ArrayParam(rule.Tags)), h.store.Dialect.NowExpr(), pb2.Add(id)),
 		pb2.Params()...)
 	if err != nil {
 		return fmt.Errorf("update rule: %w", err)
@@ -493,6 +873,11 @@ func (h *Handler) DeleteRule(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"data": fiber.Map{"id": id, "deleted": true}})
 }
 
+// ToggleRule handles POST /_admin/rules/:id/toggle. Flips active, or sets it from {"active": bool}.
+func (h *Handler) ToggleRule(c *fiber.Ctx) error {
+	return h.toggleActive(c, "_rules", "Rule")
+}
+
 // --- State Machine Endpoints ---
 
 func (h *Handler) ListStateMachines(c *fiber.Ctx) error {
@@ -627,8 +1012,10 @@ func (h *Handler) DeleteStateMachine(c *fiber.Ctx) error {
 // --- Workflow Endpoints ---
 
 func (h *Handler) ListWorkflows(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
 	rows, err := store.QueryRows(c.Context(), h.store.DB,
-		"SELECT id, name, trigger, context, steps, active, created_at, updated_at FROM _workflows ORDER BY name")
+		"SELECT id, name, trigger, context, steps, active, tags, created_at, updated_at FROM _workflows"+h.tagFilter(c, pb)+" ORDER BY name",
+		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("list workflows: %w", err)
 	}
@@ -638,6 +1025,7 @@ func (h *Handler) ListWorkflows(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans(rows, []string{"active"})
 	}
+	parseTags(rows...)
 	return c.JSON(fiber.Map{"data": rows})
 }
 
@@ -645,7 +1033,7 @@ func (h *Handler) GetWorkflow(c *fiber.Ctx) error {
 	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, name, trigger, context, steps, active, created_at, updated_at FROM _workflows WHERE id = %s", pb.Add(id)),
+		fmt.Sprintf("SELECT id, name, trigger, context, steps, active, tags, created_at, updated_at FROM _workflows WHERE id = %s", pb.Add(id)),
 		pb.Params()...)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Workflow not found: " + id}})
@@ -653,6 +1041,7 @@ func (h *Handler) GetWorkflow(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans([]map[string]any{row}, []string{"active"})
 	}
+	parseTags(row)
 	return c.JSON(fiber.Map{"data": row})
 }
 
@@ -665,6 +1054,7 @@ func (h *Handler) CreateWorkflow(c *fiber.Ctx) error {
 	if err := validateWorkflow(&wf, h.registry); err != nil {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
 	}
+	wf.Tags = normalizeTags(wf.Tags)
 
 	triggerJSON, err := json.Marshal(wf.Trigger)
 	if err != nil {
@@ -682,8 +1072,8 @@ func (h *Handler) CreateWorkflow(c *fiber.Ctx) error {
 	id := store.GenerateUUID()
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("INSERT INTO _workflows (id, name, trigger, context, steps, active) VALUES (%s, %s, %s, %s, %s, %s) RETURNING id",
-			pb.Add(id), pb.Add(wf.Name), pb.Add(triggerJSON), pb.Add(contextJSON), pb.Add(stepsJSON), pb.Add(wf.Active)),
+		fmt.Sprintf("INSERT INTO _workflows (id, name, trigger, context, steps, active, tags) VALUES (%s, %s, %s, %s, %s, %s, %s) RETURNING id",
+			pb.Add(id), pb.Add(wf.Name), pb.Add(triggerJSON), pb.Add(contextJSON), pb.Add(stepsJSON), pb.Add(wf.Active), pb.Add(h.store.Dialect.ArrayParam(wf.Tags))),
 		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("insert workflow: %w", err)
@@ -716,6 +1106,7 @@ func (h *Handler) UpdateWorkflow(c *fiber.Ctx) error {
 	if err := validateWorkflow(&wf, h.registry); err != nil {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": err.Error()}})
 	}
+	wf.Tags = normalizeTags(wf.Tags)
 
 	triggerJSON, err := json.Marshal(wf.Trigger)
 	if err != nil {
@@ -732,8 +1123,9 @@ func (h *Handler) UpdateWorkflow(c *fiber.Ctx) error {
 
 	pb2 := h.store.Dialect.NewParamBuilder()
 	_, err = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("UPDATE _workflows SET name = %s, trigger = %s, context = %s, steps = %s, active = %s, updated_at = %s WHERE id = %s",
-			pb2.Add(wf.Name), pb2.Add(triggerJSON), pb2.Add(contextJSON), pb2.Add(stepsJSON), pb2.Add(wf.Active), h.store.Dialect.NowExpr(), pb2.Add(id)),
+		fmt.Sprintf("UPDATE _workflows SET name = %s, trigger = %s, context = %s, steps = %s, active = %s, tags = %s, updated_at = %s WHERE id = %s",
+			pb2.Add(wf.Name), pb2.Add(triggerJSON), pb2.Add(contextJSON), pb2.Add(stepsJSON), pb2.Add(wf.Active), pb2.Add(h.store.Dialect.ArrayParam(wf.Tags)),
+			h.store.Dialect.NowExpr(), pb2.Add(id)),
 		pb2.Params()...)
 	if err != nil {
 		return fmt.Errorf("update workflow: %w", err)
@@ -777,6 +1169,21 @@ func validateEntity(e *metadata.Entity) error {
 	if e.Name == "" {
 		return fmt.Errorf("entity name is required")
 	}
+	if e.CacheTTL < 0 {
+		return fmt.Errorf("cache_ttl must not be negative")
+	}
+	if e.CacheTTL > 0 && !e.Cacheable {
+		return fmt.Errorf("cache_ttl requires cacheable")
+	}
+	if e.ReadOnly && e.AppendOnly {
+		return fmt.Errorf("readonly and append_only are mutually exclusive")
+	}
+	if e.RuleOrder != "" && e.RuleOrder != "phased" && e.RuleOrder != "priority" {
+		return fmt.Errorf("rule_order must be \"phased\" or \"priority\"")
+	}
+	if e.Locking != "" && e.Locking != "none" && e.Locking != "pessimistic" {
+		return fmt.Errorf("locking must be \"none\" or \"pessimistic\"")
+	}
 	if e.Table == "" {
 		return fmt.Errorf("table name is required")
 	}
@@ -789,6 +1196,160 @@ func validateEntity(e *metadata.Entity) error {
 	if !e.HasField(e.PrimaryKey.Field) {
 		return fmt.Errorf("primary key field %s not found in fields", e.PrimaryKey.Field)
 	}
+	if e.IDStrategy != "" {
+		if !e.PrimaryKey.Generated {
+			return fmt.Errorf("id_strategy requires a generated primary key")
+		}
+		pkType := e.GetField(e.PrimaryKey.Field).Type
+		var want []string
+		switch e.IDStrategy {
+		case "uuid", "uuidv7":
+			want = []string{"uuid"}
+		case "nanoid":
+			want = []string{"string", "text"}
+		case "sequence":
+			want = []string{"int", "integer", "bigint"}
+		default:
+			return fmt.Errorf("id_strategy must be uuid, uuidv7, nanoid or sequence")
+		}
+		if !slices.Contains(want, pkType) {
+			return fmt.Errorf("id_strategy %s needs a %s primary key field, not %s", e.IDStrategy, strings.Join(want, " or "), pkType)
+		}
+	}
+
+	if e.Timestamps {
+		for _, name := range []string{"created_at", "updated_at"} {
+			if f := e.GetField(name); f != nil && f.Type != "timestamp" {
+				return fmt.Errorf("field %q: must be a timestamp when timestamps is enabled", name)
+			}
+		}
+	}
+	if e.Attribution {
+		for _, name := range []string{"created_by", "updated_by"} {
+			f := e.GetField(name)
+			if f == nil {
+				continue
+			}
+			if f.Type != "string" && f.Type != "text" && f.Type != "uuid" {
+				return fmt.Errorf("field %q: must be a string, text or uuid field when attribution is enabled", name)
+			}
+			if f.Required {
+				return fmt.Errorf("field %q: can't be required when attribution is enabled; system and anonymous writes leave it null", name)
+			}
+		}
+	}
+
+	if e.WriteLimit != nil && (e.WriteLimit.Max < 1 || e.WriteLimit.WindowSeconds < 1) {
+		return fmt.Errorf("write_limit needs max and window_seconds of at least 1")
+	}
+	if e.Scope != "" {
+		if err := engine.ValidateScope(e.Scope); err != nil {
+			return fmt.Errorf("invalid scope: %v", err)
+		}
+	} else if e.ScopeBypass {
+		return fmt.Errorf("scope_admin_bypass requires scope")
+	}
+	if e.Protected != nil {
+		for _, name := range append(append([]string{}, e.Protected.Create...), e.Protected.Update...) {
+			if !e.HasField(name) {
+				return fmt.Errorf("protected_fields: unknown field %q", name)
+			}
+		}
+	}
+	if e.Search != "" && e.Search != "like" && e.Search != "fulltext" {
+		return fmt.Errorf("search must be like or fulltext")
+	}
+	for _, name := range e.SearchableFields {
+		if f := e.GetField(name); f == nil || (f.Type != "string" && f.Type != "text") {
+			return fmt.Errorf("searchable_fields: %q is not a string or text field", name)
+		}
+	}
+	if e.DefaultSort != "" {
+		for _, part := range strings.Split(e.DefaultSort, ",") {
+			name := strings.TrimPrefix(strings.TrimSpace(part), "-")
+			stamped := e.Timestamps && (name == "created_at" || name == "updated_at") ||
+				e.Attribution && (name == "created_by" || name == "updated_by")
+			if !e.HasField(name) && !stamped {
+				return fmt.Errorf("default_sort: unknown field %q", name)
+			}
+		}
+	}
+
+	for _, f := range e.Fields {
+		if f.CaseInsensitive && f.Type != "string" && f.Type != "text" {
+			return fmt.Errorf("field %q: case_insensitive is only supported on string or text fields", f.Name)
+		}
+		for _, t := range f.Transform {
+			if !metadata.ValidTransforms[t] {
+				return fmt.Errorf("field %q: unknown transform %q (must be trim, lower, upper, or normalize_email)", f.Name, t)
+			}
+			if f.Type != "string" && f.Type != "text" {
+				return fmt.Errorf("field %q: transforms are only supported on string or text fields", f.Name)
+			}
+		}
+		switch f.EmptyStrings {
+		case "", "keep", "null":
+		case "empty":
+			if f.Type != "string" && f.Type != "text" {
+				return fmt.Errorf("field %q: empty_strings \"empty\" is only supported on string or text fields", f.Name)
+			}
+		default:
+			return fmt.Errorf("field %q: empty_strings must be keep, null or empty", f.Name)
+		}
+		if f.Schema != nil {
+			if f.Type != "json" {
+				return fmt.Errorf("field %q: schema is only supported on json fields", f.Name)
+			}
+			if err := f.Schema.Compile(); err != nil {
+				return fmt.Errorf("field %q: invalid schema: %v", f.Name, err)
+			}
+		}
+		if f.Items != "" && f.Type != "array" {
+			return fmt.Errorf("field %q: items is only supported on array fields", f.Name)
+		}
+		if f.Type == "array" {
+			if !metadata.ValidArrayItems[f.ItemType()] {
+				return fmt.Errorf("field %q: unknown items type %q (must be string, int, float, boolean, or uuid)", f.Name, f.Items)
+			}
+			if f.Unique {
+				return fmt.Errorf("field %q: unique is not supported on array fields", f.Name)
+			}
+		}
+		if len(f.UniqueWhere) > 0 {
+			if err := validateUniqueWhere(e, f); err != nil {
+				return err
+			}
+		}
+		if f.Backfill != nil {
+			if !f.Required || f.Nullable {
+				return fmt.Errorf("field %q: backfill is only used when the field is required", f.Name)
+			}
+			switch f.Backfill.(type) {
+			case string, float64, bool:
+			default:
+				return fmt.Errorf("field %q: backfill must be a string, number, or boolean", f.Name)
+			}
+		}
+		if f.RenamedFrom != "" && f.RenamedFrom != f.Name && e.HasField(f.RenamedFrom) {
+			return fmt.Errorf("field %q: renamed_from %q is still used by another field", f.Name, f.RenamedFrom)
+		}
+		if f.Rollup != nil && f.Type != "rollup" {
+			return fmt.Errorf("field %q: rollup config is only supported on rollup fields", f.Name)
+		}
+		if f.Type == "rollup" {
+			if err := validateRollup(f); err != nil {
+				return err
+			}
+		}
+		if f.File != nil {
+			if f.Type != "file" {
+				return fmt.Errorf("field %q: file config is only supported on file fields", f.Name)
+			}
+			if f.File.MaxSize < 0 {
+				return fmt.Errorf("field %q: file.max_size must not be negative", f.Name)
+			}
+		}
+	}
 
 	// Validate slug config if present
 	if e.Slug != nil {
@@ -810,6 +1371,119 @@ func validateEntity(e *metadata.Entity) error {
 	return nil
 }
 
+// validateRollup checks a rollup field's config. The relation is resolved
+// when the rollup is computed, since it is usually created after the entity.
+func validateRollup(f metadata.Field) error {
+	if f.Rollup == nil || f.Rollup.Relation == "" {
+		return fmt.Errorf("field %q: rollup fields need rollup.relation", f.Name)
+	}
+	if !metadata.ValidRollupAggregates[f.Rollup.Aggregate] {
+		return fmt.Errorf("field %q: unknown rollup aggregate %q (must be count, sum, avg, min, or max)", f.Name, f.Rollup.Aggregate)
+	}
+	if f.Rollup.Aggregate != "count" && f.Rollup.Field == "" {
+		return fmt.Errorf("field %q: rollup.field is required for %s", f.Name, f.Rollup.Aggregate)
+	}
+	if f.Required || f.Unique || f.Default != nil || f.IsAuto() {
+		return fmt.Errorf("field %q: rollup fields are maintained by the engine and can't be required, unique, defaulted, or auto", f.Name)
+	}
+	return nil
+}
+
+// validateRollupField checks that the field a rollup aggregates exists on the
+// relation's target and, for aggregates other than count, is numeric: the
+// result is stored in a numeric column.
+func validateRollupField(f metadata.Field, target *metadata.Entity) error {
+	if f.Rollup.Aggregate == "count" {
+		return nil
+	}
+	tf := target.GetField(f.Rollup.Field)
+	if tf == nil {
+		return fmt.Errorf("field %q: rollup.field %q not found on %s", f.Name, f.Rollup.Field, target.Name)
+	}
+	switch tf.StorageType() {
+	case "int", "integer", "bigint", "float", "decimal":
+		return nil
+	}
+	return fmt.Errorf("field %q: rollup.field %q is %s, %s needs a numeric field", f.Name, f.Rollup.Field, tf.Type, f.Rollup.Aggregate)
+}
+
+// validateRollupTargets checks the rollups e takes part in against reg's
+// relations: e's own rollups over relations that already exist, and other
+// entities' rollups that aggregate a field of e.
+func validateRollupTargets(e *metadata.Entity, reg *metadata.Registry) error {
+	for _, f := range e.Fields {
+		if f.Rollup == nil {
+			continue
+		}
+		rel := reg.GetRelation(f.Rollup.Relation)
+		if rel == nil || rel.IsManyToMany() || rel.Source != e.Name {
+			continue
+		}
+		target := reg.GetEntity(rel.Target)
+		if rel.Target == e.Name {
+			target = e
+		}
+		if target == nil {
+			continue
+		}
+		if err := validateRollupField(f, target); err != nil {
+			return err
+		}
+	}
+	for _, rel := range reg.AllRelations() {
+		if rel.Target != e.Name || rel.Source == e.Name || rel.IsManyToMany() {
+			continue
+		}
+		source := reg.GetEntity(rel.Source)
+		if source == nil {
+			continue
+		}
+		for _, f := range source.Fields {
+			if f.Rollup == nil || f.Rollup.Relation != rel.Name {
+				continue
+			}
+			if err := validateRollupField(f, e); err != nil {
+				return fmt.Errorf("rollup on %s: %w", rel.Source, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateUniqueWhere checks a field's unique_where. Its values are inlined
+// into the index predicate, so only scalar comparisons on plain columns are allowed.
+func validateUniqueWhere(e *metadata.Entity, f metadata.Field) error {
+	if !f.Unique {
+		return fmt.Errorf("field %q: unique_where requires unique", f.Name)
+	}
+	for _, c := range f.UniqueConditions() {
+		if c.Op != "eq" && c.Op != "neq" {
+			return fmt.Errorf("field %q: unique_where operator %q must be eq or neq", f.Name, c.Op)
+		}
+		if c.Field == "deleted_at" && e.SoftDelete && e.GetField("deleted_at") == nil {
+			// {"deleted_at": null} lets soft-deleted rows free up their value
+			if c.Value != nil {
+				return fmt.Errorf("field %q: unique_where can only compare deleted_at with null", f.Name)
+			}
+			continue
+		}
+		cf := e.GetField(c.Field)
+		if cf == nil {
+			return fmt.Errorf("field %q: unique_where references unknown field %q", f.Name, c.Field)
+		}
+		switch cf.Type {
+		case "json", "file", "array", "geopoint":
+			return fmt.Errorf("field %q: unique_where can't compare %s field %q", f.Name, cf.Type, c.Field)
+		}
+		switch c.Value.(type) {
+		case nil, string, float64, bool:
+		default:
+			return fmt.Errorf("field %q: unique_where value for %q must be a string, number, boolean, or null", f.Name, c.Field)
+		}
+	}
+	return nil
+}
+
 func validateRule(r *metadata.Rule, reg *metadata.Registry) error {
 	if r.Entity == "" {
 		return fmt.Errorf("entity is required")
@@ -817,12 +1491,24 @@ func validateRule(r *metadata.Rule, reg *metadata.Registry) error {
 	if reg.GetEntity(r.Entity) == nil {
 		return fmt.Errorf("entity not found: %s", r.Entity)
 	}
-	if r.Hook != "before_write" && r.Hook != "before_delete" {
-		return fmt.Errorf("invalid hook: %s (must be before_write or before_delete)", r.Hook)
+	switch r.Hook {
+	case "before_write", "before_create", "before_update", "before_delete", "after_write", "after_delete":
+	default:
+		return fmt.Errorf("invalid hook: %s (must be before_write, before_create, before_update, before_delete, after_write or after_delete)", r.Hook)
 	}
 	if r.Type != "field" && r.Type != "expression" && r.Type != "computed" {
 		return fmt.Errorf("invalid rule type: %s (must be field, expression, or computed)", r.Type)
 	}
+	// After-hook rules run once the write has committed, so there is nothing
+	// left for a validation to reject; they can only compute and store values
+	if r.Hook == "after_write" || r.Hook == "after_delete" {
+		if r.Type != "computed" {
+			return fmt.Errorf("%s rules must be computed", r.Hook)
+		}
+		if _, err := engine.ResolveAfterRuleTarget(reg, reg.GetEntity(r.Entity), r.Hook, r.Definition.Field); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -866,8 +1552,29 @@ func validateWorkflow(wf *metadata.Workflow, reg *metadata.Registry) error {
 			return fmt.Errorf("duplicate step id: %s", s.ID)
 		}
 		stepIDs[s.ID] = true
-		if s.Type != "action" && s.Type != "condition" && s.Type != "approval" {
-			return fmt.Errorf("invalid step type: %s (must be action, condition, or approval)", s.Type)
+		if s.Type != "action" && s.Type != "condition" && s.Type != "approval" && s.Type != "callback" {
+			return fmt.Errorf("invalid step type: %s (must be action, condition, approval, or callback)", s.Type)
+		}
+		if s.Type == "callback" && s.URL == "" {
+			return fmt.Errorf("callback step %s requires a url", s.ID)
+		}
+		if len(s.Escalations) > 0 {
+			if s.Type != "approval" {
+				return fmt.Errorf("step %s: escalations are only supported on approval steps", s.ID)
+			}
+			if d, err := time.ParseDuration(s.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("step %s: escalations require a timeout", s.ID)
+			}
+			prev := 0.0
+			for _, esc := range s.Escalations {
+				if esc.At <= prev || esc.At > 1 {
+					return fmt.Errorf("step %s: escalation at must be ascending and within (0, 1]", s.ID)
+				}
+				prev = esc.At
+				if len(esc.Notify) == 0 && esc.Reassign == nil {
+					return fmt.Errorf("step %s: escalation at %g needs notify actions or a reassign", s.ID, esc.At)
+				}
+			}
 		}
 	}
 
@@ -903,16 +1610,34 @@ func validateWorkflow(wf *metadata.Workflow, reg *metadata.Registry) error {
 		if err := validTarget(s.OnTimeout); err != nil {
 			return err
 		}
-	}
-
+		if err := validTarget(s.OnSuccess); err != nil {
+			return err
+		}
+		if err := validTarget(s.OnFailure); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // --- User Endpoints ---
 
+// ListUsers handles GET /_admin/users. ?inactive_since=<date> keeps only users
+// who haven't logged in since then, including those who never have.
 func (h *Handler) ListUsers(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
+	where := ""
+	if v := c.Query("inactive_since"); v != "" {
+		cutoff, err := parseCutoff(v)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "inactive_since must be a date (2006-01-02) or RFC 3339 timestamp"}})
+		}
+		where = fmt.Sprintf(" WHERE last_login_at IS NULL OR last_login_at < %s", pb.Add(h.store.Dialect.TimeParam(cutoff)))
+	}
 	rows, err := store.QueryRows(c.Context(), h.store.DB,
-		"SELECT id, email, roles, active, created_at, updated_at FROM _users ORDER BY email")
+		"SELECT id, email, roles, active, password_reset_required, attributes, last_login_at, created_at, updated_at FROM _users"+where+" ORDER BY email",
+		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("list users: %w", err)
 	}
@@ -920,48 +1645,65 @@ func (h *Handler) ListUsers(c *fiber.Ctx) error {
 		rows = []map[string]any{}
 	}
 	if h.store.Dialect.NeedsBoolFix() {
-		store.NormalizeBooleans(rows, []string{"active"})
+		store.NormalizeBooleans(rows, []string{"active", "password_reset_required"})
 	}
 	// Normalize roles from TEXT[]/JSON text to []string
 	for _, row := range rows {
 		row["roles"] = metadata.ParseStringArray(row["roles"])
+		row["attributes"] = decodeSettingValue(row["attributes"])
 	}
 	return c.JSON(fiber.Map{"data": rows})
 }
 
+// parseCutoff accepts a date or an RFC 3339 timestamp.
+func parseCutoff(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
 func (h *Handler) GetUser(c *fiber.Ctx) error {
 	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, email, roles, active, created_at, updated_at FROM _users WHERE id = %s", pb.Add(id)),
+		fmt.Sprintf("SELECT id, email, roles, active, password_reset_required, attributes, last_login_at, created_at, updated_at FROM _users WHERE id = %s", pb.Add(id)),
 		pb.Params()...)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "User not found: " + id}})
 	}
 	if h.store.Dialect.NeedsBoolFix() {
-		store.NormalizeBooleans([]map[string]any{row}, []string{"active"})
+		store.NormalizeBooleans([]map[string]any{row}, []string{"active", "password_reset_required"})
 	}
 	row["roles"] = metadata.ParseStringArray(row["roles"])
+	row["attributes"] = decodeSettingValue(row["attributes"])
 	return c.JSON(fiber.Map{"data": row})
 }
 
 func (h *Handler) CreateUser(c *fiber.Ctx) error {
 	var body struct {
-		Email    string   `json:"email"`
-		Password string   `json:"password"`
-		Roles    []string `json:"roles"`
-		Active   *bool    `json:"active"`
+		Email      string         `json:"email"`
+		Password   string         `json:"password"`
+		Roles      []string       `json:"roles"`
+		Active     *bool          `json:"active"`
+		Attributes map[string]any `json:"attributes"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
 	}
 
+	body.Email = store.NormalizeEmail(body.Email)
 	if body.Email == "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "email is required"}})
 	}
 	if body.Password == "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "password is required"}})
 	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, body.Roles); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
 
 	hash, err := auth.HashPassword(body.Password)
 	if err != nil {
@@ -976,16 +1718,28 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 		body.Roles = []string{}
 	}
 
+	if body.Attributes == nil {
+		body.Attributes = map[string]any{}
+	}
+	attrsJSON, err := json.Marshal(body.Attributes)
+	if err != nil {
+		return fmt.Errorf("marshal attributes: %w", err)
+	}
+
 	id := store.GenerateUUID()
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("INSERT INTO _users (id, email, password_hash, roles, active) VALUES (%s, %s, %s, %s, %s) RETURNING id, email, roles, active, created_at, updated_at",
-			pb.Add(id), pb.Add(body.Email), pb.Add(hash), pb.Add(h.store.Dialect.ArrayParam(body.Roles)), pb.Add(active)),
+		fmt.Sprintf("INSERT INTO _users (id, email, password_hash, roles, active, attributes) VALUES (%s, %s, %s, %s, %s, %s) RETURNING id, email, roles, active, attributes, created_at, updated_at",
+			pb.Add(id), pb.Add(body.Email), pb.Add(hash), pb.Add(h.store.Dialect.ArrayParam(body.Roles)), pb.Add(active), pb.Add(string(attrsJSON))),
 		pb.Params()...)
 	if err != nil {
+		if errors.Is(store.MapError(h.store.Dialect, err), store.ErrUniqueViolation) {
+			return c.Status(409).JSON(fiber.Map{"error": fiber.Map{"code": "CONFLICT", "message": "A user with this email already exists"}})
+		}
 		return fmt.Errorf("insert user: %w", err)
 	}
 	row["roles"] = metadata.ParseStringArray(row["roles"])
+	row["attributes"] = decodeSettingValue(row["attributes"])
 
 	return c.Status(201).JSON(fiber.Map{"data": row})
 }
@@ -1001,18 +1755,38 @@ func (h *Handler) UpdateUser(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Email    string   `json:"email"`
-		Password string   `json:"password"`
-		Roles    []string `json:"roles"`
-		Active   *bool    `json:"active"`
+		Email      string         `json:"email"`
+		Password   string         `json:"password"`
+		Roles      []string       `json:"roles"`
+		Active     *bool          `json:"active"`
+		Attributes map[string]any `json:"attributes"` // omitted keeps the current attributes
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
 	}
 
+	body.Email = store.NormalizeEmail(body.Email)
 	if body.Email == "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "email is required"}})
 	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, body.Roles); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
+
+	if body.Attributes != nil {
+		attrsJSON, err := json.Marshal(body.Attributes)
+		if err != nil {
+			return fmt.Errorf("marshal attributes: %w", err)
+		}
+		pb2 := h.store.Dialect.NewParamBuilder()
+		if _, err := store.Exec(c.Context(), h.store.DB,
+			fmt.Sprintf("UPDATE _users SET attributes = %s WHERE id = %s", pb2.Add(string(attrsJSON)), pb2.Add(id)),
+			pb2.Params()...); err != nil {
+			return fmt.Errorf("update user attributes: %w", err)
+		}
+	}
 
 	if body.Roles == nil {
 		body.Roles = []string{}
@@ -1026,10 +1800,13 @@ func (h *Handler) UpdateUser(c *fiber.Ctx) error {
 		}
 		pb2 := h.store.Dialect.NewParamBuilder()
 		_, err = store.Exec(c.Context(), h.store.DB,
-			fmt.Sprintf("UPDATE _users SET email = %s, password_hash = %s, roles = %s, active = %s, updated_at = %s WHERE id = %s",
+			fmt.Sprintf("UPDATE _users SET email = %s, password_hash = %s, password_reset_required = false, roles = %s, active = %s, updated_at = %s WHERE id = %s",
 				pb2.Add(body.Email), pb2.Add(hash), pb2.Add(h.store.Dialect.ArrayParam(body.Roles)), pb2.Add(body.Active), h.store.Dialect.NowExpr(), pb2.Add(id)),
 			pb2.Params()...)
 		if err != nil {
+			if errors.Is(store.MapError(h.store.Dialect, err), store.ErrUniqueViolation) {
+				return c.Status(409).JSON(fiber.Map{"error": fiber.Map{"code": "CONFLICT", "message": "A user with this email already exists"}})
+			}
 			return fmt.Errorf("update user: %w", err)
 		}
 	} else {
@@ -1039,13 +1816,16 @@ func (h *Handler) UpdateUser(c *fiber.Ctx) error {
 				pb2.Add(body.Email), pb2.Add(h.store.Dialect.ArrayParam(body.Roles)), pb2.Add(body.Active), h.store.Dialect.NowExpr(), pb2.Add(id)),
 			pb2.Params()...)
 		if err != nil {
+			if errors.Is(store.MapError(h.store.Dialect, err), store.ErrUniqueViolation) {
+				return c.Status(409).JSON(fiber.Map{"error": fiber.Map{"code": "CONFLICT", "message": "A user with this email already exists"}})
+			}
 			return fmt.Errorf("update user: %w", err)
 		}
 	}
 
 	pb3 := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, email, roles, active, created_at, updated_at FROM _users WHERE id = %s", pb3.Add(id)),
+		fmt.Sprintf("SELECT id, email, roles, active, attributes, created_at, updated_at FROM _users WHERE id = %s", pb3.Add(id)),
 		pb3.Params()...)
 	if err != nil {
 		return fmt.Errorf("fetch updated user: %w", err)
@@ -1054,6 +1834,7 @@ func (h *Handler) UpdateUser(c *fiber.Ctx) error {
 		store.NormalizeBooleans([]map[string]any{row}, []string{"active"})
 	}
 	row["roles"] = metadata.ParseStringArray(row["roles"])
+	row["attributes"] = decodeSettingValue(row["attributes"])
 
 	return c.JSON(fiber.Map{"data": row})
 }
@@ -1090,17 +1871,23 @@ func (h *Handler) CreateInvite(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
 	}
 
+	body.Email = store.NormalizeEmail(body.Email)
 	if body.Email == "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "email is required"}})
 	}
 	if body.Roles == nil {
 		body.Roles = []string{}
 	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, body.Roles); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
 
 	// Check email not already a user
 	pb := h.store.Dialect.NewParamBuilder()
 	_, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id FROM _users WHERE email = %s", pb.Add(body.Email)),
+		fmt.Sprintf("SELECT id FROM _users WHERE LOWER(email) = LOWER(%s)", pb.Add(body.Email)),
 		pb.Params()...)
 	if err == nil {
 		return c.Status(409).JSON(fiber.Map{"error": fiber.Map{"code": "CONFLICT", "message": "A user with this email already exists"}})
@@ -1109,7 +1896,7 @@ func (h *Handler) CreateInvite(c *fiber.Ctx) error {
 	// Check no pending invite for this email
 	pb2 := h.store.Dialect.NewParamBuilder()
 	_, err = store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id FROM _invites WHERE email = %s AND accepted_at IS NULL AND expires_at > %s",
+		fmt.Sprintf("SELECT id FROM _invites WHERE LOWER(email) = LOWER(%s) AND accepted_at IS NULL AND expires_at > %s",
 			pb2.Add(body.Email), h.store.Dialect.NowExpr()),
 		pb2.Params()...)
 	if err == nil {
@@ -1189,17 +1976,22 @@ func (h *Handler) BulkCreateInvites(c *fiber.Ctx) error {
 	if body.Roles == nil {
 		body.Roles = []string{}
 	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, body.Roles); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
 
-	// Trim, lowercase, deduplicate emails
+	// Normalize and deduplicate emails, ignoring case
 	seen := map[string]bool{}
 	var emails []string
 	for _, e := range body.Emails {
-		e = strings.TrimSpace(strings.ToLower(e))
+		e = store.NormalizeEmail(e)
 		if e == "" {
 			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "emails must not contain blank entries"}})
 		}
-		if !seen[e] {
-			seen[e] = true
+		if key := strings.ToLower(e); !seen[key] {
+			seen[key] = true
 			emails = append(emails, e)
 		}
 	}
@@ -1223,14 +2015,16 @@ func (h *Handler) BulkCreateInvites(c *fiber.Ctx) error {
 		Reason string `json:"reason"`
 	}
 
+	// Emails that already have a user or a pending invite are skipped: there
+	// is nothing to do for them. Only inserts that fail count as failures.
 	var created []createdItem
-	var skipped []skippedItem
+	var skipped, failed []skippedItem
 
 	for _, email := range emails {
 		// Check email not already a user
 		pb := h.store.Dialect.NewParamBuilder()
 		_, err := store.QueryRow(c.Context(), h.store.DB,
-			fmt.Sprintf("SELECT id FROM _users WHERE email = %s", pb.Add(email)),
+			fmt.Sprintf("SELECT id FROM _users WHERE LOWER(email) = LOWER(%s)", pb.Add(email)),
 			pb.Params()...)
 		if err == nil {
 			skipped = append(skipped, skippedItem{Email: email, Reason: "A user with this email already exists"})
@@ -1240,7 +2034,7 @@ func (h *Handler) BulkCreateInvites(c *fiber.Ctx) error {
 		// Check no pending invite
 		pb2 := h.store.Dialect.NewParamBuilder()
 		_, err = store.QueryRow(c.Context(), h.store.DB,
-			fmt.Sprintf("SELECT id FROM _invites WHERE email = %s AND accepted_at IS NULL AND expires_at > %s",
+			fmt.Sprintf("SELECT id FROM _invites WHERE LOWER(email) = LOWER(%s) AND accepted_at IS NULL AND expires_at > %s",
 				pb2.Add(email), h.store.Dialect.NowExpr()),
 			pb2.Params()...)
 		if err == nil {
@@ -1256,7 +2050,7 @@ func (h *Handler) BulkCreateInvites(c *fiber.Ctx) error {
 				pb3.Add(id), pb3.Add(email), pb3.Add(rolesParam), pb3.Add(token), pb3.Add(expiresAt), pb3.Add(invitedBy)),
 			pb3.Params()...)
 		if err != nil {
-			skipped = append(skipped, skippedItem{Email: email, Reason: fmt.Sprintf("Insert failed: %v", err)})
+			failed = append(failed, skippedItem{Email: email, Reason: fmt.Sprintf("Insert failed: %v", err)})
 			continue
 		}
 
@@ -1274,25 +2068,44 @@ func (h *Handler) BulkCreateInvites(c *fiber.Ctx) error {
 	if skipped == nil {
 		skipped = []skippedItem{}
 	}
+	if failed == nil {
+		failed = []skippedItem{}
+	}
 
-	return c.JSON(fiber.Map{
+	return c.Status(bulkStatus(len(created), len(failed))).JSON(fiber.Map{
 		"data": fiber.Map{
 			"created": created,
 			"skipped": skipped,
+			"failed":  failed,
 			"summary": fiber.Map{
 				"total":   len(emails),
 				"created": len(created),
 				"skipped": len(skipped),
+				"failed":  len(failed),
 			},
 		},
 	})
 }
 
+// bulkStatus is the status of a bulk or import response: 200 when nothing
+// failed, 422 when nothing succeeded and something failed, and 207
+// Multi-Status for a mix.
+func bulkStatus(succeeded, failed int) int {
+	switch {
+	case failed == 0:
+		return fiber.StatusOK
+	case succeeded == 0:
+		return fiber.StatusUnprocessableEntity
+	default:
+		return fiber.StatusMultiStatus
+	}
+}
+
 // --- Permission Endpoints ---
 
 func (h *Handler) ListPermissions(c *fiber.Ctx) error {
 	rows, err := store.QueryRows(c.Context(), h.store.DB,
-		"SELECT id, entity, action, roles, conditions, created_at, updated_at FROM _permissions ORDER BY entity, action")
+		"SELECT id, entity, action, effect, roles, conditions, created_at, updated_at FROM _permissions ORDER BY entity, action")
 	if err != nil {
 		return fmt.Errorf("list permissions: %w", err)
 	}
@@ -1302,6 +2115,7 @@ func (h *Handler) ListPermissions(c *fiber.Ctx) error {
 	// Normalize roles from TEXT[]/JSON text to []string
 	for _, row := range rows {
 		row["roles"] = metadata.ParseStringArray(row["roles"])
+		row["attributes"] = decodeSettingValue(row["attributes"])
 	}
 	return c.JSON(fiber.Map{"data": rows})
 }
@@ -1310,7 +2124,7 @@ func (h *Handler) GetPermission(c *fiber.Ctx) error {
 	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, entity, action, roles, conditions, created_at, updated_at FROM _permissions WHERE id = %s", pb.Add(id)),
+		fmt.Sprintf("SELECT id, entity, action, effect, roles, conditions, created_at, updated_at FROM _permissions WHERE id = %s", pb.Add(id)),
 		pb.Params()...)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Permission not found: " + id}})
@@ -1335,9 +2149,20 @@ func (h *Handler) CreatePermission(c *fiber.Ctx) error {
 	if !validActions[perm.Action] {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "action must be read, create, update, or delete"}})
 	}
+	if errMsg := normalizePermissionEffect(&perm); errMsg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": errMsg}})
+	}
+	if errMsg := validatePermissionConditions(h.registry.GetEntity(perm.Entity), perm.Conditions); errMsg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": errMsg}})
+	}
 	if perm.Roles == nil {
 		perm.Roles = []string{}
 	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, perm.Roles); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
 
 	condJSON, err := json.Marshal(perm.Conditions)
 	if err != nil {
@@ -1347,8 +2172,8 @@ func (h *Handler) CreatePermission(c *fiber.Ctx) error {
 	id := store.GenerateUUID()
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("INSERT INTO _permissions (id, entity, action, roles, conditions) VALUES (%s, %s, %s, %s, %s) RETURNING id",
-			pb.Add(id), pb.Add(perm.Entity), pb.Add(perm.Action), pb.Add(h.store.Dialect.ArrayParam(perm.Roles)), pb.Add(condJSON)),
+		fmt.Sprintf("INSERT INTO _permissions (id, entity, action, effect, roles, conditions) VALUES (%s, %s, %s, %s, %s, %s) RETURNING id",
+			pb.Add(id), pb.Add(perm.Entity), pb.Add(perm.Action), pb.Add(perm.Effect), pb.Add(h.store.Dialect.ArrayParam(perm.Roles)), pb.Add(condJSON)),
 		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("insert permission: %w", err)
@@ -1384,9 +2209,20 @@ func (h *Handler) UpdatePermission(c *fiber.Ctx) error {
 	if perm.Action == "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "action is required"}})
 	}
+	if errMsg := normalizePermissionEffect(&perm); errMsg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": errMsg}})
+	}
+	if errMsg := validatePermissionConditions(h.registry.GetEntity(perm.Entity), perm.Conditions); errMsg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": errMsg}})
+	}
 	if perm.Roles == nil {
 		perm.Roles = []string{}
 	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, perm.Roles); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
 
 	condJSON, err := json.Marshal(perm.Conditions)
 	if err != nil {
@@ -1395,8 +2231,8 @@ func (h *Handler) UpdatePermission(c *fiber.Ctx) error {
 
 	pb2 := h.store.Dialect.NewParamBuilder()
 	_, err = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("UPDATE _permissions SET entity = %s, action = %s, roles = %s, conditions = %s, updated_at = %s WHERE id = %s",
-			pb2.Add(perm.Entity), pb2.Add(perm.Action), pb2.Add(h.store.Dialect.ArrayParam(perm.Roles)), pb2.Add(condJSON), h.store.Dialect.NowExpr(), pb2.Add(id)),
+		fmt.Sprintf("UPDATE _permissions SET entity = %s, action = %s, effect = %s, roles = %s, conditions = %s, updated_at = %s WHERE id = %s",
+			pb2.Add(perm.Entity), pb2.Add(perm.Action), pb2.Add(perm.Effect), pb2.Add(h.store.Dialect.ArrayParam(perm.Roles)), pb2.Add(condJSON), h.store.Dialect.NowExpr(), pb2.Add(id)),
 		pb2.Params()...)
 	if err != nil {
 		return fmt.Errorf("update permission: %w", err)
@@ -1434,11 +2270,187 @@ func (h *Handler) DeletePermission(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"data": fiber.Map{"id": id, "deleted": true}})
 }
 
+// BulkCreatePermissions handles POST /_admin/permissions/bulk with
+// {role, entities, actions, conditions}: one allow permission per
+// entity/action pair for the role. Pairs already granted to the role with
+// the same conditions are skipped.
+func (h *Handler) BulkCreatePermissions(c *fiber.Ctx) error {
+	var body struct {
+		Role       string                         `json:"role"`
+		Entities   []string                       `json:"entities"`
+		Actions    []string                       `json:"actions"`
+		Conditions []metadata.PermissionCondition `json:"conditions"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
+	}
+
+	body.Role = strings.TrimSpace(body.Role)
+	if body.Role == "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "role is required"}})
+	}
+	if msg, err := h.unknownRole(c.Context(), h.store.DB, []string{body.Role}); err != nil {
+		return err
+	} else if msg != "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": msg}})
+	}
+	if len(body.Entities) == 0 || len(body.Actions) == 0 {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "entities and actions are required and must be non-empty arrays"}})
+	}
+	for _, name := range body.Entities {
+		if h.registry.GetEntity(name) == nil {
+			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "Unknown entity: " + name}})
+		}
+	}
+	validActions := map[string]bool{"read": true, "create": true, "update": true, "delete": true}
+	for _, action := range body.Actions {
+		if !validActions[action] {
+			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "actions must be read, create, update, or delete"}})
+		}
+	}
+	for _, name := range body.Entities {
+		if errMsg := validatePermissionConditions(h.registry.GetEntity(name), body.Conditions); errMsg != "" {
+			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": name + ": " + errMsg}})
+		}
+	}
+
+	condJSON, err := json.Marshal(body.Conditions)
+	if err != nil {
+		return fmt.Errorf("marshal conditions: %w", err)
+	}
+	rolesParam := h.store.Dialect.ArrayParam([]string{body.Role})
+
+	type createdItem struct {
+		ID     string `json:"id"`
+		Entity string `json:"entity"`
+		Action string `json:"action"`
+	}
+	type skippedItem struct {
+		Entity string `json:"entity"`
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	created := []createdItem{}
+	skipped := []skippedItem{}
+	total := 0
+
+	seen := map[string]bool{}
+	err = h.store.Tx(c.Context(), func(tx store.Querier) error {
+		for _, entity := range body.Entities {
+			for _, action := range body.Actions {
+				key := entity + ":" + action
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				total++
+
+				if h.roleHasPermission(entity, action, body.Role, body.Conditions) {
+					skipped = append(skipped, skippedItem{Entity: entity, Action: action, Reason: "The role already has this permission"})
+					continue
+				}
+
+				id := store.GenerateUUID()
+				pb := h.store.Dialect.NewParamBuilder()
+				if _, err := store.Exec(c.Context(), tx,
+					fmt.Sprintf("INSERT INTO _permissions (id, entity, action, effect, roles, conditions) VALUES (%s, %s, %s, 'allow', %s, %s)",
+						pb.Add(id), pb.Add(entity), pb.Add(action), pb.Add(rolesParam), pb.Add(condJSON)),
+					pb.Params()...); err != nil {
+					return fmt.Errorf("insert permission %s/%s: %w", entity, action, err)
+				}
+				created = append(created, createdItem{ID: id, Entity: entity, Action: action})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(created) > 0 {
+		if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
+			return fmt.Errorf("reload registry: %w", err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"created": created,
+			"skipped": skipped,
+			"summary": fiber.Map{
+				"total":   total,
+				"created": len(created),
+				"skipped": len(skipped),
+			},
+		},
+	})
+}
+
+// roleHasPermission reports whether an allow permission on entity/action
+// already grants role under the same conditions.
+func (h *Handler) roleHasPermission(entity, action, role string, conditions []metadata.PermissionCondition) bool {
+	want, _ := json.Marshal(conditions)
+	for _, p := range h.registry.GetPermissions(entity, action) {
+		if p.IsDeny() {
+			continue
+		}
+		for _, r := range p.Roles {
+			if r != role {
+				continue
+			}
+			if len(p.Conditions) == 0 && len(conditions) == 0 {
+				return true
+			}
+			if got, _ := json.Marshal(p.Conditions); string(got) == string(want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizePermissionEffect defaults an empty effect to "allow" and rejects unknown values.
+func normalizePermissionEffect(perm *metadata.Permission) string {
+	if perm.Effect == "" {
+		perm.Effect = "allow"
+	}
+	if perm.Effect != "allow" && perm.Effect != "deny" {
+		return "effect must be allow or deny"
+	}
+	return ""
+}
+
+// validatePermissionConditions requires each condition to name a field or carry
+// an expression, and checks that expressions compile and, when entity is
+// known, translate to SQL (see engine.ValidatePermissionExpression).
+func validatePermissionConditions(entity *metadata.Entity, conditions []metadata.PermissionCondition) string {
+	for i, cond := range conditions {
+		if cond.Expression == "" {
+			if cond.Field == "" {
+				return fmt.Sprintf("conditions[%d]: field or expression is required", i)
+			}
+			continue
+		}
+		var err error
+		if entity != nil {
+			err = engine.ValidatePermissionExpression(cond.Expression, entity)
+		} else {
+			_, err = engine.CompileExpression(cond.Expression)
+		}
+		if err != nil {
+			return fmt.Sprintf("conditions[%d]: invalid expression: %v", i, err)
+		}
+	}
+	return ""
+}
+
 // --- Webhook Endpoints ---
 
 func (h *Handler) ListWebhooks(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
 	rows, err := store.QueryRows(c.Context(), h.store.DB,
-		"SELECT id, entity, hook, url, method, headers, condition, async, retry, active, created_at, updated_at FROM _webhooks ORDER BY entity, hook")
+		"SELECT id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret, created_at, updated_at FROM _webhooks"+h.tagFilter(c, pb)+" ORDER BY entity, hook",
+		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("list webhooks: %w", err)
 	}
@@ -1448,6 +2460,8 @@ func (h *Handler) ListWebhooks(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans(rows, []string{"active", "async"})
 	}
+	parseTags(rows...)
+	maskWebhookSecrets(rows...)
 	return c.JSON(fiber.Map{"data": rows})
 }
 
@@ -1455,7 +2469,7 @@ func (h *Handler) GetWebhook(c *fiber.Ctx) error {
 	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, entity, hook, url, method, headers, condition, async, retry, active, created_at, updated_at FROM _webhooks WHERE id = %s", pb.Add(id)),
+		fmt.Sprintf("SELECT id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret, created_at, updated_at FROM _webhooks WHERE id = %s", pb.Add(id)),
 		pb.Params()...)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Webhook not found: " + id}})
@@ -1463,6 +2477,8 @@ func (h *Handler) GetWebhook(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans([]map[string]any{row}, []string{"active", "async"})
 	}
+	parseTags(row)
+	maskWebhookSecrets(row)
 	return c.JSON(fiber.Map{"data": row})
 }
 
@@ -1475,6 +2491,10 @@ func (h *Handler) CreateWebhook(c *fiber.Ctx) error {
 	if errMsg := validateWebhook(body); errMsg != "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": errMsg}})
 	}
+	if err := engine.WebhookTargets.CheckURL(c.Context(), body["url"].(string), true); err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "url: " + err.Error()}})
+	}
+	tags := normalizeTags(metadata.ParseStringArray(body["tags"]))
 
 	// Defaults
 	if body["hook"] == nil {
@@ -1498,18 +2518,28 @@ func (h *Handler) CreateWebhook(c *fiber.Ctx) error {
 	if body["retry"] == nil {
 		body["retry"] = map[string]any{"max_attempts": 3, "backoff": "exponential"}
 	}
+	if body["transport"] == nil {
+		body["transport"] = "http"
+	}
 
 	headersJSON, _ := json.Marshal(body["headers"])
 	retryJSON, _ := json.Marshal(body["retry"])
+	secret, _ := body["secret"].(string)
+	storedSecret, err := engine.EncryptWebhookSecret(secret)
+	if err != nil {
+		return fmt.Errorf("encrypt webhook secret: %w", err)
+	}
 
 	id := store.GenerateUUID()
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf(`INSERT INTO _webhooks (id, entity, hook, url, method, headers, condition, async, retry, active)
-		 VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
-		 RETURNING id, entity, hook, url, method, headers, condition, async, retry, active, created_at, updated_at`,
+		fmt.Sprintf(`INSERT INTO _webhooks (id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret)
+		 VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+		 RETURNING id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret, created_at, updated_at`,
 			pb.Add(id), pb.Add(body["entity"]), pb.Add(body["hook"]), pb.Add(body["url"]), pb.Add(body["method"]),
-			pb.Add(string(headersJSON)), pb.Add(body["condition"]), pb.Add(body["async"]), pb.Add(string(retryJSON)), pb.Add(body["active"])),
+			pb.Add(string(headersJSON)), pb.Add(body["condition"]), pb.Add(body["async"]), pb.Add(string(retryJSON)), pb.Add(body["active"]),
+			pb.Add(webhookJSONParam(body["batch"])), pb.Add(body["transport"]), pb.Add(webhookJSONParam(body["transport_config"])),
+			pb.Add(h.store.Dialect.ArrayParam(tags)), pb.Add(nullableSecret(storedSecret))),
 		pb.Params()...)
 	if err != nil {
 		return fmt.Errorf("insert webhook: %w", err)
@@ -1518,6 +2548,8 @@ func (h *Handler) CreateWebhook(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans([]map[string]any{row}, []string{"active", "async"})
 	}
+	parseTags(row)
+	maskWebhookSecrets(row)
 
 	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
 		return fmt.Errorf("reload registry: %w", err)
@@ -1544,16 +2576,36 @@ func (h *Handler) UpdateWebhook(c *fiber.Ctx) error {
 	if errMsg := validateWebhook(body); errMsg != "" {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": errMsg}})
 	}
+	if err := engine.WebhookTargets.CheckURL(c.Context(), body["url"].(string), true); err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "url: " + err.Error()}})
+	}
+	tags := normalizeTags(metadata.ParseStringArray(body["tags"]))
 
 	headersJSON, _ := json.Marshal(body["headers"])
 	retryJSON, _ := json.Marshal(body["retry"])
+	if body["transport"] == nil {
+		body["transport"] = "http"
+	}
 
 	pb2 := h.store.Dialect.NewParamBuilder()
+	// The secret is never returned, so an update that leaves it out keeps
+	// the stored one; "" or null removes it
+	secretSet := ""
+	if raw, ok := body["secret"]; ok {
+		secret, _ := raw.(string)
+		storedSecret, err := engine.EncryptWebhookSecret(secret)
+		if err != nil {
+			return fmt.Errorf("encrypt webhook secret: %w", err)
+		}
+		secretSet = ", secret = " + pb2.Add(nullableSecret(storedSecret))
+	}
 	_, err = store.Exec(c.Context(), h.store.DB,
 		fmt.Sprintf(`UPDATE _webhooks SET entity = %s, hook = %s, url = %s, method = %s, headers = %s,
-		 condition = %s, async = %s, retry = %s, active = %s, updated_at = %s WHERE id = %s`,
+		 condition = %s, async = %s, retry = %s, active = %s, batch = %s, transport = %s, transport_config = %s, tags = %s%s, updated_at = %s WHERE id = %s`,
 			pb2.Add(body["entity"]), pb2.Add(body["hook"]), pb2.Add(body["url"]), pb2.Add(body["method"]),
-			pb2.Add(string(headersJSON)), pb2.Add(body["condition"]), pb2.Add(body["async"]), pb2.Add(string(retryJSON)), pb2.Add(body["active"]), h.store.Dialect.NowExpr(), pb2.Add(id)),
+			pb2.Add(string(headersJSON)), pb2.Add(body["condition"]), pb2.Add(body["async"]), pb2.Add(string(retryJSON)), pb2.Add(body["active"]),
+			pb2.Add(webhookJSONParam(body["batch"])), pb2.Add(body["transport"]), pb2.Add(webhookJSONParam(body["transport_config"])),
+			pb2.Add(h.store.Dialect.ArrayParam(tags)), secretSet, h.store.Dialect.NowExpr(), pb2.Add(id)),
 		pb2.Params()...)
 	if err != nil {
 		return fmt.Errorf("update webhook: %w", err)
@@ -1565,7 +2617,7 @@ func (h *Handler) UpdateWebhook(c *fiber.Ctx) error {
 
 	pb3 := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, entity, hook, url, method, headers, condition, async, retry, active, created_at, updated_at FROM _webhooks WHERE id = %s", pb3.Add(id)),
+		fmt.Sprintf("SELECT id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret, created_at, updated_at FROM _webhooks WHERE id = %s", pb3.Add(id)),
 		pb3.Params()...)
 	if err != nil {
 		return fmt.Errorf("fetch updated webhook: %w", err)
@@ -1573,6 +2625,8 @@ func (h *Handler) UpdateWebhook(c *fiber.Ctx) error {
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans([]map[string]any{row}, []string{"active", "async"})
 	}
+	parseTags(row)
+	maskWebhookSecrets(row)
 
 	return c.JSON(fiber.Map{"data": row})
 }
@@ -1602,11 +2656,62 @@ func (h *Handler) DeleteWebhook(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"data": fiber.Map{"id": id, "deleted": true}})
 }
 
-// --- Webhook Log Endpoints ---
+// ToggleWebhook handles POST /_admin/webhooks/:id/toggle. Flips active, or sets it from {"active": bool}.
+// Pending retries of a disabled webhook are held until it is re-enabled.
+func (h *Handler) ToggleWebhook(c *fiber.Ctx) error {
+	return h.toggleActive(c, "_webhooks", "Webhook")
+}
 
-func (h *Handler) ListWebhookLogs(c *fiber.Ctx) error {
-	query := "SELECT id, webhook_id, entity, hook, url, method, request_headers, request_body, response_status, response_body, status, attempt, max_attempts, next_retry_at, error, idempotency_key, created_at, updated_at FROM _webhook_logs"
+// toggleActive updates the active flag of a rule or webhook without touching its configuration.
+func (h *Handler) toggleActive(c *fiber.Ctx, table, label string) error {
+	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
+	row, err := store.QueryRow(c.Context(), h.store.DB,
+		fmt.Sprintf("SELECT id, active FROM %s WHERE id = %s", table, pb.Add(id)),
+		pb.Params()...)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": label + " not found: " + id}})
+	}
+	if h.store.Dialect.NeedsBoolFix() {
+		store.NormalizeBooleans([]map[string]any{row}, []string{"active"})
+	}
+	current, _ := row["active"].(bool)
+	active := !current
+
+	if len(c.Body()) > 0 {
+		var body struct {
+			Active *bool `json:"active"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
+		}
+		if body.Active != nil {
+			active = *body.Active
+		}
+	}
+
+	pb2 := h.store.Dialect.NewParamBuilder()
+	_, err = store.Exec(c.Context(), h.store.DB,
+		fmt.Sprintf("UPDATE %s SET active = %s, updated_at = %s WHERE id = %s",
+			table, pb2.Add(active), h.store.Dialect.NowExpr(), pb2.Add(id)),
+		pb2.Params()...)
+	if err != nil {
+		return fmt.Errorf("toggle %s %s: %w", table, id, err)
+	}
+
+	if err := metadata.Reload(c.Context(), h.store.DB, h.registry); err != nil {
+		return fmt.Errorf("reload registry: %w", err)
+	}
+
+	return c.JSON(fiber.Map{"data": fiber.Map{"id": id, "active": active}})
+}
+
+// --- Webhook Log Endpoints ---
+
+const webhookLogColumns = "id, webhook_id, entity, hook, url, method, request_headers, request_body, response_status, response_body, status, attempt, max_attempts, next_retry_at, error, idempotency_key, created_at, updated_at"
+
+// webhookLogWhere builds the WHERE clause shared by the webhook log list and export endpoints.
+func webhookLogWhere(c *fiber.Ctx, pb store.ParamBuilder) string {
 	var conditions []string
 
 	if v := c.Query("webhook_id"); v != "" {
@@ -1619,26 +2724,148 @@ func (h *Handler) ListWebhookLogs(c *fiber.Ctx) error {
 		conditions = append(conditions, fmt.Sprintf("entity = %s", pb.Add(v)))
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if len(conditions) == 0 {
+		return ""
 	}
-	query += " ORDER BY created_at DESC LIMIT 200"
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
 
+// pageParams reads ?page and ?per_page for an admin list, defaulting per_page
+// to DefaultPerPage and capping it at MaxPerPage.
+func pageParams(c *fiber.Ctx) (page, perPage int) {
+	page = c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	perPage = c.QueryInt("per_page", DefaultPerPage)
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+	return page, perPage
+}
+
+// listPage runs a paginated admin list: the count over from+where, then the
+// requested page of columns ordered by orderBy.
+func (h *Handler) listPage(c *fiber.Ctx, columns, from, where, orderBy string, pb store.ParamBuilder) ([]map[string]any, fiber.Map, error) {
+	page, perPage := pageParams(c)
+	countRow, err := store.QueryRow(c.Context(), h.store.DB, "SELECT COUNT(*) AS count FROM "+from+where, pb.Params()...)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT %s OFFSET %s",
+		columns, from, where, orderBy, pb.Add(perPage), pb.Add((page-1)*perPage))
 	rows, err := store.QueryRows(c.Context(), h.store.DB, query, pb.Params()...)
 	if err != nil {
-		return fmt.Errorf("list webhook logs: %w", err)
+		return nil, nil, err
 	}
 	if rows == nil {
 		rows = []map[string]any{}
 	}
-	return c.JSON(fiber.Map{"data": rows})
+	return rows, fiber.Map{"page": page, "per_page": perPage, "total": countRow["count"]}, nil
+}
+
+func (h *Handler) ListWebhookLogs(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
+	rows, meta, err := h.listPage(c, webhookLogColumns, "_webhook_logs", webhookLogWhere(c, pb), "created_at DESC", pb)
+	if err != nil {
+		return fmt.Errorf("list webhook logs: %w", err)
+	}
+	return c.JSON(fiber.Map{"data": rows, "meta": meta})
+}
+
+// ExportWebhookLogs returns all webhook logs matching the list filters as a
+// CSV download. The file is built in memory before it is sent.
+func (h *Handler) ExportWebhookLogs(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
+	query := "SELECT " + webhookLogColumns + " FROM _webhook_logs" + webhookLogWhere(c, pb) + " ORDER BY created_at DESC"
+
+	rows, err := store.QueryRows(c.Context(), h.store.DB, query, pb.Params()...)
+	if err != nil {
+		return fmt.Errorf("export webhook logs: %w", err)
+	}
+
+	columns := strings.Split(webhookLogColumns, ", ")
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, col := range columns {
+			record[i] = csvValue(row[col])
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="webhook-logs.csv"`)
+	return c.Send(buf.Bytes())
+}
+
+// csvValue formats a database value for a CSV cell.
+func csvValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	case []byte:
+		return string(val)
+	case string:
+		return val
+	case map[string]any, []any:
+		b, _ := json.Marshal(val)
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// ListAuditLog returns the latest field-level audit entries of audited
+// entities, filtered by entity, record_id and user_id.
+func (h *Handler) ListAuditLog(c *fiber.Ctx) error {
+	pb := h.store.Dialect.NewParamBuilder()
+	var conditions []string
+	for _, col := range []string{"entity", "record_id", "user_id"} {
+		if v := c.Query(col); v != "" {
+			conditions = append(conditions, fmt.Sprintf("%s = %s", col, pb.Add(v)))
+		}
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, meta, err := h.listPage(c, "id, entity, record_id, action, user_id, changes, created_at", "_audit_log", where, "created_at DESC", pb)
+	if err != nil {
+		return fmt.Errorf("list audit log: %w", err)
+	}
+	for _, row := range rows {
+		if text, ok := row["changes"].(string); ok {
+			var changes any
+			if json.Unmarshal([]byte(text), &changes) == nil {
+				row["changes"] = changes
+			}
+		}
+	}
+	return c.JSON(fiber.Map{"data": rows, "meta": meta})
 }
 
 func (h *Handler) GetWebhookLog(c *fiber.Ctx) error {
 	id := c.Params("id")
 	pb := h.store.Dialect.NewParamBuilder()
 	row, err := store.QueryRow(c.Context(), h.store.DB,
-		fmt.Sprintf("SELECT id, webhook_id, entity, hook, url, method, request_headers, request_body, response_status, response_body, status, attempt, max_attempts, next_retry_at, error, idempotency_key, created_at, updated_at FROM _webhook_logs WHERE id = %s", pb.Add(id)),
+		fmt.Sprintf("SELECT %s FROM _webhook_logs WHERE id = %s", webhookLogColumns, pb.Add(id)),
 		pb.Params()...)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Webhook log not found: " + id}})
@@ -1694,13 +2921,77 @@ func validateWebhook(body map[string]any) string {
 			return "hook must be after_write, before_write, after_delete, or before_delete"
 		}
 	}
+	if entity == engine.MetaWebhookEntity && hook != "" && hook != "after_write" {
+		return "_webhook webhooks fire on failed deliveries and must use the after_write hook"
+	}
 
 	url, _ := body["url"].(string)
 	if url == "" {
 		return "url is required"
 	}
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		return "url must start with http:// or https://"
+	if raw, ok := body["tags"]; ok && raw != nil {
+		tags, ok := raw.([]any)
+		if !ok {
+			return "tags must be an array of strings"
+		}
+		for _, t := range tags {
+			if _, ok := t.(string); !ok {
+				return "tags must be an array of strings"
+			}
+		}
+	}
+	if raw, ok := body["secret"]; ok && raw != nil {
+		if _, ok := raw.(string); !ok {
+			return "secret must be a string"
+		}
+	}
+	transportConfig := map[string]string{}
+	if raw, ok := body["transport_config"]; ok && raw != nil {
+		cfg, ok := raw.(map[string]any)
+		if !ok {
+			return "transport_config must be an object of strings"
+		}
+		for k, v := range cfg {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Sprintf("transport_config.%s must be a string", k)
+			}
+			transportConfig[k] = s
+		}
+	}
+	transport, _ := body["transport"].(string)
+	if transport != "" && transport != "http" && !engine.HasWebhookTransport(transport) {
+		return fmt.Sprintf("transport must be http or a registered transport; no %s transport is registered", transport)
+	}
+	switch transport {
+	case "", "http":
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return "url must start with http:// or https://"
+		}
+	case "nats":
+		if !strings.HasPrefix(url, "tls://") {
+			return "url must start with tls:// for the nats transport"
+		}
+		if transportConfig["subject"] == "" {
+			return "transport_config.subject is required for the nats transport"
+		}
+		headers := map[string]string{}
+		if raw, ok := body["headers"].(map[string]any); ok {
+			for k, v := range raw {
+				headers[k] = fmt.Sprintf("%v", v)
+			}
+		}
+		if err := engine.ValidateNATSMessage(transportConfig["subject"], headers); err != nil {
+			return err.Error()
+		}
+	case "kafka":
+		if transportConfig["topic"] == "" {
+			return "transport_config.topic is required for the kafka transport"
+		}
+	case "sqs":
+		if !strings.HasPrefix(url, "https://") {
+			return "url must be the https:// queue URL for the sqs transport"
+		}
 	}
 
 	method, _ := body["method"].(string)
@@ -1711,9 +3002,56 @@ func validateWebhook(body map[string]any) string {
 		}
 	}
 
+	if raw, ok := body["batch"]; ok && raw != nil {
+		batch, ok := raw.(map[string]any)
+		if !ok {
+			return "batch must be an object with size and interval_ms"
+		}
+		if async, ok := body["async"].(bool); ok && !async {
+			return "batch requires an async webhook"
+		}
+		if size, _ := batch["size"].(float64); size < 1 {
+			return "batch.size must be at least 1"
+		}
+		if interval, _ := batch["interval_ms"].(float64); interval <= 0 {
+			return "batch.interval_ms must be greater than 0"
+		}
+	}
+
 	return ""
 }
 
+// maskWebhookSecrets replaces the secret column of webhook rows with
+// has_secret, so secrets never leave the server.
+func maskWebhookSecrets(rows ...map[string]any) {
+	for _, row := range rows {
+		secret, _ := row["secret"].(string)
+		row["has_secret"] = secret != ""
+		delete(row, "secret")
+	}
+}
+
+// nullableSecret stores an empty webhook secret as NULL.
+func nullableSecret(secret string) any {
+	if secret == "" {
+		return nil
+	}
+	return secret
+}
+
+// webhookJSONParam encodes an optional JSON webhook column (batch,
+// transport_config), or NULL when it is unset.
+func webhookJSONParam(v any) any {
+	switch b := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return b // already encoded, e.g. from an export taken on SQLite
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
 // --- UI Config Endpoints ---
 
 func (h *Handler) ListUIConfigs(c *fiber.Ctx) error {
@@ -1914,7 +3252,7 @@ func (h *Handler) Export(c *fiber.Ctx) error {
 
 	// Rules
 	ruleRows, err := store.QueryRows(ctx, h.store.DB,
-		"SELECT entity, hook, type, definition, priority, active FROM _rules ORDER BY entity, priority")
+		"SELECT entity, hook, type, definition, priority, active, tags FROM _rules ORDER BY entity, priority, created_at, id")
 	if err != nil {
 		return fmt.Errorf("export rules: %w", err)
 	}
@@ -1926,6 +3264,7 @@ func (h *Handler) Export(c *fiber.Ctx) error {
 		rules = append(rules, map[string]any{
 			"entity": row["entity"], "hook": row["hook"], "type": row["type"],
 			"definition": row["definition"], "priority": row["priority"], "active": row["active"],
+			"tags": metadata.ParseStringArray(row["tags"]),
 		})
 	}
 
@@ -1948,7 +3287,7 @@ func (h *Handler) Export(c *fiber.Ctx) error {
 
 	// Workflows
 	wfRows, err := store.QueryRows(ctx, h.store.DB,
-		"SELECT name, trigger, context, steps, active FROM _workflows ORDER BY name")
+		"SELECT name, trigger, context, steps, active, tags FROM _workflows ORDER BY name")
 	if err != nil {
 		return fmt.Errorf("export workflows: %w", err)
 	}
@@ -1960,39 +3299,49 @@ func (h *Handler) Export(c *fiber.Ctx) error {
 		workflows = append(workflows, map[string]any{
 			"name": row["name"], "trigger": row["trigger"],
 			"context": row["context"], "steps": row["steps"], "active": row["active"],
+			"tags": metadata.ParseStringArray(row["tags"]),
 		})
 	}
 
 	// Permissions
 	permRows, err := store.QueryRows(ctx, h.store.DB,
-		"SELECT entity, action, roles, conditions FROM _permissions ORDER BY entity, action")
+		"SELECT entity, action, effect, roles, conditions FROM _permissions ORDER BY entity, action")
 	if err != nil {
 		return fmt.Errorf("export permissions: %w", err)
 	}
 	permissions := make([]map[string]any, 0, len(permRows))
 	for _, row := range permRows {
 		permissions = append(permissions, map[string]any{
-			"entity": row["entity"], "action": row["action"],
+			"entity": row["entity"], "action": row["action"], "effect": row["effect"],
 			"roles": metadata.ParseStringArray(row["roles"]), "conditions": row["conditions"],
 		})
 	}
 
 	// Webhooks
 	whRows, err := store.QueryRows(ctx, h.store.DB,
-		"SELECT entity, hook, url, method, headers, condition, async, retry, active FROM _webhooks ORDER BY entity, hook")
+		"SELECT entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret FROM _webhooks ORDER BY entity, hook")
 	if err != nil {
 		return fmt.Errorf("export webhooks: %w", err)
 	}
 	if h.store.Dialect.NeedsBoolFix() {
 		store.NormalizeBooleans(whRows, []string{"active", "async"})
 	}
+	// Secrets never leave the server. A signed webhook is exported with
+	// has_secret and a warning, so it isn't silently imported unsigned.
 	webhooks := make([]map[string]any, 0, len(whRows))
+	warnings := []string{}
 	for _, row := range whRows {
+		secret, _ := row["secret"].(string)
 		webhooks = append(webhooks, map[string]any{
 			"entity": row["entity"], "hook": row["hook"], "url": row["url"],
 			"method": row["method"], "headers": row["headers"], "condition": row["condition"],
-			"async": row["async"], "retry": row["retry"], "active": row["active"],
+			"async": row["async"], "retry": row["retry"], "active": row["active"], "batch": row["batch"],
+			"transport": row["transport"], "transport_config": row["transport_config"],
+			"tags": metadata.ParseStringArray(row["tags"]), "has_secret": secret != "",
 		})
+		if secret != "" {
+			warnings = append(warnings, fmt.Sprintf("Webhook (%v/%v/%v): signing secret not exported; add \"secret\" before importing or set it afterwards", row["entity"], row["hook"], row["url"]))
+		}
 	}
 
 	// UI Configs
@@ -2008,7 +3357,7 @@ func (h *Handler) Export(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{"data": fiber.Map{
+	data := fiber.Map{
 		"version":        1,
 		"exported_at":    time.Now().UTC().Format(time.RFC3339),
 		"entities":       entities,
@@ -2019,22 +3368,51 @@ func (h *Handler) Export(c *fiber.Ctx) error {
 		"permissions":    permissions,
 		"webhooks":       webhooks,
 		"ui_configs":     uiConfigs,
-	}})
+	}
+	if len(warnings) > 0 {
+		data["warnings"] = warnings
+	}
+
+	// Users and pending invites are opt-in
+	if c.QueryBool("include_users") {
+		users, invites, err := h.exportUsers(ctx)
+		if err != nil {
+			return err
+		}
+		data["users"] = users
+		data["invites"] = invites
+	}
+
+	return c.JSON(fiber.Map{"data": data})
+}
+
+// importPayload is the body of POST /_admin/import, in the shape Export writes.
+type importPayload struct {
+	Version       int                         `json:"version"`
+	Entities      []map[string]any            `json:"entities"`
+	Relations     []map[string]any            `json:"relations"`
+	Rules         []map[string]any            `json:"rules"`
+	StateMachines []map[string]any            `json:"state_machines"`
+	Workflows     []map[string]any            `json:"workflows"`
+	Permissions   []map[string]any            `json:"permissions"`
+	Webhooks      []map[string]any            `json:"webhooks"`
+	UIConfigs     []map[string]any            `json:"ui_configs"`
+	SampleData    map[string][]map[string]any `json:"sample_data"`
+	Users         []map[string]any            `json:"users"`
+	Invites       []map[string]any            `json:"invites"`
 }
 
+// Import handles POST /_admin/import. With ?validate_only=true nothing is
+// written; the response is a per-section report of invalid definitions.
+// ?dry_run=true also writes nothing and returns the summary and errors the
+// import would produce. The new tables are created first, then definitions
+// and sample data are written in one transaction; the registry is reloaded
+// only once it commits, so no request sees a half-imported entity. With
+// ?atomic=true any error rolls the import back and drops the tables it
+// created. The users and invites sections are only imported with
+// ?include_users=true.
 func (h *Handler) Import(c *fiber.Ctx) error {
-	var payload struct {
-		Version       int                         `json:"version"`
-		Entities      []map[string]any            `json:"entities"`
-		Relations     []map[string]any            `json:"relations"`
-		Rules         []map[string]any            `json:"rules"`
-		StateMachines []map[string]any            `json:"state_machines"`
-		Workflows     []map[string]any            `json:"workflows"`
-		Permissions   []map[string]any            `json:"permissions"`
-		Webhooks      []map[string]any            `json:"webhooks"`
-		UIConfigs     []map[string]any            `json:"ui_configs"`
-		SampleData    map[string][]map[string]any `json:"sample_data"`
-	}
+	var payload importPayload
 	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
 	}
@@ -2042,17 +3420,184 @@ func (h *Handler) Import(c *fiber.Ctx) error {
 		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED",
 			"message": fmt.Sprintf("Unsupported export version: %d", payload.Version)}})
 	}
+	if c.QueryBool("validate_only") {
+		report := h.validateImport(&payload)
+		valid := true
+		for _, issues := range report {
+			if len(issues) > 0 {
+				valid = false
+			}
+		}
+		return c.JSON(fiber.Map{"data": fiber.Map{"valid": valid, "errors": report}})
+	}
+	if c.QueryBool("dry_run") {
+		summary, errs, err := h.dryRunImport(c.Context(), &payload, c.QueryBool("include_users"))
+		if err != nil {
+			return err
+		}
+		result := fiber.Map{"message": "Dry run: nothing was imported", "dry_run": true, "valid": len(errs) == 0, "summary": summary}
+		if len(errs) > 0 {
+			result["errors"] = errs
+		}
+		return c.JSON(fiber.Map{"data": result})
+	}
 
 	ctx := c.Context()
-	summary := map[string]int{
+	atomic := c.QueryBool("atomic")
+	includeUsers := c.QueryBool("include_users")
+
+	// Plan: run the definitions in a transaction that is rolled back, to
+	// learn which entities and join tables are new
+	planTx, err := h.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("begin import: %w", err)
+	}
+	plan := h.importDefinitions(ctx, planTx, &payload, includeUsers, nil)
+	if err := planTx.Rollback(); err != nil {
+		return fmt.Errorf("roll back import plan: %w", err)
+	}
+	if atomic && len(plan.errors) > 0 {
+		return importRolledBack(c, plan.summary, plan.errors)
+	}
+
+	// Staged DDL, before any definition is stored: the new entities' tables,
+	// then the join tables that reference them. Nothing can reach the tables
+	// until the registry is reloaded below. Only tables that didn't exist are
+	// dropped on rollback.
+	var tables, errors []string
+	created := func(table string) {
+		exists, err := h.store.Dialect.TableExists(ctx, h.store.DB, table)
+		if err == nil && !exists {
+			tables = append(tables, table)
+		}
+	}
+	entities := h.importEntities(&payload, plan.toMigrate)
+	for _, e := range plan.toMigrate {
+		created(e.PhysicalTable(h.store.TablePrefix))
+	}
+	migrations := h.migrateImported(ctx, plan.toMigrate)
+	for _, m := range migrations {
+		if m.Error != "" {
+			errors = append(errors, fmt.Sprintf("Entity %s: migrate: %s", m.Entity, m.Error))
+		}
+	}
+	for _, rel := range plan.joinRelations {
+		src, tgt := entities[rel.Source], entities[rel.Target]
+		if src == nil || tgt == nil {
+			continue
+		}
+		created(rel.PhysicalJoinTable(h.store.TablePrefix))
+		if err := h.migrator.MigrateJoinTable(ctx, h.store.DB, rel, src, tgt); err != nil {
+			errors = append(errors, fmt.Sprintf("Relation %s: join table: %v", rel.Name, err))
+		}
+	}
+	rollBack := func(summary map[string]int, errors []string) error {
+		if err := h.dropImportedTables(ctx, tables); err != nil {
+			errors = append(errors, fmt.Sprintf("Rollback: %v", err))
+		}
+		return importRolledBack(c, summary, errors)
+	}
+	if atomic && len(errors) > 0 {
+		return rollBack(plan.summary, errors)
+	}
+
+	// Every definition and sample record is written in one transaction, each
+	// under its own savepoint, and published when it commits
+	tx, err := h.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("begin import: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	run := h.importDefinitions(ctx, tx, &payload, includeUsers, nil)
+	summary := run.summary
+	errors = append(errors, run.errors...)
+	if len(payload.SampleData) > 0 && !(atomic && len(errors) > 0) {
+		errors = append(errors, h.importSampleData(ctx, tx, &payload, entities, summary)...)
+	}
+	if atomic && len(errors) > 0 {
+		tx.Rollback() //nolint:errcheck
+		return rollBack(summary, errors)
+	}
+	if err := tx.Commit(); err != nil {
+		_ = h.dropImportedTables(ctx, tables)
+		return fmt.Errorf("commit import: %w", err)
+	}
+	_ = metadata.Reload(ctx, h.store.DB, h.registry)
+
+	imported := 0
+	for _, n := range summary {
+		imported += n
+	}
+	status := bulkStatus(imported, len(errors))
+	message := "Import completed"
+	switch status {
+	case fiber.StatusMultiStatus:
+		message = "Import completed with errors"
+	case fiber.StatusUnprocessableEntity:
+		message = "Import failed"
+	}
+
+	result := fiber.Map{
+		"message":    message,
+		"committed":  true,
+		"summary":    summary,
+		"migrations": migrations,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	if len(run.warnings) > 0 {
+		result["warnings"] = run.warnings
+	}
+	return c.Status(status).JSON(fiber.Map{"data": result})
+}
+
+// importRun is what importDefinitions wrote: the per-section counts, the
+// items that failed, notes on items imported with something missing, and the
+// entities and many-to-many relations whose
+// tables have to be created.
+type importRun struct {
+	summary       map[string]int
+	errors        []string
+	warnings      []string
+	toMigrate     []*metadata.Entity
+	joinRelations []*metadata.Relation
+}
+
+// importEntities returns the payload's entities by name: the new ones from
+// toMigrate and those the registry already has.
+func (h *Handler) importEntities(payload *importPayload, toMigrate []*metadata.Entity) map[string]*metadata.Entity {
+	entities := make(map[string]*metadata.Entity)
+	for _, e := range toMigrate {
+		entities[e.Name] = e
+	}
+	for _, raw := range payload.Entities {
+		name, _ := raw["name"].(string)
+		if existing := h.registry.GetEntity(name); existing != nil {
+			entities[name] = existing
+		}
+	}
+	return entities
+}
+
+// importDefinitions writes payload's definitions in tx, each under its own
+// savepoint, skipping those that already exist; with includeUsers it writes
+// the users and invites too. reject, when set, names the items that must not
+// be written (by section and index) and why; they are reported as errors.
+// Import commits tx, the dry run rolls it back, so both skip and count alike.
+func (h *Handler) importDefinitions(ctx context.Context, tx store.Querier, payload *importPayload, includeUsers bool, reject func(section string, i int) string) *importRun {
+	if reject == nil {
+		reject = func(string, int) string { return "" }
+	}
+	run := &importRun{summary: map[string]int{
 		"entities": 0, "relations": 0, "rules": 0,
 		"state_machines": 0, "workflows": 0,
 		"permissions": 0, "webhooks": 0,
-	}
-	var errors []string
+	}}
 
 	// Step 1: Entities
-	for _, raw := range payload.Entities {
+	for i, raw := range payload.Entities {
 		name, _ := raw["name"].(string)
 		table, _ := raw["table"].(string)
 		if name == "" || table == "" {
@@ -2061,33 +3606,37 @@ func (h *Handler) Import(c *fiber.Ctx) error {
 		if h.registry.GetEntity(name) != nil {
 			continue
 		}
+		if msg := reject("entities", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("Entity %s: %s", name, msg))
+			continue
+		}
 		defJSON, err := json.Marshal(raw)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Entity %s: %v", name, err))
+			run.errors = append(run.errors, fmt.Sprintf("Entity %s: %v", name, err))
 			continue
 		}
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err = store.Exec(ctx, h.store.DB,
-			fmt.Sprintf("INSERT INTO _entities (name, table_name, definition) VALUES (%s, %s, %s)",
-				pb.Add(name), pb.Add(table), pb.Add(defJSON)),
-			pb.Params()...)
+		err = importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.Exec(ctx, tx,
+				fmt.Sprintf("INSERT INTO _entities (name, table_name, definition) VALUES (%s, %s, %s)",
+					pb.Add(name), pb.Add(table), pb.Add(defJSON)),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Entity %s: %v", name, err))
+			run.errors = append(run.errors, fmt.Sprintf("Entity %s: %v", name, err))
 			continue
 		}
-		// Migrate: create the business table
+		// Migrated after the commit
 		var entity metadata.Entity
 		if err := json.Unmarshal(defJSON, &entity); err == nil {
-			_ = h.migrator.Migrate(ctx, &entity)
+			run.toMigrate = append(run.toMigrate, &entity)
 		}
-		summary["entities"]++
+		run.summary["entities"]++
 	}
 
-	// Reload so relations can reference the new entities
-	_ = metadata.Reload(ctx, h.store.DB, h.registry)
-
 	// Step 2: Relations
-	for _, raw := range payload.Relations {
+	for i, raw := range payload.Relations {
 		name, _ := raw["name"].(string)
 		source, _ := raw["source"].(string)
 		target, _ := raw["target"].(string)
@@ -2097,34 +3646,37 @@ func (h *Handler) Import(c *fiber.Ctx) error {
 		if h.registry.GetRelation(name) != nil {
 			continue
 		}
+		if msg := reject("relations", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("Relation %s: %s", name, msg))
+			continue
+		}
 		defJSON, err := json.Marshal(raw)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Relation %s: %v", name, err))
+			run.errors = append(run.errors, fmt.Sprintf("Relation %s: %v", name, err))
 			continue
 		}
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err = store.Exec(ctx, h.store.DB,
-			fmt.Sprintf("INSERT INTO _relations (name, source, target, definition) VALUES (%s, %s, %s, %s)",
-				pb.Add(name), pb.Add(source), pb.Add(target), pb.Add(defJSON)),
-			pb.Params()...)
+		err = importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.Exec(ctx, tx,
+				fmt.Sprintf("INSERT INTO _relations (name, source, target, definition) VALUES (%s, %s, %s, %s)",
+					pb.Add(name), pb.Add(source), pb.Add(target), pb.Add(defJSON)),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Relation %s: %v", name, err))
+			run.errors = append(run.errors, fmt.Sprintf("Relation %s: %v", name, err))
 			continue
 		}
-		// Create join table for many-to-many
+		// Join tables for many-to-many are created after the entity tables
 		var rel metadata.Relation
 		if err := json.Unmarshal(defJSON, &rel); err == nil && rel.IsManyToMany() {
-			src := h.registry.GetEntity(rel.Source)
-			tgt := h.registry.GetEntity(rel.Target)
-			if src != nil && tgt != nil {
-				_ = h.migrator.MigrateJoinTable(ctx, &rel, src, tgt)
-			}
+			run.joinRelations = append(run.joinRelations, &rel)
 		}
-		summary["relations"]++
+		run.summary["relations"]++
 	}
 
 	// Step 3: Rules (dedup by entity+hook+type+definition)
-	existingRules, _ := store.QueryRows(ctx, h.store.DB,
+	existingRules, _ := store.QueryRows(ctx, tx,
 		"SELECT entity, hook, type, definition FROM _rules")
 	ruleSet := make(map[string]bool)
 	for _, r := range existingRules {
@@ -2132,123 +3684,164 @@ func (h *Handler) Import(c *fiber.Ctx) error {
 		key := fmt.Sprintf("%v|%v|%v|%s", r["entity"], r["hook"], r["type"], defJSON)
 		ruleSet[key] = true
 	}
-	for _, raw := range payload.Rules {
+	for i, raw := range payload.Rules {
 		defJSON, _ := json.Marshal(raw["definition"])
 		key := fmt.Sprintf("%v|%v|%v|%s", raw["entity"], raw["hook"], raw["type"], defJSON)
 		if ruleSet[key] {
 			continue
 		}
+		if msg := reject("rules", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("Rule (%v/%v): %s", raw["entity"], raw["hook"], msg))
+			continue
+		}
 		id := store.GenerateUUID()
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err := store.QueryRow(ctx, h.store.DB,
-			fmt.Sprintf("INSERT INTO _rules (id, entity, hook, type, definition, priority, active) VALUES (%s, %s, %s, %s, %s, %s, %s) RETURNING id",
-				pb.Add(id), pb.Add(raw["entity"]), pb.Add(raw["hook"]), pb.Add(raw["type"]), pb.Add(defJSON), pb.Add(raw["priority"]), pb.Add(raw["active"])),
-			pb.Params()...)
+		err := importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.QueryRow(ctx, tx,
+				fmt.Sprintf("INSERT INTO _rules (id, entity, hook, type, definition, priority, active, tags) VALUES (%s, %s, %s, %s, %s, %s, %s, %s) RETURNING id",
+					pb.Add(id), pb.Add(raw["entity"]), pb.Add(raw["hook"]), pb.Add(raw["type"]), pb.Add(defJSON), pb.Add(importDefault(raw["priority"], 0)), pb.Add(importDefault(raw["active"], true)),
+					pb.Add(h.store.Dialect.ArrayParam(normalizeTags(metadata.ParseStringArray(raw["tags"]))))),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Rule (%v/%v): %v", raw["entity"], raw["hook"], err))
+			run.errors = append(run.errors, fmt.Sprintf("Rule (%v/%v): %v", raw["entity"], raw["hook"], err))
 			continue
 		}
 		ruleSet[key] = true
-		summary["rules"]++
+		run.summary["rules"]++
 	}
 
 	// Step 4: State machines (dedup by entity+field)
-	existingSMs, _ := store.QueryRows(ctx, h.store.DB,
+	existingSMs, _ := store.QueryRows(ctx, tx,
 		"SELECT entity, field FROM _state_machines")
 	smSet := make(map[string]bool)
 	for _, r := range existingSMs {
 		smSet[fmt.Sprintf("%v|%v", r["entity"], r["field"])] = true
 	}
-	for _, raw := range payload.StateMachines {
+	for i, raw := range payload.StateMachines {
 		key := fmt.Sprintf("%v|%v", raw["entity"], raw["field"])
 		if smSet[key] {
 			continue
 		}
+		if msg := reject("state_machines", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("State machine (%v/%v): %s", raw["entity"], raw["field"], msg))
+			continue
+		}
 		defJSON, _ := json.Marshal(raw["definition"])
 		id := store.GenerateUUID()
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err := store.QueryRow(ctx, h.store.DB,
-			fmt.Sprintf("INSERT INTO _state_machines (id, entity, field, definition, active) VALUES (%s, %s, %s, %s, %s) RETURNING id",
-				pb.Add(id), pb.Add(raw["entity"]), pb.Add(raw["field"]), pb.Add(defJSON), pb.Add(raw["active"])),
-			pb.Params()...)
+		err := importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.QueryRow(ctx, tx,
+				fmt.Sprintf("INSERT INTO _state_machines (id, entity, field, definition, active) VALUES (%s, %s, %s, %s, %s) RETURNING id",
+					pb.Add(id), pb.Add(raw["entity"]), pb.Add(raw["field"]), pb.Add(defJSON), pb.Add(importDefault(raw["active"], true))),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("State machine (%v/%v): %v", raw["entity"], raw["field"], err))
+			run.errors = append(run.errors, fmt.Sprintf("State machine (%v/%v): %v", raw["entity"], raw["field"], err))
 			continue
 		}
 		smSet[key] = true
-		summary["state_machines"]++
+		run.summary["state_machines"]++
 	}
 
 	// Step 5: Workflows (dedup by name)
-	for _, raw := range payload.Workflows {
+	for i, raw := range payload.Workflows {
 		name, _ := raw["name"].(string)
 		if name == "" {
 			continue
 		}
 		pbCheck := h.store.Dialect.NewParamBuilder()
-		_, err := store.QueryRow(ctx, h.store.DB,
+		_, err := store.QueryRow(ctx, tx,
 			fmt.Sprintf("SELECT id FROM _workflows WHERE name = %s", pbCheck.Add(name)),
 			pbCheck.Params()...)
 		if err == nil {
 			continue // already exists
 		}
+		if msg := reject("workflows", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("Workflow %s: %s", name, msg))
+			continue
+		}
 		triggerJSON, _ := json.Marshal(raw["trigger"])
 		contextJSON, _ := json.Marshal(raw["context"])
 		stepsJSON, _ := json.Marshal(raw["steps"])
 		id := store.GenerateUUID()
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err = store.QueryRow(ctx, h.store.DB,
-			fmt.Sprintf("INSERT INTO _workflows (id, name, trigger, context, steps, active) VALUES (%s, %s, %s, %s, %s, %s) RETURNING id",
-				pb.Add(id), pb.Add(name), pb.Add(triggerJSON), pb.Add(contextJSON), pb.Add(stepsJSON), pb.Add(raw["active"])),
-			pb.Params()...)
+		err = importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.QueryRow(ctx, tx,
+				fmt.Sprintf("INSERT INTO _workflows (id, name, trigger, context, steps, active, tags) VALUES (%s, %s, %s, %s, %s, %s, %s) RETURNING id",
+					pb.Add(id), pb.Add(name), pb.Add(triggerJSON), pb.Add(contextJSON), pb.Add(stepsJSON), pb.Add(importDefault(raw["active"], true)),
+					pb.Add(h.store.Dialect.ArrayParam(normalizeTags(metadata.ParseStringArray(raw["tags"]))))),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Workflow %s: %v", name, err))
+			run.errors = append(run.errors, fmt.Sprintf("Workflow %s: %v", name, err))
 			continue
 		}
-		summary["workflows"]++
+		run.summary["workflows"]++
 	}
 
-	// Step 6: Permissions (dedup by entity+action)
-	existingPerms, _ := store.QueryRows(ctx, h.store.DB,
-		"SELECT entity, action FROM _permissions")
+	// Step 6: Permissions (dedup by entity+action+effect)
+	existingPerms, _ := store.QueryRows(ctx, tx,
+		"SELECT entity, action, effect FROM _permissions")
 	permSet := make(map[string]bool)
 	for _, r := range existingPerms {
-		permSet[fmt.Sprintf("%v|%v", r["entity"], r["action"])] = true
+		permSet[fmt.Sprintf("%v|%v|%v", r["entity"], r["action"], r["effect"])] = true
 	}
-	for _, raw := range payload.Permissions {
-		key := fmt.Sprintf("%v|%v", raw["entity"], raw["action"])
+	for i, raw := range payload.Permissions {
+		effect, _ := raw["effect"].(string)
+		if effect == "" {
+			effect = "allow"
+		}
+		key := fmt.Sprintf("%v|%v|%v", raw["entity"], raw["action"], effect)
 		if permSet[key] {
 			continue
 		}
+		if msg := reject("permissions", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("Permission (%v/%v): %s", raw["entity"], raw["action"], msg))
+			continue
+		}
 		condJSON, _ := json.Marshal(raw["conditions"])
 		// Convert roles from any to []string for ArrayParam
 		rolesRaw := metadata.ParseStringArray(raw["roles"])
 		id := store.GenerateUUID()
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err := store.QueryRow(ctx, h.store.DB,
-			fmt.Sprintf("INSERT INTO _permissions (id, entity, action, roles, conditions) VALUES (%s, %s, %s, %s, %s) RETURNING id",
-				pb.Add(id), pb.Add(raw["entity"]), pb.Add(raw["action"]), pb.Add(h.store.Dialect.ArrayParam(rolesRaw)), pb.Add(condJSON)),
-			pb.Params()...)
+		err := importSavepoint(ctx, tx, func() error {
+			if err := h.addRoles(ctx, tx, rolesRaw); err != nil {
+				return err
+			}
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.QueryRow(ctx, tx,
+				fmt.Sprintf("INSERT INTO _permissions (id, entity, action, effect, roles, conditions) VALUES (%s, %s, %s, %s, %s, %s) RETURNING id",
+					pb.Add(id), pb.Add(raw["entity"]), pb.Add(raw["action"]), pb.Add(effect), pb.Add(h.store.Dialect.ArrayParam(rolesRaw)), pb.Add(condJSON)),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Permission (%v/%v): %v", raw["entity"], raw["action"], err))
+			run.errors = append(run.errors, fmt.Sprintf("Permission (%v/%v): %v", raw["entity"], raw["action"], err))
 			continue
 		}
 		permSet[key] = true
-		summary["permissions"]++
+		run.summary["permissions"]++
 	}
 
 	// Step 7: Webhooks (dedup by entity+hook+url)
-	existingWHs, _ := store.QueryRows(ctx, h.store.DB,
+	existingWHs, _ := store.QueryRows(ctx, tx,
 		"SELECT entity, hook, url FROM _webhooks")
 	whSet := make(map[string]bool)
 	for _, r := range existingWHs {
 		whSet[fmt.Sprintf("%v|%v|%v", r["entity"], r["hook"], r["url"])] = true
 	}
-	for _, raw := range payload.Webhooks {
+	for i, raw := range payload.Webhooks {
 		key := fmt.Sprintf("%v|%v|%v", raw["entity"], raw["hook"], raw["url"])
 		if whSet[key] {
 			continue
 		}
+		if msg := reject("webhooks", i); msg != "" {
+			run.errors = append(run.errors, fmt.Sprintf("Webhook (%v/%v/%v): %s", raw["entity"], raw["hook"], raw["url"], msg))
+			continue
+		}
 		headersJSON, _ := json.Marshal(raw["headers"])
 		retryJSON, _ := json.Marshal(raw["retry"])
 		method := raw["method"]
@@ -2271,24 +3864,42 @@ func (h *Handler) Import(c *fiber.Ctx) error {
 		if condition == nil {
 			condition = ""
 		}
+		transport := raw["transport"]
+		if transport == nil {
+			transport = "http"
+		}
+		secret, _ := raw["secret"].(string)
+		storedSecret, err := engine.EncryptWebhookSecret(secret)
+		if err != nil {
+			run.errors = append(run.errors, fmt.Sprintf("Webhook (%v/%v/%v): encrypt secret: %v", raw["entity"], raw["hook"], raw["url"], err))
+			continue
+		}
 		id := store.GenerateUUID()
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err := store.QueryRow(ctx, h.store.DB,
-			fmt.Sprintf(`INSERT INTO _webhooks (id, entity, hook, url, method, headers, condition, async, retry, active)
-			 VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s) RETURNING id`,
-				pb.Add(id), pb.Add(raw["entity"]), pb.Add(hook), pb.Add(raw["url"]), pb.Add(method),
-				pb.Add(string(headersJSON)), pb.Add(condition), pb.Add(async), pb.Add(string(retryJSON)), pb.Add(active)),
-			pb.Params()...)
+		err = importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.QueryRow(ctx, tx,
+				fmt.Sprintf(`INSERT INTO _webhooks (id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret)
+			 VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s) RETURNING id`,
+					pb.Add(id), pb.Add(raw["entity"]), pb.Add(hook), pb.Add(raw["url"]), pb.Add(method),
+					pb.Add(string(headersJSON)), pb.Add(condition), pb.Add(async), pb.Add(string(retryJSON)), pb.Add(active),
+					pb.Add(webhookJSONParam(raw["batch"])), pb.Add(transport), pb.Add(webhookJSONParam(raw["transport_config"])),
+					pb.Add(h.store.Dialect.ArrayParam(normalizeTags(metadata.ParseStringArray(raw["tags"])))), pb.Add(nullableSecret(storedSecret))),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Webhook (%v/%v/%v): %v", raw["entity"], raw["hook"], raw["url"], err))
+			run.errors = append(run.errors, fmt.Sprintf("Webhook (%v/%v/%v): %v", raw["entity"], raw["hook"], raw["url"], err))
 			continue
 		}
+		if raw["has_secret"] == true && secret == "" {
+			run.warnings = append(run.warnings, fmt.Sprintf("Webhook (%v/%v/%v): imported unsigned; its secret was not in the export", raw["entity"], raw["hook"], raw["url"]))
+		}
 		whSet[key] = true
-		summary["webhooks"]++
+		run.summary["webhooks"]++
 	}
 
 	// Step 8: UI Configs (upsert by entity+scope)
-	summary["ui_configs"] = 0
+	run.summary["ui_configs"] = 0
 	for _, raw := range payload.UIConfigs {
 		entity, _ := raw["entity"].(string)
 		scope, _ := raw["scope"].(string)
@@ -2299,139 +3910,159 @@ func (h *Handler) Import(c *fiber.Ctx) error {
 			scope = "default"
 		}
 		configJSON, _ := json.Marshal(raw["config"])
-		id := store.GenerateUUID()
-		pb := h.store.Dialect.NewParamBuilder()
-		_, err := store.QueryRow(ctx, h.store.DB,
-			fmt.Sprintf(`INSERT INTO _ui_configs (id, entity, scope, config) VALUES (%s, %s, %s, %s)
-			 ON CONFLICT (entity, scope) DO UPDATE SET config = EXCLUDED.config, updated_at = %s
-			 RETURNING id`,
-				pb.Add(id), pb.Add(entity), pb.Add(scope), pb.Add(configJSON), h.store.Dialect.NowExpr()),
-			pb.Params()...)
+		err := importSavepoint(ctx, tx, func() error {
+			pb := h.store.Dialect.NewParamBuilder()
+			_, err := store.Exec(ctx, tx,
+				fmt.Sprintf(`INSERT INTO _ui_configs (id, entity, scope, config) VALUES (%s, %s, %s, %s)
+			 ON CONFLICT (entity, scope) DO UPDATE SET config = EXCLUDED.config, updated_at = %s`,
+					pb.Add(store.GenerateUUID()), pb.Add(entity), pb.Add(scope), pb.Add(configJSON), h.store.Dialect.NowExpr()),
+				pb.Params()...)
+			return err
+		})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("UI config (%v/%v): %v", entity, scope, err))
+			run.errors = append(run.errors, fmt.Sprintf("UI config (%v/%v): %v", entity, scope, err))
 			continue
 		}
-		summary["ui_configs"]++
+		run.summary["ui_configs"]++
 	}
 
-	// Final reload
-	_ = metadata.Reload(ctx, h.store.DB, h.registry)
+	// Users and pending invites
+	if includeUsers {
+		run.errors = append(run.errors, h.importUsers(ctx, tx, payload, run.summary)...)
+	}
 
-	// Step 9: Sample data (insert records into business tables)
-	if len(payload.SampleData) > 0 {
-		summary["records"] = 0
+	return run
+}
 
-		// Process entity records in definition order
-		for _, entRaw := range payload.Entities {
-			name, _ := entRaw["name"].(string)
-			entity := h.registry.GetEntity(name)
-			if entity == nil {
-				continue
-			}
-			records, ok := payload.SampleData[name]
-			if !ok || len(records) == 0 {
-				continue
-			}
-			// Build field type map from entity definition
-			fieldTypes := make(map[string]string)
-			for _, f := range entity.Fields {
-				fieldTypes[f.Name] = f.Type
-			}
-			for _, record := range records {
-				pb := h.store.Dialect.NewParamBuilder()
-				cols := make([]string, 0, len(record))
-				placeholders := make([]string, 0, len(record))
-				for key, val := range record {
-					ft, ok := fieldTypes[key]
-					if !ok {
-						continue
-					}
-					// Convert JSON float64 to proper Go types
-					if v, isFloat := val.(float64); isFloat {
-						switch ft {
-						case "integer", "int", "bigint":
-							val = int64(v)
-						}
-					}
-					cols = append(cols, `"`+key+`"`)
-					placeholders = append(placeholders, pb.Add(val))
-				}
-				if len(cols) == 0 {
+// importDefault returns v, or def when the payload left v out, so a missing
+// value gets the column's default instead of an explicit NULL.
+func importDefault(v, def any) any {
+	if v == nil {
+		return def
+	}
+	return v
+}
+
+// importRolledBack answers an atomic import that failed and was rolled back.
+// summary still counts what would have been imported.
+func importRolledBack(c *fiber.Ctx, summary map[string]int, errors []string) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"data": fiber.Map{
+		"message":   "Import rolled back",
+		"committed": false,
+		"summary":   summary,
+		"errors":    errors,
+	}})
+}
+
+// importSampleData inserts the payload's sample_data records on tx, the
+// import's transaction, into the business tables of the payload's entities
+// (looked up in entities) and join tables. Each record runs under a savepoint
+// and a failing one is reported.
+func (h *Handler) importSampleData(ctx context.Context, tx store.Querier, payload *importPayload, entities map[string]*metadata.Entity, summary map[string]int) []string {
+	var errors []string
+	summary["records"] = 0
+
+	insert := func(label, table string, cols, placeholders []string, params []any) {
+		query := fmt.Sprintf(
+			`INSERT INTO %q (%s) VALUES (%s) ON CONFLICT DO NOTHING`,
+			table, strings.Join(cols, ", "), strings.Join(placeholders, ", "),
+		)
+		err := importSavepoint(ctx, tx, func() error {
+			_, err := store.Exec(ctx, tx, query, params...)
+			return err
+		})
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Record %s: %v", label, err))
+			return
+		}
+		summary["records"]++
+	}
+
+	// Process entity records in definition order
+	for _, entRaw := range payload.Entities {
+		name, _ := entRaw["name"].(string)
+		entity := entities[name]
+		if entity == nil {
+			continue
+		}
+		records, ok := payload.SampleData[name]
+		if !ok || len(records) == 0 {
+			continue
+		}
+		// Build field type map from entity definition
+		fieldTypes := make(map[string]string)
+		for _, f := range entity.Fields {
+			fieldTypes[f.Name] = f.Type
+		}
+		for _, record := range records {
+			pb := h.store.Dialect.NewParamBuilder()
+			cols := make([]string, 0, len(record))
+			placeholders := make([]string, 0, len(record))
+			for key, val := range record {
+				ft, ok := fieldTypes[key]
+				if !ok {
 					continue
 				}
-				query := fmt.Sprintf(
-					`INSERT INTO %q (%s) VALUES (%s) ON CONFLICT DO NOTHING`,
-					entity.Table, strings.Join(cols, ", "), strings.Join(placeholders, ", "),
-				)
-				_, err := store.Exec(ctx, h.store.DB, query, pb.Params()...)
-				if err != nil {
-					errors = append(errors, fmt.Sprintf("Record %s: %v", name, err))
-					continue
+				// Convert JSON float64 to proper Go types
+				if v, isFloat := val.(float64); isFloat {
+					switch ft {
+					case "integer", "int", "bigint":
+						val = int64(v)
+					}
 				}
-				summary["records"]++
+				cols = append(cols, `"`+key+`"`)
+				placeholders = append(placeholders, pb.Add(val))
 			}
+			if len(cols) == 0 {
+				continue
+			}
+			insert(name, entity.PhysicalTable(h.store.TablePrefix), cols, placeholders, pb.Params())
 		}
+	}
 
-		// Process join table data (keys that don't match entity names)
-		for key, records := range payload.SampleData {
-			if h.registry.GetEntity(key) != nil {
-				continue // already processed above
-			}
-			if len(records) == 0 {
-				continue
+	// Process join table data (keys that don't match entity names)
+	for key, records := range payload.SampleData {
+		if entities[key] != nil {
+			continue // already processed above
+		}
+		if len(records) == 0 {
+			continue
+		}
+		// Find matching join table from payload relations
+		tableName := ""
+		var validCols map[string]bool
+		for _, rel := range payload.Relations {
+			jt, _ := rel["join_table"].(string)
+			if jt == key {
+				tableName = key
+				sjk, _ := rel["source_join_key"].(string)
+				tjk, _ := rel["target_join_key"].(string)
+				validCols = map[string]bool{sjk: true, tjk: true}
+				break
 			}
-			// Find matching join table from payload relations
-			tableName := ""
-			var validCols map[string]bool
-			for _, rel := range payload.Relations {
-				jt, _ := rel["join_table"].(string)
-				if jt == key {
-					tableName = key
-					sjk, _ := rel["source_join_key"].(string)
-					tjk, _ := rel["target_join_key"].(string)
-					validCols = map[string]bool{sjk: true, tjk: true}
-					break
+		}
+		if tableName == "" {
+			continue
+		}
+		for _, record := range records {
+			pb := h.store.Dialect.NewParamBuilder()
+			cols := make([]string, 0, len(record))
+			placeholders := make([]string, 0, len(record))
+			for k, v := range record {
+				if !validCols[k] {
+					continue
 				}
+				cols = append(cols, `"`+k+`"`)
+				placeholders = append(placeholders, pb.Add(v))
 			}
-			if tableName == "" {
+			if len(cols) == 0 {
 				continue
 			}
-			for _, record := range records {
-				pb := h.store.Dialect.NewParamBuilder()
-				cols := make([]string, 0, len(record))
-				placeholders := make([]string, 0, len(record))
-				for k, v := range record {
-					if !validCols[k] {
-						continue
-					}
-					cols = append(cols, `"`+k+`"`)
-					placeholders = append(placeholders, pb.Add(v))
-				}
-				if len(cols) == 0 {
-					continue
-				}
-				query := fmt.Sprintf(
-					`INSERT INTO %q (%s) VALUES (%s) ON CONFLICT DO NOTHING`,
-					tableName, strings.Join(cols, ", "), strings.Join(placeholders, ", "),
-				)
-				_, err := store.Exec(ctx, h.store.DB, query, pb.Params()...)
-				if err != nil {
-					errors = append(errors, fmt.Sprintf("Record %s: %v", key, err))
-					continue
-				}
-				summary["records"]++
-			}
+			insert(key, tableName, cols, placeholders, pb.Params())
 		}
 	}
 
-	result := fiber.Map{
-		"message": "Import completed",
-		"summary": summary,
-	}
-	if len(errors) > 0 {
-		result["errors"] = errors
-	}
-	return c.JSON(fiber.Map{"data": result})
+	return errors
 }
 
 func validateRelation(r *metadata.Relation, reg *metadata.Registry) error {
@@ -2453,5 +4084,19 @@ func validateRelation(r *metadata.Relation, reg *metadata.Registry) error {
 	if r.IsManyToMany() && r.JoinTable == "" {
 		return fmt.Errorf("join_table is required for many_to_many relations")
 	}
+	if r.Soft && (r.IsManyToMany() || r.TargetKey == "") {
+		return fmt.Errorf("soft applies to one_to_one and one_to_many relations with a target_key")
+	}
+	for _, f := range reg.GetEntity(r.Source).Fields {
+		if f.Rollup == nil || f.Rollup.Relation != r.Name {
+			continue
+		}
+		if r.IsManyToMany() {
+			return fmt.Errorf("rollup field %s.%s can't aggregate over a many_to_many relation", r.Source, f.Name)
+		}
+		if err := validateRollupField(f, reg.GetEntity(r.Target)); err != nil {
+			return fmt.Errorf("rollup on %s: %w", r.Source, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,1378 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/auth"
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func testAdminApp(t *testing.T) (*fiber.App, *metadata.Registry) {
+	t.Helper()
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	reg := metadata.NewRegistry()
+	app := fiber.New()
+	RegisterAdminRoutes(app, NewHandler(s, reg, store.NewMigrator(s)))
+	return app, reg
+}
+
+func doJSON(t *testing.T, app *fiber.App, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	var out map[string]any
+	_ = json.Unmarshal(raw, &out)
+	return resp.StatusCode, out
+}
+
+func TestToggleRule_DisablesAndRestoresEnforcement(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	status, out := doJSON(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name":        "invoice",
+		"table":       "invoices",
+		"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+		"fields": []map[string]any{
+			{"name": "id", "type": "uuid"},
+			{"name": "total", "type": "decimal"},
+		},
+	})
+	if status != 201 {
+		t.Fatalf("create entity: %d %v", status, out)
+	}
+
+	status, out = doJSON(t, app, "POST", "/api/_admin/rules", map[string]any{
+		"entity": "invoice",
+		"hook":   "before_write",
+		"type":   "field",
+		"active": true,
+		"definition": map[string]any{
+			"field": "total", "operator": "min", "value": 0, "message": "Total must be non-negative",
+		},
+	})
+	if status != 201 {
+		t.Fatalf("create rule: %d %v", status, out)
+	}
+	ruleID := out["data"].(map[string]any)["id"].(string)
+
+	violations := func() int {
+		return len(engine.EvaluateRules(context.Background(), reg, "invoice", "before_write",
+			map[string]any{"total": float64(-5)}, map[string]any{}, true))
+	}
+	if violations() != 1 {
+		t.Fatal("expected active rule to reject negative total")
+	}
+
+	status, out = doJSON(t, app, "POST", "/api/_admin/rules/"+ruleID+"/toggle", nil)
+	if status != 200 || out["data"].(map[string]any)["active"] != false {
+		t.Fatalf("toggle off: %d %v", status, out)
+	}
+	if violations() != 0 {
+		t.Fatal("expected disabled rule to stop enforcing")
+	}
+
+	status, out = doJSON(t, app, "POST", "/api/_admin/rules/"+ruleID+"/toggle", map[string]any{"active": true})
+	if status != 200 || out["data"].(map[string]any)["active"] != true {
+		t.Fatalf("toggle on: %d %v", status, out)
+	}
+	if violations() != 1 {
+		t.Fatal("expected re-enabled rule to enforce again")
+	}
+
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/rules/missing/toggle", nil); status != 404 {
+		t.Fatalf("expected 404 for unknown rule, got %d", status)
+	}
+}
+
+func TestCreateRule_AfterHooksOnlyAcceptComputedRules(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	status, out := doJSON(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name":        "invoice",
+		"table":       "invoices",
+		"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+		"fields": []map[string]any{
+			{"name": "id", "type": "uuid"},
+			{"name": "total", "type": "decimal"},
+			{"name": "total_label", "type": "string"},
+		},
+	})
+	if status != 201 {
+		t.Fatalf("create entity: %d %v", status, out)
+	}
+
+	for _, tc := range []struct {
+		hook, typ, field string
+		want             int
+	}{
+		{"after_write", "computed", "total_label", 201},
+		{"after_write", "expression", "total_label", 422},
+		{"after_write", "computed", "missing", 422},
+		{"after_delete", "computed", "total_label", 422},
+		{"after_commit", "computed", "total_label", 422},
+	} {
+		status, out := doJSON(t, app, "POST", "/api/_admin/rules", map[string]any{
+			"entity": "invoice",
+			"hook":   tc.hook,
+			"type":   tc.typ,
+			"active": true,
+			"definition": map[string]any{
+				"field": tc.field, "expression": "string(record.total)",
+			},
+		})
+		if status != tc.want {
+			t.Errorf("%s %s rule on %q: expected %d, got %d %v", tc.hook, tc.typ, tc.field, tc.want, status, out)
+		}
+	}
+}
+
+func TestWebhookSecret_NeverReturned(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	hasSecret := func(label string, row map[string]any, want bool) {
+		t.Helper()
+		if _, leaked := row["secret"]; leaked {
+			t.Fatalf("%s: expected the secret to be withheld, got %v", label, row)
+		}
+		if row["has_secret"] != want {
+			t.Fatalf("%s: expected has_secret=%v, got %v", label, want, row["has_secret"])
+		}
+	}
+
+	webhook := map[string]any{
+		"entity": "order", "hook": "after_write", "url": "https://example.com/hook", "method": "POST",
+		"async": true, "active": true, "condition": "", "secret": "whsec_123",
+	}
+	status, out := doJSON(t, app, "POST", "/api/_admin/webhooks", webhook)
+	if status != 201 {
+		t.Fatalf("create webhook: %d %v", status, out)
+	}
+	created := out["data"].(map[string]any)
+	hasSecret("create", created, true)
+	id := created["id"].(string)
+
+	_, out = doJSON(t, app, "GET", "/api/_admin/webhooks/"+id, nil)
+	hasSecret("get", out["data"].(map[string]any), true)
+	_, out = doJSON(t, app, "GET", "/api/_admin/webhooks", nil)
+	hasSecret("list", out["data"].([]any)[0].(map[string]any), true)
+
+	// Leaving the secret out of an update keeps it; an empty one removes it
+	delete(webhook, "secret")
+	webhook["url"] = "https://example.com/hook2"
+	status, out = doJSON(t, app, "PUT", "/api/_admin/webhooks/"+id, webhook)
+	if status != 200 {
+		t.Fatalf("update webhook: %d %v", status, out)
+	}
+	hasSecret("update without secret", out["data"].(map[string]any), true)
+
+	webhook["secret"] = ""
+	_, out = doJSON(t, app, "PUT", "/api/_admin/webhooks/"+id, webhook)
+	hasSecret("update clearing secret", out["data"].(map[string]any), false)
+
+	webhook["secret"] = 42
+	if status, _ := doJSON(t, app, "PUT", "/api/_admin/webhooks/"+id, webhook); status != 422 {
+		t.Fatalf("expected 422 for a non-string secret, got %d", status)
+	}
+}
+
+func TestWebhook_RejectsUnregisteredAndPlaintextTransports(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	create := func(transport, url string, config map[string]any) (int, map[string]any) {
+		t.Helper()
+		return doJSON(t, app, "POST", "/api/_admin/webhooks", map[string]any{
+			"entity": "order", "url": url, "transport": transport, "transport_config": config,
+		})
+	}
+	if status, out := create("kafka", "broker.example.com:9092", map[string]any{"topic": "orders"}); status != 422 ||
+		!strings.Contains(out["error"].(map[string]any)["message"].(string), "no kafka transport is registered") {
+		t.Fatalf("expected an unregistered kafka transport to be rejected, got %d %v", status, out)
+	}
+	if status, _ := create("nats", "nats://queue.example.com", map[string]any{"subject": "orders"}); status != 422 {
+		t.Fatalf("expected a plaintext nats url to be rejected, got %d", status)
+	}
+	if status, out := create("nats", "tls://queue.example.com", map[string]any{"subject": "orders"}); status != 201 {
+		t.Fatalf("expected a tls nats url to be accepted, got %d %v", status, out)
+	}
+	if status, _ := create("nats", "tls://queue.example.com", map[string]any{"subject": "orders 0\r\nPUB evil"}); status != 422 {
+		t.Fatalf("expected a nats subject with a protocol break to be rejected, got %d", status)
+	}
+	status, _ := doJSON(t, app, "POST", "/api/_admin/webhooks", map[string]any{
+		"entity": "order", "url": "tls://queue.example.com", "transport": "nats",
+		"transport_config": map[string]any{"subject": "orders"},
+		"headers":          map[string]any{"X-Source": "rocket\r\nPUB evil 0"},
+	})
+	if status != 422 {
+		t.Fatalf("expected a nats header value with CR/LF to be rejected, got %d", status)
+	}
+}
+
+func TestRelationGraph_SelfReferenceAndManyToMany(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	pk := metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true}
+	reg.Load([]*metadata.Entity{
+		{Name: "employee", Table: "employees", PrimaryKey: pk, Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"}, {Name: "manager_id", Type: "uuid", Nullable: true},
+		}},
+		{Name: "post", Table: "posts", PrimaryKey: pk, Fields: []metadata.Field{{Name: "id", Type: "uuid"}}},
+		{Name: "tag", Table: "tags", PrimaryKey: pk, Fields: []metadata.Field{{Name: "id", Type: "uuid"}}},
+	}, []*metadata.Relation{
+		{Name: "reports", Type: "one_to_many", Source: "employee", Target: "employee",
+			SourceKey: "id", TargetKey: "manager_id", Ownership: "none", OnDelete: "set_null"},
+		{Name: "post_tags", Type: "many_to_many", Source: "post", Target: "tag", SourceKey: "id",
+			JoinTable: "post_tags", SourceJoinKey: "post_id", TargetJoinKey: "tag_id", Ownership: "none", OnDelete: "detach"},
+	})
+
+	status, out := doJSON(t, app, "GET", "/api/_admin/relations/graph", nil)
+	if status != 200 {
+		t.Fatalf("graph: %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+
+	nodes := data["nodes"].([]any)
+	if len(nodes) != 3 || nodes[0].(map[string]any)["id"] != "employee" {
+		t.Fatalf("expected 3 nodes sorted by name, got %v", nodes)
+	}
+	idField := nodes[0].(map[string]any)["fields"].([]any)[0].(map[string]any)
+	if idField["primary_key"] != true {
+		t.Fatalf("expected id to be flagged as primary key, got %v", idField)
+	}
+
+	edges := data["edges"].([]any)
+	if len(edges) != 2 {
+		t.Fatalf("expected 2 edges, got %v", edges)
+	}
+	m2m, self := edges[0].(map[string]any), edges[1].(map[string]any)
+	if m2m["join_table"] != "post_tags" || m2m["source_join_key"] != "post_id" || m2m["target_join_key"] != "tag_id" {
+		t.Fatalf("expected join info on many_to_many edge, got %v", m2m)
+	}
+	if self["source"] != "employee" || self["target"] != "employee" || self["self_reference"] != true {
+		t.Fatalf("expected self-referential edge, got %v", self)
+	}
+	if _, ok := self["join_table"]; ok {
+		t.Fatalf("expected no join info on one_to_many edge, got %v", self)
+	}
+}
+
+func TestRollup_FieldMustBeNumericFieldOfTarget(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	pk := metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true}
+	rollup := func(field string) metadata.Field {
+		return metadata.Field{Name: "total", Type: "rollup", Rollup: &metadata.RollupConfig{Relation: "lines", Aggregate: "sum", Field: field}}
+	}
+	order := &metadata.Entity{Name: "order", Table: "orders", PrimaryKey: pk, Fields: []metadata.Field{{Name: "id", Type: "uuid"}}}
+	line := &metadata.Entity{Name: "line", Table: "order_lines", PrimaryKey: pk, Fields: []metadata.Field{
+		{Name: "id", Type: "uuid"}, {Name: "order_id", Type: "uuid"},
+		{Name: "amount", Type: "float"}, {Name: "note", Type: "string"},
+	}}
+	lines := &metadata.Relation{Name: "lines", Type: "one_to_many", Source: "order", Target: "line",
+		SourceKey: "id", TargetKey: "order_id", Ownership: "source", OnDelete: "cascade"}
+	reg.Load([]*metadata.Entity{order, line}, []*metadata.Relation{lines})
+
+	// Adding the rollup to an entity whose relation already exists
+	for field, want := range map[string]string{"missing": "not found on line", "note": "needs a numeric field"} {
+		body := map[string]any{"name": "order", "table": "orders", "primary_key": pk,
+			"fields": []metadata.Field{{Name: "id", Type: "uuid"}, rollup(field)}}
+		status, out := doJSON(t, app, "PUT", "/api/_admin/entities/order", body)
+		if status != 422 || !strings.Contains(out["error"].(map[string]any)["message"].(string), want) {
+			t.Fatalf("rollup.field %q: expected a 422 saying %q, got %d %v", field, want, status, out)
+		}
+	}
+
+	// Creating the relation a rollup already names
+	withRollup := *order
+	withRollup.Fields = []metadata.Field{{Name: "id", Type: "uuid"}, rollup("missing")}
+	reg.Load([]*metadata.Entity{&withRollup, line}, nil)
+	status, out := doJSON(t, app, "POST", "/api/_admin/relations", lines)
+	if status != 422 || !strings.Contains(out["error"].(map[string]any)["message"].(string), "not found on line") {
+		t.Fatalf("expected the relation to be rejected, got %d %v", status, out)
+	}
+
+	withRollup.Fields = []metadata.Field{{Name: "id", Type: "uuid"}, rollup("amount")}
+	reg.Load([]*metadata.Entity{&withRollup, line}, nil)
+	if err := validateRelation(lines, reg); err != nil {
+		t.Fatalf("expected a rollup over a numeric field to pass, got %v", err)
+	}
+}
+
+func TestSettings_RoundTripAndPublicRead(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	status, out := doJSON(t, app, "PUT", "/api/_admin/settings/branding", map[string]any{
+		"value":  map[string]any{"name": "Acme", "color": "#f00"},
+		"public": true,
+	})
+	if status != 200 {
+		t.Fatalf("put branding: %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "PUT", "/api/_admin/settings/limits.max_seats", map[string]any{"value": 25}); status != 200 {
+		t.Fatalf("put limits: %d %v", status, out)
+	}
+	if status, _ := doJSON(t, app, "PUT", "/api/_admin/settings/Bad Key", map[string]any{"value": 1}); status != 422 {
+		t.Fatalf("expected an invalid key to be rejected, got %d", status)
+	}
+
+	status, out = doJSON(t, app, "GET", "/api/_admin/settings/limits.max_seats", nil)
+	if status != 200 || out["data"].(map[string]any)["value"] != float64(25) {
+		t.Fatalf("expected the value to round-trip, got %d %v", status, out)
+	}
+
+	_, out = doJSON(t, app, "GET", "/api/settings/public", nil)
+	public := out["data"].(map[string]any)
+	if _, leaked := public["limits.max_seats"]; leaked {
+		t.Fatalf("expected private settings to stay hidden, got %v", public)
+	}
+	if branding, _ := public["branding"].(map[string]any); branding["name"] != "Acme" {
+		t.Fatalf("expected the public branding setting, got %v", public)
+	}
+
+	// Writes invalidate the cache: updates and deletes show up immediately
+	doJSON(t, app, "PUT", "/api/_admin/settings/branding", map[string]any{"value": map[string]any{"name": "Acme"}, "public": false})
+	doJSON(t, app, "PUT", "/api/_admin/settings/limits.max_seats", map[string]any{"value": 50, "public": true})
+	_, out = doJSON(t, app, "GET", "/api/settings/public", nil)
+	public = out["data"].(map[string]any)
+	if _, ok := public["branding"]; ok || public["limits.max_seats"] != float64(50) {
+		t.Fatalf("expected the cache to reflect the updates, got %v", public)
+	}
+	if status, _ := doJSON(t, app, "DELETE", "/api/_admin/settings/limits.max_seats", nil); status != 200 {
+		t.Fatalf("delete: %d", status)
+	}
+	if status, _ := doJSON(t, app, "GET", "/api/_admin/settings/limits.max_seats", nil); status != 404 {
+		t.Fatalf("expected the deleted setting to be gone, got %d", status)
+	}
+}
+
+func TestBulkCreatePermissions_CrossProductWithDedup(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	for _, name := range []string{"customer", "invoice", "product"} {
+		status, out := doJSON(t, app, "POST", "/api/_admin/entities", map[string]any{
+			"name":        name,
+			"table":       name + "s",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}},
+		})
+		if status != 201 {
+			t.Fatalf("create entity %s: %d %v", name, status, out)
+		}
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/roles", map[string]any{"name": "accountant"}); status != 201 {
+		t.Fatalf("create role: %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/roles", map[string]any{"name": "auditor"}); status != 201 {
+		t.Fatalf("create role: %d %v", status, out)
+	}
+	// An existing grant to the role is left alone
+	if status, out := doJSON(t, app, "POST", "/api/_admin/permissions", map[string]any{
+		"entity": "invoice", "action": "read", "roles": []string{"accountant", "auditor"},
+	}); status != 201 {
+		t.Fatalf("create permission: %d %v", status, out)
+	}
+
+	status, out := doJSON(t, app, "POST", "/api/_admin/permissions/bulk", map[string]any{
+		"role":     "accountant",
+		"entities": []string{"customer", "invoice", "product"},
+		"actions":  []string{"read", "create"},
+	})
+	if status != 200 {
+		t.Fatalf("bulk create: %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+	summary := data["summary"].(map[string]any)
+	if summary["total"] != float64(6) || summary["created"] != float64(5) || summary["skipped"] != float64(1) {
+		t.Fatalf("expected 5 created and 1 skipped of 6, got %v", summary)
+	}
+	if skipped := data["skipped"].([]any)[0].(map[string]any); skipped["entity"] != "invoice" || skipped["action"] != "read" {
+		t.Fatalf("expected invoice/read to be skipped, got %v", skipped)
+	}
+	user := &metadata.UserContext{ID: "u1", Roles: []string{"accountant"}}
+	for _, entity := range []string{"customer", "invoice", "product"} {
+		for _, action := range []string{"read", "create"} {
+			if err := engine.CheckPermission(context.Background(), user, entity, action, reg, nil); err != nil {
+				t.Fatalf("expected accountant to be allowed %s on %s: %v", action, entity, err)
+			}
+		}
+	}
+
+	// Re-running is a no-op; conditions make a distinct grant
+	_, out = doJSON(t, app, "POST", "/api/_admin/permissions/bulk", map[string]any{
+		"role": "accountant", "entities": []string{"customer", "invoice", "product"}, "actions": []string{"read", "create"},
+	})
+	if created := out["data"].(map[string]any)["summary"].(map[string]any)["created"]; created != float64(0) {
+		t.Fatalf("expected a repeat call to create nothing, got %v", out)
+	}
+	_, out = doJSON(t, app, "POST", "/api/_admin/permissions/bulk", map[string]any{
+		"role": "accountant", "entities": []string{"invoice"}, "actions": []string{"update"},
+		"conditions": []map[string]any{{"field": "status", "operator": "eq", "value": "draft"}},
+	})
+	if created := out["data"].(map[string]any)["summary"].(map[string]any)["created"]; created != float64(1) {
+		t.Fatalf("expected the conditional grant to be created, got %v", out)
+	}
+
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/permissions/bulk", map[string]any{
+		"role": "accountant", "entities": []string{"missing"}, "actions": []string{"read"},
+	}); status != 422 {
+		t.Fatalf("expected an unknown entity to be rejected, got %d", status)
+	}
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/permissions/bulk", map[string]any{
+		"role": "accountant", "entities": []string{"invoice"}, "actions": []string{"publish"},
+	}); status != 422 {
+		t.Fatalf("expected an unknown action to be rejected, got %d", status)
+	}
+}
+
+func TestListUsers_InactiveSinceUsesLastLogin(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{AdminEmail: "active@x.com", AdminPassword: "secret123"}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	hash, _ := auth.HashPassword("secret123")
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf("INSERT INTO _users (id, email, password_hash, last_login_at) VALUES (%s, 'dormant@x.com', %s, '2020-01-01 00:00:00')",
+			pb.Add(store.GenerateUUID()), pb.Add(hash)),
+		pb.Params()...); err != nil {
+		t.Fatalf("insert dormant user: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/api/auth/login", auth.NewAuthHandler(s, auth.TokenKeys{Secret: "secret"}).Login)
+	RegisterAdminRoutes(app, NewHandler(s, metadata.NewRegistry(), store.NewMigrator(s)))
+
+	if status, out := doJSON(t, app, "POST", "/api/auth/login", map[string]any{"email": "active@x.com", "password": "secret123"}); status != 200 {
+		t.Fatalf("login: %d %v", status, out)
+	}
+	// The timestamp is written in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT last_login_at FROM _users WHERE email = 'active@x.com'")
+		if err == nil && row["last_login_at"] != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected login to record last_login_at")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	emails := func(query string) []string {
+		status, out := doJSON(t, app, "GET", "/api/_admin/users"+query, nil)
+		if status != 200 {
+			t.Fatalf("list users%s: %d %v", query, status, out)
+		}
+		var got []string
+		for _, u := range out["data"].([]any) {
+			got = append(got, u.(map[string]any)["email"].(string))
+		}
+		return got
+	}
+	if got := emails("?inactive_since=" + time.Now().AddDate(0, 0, -30).Format("2006-01-02")); len(got) != 1 || got[0] != "dormant@x.com" {
+		t.Fatalf("expected only the dormant user, got %v", got)
+	}
+	if got := emails(""); len(got) != 2 {
+		t.Fatalf("expected the unfiltered list to have both users, got %v", got)
+	}
+	if status, _ := doJSON(t, app, "GET", "/api/_admin/users?inactive_since=last-week", nil); status != 422 {
+		t.Fatalf("expected an invalid cutoff to be rejected, got %d", status)
+	}
+}
+
+func TestUsers_EmailsMatchRegardlessOfCase(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/api/auth/login", auth.NewAuthHandler(s, auth.TokenKeys{Secret: "secret"}).Login)
+	RegisterAdminRoutes(app, NewHandler(s, metadata.NewRegistry(), store.NewMigrator(s)))
+
+	status, out := doJSON(t, app, "POST", "/api/_admin/users", map[string]any{"email": " Jane.Doe@Example.com", "password": "secret123"})
+	if status != 201 || out["data"].(map[string]any)["email"] != "jane.doe@example.com" {
+		t.Fatalf("create: expected the email stored trimmed and lowercased, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/auth/login", map[string]any{"email": "JANE.DOE@example.COM", "password": "secret123"}); status != 200 {
+		t.Fatalf("login with a differently-cased email: %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/users", map[string]any{"email": "jane.doe@EXAMPLE.com", "password": "secret123"}); status != 409 {
+		t.Fatalf("expected a mixed-case duplicate to be rejected, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/invites", map[string]any{"email": "Jane.Doe@example.com"}); status != 409 {
+		t.Fatalf("expected inviting an existing user to be rejected, got %d %v", status, out)
+	}
+
+	// The index catches duplicates written without normalization too
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf("INSERT INTO _users (id, email, password_hash) VALUES (%s, 'Jane.Doe@Example.com', 'x')", pb.Add(store.GenerateUUID())),
+		pb.Params()...); err == nil {
+		t.Fatal("expected the case-insensitive unique index to reject the insert")
+	}
+}
+
+func TestPagination_AdminAndAPIUseTheirOwnPageSizes(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	defer func(d, m, ad, am int) {
+		engine.DefaultPerPage, engine.MaxPerPage, DefaultPerPage, MaxPerPage = d, m, ad, am
+	}(engine.DefaultPerPage, engine.MaxPerPage, DefaultPerPage, MaxPerPage)
+	engine.DefaultPerPage, engine.MaxPerPage = 2, 3
+	DefaultPerPage, MaxPerPage = 4, 5
+
+	note := &metadata.Entity{
+		Name:       "note",
+		Table:      "notes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "body", Type: "string"}},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, note); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{note}, nil)
+	for i := 0; i < 6; i++ {
+		pb := s.Dialect.NewParamBuilder()
+		if _, err := store.Exec(ctx, s.DB,
+			fmt.Sprintf("INSERT INTO notes (id, body) VALUES (%s, 'n')", pb.Add(store.GenerateUUID())), pb.Params()...); err != nil {
+			t.Fatalf("insert note: %v", err)
+		}
+		pb = s.Dialect.NewParamBuilder()
+		if _, err := store.Exec(ctx, s.DB,
+			fmt.Sprintf("INSERT INTO _audit_log (id, entity, record_id, action) VALUES (%s, 'note', 'n1', 'update')", pb.Add(store.GenerateUUID())), pb.Params()...); err != nil {
+			t.Fatalf("insert audit entry: %v", err)
+		}
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	RegisterAdminRoutes(app, NewHandler(s, reg, store.NewMigrator(s)))
+	app.Get("/api/:entity", engine.NewHandler(s, reg).List)
+
+	for _, tc := range []struct {
+		path    string
+		perPage int
+	}{
+		{"/api/note", 2},
+		{"/api/note?per_page=50", 3},
+		{"/api/_admin/audit-log", 4},
+		{"/api/_admin/audit-log?per_page=50", 5},
+		{"/api/_admin/audit-log?per_page=4&page=2", 2},
+	} {
+		status, out := doJSON(t, app, "GET", tc.path, nil)
+		if status != 200 {
+			t.Fatalf("GET %s: %d %v", tc.path, status, out)
+		}
+		meta := out["meta"].(map[string]any)
+		if got := len(out["data"].([]any)); got != tc.perPage || meta["total"] != float64(6) {
+			t.Fatalf("GET %s: expected %d of 6 rows, got %d (meta %v)", tc.path, tc.perPage, got, meta)
+		}
+	}
+}
+
+func TestListRules_FiltersByTag(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	status, out := doJSON(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name":        "invoice",
+		"table":       "invoices",
+		"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+		"fields": []map[string]any{
+			{"name": "id", "type": "uuid"},
+			{"name": "total", "type": "decimal"},
+			{"name": "tax", "type": "decimal"},
+		},
+	})
+	if status != 201 {
+		t.Fatalf("create entity: %d %v", status, out)
+	}
+
+	createRule := func(field string, tags []string) {
+		t.Helper()
+		status, out := doJSON(t, app, "POST", "/api/_admin/rules", map[string]any{
+			"entity": "invoice", "hook": "before_write", "type": "field", "active": true, "tags": tags,
+			"definition": map[string]any{"field": field, "operator": "min", "value": 0, "message": field + " must be non-negative"},
+		})
+		if status != 201 {
+			t.Fatalf("create rule: %d %v", status, out)
+		}
+	}
+	createRule("total", []string{"billing", " compliance ", "billing"})
+	createRule("tax", []string{"compliance"})
+	createRule("tax", nil)
+
+	listTags := func(path string) [][]any {
+		t.Helper()
+		status, out := doJSON(t, app, "GET", path, nil)
+		if status != 200 {
+			t.Fatalf("GET %s: %d %v", path, status, out)
+		}
+		var tags [][]any
+		for _, r := range out["data"].([]any) {
+			tags = append(tags, r.(map[string]any)["tags"].([]any))
+		}
+		return tags
+	}
+
+	if got := listTags("/api/_admin/rules"); len(got) != 3 {
+		t.Fatalf("expected 3 rules unfiltered, got %v", got)
+	}
+	if got := listTags("/api/_admin/rules?tag=compliance"); len(got) != 2 {
+		t.Fatalf("expected 2 compliance rules, got %v", got)
+	}
+	got := listTags("/api/_admin/rules?tag=billing")
+	if len(got) != 1 || fmt.Sprint(got[0]) != "[billing compliance]" {
+		t.Fatalf("expected the billing rule with trimmed, deduplicated tags, got %v", got)
+	}
+	if got := listTags("/api/_admin/rules?tag=missing"); len(got) != 0 {
+		t.Fatalf("expected no rules for an unused tag, got %v", got)
+	}
+
+	for _, r := range reg.GetRulesForEntity("invoice", "before_write") {
+		if r.Definition.Field == "total" && fmt.Sprint(r.Tags) != "[billing compliance]" {
+			t.Fatalf("expected tags loaded into the registry, got %v", r.Tags)
+		}
+	}
+}
+
+func TestBulkCreateInvites_StatusReflectsPartialSuccess(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	invite := func(emails ...string) (int, map[string]any) {
+		t.Helper()
+		status, out := doJSON(t, app, "POST", "/api/_admin/invites/bulk", map[string]any{"emails": emails})
+		return status, out["data"].(map[string]any)["summary"].(map[string]any)
+	}
+
+	if status, summary := invite("a@example.com", "b@example.com"); status != 200 || summary["created"] != float64(2) {
+		t.Fatalf("expected 200 with every invite created, got %d %v", status, summary)
+	}
+	// b already has a pending invite
+	if status, summary := invite("b@example.com", "c@example.com"); status != 200 || summary["created"] != float64(1) || summary["skipped"] != float64(1) {
+		t.Fatalf("expected 200 for a mix of created and skipped, got %d %v", status, summary)
+	}
+	// Skipping is not failing: a batch with nothing left to invite is a 200
+	if status, summary := invite("a@example.com", "c@example.com"); status != 200 || summary["skipped"] != float64(2) || summary["failed"] != float64(0) {
+		t.Fatalf("expected 200 when every invite is skipped, got %d %v", status, summary)
+	}
+}
+
+func TestImport_StatusReflectsPartialSuccess(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	entity := func(name string) map[string]any {
+		return map[string]any{
+			"name":        name,
+			"table":       name + "s",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}},
+		}
+	}
+	// A rule without an entity or hook can't be stored
+	badRule := map[string]any{"type": "field", "definition": map[string]any{"field": "id", "operator": "min", "value": 1}}
+
+	importPayload := func(payload map[string]any) (int, map[string]any) {
+		t.Helper()
+		payload["version"] = 1
+		status, out := doJSON(t, app, "POST", "/api/_admin/import", payload)
+		return status, out["data"].(map[string]any)
+	}
+
+	if status, data := importPayload(map[string]any{"entities": []any{entity("widget")}}); status != 200 || data["errors"] != nil {
+		t.Fatalf("expected 200 for a clean import, got %d %v", status, data)
+	}
+	status, data := importPayload(map[string]any{"entities": []any{entity("gadget")}, "rules": []any{badRule}})
+	if status != 207 || data["summary"].(map[string]any)["entities"] != float64(1) || len(data["errors"].([]any)) != 1 {
+		t.Fatalf("expected 207 with the entity imported and the rule reported, got %d %v", status, data)
+	}
+	if status, data := importPayload(map[string]any{"rules": []any{badRule}}); status != 422 || len(data["errors"].([]any)) != 1 {
+		t.Fatalf("expected 422 when nothing imports, got %d %v", status, data)
+	}
+}
+
+func TestImport_ValidateOnlyReportsErrorsWithoutWriting(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	payload := map[string]any{
+		"version": 1,
+		"entities": []any{map[string]any{
+			"name":        "ticket",
+			"table":       "tickets",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}, {"name": "status", "type": "string"}},
+		}},
+		// References the payload's own entity, so it's valid
+		"rules": []any{map[string]any{"entity": "ticket", "hook": "before_write", "type": "field",
+			"definition": map[string]any{"field": "status", "operator": "min_length", "value": 1}}},
+		"workflows": []any{map[string]any{
+			"name":    "escalate",
+			"trigger": map[string]any{"type": "state_change", "entity": "ticket", "field": "status", "to": "open"},
+			"steps":   []any{map[string]any{"id": "s1", "type": "teleport"}},
+		}},
+	}
+	status, out := doJSON(t, app, "POST", "/api/_admin/import?validate_only=true", payload)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+	if data["valid"] != false {
+		t.Fatalf("expected the payload to be reported invalid, got %v", data)
+	}
+	report := data["errors"].(map[string]any)
+	for _, section := range []string{"entities", "relations", "rules", "permissions"} {
+		if issues := report[section].([]any); len(issues) != 0 {
+			t.Fatalf("expected no %s errors, got %v", section, issues)
+		}
+	}
+	issues := report["workflows"].([]any)
+	if len(issues) != 1 {
+		t.Fatalf("expected one workflow error, got %v", issues)
+	}
+	issue := issues[0].(map[string]any)
+	if issue["name"] != "escalate" || !strings.Contains(issue["message"].(string), "invalid step type: teleport") {
+		t.Fatalf("unexpected workflow error %v", issue)
+	}
+
+	// Nothing was written
+	if reg.GetEntity("ticket") != nil {
+		t.Fatal("expected validate_only not to create the entity")
+	}
+	for _, path := range []string{"/api/_admin/entities", "/api/_admin/rules", "/api/_admin/workflows"} {
+		if _, out := doJSON(t, app, "GET", path, nil); len(out["data"].([]any)) != 0 {
+			t.Fatalf("expected %s to be empty after validate_only, got %v", path, out["data"])
+		}
+	}
+}
+
+func TestImport_DryRunSummarizesWithoutWriting(t *testing.T) {
+	app, reg := testAdminApp(t)
+
+	entity := func(name string) map[string]any {
+		return map[string]any{
+			"name":        name,
+			"table":       name + "s",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}, {"name": "title", "type": "string"}, {"name": "project_id", "type": "uuid", "nullable": true}},
+		}
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/import", map[string]any{"version": 1, "entities": []any{entity("project")}}); status != 200 {
+		t.Fatalf("seed import: %d %v", status, out)
+	}
+
+	payload := map[string]any{
+		"version": 1,
+		// project exists and is skipped; task is new
+		"entities": []any{entity("project"), entity("task")},
+		// Refers to the payload's own entity, resolved as in the real import
+		"relations": []any{map[string]any{"name": "tasks", "type": "one_to_many", "source": "project", "target": "task",
+			"source_key": "id", "target_key": "project_id", "ownership": "source", "on_delete": "cascade"}},
+		"rules": []any{
+			map[string]any{"entity": "task", "hook": "before_write", "type": "field",
+				"definition": map[string]any{"field": "title", "operator": "min_length", "value": 1}},
+			map[string]any{"entity": "task", "hook": "before_write", "type": "telepathy", "definition": map[string]any{}},
+		},
+		"sample_data": map[string]any{"task": []any{map[string]any{"title": "a"}, map[string]any{"title": "b"}}},
+	}
+	status, out := doJSON(t, app, "POST", "/api/_admin/import?dry_run=true", payload)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+	summary := data["summary"].(map[string]any)
+	if data["dry_run"] != true || data["valid"] != false ||
+		summary["entities"] != float64(1) || summary["relations"] != float64(1) || summary["rules"] != float64(1) || summary["records"] != float64(2) {
+		t.Fatalf("unexpected dry run result %v", data)
+	}
+	errs := data["errors"].([]any)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].(string), "Rule (task/before_write)") {
+		t.Fatalf("expected the unknown rule type reported, got %v", errs)
+	}
+
+	// Nothing was written
+	if reg.GetEntity("task") != nil || reg.GetRelation("tasks") != nil {
+		t.Fatal("expected dry_run not to create the entity or relation")
+	}
+	if _, out := doJSON(t, app, "GET", "/api/_admin/rules", nil); len(out["data"].([]any)) != 0 {
+		t.Fatalf("expected no rules after dry_run, got %v", out["data"])
+	}
+
+	// Without the invalid rule, the real import stores what the dry run counted
+	payload["rules"] = payload["rules"].([]any)[:1]
+	status, out = doJSON(t, app, "POST", "/api/_admin/import", payload)
+	if status != 200 {
+		t.Fatalf("import: %d %v", status, out)
+	}
+	for section, n := range summary {
+		if got := out["data"].(map[string]any)["summary"].(map[string]any)[section]; got != n {
+			t.Fatalf("%s: dry run counted %v, import stored %v", section, n, got)
+		}
+	}
+}
+
+func TestImport_AtomicRollsBackEverything(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	app := fiber.New()
+	RegisterAdminRoutes(app, NewHandler(s, reg, store.NewMigrator(s)))
+
+	payload := func(rules ...any) map[string]any {
+		return map[string]any{
+			"version": 1,
+			"entities": []any{map[string]any{
+				"name":        "task",
+				"table":       "tasks",
+				"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+				"fields":      []map[string]any{{"name": "id", "type": "uuid"}, {"name": "title", "type": "string", "required": true}},
+			}},
+			"rules":      rules,
+			"ui_configs": []any{map[string]any{"entity": "task", "config": map[string]any{"list": []any{"title"}}}},
+			// The second record breaks title's NOT NULL once the table exists
+			"sample_data": map[string]any{"task": []any{
+				map[string]any{"id": "00000000-0000-0000-0000-000000000001", "title": "a"},
+				map[string]any{"id": "00000000-0000-0000-0000-000000000002", "title": nil},
+			}},
+		}
+	}
+	assertRolledBack := func(status int, out map[string]any) {
+		t.Helper()
+		data := out["data"].(map[string]any)
+		if status != 422 || data["committed"] != false || len(data["errors"].([]any)) != 1 {
+			t.Fatalf("expected a rolled back import, got %d %v", status, data)
+		}
+		if reg.GetEntity("task") != nil {
+			t.Fatal("expected the entity removed from the registry")
+		}
+		for _, path := range []string{"/api/_admin/entities", "/api/_admin/rules"} {
+			if _, out := doJSON(t, app, "GET", path, nil); len(out["data"].([]any)) != 0 {
+				t.Fatalf("expected %s to be empty after the rollback, got %v", path, out["data"])
+			}
+		}
+		if rows, err := store.QueryRows(ctx, s.DB, "SELECT id FROM _ui_configs"); err != nil || len(rows) != 0 {
+			t.Fatalf("expected no ui configs after the rollback, got %v (%v)", rows, err)
+		}
+		if exists, err := s.Dialect.TableExists(ctx, s.DB, "tasks"); err != nil || exists {
+			t.Fatalf("expected the tasks table dropped (err %v)", err)
+		}
+	}
+
+	// A failing definition rolls the metadata transaction back
+	badRule := map[string]any{"type": "field", "definition": map[string]any{"field": "title", "operator": "min", "value": 1}}
+	assertRolledBack(doJSON(t, app, "POST", "/api/_admin/import?atomic=true", payload(badRule)))
+
+	// A failing sample record rolls back with the definitions, and the tables
+	// created for them are dropped
+	assertRolledBack(doJSON(t, app, "POST", "/api/_admin/import?atomic=true", payload()))
+
+	// Without atomic, the same payload keeps what succeeded
+	status, out := doJSON(t, app, "POST", "/api/_admin/import", payload())
+	data := out["data"].(map[string]any)
+	if status != 207 || data["committed"] != true || data["summary"].(map[string]any)["records"] != float64(1) {
+		t.Fatalf("expected a partial import, got %d %v", status, data)
+	}
+	if row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM tasks"); err != nil || row["n"] != int64(1) {
+		t.Fatalf("expected one task stored, got %v (%v)", row, err)
+	}
+}
+
+func TestTruncateEntity_EmptiesTableAndKeepsSchema(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	app := fiber.New()
+	RegisterAdminRoutes(app, NewHandler(s, metadata.NewRegistry(), store.NewMigrator(s)))
+
+	for _, e := range []map[string]any{
+		{"name": "post", "table": "posts", "id_strategy": "sequence",
+			"primary_key": map[string]any{"field": "id", "type": "integer", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "integer"}, {"name": "title", "type": "string"}}},
+		{"name": "tag", "table": "tags",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}}},
+	} {
+		if status, out := doJSON(t, app, "POST", "/api/_admin/entities", e); status != 201 {
+			t.Fatalf("create entity: %d %v", status, out)
+		}
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/relations", map[string]any{
+		"name": "post_tags", "type": "many_to_many", "source": "post", "target": "tag", "source_key": "id",
+		"join_table": "post_tags", "source_join_key": "post_id", "target_join_key": "tag_id", "ownership": "none", "on_delete": "detach",
+	}); status != 201 {
+		t.Fatalf("create relation: %d %v", status, out)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO posts (title) VALUES ('a')",
+		"INSERT INTO posts (title) VALUES ('b')",
+		"INSERT INTO post_tags (post_id, tag_id) VALUES (1, 'x')",
+	} {
+		if _, err := store.Exec(ctx, s.DB, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	AllowTruncate = false
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/entities/post/truncate?confirm=true", nil); status != 403 {
+		t.Fatalf("expected 403 while truncate is disabled, got %d", status)
+	}
+	AllowTruncate = true
+	defer func() { AllowTruncate = false }()
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/entities/post/truncate", nil); status != 400 {
+		t.Fatalf("expected 400 without confirm, got %d", status)
+	}
+
+	status, out := doJSON(t, app, "POST", "/api/_admin/entities/post/truncate?confirm=true&join_tables=true&restart_identity=true", nil)
+	if status != 200 {
+		t.Fatalf("truncate: %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+	if data["removed"] != float64(2) || fmt.Sprint(data["join_tables"]) != "[post_tags]" {
+		t.Fatalf("expected 2 rows removed with the join table, got %v", data)
+	}
+
+	for _, table := range []string{"posts", "post_tags"} {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS count FROM "+table)
+		if err != nil || fmt.Sprint(row["count"]) != "0" {
+			t.Fatalf("expected %s to be empty, got %v (%v)", table, row, err)
+		}
+	}
+	// The table and its key sequence are still there
+	row, err := store.QueryRow(ctx, s.DB, "INSERT INTO posts (title) VALUES ('c') RETURNING id")
+	if err != nil || fmt.Sprint(row["id"]) != "1" {
+		t.Fatalf("expected the next post to get id 1, got %v (%v)", row, err)
+	}
+
+	row, err = store.QueryRow(ctx, s.DB, "SELECT record_id, action FROM _audit_log WHERE entity = 'post'")
+	if err != nil || row["action"] != "truncate" || row["record_id"] != "*" {
+		t.Fatalf("expected a truncate audit entry, got %v (%v)", row, err)
+	}
+}
+
+func TestRoles_UsersAndPermissionsNeedAKnownRole(t *testing.T) {
+	app, _ := testAdminApp(t)
+
+	user := map[string]any{"email": "ada@example.com", "password": "s3cret-Passw0rd", "roles": []string{"editor"}}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/users", user); status != 422 ||
+		!strings.Contains(out["error"].(map[string]any)["message"].(string), "Unknown role: editor") {
+		t.Fatalf("expected an unknown role to be rejected, got %d %v", status, out)
+	}
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/permissions", map[string]any{
+		"entity": "post", "action": "read", "roles": []string{"editor"},
+	}); status != 422 {
+		t.Fatalf("expected a permission for an unknown role to be rejected, got %d", status)
+	}
+
+	if status, out := doJSON(t, app, "POST", "/api/_admin/roles", map[string]any{"name": "editor"}); status != 201 {
+		t.Fatalf("create role: %d %v", status, out)
+	}
+	if status, _ := doJSON(t, app, "POST", "/api/_admin/roles", map[string]any{"name": "editor"}); status != 409 {
+		t.Fatalf("expected a duplicate role to conflict, got %d", status)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/users", user); status != 201 {
+		t.Fatalf("expected a known role to be accepted, got %d %v", status, out)
+	}
+
+	_, out := doJSON(t, app, "GET", "/api/_admin/roles", nil)
+	var names []string
+	for _, r := range out["data"].([]any) {
+		names = append(names, r.(map[string]any)["name"].(string))
+	}
+	if strings.Join(names, ",") != "admin,editor" {
+		t.Fatalf("expected the seeded admin role and editor, got %v", names)
+	}
+}
+
+func TestExportImport_IncludeUsersRoundTripsWithoutHashes(t *testing.T) {
+	src, _ := testAdminApp(t)
+	if status, out := doJSON(t, src, "POST", "/api/_admin/users", map[string]any{
+		"email": "ada@example.com", "password": "s3cret-Passw0rd", "roles": []string{"admin"},
+	}); status != 201 {
+		t.Fatalf("create user: %d %v", status, out)
+	}
+	if status, out := doJSON(t, src, "POST", "/api/_admin/roles", map[string]any{"name": "user"}); status != 201 {
+		t.Fatalf("create role: %d %v", status, out)
+	}
+	if status, out := doJSON(t, src, "POST", "/api/_admin/invites", map[string]any{
+		"email": "bob@example.com", "roles": []string{"user"},
+	}); status != 201 {
+		t.Fatalf("create invite: %d %v", status, out)
+	}
+
+	if _, out := doJSON(t, src, "GET", "/api/_admin/export", nil); out["data"].(map[string]any)["users"] != nil {
+		t.Fatalf("expected users only with include_users=true, got %v", out["data"])
+	}
+	status, out := doJSON(t, src, "GET", "/api/_admin/export?include_users=true", nil)
+	if status != 200 {
+		t.Fatalf("export: %d %v", status, out)
+	}
+	raw, _ := json.Marshal(out)
+	for _, secret := range []string{"password", "$2a$", "token"} {
+		if bytes.Contains(raw, []byte(secret)) {
+			t.Fatalf("export leaks %q: %s", secret, raw)
+		}
+	}
+	export := out["data"].(map[string]any)
+
+	dst, _ := testAdminApp(t)
+	status, out = doJSON(t, dst, "POST", "/api/_admin/import?include_users=true", export)
+	if status != 200 {
+		t.Fatalf("import: %d %v", status, out)
+	}
+	summary := out["data"].(map[string]any)["summary"].(map[string]any)
+	if summary["users"] == float64(0) || summary["invites"] != float64(1) {
+		t.Fatalf("expected users and the pending invite imported, got %v", summary)
+	}
+
+	_, out = doJSON(t, dst, "GET", "/api/_admin/users", nil)
+	var ada map[string]any
+	for _, u := range out["data"].([]any) {
+		if u.(map[string]any)["email"] == "ada@example.com" {
+			ada = u.(map[string]any)
+		}
+	}
+	if ada == nil || ada["password_reset_required"] != true || ada["active"] != true {
+		t.Fatalf("expected ada imported active with a forced reset, got %v", ada)
+	}
+	if roles := ada["roles"].([]any); len(roles) != 1 || roles[0] != "admin" {
+		t.Fatalf("expected ada's roles kept, got %v", roles)
+	}
+	_, out = doJSON(t, dst, "GET", "/api/_admin/invites", nil)
+	if invites := out["data"].([]any); len(invites) != 1 || invites[0].(map[string]any)["email"] != "bob@example.com" {
+		t.Fatalf("expected bob's invite reissued, got %v", invites)
+	}
+
+	// Setting a password clears the flag
+	status, out = doJSON(t, dst, "PUT", "/api/_admin/users/"+ada["id"].(string), map[string]any{
+		"email": "ada@example.com", "password": "n3w-Passw0rd!", "roles": []string{"admin"}, "active": true,
+	})
+	if status != 200 {
+		t.Fatalf("update user: %d %v", status, out)
+	}
+	_, out = doJSON(t, dst, "GET", "/api/_admin/users/"+ada["id"].(string), nil)
+	if out["data"].(map[string]any)["password_reset_required"] != false {
+		t.Fatalf("expected the reset flag cleared, got %v", out["data"])
+	}
+
+	// Importing again skips existing users and pending invites
+	_, out = doJSON(t, dst, "POST", "/api/_admin/import?include_users=true", export)
+	if summary := out["data"].(map[string]any)["summary"].(map[string]any); summary["users"] != float64(0) || summary["invites"] != float64(0) {
+		t.Fatalf("expected a re-import to skip existing users, got %v", summary)
+	}
+}
+
+func TestExportImport_WarnsAboutWebhookSecrets(t *testing.T) {
+	src, _ := testAdminApp(t)
+	if status, out := doJSON(t, src, "POST", "/api/_admin/webhooks", map[string]any{
+		"entity": "order", "hook": "after_write", "url": "https://example.com/hook", "secret": "whsec_123",
+	}); status != 201 {
+		t.Fatalf("create webhook: %d %v", status, out)
+	}
+
+	_, out := doJSON(t, src, "GET", "/api/_admin/export", nil)
+	raw, _ := json.Marshal(out)
+	if bytes.Contains(raw, []byte("whsec_123")) {
+		t.Fatalf("export leaks the webhook secret: %s", raw)
+	}
+	export := out["data"].(map[string]any)
+	wh := export["webhooks"].([]any)[0].(map[string]any)
+	if wh["has_secret"] != true || len(export["warnings"].([]any)) != 1 {
+		t.Fatalf("expected the signed webhook flagged with a warning, got %v", export)
+	}
+
+	dst, _ := testAdminApp(t)
+	_, out = doJSON(t, dst, "POST", "/api/_admin/import", export)
+	if warnings, _ := out["data"].(map[string]any)["warnings"].([]any); len(warnings) != 1 {
+		t.Fatalf("expected a warning for the unsigned import, got %v", out["data"])
+	}
+
+	// A secret added to the file is stored, so the webhook stays signed
+	other, _ := testAdminApp(t)
+	wh["secret"] = "whsec_456"
+	_, out = doJSON(t, other, "POST", "/api/_admin/import", export)
+	if _, warned := out["data"].(map[string]any)["warnings"]; warned {
+		t.Fatalf("expected no warning with the secret supplied, got %v", out["data"])
+	}
+	_, out = doJSON(t, other, "GET", "/api/_admin/webhooks", nil)
+	if got := out["data"].([]any)[0].(map[string]any); got["has_secret"] != true {
+		t.Fatalf("expected the imported webhook signed, got %v", got)
+	}
+}
+
+func TestImport_MigratesEntitiesConcurrently(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	app := fiber.New()
+	RegisterAdminRoutes(app, NewHandler(s, metadata.NewRegistry(), store.NewMigrator(s)))
+	ImportMigrationConcurrency = 3
+	defer func() { ImportMigrationConcurrency = 4 }()
+
+	names := []string{"author", "book", "shelf", "library", "review", "genre"}
+	var entities []any
+	for _, name := range names {
+		entities = append(entities, map[string]any{
+			"name":        name,
+			"table":       name + "s",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}, {"name": "title", "type": "string"}},
+		})
+	}
+	status, out := doJSON(t, app, "POST", "/api/_admin/import", map[string]any{
+		"version":  1,
+		"entities": entities,
+		"relations": []any{map[string]any{
+			"name": "book_genres", "type": "many_to_many", "source": "book", "target": "genre",
+			"source_key": "id", "join_table": "book_genres", "source_join_key": "book_id", "target_join_key": "genre_id",
+			"ownership": "none", "on_delete": "detach",
+		}},
+	})
+	if status != 200 {
+		t.Fatalf("import: %d %v", status, out)
+	}
+
+	migrations := out["data"].(map[string]any)["migrations"].([]any)
+	if len(migrations) != len(names) {
+		t.Fatalf("expected a migration per entity, got %v", migrations)
+	}
+	for i, m := range migrations {
+		m := m.(map[string]any)
+		if m["entity"] != names[i] || m["error"] != nil {
+			t.Fatalf("expected %s migrated in payload order, got %v", names[i], m)
+		}
+		if _, ok := m["duration_ms"].(float64); !ok {
+			t.Fatalf("expected a duration for %s, got %v", names[i], m)
+		}
+	}
+	for _, table := range []string{"authors", "books", "shelfs", "librarys", "reviews", "genres", "book_genres"} {
+		if exists, err := s.Dialect.TableExists(ctx, s.DB, table); err != nil || !exists {
+			t.Fatalf("expected table %s after import (err %v)", table, err)
+		}
+	}
+}
+
+func TestCountBy_GroupsRecordsByFieldValue(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	app := fiber.New()
+	RegisterAdminRoutes(app, NewHandler(s, metadata.NewRegistry(), store.NewMigrator(s)))
+
+	if status, out := doJSON(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name": "order", "table": "orders", "soft_delete": true,
+		"primary_key": map[string]any{"field": "id", "type": "int", "generated": true},
+		"fields": []map[string]any{
+			{"name": "id", "type": "int"}, {"name": "status", "type": "string"},
+			{"name": "meta", "type": "json", "nullable": true}, {"name": "created_at", "type": "timestamp"},
+		},
+	}); status != 201 {
+		t.Fatalf("create entity: %d %v", status, out)
+	}
+	// 3 paid, 2 pending, 1 refunded in January; one more paid in February
+	// and a deleted pending order that is never counted.
+	for _, stmt := range []string{
+		"INSERT INTO orders (status, created_at) VALUES ('paid', '2025-01-05 10:00:00'), ('paid', '2025-01-06 10:00:00'), ('paid', '2025-01-07 10:00:00')",
+		"INSERT INTO orders (status, created_at) VALUES ('pending', '2025-01-08 10:00:00'), ('pending', '2025-01-09 10:00:00')",
+		"INSERT INTO orders (status, created_at) VALUES ('refunded', '2025-01-10 10:00:00'), ('paid', '2025-02-01 10:00:00')",
+		"INSERT INTO orders (status, created_at, deleted_at) VALUES ('pending', '2025-01-11 10:00:00', '2025-01-12 10:00:00')",
+	} {
+		if _, err := store.Exec(ctx, s.DB, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	counts := func(query string) (string, map[string]any) {
+		t.Helper()
+		status, out := doJSON(t, app, "GET", "/api/_admin/entities/order/records/count-by/status"+query, nil)
+		if status != 200 {
+			t.Fatalf("count-by %s: %d %v", query, status, out)
+		}
+		var got []string
+		for _, g := range out["data"].([]any) {
+			group := g.(map[string]any)
+			got = append(got, fmt.Sprintf("%v=%v", group["value"], group["count"]))
+		}
+		return strings.Join(got, ","), out["meta"].(map[string]any)
+	}
+
+	if got, meta := counts(""); got != "paid=4,pending=2,refunded=1" || meta["truncated"] != false {
+		t.Fatalf("expected all orders by status, got %s %v", got, meta)
+	}
+	if got, _ := counts("?from=2025-01-01&to=2025-02-01"); got != "paid=3,pending=2,refunded=1" {
+		t.Fatalf("expected January orders by status, got %s", got)
+	}
+	if got, meta := counts("?limit=2"); got != "paid=4,pending=2" || meta["truncated"] != true {
+		t.Fatalf("expected the two largest groups, truncated, got %s %v", got, meta)
+	}
+
+	for path, want := range map[string]int{
+		"/api/_admin/entities/order/records/count-by/nope":                                     400,
+		"/api/_admin/entities/order/records/count-by/meta":                                     400,
+		"/api/_admin/entities/order/records/count-by/status?from=2025-01-01&time_field=status": 400,
+		"/api/_admin/entities/order/records/count-by/status?from=yesterday":                    422,
+		"/api/_admin/entities/missing/records/count-by/status":                                 404,
+	} {
+		if status, _ := doJSON(t, app, "GET", path, nil); status != want {
+			t.Fatalf("GET %s: expected %d, got %d", path, want, status)
+		}
+	}
+}
+
+func TestDeleteEntity_RemovesDependentMetadataAndTable(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	app := fiber.New()
+	RegisterAdminRoutes(app, NewHandler(s, reg, store.NewMigrator(s)))
+
+	for _, name := range []string{"ticket", "note"} {
+		if status, out := doJSON(t, app, "POST", "/api/_admin/entities", map[string]any{
+			"name": name, "table": name + "s",
+			"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+			"fields":      []map[string]any{{"name": "id", "type": "uuid"}, {"name": "status", "type": "string"}},
+		}); status != 201 {
+			t.Fatalf("create entity %s: %d %v", name, status, out)
+		}
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/rules", map[string]any{
+		"entity": "ticket", "hook": "before_write", "type": "field", "active": true,
+		"definition": map[string]any{"field": "status", "operator": "min_length", "value": 1},
+	}); status != 201 {
+		t.Fatalf("create rule: %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/state-machines", map[string]any{
+		"entity": "ticket", "field": "status", "active": true,
+		"definition": map[string]any{"initial": "open", "transitions": []map[string]any{{"from": []string{"open"}, "to": "closed"}}},
+	}); status != 201 {
+		t.Fatalf("create state machine: %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/roles", map[string]any{"name": "agent"}); status != 201 {
+		t.Fatalf("create role: %d %v", status, out)
+	}
+	if status, out := doJSON(t, app, "POST", "/api/_admin/permissions", map[string]any{
+		"entity": "ticket", "action": "read", "roles": []string{"agent"},
+	}); status != 201 {
+		t.Fatalf("create permission: %d %v", status, out)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO _webhooks (id, entity, hook, url) VALUES ('w1', 'ticket', 'after_write', 'https://example.com/hook')",
+		"INSERT INTO _ui_configs (id, entity, scope, config) VALUES ('u1', 'ticket', 'default', '{}')",
+	} {
+		if _, err := store.Exec(ctx, s.DB, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	status, out := doJSON(t, app, "DELETE", "/api/_admin/entities/ticket", nil)
+	if status != 200 {
+		t.Fatalf("delete entity: %d %v", status, out)
+	}
+	if dropped := fmt.Sprint(out["data"].(map[string]any)["dropped_tables"]); dropped != "[tickets]" {
+		t.Fatalf("expected the tickets table dropped, got %s", dropped)
+	}
+	for _, table := range []string{"_rules", "_state_machines", "_permissions", "_webhooks", "_ui_configs"} {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS count FROM "+table+" WHERE entity = 'ticket'")
+		if err != nil || fmt.Sprint(row["count"]) != "0" {
+			t.Fatalf("expected no %s rows left for ticket, got %v (%v)", table, row, err)
+		}
+	}
+	if len(reg.GetRulesForEntity("ticket", "before_write")) != 0 || len(reg.GetStateMachinesForEntity("ticket")) != 0 {
+		t.Fatal("expected the registry to drop the ticket rules and state machines")
+	}
+	if exists, err := s.Dialect.TableExists(ctx, s.DB, "tickets"); err != nil || exists {
+		t.Fatalf("expected the tickets table to be dropped (%v)", err)
+	}
+
+	if status, out := doJSON(t, app, "DELETE", "/api/_admin/entities/note?drop_table=false", nil); status != 200 {
+		t.Fatalf("delete entity keeping its table: %d %v", status, out)
+	}
+	if exists, err := s.Dialect.TableExists(ctx, s.DB, "notes"); err != nil || !exists {
+		t.Fatalf("expected drop_table=false to keep the notes table (%v)", err)
+	}
+}
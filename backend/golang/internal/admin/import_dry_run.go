@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+)
+
+// dryRunImport works out what POST /_admin/import would create for payload
+// without writing anything: it runs the import's own definition step in a
+// transaction and rolls it back, so existing definitions are skipped exactly
+// as the import skips them. Definitions validateImport rejects are reported
+// as errors instead of being written.
+func (h *Handler) dryRunImport(ctx context.Context, payload *importPayload, includeUsers bool) (map[string]int, []string, error) {
+	report := h.validateImport(payload)
+	invalid := func(section string, i int) string {
+		for _, issue := range report[section] {
+			if issue.Index == i {
+				return issue.Message
+			}
+		}
+		return ""
+	}
+
+	tx, err := h.store.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin dry run: %w", err)
+	}
+	run := h.importDefinitions(ctx, tx, payload, includeUsers, invalid)
+	if err := tx.Rollback(); err != nil {
+		return nil, nil, fmt.Errorf("roll back dry run: %w", err)
+	}
+	summary, errors := run.summary, run.errors
+
+	// The payload's entities, kept by name for the sample data step
+	entities := h.importEntities(payload, run.toMigrate)
+
+	// Sample data: records of the payload's entities and join tables that set
+	// at least one known column. Conflicting rows the import would skip are
+	// still counted.
+	if len(payload.SampleData) > 0 {
+		summary["records"] = 0
+		for key, records := range payload.SampleData {
+			known := func(col string) bool { return false }
+			if entity := entities[key]; entity != nil {
+				known = func(col string) bool { return entity.GetField(col) != nil }
+			} else if h.registry.GetEntity(key) == nil {
+				for _, rel := range payload.Relations {
+					if jt, _ := rel["join_table"].(string); jt == key {
+						sjk, _ := rel["source_join_key"].(string)
+						tjk, _ := rel["target_join_key"].(string)
+						known = func(col string) bool { return col == sjk || col == tjk }
+						break
+					}
+				}
+			}
+			for _, record := range records {
+				for col := range record {
+					if known(col) {
+						summary["records"]++
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return summary, errors, nil
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"rocket-backend/internal/metadata"
+)
+
+// importMigration is the outcome of creating one imported entity's table.
+type importMigration struct {
+	Entity     string `json:"entity"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// migrateImported creates or updates the tables of newly imported entities on
+// up to ImportMigrationConcurrency workers. Entity tables don't depend on one
+// another; join tables do, so the caller creates them afterwards. Results
+// follow the order of entities.
+func (h *Handler) migrateImported(ctx context.Context, entities []*metadata.Entity) []importMigration {
+	results := make([]importMigration, len(entities))
+	workers := max(1, min(ImportMigrationConcurrency, len(entities)))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				start := time.Now()
+				err := h.migrator.Migrate(ctx, entities[i])
+				results[i] = importMigration{Entity: entities[i].Name, DurationMs: time.Since(start).Milliseconds()}
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range entities {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
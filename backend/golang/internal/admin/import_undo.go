@@ -0,0 +1,40 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"rocket-backend/internal/store"
+)
+
+// importSavepoint runs one item's writes under a savepoint of q, so a failing
+// item is rolled back without aborting the rest of the import's transaction.
+func importSavepoint(ctx context.Context, q store.Querier, fn func() error) error {
+	if _, err := store.Exec(ctx, q, "SAVEPOINT import_item"); err != nil {
+		return fmt.Errorf("savepoint: %w", err)
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := store.Exec(ctx, q, "ROLLBACK TO SAVEPOINT import_item"); rbErr != nil {
+			return fmt.Errorf("%v (rollback to savepoint: %v)", err, rbErr)
+		}
+		return err
+	}
+	if _, err := store.Exec(ctx, q, "RELEASE SAVEPOINT import_item"); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// dropImportedTables drops the tables an atomic import created before it
+// failed, join tables before the entity tables they point at.
+func (h *Handler) dropImportedTables(ctx context.Context, tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	return h.store.Tx(ctx, func(tx store.Querier) error {
+		tables := slices.Clone(tables)
+		slices.Reverse(tables)
+		return h.migrator.DropTables(ctx, tx, tables)
+	})
+}
@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"rocket-backend/internal/metadata"
+)
+
+// importIssue is one invalid definition in an import payload. Index is its
+// position in the payload section.
+type importIssue struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// validateImport runs the admin API's create-time validation over every
+// definition in payload without writing anything, and returns the problems
+// found per section. Relations, rules, state machines and workflows are
+// checked against the current entities plus those in the payload, as they
+// would be after the import's entity step.
+func (h *Handler) validateImport(payload *importPayload) map[string][]importIssue {
+	report := map[string][]importIssue{}
+	for _, section := range []string{"entities", "relations", "rules", "state_machines", "workflows", "permissions", "webhooks", "ui_configs"} {
+		report[section] = []importIssue{}
+	}
+	add := func(section string, i int, name string, err any) {
+		report[section] = append(report[section], importIssue{Index: i, Name: name, Message: fmt.Sprint(err)})
+	}
+
+	entities := h.registry.AllEntities()
+	seen := make(map[string]bool)
+	for i, raw := range payload.Entities {
+		name, _ := raw["name"].(string)
+		var e metadata.Entity
+		if err := decodeImportItem(raw, &e); err != nil {
+			add("entities", i, name, err)
+			continue
+		}
+		if err := validateEntity(&e); err != nil {
+			add("entities", i, name, err)
+			continue
+		}
+		if seen[e.Name] {
+			add("entities", i, name, "duplicate entity name in payload")
+			continue
+		}
+		seen[e.Name] = true
+		if h.registry.GetEntity(e.Name) == nil {
+			entities = append(entities, &e)
+		}
+	}
+
+	relations := h.registry.AllRelations()
+	scratch := metadata.NewRegistry()
+	scratch.Load(entities, relations)
+	seen = make(map[string]bool)
+	for i, raw := range payload.Relations {
+		name, _ := raw["name"].(string)
+		var rel metadata.Relation
+		if err := decodeImportItem(raw, &rel); err != nil {
+			add("relations", i, name, err)
+			continue
+		}
+		if err := validateRelation(&rel, scratch); err != nil {
+			add("relations", i, name, err)
+			continue
+		}
+		if seen[rel.Name] {
+			add("relations", i, name, "duplicate relation name in payload")
+			continue
+		}
+		seen[rel.Name] = true
+		if h.registry.GetRelation(rel.Name) == nil {
+			relations = append(relations, &rel)
+		}
+	}
+	scratch.Load(entities, relations)
+
+	for i, raw := range payload.Rules {
+		var r metadata.Rule
+		if err := decodeImportItem(raw, &r); err != nil {
+			add("rules", i, "", err)
+			continue
+		}
+		if err := validateRule(&r, scratch); err != nil {
+			add("rules", i, "", err)
+		}
+	}
+
+	for i, raw := range payload.StateMachines {
+		var sm metadata.StateMachine
+		if err := decodeImportItem(raw, &sm); err != nil {
+			add("state_machines", i, "", err)
+			continue
+		}
+		if err := validateStateMachine(&sm, scratch); err != nil {
+			add("state_machines", i, "", err)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, raw := range payload.Workflows {
+		name, _ := raw["name"].(string)
+		var wf metadata.Workflow
+		if err := decodeImportItem(raw, &wf); err != nil {
+			add("workflows", i, name, err)
+			continue
+		}
+		if err := validateWorkflow(&wf, scratch); err != nil {
+			add("workflows", i, name, err)
+			continue
+		}
+		if seen[wf.Name] {
+			add("workflows", i, name, "duplicate workflow name in payload")
+		}
+		seen[wf.Name] = true
+	}
+
+	validActions := map[string]bool{"read": true, "create": true, "update": true, "delete": true}
+	for i, raw := range payload.Permissions {
+		var perm metadata.Permission
+		if err := decodeImportItem(raw, &perm); err != nil {
+			add("permissions", i, "", err)
+			continue
+		}
+		if perm.Entity == "" {
+			add("permissions", i, "", "entity is required")
+		} else if !validActions[perm.Action] {
+			add("permissions", i, "", "action must be read, create, update, or delete")
+		} else if msg := normalizePermissionEffect(&perm); msg != "" {
+			add("permissions", i, "", msg)
+		} else if msg := validatePermissionConditions(scratch.GetEntity(perm.Entity), perm.Conditions); msg != "" {
+			add("permissions", i, "", msg)
+		}
+	}
+
+	for i, raw := range payload.Webhooks {
+		url, _ := raw["url"].(string)
+		if msg := validateWebhook(raw); msg != "" {
+			add("webhooks", i, url, msg)
+		}
+	}
+
+	for i, raw := range payload.UIConfigs {
+		if entity, _ := raw["entity"].(string); entity == "" {
+			add("ui_configs", i, "", "entity is required")
+		}
+	}
+
+	return report
+}
+
+// decodeImportItem decodes one payload definition into its metadata type.
+func decodeImportItem(raw map[string]any, dst any) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("invalid definition: %w", err)
+	}
+	return nil
+}
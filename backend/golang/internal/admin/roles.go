@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/store"
+)
+
+// ListRoles handles GET /_admin/roles: the known role names, the only ones
+// users, invites and permissions can be given.
+func (h *Handler) ListRoles(c *fiber.Ctx) error {
+	rows, err := store.QueryRows(c.Context(), h.store.DB, "SELECT name, created_at FROM _roles ORDER BY name")
+	if err != nil {
+		return fmt.Errorf("list roles: %w", err)
+	}
+	if rows == nil {
+		rows = []map[string]any{}
+	}
+	return c.JSON(fiber.Map{"data": rows})
+}
+
+// CreateRole handles POST /_admin/roles with {name}.
+func (h *Handler) CreateRole(c *fiber.Ctx) error {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "name is required"}})
+	}
+
+	pb := h.store.Dialect.NewParamBuilder()
+	row, err := store.QueryRow(c.Context(), h.store.DB,
+		fmt.Sprintf("INSERT INTO _roles (name) VALUES (%s) RETURNING name, created_at", pb.Add(body.Name)),
+		pb.Params()...)
+	if err != nil {
+		if errors.Is(store.MapError(h.store.Dialect, err), store.ErrUniqueViolation) {
+			return c.Status(409).JSON(fiber.Map{"error": fiber.Map{"code": "CONFLICT", "message": "Role already exists: " + body.Name}})
+		}
+		return fmt.Errorf("insert role: %w", err)
+	}
+	return c.Status(201).JSON(fiber.Map{"data": row})
+}
+
+// unknownRole returns a validation message naming the first of roles that is
+// not in _roles, or "" when all of them are known.
+func (h *Handler) unknownRole(ctx context.Context, q store.Querier, roles []string) (string, error) {
+	if len(roles) == 0 {
+		return "", nil
+	}
+	names := make([]any, len(roles))
+	for i, r := range roles {
+		names[i] = r
+	}
+	pb := h.store.Dialect.NewParamBuilder()
+	rows, err := store.QueryRows(ctx, q,
+		"SELECT name FROM _roles WHERE "+h.store.Dialect.InExpr("name", pb, names), pb.Params()...)
+	if err != nil {
+		return "", fmt.Errorf("look up roles: %w", err)
+	}
+	known := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		known[fmt.Sprintf("%v", row["name"])] = true
+	}
+	for _, r := range roles {
+		if !known[r] {
+			return fmt.Sprintf("Unknown role: %s (add it with POST /_admin/roles first)", r), nil
+		}
+	}
+	return "", nil
+}
+
+// addRoles inserts any of roles missing from _roles. Imports use it, since the
+// users and permissions they bring name their own roles.
+func (h *Handler) addRoles(ctx context.Context, q store.Querier, roles []string) error {
+	for _, r := range roles {
+		if r == "" {
+			continue
+		}
+		pb := h.store.Dialect.NewParamBuilder()
+		if _, err := store.Exec(ctx, q,
+			fmt.Sprintf("INSERT INTO _roles (name) VALUES (%s) ON CONFLICT (name) DO NOTHING", pb.Add(r)),
+			pb.Params()...); err != nil {
+			return fmt.Errorf("insert role %s: %w", r, err)
+		}
+	}
+	return nil
+}
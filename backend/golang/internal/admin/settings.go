@@ -0,0 +1,180 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/store"
+)
+
+var settingKeyRE = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,127}$`)
+
+// Setting is one app-wide key in _settings. Public settings are readable
+// without authentication.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Public bool   `json:"public"`
+}
+
+// settingsCache holds all of _settings in memory. It loads on first read and
+// is dropped on every write through the admin API.
+type settingsCache struct {
+	mu     sync.RWMutex
+	loaded bool
+	byKey  map[string]Setting
+}
+
+func (sc *settingsCache) get(ctx context.Context, s *store.Store) (map[string]Setting, error) {
+	sc.mu.RLock()
+	if sc.loaded {
+		defer sc.mu.RUnlock()
+		return sc.byKey, nil
+	}
+	sc.mu.RUnlock()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.loaded {
+		return sc.byKey, nil
+	}
+	rows, err := store.QueryRows(ctx, s.DB, "SELECT key, value, public FROM _settings")
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	if s.Dialect.NeedsBoolFix() {
+		store.NormalizeBooleans(rows, []string{"public"})
+	}
+	byKey := make(map[string]Setting, len(rows))
+	for _, row := range rows {
+		key, _ := row["key"].(string)
+		public, _ := row["public"].(bool)
+		byKey[key] = Setting{Key: key, Value: decodeSettingValue(row["value"]), Public: public}
+	}
+	sc.byKey = byKey
+	sc.loaded = true
+	return byKey, nil
+}
+
+func (sc *settingsCache) invalidate() {
+	sc.mu.Lock()
+	sc.loaded = false
+	sc.byKey = nil
+	sc.mu.Unlock()
+}
+
+// decodeSettingValue parses a stored JSON value. Both dialects return it as text.
+func decodeSettingValue(v any) any {
+	var raw []byte
+	switch val := v.(type) {
+	case string:
+		raw = []byte(val)
+	case []byte:
+		raw = val
+	default:
+		return val
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return string(raw)
+	}
+	return out
+}
+
+// --- Setting Endpoints ---
+
+func (h *Handler) ListSettings(c *fiber.Ctx) error {
+	byKey, err := h.settings.get(c.Context(), h.store)
+	if err != nil {
+		return err
+	}
+	out := make([]Setting, 0, len(byKey))
+	for _, s := range byKey {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return c.JSON(fiber.Map{"data": out})
+}
+
+func (h *Handler) GetSetting(c *fiber.Ctx) error {
+	key := c.Params("key")
+	byKey, err := h.settings.get(c.Context(), h.store)
+	if err != nil {
+		return err
+	}
+	s, ok := byKey[key]
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Setting not found: " + key}})
+	}
+	return c.JSON(fiber.Map{"data": s})
+}
+
+// PutSetting handles PUT /_admin/settings/:key with {"value": any, "public": bool},
+// creating or replacing the setting.
+func (h *Handler) PutSetting(c *fiber.Ctx) error {
+	key := c.Params("key")
+	if !settingKeyRE.MatchString(key) {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "key must start with a lowercase letter and contain only a-z, 0-9, '_', '.', '-' (max 128)"}})
+	}
+
+	var body struct {
+		Value  json.RawMessage `json:"value"`
+		Public bool            `json:"public"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": fiber.Map{"code": "INVALID_PAYLOAD", "message": "Invalid JSON body"}})
+	}
+	if len(body.Value) == 0 {
+		return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "value is required"}})
+	}
+
+	pb := h.store.Dialect.NewParamBuilder()
+	_, err := store.Exec(c.Context(), h.store.DB,
+		fmt.Sprintf(`INSERT INTO _settings (key, value, public) VALUES (%s, %s, %s)
+		 ON CONFLICT (key) DO UPDATE SET value = excluded.value, public = excluded.public, updated_at = %s`,
+			pb.Add(key), pb.Add(string(body.Value)), pb.Add(body.Public), h.store.Dialect.NowExpr()),
+		pb.Params()...)
+	if err != nil {
+		return fmt.Errorf("save setting %s: %w", key, err)
+	}
+	h.settings.invalidate()
+
+	return c.JSON(fiber.Map{"data": Setting{Key: key, Value: decodeSettingValue(string(body.Value)), Public: body.Public}})
+}
+
+func (h *Handler) DeleteSetting(c *fiber.Ctx) error {
+	key := c.Params("key")
+	pb := h.store.Dialect.NewParamBuilder()
+	n, err := store.Exec(c.Context(), h.store.DB,
+		fmt.Sprintf("DELETE FROM _settings WHERE key = %s", pb.Add(key)), pb.Params()...)
+	if err != nil {
+		return fmt.Errorf("delete setting %s: %w", key, err)
+	}
+	if n == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": fiber.Map{"code": "NOT_FOUND", "message": "Setting not found: " + key}})
+	}
+	h.settings.invalidate()
+	return c.JSON(fiber.Map{"data": fiber.Map{"key": key, "deleted": true}})
+}
+
+// PublicSettings returns the public settings as a key → value object
+// (unauthenticated endpoint for client bootstrapping).
+func (h *Handler) PublicSettings(c *fiber.Ctx) error {
+	byKey, err := h.settings.get(c.Context(), h.store)
+	if err != nil {
+		return err
+	}
+	out := fiber.Map{}
+	for key, s := range byKey {
+		if s.Public {
+			out[key] = s.Value
+		}
+	}
+	return c.JSON(fiber.Map{"data": out})
+}
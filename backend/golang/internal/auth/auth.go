@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"time"
+	"unicode"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -24,6 +25,9 @@ type Claims struct {
 const (
 	AccessTokenTTL  = 15 * time.Minute
 	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	// MinPasswordLength is the minimum length enforced by ValidatePassword.
+	MinPasswordLength = 8
 )
 
 // GenerateAccessToken creates a signed JWT with user ID and roles.
@@ -79,6 +83,27 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
+// ValidatePassword enforces the password policy: at least MinPasswordLength
+// characters, containing at least one letter and one digit.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return fmt.Errorf("password must contain at least one letter and one digit")
+	}
+	return nil
+}
+
 // CheckPassword compares a plaintext password against a bcrypt hash.
 func CheckPassword(password, hash string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
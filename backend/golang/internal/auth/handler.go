@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -15,13 +16,13 @@ import (
 
 // AuthHandler handles authentication endpoints.
 type AuthHandler struct {
-	store     *store.Store
-	jwtSecret string
+	store *store.Store
+	keys  TokenKeys
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(s *store.Store, jwtSecret string) *AuthHandler {
-	return &AuthHandler{store: s, jwtSecret: jwtSecret}
+func NewAuthHandler(s *store.Store, keys TokenKeys) *AuthHandler {
+	return &AuthHandler{store: s, keys: keys}
 }
 
 // Login handles POST /api/auth/login.
@@ -33,6 +34,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	if err := c.BodyParser(&body); err != nil {
 		return engine.NewAppError("INVALID_PAYLOAD", 400, "Invalid request body")
 	}
+	body.Email = store.NormalizeEmail(body.Email)
 	if body.Email == "" || body.Password == "" {
 		return engine.UnauthorizedError("Email and password are required")
 	}
@@ -57,6 +59,13 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return engine.UnauthorizedError("Invalid email or password")
 	}
 
+	// Users brought in by an import have no usable password until an admin
+	// sets one. Checked after the password, so only someone who knows it
+	// learns the account needs a reset.
+	if toBool(user["password_reset_required"]) {
+		return engine.UnauthorizedError("Password reset required")
+	}
+
 	// Extract user info
 	userID, _ := user["id"].(string)
 	roles := extractRoles(user["roles"])
@@ -67,9 +76,23 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return err
 	}
 
+	// Recorded in the background so login latency doesn't depend on it
+	go h.recordLogin(userID)
+
 	return c.JSON(fiber.Map{"data": pair})
 }
 
+// recordLogin stamps the user's last_login_at.
+func (h *AuthHandler) recordLogin(userID string) {
+	pb := h.store.Dialect.NewParamBuilder()
+	_, err := store.Exec(context.Background(), h.store.DB,
+		fmt.Sprintf("UPDATE _users SET last_login_at = %s WHERE id = %s", h.store.Dialect.NowExpr(), pb.Add(userID)),
+		pb.Params()...)
+	if err != nil {
+		log.Printf("WARN: record login for user %s: %v", userID, err)
+	}
+}
+
 // Refresh handles POST /api/auth/refresh.
 func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 	var body struct {
@@ -84,43 +107,12 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 
 	ctx := c.Context()
 
-	// Look up refresh token
-	pb := h.store.Dialect.NewParamBuilder()
-	row, err := store.QueryRow(ctx, h.store.DB,
-		fmt.Sprintf(`SELECT rt.id, rt.user_id, rt.expires_at, u.roles, u.active
-		 FROM _refresh_tokens rt
-		 JOIN _users u ON u.id = rt.user_id
-		 WHERE rt.token = %s`, pb.Add(body.RefreshToken)), pb.Params()...)
+	// Consume the token (rotation) and issue a new pair
+	userID, roles, err := RotateRefreshToken(ctx, h.store, AppRefreshTables, body.RefreshToken)
 	if err != nil {
-		return engine.UnauthorizedError("Invalid refresh token")
-	}
-
-	// Check expiration
-	expiresAt, _ := row["expires_at"].(time.Time)
-	if time.Now().After(expiresAt) {
-		// Delete expired token
-		pb2 := h.store.Dialect.NewParamBuilder()
-		_, _ = store.Exec(ctx, h.store.DB,
-			fmt.Sprintf("DELETE FROM _refresh_tokens WHERE token = %s", pb2.Add(body.RefreshToken)), pb2.Params()...)
-		return engine.UnauthorizedError("Refresh token expired")
-	}
-
-	// Check user is active
-	active := toBool(row["active"])
-	if !active {
-		return engine.UnauthorizedError("Account is disabled")
+		return err
 	}
 
-	// Delete the used refresh token (rotation)
-	tokenID, _ := row["id"].(string)
-	pb3 := h.store.Dialect.NewParamBuilder()
-	_, _ = store.Exec(ctx, h.store.DB,
-		fmt.Sprintf("DELETE FROM _refresh_tokens WHERE id = %s", pb3.Add(tokenID)), pb3.Params()...)
-
-	// Generate new token pair
-	userID, _ := row["user_id"].(string)
-	roles := extractRoles(row["roles"])
-
 	pair, err := h.generateTokenPair(ctx, userID, roles)
 	if err != nil {
 		return err
@@ -141,9 +133,7 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 		return engine.UnauthorizedError("Refresh token is required")
 	}
 
-	pb := h.store.Dialect.NewParamBuilder()
-	_, _ = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("DELETE FROM _refresh_tokens WHERE token = %s", pb.Add(body.RefreshToken)), pb.Params()...)
+	RevokeRefreshToken(c.Context(), h.store, AppRefreshTables, body.RefreshToken)
 
 	return c.JSON(fiber.Map{"message": "Logged out"})
 }
@@ -199,7 +189,7 @@ func (h *AuthHandler) AcceptInvite(c *fiber.Ctx) error {
 		return engine.NewAppError("INTERNAL_ERROR", 500, "Failed to hash password")
 	}
 
-	email := fmt.Sprintf("%v", invite["email"])
+	email := store.NormalizeEmail(fmt.Sprintf("%v", invite["email"]))
 	roles := extractRoles(invite["roles"])
 
 	// Begin transaction: create user + mark invite accepted
@@ -267,31 +257,11 @@ func RegisterAuthRoutes(app *fiber.App, h *AuthHandler) {
 func (h *AuthHandler) findUserByEmail(ctx context.Context, email string) (map[string]any, error) {
 	pb := h.store.Dialect.NewParamBuilder()
 	return store.QueryRow(ctx, h.store.DB,
-		fmt.Sprintf("SELECT id, email, password_hash, roles, active FROM _users WHERE email = %s", pb.Add(email)), pb.Params()...)
+		fmt.Sprintf("SELECT id, email, password_hash, roles, active, password_reset_required FROM _users WHERE LOWER(email) = LOWER(%s)", pb.Add(email)), pb.Params()...)
 }
 
 func (h *AuthHandler) generateTokenPair(ctx context.Context, userID string, roles []string) (*TokenPair, error) {
-	accessToken, err := GenerateAccessToken(userID, roles, h.jwtSecret)
-	if err != nil {
-		return nil, engine.NewAppError("INTERNAL_ERROR", 500, "Failed to generate access token")
-	}
-
-	refreshToken := GenerateRefreshToken()
-	expiresAt := time.Now().Add(RefreshTokenTTL)
-
-	pb := h.store.Dialect.NewParamBuilder()
-	_, err = store.Exec(ctx, h.store.DB,
-		fmt.Sprintf(`INSERT INTO _refresh_tokens (user_id, token, expires_at) VALUES (%s, %s, %s)`,
-			pb.Add(userID), pb.Add(refreshToken), pb.Add(expiresAt)),
-		pb.Params()...)
-	if err != nil {
-		return nil, engine.NewAppError("INTERNAL_ERROR", 500, "Failed to store refresh token")
-	}
-
-	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	return IssueTokenPair(ctx, h.store, AppRefreshTables, h.keys, userID, roles)
 }
 
 func extractRoles(v any) []string {
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/engine"
+)
+
+// PlatformAudience is the aud claim of platform tokens signed with the key
+// ring. App tokens use the app name, which can't start with "_".
+const PlatformAudience = "_platform"
+
+// SigningKeys signs every access token when jwt.algorithm is RS256 or ES256.
+// Nil means HS256 with the per-app (or platform) secret. Set from config at
+// startup.
+var SigningKeys *KeyRing
+
+// TokenKeys signs and verifies the access tokens of one audience: an app or
+// the platform.
+type TokenKeys struct {
+	Secret   string // HS256 secret, used when SigningKeys is nil
+	Audience string // aud claim on tokens signed with SigningKeys
+}
+
+// Sign creates an access token for the user.
+func (k TokenKeys) Sign(userID string, roles []string) (string, error) {
+	ring := SigningKeys
+	if ring == nil {
+		return GenerateAccessToken(userID, roles, k.Secret)
+	}
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{k.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
+		Roles: roles,
+	}
+	return ring.sign(claims)
+}
+
+// Parse validates an access token and returns its claims.
+func (k TokenKeys) Parse(tokenStr string) (*Claims, error) {
+	ring := SigningKeys
+	if ring == nil {
+		return ParseAccessToken(tokenStr, k.Secret)
+	}
+	return ring.parse(tokenStr, k.Audience)
+}
+
+// KeyRing holds the current RS256/ES256 signing key and the retired keys
+// still accepted for verification. Tokens name their key in the kid header,
+// so a new key can be rolled out while tokens signed with the old one run
+// out.
+type KeyRing struct {
+	current *ringKey
+	retired []*ringKey
+}
+
+type ringKey struct {
+	id          string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	public      crypto.PublicKey
+	acceptUntil time.Time // retired keys only
+}
+
+// LoadKeyRing reads the keys named in cfg. It returns nil for HS256.
+func LoadKeyRing(cfg config.JWTConfig) (*KeyRing, error) {
+	var method jwt.SigningMethod
+	switch cfg.Algorithm {
+	case "", "HS256":
+		return nil, nil
+	case "RS256":
+		method = jwt.SigningMethodRS256
+	case "ES256":
+		method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("jwt.algorithm must be HS256, RS256 or ES256, got %q", cfg.Algorithm)
+	}
+	if cfg.KeyID == "" || cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("jwt.key_id and jwt.private_key_file are required for %s", cfg.Algorithm)
+	}
+
+	pemBytes, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt private key: %w", err)
+	}
+	current := &ringKey{id: cfg.KeyID, method: method}
+	if method == jwt.SigningMethodRS256 {
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt private key: %w", err)
+		}
+		current.private, current.public = key, &key.PublicKey
+	} else {
+		key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt private key: %w", err)
+		}
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 needs a P-256 key")
+		}
+		current.private, current.public = key, &key.PublicKey
+	}
+
+	ring := &KeyRing{current: current}
+	seen := map[string]bool{cfg.KeyID: true}
+	for _, rk := range cfg.RetiredKeys {
+		if rk.KeyID == "" || rk.PublicKeyFile == "" || rk.AcceptUntil == "" {
+			return nil, fmt.Errorf("jwt.retired_keys entries need key_id, public_key_file and accept_until")
+		}
+		if seen[rk.KeyID] {
+			return nil, fmt.Errorf("duplicate jwt key id %q", rk.KeyID)
+		}
+		seen[rk.KeyID] = true
+		until, err := time.Parse(time.RFC3339, rk.AcceptUntil)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: accept_until must be an RFC 3339 time: %w", rk.KeyID, err)
+		}
+		key, err := loadPublicKey(rk.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", rk.KeyID, err)
+		}
+		ring.retired = append(ring.retired, &ringKey{id: rk.KeyID, method: key.method, public: key.public, acceptUntil: until})
+	}
+	return ring, nil
+}
+
+// loadPublicKey reads an RSA or P-256 public key; the key type decides the algorithm.
+func loadPublicKey(path string) (*ringKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		return &ringKey{method: jwt.SigningMethodRS256, public: key}, nil
+	}
+	key, err := jwt.ParseECPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("public key is neither RSA nor EC")
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("EC public key must use P-256")
+	}
+	return &ringKey{method: jwt.SigningMethodES256, public: key}, nil
+}
+
+func (r *KeyRing) sign(claims Claims) (string, error) {
+	token := jwt.NewWithClaims(r.current.method, claims)
+	token.Header["kid"] = r.current.id
+	signed, err := token.SignedString(r.current.private)
+	if err != nil {
+		return "", fmt.Errorf("sign access token: %w", err)
+	}
+	return signed, nil
+}
+
+func (r *KeyRing) parse(tokenStr, audience string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key := r.lookup(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if !key.acceptUntil.IsZero() && time.Now().After(key.acceptUntil) {
+			return nil, fmt.Errorf("signing key %q is retired", kid)
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.public, nil
+	}, jwt.WithAudience(audience))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	return claims, nil
+}
+
+func (r *KeyRing) lookup(kid string) *ringKey {
+	if kid == r.current.id {
+		return r.current
+	}
+	for _, k := range r.retired {
+		if k.id == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys that currently verify tokens, as a JSON Web
+// Key Set: the signing key and retired keys still inside their grace window.
+func (r *KeyRing) JWKS() map[string]any {
+	keys := []map[string]any{jwk(r.current)}
+	now := time.Now()
+	for _, k := range r.retired {
+		if now.Before(k.acceptUntil) {
+			keys = append(keys, jwk(k))
+		}
+	}
+	return map[string]any{"keys": keys}
+}
+
+func jwk(k *ringKey) map[string]any {
+	b64 := base64.RawURLEncoding.EncodeToString
+	out := map[string]any{"kid": k.id, "alg": k.method.Alg(), "use": "sig"}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		out["kty"] = "RSA"
+		out["n"] = b64(pub.N.Bytes())
+		out["e"] = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdhKey, _ := pub.ECDH()
+		point := ecdhKey.Bytes() // 0x04 || X || Y
+		out["kty"] = "EC"
+		out["crv"] = "P-256"
+		out["x"] = b64(point[1:33])
+		out["y"] = b64(point[33:65])
+	}
+	return out
+}
+
+// JWKSHandler handles GET /.well-known/jwks.json. It is only served when
+// tokens are signed with RS256 or ES256; HMAC secrets are never published.
+func JWKSHandler(c *fiber.Ctx) error {
+	ring := SigningKeys
+	if ring == nil {
+		return engine.NewAppError("NOT_FOUND", 404, "JWKS is only available when jwt.algorithm is RS256 or ES256")
+	}
+	c.Set("Cache-Control", "public, max-age=300")
+	return c.JSON(ring.JWKS())
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rocket-backend/internal/config"
+)
+
+func writeKeyPair(t *testing.T, dir, name string, key crypto.Signer) (privPath, pubPath string) {
+	t.Helper()
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	privPath = filepath.Join(dir, name+".pem")
+	pubPath = filepath.Join(dir, name+".pub.pem")
+	os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600)
+	os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)
+	return privPath, pubPath
+}
+
+func TestKeyRing_RotationAcceptsCurrentAndRejectsRetiredKeys(t *testing.T) {
+	defer func() { SigningKeys = nil }()
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	oldPriv, oldPub := writeKeyPair(t, dir, "2026-04", rsaKey)
+	graceKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	gracePriv, gracePub := writeKeyPair(t, dir, "2026-09", graceKey)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newPriv, _ := writeKeyPair(t, dir, "2026-10", newKey)
+
+	signWith := func(cfg config.JWTConfig, keys TokenKeys) string {
+		t.Helper()
+		ring, err := LoadKeyRing(cfg)
+		if err != nil {
+			t.Fatalf("load key ring %s: %v", cfg.KeyID, err)
+		}
+		SigningKeys = ring
+		token, err := keys.Sign("u1", []string{"admin"})
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+	app := TokenKeys{Secret: "app-secret", Audience: "shop"}
+	oldToken := signWith(config.JWTConfig{Algorithm: "RS256", KeyID: "2026-04", PrivateKeyFile: oldPriv}, app)
+	graceToken := signWith(config.JWTConfig{Algorithm: "ES256", KeyID: "2026-09", PrivateKeyFile: gracePriv}, app)
+
+	// Rotate: 2026-10 signs, 2026-09 is still inside its grace window, 2026-04 is past it
+	rotated := config.JWTConfig{
+		Algorithm: "ES256", KeyID: "2026-10", PrivateKeyFile: newPriv,
+		RetiredKeys: []config.RetiredJWTKey{
+			{KeyID: "2026-09", PublicKeyFile: gracePub, AcceptUntil: time.Now().Add(time.Hour).Format(time.RFC3339)},
+			{KeyID: "2026-04", PublicKeyFile: oldPub, AcceptUntil: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		},
+	}
+	current := signWith(rotated, app)
+
+	claims, err := app.Parse(current)
+	if err != nil || claims.Subject != "u1" || len(claims.Roles) != 1 {
+		t.Fatalf("expected the current key's token to verify, got %+v (%v)", claims, err)
+	}
+	if _, err := app.Parse(graceToken); err != nil {
+		t.Fatalf("expected a token from a key inside its grace window to verify: %v", err)
+	}
+	if _, err := app.Parse(oldToken); err == nil {
+		t.Fatal("expected a token signed with a retired key to be rejected")
+	}
+	if _, err := (TokenKeys{Audience: "other-app"}).Parse(current); err == nil {
+		t.Fatal("expected a token for another audience to be rejected")
+	}
+
+	jwks := SigningKeys.JWKS()["keys"].([]map[string]any)
+	if len(jwks) != 2 || jwks[0]["kid"] != "2026-10" || jwks[0]["kty"] != "EC" || jwks[1]["kid"] != "2026-09" {
+		t.Fatalf("expected the current and in-grace keys in the JWKS, got %v", jwks)
+	}
+
+	// HS256 stays the default
+	SigningKeys = nil
+	hs, _ := app.Sign("u1", nil)
+	if _, err := ParseAccessToken(hs, "app-secret"); err != nil {
+		t.Fatalf("expected an HS256 token without a key ring: %v", err)
+	}
+	if _, err := app.Parse(current); err == nil {
+		t.Fatal("expected an ES256 token to be rejected once the key ring is gone")
+	}
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/store"
+)
+
+const meColumns = "id, email, roles, active, metadata, created_at, updated_at"
+
+// GetMe handles GET /api/me — returns the caller's own user record (never the password hash).
+func (h *AuthHandler) GetMe(c *fiber.Ctx) error {
+	user := GetUser(c)
+	if user == nil {
+		return engine.UnauthorizedError("Authentication required")
+	}
+
+	row, err := h.fetchMe(c, user.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"data": row})
+}
+
+// UpdateMe handles PUT /api/me — lets the caller change their own email, password,
+// and profile metadata. Roles and active status can only be changed by an admin.
+// Changing the password requires the current password and must satisfy the password policy.
+func (h *AuthHandler) UpdateMe(c *fiber.Ctx) error {
+	user := GetUser(c)
+	if user == nil {
+		return engine.UnauthorizedError("Authentication required")
+	}
+
+	var body map[string]any
+	if err := c.BodyParser(&body); err != nil {
+		return engine.NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body")
+	}
+	for _, key := range []string{"roles", "active"} {
+		if _, ok := body[key]; ok {
+			return engine.ForbiddenError(fmt.Sprintf("%s cannot be changed on your own account", key))
+		}
+	}
+
+	ctx := c.Context()
+	current, err := h.fetchMe(c, user.ID)
+	if err != nil {
+		return err
+	}
+
+	pb := h.store.Dialect.NewParamBuilder()
+	var sets []string
+	var details []engine.ErrorDetail
+
+	if v, ok := body["email"]; ok {
+		email, _ := v.(string)
+		email = store.NormalizeEmail(email)
+		if email == "" {
+			details = append(details, engine.ErrorDetail{Field: "email", Rule: "required", Message: "email is required"})
+		} else if email != current["email"] {
+			sets = append(sets, "email = "+pb.Add(email))
+		}
+	}
+
+	if v, ok := body["password"]; ok {
+		password, _ := v.(string)
+		currentPassword, _ := body["current_password"].(string)
+		if err := ValidatePassword(password); err != nil {
+			details = append(details, engine.ErrorDetail{Field: "password", Rule: "policy", Message: err.Error()})
+		} else {
+			pbHash := h.store.Dialect.NewParamBuilder()
+			hashRow, err := store.QueryRow(ctx, h.store.DB,
+				fmt.Sprintf("SELECT password_hash FROM _users WHERE id = %s", pbHash.Add(user.ID)), pbHash.Params()...)
+			if err != nil {
+				return fmt.Errorf("fetch password hash: %w", err)
+			}
+			hash, _ := hashRow["password_hash"].(string)
+			if !CheckPassword(currentPassword, hash) {
+				details = append(details, engine.ErrorDetail{Field: "current_password", Rule: "invalid", Message: "current password is incorrect"})
+			} else {
+				newHash, err := HashPassword(password)
+				if err != nil {
+					return engine.NewAppError("INTERNAL_ERROR", 500, "Failed to hash password")
+				}
+				sets = append(sets, "password_hash = "+pb.Add(newHash))
+			}
+		}
+	}
+
+	if v, ok := body["metadata"]; ok {
+		meta, isMap := v.(map[string]any)
+		if !isMap && v != nil {
+			details = append(details, engine.ErrorDetail{Field: "metadata", Rule: "type", Message: "metadata must be an object"})
+		} else {
+			if meta == nil {
+				meta = map[string]any{}
+			}
+			metaJSON, err := json.Marshal(meta)
+			if err != nil {
+				return fmt.Errorf("marshal metadata: %w", err)
+			}
+			sets = append(sets, "metadata = "+pb.Add(string(metaJSON)))
+		}
+	}
+
+	if len(details) > 0 {
+		return engine.ValidationError(details)
+	}
+
+	if len(sets) > 0 {
+		sets = append(sets, "updated_at = "+h.store.Dialect.NowExpr())
+		_, err := store.Exec(ctx, h.store.DB,
+			fmt.Sprintf("UPDATE _users SET %s WHERE id = %s", strings.Join(sets, ", "), pb.Add(user.ID)),
+			pb.Params()...)
+		if err != nil {
+			if errors.Is(store.MapError(h.store.Dialect, err), store.ErrUniqueViolation) {
+				return engine.ConflictError("A user with this email already exists")
+			}
+			return fmt.Errorf("update user %s: %w", user.ID, err)
+		}
+	}
+
+	row, err := h.fetchMe(c, user.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"data": row})
+}
+
+// ChangePassword handles POST /api/auth/change-password. It checks the current
+// password, applies the password policy and replaces the hash. Every refresh
+// token of the user is revoked; unless keep_session is false, a new token pair
+// is returned so the caller stays signed in. Issued access tokens stay valid
+// until they expire.
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	user := GetUser(c)
+	if user == nil {
+		return engine.UnauthorizedError("Authentication required")
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+		KeepSession     *bool  `json:"keep_session"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return engine.NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body")
+	}
+
+	ctx := c.Context()
+	pb := h.store.Dialect.NewParamBuilder()
+	row, err := store.QueryRow(ctx, h.store.DB,
+		fmt.Sprintf("SELECT password_hash, roles FROM _users WHERE id = %s", pb.Add(user.ID)), pb.Params()...)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return engine.NewAppError("NOT_FOUND", 404, "User not found")
+		}
+		return fmt.Errorf("fetch password hash: %w", err)
+	}
+	hash, _ := row["password_hash"].(string)
+	if !CheckPassword(body.CurrentPassword, hash) {
+		return engine.UnauthorizedError("Current password is incorrect")
+	}
+	if err := ValidatePassword(body.NewPassword); err != nil {
+		return engine.ValidationError([]engine.ErrorDetail{{Field: "new_password", Rule: "policy", Message: err.Error()}})
+	}
+
+	newHash, err := HashPassword(body.NewPassword)
+	if err != nil {
+		return engine.NewAppError("INTERNAL_ERROR", 500, "Failed to hash password")
+	}
+	pb = h.store.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, h.store.DB,
+		fmt.Sprintf("UPDATE _users SET password_hash = %s, updated_at = %s WHERE id = %s",
+			pb.Add(newHash), h.store.Dialect.NowExpr(), pb.Add(user.ID)),
+		pb.Params()...); err != nil {
+		return fmt.Errorf("update password for user %s: %w", user.ID, err)
+	}
+	if err := RevokeUserRefreshTokens(ctx, h.store, AppRefreshTables, user.ID); err != nil {
+		return err
+	}
+
+	if body.KeepSession != nil && !*body.KeepSession {
+		return c.JSON(fiber.Map{"message": "Password changed"})
+	}
+	pair, err := h.generateTokenPair(ctx, user.ID, extractRoles(row["roles"]))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"data": pair})
+}
+
+func (h *AuthHandler) fetchMe(c *fiber.Ctx, userID string) (map[string]any, error) {
+	pb := h.store.Dialect.NewParamBuilder()
+	row, err := store.QueryRow(c.Context(), h.store.DB,
+		fmt.Sprintf("SELECT %s FROM _users WHERE id = %s", meColumns, pb.Add(userID)),
+		pb.Params()...)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, engine.NewAppError("NOT_FOUND", 404, "User not found")
+		}
+		return nil, fmt.Errorf("fetch user %s: %w", userID, err)
+	}
+	row["roles"] = extractRoles(row["roles"])
+	row["active"] = toBool(row["active"])
+	row["metadata"] = parseJSONObject(row["metadata"])
+	return row, nil
+}
+
+// parseJSONObject decodes a JSON/JSONB column into a map, defaulting to an empty object.
+func parseJSONObject(v any) map[string]any {
+	var raw []byte
+	switch val := v.(type) {
+	case map[string]any:
+		return val
+	case string:
+		raw = []byte(val)
+	case []byte:
+		raw = val
+	}
+	result := map[string]any{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &result)
+	}
+	return result
+}
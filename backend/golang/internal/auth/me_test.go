@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func setupMeApp(t *testing.T) (*fiber.App, string) {
+	t.Helper()
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	hash, err := HashPassword("oldpass123")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	userID := store.GenerateUUID()
+	pb := s.Dialect.NewParamBuilder()
+	_, err = store.Exec(ctx, s.DB,
+		fmt.Sprintf("INSERT INTO _users (id, email, password_hash, roles) VALUES (%s, %s, %s, %s)",
+			pb.Add(userID), pb.Add("member@test.com"), pb.Add(hash), pb.Add(s.Dialect.ArrayParam([]string{"staff"}))),
+		pb.Params()...)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	h := NewAuthHandler(s, TokenKeys{Secret: "test-secret"})
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if appErr, ok := err.(*engine.AppError); ok {
+				return c.Status(appErr.Status).JSON(engine.ErrorResponse{Error: appErr})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		},
+	})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: userID, Roles: []string{"staff"}})
+		return c.Next()
+	})
+	app.Get("/api/me", h.GetMe)
+	app.Put("/api/me", h.UpdateMe)
+	app.Post("/api/auth/login", h.Login)
+	app.Post("/api/auth/refresh", h.Refresh)
+	app.Post("/api/auth/change-password", h.ChangePassword)
+	return app, userID
+}
+
+func doMeRequest(t *testing.T, app *fiber.App, method string, body any) (int, map[string]any) {
+	t.Helper()
+	return doAuthRequest(t, app, method, "/api/me", body)
+}
+
+func doAuthRequest(t *testing.T, app *fiber.App, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result map[string]any
+	raw, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(raw, &result)
+	return resp.StatusCode, result
+}
+
+func TestMe_NonAdminCanUpdateEmailButNotRoles(t *testing.T) {
+	app, _ := setupMeApp(t)
+
+	status, res := doMeRequest(t, app, "GET", nil)
+	if status != 200 {
+		t.Fatalf("GET /api/me: expected 200, got %d (%v)", status, res)
+	}
+	data := res["data"].(map[string]any)
+	if data["email"] != "member@test.com" {
+		t.Fatalf("expected own email, got %v", data["email"])
+	}
+	if _, ok := data["password_hash"]; ok {
+		t.Fatal("password_hash must never be returned")
+	}
+
+	status, res = doMeRequest(t, app, "PUT", map[string]any{"email": "renamed@test.com"})
+	if status != 200 {
+		t.Fatalf("update email: expected 200, got %d (%v)", status, res)
+	}
+	if got := res["data"].(map[string]any)["email"]; got != "renamed@test.com" {
+		t.Fatalf("expected updated email, got %v", got)
+	}
+
+	status, _ = doMeRequest(t, app, "PUT", map[string]any{"roles": []string{"admin"}})
+	if status != 403 {
+		t.Fatalf("update roles: expected 403, got %d", status)
+	}
+	_, res = doMeRequest(t, app, "GET", nil)
+	roles := res["data"].(map[string]any)["roles"].([]any)
+	if len(roles) != 1 || roles[0] != "staff" {
+		t.Fatalf("roles must be unchanged, got %v", roles)
+	}
+}
+
+func TestMe_PasswordChangeEnforcesPolicy(t *testing.T) {
+	app, _ := setupMeApp(t)
+
+	status, _ := doMeRequest(t, app, "PUT", map[string]any{"password": "short", "current_password": "oldpass123"})
+	if status != 422 {
+		t.Fatalf("weak password: expected 422, got %d", status)
+	}
+	status, _ = doMeRequest(t, app, "PUT", map[string]any{"password": "newpass123", "current_password": "wrong"})
+	if status != 422 {
+		t.Fatalf("wrong current password: expected 422, got %d", status)
+	}
+	status, _ = doMeRequest(t, app, "PUT", map[string]any{"password": "newpass123", "current_password": "oldpass123"})
+	if status != 200 {
+		t.Fatalf("valid password change: expected 200, got %d", status)
+	}
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	app, _ := setupMeApp(t)
+
+	status, res := doAuthRequest(t, app, "POST", "/api/auth/login", map[string]any{"email": "member@test.com", "password": "oldpass123"})
+	if status != 200 {
+		t.Fatalf("login: expected 200, got %d (%v)", status, res)
+	}
+	oldRefresh := res["data"].(map[string]any)["refresh_token"]
+
+	status, _ = doAuthRequest(t, app, "POST", "/api/auth/change-password", map[string]any{"current_password": "wrong", "new_password": "newpass123"})
+	if status != 401 {
+		t.Fatalf("wrong current password: expected 401, got %d", status)
+	}
+	status, _ = doAuthRequest(t, app, "POST", "/api/auth/change-password", map[string]any{"current_password": "oldpass123", "new_password": "short"})
+	if status != 422 {
+		t.Fatalf("weak password: expected 422, got %d", status)
+	}
+
+	status, res = doAuthRequest(t, app, "POST", "/api/auth/change-password", map[string]any{"current_password": "oldpass123", "new_password": "newpass123"})
+	if status != 200 {
+		t.Fatalf("change password: expected 200, got %d (%v)", status, res)
+	}
+	newRefresh := res["data"].(map[string]any)["refresh_token"]
+
+	if status, _ := doAuthRequest(t, app, "POST", "/api/auth/refresh", map[string]any{"refresh_token": oldRefresh}); status != 401 {
+		t.Fatalf("old refresh token: expected 401, got %d", status)
+	}
+	if status, _ := doAuthRequest(t, app, "POST", "/api/auth/refresh", map[string]any{"refresh_token": newRefresh}); status != 200 {
+		t.Fatalf("new refresh token: expected 200, got %d", status)
+	}
+	if status, _ := doAuthRequest(t, app, "POST", "/api/auth/login", map[string]any{"email": "member@test.com", "password": "oldpass123"}); status != 401 {
+		t.Fatalf("login with old password: expected 401, got %d", status)
+	}
+	if status, _ := doAuthRequest(t, app, "POST", "/api/auth/login", map[string]any{"email": "member@test.com", "password": "newpass123"}); status != 200 {
+		t.Fatalf("login with new password: expected 200, got %d", status)
+	}
+}
+
+func TestLogin_ResetRequiredIsOnlyRevealedToTheRightPassword(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	hash, err := HashPassword("rightpass1")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf("INSERT INTO _users (id, email, password_hash, password_reset_required) VALUES (%s, %s, %s, %s)",
+			pb.Add(store.GenerateUUID()), pb.Add("imported@test.com"), pb.Add(hash), pb.Add(true)),
+		pb.Params()...); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if appErr, ok := err.(*engine.AppError); ok {
+				return c.Status(appErr.Status).JSON(engine.ErrorResponse{Error: appErr})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		},
+	})
+	app.Post("/api/auth/login", NewAuthHandler(s, TokenKeys{Secret: "test-secret"}).Login)
+
+	message := func(password string) string {
+		t.Helper()
+		status, res := doAuthRequest(t, app, "POST", "/api/auth/login", map[string]any{"email": "imported@test.com", "password": password})
+		if status != 401 {
+			t.Fatalf("expected 401, got %d %v", status, res)
+		}
+		return res["error"].(map[string]any)["message"].(string)
+	}
+	if got := message("wrongpass1"); got != "Invalid email or password" {
+		t.Fatalf("expected the generic error for a wrong password, got %q", got)
+	}
+	if got := message("rightpass1"); got != "Password reset required" {
+		t.Fatalf("expected the reset to be reported once the password checks out, got %q", got)
+	}
+}
@@ -12,7 +12,7 @@ import (
 
 // AuthMiddleware returns a Fiber middleware that validates JWT tokens
 // and sets the UserContext on the request.
-func AuthMiddleware(secret string) fiber.Handler {
+func AuthMiddleware(keys TokenKeys) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.UserContext()
 		ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "auth", "middleware", "auth.validate")
@@ -33,7 +33,7 @@ func AuthMiddleware(secret string) fiber.Handler {
 			return engine.UnauthorizedError("Invalid auth header format")
 		}
 
-		claims, err := ParseAccessToken(parts[1], secret)
+		claims, err := keys.Parse(parts[1])
 		if err != nil {
 			span.SetStatus("error")
 			span.SetMetadata("error", "invalid or expired token")
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/store"
+)
+
+// RefreshTables names the tables a token pair is issued against. App and
+// platform auth share the issue/rotate/revoke logic but keep separate users.
+type RefreshTables struct {
+	Users  string
+	Tokens string
+}
+
+var (
+	AppRefreshTables      = RefreshTables{Users: "_users", Tokens: "_refresh_tokens"}
+	PlatformRefreshTables = RefreshTables{Users: "_platform_users", Tokens: "_platform_refresh_tokens"}
+)
+
+// IssueTokenPair signs an access token with keys and stores a new refresh
+// token for the user.
+func IssueTokenPair(ctx context.Context, s *store.Store, tables RefreshTables, keys TokenKeys, userID string, roles []string) (*TokenPair, error) {
+	accessToken, err := keys.Sign(userID, roles)
+	if err != nil {
+		return nil, engine.NewAppError("INTERNAL_ERROR", 500, "Failed to generate access token")
+	}
+
+	refreshToken := GenerateRefreshToken()
+	// UTC drops the monotonic reading, which SQLite would otherwise store as text
+	expiresAt := time.Now().Add(RefreshTokenTTL).UTC()
+
+	pb := s.Dialect.NewParamBuilder()
+	_, err = store.Exec(ctx, s.DB,
+		fmt.Sprintf(`INSERT INTO %s (user_id, token, expires_at) VALUES (%s, %s, %s)`,
+			tables.Tokens, pb.Add(userID), pb.Add(refreshToken), pb.Add(expiresAt)),
+		pb.Params()...)
+	if err != nil {
+		return nil, engine.NewAppError("INTERNAL_ERROR", 500, "Failed to store refresh token")
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// RotateRefreshToken consumes a refresh token and returns its user's ID and
+// current roles. Each token works once; expired tokens are deleted and
+// refused, as are tokens of disabled users.
+func RotateRefreshToken(ctx context.Context, s *store.Store, tables RefreshTables, token string) (string, []string, error) {
+	pb := s.Dialect.NewParamBuilder()
+	row, err := store.QueryRow(ctx, s.DB,
+		fmt.Sprintf(`SELECT rt.user_id, rt.expires_at, u.roles, u.active
+		 FROM %s rt
+		 JOIN %s u ON u.id = rt.user_id
+		 WHERE rt.token = %s`, tables.Tokens, tables.Users, pb.Add(token)),
+		pb.Params()...)
+	if err != nil {
+		return "", nil, engine.UnauthorizedError("Invalid refresh token")
+	}
+
+	if refreshTokenExpired(row["expires_at"]) {
+		RevokeRefreshToken(ctx, s, tables, token)
+		return "", nil, engine.UnauthorizedError("Refresh token expired")
+	}
+
+	if !toBool(row["active"]) {
+		return "", nil, engine.UnauthorizedError("Account is disabled")
+	}
+
+	// Deleting by token (ids are unset on SQLite) also settles concurrent
+	// refreshes of the same token: only the one that deletes it wins
+	pb2 := s.Dialect.NewParamBuilder()
+	n, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf("DELETE FROM %s WHERE token = %s", tables.Tokens, pb2.Add(token)), pb2.Params()...)
+	if err != nil || n == 0 {
+		return "", nil, engine.UnauthorizedError("Invalid refresh token")
+	}
+
+	userID, _ := row["user_id"].(string)
+	return userID, extractRoles(row["roles"]), nil
+}
+
+// RevokeRefreshToken deletes a refresh token. Unknown tokens are ignored.
+func RevokeRefreshToken(ctx context.Context, s *store.Store, tables RefreshTables, token string) {
+	pb := s.Dialect.NewParamBuilder()
+	_, _ = store.Exec(ctx, s.DB,
+		fmt.Sprintf("DELETE FROM %s WHERE token = %s", tables.Tokens, pb.Add(token)), pb.Params()...)
+}
+
+// RevokeUserRefreshTokens deletes every refresh token of the user, signing
+// them out of all sessions once their access tokens expire.
+func RevokeUserRefreshTokens(ctx context.Context, s *store.Store, tables RefreshTables, userID string) error {
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf("DELETE FROM %s WHERE user_id = %s", tables.Tokens, pb.Add(userID)), pb.Params()...); err != nil {
+		return fmt.Errorf("revoke refresh tokens of user %s: %w", userID, err)
+	}
+	return nil
+}
+
+// refreshTokenExpired reports whether a stored expiry has passed. Postgres
+// returns a time.Time; SQLite returns the text the time was written as.
+// Anything unparseable counts as expired.
+func refreshTokenExpired(v any) bool {
+	switch val := v.(type) {
+	case time.Time:
+		return time.Now().After(val)
+	case string:
+		// Rows written before expiries were stored in UTC carry a monotonic suffix
+		if i := strings.Index(val, " m="); i >= 0 {
+			val = val[:i]
+		}
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999 -0700 MST", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, val); err == nil {
+				return time.Now().After(t)
+			}
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/spf13/viper"
 )
@@ -12,6 +13,89 @@ type InstrumentationConfig struct {
 	SamplingRate   float64 `mapstructure:"sampling_rate"`
 	BufferSize     int     `mapstructure:"buffer_size"`
 	FlushIntervalMs int    `mapstructure:"flush_interval_ms"`
+	MaxPageSize     int    `mapstructure:"max_page_size"` // cap on per_page for GET /_events
+}
+
+type WebhookConfig struct {
+	LogRetentionDays       int `mapstructure:"log_retention_days"`        // delivered logs older than this are purged
+	FailedLogRetentionDays int `mapstructure:"failed_log_retention_days"` // failed logs are kept longer for investigation
+	DedupWindowSeconds     int `mapstructure:"dedup_window_seconds"`      // identical events delivered within this window are not re-sent (0 disables)
+
+	AllowedTargets       []string `mapstructure:"allowed_targets"`        // hosts, *.domains, IPs or CIDRs webhooks may call; empty allows any not denied
+	DeniedTargets        []string `mapstructure:"denied_targets"`         // hosts, *.domains, IPs or CIDRs webhooks may never call
+	AllowInternalTargets bool     `mapstructure:"allow_internal_targets"` // permit loopback and link-local targets (local development only)
+
+	SecretKey string `mapstructure:"secret_key"` // encrypts webhook signing secrets at rest; empty stores them as plaintext
+}
+
+type WorkflowConfig struct {
+	MaxSteps            int `mapstructure:"max_steps"`             // step executions allowed in one run before the instance is failed
+	RetryMaxAttempts    int `mapstructure:"retry_max_attempts"`    // automatic retries of an instance failed by a transient error; 0 disables
+	RetryBackoffSeconds int `mapstructure:"retry_backoff_seconds"` // delay before the first retry, doubled for each further attempt
+}
+
+type QueryConfig struct {
+	MaxIncludeDepth   int `mapstructure:"max_include_depth"`   // segments allowed in a nested include path (order.customer.account is 3)
+	MaxIncludeRecords int `mapstructure:"max_include_records"` // related records loaded per request across all includes
+	MaxCacheEntries   int `mapstructure:"max_cache_entries"`   // entries each app's read cache holds before evicting; 0 is unbounded
+}
+
+// PaginationConfig sizes list pages separately for the dynamic API and the
+// admin API, so neither family's needs set the other's limits.
+type PaginationConfig struct {
+	API   PageSizeConfig `mapstructure:"api"`   // /api/:entity lists, search, related lists and workflow instances
+	Admin PageSizeConfig `mapstructure:"admin"` // admin webhook log and audit log lists
+}
+
+// PageSizeConfig is the per_page used when a request omits it, and the largest
+// one accepted.
+type PageSizeConfig struct {
+	DefaultPerPage int `mapstructure:"default_per_page"`
+	MaxPerPage     int `mapstructure:"max_per_page"`
+}
+
+type WriteConfig struct {
+	StrictFields   bool   `mapstructure:"strict_fields"`    // reject unknown keys in write bodies with 422 instead of dropping them (default false); entities can override
+	RuleOrder      string `mapstructure:"rule_order"`       // "phased" (field, expression, then computed rules) or "priority" (one pass in priority order); entities can override
+	ExprTimeoutMs  int    `mapstructure:"expr_timeout_ms"`  // longest a rule, guard or condition expression may run; 0 disables the limit
+	EmptyStrings   string `mapstructure:"empty_strings"`    // "keep" (as sent), "null" ("" becomes null) or "empty" (null becomes "" on string/text fields); fields can override
+	MaxBulkRecords int    `mapstructure:"max_bulk_records"` // most records one POST /api/:entity/_bulk may create
+}
+
+type AdminConfig struct {
+	AllowTruncate              bool `mapstructure:"allow_truncate"`               // enable POST /_admin/entities/:name/truncate; keep false in production
+	ImportMigrationConcurrency int  `mapstructure:"import_migration_concurrency"` // entity tables POST /_admin/import creates at once
+}
+
+type AuthConfig struct {
+	NormalizeEmails bool `mapstructure:"normalize_emails"` // trim and lowercase user emails before storing them; lookups ignore case either way
+}
+
+// JWTConfig selects how access tokens are signed. HS256 (the default) uses
+// each app's generated secret and platform_jwt_secret. RS256 and ES256 sign
+// all tokens with one key pair, named in the kid header.
+type JWTConfig struct {
+	Algorithm      string          `mapstructure:"algorithm"`        // HS256, RS256 or ES256
+	KeyID          string          `mapstructure:"key_id"`           // kid of the signing key
+	PrivateKeyFile string          `mapstructure:"private_key_file"` // PEM; RSA for RS256, P-256 for ES256
+	RetiredKeys    []RetiredJWTKey `mapstructure:"retired_keys"`     // previous keys still accepted for verification
+}
+
+// RetiredJWTKey is a previous signing key. Tokens signed with it verify until
+// AcceptUntil, so a rotation doesn't log everyone out.
+type RetiredJWTKey struct {
+	KeyID         string `mapstructure:"key_id"`
+	PublicKeyFile string `mapstructure:"public_key_file"`
+	AcceptUntil   string `mapstructure:"accept_until"` // RFC 3339
+}
+
+// BootstrapConfig seeds a new app database: the first admin user (only when
+// _users is empty) and the roles listed in _roles.
+type BootstrapConfig struct {
+	AdminEmail    string   `mapstructure:"admin_email"`
+	AdminPassword string   `mapstructure:"admin_password"`
+	AdminRoles    []string `mapstructure:"admin_roles"`
+	Roles         []string `mapstructure:"roles"` // extra roles seeded into _roles
 }
 
 type AIConfig struct {
@@ -26,10 +110,21 @@ func (a AIConfig) Configured() bool {
 
 type Config struct {
 	Server            ServerConfig          `mapstructure:"server"`
+	Security          SecurityConfig        `mapstructure:"security"`
+	CORS              CORSConfig            `mapstructure:"cors"`
 	Database          DatabaseConfig        `mapstructure:"database"`
 	Storage           StorageConfig         `mapstructure:"storage"`
 	Instrumentation   InstrumentationConfig `mapstructure:"instrumentation"`
+	Webhooks          WebhookConfig         `mapstructure:"webhooks"`
+	Workflows         WorkflowConfig        `mapstructure:"workflows"`
+	Query             QueryConfig           `mapstructure:"query"`
+	Writes            WriteConfig           `mapstructure:"writes"`
+	Pagination        PaginationConfig      `mapstructure:"pagination"`
+	Bootstrap         BootstrapConfig       `mapstructure:"bootstrap"`
+	Auth              AuthConfig            `mapstructure:"auth"`
+	Admin             AdminConfig           `mapstructure:"admin"`
 	AI                AIConfig              `mapstructure:"ai"`
+	JWT               JWTConfig             `mapstructure:"jwt"`
 	JWTSecret         string                `mapstructure:"jwt_secret"`
 	PlatformJWTSecret string                `mapstructure:"platform_jwt_secret"`
 	AppPoolSize       int                   `mapstructure:"app_pool_size"`
@@ -42,7 +137,26 @@ type StorageConfig struct {
 }
 
 type ServerConfig struct {
-	Port int `mapstructure:"port"`
+	Port                  int      `mapstructure:"port"`
+	RequestTimeoutMs      int      `mapstructure:"request_timeout_ms"`      // overall budget per request, answered with 503 when exceeded; 0 disables it
+	RequestTimeoutExclude []string `mapstructure:"request_timeout_exclude"` // path fragments exempt from the budget, e.g. file transfers and event streams
+}
+
+// SecurityConfig sets the security headers added to every response. File
+// downloads and event streams don't get X-Frame-Options or the CSP.
+type SecurityConfig struct {
+	Headers               bool   `mapstructure:"headers"`                 // add the headers below; false leaves responses untouched
+	FrameOptions          string `mapstructure:"frame_options"`           // X-Frame-Options, e.g. DENY or SAMEORIGIN; empty omits it
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"` // empty omits the header
+	ReferrerPolicy        string `mapstructure:"referrer_policy"`         // empty omits the header
+	HSTSMaxAge            int    `mapstructure:"hsts_max_age"`            // seconds; Strict-Transport-Security is only sent over HTTPS, 0 disables it
+}
+
+// CORSConfig lets browsers on other origins call the API.
+type CORSConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	AllowOrigins string `mapstructure:"allow_origins"` // comma-separated origins, or * for any
+	MaxAge       int    `mapstructure:"max_age"`       // seconds browsers may cache a preflight response; 0 disables caching
 }
 
 type DatabaseConfig struct {
@@ -54,6 +168,18 @@ type DatabaseConfig struct {
 	Name     string `mapstructure:"name"`
 	PoolSize int    `mapstructure:"pool_size"`
 	Path     string `mapstructure:"path"` // directory for SQLite database files
+
+	TablePrefix string `mapstructure:"table_prefix"` // prepended to every entity and join table; {app} is replaced by the app name
+
+	ConnectRetries      int `mapstructure:"connect_retries"`        // extra attempts when the platform database is not reachable yet at startup; app pools never retry
+	ConnectRetryDelayMs int `mapstructure:"connect_retry_delay_ms"` // initial delay, doubled after each failed attempt
+
+	ReplicaHost      string `mapstructure:"replica_host"`        // optional Postgres read replica (same credentials and database name)
+	ReplicaPort      int    `mapstructure:"replica_port"`        // defaults to port
+	ReadYourWritesMs int    `mapstructure:"read_your_writes_ms"` // after a client's write, its reads go to the primary for this long (0 disables)
+
+	ApplicationName string `mapstructure:"application_name"` // Postgres application_name of every connection, shown in pg_stat_activity
+	QueryTags       bool   `mapstructure:"query_tags"`       // prefix request queries with a /* method route trace */ comment
 }
 
 // DSN returns the driver-specific data source name.
@@ -61,8 +187,12 @@ func (d DatabaseConfig) DSN() string {
 	if d.Driver == "sqlite" {
 		return d.Path + "/" + d.Name + ".db"
 	}
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		d.User, d.Password, d.Host, d.Port, d.Name)
+	if d.ApplicationName != "" {
+		dsn += "&application_name=" + url.QueryEscape(d.ApplicationName)
+	}
+	return dsn
 }
 
 // ConnString returns the PostgreSQL connection string (for backward compatibility).
@@ -83,11 +213,24 @@ func Load() (*Config, error) {
 	viper.AddConfigPath("../..")
 
 	viper.SetDefault("server.port", 8080)
+	viper.SetDefault("server.request_timeout_ms", 30000)
+	viper.SetDefault("server.request_timeout_exclude", []string{"/_files/", "/_admin/ai/generate"})
 	viper.SetDefault("database.driver", "postgres")
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 5432)
 	viper.SetDefault("database.pool_size", 10)
 	viper.SetDefault("database.path", "./data")
+	viper.SetDefault("database.connect_retries", 5)
+	viper.SetDefault("database.connect_retry_delay_ms", 1000)
+	viper.SetDefault("database.application_name", "rocket-backend")
+	viper.SetDefault("security.headers", true)
+	viper.SetDefault("security.frame_options", "DENY")
+	viper.SetDefault("security.content_security_policy", "default-src 'none'; frame-ancestors 'none'")
+	viper.SetDefault("security.referrer_policy", "no-referrer")
+	viper.SetDefault("security.hsts_max_age", 31536000)
+	viper.SetDefault("cors.enabled", false)
+	viper.SetDefault("cors.allow_origins", "*")
+	viper.SetDefault("cors.max_age", 600)
 	viper.SetDefault("jwt_secret", "changeme-secret")
 	viper.SetDefault("platform_jwt_secret", "changeme-platform-secret")
 	viper.SetDefault("app_pool_size", 5)
@@ -99,6 +242,32 @@ func Load() (*Config, error) {
 	viper.SetDefault("instrumentation.sampling_rate", 1.0)
 	viper.SetDefault("instrumentation.buffer_size", 500)
 	viper.SetDefault("instrumentation.flush_interval_ms", 100)
+	viper.SetDefault("instrumentation.max_page_size", 100)
+	viper.SetDefault("webhooks.log_retention_days", 30)
+	viper.SetDefault("webhooks.failed_log_retention_days", 90)
+	viper.SetDefault("webhooks.dedup_window_seconds", 60)
+	viper.SetDefault("workflows.max_steps", 100)
+	viper.SetDefault("workflows.retry_max_attempts", 0)
+	viper.SetDefault("workflows.retry_backoff_seconds", 30)
+	viper.SetDefault("query.max_include_depth", 3)
+	viper.SetDefault("query.max_include_records", 5000)
+	viper.SetDefault("query.max_cache_entries", 10000)
+	viper.SetDefault("pagination.api.default_per_page", 25)
+	viper.SetDefault("pagination.api.max_per_page", 100)
+	viper.SetDefault("pagination.admin.default_per_page", 200)
+	viper.SetDefault("pagination.admin.max_per_page", 1000)
+	viper.SetDefault("writes.strict_fields", false)
+	viper.SetDefault("writes.rule_order", "phased")
+	viper.SetDefault("writes.expr_timeout_ms", 100)
+	viper.SetDefault("writes.empty_strings", "keep")
+	viper.SetDefault("writes.max_bulk_records", 1000)
+	viper.SetDefault("jwt.algorithm", "HS256")
+	viper.SetDefault("auth.normalize_emails", true)
+	viper.SetDefault("admin.allow_truncate", false)
+	viper.SetDefault("admin.import_migration_concurrency", 4)
+	viper.SetDefault("bootstrap.admin_email", "admin@localhost")
+	viper.SetDefault("bootstrap.admin_password", "changeme")
+	viper.SetDefault("bootstrap.admin_roles", []string{"admin"})
 
 	viper.AutomaticEnv()
 
@@ -107,6 +276,15 @@ func Load() (*Config, error) {
 	_ = viper.BindEnv("ai.api_key", "ROCKET_AI_API_KEY")
 	_ = viper.BindEnv("ai.model", "ROCKET_AI_MODEL")
 
+	// Bind bootstrap env vars; role lists are comma-separated
+	_ = viper.BindEnv("bootstrap.admin_email", "ROCKET_BOOTSTRAP_ADMIN_EMAIL")
+	_ = viper.BindEnv("bootstrap.admin_password", "ROCKET_BOOTSTRAP_ADMIN_PASSWORD")
+	_ = viper.BindEnv("bootstrap.admin_roles", "ROCKET_BOOTSTRAP_ADMIN_ROLES")
+	_ = viper.BindEnv("bootstrap.roles", "ROCKET_BOOTSTRAP_ROLES")
+
+	// Keep the webhook secret key out of app.yaml (ROCKET_WEBHOOK_SECRET_KEY)
+	_ = viper.BindEnv("webhooks.secret_key", "ROCKET_WEBHOOK_SECRET_KEY")
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
@@ -115,6 +293,27 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
+	if cfg.Writes.RuleOrder != "phased" && cfg.Writes.RuleOrder != "priority" {
+		return nil, fmt.Errorf("writes.rule_order must be \"phased\" or \"priority\", got %q", cfg.Writes.RuleOrder)
+	}
+	if cfg.Workflows.RetryMaxAttempts < 0 || cfg.Workflows.RetryBackoffSeconds < 0 {
+		return nil, fmt.Errorf("workflows.retry_max_attempts and workflows.retry_backoff_seconds must not be negative")
+	}
+	if cfg.Admin.ImportMigrationConcurrency < 1 {
+		return nil, fmt.Errorf("admin.import_migration_concurrency must be at least 1, got %d", cfg.Admin.ImportMigrationConcurrency)
+	}
+	if cfg.Server.RequestTimeoutMs < 0 {
+		return nil, fmt.Errorf("server.request_timeout_ms must not be negative, got %d", cfg.Server.RequestTimeoutMs)
+	}
+	if cfg.Writes.ExprTimeoutMs < 0 {
+		return nil, fmt.Errorf("writes.expr_timeout_ms must not be negative, got %d", cfg.Writes.ExprTimeoutMs)
+	}
+	if cfg.Writes.EmptyStrings != "keep" && cfg.Writes.EmptyStrings != "null" && cfg.Writes.EmptyStrings != "empty" {
+		return nil, fmt.Errorf("writes.empty_strings must be \"keep\", \"null\" or \"empty\", got %q", cfg.Writes.EmptyStrings)
+	}
+	if cfg.Writes.MaxBulkRecords < 1 {
+		return nil, fmt.Errorf("writes.max_bulk_records must be at least 1, got %d", cfg.Writes.MaxBulkRecords)
+	}
 
 	return &cfg, nil
 }
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// AfterRuleTarget is the column an after_write or after_delete computed rule
+// writes: a field of the record itself, or of the records at the other end
+// of one of its relations.
+type AfterRuleTarget struct {
+	Entity *metadata.Entity
+	Field  *metadata.Field
+	// KeyColumn and RecordKey select the rows to update: those whose
+	// KeyColumn equals the record's RecordKey.
+	KeyColumn string
+	RecordKey string
+}
+
+// ResolveAfterRuleTarget resolves the field of an after-hook computed rule.
+// A plain field name targets the record's own row and is only valid for
+// after_write, since a deleted row can't be updated. "relation.field"
+// targets the related records: the parent the record points at, or the
+// children that point at it. Many-to-many relations are not supported.
+func ResolveAfterRuleTarget(reg *metadata.Registry, entity *metadata.Entity, hook, field string) (*AfterRuleTarget, error) {
+	if field == "" {
+		return nil, fmt.Errorf("%s rules need a field to write", hook)
+	}
+	relName, fieldName, related := strings.Cut(field, ".")
+	if !related {
+		if hook == "after_delete" {
+			return nil, fmt.Errorf("after_delete rules must write a related field (relation.field), not %s", field)
+		}
+		f := entity.GetField(field)
+		if f == nil {
+			return nil, fmt.Errorf("unknown field %s on %s", field, entity.Name)
+		}
+		return &AfterRuleTarget{Entity: entity, Field: f, KeyColumn: entity.PrimaryKey.Field, RecordKey: entity.PrimaryKey.Field}, nil
+	}
+
+	rel := reg.FindRelationForEntity(relName, entity.Name)
+	if rel == nil {
+		return nil, fmt.Errorf("unknown relation %s on %s", relName, entity.Name)
+	}
+	if rel.IsManyToMany() {
+		return nil, fmt.Errorf("relation %s is many_to_many; after-hook rules can't write through it", rel.Name)
+	}
+	target := &AfterRuleTarget{}
+	var targetName string
+	if rel.Source == entity.Name && (rel.Name == relName || rel.Target != entity.Name) {
+		// The record is the parent: update the children pointing at it
+		targetName, target.KeyColumn, target.RecordKey = rel.Target, rel.TargetKey, rel.SourceKey
+	} else {
+		// The record is the child: update the parent it points at
+		targetName, target.KeyColumn, target.RecordKey = rel.Source, rel.SourceKey, rel.TargetKey
+	}
+	target.Entity = reg.GetEntity(targetName)
+	if target.Entity == nil {
+		return nil, fmt.Errorf("unknown entity %s", targetName)
+	}
+	target.Field = target.Entity.GetField(fieldName)
+	if target.Field == nil {
+		return nil, fmt.Errorf("unknown field %s on %s", fieldName, targetName)
+	}
+	return target, nil
+}
+
+// runAfterRules evaluates the entity's after_write or after_delete computed
+// rules once the write has committed, and stores each result in its target
+// column. A failing rule can't undo the write: it is logged and recorded as
+// an errored rules.after event, and the remaining rules still run.
+func runAfterRules(ctx context.Context, s *store.Store, reg *metadata.Registry, entity *metadata.Entity, hook, action string, record, old map[string]any) {
+	rules := reg.GetRulesForEntity(entity.Name, hook)
+	if len(rules) == 0 {
+		return
+	}
+	env := map[string]any{
+		"record": record,
+		"old":    old,
+		"action": action,
+	}
+	for _, r := range rules {
+		if r.Type != "computed" {
+			continue
+		}
+		if err := applyAfterRule(ctx, s, reg, entity, r, env, record); err != nil {
+			log.Printf("ERROR: %s rule %s for %s: %v", hook, r.ID, entity.Name, err)
+		}
+	}
+}
+
+// applyAfterRule evaluates one after-hook computed rule and writes the value.
+func applyAfterRule(ctx context.Context, s *store.Store, reg *metadata.Registry, entity *metadata.Entity, r *metadata.Rule, env, record map[string]any) error {
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "rules", "rules.after")
+	defer span.End()
+	span.SetEntity(r.Entity, fmt.Sprintf("%v", record[entity.PrimaryKey.Field]))
+	span.SetMetadata("rule_id", r.ID)
+	span.SetMetadata("hook", r.Hook)
+
+	fail := func(err error) error {
+		span.SetStatus(ruleSpanStatus(err))
+		span.SetMetadata("error", err.Error())
+		return err
+	}
+
+	target, err := ResolveAfterRuleTarget(reg, entity, r.Hook, r.Definition.Field)
+	if err != nil {
+		return fail(err)
+	}
+	key := record[target.RecordKey]
+	if key == nil {
+		span.SetStatus("ok")
+		return nil
+	}
+
+	var val any
+	if target.Field.Type == "decimal" {
+		val, err = EvaluateDecimalComputedField(ctx, r, env, target.Field.Precision)
+	} else {
+		val, err = EvaluateComputedField(ctx, r, env)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	pb := s.Dialect.NewParamBuilder()
+	sql := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s",
+		target.Entity.Table, target.Field.Name, pb.Add(val), target.KeyColumn, pb.Add(key))
+	if target.Entity != entity && target.Entity.SoftDelete {
+		sql += " AND deleted_at IS NULL"
+	}
+	if _, err := store.Exec(ctx, s.DB, sql, pb.Params()...); err != nil {
+		return fail(fmt.Errorf("update %s.%s: %w", target.Entity.Table, target.Field.Name, err))
+	}
+	invalidateEntityCache(reg, target.Entity.Name)
+	span.SetStatus("ok")
+	return nil
+}
@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestAfterRules_WriteRelatedRecordAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	events := instrument.NewEventBuffer(s.DB, s.Dialect, 1000, 60000)
+	defer events.Stop()
+
+	post := &metadata.Entity{
+		Name:       "post",
+		Table:      "posts",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string"},
+			{Name: "last_comment", Type: "string", Nullable: true},
+			{Name: "comment_score", Type: "int", Nullable: true},
+		},
+	}
+	comment := &metadata.Entity{
+		Name:       "comment",
+		Table:      "comments",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "post_id", Type: "uuid"},
+			{Name: "body", Type: "string"},
+			{Name: "summary", Type: "string", Nullable: true},
+		},
+	}
+	m := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{post, comment} {
+		if err := m.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{post, comment}, []*metadata.Relation{{
+		Name: "comments", Type: "one_to_many", Source: "post", Target: "comment",
+		SourceKey: "id", TargetKey: "post_id", Ownership: "none",
+	}})
+	reg.LoadRules([]*metadata.Rule{
+		{
+			ID: "denormalize", Entity: "comment", Hook: "after_write", Type: "computed", Active: true, Priority: 1,
+			Definition: metadata.RuleDefinition{Field: "post.last_comment", Expression: "action + ': ' + record.body"},
+		},
+		{
+			ID: "stamp", Entity: "comment", Hook: "after_write", Type: "computed", Active: true, Priority: 2,
+			Definition: metadata.RuleDefinition{Field: "summary", Expression: "upper(record.body)"},
+		},
+		{
+			ID: "broken", Entity: "comment", Hook: "after_write", Type: "computed", Active: true, Priority: 3,
+			Definition: metadata.RuleDefinition{Field: "post.comment_score", Expression: "int('not a number')"},
+		},
+		{
+			ID: "on_delete", Entity: "comment", Hook: "after_delete", Type: "computed", Active: true,
+			Definition: metadata.RuleDefinition{Field: "post.last_comment", Expression: "'removed: ' + record.body"},
+		},
+	})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(instrument.Middleware(config.InstrumentationConfig{Enabled: true, SamplingRate: 1},
+		func(*fiber.Ctx) *instrument.EventBuffer { return events }))
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+	app.Delete("/api/:entity/:id", h.Delete)
+
+	send := func(method, path string, body map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	stored := func(q string, args ...any) map[string]any {
+		t.Helper()
+		row, err := store.QueryRow(ctx, s.DB, q, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		return row
+	}
+
+	status, out := send("POST", "/api/post", map[string]any{"title": "Hello"})
+	if status != 201 {
+		t.Fatalf("create post: %d %v", status, out)
+	}
+	postID := out["data"].(map[string]any)["id"].(string)
+
+	// The failing rule doesn't abort the write or stop the rules after it
+	status, out = send("POST", "/api/comment", map[string]any{"post_id": postID, "body": "first"})
+	if status != 201 {
+		t.Fatalf("expected the comment to be created despite the failing after-rule, got %d %v", status, out)
+	}
+	commentID := out["data"].(map[string]any)["id"].(string)
+	if row := stored("SELECT last_comment, comment_score FROM posts WHERE id = ?1", postID); row["last_comment"] != "create: first" || row["comment_score"] != nil {
+		t.Fatalf("expected the post to carry the new comment and no score, got %v", row)
+	}
+	if row := stored("SELECT summary FROM comments WHERE id = ?1", commentID); row["summary"] != "FIRST" {
+		t.Fatalf("expected the comment's own row to be stamped, got %v", row)
+	}
+	// ...but it is recorded as an errored event
+	events.Flush()
+	if row := stored("SELECT COUNT(*) AS n FROM _events WHERE action = 'rules.after' AND status = 'error' AND metadata LIKE '%broken%'"); toInt(row["n"]) != 1 {
+		t.Fatalf("expected one errored rules.after event for the failing rule, got %v", row["n"])
+	}
+
+	status, out = send("PUT", "/api/comment/"+commentID, map[string]any{"post_id": postID, "body": "edited"})
+	if status != 200 {
+		t.Fatalf("update comment: %d %v", status, out)
+	}
+	if row := stored("SELECT last_comment FROM posts WHERE id = ?1", postID); row["last_comment"] != "update: edited" {
+		t.Fatalf("expected the post to follow the update, got %v", row)
+	}
+
+	if status, out := send("DELETE", "/api/comment/"+commentID, nil); status != 200 {
+		t.Fatalf("delete comment: %d %v", status, out)
+	}
+	if row := stored("SELECT last_comment FROM posts WHERE id = ?1", postID); row["last_comment"] != "removed: edited" {
+		t.Fatalf("expected the after_delete rule to see the deleted record, got %v", row)
+	}
+}
+
+func TestResolveAfterRuleTarget(t *testing.T) {
+	pk := metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{
+		{Name: "post", Table: "posts", PrimaryKey: pk, Fields: []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "comment_count", Type: "int"}}},
+		{Name: "comment", Table: "comments", PrimaryKey: pk, Fields: []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "post_id", Type: "uuid"}, {Name: "flagged", Type: "boolean"}}},
+		{Name: "tag", Table: "tags", PrimaryKey: pk, Fields: []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "label", Type: "string"}}},
+	}, []*metadata.Relation{
+		{Name: "comments", Type: "one_to_many", Source: "post", Target: "comment", SourceKey: "id", TargetKey: "post_id"},
+		{Name: "post_tags", Type: "many_to_many", Source: "post", Target: "tag", SourceKey: "id", JoinTable: "post_tags", SourceJoinKey: "post_id", TargetJoinKey: "tag_id"},
+	})
+	post, comment := reg.GetEntity("post"), reg.GetEntity("comment")
+
+	target, err := ResolveAfterRuleTarget(reg, comment, "after_write", "post.comment_count")
+	if err != nil || target.Entity.Name != "post" || target.KeyColumn != "id" || target.RecordKey != "post_id" {
+		t.Fatalf("expected the parent post keyed by post_id, got %+v (%v)", target, err)
+	}
+	target, err = ResolveAfterRuleTarget(reg, post, "after_delete", "comments.flagged")
+	if err != nil || target.Entity.Name != "comment" || target.KeyColumn != "post_id" || target.RecordKey != "id" {
+		t.Fatalf("expected the child comments keyed by post_id, got %+v (%v)", target, err)
+	}
+
+	for _, tc := range []struct{ hook, field string }{
+		{"after_write", ""},
+		{"after_write", "missing"},
+		{"after_delete", "comment_count"},
+		{"after_write", "nope.comment_count"},
+		{"after_write", "comments.missing"},
+		{"after_write", "post_tags.label"},
+	} {
+		if _, err := ResolveAfterRuleTarget(reg, post, tc.hook, tc.field); err == nil {
+			t.Errorf("expected %s %q to be rejected", tc.hook, tc.field)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"rocket-backend/internal/metadata"
+)
+
+// Array fields are stored as TEXT[] (PostgreSQL) or a JSON array of strings
+// (SQLite). Elements are written in a canonical string form for their item
+// type, so filters compare the same text on both dialects, and are converted
+// back to the item type on read.
+
+// arrayItemString validates one element against the item type and returns its
+// stored form. v is a decoded JSON value on writes and a raw string in filters.
+func arrayItemString(itemType string, v any) (string, error) {
+	switch itemType {
+	case "int":
+		switch n := v.(type) {
+		case float64:
+			if n != math.Trunc(n) {
+				return "", fmt.Errorf("%v is not an integer", n)
+			}
+			return strconv.FormatInt(int64(n), 10), nil
+		case int:
+			return strconv.Itoa(n), nil
+		case int64:
+			return strconv.FormatInt(n, 10), nil
+		case string:
+			i, err := strconv.ParseInt(n, 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("%q is not an integer", n)
+			}
+			return strconv.FormatInt(i, 10), nil
+		}
+		return "", fmt.Errorf("%v is not an integer", v)
+	case "float":
+		switch n := v.(type) {
+		case float64:
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(n), nil
+		case int64:
+			return strconv.FormatInt(n, 10), nil
+		case string:
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return "", fmt.Errorf("%q is not a number", n)
+			}
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return "", fmt.Errorf("%v is not a number", v)
+	case "boolean":
+		switch b := v.(type) {
+		case bool:
+			return strconv.FormatBool(b), nil
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return "", fmt.Errorf("%q is not a boolean", b)
+			}
+			return strconv.FormatBool(parsed), nil
+		}
+		return "", fmt.Errorf("%v is not a boolean", v)
+	case "uuid":
+		s, ok := v.(string)
+		if !ok || !uuidRE.MatchString(strings.ToLower(s)) {
+			return "", fmt.Errorf("%v is not a uuid", v)
+		}
+		return strings.ToLower(s), nil
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("%v is not a string", v)
+		}
+		return s, nil
+	}
+}
+
+// encodeArray converts a write value for an array field to its stored
+// elements. nil stays nil so the column can be cleared.
+func encodeArray(f *metadata.Field, val any) ([]string, error) {
+	if val == nil {
+		return nil, nil
+	}
+	var items []any
+	switch v := val.(type) {
+	case []any:
+		items = v
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be an array", f.Name)
+	}
+	out := make([]string, 0, len(items))
+	for i, item := range items {
+		s, err := arrayItemString(f.ItemType(), item)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %v", f.Name, i, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// validateArrayField checks the value's shape, element types and, when the
+// field has an enum, that every element is one of the options (multi-select).
+func validateArrayField(f *metadata.Field, val any) *ErrorDetail {
+	items, err := encodeArray(f, val)
+	if err != nil {
+		return &ErrorDetail{Field: f.Name, Rule: "type", Message: err.Error()}
+	}
+	if len(f.Enum) == 0 {
+		return nil
+	}
+	for _, item := range items {
+		if !containsString(f.Enum, item) {
+			return &ErrorDetail{
+				Field:   f.Name,
+				Rule:    "enum",
+				Message: fmt.Sprintf("%s items must be one of: %s", f.Name, strings.Join(f.Enum, ", ")),
+			}
+		}
+	}
+	return nil
+}
+
+// decodeStoredFields replaces stored array columns in rows with typed slices,
+// and geopoint columns with {lat, lng} objects.
+func decodeStoredFields(entity *metadata.Entity, rows ...map[string]any) {
+	for _, f := range entity.Fields {
+		if f.Type != "array" && f.Type != "geopoint" {
+			continue
+		}
+		for _, row := range rows {
+			raw, ok := row[f.Name]
+			if !ok || raw == nil {
+				continue
+			}
+			if f.Type == "geopoint" {
+				row[f.Name] = decodeGeopoint(raw)
+				continue
+			}
+			if _, done := raw.([]any); done {
+				continue
+			}
+			row[f.Name] = decodeArrayItems(f.ItemType(), metadata.ParseStringArray(raw))
+		}
+	}
+}
+
+func decodeArrayItems(itemType string, items []string) []any {
+	out := make([]any, 0, len(items))
+	for _, s := range items {
+		var v any = s
+		switch itemType {
+		case "int":
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				v = n
+			}
+		case "float":
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				v = n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(s); err == nil {
+				v = b
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// coerceArrayFilter parses a contains (single element) or contains_any
+// (comma-separated elements) filter on an array field.
+func coerceArrayFilter(field *metadata.Field, val, op string) (any, error) {
+	switch op {
+	case "contains":
+		return arrayItemString(field.ItemType(), val)
+	case "contains_any":
+		parts := strings.Split(val, ",")
+		out := make([]string, 0, len(parts))
+		for _, p := range parts {
+			s, err := arrayItemString(field.ItemType(), strings.TrimSpace(p))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("operator %q is not supported on array fields (use contains or contains_any)", op)
+	}
+}
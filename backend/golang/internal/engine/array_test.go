@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestArrayField_WriteAndFilter(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "article",
+		Table:      "articles",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string"},
+			{Name: "tags", Type: "array", Enum: []string{"go", "sql", "web", "ops"}},
+			{Name: "ratings", Type: "array", Items: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+	app.Get("/api/:entity/:id", h.GetByID)
+	app.Post("/api/:entity", h.Create)
+
+	do := func(method, path string, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+	titles := func(filter string) []string {
+		status, out := do("GET", "/api/article?"+filter, nil)
+		if status != 200 {
+			t.Fatalf("list %s: %d %v", filter, status, out)
+		}
+		var got []string
+		for _, row := range out["data"].([]any) {
+			got = append(got, row.(map[string]any)["title"].(string))
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	for _, a := range []map[string]any{
+		{"title": "a", "tags": []any{"go", "sql"}, "ratings": []any{5, 4}},
+		{"title": "b", "tags": []any{"web"}},
+		{"title": "c", "tags": []any{"go", "ops"}, "ratings": []any{3}},
+	} {
+		if status, out := do("POST", "/api/article", a); status != 201 {
+			t.Fatalf("create %v: %d %v", a["title"], status, out)
+		}
+	}
+
+	_, out := do("GET", "/api/article?filter[title]=a", nil)
+	first := out["data"].([]any)[0].(map[string]any)
+	if !reflect.DeepEqual(first["tags"], []any{"go", "sql"}) || !reflect.DeepEqual(first["ratings"], []any{float64(5), float64(4)}) {
+		t.Fatalf("expected typed arrays back, got tags=%v ratings=%v", first["tags"], first["ratings"])
+	}
+	status, out := do("GET", "/api/article/"+first["id"].(string), nil)
+	if status != 200 || !reflect.DeepEqual(out["data"].(map[string]any)["tags"], []any{"go", "sql"}) {
+		t.Fatalf("expected get to decode the array, got %d %v", status, out)
+	}
+
+	if got := titles("filter[tags.contains]=go"); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Fatalf("contains: got %v", got)
+	}
+	if got := titles("filter[tags.contains_any]=" + url.QueryEscape("web,ops")); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("contains_any: got %v", got)
+	}
+	if got := titles("filter[ratings.contains]=3"); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Fatalf("int contains: got %v", got)
+	}
+
+	for name, body := range map[string]map[string]any{
+		"not an array":    {"title": "x", "tags": "go"},
+		"wrong item type": {"title": "x", "ratings": []any{"five"}},
+		"fraction in int": {"title": "x", "ratings": []any{4.5}},
+		"not in enum":     {"title": "x", "tags": []any{"go", "rust"}},
+	} {
+		if status, _ := do("POST", "/api/article", body); status != 422 {
+			t.Fatalf("%s: expected 422, got %d", name, status)
+		}
+	}
+	if status, _ := do("GET", "/api/article?filter[tags]=go", nil); status != 400 {
+		t.Fatalf("expected eq on an array field to be rejected, got %d", status)
+	}
+	if status, _ := do("GET", "/api/article?filter[title.contains]=a", nil); status != 400 {
+		t.Fatalf("expected contains on a scalar field to be rejected, got %d", status)
+	}
+}
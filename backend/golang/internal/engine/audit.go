@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// SystemActor is the changed_by of audit changes the engine made rather than
+// the client: auto timestamps, computed fields and values set by rules,
+// state machines or hooks.
+const SystemActor = "system"
+
+// AuditChange is the old and new value of one field changed by an update.
+// An audit entry's changes map field names to them.
+type AuditChange struct {
+	Old       any    `json:"old"`
+	New       any    `json:"new"`
+	ChangedBy string `json:"changed_by"`
+}
+
+// auditChanges lists the fields whose value differs between old and updated.
+// supplied holds the fields as the client sent them; a change is attributed to
+// the user only when the written value is the one they supplied.
+func auditChanges(entity *metadata.Entity, old, updated, supplied, written map[string]any, user *metadata.UserContext) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+	for _, f := range entity.Fields {
+		before, after := old[f.Name], updated[f.Name]
+		if fmt.Sprintf("%v", before) == fmt.Sprintf("%v", after) {
+			continue
+		}
+		by := SystemActor
+		if sent, ok := supplied[f.Name]; ok && user != nil && !f.IsAuto() &&
+			fmt.Sprintf("%v", sent) == fmt.Sprintf("%v", written[f.Name]) {
+			by = user.ID
+		}
+		changes[f.Name] = AuditChange{Old: before, New: after, ChangedBy: by}
+	}
+	return changes
+}
+
+// WriteAuditEntry records one change in _audit_log: an update of an audited
+// entity, an admin truncate (record id "*", no field changes), or a write
+// that skipped rules or webhooks (see Bypass).
+func WriteAuditEntry(ctx context.Context, q store.Querier, dialect store.Dialect, entity *metadata.Entity, recordID any, action string, user *metadata.UserContext, changes map[string]AuditChange) error {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("marshal audit changes: %w", err)
+	}
+	var userID any
+	if user != nil {
+		userID = user.ID
+	}
+	pb := dialect.NewParamBuilder()
+	_, err = store.Exec(ctx, q,
+		fmt.Sprintf("INSERT INTO _audit_log (id, entity, record_id, action, user_id, changes) VALUES (%s, %s, %s, %s, %s, %s)",
+			pb.Add(store.GenerateUUID()), pb.Add(entity.Name), pb.Add(fmt.Sprintf("%v", recordID)), pb.Add(action), pb.Add(userID), pb.Add(string(changesJSON))),
+		pb.Params()...)
+	if err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestAuditedUpdate_RecordsOnlyChangedFields(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "order_line",
+		Table:      "order_lines",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Audit:      true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "sku", Type: "string"},
+			{Name: "qty", Type: "int"},
+			{Name: "price", Type: "int"},
+			{Name: "total", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadRules([]*metadata.Rule{{
+		ID: "r1", Entity: "order_line", Hook: "before_write", Type: "computed", Active: true,
+		Definition: metadata.RuleDefinition{Field: "total", Expression: "record.qty * record.price"},
+	}})
+	user := &metadata.UserContext{ID: "u-42", Roles: []string{"clerk"}}
+
+	write := func(id any, body map[string]any) map[string]any {
+		plan, verrs := PlanWrite(entity, reg, body, id)
+		if len(verrs) > 0 {
+			t.Fatalf("unexpected validation errors: %v", verrs)
+		}
+		plan.User = user
+		rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return rec
+	}
+	id := write(nil, map[string]any{"sku": "A-1", "qty": 2, "price": 5})["id"]
+
+	// price is sent but unchanged; total changes through the computed rule
+	write(id, map[string]any{"qty": 3, "price": 5})
+
+	rows, err := store.QueryRows(ctx, s.DB, "SELECT record_id, action, user_id, changes FROM _audit_log WHERE entity = ?1", "order_line")
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected one audit entry for the update only, got %v", rows)
+	}
+	entry := rows[0]
+	if entry["record_id"] != id || entry["action"] != "update" || entry["user_id"] != "u-42" {
+		t.Fatalf("unexpected audit entry: %v", entry)
+	}
+	var got map[string]AuditChange
+	if err := json.Unmarshal([]byte(entry["changes"].(string)), &got); err != nil {
+		t.Fatalf("decode changes: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected only qty and total in the entry, got %+v", got)
+	}
+	if ch := got["qty"]; toInt(ch.Old) != 2 || toInt(ch.New) != 3 || ch.ChangedBy != "u-42" {
+		t.Fatalf("expected qty 2 -> 3 by the user, got %+v", ch)
+	}
+	if ch := got["total"]; toInt(ch.Old) != 10 || toInt(ch.New) != 15 || ch.ChangedBy != SystemActor {
+		t.Fatalf("expected total 10 -> 15 by system, got %+v", ch)
+	}
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// MaxBulkRecords caps the records of one POST /api/:entity/_bulk
+// (writes.max_bulk_records).
+var MaxBulkRecords = 1000
+
+type bulkCreated struct {
+	Index int `json:"index"`
+	ID    any `json:"id"`
+}
+
+type bulkFailed struct {
+	Index   int           `json:"index"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// BulkCreate handles POST /api/:entity/_bulk with {"records": [...]}. Each
+// record goes through the create pipeline (hooks, rules, state machines,
+// nested writes) inside one transaction, under a savepoint so a failing
+// record is skipped without undoing the others. ?atomic=true instead rolls
+// the whole batch back when any record fails. Like the admin bulk endpoints
+// it answers 200 when every record was created, 207 for a mix and 422 when
+// none was.
+func (h *Handler) BulkCreate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", "record.bulk_create")
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	entity, err := h.resolveEntity(c)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	span.SetEntity(entity.Name, "")
+
+	user := getUser(c)
+	if err := CheckPermission(c.Context(), user, entity.Name, "create", h.registry, nil); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	if err := checkWriteMode(c, user, entity, "create"); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	bypass, err := readBypass(c, user)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	var body struct {
+		Records []map[string]any `json:"records"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		span.SetStatus("error")
+		return respondError(c, NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body"))
+	}
+	if len(body.Records) == 0 {
+		span.SetStatus("error")
+		return respondError(c, ValidationError([]ErrorDetail{{Field: "records", Rule: "required", Message: "records must be a non-empty array"}}))
+	}
+	if len(body.Records) > MaxBulkRecords {
+		span.SetStatus("error")
+		return respondError(c, ValidationError([]ErrorDetail{{Field: "records", Rule: "max", Message: fmt.Sprintf("at most %d records per request", MaxBulkRecords)}}))
+	}
+	// The whole batch has to fit in what is left of the write limit
+	reservation, err := h.checkWriteLimit(c, entity, user, len(body.Records))
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	defer reservation.release()
+	atomic := c.QueryBool("atomic")
+
+	tx, err := h.store.BeginTx(c.Context())
+	if err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	created := []bulkCreated{}
+	failed := []bulkFailed{}
+	var plans []*WritePlan
+	var records []map[string]any
+	for i, rec := range body.Records {
+		if rec == nil {
+			rec = map[string]any{}
+		}
+		plan, failure := h.planBulkRecord(c, user, entity, rec)
+		var record map[string]any
+		if failure == nil {
+			plan.Bypass = bypass
+			if record, failure, err = h.applyBulkRecord(c, tx, plan); err != nil {
+				span.SetStatus("error")
+				span.SetMetadata("error", err.Error())
+				return err
+			}
+		}
+		if failure != nil {
+			failed = append(failed, bulkFailure(i, failure))
+			continue
+		}
+		plans = append(plans, plan)
+		records = append(records, record)
+		created = append(created, bulkCreated{Index: i, ID: record[entity.PrimaryKey.Field]})
+	}
+
+	if atomic && len(failed) > 0 {
+		span.SetStatus("error")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"data": fiber.Map{
+			"created": []bulkCreated{},
+			"failed":  failed,
+			"summary": fiber.Map{"total": len(body.Records), "created": 0, "failed": len(failed)},
+		}})
+	}
+	if err := tx.Commit(); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return fmt.Errorf("commit: %w", err)
+	}
+	if len(plans) > 0 {
+		invalidateEntityCache(h.registry, entity.Name)
+		h.markWrite(c)
+		reservation.keep(len(plans))
+		for i, plan := range plans {
+			finishWritePlan(ctx, h.store, h.registry, plan, records[i], map[string]any{})
+		}
+	}
+
+	span.SetStatus("ok")
+	return c.Status(bulkStatus(len(created), len(failed))).JSON(fiber.Map{"data": fiber.Map{
+		"created": created,
+		"failed":  failed,
+		"summary": fiber.Map{"total": len(body.Records), "created": len(created), "failed": len(failed)},
+	}})
+}
+
+// planBulkRecord applies the per-record checks of a create to one record of
+// a bulk request and plans its write.
+func (h *Handler) planBulkRecord(c *fiber.Ctx, user *metadata.UserContext, entity *metadata.Entity, rec map[string]any) (*WritePlan, error) {
+	stripProtectedFields(entity, user, "create", rec)
+	if err := h.applyScopeDefaults(c.Context(), entity, user, rec); err != nil {
+		return nil, err
+	}
+	if err := h.checkScopedWrite(c.Context(), entity, user, rec); err != nil {
+		return nil, err
+	}
+	if err := CheckRecordDenied(user, entity.Name, "create", h.registry, rec); err != nil {
+		return nil, err
+	}
+	plan, validationErrs := PlanWrite(entity, h.registry, rec, nil)
+	if len(validationErrs) > 0 {
+		return nil, ValidationError(validationErrs)
+	}
+	stripProtectedChildFields(h.registry, user, plan.ChildOps)
+	if err := checkChildWriteModes(c, user, h.registry, plan.ChildOps); err != nil {
+		return nil, err
+	}
+	plan.User = user
+	plan.Hooks = h.hooks.For(entity.Name)
+	return plan, nil
+}
+
+// applyBulkRecord writes one planned record under a savepoint, rolling back
+// to it when the write fails so the transaction can go on. failure is the
+// record's own error; err is a savepoint failure that ends the request.
+func (h *Handler) applyBulkRecord(c *fiber.Ctx, tx store.Querier, plan *WritePlan) (record map[string]any, failure, err error) {
+	if _, err := store.Exec(c.Context(), tx, "SAVEPOINT bulk_record"); err != nil {
+		return nil, nil, fmt.Errorf("savepoint: %w", err)
+	}
+	record, _, failure = applyWritePlan(c.Context(), tx, h.store, h.registry, plan)
+	if failure != nil {
+		if _, err := store.Exec(c.Context(), tx, "ROLLBACK TO SAVEPOINT bulk_record"); err != nil {
+			return nil, nil, fmt.Errorf("rollback to savepoint: %w", err)
+		}
+		return nil, failure, nil
+	}
+	if _, err := store.Exec(c.Context(), tx, "RELEASE SAVEPOINT bulk_record"); err != nil {
+		return nil, nil, fmt.Errorf("release savepoint: %w", err)
+	}
+	return record, nil, nil
+}
+
+// bulkFailure reports a record's error, with the details of a validation
+// error, as a failed entry.
+func bulkFailure(index int, err error) bulkFailed {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return bulkFailed{Index: index, Message: appErr.Message, Details: appErr.Details}
+	}
+	return bulkFailed{Index: index, Message: err.Error()}
+}
+
+// bulkStatus is the status of a bulk response: 200 when nothing failed, 422
+// when nothing succeeded and something failed, and 207 Multi-Status for a mix.
+func bulkStatus(succeeded, failed int) int {
+	switch {
+	case failed == 0:
+		return fiber.StatusOK
+	case succeeded == 0:
+		return fiber.StatusUnprocessableEntity
+	default:
+		return fiber.StatusMultiStatus
+	}
+}
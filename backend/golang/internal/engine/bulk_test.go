@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestBulkCreate_PartialAndAtomic(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "part",
+		Table:      "parts",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "sku", Type: "string", Required: true, Unique: true},
+			{Name: "qty", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadRules([]*metadata.Rule{{
+		ID: "r1", Entity: "part", Hook: "before_write", Type: "field", Active: true,
+		Definition: metadata.RuleDefinition{Field: "qty", Operator: "min", Value: float64(1), Message: "qty must be at least 1"},
+	}})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity/_bulk", h.BulkCreate)
+
+	send := func(path string, records []map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(map[string]any{"records": records})
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	count := func() int {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM parts")
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return toInt(row["n"])
+	}
+
+	status, out := send("/api/part/_bulk", []map[string]any{
+		{"sku": "A", "qty": 2},
+		{"sku": "B", "qty": 0},
+		{"sku": "A", "qty": 3},
+		{"sku": "C", "qty": 1},
+	})
+	if status != 207 {
+		t.Fatalf("expected 207 for a partial batch, got %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+	created := data["created"].([]any)
+	failed := data["failed"].([]any)
+	if len(created) != 2 || toInt(created[0].(map[string]any)["index"]) != 0 || toInt(created[1].(map[string]any)["index"]) != 3 {
+		t.Fatalf("expected records 0 and 3 created, got %v", created)
+	}
+	if len(failed) != 2 || toInt(failed[0].(map[string]any)["index"]) != 1 || toInt(failed[1].(map[string]any)["index"]) != 2 {
+		t.Fatalf("expected records 1 and 2 failed, got %v", failed)
+	}
+	if summary := data["summary"].(map[string]any); toInt(summary["total"]) != 4 || toInt(summary["created"]) != 2 || toInt(summary["failed"]) != 2 {
+		t.Fatalf("unexpected summary %v", summary)
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("expected 2 stored parts, got %d", n)
+	}
+
+	status, out = send("/api/part/_bulk?atomic=true", []map[string]any{
+		{"sku": "D", "qty": 1},
+		{"sku": "E", "qty": 0},
+	})
+	if status != 422 {
+		t.Fatalf("expected 422 for a failing atomic batch, got %d %v", status, out)
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("expected the atomic batch rolled back, got %d stored parts", n)
+	}
+
+	if status, out := send("/api/part/_bulk", nil); status != 422 {
+		t.Fatalf("expected 422 for an empty batch, got %d %v", status, out)
+	}
+}
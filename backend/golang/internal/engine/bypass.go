@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// SkipRulesHeader and SkipWebhooksHeader let an admin fixing or migrating data
+// write without side effects: X-Skip-Rules: true skips rule evaluation and
+// X-Skip-Webhooks: true skips webhook dispatch for that request. State
+// machines, hooks and workflows still run. Each bypass is recorded in
+// _audit_log as a skip_rules or skip_webhooks entry for the record.
+const (
+	SkipRulesHeader    = "X-Skip-Rules"
+	SkipWebhooksHeader = "X-Skip-Webhooks"
+)
+
+// Bypass is the set of side effects an admin request skips.
+type Bypass struct {
+	Rules    bool
+	Webhooks bool
+}
+
+// readBypass reads the skip headers of a request. Like the write-mode
+// override, anyone but an admin sending one gets a 403.
+func readBypass(c *fiber.Ctx, user *metadata.UserContext) (Bypass, error) {
+	b := Bypass{
+		Rules:    c.Get(SkipRulesHeader) == "true",
+		Webhooks: c.Get(SkipWebhooksHeader) == "true",
+	}
+	if (b.Rules || b.Webhooks) && (user == nil || !user.IsAdmin()) {
+		return Bypass{}, ForbiddenError("Only admins can skip rules or webhooks")
+	}
+	return b, nil
+}
+
+// auditBypass records each skipped side effect of a write to recordID.
+func auditBypass(ctx context.Context, q store.Querier, dialect store.Dialect, entity *metadata.Entity, recordID any, user *metadata.UserContext, b Bypass) error {
+	for _, skip := range []struct {
+		action  string
+		skipped bool
+	}{{"skip_rules", b.Rules}, {"skip_webhooks", b.Webhooks}} {
+		if !skip.skipped {
+			continue
+		}
+		if err := WriteAuditEntry(ctx, q, dialect, entity, recordID, skip.action, user, map[string]AuditChange{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestSkipRulesHeader_AdminBypassesValidationRule(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	invoice := &metadata.Entity{
+		Name:       "invoice",
+		Table:      "invoices",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "total", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, invoice); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{invoice}, nil)
+	reg.LoadRules([]*metadata.Rule{{
+		ID: "r1", Entity: "invoice", Hook: "before_write", Type: "field", Active: true,
+		Definition: metadata.RuleDefinition{Field: "total", Operator: "min", Value: float64(0), Message: "Total must be non-negative"},
+	}})
+	reg.LoadPermissions([]*metadata.Permission{{Entity: "invoice", Action: "create", Roles: []string{"clerk"}}})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{c.Get("X-Test-Role")}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+
+	create := func(role string, skip bool, total int) (int, map[string]any) {
+		t.Helper()
+		raw, _ := json.Marshal(map[string]any{"total": total})
+		req, _ := http.NewRequest("POST", "/api/invoice", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Role", role)
+		if skip {
+			req.Header.Set(SkipRulesHeader, "true")
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(body, &out)
+		return resp.StatusCode, out
+	}
+
+	if status, out := create("admin", false, -5); status != 422 {
+		t.Fatalf("expected the rule to reject a normal write, got %d %v", status, out)
+	}
+	if status, _ := create("clerk", true, -5); status != 403 {
+		t.Fatalf("expected 403 for a non-admin skipping rules, got %d", status)
+	}
+	status, out := create("admin", true, -5)
+	if status != 201 {
+		t.Fatalf("expected an admin write with skip-rules to pass, got %d %v", status, out)
+	}
+	id := out["data"].(map[string]any)["id"]
+
+	rows, err := store.QueryRows(ctx, s.DB, "SELECT record_id, action, user_id FROM _audit_log WHERE entity = 'invoice'")
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["action"] != "skip_rules" || rows[0]["record_id"] != id || rows[0]["user_id"] != "u1" {
+		t.Fatalf("expected one skip_rules audit entry for %v, got %v", id, rows)
+	}
+}
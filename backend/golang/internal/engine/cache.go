@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+)
+
+const defaultCacheTTL = 60 * time.Second
+
+// MaxCacheEntries caps the entries one app's read cache holds
+// (query.max_cache_entries). When it is full, expired and stale entries are
+// swept, then a random live one is evicted. 0 disables the cap.
+var MaxCacheEntries = 10_000
+
+// EntityCache holds list and get results for entities flagged cacheable.
+// Entries are keyed by the final SQL, so row-level permission filters are part
+// of the key; record-level checks still run on every request. A write to an
+// entity drops its entries and those that included it, and a registry reload
+// makes every entry stale.
+type EntityCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	stats   map[string]*CacheStats
+}
+
+// CacheStats counts lookups for one entity.
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+type cacheEntry struct {
+	entity  string
+	deps    []string // included entities whose writes also invalidate the entry
+	version uint64
+	expires time.Time
+	value   any
+}
+
+// entityCaches keeps one cache per registry, i.e. per app.
+var entityCaches sync.Map
+
+func entityCacheFor(reg *metadata.Registry) *EntityCache {
+	if c, ok := entityCaches.Load(reg); ok {
+		return c.(*EntityCache)
+	}
+	c, _ := entityCaches.LoadOrStore(reg, &EntityCache{
+		entries: make(map[string]*cacheEntry),
+		stats:   make(map[string]*CacheStats),
+	})
+	return c.(*EntityCache)
+}
+
+func (c *EntityCache) get(reg *metadata.Registry, entity, key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.statsFor(entity)
+	e, ok := c.entries[key]
+	if ok && (e.version != reg.Version() || time.Now().After(e.expires)) {
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		st.Misses++
+		return nil, false
+	}
+	st.Hits++
+	return e.value, true
+}
+
+func (c *EntityCache) put(reg *metadata.Registry, entity *metadata.Entity, deps []string, key string, value any) {
+	ttl := defaultCacheTTL
+	if entity.CacheTTL > 0 {
+		ttl = time.Duration(entity.CacheTTL) * time.Second
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && MaxCacheEntries > 0 && len(c.entries) >= MaxCacheEntries {
+		c.evict(reg)
+	}
+	c.entries[key] = &cacheEntry{
+		entity:  entity.Name,
+		deps:    deps,
+		version: reg.Version(),
+		expires: time.Now().Add(ttl),
+		value:   value,
+	}
+}
+
+// evict makes room for one entry: it drops every expired or stale entry, and
+// one random live entry if none was. Callers hold c.mu.
+func (c *EntityCache) evict(reg *metadata.Registry) {
+	now := time.Now()
+	freed := false
+	for key, e := range c.entries {
+		if e.version != reg.Version() || now.After(e.expires) {
+			delete(c.entries, key)
+			freed = true
+		}
+	}
+	if freed {
+		return
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+		return
+	}
+}
+
+// Invalidate drops every entry for the entity or that included it.
+func (c *EntityCache) Invalidate(entity string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if e.entity == entity || containsString(e.deps, entity) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Stats returns hit/miss counters and live entry counts per entity.
+func (c *EntityCache) Stats() map[string]CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]CacheStats, len(c.stats))
+	for name, st := range c.stats {
+		out[name] = CacheStats{Hits: st.Hits, Misses: st.Misses}
+	}
+	for _, e := range c.entries {
+		st := out[e.entity]
+		st.Entries++
+		out[e.entity] = st
+	}
+	return out
+}
+
+func (c *EntityCache) statsFor(entity string) *CacheStats {
+	st, ok := c.stats[entity]
+	if !ok {
+		st = &CacheStats{}
+		c.stats[entity] = st
+	}
+	return st
+}
+
+// EntityCacheStats reports cache counters for the app that owns reg.
+func EntityCacheStats(reg *metadata.Registry) map[string]CacheStats {
+	return entityCacheFor(reg).Stats()
+}
+
+// InvalidateEntityCache drops cached reads of entityName after a change made
+// outside the engine, such as an admin truncate.
+func InvalidateEntityCache(reg *metadata.Registry, entityName string) {
+	invalidateEntityCache(reg, entityName)
+}
+
+// invalidateEntityCache drops cached reads after a write to entityName. Related
+// entities are dropped too since cascades and nested writes reach them.
+func invalidateEntityCache(reg *metadata.Registry, entityName string) {
+	c, ok := entityCaches.Load(reg)
+	if !ok {
+		return
+	}
+	cache := c.(*EntityCache)
+	cache.Invalidate(entityName)
+	for _, rel := range reg.AllRelations() {
+		if rel.Source == entityName {
+			cache.Invalidate(rel.Target)
+		} else if rel.Target == entityName {
+			cache.Invalidate(rel.Source)
+		}
+	}
+}
+
+// cachedList is a list response before per-request permission filtering.
+type cachedList struct {
+	rows  []map[string]any
+	total any
+	next  string // next_cursor of a cursor page
+}
+
+func readCacheKey(kind, sql string, params []any, includes []string) string {
+	return fmt.Sprintf("%s:%s|%v|%s", kind, sql, params, strings.Join(includes, ","))
+}
+
+// cacheGet looks up a read for a cacheable entity and tags the response and
+// span with the outcome. It always misses for entities that aren't cacheable.
+func (h *Handler) cacheGet(c *fiber.Ctx, span instrument.Span, entity *metadata.Entity, key string) (any, bool) {
+	if !entity.Cacheable {
+		return nil, false
+	}
+	v, ok := entityCacheFor(h.registry).get(h.registry, entity.Name, key)
+	outcome := "miss"
+	if ok {
+		outcome = "hit"
+	}
+	span.SetMetadata("cache", outcome)
+	c.Set("X-Cache", strings.ToUpper(outcome))
+	return v, ok
+}
+
+func (h *Handler) cachePut(entity *metadata.Entity, includes []string, key string, value any, deps ...string) {
+	if !entity.Cacheable {
+		return
+	}
+	for _, d := range includeDeps(h.registry, entity, includes) {
+		if !containsString(deps, d) {
+			deps = append(deps, d)
+		}
+	}
+	entityCacheFor(h.registry).put(h.registry, entity, deps, key, value)
+}
+
+// includeDeps lists the entities reached by the requested includes, following
+// every segment of nested paths.
+func includeDeps(reg *metadata.Registry, entity *metadata.Entity, includes []string) []string {
+	var deps []string
+	for _, path := range includes {
+		current := entity.Name
+		for _, seg := range strings.Split(path, ".") {
+			rel := reg.FindRelationForEntity(seg, current)
+			if rel == nil {
+				break
+			}
+			if rel.Source == current {
+				current = rel.Target
+			} else {
+				current = rel.Source
+			}
+			if !containsString(deps, current) {
+				deps = append(deps, current)
+			}
+		}
+	}
+	return deps
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
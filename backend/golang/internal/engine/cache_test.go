@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestHandler_CacheableEntityWriteBustsCache(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "country",
+		Table:      "countries",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "name", Type: "string"},
+		},
+		Cacheable: true,
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+	app.Get("/api/:entity/:id", h.GetByID)
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	do := func(method, path string, body any) (string, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: status %d: %s", method, path, resp.StatusCode, raw)
+		}
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.Header.Get("X-Cache"), out
+	}
+
+	_, created := do("POST", "/api/country", map[string]any{"name": "France"})
+	id := created["data"].(map[string]any)["id"].(string)
+
+	if c, _ := do("GET", "/api/country", nil); c != "MISS" {
+		t.Fatalf("expected first list to miss, got %q", c)
+	}
+	if c, _ := do("GET", "/api/country", nil); c != "HIT" {
+		t.Fatalf("expected second list to hit, got %q", c)
+	}
+	do("GET", "/api/country/"+id, nil)
+	if c, _ := do("GET", "/api/country/"+id, nil); c != "HIT" {
+		t.Fatalf("expected repeated get to hit, got %q", c)
+	}
+
+	do("PUT", "/api/country/"+id, map[string]any{"name": "République française"})
+
+	c, got := do("GET", "/api/country/"+id, nil)
+	if c != "MISS" {
+		t.Fatalf("expected get after update to miss, got %q", c)
+	}
+	if name := got["data"].(map[string]any)["name"]; name != "République française" {
+		t.Fatalf("expected updated name, got %v", name)
+	}
+
+	do("POST", "/api/country", map[string]any{"name": "Japan"})
+	c, list := do("GET", "/api/country", nil)
+	if c != "MISS" || len(list["data"].([]any)) != 2 {
+		t.Fatalf("expected list after create to miss with 2 rows, got %q with %d", c, len(list["data"].([]any)))
+	}
+
+	// A registry reload makes existing entries stale
+	reg.Load([]*metadata.Entity{entity}, nil)
+	if c, _ := do("GET", "/api/country", nil); c != "MISS" {
+		t.Fatalf("expected list after reload to miss, got %q", c)
+	}
+
+	st := EntityCacheStats(reg)["country"]
+	if st.Hits != 2 || st.Misses != 5 || st.Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestEntityCache_BoundsEntries(t *testing.T) {
+	defer func(n int) { MaxCacheEntries = n }(MaxCacheEntries)
+	MaxCacheEntries = 3
+
+	reg := metadata.NewRegistry()
+	entity := &metadata.Entity{Name: "country", Cacheable: true, CacheTTL: 60}
+	cache := &EntityCache{entries: make(map[string]*cacheEntry), stats: make(map[string]*CacheStats)}
+
+	for i := 0; i < 10; i++ {
+		cache.put(reg, entity, nil, fmt.Sprintf("list:%d", i), i)
+		if n := len(cache.entries); n > MaxCacheEntries {
+			t.Fatalf("expected at most %d entries, got %d after %d puts", MaxCacheEntries, n, i+1)
+		}
+	}
+	if _, ok := cache.get(reg, "country", "list:9"); !ok {
+		t.Fatal("expected the latest entry to be cached")
+	}
+
+	// Expired entries are swept before a live one is evicted
+	for key, e := range cache.entries {
+		if key != "list:9" {
+			e.expires = time.Now().Add(-time.Second)
+		}
+	}
+	cache.put(reg, entity, nil, "list:10", 10)
+	if len(cache.entries) != 2 {
+		t.Fatalf("expected the expired entries swept, got %d entries", len(cache.entries))
+	}
+	if _, ok := cache.get(reg, "country", "list:9"); !ok {
+		t.Fatal("expected the live entry to survive the sweep")
+	}
+}
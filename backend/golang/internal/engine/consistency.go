@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/store"
+)
+
+// A write stamps the response with its time (unix ms) in a cookie and a header.
+// Clients that don't keep cookies can echo the header back on later reads.
+const (
+	LastWriteCookie = "rocket_last_write"
+	LastWriteHeader = "X-Rocket-Last-Write"
+)
+
+// markWrite records that this client just wrote, when read-your-writes is enabled.
+func (h *Handler) markWrite(c *fiber.Ctx) {
+	window := h.store.ReadYourWrites()
+	if window <= 0 {
+		return
+	}
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	c.Cookie(&fiber.Cookie{
+		Name:     LastWriteCookie,
+		Value:    ts,
+		Path:     "/",
+		MaxAge:   int((window + time.Second - 1) / time.Second),
+		HTTPOnly: true,
+	})
+	c.Set(LastWriteHeader, ts)
+}
+
+// reader returns the connection for a read: the replica, unless this client
+// wrote within the read-your-writes window and must see its own data.
+func (h *Handler) reader(c *fiber.Ctx) store.Querier {
+	window := h.store.ReadYourWrites()
+	if window <= 0 {
+		return h.store.Reader()
+	}
+	v := c.Get(LastWriteHeader)
+	if v == "" {
+		v = c.Cookies(LastWriteCookie)
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return h.store.Reader()
+	}
+	if age := time.Since(time.UnixMilli(ms)); age < window && age > -window {
+		return h.store.DB
+	}
+	return h.store.Reader()
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestHandler_ReadYourWritesDuringReplicaLag(t *testing.T) {
+	ctx := context.Background()
+	entity := &metadata.Entity{
+		Name:       "note",
+		Table:      "notes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "body", Type: "string"},
+		},
+	}
+	open := func(name string) *store.Store {
+		s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: name})
+		if err != nil {
+			t.Fatalf("open sqlite store: %v", err)
+		}
+		t.Cleanup(s.Close)
+		if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+			t.Fatalf("bootstrap: %v", err)
+		}
+		if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		return s
+	}
+
+	// The replica is a separate database that never receives the write: maximal lag
+	primary, replica := open("primary"), open("replica")
+	primary.SetReplica(replica.DB)
+	primary.SetReadYourWrites(5 * time.Second)
+
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(primary, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Get("/api/:entity/:id", h.GetByID)
+
+	raw, _ := json.Marshal(map[string]any{"body": "hello"})
+	req, _ := http.NewRequest("POST", "/api/note", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil || resp.StatusCode != 201 {
+		t.Fatalf("create: %v %v", resp.StatusCode, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var created map[string]any
+	_ = json.Unmarshal(body, &created)
+	id := created["data"].(map[string]any)["id"].(string)
+
+	var cookie *http.Cookie
+	for _, ck := range resp.Cookies() {
+		if ck.Name == LastWriteCookie {
+			cookie = ck
+		}
+	}
+	if cookie == nil || resp.Header.Get(LastWriteHeader) == "" {
+		t.Fatal("expected the write to set the last-write cookie and header")
+	}
+
+	get := func(setup func(*http.Request)) int {
+		req, _ := http.NewRequest("GET", "/api/note/"+id, nil)
+		setup(req)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := get(func(r *http.Request) { r.AddCookie(cookie) }); status != 200 {
+		t.Fatalf("expected the writer to read its own record via the cookie, got %d", status)
+	}
+	if status := get(func(r *http.Request) { r.Header.Set(LastWriteHeader, cookie.Value) }); status != 200 {
+		t.Fatalf("expected the writer to read its own record via the header, got %d", status)
+	}
+	if status := get(func(*http.Request) {}); status != 404 {
+		t.Fatalf("expected other clients to read from the lagging replica, got %d", status)
+	}
+	stale := strconv.FormatInt(time.Now().Add(-time.Minute).UnixMilli(), 10)
+	if status := get(func(r *http.Request) { r.Header.Set(LastWriteHeader, stale) }); status != 404 {
+		t.Fatalf("expected a write outside the window to read from the replica, got %d", status)
+	}
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"rocket-backend/internal/store"
+)
+
+// listCursor is the keyset position of the last record on a page: the sort
+// and filters it was taken under and that record's value for each sort
+// field, the primary key last. Times lists the values that were time.Time;
+// any other value is compared as it was read, so a timestamp SQLite keeps as
+// text is matched against the same text.
+type listCursor struct {
+	Sort   string `json:"s"`
+	Filter string `json:"f"`
+	Values []any  `json:"v"`
+	Times  []int  `json:"t,omitempty"`
+}
+
+// cursorFilterKey identifies the filters, search and include_deleted of a
+// list request, so a cursor can't be carried over to a different query.
+func cursorFilterKey(queries map[string]string) string {
+	var parts []string
+	for k, v := range queries {
+		if strings.HasPrefix(k, "filter[") || k == "q" || k == "include_deleted" {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "&")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sortSpec renders sorts the way ?sort= takes them, e.g. "-created_at,id".
+func sortSpec(sorts []OrderClause) string {
+	parts := make([]string, len(sorts))
+	for i, s := range sorts {
+		parts[i] = s.Field
+		if s.Dir == "DESC" {
+			parts[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// keysetSorts completes plan's sort for cursor pagination: the primary key
+// breaks ties, so every record has a distinct position. Sort fields must not
+// be nullable, since NULL has no position to continue from.
+func keysetSorts(plan *QueryPlan) error {
+	entity := plan.Entity
+	pk := entity.PrimaryKey.Field
+	hasPK := false
+	for _, s := range plan.Sorts {
+		if s.Field == pk {
+			hasPK = true
+			continue
+		}
+		if f := entity.GetField(s.Field); f != nil && f.Nullable {
+			return &AppError{Code: "INVALID_PAYLOAD", Status: 400,
+				Message: fmt.Sprintf("Cursor pagination can't sort on nullable field %s", s.Field)}
+		}
+	}
+	if !hasPK {
+		plan.Sorts = append(plan.Sorts, OrderClause{Field: pk, Dir: "ASC"})
+	}
+	return nil
+}
+
+// encodeCursor returns the opaque cursor of row under plan's sort.
+func encodeCursor(plan *QueryPlan, row map[string]any) string {
+	cur := listCursor{Sort: sortSpec(plan.Sorts), Filter: plan.Filter, Values: make([]any, len(plan.Sorts))}
+	for i, s := range plan.Sorts {
+		v := row[s.Field]
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339Nano)
+			cur.Times = append(cur.Times, i)
+		}
+		cur.Values[i] = v
+	}
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a ?cursor= value taken under plan's sort into one
+// typed value per sort field.
+func decodeCursor(s string, plan *QueryPlan) ([]any, error) {
+	invalid := &AppError{Code: "INVALID_PAYLOAD", Status: 400, Message: "Invalid cursor"}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	var cur listCursor
+	if err := json.Unmarshal(raw, &cur); err != nil || len(cur.Values) != len(plan.Sorts) {
+		return nil, invalid
+	}
+	if cur.Sort != sortSpec(plan.Sorts) {
+		return nil, &AppError{Code: "INVALID_PAYLOAD", Status: 400,
+			Message: fmt.Sprintf("Cursor was taken with sort %q, not %q", cur.Sort, sortSpec(plan.Sorts))}
+	}
+	if cur.Filter != plan.Filter {
+		return nil, &AppError{Code: "INVALID_PAYLOAD", Status: 400,
+			Message: "Cursor was taken with different filters; start again without a cursor"}
+	}
+	values := make([]any, len(plan.Sorts))
+	for i, sort := range plan.Sorts {
+		str, err := searchParamString(cur.Values[i])
+		if err != nil || cur.Values[i] == nil {
+			return nil, invalid
+		}
+		if slices.Contains(cur.Times, i) {
+			if values[i], err = time.Parse(time.RFC3339Nano, str); err != nil {
+				return nil, invalid
+			}
+			continue
+		}
+		f := plan.Entity.GetField(sort.Field)
+		if f == nil {
+			values[i] = str
+			continue
+		}
+		if values[i], err = coerceSingleValue(f, str); err != nil {
+			return nil, invalid
+		}
+	}
+	return values, nil
+}
+
+// keysetClause returns the condition selecting the records after plan.After
+// under plan's sort: (a > ?) OR (a = ? AND b > ?) ..., with < for
+// descending fields.
+func keysetClause(plan *QueryPlan, pb store.ParamBuilder, dialect store.Dialect) string {
+	param := func(v any) string {
+		if t, ok := v.(time.Time); ok {
+			return pb.Add(dialect.TimeParam(t))
+		}
+		return pb.Add(v)
+	}
+	var branches []string
+	for i, s := range plan.Sorts {
+		parts := make([]string, 0, i+1)
+		for j := range i {
+			parts = append(parts, fmt.Sprintf("%s = %s", plan.Sorts[j].Field, param(plan.After[j])))
+		}
+		op := ">"
+		if s.Dir == "DESC" {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", s.Field, op, param(plan.After[i])))
+		branches = append(branches, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(branches, " OR ") + ")"
+}
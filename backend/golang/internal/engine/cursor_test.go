@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestList_CursorPagination(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:        "score",
+		Table:       "scores",
+		PrimaryKey:  metadata.PrimaryKey{Field: "id", Type: "string"},
+		DefaultSort: "-points",
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "points", Type: "int"},
+			{Name: "note", Type: "string", Nullable: true},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// Ties on points are broken by id
+	for i, points := range []int{10, 30, 20, 30, 10} {
+		if _, err := store.Exec(ctx, s.DB, "INSERT INTO scores (id, points) VALUES (?1, ?2)", fmt.Sprintf("s%d", i), points); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+
+	get := func(path string) (int, map[string]any) {
+		req, _ := http.NewRequest("GET", path, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	var ids []any
+	path := "/api/score?limit=2&cursor="
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("expected 3 pages, got more: %v", ids)
+		}
+		status, out := get(path)
+		if status != 200 {
+			t.Fatalf("list %s: %d %v", path, status, out)
+		}
+		meta := out["meta"].(map[string]any)
+		if meta["total"] != nil || toInt(meta["limit"]) != 2 {
+			t.Fatalf("expected a cursor page without a total, got %v", meta)
+		}
+		for _, row := range out["data"].([]any) {
+			ids = append(ids, row.(map[string]any)["id"])
+		}
+		next, ok := meta["next_cursor"].(string)
+		if !ok {
+			break
+		}
+		path = "/api/score?limit=2&cursor=" + next
+	}
+	if fmt.Sprint(ids) != "[s1 s3 s2 s0 s4]" {
+		t.Fatalf("expected every record once in the default sort, got %v", ids)
+	}
+
+	// Offset paging still works and counts, with limit alone as the page size
+	if status, out := get("/api/score?page=2&per_page=2"); status != 200 || toInt(out["meta"].(map[string]any)["total"]) != 5 {
+		t.Fatalf("expected offset paging with a total, got %d %v", status, out)
+	}
+	if status, out := get("/api/score?limit=2"); status != 200 || toInt(out["meta"].(map[string]any)["total"]) != 5 || len(out["data"].([]any)) != 2 {
+		t.Fatalf("expected limit without a cursor to stay in offset mode, got %d %v", status, out)
+	}
+
+	// A cursor only continues the sort and filters it was taken with
+	_, out := get("/api/score?limit=2&cursor=&filter[points.gte]=20")
+	next := out["meta"].(map[string]any)["next_cursor"].(string)
+	if status, out := get("/api/score?limit=2&sort=points&filter[points.gte]=20&cursor=" + next); status != 400 {
+		t.Fatalf("expected 400 for a cursor from another sort, got %d %v", status, out)
+	}
+	if status, out := get("/api/score?limit=2&cursor=" + next); status != 400 {
+		t.Fatalf("expected 400 for a cursor from other filters, got %d %v", status, out)
+	}
+	if status, out := get("/api/score?limit=2&filter[points.gte]=20&cursor=" + next); status != 200 || len(out["data"].([]any)) != 1 {
+		t.Fatalf("expected the cursor to continue its own query, got %d %v", status, out)
+	}
+	if status, out := get("/api/score?limit=2&cursor=garbage"); status != 400 {
+		t.Fatalf("expected 400 for an invalid cursor, got %d %v", status, out)
+	}
+	if status, out := get("/api/score?limit=2&cursor=&sort=note"); status != 400 {
+		t.Fatalf("expected 400 for a nullable sort field, got %d %v", status, out)
+	}
+}
+
+func TestList_CursorKeepsSQLiteTimestampText(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	entity := &metadata.Entity{
+		Name:       "reading",
+		Table:      "readings",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields:     []metadata.Field{{Name: "id", Type: "string"}, {Name: "taken_at", Type: "timestamp"}},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// Sub-second values that a seconds-precision time parameter can't tell apart
+	for i, at := range []string{"2026-01-01T00:00:00.300Z", "2026-01-01T00:00:00.100Z", "2026-01-01T00:00:00.200Z"} {
+		if _, err := store.Exec(ctx, s.DB, "INSERT INTO readings (id, taken_at) VALUES (?1, ?2)", fmt.Sprintf("r%d", i), at); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+	app := newTestApp(t, testAdmin())
+	app.Get("/api/:entity", h.List)
+
+	var ids []any
+	path := "/api/reading?sort=taken_at&limit=1&cursor="
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("expected 3 pages, got more: %v", ids)
+		}
+		req, _ := http.NewRequest("GET", path, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		for _, row := range out["data"].([]any) {
+			ids = append(ids, row.(map[string]any)["id"])
+		}
+		next, ok := out["meta"].(map[string]any)["next_cursor"].(string)
+		if !ok {
+			break
+		}
+		path = "/api/reading?sort=taken_at&limit=1&cursor=" + next
+	}
+	if fmt.Sprint(ids) != "[r1 r2 r0]" {
+		t.Fatalf("expected each reading once in time order, got %v", ids)
+	}
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+
+	"rocket-backend/internal/metadata"
+)
+
+// decimalPrograms caches compiled decimal computed expressions by source.
+var decimalPrograms sync.Map
+
+// decimalPatcher rewrites arithmetic and comparisons into _dec/_deccmp calls,
+// so every intermediate value stays a *big.Rat and the expression is rounded
+// only once, to the field's precision. Arguments to builtins and functions are
+// turned back into float64 through _decnum, since they don't understand
+// *big.Rat.
+type decimalPatcher struct{}
+
+func (decimalPatcher) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.BinaryNode:
+		switch n.Operator {
+		case "+", "-", "*", "/":
+			ast.Patch(node, decimalCall("_dec", &ast.StringNode{Value: n.Operator}, n.Left, n.Right))
+		case "<", ">", "<=", ">=", "==", "!=":
+			ast.Patch(node, decimalCall("_deccmp", &ast.StringNode{Value: n.Operator}, n.Left, n.Right))
+		}
+	case *ast.UnaryNode:
+		if n.Operator == "-" {
+			ast.Patch(node, decimalCall("_dec", &ast.StringNode{Value: "-"}, &ast.IntegerNode{Value: 0}, n.Node))
+		}
+	case *ast.BuiltinNode:
+		for i, arg := range n.Arguments {
+			n.Arguments[i] = decimalCall("_decnum", arg)
+		}
+	case *ast.CallNode:
+		if id, ok := n.Callee.(*ast.IdentifierNode); ok && strings.HasPrefix(id.Value, "_dec") {
+			return
+		}
+		for i, arg := range n.Arguments {
+			n.Arguments[i] = decimalCall("_decnum", arg)
+		}
+	}
+}
+
+func decimalCall(name string, args ...ast.Node) ast.Node {
+	return &ast.CallNode{Callee: &ast.IdentifierNode{Value: name}, Arguments: args}
+}
+
+// decimalOp applies an arithmetic operator with big.Rat precision and returns
+// the exact *big.Rat. Operands are read by their shortest decimal form, so 1.1
+// is exactly 11/10.
+func decimalOp(params ...any) (any, error) {
+	op, _ := params[0].(string)
+	l, lok := toRat(params[1])
+	r, rok := toRat(params[2])
+	if !lok || !rok {
+		if ls, ok := params[1].(string); ok && op == "+" {
+			if rs, ok := params[2].(string); ok {
+				return ls + rs, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid operands for %s: %T and %T", op, params[1], params[2])
+	}
+
+	res := new(big.Rat)
+	switch op {
+	case "+":
+		res.Add(l, r)
+	case "-":
+		res.Sub(l, r)
+	case "*":
+		res.Mul(l, r)
+	case "/":
+		if r.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		res.Quo(l, r)
+	}
+	return res, nil
+}
+
+// decimalCompare compares two values exactly when both are numeric. Equality
+// between anything else falls back to deep equality; ordering requires
+// numbers or two strings.
+func decimalCompare(params ...any) (any, error) {
+	op, _ := params[0].(string)
+	l, lok := toRat(params[1])
+	r, rok := toRat(params[2])
+	if _, isStr := params[1].(string); isStr {
+		lok = false
+	}
+	if _, isStr := params[2].(string); isStr {
+		rok = false
+	}
+	var cmp int
+	switch {
+	case lok && rok:
+		cmp = l.Cmp(r)
+	case op == "==":
+		return reflect.DeepEqual(params[1], params[2]), nil
+	case op == "!=":
+		return !reflect.DeepEqual(params[1], params[2]), nil
+	default:
+		ls, lsok := params[1].(string)
+		rs, rsok := params[2].(string)
+		if !lsok || !rsok {
+			return nil, fmt.Errorf("invalid operands for %s: %T and %T", op, params[1], params[2])
+		}
+		cmp = strings.Compare(ls, rs)
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "==":
+		return cmp == 0, nil
+	default:
+		return cmp != 0, nil
+	}
+}
+
+// decimalNumber hands an intermediate *big.Rat to code that expects a plain
+// number; anything else passes through unchanged.
+func decimalNumber(params ...any) (any, error) {
+	if r, ok := params[0].(*big.Rat); ok {
+		f, _ := r.Float64()
+		return f, nil
+	}
+	return params[0], nil
+}
+
+// toRat converts a numeric value (or numeric string, as NUMERIC columns may be
+// scanned) to a big.Rat.
+func toRat(v any) (*big.Rat, bool) {
+	var s string
+	switch n := v.(type) {
+	case *big.Rat:
+		return n, true
+	case float64:
+		s = strconv.FormatFloat(n, 'g', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'g', -1, 32)
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case json.Number:
+		s = n.String()
+	case string:
+		s = n
+	case []byte:
+		s = string(n)
+	default:
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(s)
+	return r, ok
+}
+
+// roundRat rounds r to places decimal places, halves away from zero.
+func roundRat(r *big.Rat, places int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+	q, m := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if new(big.Int).Mul(m.Abs(m), big.NewInt(2)).Cmp(scaled.Denom()) >= 0 {
+		if scaled.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return new(big.Rat).SetFrac(q, scale)
+}
+
+func compileDecimalExpression(expression string) (*vm.Program, error) {
+	if p, ok := decimalPrograms.Load(expression); ok {
+		return p.(*vm.Program), nil
+	}
+	prog, err := compileExpr(expression, expr.Patch(decimalPatcher{}),
+		expr.Function("_dec", decimalOp),
+		expr.Function("_deccmp", decimalCompare),
+		expr.Function("_decnum", decimalNumber))
+	if err != nil {
+		return nil, err
+	}
+	decimalPrograms.Store(expression, prog)
+	return prog, nil
+}
+
+// EvaluateDecimalComputedField evaluates a computed rule for a decimal field with
+// exact arithmetic, then rounds to precision places (0 leaves it unrounded).
+func EvaluateDecimalComputedField(ctx context.Context, rule *metadata.Rule, env map[string]any, precision int) (any, error) {
+	prog, err := compileDecimalExpression(rule.Definition.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("compile computed expression: %w", err)
+	}
+
+	result, err := runExpr(ctx, prog, env)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate computed field %s: %w", rule.Definition.Field, err)
+	}
+
+	r, ok := toRat(result)
+	if !ok {
+		return result, nil
+	}
+	if precision > 0 {
+		r = roundRat(r, precision)
+	}
+	f, _ := r.Float64()
+	return f, nil
+}
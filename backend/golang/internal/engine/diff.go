@@ -254,6 +254,7 @@ func indexByPK(rows []map[string]any, pkField string) map[string]map[string]any
 }
 
 func insertChild(ctx context.Context, q store.Querier, dialect store.Dialect, entity *metadata.Entity, fields map[string]any) error {
+	ApplyFieldTransforms(entity, fields)
 	sql, params := BuildInsertSQL(entity, fields, dialect)
 	_, err := store.QueryRows(ctx, q, sql, params...)
 	if err != nil {
@@ -263,6 +264,7 @@ func insertChild(ctx context.Context, q store.Querier, dialect store.Dialect, en
 }
 
 func updateChild(ctx context.Context, q store.Querier, dialect store.Dialect, entity *metadata.Entity, id any, fields map[string]any) error {
+	ApplyFieldTransforms(entity, fields)
 	sql, params := BuildUpdateSQL(entity, id, fields, dialect)
 	if sql == "" {
 		return nil // nothing to update
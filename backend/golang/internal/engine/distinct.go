@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// Distinct handles GET /api/:entity/distinct?field=status — the unique values
+// of a field among the records the caller may read, e.g. for filter dropdowns.
+// filter[...] params narrow the records as on a list, counts=true adds how
+// many records hold each value, and limit caps the values (at most MaxPerPage).
+func (h *Handler) Distinct(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", "record.distinct")
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	entity, err := h.resolveEntity(c)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	span.SetEntity(entity.Name, "")
+
+	user := getUser(c)
+	if err := CheckPermission(c.Context(), user, entity.Name, "read", h.registry, nil); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+
+	field, err := distinctField(entity, c.Query("field"))
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	plan, err := parseListParams(c.Queries(), entity, h.registry)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	if plan.IncludeDeleted {
+		if err := CheckIncludeDeleted(c.Context(), user, entity.Name, h.registry); err != nil {
+			span.SetStatus("error")
+			return err
+		}
+	}
+	if filters := GetReadFilters(user, entity.Name, h.registry); len(filters) > 0 {
+		plan.Filters = append(plan.Filters, filters...)
+	}
+	scope, err := h.scopeFilter(c.Context(), entity, user)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	plan.Filters = append(plan.Filters, scope...)
+
+	limit := MaxPerPage
+	if l := c.QueryInt("limit"); l > 0 && l < limit {
+		limit = l
+	}
+
+	qr := BuildDistinctSQL(plan, field.Name, limit, h.store.Dialect)
+	values, err := store.QueryRows(c.Context(), h.reader(c), qr.SQL, qr.Params...)
+	if err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return fmt.Errorf("distinct %s.%s: %w", entity.Name, field.Name, err)
+	}
+
+	span.SetStatus("ok")
+	if c.QueryBool("counts") {
+		if values == nil {
+			values = []map[string]any{}
+		}
+		return c.JSON(fiber.Map{"data": values})
+	}
+	data := make([]any, len(values))
+	for i, v := range values {
+		data[i] = v["value"]
+	}
+	return c.JSON(fiber.Map{"data": data})
+}
+
+// distinctField resolves the field of a distinct query. JSON, file, array and
+// geopoint values have no meaningful equality in SQL, so they are rejected.
+func distinctField(entity *metadata.Entity, name string) (*metadata.Field, error) {
+	if name == "" {
+		return nil, ValidationError([]ErrorDetail{{Field: "field", Rule: "required", Message: "field is required"}})
+	}
+	f := entity.GetField(name)
+	if f == nil {
+		return nil, &AppError{Code: "UNKNOWN_FIELD", Status: 400, Message: fmt.Sprintf("Unknown field: %s", name)}
+	}
+	switch f.Type {
+	case "json", "file", "array", "geopoint":
+		return nil, &AppError{Code: "INVALID_PAYLOAD", Status: 400, Message: fmt.Sprintf("distinct is not supported on %s field %s", f.Type, name)}
+	}
+	return f, nil
+}
+
+// BuildDistinctSQL builds a query for the unique values of field (as "value")
+// and their record counts (as "count") under the plan's filters.
+func BuildDistinctSQL(plan *QueryPlan, field string, limit int, dialect store.Dialect) QueryResult {
+	return buildGroupedCountSQL(plan, field, field, limit, dialect)
+}
+
+// BuildCountBySQL is BuildDistinctSQL with the largest groups first.
+func BuildCountBySQL(plan *QueryPlan, field string, limit int, dialect store.Dialect) QueryResult {
+	return buildGroupedCountSQL(plan, field, "count DESC, "+field, limit, dialect)
+}
+
+func buildGroupedCountSQL(plan *QueryPlan, field, orderBy string, limit int, dialect store.Dialect) QueryResult {
+	pb := dialect.NewParamBuilder()
+	sql := fmt.Sprintf("SELECT %s AS value, COUNT(*) AS count FROM %s", field, plan.Entity.Table)
+	if where := planWhere(plan, pb, dialect); len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += fmt.Sprintf(" GROUP BY %s ORDER BY %s LIMIT %s", field, orderBy, pb.Add(limit))
+	return QueryResult{SQL: sql, Params: pb.Params()}
+}
+
+// planWhere returns the soft-delete and filter conditions of a plan.
+func planWhere(plan *QueryPlan, pb store.ParamBuilder, dialect store.Dialect) []string {
+	var where []string
+	if plan.Entity.SoftDelete && !plan.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	for _, f := range plan.Filters {
+		where = append(where, buildWhereClause(f, pb, dialect))
+	}
+	return where
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestDistinct_HonorsRowLevelFilters(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "ticket",
+		Table:      "tickets",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "owner_id", Type: "string"},
+			{Name: "status", Type: "string"},
+			{Name: "meta", Type: "json"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for i, r := range [][2]string{{"u1", "open"}, {"u1", "closed"}, {"u1", "open"}, {"u2", "pending"}, {"u2", "open"}} {
+		if _, err := store.Exec(ctx, s.DB, "INSERT INTO tickets (id, owner_id, status) VALUES (?1, ?2, ?3)",
+			string(rune('a'+i)), r[0], r[1]); err != nil {
+			t.Fatalf("insert ticket: %v", err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "ticket", Action: "read", Roles: []string{"staff"},
+			Conditions: []metadata.PermissionCondition{{Field: "owner_id", Operator: "eq", Value: "u1"}}},
+		// len() has no SQL translation, so the policy shows nothing
+		{Entity: "ticket", Action: "read", Roles: []string{"auditor"},
+			Conditions: []metadata.PermissionCondition{{Expression: "record.owner_id == user.id && len(record.status) > 4"}}},
+	})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: c.Get("X-Test-User"), Roles: []string{c.Get("X-Test-Role")}})
+		return c.Next()
+	})
+	app.Get("/api/:entity/distinct", h.Distinct)
+
+	get := func(path, user, role string) (int, map[string]any) {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Role", role)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, body := get("/api/ticket/distinct?field=status", "u1", "staff")
+	if data, _ := body["data"].([]any); status != 200 || len(data) != 2 || data[0] != "closed" || data[1] != "open" {
+		t.Fatalf("expected [closed open], got %d %v", status, body)
+	}
+
+	status, body = get("/api/ticket/distinct?field=status&counts=true", "u1", "staff")
+	data, _ := body["data"].([]any)
+	if status != 200 || len(data) != 2 {
+		t.Fatalf("expected 2 counted values, got %d %v", status, body)
+	}
+	if open := data[1].(map[string]any); open["value"] != "open" || toInt(open["count"]) != 2 {
+		t.Fatalf("expected 2 open tickets, got %v", open)
+	}
+
+	status, body = get("/api/ticket/distinct?field=status&filter[status.neq]=closed", "u1", "staff")
+	if data, _ := body["data"].([]any); status != 200 || len(data) != 1 || data[0] != "open" {
+		t.Fatalf("expected [open] with filter, got %d %v", status, body)
+	}
+
+	// The cap applies in SQL, to the grouped values rather than the rows
+	status, body = get("/api/ticket/distinct?field=status&limit=1", "u1", "staff")
+	if data, _ := body["data"].([]any); status != 200 || len(data) != 1 || data[0] != "closed" {
+		t.Fatalf("expected [closed] with limit=1, got %d %v", status, body)
+	}
+
+	status, body = get("/api/ticket/distinct?field=status&counts=true", "u2", "auditor")
+	if data, _ := body["data"].([]any); status != 200 || len(data) != 0 {
+		t.Fatalf("expected no values under an untranslatable policy, got %d %v", status, body)
+	}
+
+	if status, _ := get("/api/ticket/distinct?field=meta", "u1", "staff"); status != 400 {
+		t.Fatalf("expected 400 for a json field, got %d", status)
+	}
+	if status, _ := get("/api/ticket/distinct?field=nope", "u1", "staff"); status != 400 {
+		t.Fatalf("expected 400 for an unknown field, got %d", status)
+	}
+}
@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestDryRun_ComputesRecordWithoutPersisting(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "quote",
+		Table:      "quotes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "customer", Type: "string", Transform: []string{"trim"}},
+			{Name: "qty", Type: "int"},
+			{Name: "price", Type: "int"},
+			{Name: "total", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadRules([]*metadata.Rule{{
+		ID: "r1", Entity: "quote", Hook: "before_write", Type: "computed", Active: true,
+		Definition: metadata.RuleDefinition{Field: "total", Expression: "record.qty * record.price"},
+	}})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	send := func(method, path string, body map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	count := func() int {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM quotes")
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return toInt(row["n"])
+	}
+
+	status, out := send("POST", "/api/quote?dry_run=true", map[string]any{"customer": "  Acme ", "qty": 3, "price": 7})
+	if status != 200 {
+		t.Fatalf("dry-run create: expected 200, got %d %v", status, out)
+	}
+	rec := out["data"].(map[string]any)
+	if toInt(rec["total"]) != 21 || rec["customer"] != "Acme" {
+		t.Fatalf("expected the computed total and transformed fields, got %v", rec)
+	}
+	if meta, _ := out["meta"].(map[string]any); meta["dry_run"] != true {
+		t.Fatalf("expected meta.dry_run, got %v", out["meta"])
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected the dry run to leave the table empty, got %d rows", n)
+	}
+
+	status, out = send("POST", "/api/quote", map[string]any{"customer": "Acme", "qty": 1, "price": 7})
+	if status != 201 {
+		t.Fatalf("create: %d %v", status, out)
+	}
+	id := out["data"].(map[string]any)["id"].(string)
+	status, out = send("PUT", "/api/quote/"+id+"?dry_run=true", map[string]any{"qty": 10, "price": 7})
+	if status != 200 || toInt(out["data"].(map[string]any)["total"]) != 70 {
+		t.Fatalf("dry-run update: expected a total of 70, got %d %v", status, out)
+	}
+	row, err := store.QueryRow(ctx, s.DB, "SELECT qty, total FROM quotes WHERE id = ?1", id)
+	if err != nil || toInt(row["qty"]) != 1 || toInt(row["total"]) != 7 {
+		t.Fatalf("expected the stored record to be unchanged, got %v (%v)", row, err)
+	}
+}
+
+func TestDeleteDryRun_ReportsCascadeWithoutDeleting(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := func(name string, fields ...string) *metadata.Entity {
+		e := &metadata.Entity{
+			Name:       name,
+			Table:      name + "s",
+			PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+			Fields:     []metadata.Field{{Name: "id", Type: "string"}},
+		}
+		for _, f := range fields {
+			e.Fields = append(e.Fields, metadata.Field{Name: f, Type: "string", Nullable: true})
+		}
+		return e
+	}
+	order, line, note, secret := entity("order"), entity("line", "order_id"), entity("note", "order_id"), entity("secret", "order_id")
+	migrator := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{order, line, note, secret} {
+		if err := migrator.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	rel := func(name, target, onDelete string) *metadata.Relation {
+		return &metadata.Relation{Name: name, Type: "one_to_many", Source: "order", Target: target,
+			SourceKey: "id", TargetKey: "order_id", Ownership: "source", OnDelete: onDelete}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{order, line, note, secret},
+		[]*metadata.Relation{rel("lines", "line", "cascade"), rel("notes", "note", "set_null"), rel("secrets", "secret", "cascade")})
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "order", Action: "delete", Roles: []string{"manager"}},
+		{Entity: "line", Action: "read", Roles: []string{"manager"}},
+		{Entity: "note", Action: "read", Roles: []string{"manager"}},
+	})
+	h := NewHandler(s, reg)
+
+	for _, q := range []string{
+		"INSERT INTO orders (id) VALUES ('o1')",
+		"INSERT INTO lines (id, order_id) VALUES ('l1', 'o1'), ('l2', 'o1'), ('l3', 'o2')",
+		"INSERT INTO notes (id, order_id) VALUES ('n1', 'o1')",
+		"INSERT INTO secrets (id, order_id) VALUES ('s1', 'o1')",
+	} {
+		if _, err := store.Exec(ctx, s.DB, q); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"manager"}})
+		return c.Next()
+	})
+	app.Delete("/api/:entity/:id", h.Delete)
+	req, _ := http.NewRequest("DELETE", "/api/order/o1?dry_run=true", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("DELETE: %v", err)
+	}
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d %v", resp.StatusCode, out)
+	}
+
+	effects := map[string]map[string]any{}
+	for _, e := range out["meta"].(map[string]any)["cascade"].([]any) {
+		effect := e.(map[string]any)
+		effects[effect["relation"].(string)] = effect
+	}
+	if e := effects["lines"]; e["action"] != "delete" || e["count"] != float64(2) {
+		t.Fatalf("expected two lines to be deleted, got %v", e)
+	}
+	if e := effects["notes"]; e["action"] != "set_null" || e["count"] != float64(1) {
+		t.Fatalf("expected one note to be unlinked, got %v", e)
+	}
+	if e := effects["secrets"]; e == nil || e["count"] != nil {
+		t.Fatalf("expected the secrets cascade without a count the caller can't read, got %v", e)
+	}
+
+	for table, want := range map[string]int{"orders": 1, "lines": 3, "secrets": 1} {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM "+table)
+		if err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if toInt(row["n"]) != want {
+			t.Fatalf("expected %d rows left in %s, got %v", want, table, row["n"])
+		}
+	}
+	row, _ := store.QueryRow(ctx, s.DB, "SELECT order_id FROM notes WHERE id = 'n1'")
+	if row["order_id"] != "o1" {
+		t.Fatalf("expected the note to keep its order, got %v", row["order_id"])
+	}
+}
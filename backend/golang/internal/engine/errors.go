@@ -1,6 +1,12 @@
 package engine
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
 
 type AppError struct {
 	Code    string        `json:"code"`
@@ -9,8 +15,12 @@ type AppError struct {
 	Details []ErrorDetail `json:"details,omitempty"`
 }
 
+// ErrorDetail is one violation in a 422. Code is a stable machine-readable
+// reason (REQUIRED, ENUM, INVALID_TRANSITION, ...); Rule is the kind of check
+// that failed.
 type ErrorDetail struct {
 	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
 	Rule    string `json:"rule,omitempty"`
 	Message string `json:"message"`
 }
@@ -51,7 +61,18 @@ func ConflictError(msg string) *AppError {
 	}
 }
 
+// ValidationError wraps every violation found. Details without an explicit
+// code get one derived from their rule ("min_length" -> "MIN_LENGTH").
 func ValidationError(details []ErrorDetail) *AppError {
+	for i := range details {
+		if details[i].Code != "" {
+			continue
+		}
+		details[i].Code = "INVALID"
+		if details[i].Rule != "" {
+			details[i].Code = strings.ToUpper(details[i].Rule)
+		}
+	}
 	return &AppError{
 		Code:    "VALIDATION_FAILED",
 		Status:  422,
@@ -75,3 +96,24 @@ func ForbiddenError(msg string) *AppError {
 		Message: msg,
 	}
 }
+
+// UniqueViolationError converts a unique constraint violation into a 409 that
+// names the offending field, matched via the migrator's index naming or the
+// driver's "table.column" message.
+func UniqueViolationError(entity *metadata.Entity, err error) *AppError {
+	appErr := ConflictError("A record with this value already exists")
+	msg := err.Error()
+	for _, f := range entity.Fields {
+		if !f.Unique {
+			continue
+		}
+		idx := store.FieldUniqueIndexName(entity.Table, f)
+		if strings.Contains(msg, `"`+idx+`"`) || strings.Contains(msg, "'"+idx+"'") ||
+			strings.HasSuffix(msg, entity.Table+"."+f.Name) || strings.Contains(msg, entity.Table+"."+f.Name+" ") {
+			appErr.Message = fmt.Sprintf("A record with this %s already exists", f.Name)
+			appErr.Details = []ErrorDetail{{Field: f.Name, Code: "UNIQUE", Rule: "unique", Message: fmt.Sprintf("%s must be unique", f.Name)}}
+			break
+		}
+	}
+	return appErr
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ExprTimeout bounds a single evaluation of a rule, guard or condition
+// expression (writes.expr_timeout_ms); 0 disables the limit.
+var ExprTimeout = 100 * time.Millisecond
+
+// ExprMaxNodes caps the size of a compiled expression, ExprMaxSteps the number
+// of loop iterations (all, map, filter, ...) one evaluation may run and
+// ExprMemoryBudget the allocations expr's VM may make for ranges and results.
+var (
+	ExprMaxNodes     uint = 1000
+	ExprMaxSteps          = 100_000
+	ExprMemoryBudget uint = 1_000_000
+)
+
+// ErrExprTimeout is returned when an expression outlives ExprTimeout or runs
+// more than ExprMaxSteps loop iterations.
+var ErrExprTimeout = errors.New("expression evaluation timed out")
+
+// exprBudgetVar is the env key runExpr puts an evaluation's budget under.
+const exprBudgetVar = "__expr_budget"
+
+// compileExpr compiles an expression with the sandbox's limits: at most
+// ExprMaxNodes nodes, and every loop body charged against the evaluation's
+// budget.
+func compileExpr(expression string, ops ...expr.Option) (*vm.Program, error) {
+	ops = append(ops,
+		expr.MaxNodes(ExprMaxNodes),
+		expr.Patch(exprStepPatcher{}),
+		expr.Function("_step", exprStep))
+	return expr.Compile(expression, ops...)
+}
+
+// runExpr runs prog (compiled with compileExpr) against env. The VM can't be
+// interrupted from outside, so each loop iteration checks the budget itself:
+// the run stops with ErrExprTimeout once ExprTimeout passes or ExprMaxSteps
+// iterations have run, and with ctx's error once ctx is done.
+func runExpr(ctx context.Context, prog *vm.Program, env map[string]any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	budget := &exprBudget{ctx: ctx}
+	if ExprTimeout > 0 {
+		budget.deadline = time.Now().Add(ExprTimeout)
+	}
+	withBudget := make(map[string]any, len(env)+1)
+	for k, v := range env {
+		withBudget[k] = v
+	}
+	withBudget[exprBudgetVar] = budget
+
+	machine := vm.VM{MemoryBudget: ExprMemoryBudget}
+	return machine.Run(prog, withBudget)
+}
+
+type exprBudget struct {
+	ctx      context.Context
+	deadline time.Time
+	steps    int
+}
+
+func (b *exprBudget) step() error {
+	b.steps++
+	if b.steps > ExprMaxSteps {
+		return fmt.Errorf("%w after %d steps", ErrExprTimeout, ExprMaxSteps)
+	}
+	// Reading the clock on every iteration would dominate tight loops
+	if b.steps%64 != 0 {
+		return nil
+	}
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		return fmt.Errorf("%w after %s", ErrExprTimeout, ExprTimeout)
+	}
+	return b.ctx.Err()
+}
+
+// exprStep charges one loop iteration, then returns the iteration's value.
+func exprStep(params ...any) (any, error) {
+	if b, ok := params[0].(*exprBudget); ok {
+		if err := b.step(); err != nil {
+			return nil, err
+		}
+	}
+	return params[1], nil
+}
+
+// exprStepPatcher wraps every predicate body (the closure of all, map,
+// filter, ...) as _step(budget, body).
+type exprStepPatcher struct{}
+
+func (exprStepPatcher) Visit(node *ast.Node) {
+	p, ok := (*node).(*ast.PredicateNode)
+	if !ok {
+		return
+	}
+	p.Node = &ast.CallNode{
+		Callee:    &ast.IdentifierNode{Value: "_step"},
+		Arguments: []ast.Node{&ast.IdentifierNode{Value: exprBudgetVar}, p.Node},
+	}
+}
@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"context"
 	"fmt"
+	"mime/multipart"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -44,28 +46,6 @@ func (h *FileHandler) Upload(c *fiber.Ctx) error {
 		return respondError(c, NewAppError("FILE_TOO_LARGE", 413, msg))
 	}
 
-	src, err := file.Open()
-	if err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("open uploaded file: %w", err)
-	}
-	defer src.Close()
-
-	fileID := uuid.New().String()
-	filename := file.Filename
-	mimeType := file.Header.Get("Content-Type")
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
-	}
-
-	storagePath, err := h.storage.Save(c.Context(), h.appName, fileID, filename, src)
-	if err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("save file: %w", err)
-	}
-
 	// Get uploader ID if authenticated
 	var uploadedBy *string
 	user := getUser(c)
@@ -73,34 +53,80 @@ func (h *FileHandler) Upload(c *fiber.Ctx) error {
 		uploadedBy = &user.ID
 	}
 
-	pb := h.store.Dialect.NewParamBuilder()
-	insertSQL := fmt.Sprintf(`INSERT INTO _files (id, filename, storage_path, mime_type, size, uploaded_by)
-	        VALUES (%s, %s, %s, %s, %s, %s)`,
-		pb.Add(fileID), pb.Add(filename), pb.Add(storagePath), pb.Add(mimeType), pb.Add(file.Size), pb.Add(uploadedBy))
-	_, err = store.Exec(c.Context(), h.store.DB, insertSQL, pb.Params()...)
+	saved, err := saveUploadedFile(c.Context(), h.store, h.storage, h.appName, file, uploadedBy)
 	if err != nil {
-		// Clean up stored file on DB failure
-		_ = h.storage.Delete(c.Context(), storagePath)
 		span.SetStatus("error")
 		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("insert _files: %w", err)
+		return err
 	}
 
-	url := fmt.Sprintf("/api/%s/_files/%s", h.appName, fileID)
+	url := fmt.Sprintf("/api/%s/_files/%s", h.appName, saved.ID)
 
 	span.SetStatus("ok")
-	span.SetMetadata("file_id", fileID)
+	span.SetMetadata("file_id", saved.ID)
 	return c.Status(201).JSON(fiber.Map{
 		"data": fiber.Map{
-			"id":        fileID,
-			"filename":  filename,
-			"size":      file.Size,
-			"mime_type": mimeType,
+			"id":        saved.ID,
+			"filename":  saved.Filename,
+			"size":      saved.Size,
+			"mime_type": saved.MimeType,
 			"url":       url,
 		},
 	})
 }
 
+// storedFile describes a file persisted by saveUploadedFile.
+type storedFile struct {
+	ID          string
+	Filename    string
+	MimeType    string
+	Size        int64
+	StoragePath string
+}
+
+// saveUploadedFile writes an uploaded file to storage and records its _files row.
+// The stored file is removed again if the row cannot be inserted.
+func saveUploadedFile(ctx context.Context, s *store.Store, fs storage.FileStorage, appName string, file *multipart.FileHeader, uploadedBy *string) (*storedFile, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	fileID := uuid.New().String()
+	storagePath, err := fs.Save(ctx, appName, fileID, file.Filename, src)
+	if err != nil {
+		return nil, fmt.Errorf("save file: %w", err)
+	}
+
+	mimeType := uploadMimeType(file)
+	pb := s.Dialect.NewParamBuilder()
+	insertSQL := fmt.Sprintf(`INSERT INTO _files (id, filename, storage_path, mime_type, size, uploaded_by)
+	        VALUES (%s, %s, %s, %s, %s, %s)`,
+		pb.Add(fileID), pb.Add(file.Filename), pb.Add(storagePath), pb.Add(mimeType), pb.Add(file.Size), pb.Add(uploadedBy))
+	if _, err := store.Exec(ctx, s.DB, insertSQL, pb.Params()...); err != nil {
+		// Clean up stored file on DB failure
+		_ = fs.Delete(ctx, storagePath)
+		return nil, fmt.Errorf("insert _files: %w", err)
+	}
+
+	return &storedFile{ID: fileID, Filename: file.Filename, MimeType: mimeType, Size: file.Size, StoragePath: storagePath}, nil
+}
+
+// discardStoredFile removes a file saved by saveUploadedFile, both from storage and _files.
+func discardStoredFile(ctx context.Context, s *store.Store, fs storage.FileStorage, f *storedFile) {
+	_ = fs.Delete(ctx, f.StoragePath)
+	pb := s.Dialect.NewParamBuilder()
+	_, _ = store.Exec(ctx, s.DB, fmt.Sprintf("DELETE FROM _files WHERE id = %s", pb.Add(f.ID)), pb.Params()...)
+}
+
+func uploadMimeType(file *multipart.FileHeader) string {
+	if mimeType := file.Header.Get("Content-Type"); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
 func (h *FileHandler) Serve(c *fiber.Ctx) error {
 	ctx := c.UserContext()
 	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "storage", "file", "file.serve")
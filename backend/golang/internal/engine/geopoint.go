@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rocket-backend/internal/metadata"
+)
+
+// Geopoint fields hold a {"lat": ..., "lng": ...} object, stored as JSON
+// (JSONB on PostgreSQL, TEXT on SQLite) like file fields. Lists filter them
+// with near[field]=lat,lng,radius_km through the dialect's GeoWithinExpr.
+
+// geoNear is the value of a "near" filter.
+type geoNear struct {
+	Lat, Lng, RadiusKm float64
+}
+
+// parseGeopoint checks a write value for a geopoint field: an object with
+// numeric lat in [-90, 90] and lng in [-180, 180], and no other keys.
+func parseGeopoint(val any) (map[string]any, error) {
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be an object with lat and lng")
+	}
+	for key := range obj {
+		if key != "lat" && key != "lng" {
+			return nil, fmt.Errorf("unknown key %q, expected lat and lng", key)
+		}
+	}
+	lat, latOK := obj["lat"].(float64)
+	lng, lngOK := obj["lng"].(float64)
+	if !latOK || !lngOK {
+		return nil, fmt.Errorf("lat and lng must be numbers")
+	}
+	if err := checkCoordinates(lat, lng); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func checkCoordinates(lat, lng float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("lat must be between -90 and 90")
+	}
+	if lng < -180 || lng > 180 {
+		return fmt.Errorf("lng must be between -180 and 180")
+	}
+	return nil
+}
+
+// validateGeopointField reports a malformed geopoint; null clears the field.
+func validateGeopointField(f *metadata.Field, val any) *ErrorDetail {
+	if val == nil {
+		return nil
+	}
+	if _, err := parseGeopoint(val); err != nil {
+		return &ErrorDetail{Field: f.Name, Rule: "type", Message: fmt.Sprintf("%s %v", f.Name, err)}
+	}
+	return nil
+}
+
+// parseNearFilter parses the "lat,lng,radius_km" value of near[field].
+func parseNearFilter(val string) (geoNear, error) {
+	parts := strings.Split(val, ",")
+	if len(parts) != 3 {
+		return geoNear{}, fmt.Errorf("expected lat,lng,radius_km")
+	}
+	var nums [3]float64
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return geoNear{}, fmt.Errorf("%q is not a number", p)
+		}
+		nums[i] = n
+	}
+	if err := checkCoordinates(nums[0], nums[1]); err != nil {
+		return geoNear{}, err
+	}
+	if nums[2] <= 0 {
+		return geoNear{}, fmt.Errorf("radius_km must be positive")
+	}
+	return geoNear{Lat: nums[0], Lng: nums[1], RadiusKm: nums[2]}, nil
+}
+
+// decodeGeopoint turns a stored geopoint (JSON text on SQLite, and on
+// PostgreSQL through database/sql) back into an object.
+func decodeGeopoint(raw any) any {
+	var text []byte
+	switch v := raw.(type) {
+	case string:
+		text = []byte(v)
+	case []byte:
+		text = v
+	default:
+		return raw
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(text, &obj); err != nil {
+		return raw
+	}
+	return obj
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestGeopointField_NearFilter(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "place",
+		Table:      "places",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "name", Type: "string"},
+			{Name: "location", Type: "geopoint", Nullable: true},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+	app.Post("/api/:entity", h.Create)
+
+	do := func(method, path string, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+	near := func(value string) []string {
+		t.Helper()
+		status, out := do("GET", "/api/place?near[location]="+value, nil)
+		if status != 200 {
+			t.Fatalf("near %s: %d %v", value, status, out)
+		}
+		var got []string
+		for _, row := range out["data"].([]any) {
+			got = append(got, row.(map[string]any)["name"].(string))
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	for _, p := range []map[string]any{
+		{"name": "paris", "location": map[string]any{"lat": 48.8566, "lng": 2.3522}},
+		{"name": "versailles", "location": map[string]any{"lat": 48.8049, "lng": 2.1204}}, // ~18 km from Paris
+		{"name": "london", "location": map[string]any{"lat": 51.5074, "lng": -0.1278}},
+		{"name": "nowhere"},
+	} {
+		if status, out := do("POST", "/api/place", p); status != 201 {
+			t.Fatalf("create %v: %d %v", p["name"], status, out)
+		}
+	}
+
+	_, out := do("GET", "/api/place?filter[name]=paris", nil)
+	loc := out["data"].([]any)[0].(map[string]any)["location"]
+	if !reflect.DeepEqual(loc, map[string]any{"lat": 48.8566, "lng": 2.3522}) {
+		t.Fatalf("expected the geopoint back as an object, got %#v", loc)
+	}
+
+	if got := near("48.8566,2.3522,25"); !reflect.DeepEqual(got, []string{"paris", "versailles"}) {
+		t.Fatalf("25 km around Paris: got %v", got)
+	}
+	if got := near("48.8566,2.3522,5"); !reflect.DeepEqual(got, []string{"paris"}) {
+		t.Fatalf("5 km around Paris: got %v", got)
+	}
+	if got := near("48.8566,2.3522,500"); !reflect.DeepEqual(got, []string{"london", "paris", "versailles"}) {
+		t.Fatalf("500 km around Paris: got %v", got)
+	}
+
+	if status, _ := do("GET", "/api/place?near[location]=91,0,5", nil); status != 400 {
+		t.Fatalf("expected 400 for an out-of-range latitude, got %d", status)
+	}
+	if status, _ := do("GET", "/api/place?near[name]=0,0,5", nil); status != 400 {
+		t.Fatalf("expected 400 for near on a non-geopoint field, got %d", status)
+	}
+	status, out := do("POST", "/api/place", map[string]any{"name": "bad", "location": map[string]any{"lat": 12, "lng": 200}})
+	if status != 422 || !strings.Contains(out["error"].(map[string]any)["details"].([]any)[0].(map[string]any)["message"].(string), "lng") {
+		t.Fatalf("expected 422 for an out-of-range longitude, got %d %v", status, out)
+	}
+}
+
+func TestSQLiteGeoWithinExpr_CrossesAntimeridian(t *testing.T) {
+	d := store.NewDialect("sqlite")
+	pb := d.NewParamBuilder()
+	expr := d.GeoWithinExpr("location", pb, 0, 179.9, 50)
+	if !strings.Contains(expr, " OR ") {
+		t.Fatalf("expected the longitude range to wrap, got %s", expr)
+	}
+	params := pb.Params()
+	if lo, hi := params[2].(float64), params[3].(float64); lo < 179 || hi > -179 {
+		t.Fatalf("expected longitudes wrapped to [>179, <-179], got %v %v", lo, hi)
+	}
+}
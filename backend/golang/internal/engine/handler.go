@@ -3,27 +3,64 @@ package engine
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"net/url"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
 	"rocket-backend/internal/instrument"
 	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/storage"
 	"rocket-backend/internal/store"
 )
 
 type Handler struct {
 	store    *store.Store
 	registry *metadata.Registry
+	hooks    *HookRegistry
+	throttle *writeThrottle
+
+	// Optional: enables inline file fields on multipart writes
+	fileStorage storage.FileStorage
+	maxFileSize int64
+	appName     string
 }
 
 func NewHandler(s *store.Store, reg *metadata.Registry) *Handler {
-	return &Handler{store: s, registry: reg}
+	return &Handler{store: s, registry: reg, hooks: DefaultHooks, throttle: newWriteThrottle()}
+}
+
+// SetHooks replaces the Go hook registry (DefaultHooks by default).
+func (h *Handler) SetHooks(r *HookRegistry) {
+	h.hooks = r
+}
+
+// SetFileStorage enables multipart/form-data writes that carry file fields inline.
+func (h *Handler) SetFileStorage(fs storage.FileStorage, maxSize int64, appName string) {
+	h.fileStorage = fs
+	h.maxFileSize = maxSize
+	h.appName = appName
 }
 
 // List handles GET /api/:entity
 func (h *Handler) List(c *fiber.Ctx) error {
+	return h.list(c, "record.list", func(entity *metadata.Entity) (*QueryPlan, error) {
+		return ParseQueryParams(c, entity, h.registry)
+	})
+}
+
+// Search handles POST /api/:entity/search — a list whose parameters come
+// from a JSON body (see SearchQuery) rather than the query string.
+func (h *Handler) Search(c *fiber.Ctx) error {
+	return h.list(c, "record.search", func(entity *metadata.Entity) (*QueryPlan, error) {
+		return ParseSearchBody(c.Body(), entity, h.registry)
+	})
+}
+
+func (h *Handler) list(c *fiber.Ctx, spanName string, parse func(*metadata.Entity) (*QueryPlan, error)) error {
 	ctx := c.UserContext()
-	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", "record.list")
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", spanName)
 	defer span.End()
 	c.SetUserContext(ctx)
 
@@ -33,50 +70,90 @@ func (h *Handler) List(c *fiber.Ctx) error {
 		return err
 	}
 	span.SetEntity(entity.Name, "")
+	return h.listRecords(c, span, entity, parse)
+}
 
+// listRecords responds with one page of entity's records, after the read
+// permission check, row-level filters and scope. deps are further entities
+// whose writes invalidate the cached page.
+func (h *Handler) listRecords(c *fiber.Ctx, span instrument.Span, entity *metadata.Entity, parse func(*metadata.Entity) (*QueryPlan, error), deps ...string) error {
 	user := getUser(c)
 	if err := CheckPermission(c.Context(), user, entity.Name, "read", h.registry, nil); err != nil {
 		span.SetStatus("error")
 		return err
 	}
 
-	plan, err := ParseQueryParams(c, entity, h.registry)
+	plan, err := parse(entity)
 	if err != nil {
 		span.SetStatus("error")
 		return err
 	}
+	if plan.IncludeDeleted {
+		if err := CheckIncludeDeleted(c.Context(), user, entity.Name, h.registry); err != nil {
+			span.SetStatus("error")
+			return err
+		}
+	}
 
 	// Inject row-level security filters
 	if filters := GetReadFilters(user, entity.Name, h.registry); len(filters) > 0 {
 		plan.Filters = append(plan.Filters, filters...)
 	}
-
-	// Execute data query
-	qr := BuildSelectSQL(plan, h.store.Dialect)
-	rows, err := store.QueryRows(c.Context(), h.store.DB, qr.SQL, qr.Params...)
-	if err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("list %s: %w", entity.Name, err)
-	}
-
-	// Execute count query
-	cr := BuildCountSQL(plan, h.store.Dialect)
-	countRow, err := store.QueryRow(c.Context(), h.store.DB, cr.SQL, cr.Params...)
+	scope, err := h.scopeFilter(c.Context(), entity, user)
 	if err != nil {
 		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("count %s: %w", entity.Name, err)
+		return err
 	}
-	total := countRow["count"]
+	plan.Filters = append(plan.Filters, scope...)
 
-	// Load includes
-	if len(plan.Includes) > 0 {
-		if err := LoadIncludes(c.Context(), h.store.DB, h.store.Dialect, h.registry, entity, rows, plan.Includes); err != nil {
+	// Execute data query
+	db := h.reader(c)
+	qr := BuildSelectSQL(plan, h.store.Dialect)
+	cacheKey := readCacheKey("list", qr.SQL, qr.Params, plan.Includes)
+	var rows []map[string]any
+	var total any
+	var next string
+	if v, ok := h.cacheGet(c, span, entity, cacheKey); ok {
+		cached := v.(cachedList)
+		rows = append([]map[string]any(nil), cached.rows...)
+		total = cached.total
+		next = cached.next
+	} else {
+		rows, err = store.QueryRows(c.Context(), db, qr.SQL, qr.Params...)
+		if err != nil {
 			span.SetStatus("error")
 			span.SetMetadata("error", err.Error())
-			return fmt.Errorf("load includes: %w", err)
+			return fmt.Errorf("list %s: %w", entity.Name, err)
+		}
+		decodeStoredFields(entity, rows...)
+
+		if plan.Keyset {
+			// A cursor page has no count; the extra record means more follow
+			if len(rows) > plan.PerPage {
+				rows = rows[:plan.PerPage]
+				next = encodeCursor(plan, rows[len(rows)-1])
+			}
+		} else {
+			// Execute count query
+			cr := BuildCountSQL(plan, h.store.Dialect)
+			countRow, err := store.QueryRow(c.Context(), db, cr.SQL, cr.Params...)
+			if err != nil {
+				span.SetStatus("error")
+				span.SetMetadata("error", err.Error())
+				return fmt.Errorf("count %s: %w", entity.Name, err)
+			}
+			total = countRow["count"]
 		}
+
+		// Load includes
+		if len(plan.Includes) > 0 {
+			if err := LoadIncludes(c.Context(), db, h.store.Dialect, h.registry, entity, rows, plan.Includes); err != nil {
+				span.SetStatus("error")
+				span.SetMetadata("error", err.Error())
+				return fmt.Errorf("load includes: %w", err)
+			}
+		}
+		h.cachePut(entity, plan.Includes, cacheKey, cachedList{rows: append([]map[string]any(nil), rows...), total: total, next: next}, deps...)
 	}
 
 	// Ensure non-nil slice for JSON
@@ -84,14 +161,22 @@ func (h *Handler) List(c *fiber.Ctx) error {
 		rows = []map[string]any{}
 	}
 
+	meta := fiber.Map{
+		"page":     plan.Page,
+		"per_page": plan.PerPage,
+		"total":    total,
+	}
+	if plan.Keyset {
+		meta = fiber.Map{"limit": plan.PerPage}
+		if next != "" {
+			meta["next_cursor"] = next
+		}
+	}
+
 	span.SetStatus("ok")
 	return c.JSON(fiber.Map{
 		"data": rows,
-		"meta": fiber.Map{
-			"page":     plan.Page,
-			"per_page": plan.PerPage,
-			"total":    total,
-		},
+		"meta": meta,
 	})
 }
 
@@ -117,27 +202,62 @@ func (h *Handler) GetByID(c *fiber.Ctx) error {
 		return err
 	}
 
-	row, err := fetchRecord(c.Context(), h.store.DB, entity, id, h.store.Dialect)
-	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
+	includes := parseIncludes(c)
+	if err := validateIncludes(h.registry, entity, includes); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	includeDeleted := c.QueryBool("include_deleted")
+	if includeDeleted {
+		if err := CheckIncludeDeleted(c.Context(), user, entity.Name, h.registry); err != nil {
 			span.SetStatus("error")
-			return respondError(c, NotFoundError(entity.Name, id))
+			return err
 		}
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("get %s/%s: %w", entity.Name, id, err)
 	}
-
-	// Load includes
-	includes := parseIncludes(c)
-	if len(includes) > 0 {
-		rows := []map[string]any{row}
-		if err := LoadIncludes(c.Context(), h.store.DB, h.store.Dialect, h.registry, entity, rows, includes); err != nil {
+	kind := "get"
+	if includeDeleted {
+		kind = "get_deleted"
+	}
+	cacheKey := readCacheKey(kind, id, nil, includes)
+	var row map[string]any
+	if v, ok := h.cacheGet(c, span, entity, cacheKey); ok {
+		row = v.(map[string]any)
+	} else {
+		db := h.reader(c)
+		row, err = loadRecord(c.Context(), db, entity, id, h.store.Dialect, includeDeleted)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				span.SetStatus("error")
+				return respondError(c, NotFoundError(entity.Name, id))
+			}
 			span.SetStatus("error")
 			span.SetMetadata("error", err.Error())
-			return fmt.Errorf("load includes: %w", err)
+			return fmt.Errorf("get %s/%s: %w", entity.Name, id, err)
+		}
+
+		// Load includes
+		if len(includes) > 0 {
+			rows := []map[string]any{row}
+			if err := LoadIncludes(c.Context(), db, h.store.Dialect, h.registry, entity, rows, includes); err != nil {
+				span.SetStatus("error")
+				span.SetMetadata("error", err.Error())
+				return fmt.Errorf("load includes: %w", err)
+			}
+			row = rows[0]
+		}
+		h.cachePut(entity, includes, cacheKey, row)
+	}
+
+	if ok, err := h.inScope(c.Context(), entity, user, row); err != nil || !ok {
+		span.SetStatus("error")
+		if err != nil {
+			return err
 		}
-		row = rows[0]
+		return respondError(c, NotFoundError(entity.Name, id))
+	}
+	if err := CheckRecordDenied(user, entity.Name, "read", h.registry, row); err != nil {
+		span.SetStatus("error")
+		return err
 	}
 
 	span.SetStatus("ok")
@@ -163,35 +283,115 @@ func (h *Handler) Create(c *fiber.Ctx) error {
 		span.SetStatus("error")
 		return err
 	}
+	if err := checkWriteMode(c, user, entity, "create"); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	bypass, err := readBypass(c, user)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	reservation, err := h.checkWriteLimit(c, entity, user, 1)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	defer reservation.release()
 
 	var body map[string]any
-	if err := c.BodyParser(&body); err != nil {
+	var uploaded []*storedFile
+	if isMultipart(c) {
+		body, uploaded, err = h.parseMultipartBody(c, entity)
+		if err != nil {
+			span.SetStatus("error")
+			return err
+		}
+	} else if err := c.BodyParser(&body); err != nil {
 		span.SetStatus("error")
 		return respondError(c, NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body"))
 	}
+	if body == nil {
+		body = map[string]any{}
+	}
+	stripProtectedFields(entity, user, "create", body)
+	if err := h.applyScopeDefaults(c.Context(), entity, user, body); err != nil {
+		h.discardFiles(c.Context(), uploaded)
+		span.SetStatus("error")
+		return err
+	}
+	if err := h.checkScopedWrite(c.Context(), entity, user, body); err != nil {
+		h.discardFiles(c.Context(), uploaded)
+		span.SetStatus("error")
+		return err
+	}
+	// A create has no stored record; row-level denies match the new one
+	if err := CheckRecordDenied(user, entity.Name, "create", h.registry, body); err != nil {
+		h.discardFiles(c.Context(), uploaded)
+		span.SetStatus("error")
+		return err
+	}
 
 	plan, validationErrs := PlanWrite(entity, h.registry, body, nil)
 	if len(validationErrs) > 0 {
+		h.discardFiles(c.Context(), uploaded)
 		span.SetStatus("error")
 		return respondError(c, ValidationError(validationErrs))
 	}
+	stripProtectedChildFields(h.registry, user, plan.ChildOps)
+	if err := checkChildWriteModes(c, user, h.registry, plan.ChildOps); err != nil {
+		h.discardFiles(c.Context(), uploaded)
+		span.SetStatus("error")
+		return err
+	}
 	plan.User = user
+	plan.Hooks = h.hooks.For(entity.Name)
+	plan.DryRun = c.QueryBool("dry_run")
+	plan.Bypass = bypass
 
-	record, err := ExecuteWritePlan(c.Context(), h.store, h.registry, plan)
+	record, err := ExecuteWritePlan(ctx, h.store, h.registry, plan)
 	if err != nil {
+		h.discardFiles(c.Context(), uploaded)
 		span.SetStatus("error")
 		span.SetMetadata("error", err.Error())
 		return handleWriteError(c, err)
 	}
+	if plan.DryRun {
+		h.discardFiles(c.Context(), uploaded)
+		span.SetStatus("ok")
+		return respondDryRun(c, record)
+	}
 
+	h.markWrite(c)
+	reservation.keep(1)
 	span.SetStatus("ok")
+	if preferMinimal(c) {
+		c.Location(c.Path() + "/" + url.PathEscape(fmt.Sprint(record[entity.PrimaryKey.Field])))
+		return c.Status(201).Send(nil)
+	}
 	return c.Status(201).JSON(fiber.Map{"data": record})
 }
 
 // Update handles PUT /api/:entity/:id
 func (h *Handler) Update(c *fiber.Ctx) error {
+	return h.update(c, false)
+}
+
+// Patch handles PATCH /api/:entity/:id. Only the submitted fields are
+// written, as with PUT, but rules and state machines see the stored record
+// with them applied, so a computed rule reads the current value of any field
+// the body leaves out.
+func (h *Handler) Patch(c *fiber.Ctx) error {
+	return h.update(c, true)
+}
+
+func (h *Handler) update(c *fiber.Ctx, partial bool) error {
+	operation := "record.update"
+	if partial {
+		operation = "record.patch"
+	}
 	ctx := c.UserContext()
-	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", "record.update")
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", operation)
 	defer span.End()
 	c.SetUserContext(ctx)
 
@@ -217,32 +417,93 @@ func (h *Handler) Update(c *fiber.Ctx) error {
 	}
 
 	user := getUser(c)
+	if ok, err := h.inScope(c.Context(), entity, user, currentRecord); err != nil || !ok {
+		span.SetStatus("error")
+		if err != nil {
+			return err
+		}
+		return respondError(c, NotFoundError(entity.Name, id))
+	}
 	if err := CheckPermission(c.Context(), user, entity.Name, "update", h.registry, currentRecord); err != nil {
 		span.SetStatus("error")
 		return err
 	}
+	if err := checkWriteMode(c, user, entity, "update"); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	bypass, err := readBypass(c, user)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	reservation, err := h.checkWriteLimit(c, entity, user, 1)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	defer reservation.release()
 
 	var body map[string]any
 	if err := c.BodyParser(&body); err != nil {
 		span.SetStatus("error")
 		return respondError(c, NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body"))
 	}
+	stripProtectedFields(entity, user, "update", body)
+	if err := checkImmutableFields(c, user, entity, body, currentRecord); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	merged := make(map[string]any, len(currentRecord)+len(body))
+	for k, v := range currentRecord {
+		merged[k] = v
+	}
+	for k, v := range body {
+		merged[k] = v
+	}
+	if err := h.checkScopedWrite(c.Context(), entity, user, merged); err != nil {
+		span.SetStatus("error")
+		return err
+	}
 
 	plan, validationErrs := PlanWrite(entity, h.registry, body, id)
 	if len(validationErrs) > 0 {
 		span.SetStatus("error")
 		return respondError(c, ValidationError(validationErrs))
 	}
+	stripProtectedChildFields(h.registry, user, plan.ChildOps)
+	if err := checkChildWriteModes(c, user, h.registry, plan.ChildOps); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	if err := checkImmutableChildFields(c, user, h.store, h.registry, id, plan.ChildOps); err != nil {
+		span.SetStatus("error")
+		return err
+	}
 	plan.User = user
+	plan.Hooks = h.hooks.For(entity.Name)
+	plan.DryRun = c.QueryBool("dry_run")
+	plan.Bypass = bypass
+	plan.Partial = partial
 
-	record, err := ExecuteWritePlan(c.Context(), h.store, h.registry, plan)
+	record, err := ExecuteWritePlan(ctx, h.store, h.registry, plan)
 	if err != nil {
 		span.SetStatus("error")
 		span.SetMetadata("error", err.Error())
 		return handleWriteError(c, err)
 	}
+	if plan.DryRun {
+		span.SetStatus("ok")
+		return respondDryRun(c, record)
+	}
 
+	h.markWrite(c)
+	reservation.keep(1)
 	span.SetStatus("ok")
+	if preferMinimal(c) {
+		c.Location(c.Path())
+		return c.Status(204).Send(nil)
+	}
 	return c.JSON(fiber.Map{"data": record})
 }
 
@@ -275,10 +536,37 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 	}
 
 	user := getUser(c)
+	if ok, err := h.inScope(c.Context(), entity, user, currentRecord); err != nil || !ok {
+		span.SetStatus("error")
+		if err != nil {
+			return err
+		}
+		return respondError(c, NotFoundError(entity.Name, id))
+	}
 	if err := CheckPermission(c.Context(), user, entity.Name, "delete", h.registry, currentRecord); err != nil {
 		span.SetStatus("error")
 		return err
 	}
+	if err := checkWriteMode(c, user, entity, "delete"); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	bypass, err := readBypass(c, user)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	bypass.Rules = false // no rules run on delete
+	reservation, err := h.checkWriteLimit(c, entity, user, 1)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	defer reservation.release()
+	if err := checkCascadeWriteModes(c, user, h.registry, entity); err != nil {
+		span.SetStatus("error")
+		return err
+	}
 
 	tx, err := h.store.BeginTx(c.Context())
 	if err != nil {
@@ -288,6 +576,40 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 	}
 	defer tx.Rollback() //nolint:errcheck
 
+	// ?dry_run=true runs the cascades, reports what each relation's on_delete
+	// policy changed, and rolls back; hooks and webhooks don't run
+	if c.QueryBool("dry_run") {
+		span.SetMetadata("dry_run", true)
+		effects, err := cascadeDelete(c.Context(), tx, h.store.Dialect, h.registry, entity, id)
+		if err != nil {
+			span.SetStatus("error")
+			var appErr *AppError
+			if errors.As(err, &appErr) {
+				return respondError(c, appErr)
+			}
+			span.SetMetadata("error", err.Error())
+			return fmt.Errorf("cascade delete: %w", err)
+		}
+		for i := range effects {
+			if CheckPermission(c.Context(), user, effects[i].Entity, "read", h.registry, nil) != nil {
+				effects[i].Count = nil
+			}
+		}
+		if effects == nil {
+			effects = []CascadeEffect{}
+		}
+		span.SetStatus("ok")
+		return c.JSON(fiber.Map{"data": currentRecord, "meta": fiber.Map{"dry_run": true, "cascade": effects}})
+	}
+
+	// Delete webhooks carry the record as loaded before the delete, since the
+	// row can't be re-fetched afterwards; hooks get their own copy
+	snapshot := maps.Clone(currentRecord)
+	if err := runBeforeDeleteHooks(c.Context(), h.hooks.For(entity.Name), entity, currentRecord, user); err != nil {
+		span.SetStatus("error")
+		return handleWriteError(c, err)
+	}
+
 	// Handle cascades
 	if err := HandleCascadeDelete(c.Context(), tx, h.store.Dialect, h.registry, entity, id); err != nil {
 		var appErr *AppError
@@ -319,12 +641,25 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 		span.SetStatus("error")
 		return respondError(c, NotFoundError(entity.Name, id))
 	}
+	if err := refreshRollupsOver(c.Context(), tx, h.store.Dialect, h.registry, entity, currentRecord); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return err
+	}
 
-	// Pre-commit: fire sync (before_delete) webhooks
-	if err := FireSyncWebhooks(c.Context(), tx, h.store.Dialect, h.registry, "before_delete", entity.Name, "delete", currentRecord, nil, user); err != nil {
+	if err := auditBypass(c.Context(), tx, h.store.Dialect, entity, id, user, bypass); err != nil {
 		span.SetStatus("error")
 		span.SetMetadata("error", err.Error())
-		return fmt.Errorf("sync webhook: %w", err)
+		return err
+	}
+
+	// Pre-commit: fire sync (before_delete) webhooks
+	if !bypass.Webhooks {
+		if err := FireSyncWebhooks(ctx, tx, h.store.Dialect, h.registry, "before_delete", entity.Name, "delete", snapshot, nil, user); err != nil {
+			span.SetStatus("error")
+			span.SetMetadata("error", err.Error())
+			return fmt.Errorf("sync webhook: %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -332,14 +667,115 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 		span.SetMetadata("error", err.Error())
 		return fmt.Errorf("commit: %w", err)
 	}
+	invalidateEntityCache(h.registry, entity.Name)
+
+	if !bypass.Rules {
+		runAfterRules(ctx, h.store, h.registry, entity, "after_delete", "delete", snapshot, snapshot)
+	}
 
 	// Post-commit: fire async (after_delete) webhooks
-	FireAsyncWebhooks(c.Context(), h.store, h.registry, "after_delete", entity.Name, "delete", currentRecord, nil, user)
+	if !bypass.Webhooks {
+		FireAsyncWebhooks(ctx, h.store, h.registry, "after_delete", entity.Name, "delete", snapshot, nil, user)
+	}
 
+	h.markWrite(c)
+	reservation.keep(1)
 	span.SetStatus("ok")
 	return c.JSON(fiber.Map{"data": fiber.Map{"id": id}})
 }
 
+// Restore handles POST /api/:entity/:id/restore. It clears deleted_at on a
+// soft-deleted record and returns it; restoring a record that isn't deleted
+// returns it unchanged. It needs the delete permission, as it undoes one.
+// Children soft-deleted by a cascade stay deleted, and no hooks, rules or
+// webhooks run.
+func (h *Handler) Restore(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", "record.restore")
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	entity, err := h.resolveEntity(c)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	id := c.Params("id")
+	span.SetEntity(entity.Name, id)
+	if !entity.SoftDelete {
+		span.SetStatus("error")
+		return respondError(c, NewAppError("INVALID_PAYLOAD", 400, fmt.Sprintf("%s does not use soft delete", entity.Name)))
+	}
+
+	currentRecord, err := fetchRecordIncludingDeleted(c.Context(), h.store.DB, entity, id, h.store.Dialect)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			span.SetStatus("error")
+			return respondError(c, NotFoundError(entity.Name, id))
+		}
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return fmt.Errorf("fetch %s/%s: %w", entity.Name, id, err)
+	}
+
+	user := getUser(c)
+	if ok, err := h.inScope(c.Context(), entity, user, currentRecord); err != nil || !ok {
+		span.SetStatus("error")
+		if err != nil {
+			return err
+		}
+		return respondError(c, NotFoundError(entity.Name, id))
+	}
+	if err := CheckPermission(c.Context(), user, entity.Name, "delete", h.registry, currentRecord); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	if err := checkWriteMode(c, user, entity, "update"); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	reservation, err := h.checkWriteLimit(c, entity, user, 1)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	defer reservation.release()
+	if currentRecord["deleted_at"] == nil {
+		span.SetStatus("ok")
+		return c.JSON(fiber.Map{"data": currentRecord})
+	}
+
+	var record map[string]any
+	err = h.store.Tx(c.Context(), func(tx store.Querier) error {
+		pkField := entity.PrimaryKey.Field
+		sql, params := BuildRestoreSQL(entity, currentRecord[pkField], h.store.Dialect)
+		if _, err := store.Exec(c.Context(), tx, sql, params...); err != nil {
+			return fmt.Errorf("restore %s/%s: %w", entity.Name, id, err)
+		}
+		if record, err = fetchRecord(c.Context(), tx, entity, currentRecord[pkField], h.store.Dialect); err != nil {
+			return err
+		}
+		if err := refreshRollupsOver(c.Context(), tx, h.store.Dialect, h.registry, entity, record); err != nil {
+			return err
+		}
+		if entity.Audit {
+			return WriteAuditEntry(c.Context(), tx, h.store.Dialect, entity, record[pkField], "restore", user, map[string]AuditChange{})
+		}
+		return nil
+	})
+	if err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return err
+	}
+	invalidateEntityCache(h.registry, entity.Name)
+
+	h.markWrite(c)
+	reservation.keep(1)
+	span.SetStatus("ok")
+	return c.JSON(fiber.Map{"data": record})
+}
+
 func (h *Handler) resolveEntity(c *fiber.Ctx) (*metadata.Entity, error) {
 	name := c.Params("entity")
 	entity := h.registry.GetEntity(name)
@@ -354,6 +790,26 @@ func getUser(c *fiber.Ctx) *metadata.UserContext {
 	return user
 }
 
+// respondDryRun returns the record a ?dry_run=true write would have stored.
+// Nothing was committed, so the status is 200 even for a create.
+func respondDryRun(c *fiber.Ctx, record map[string]any) error {
+	return c.JSON(fiber.Map{"data": record, "meta": fiber.Map{"dry_run": true}})
+}
+
+// preferMinimal reports whether the client sent Prefer: return=minimal (RFC
+// 7240), asking a write to respond with just the record's Location and no
+// body. It sets Preference-Applied when it does. Dry runs always return the
+// record, since showing it is their point.
+func preferMinimal(c *fiber.Ctx) bool {
+	for _, pref := range strings.Split(c.Get("Prefer"), ",") {
+		if strings.EqualFold(strings.ReplaceAll(strings.TrimSpace(pref), " ", ""), "return=minimal") {
+			c.Set("Preference-Applied", "return=minimal")
+			return true
+		}
+	}
+	return false
+}
+
 func respondError(c *fiber.Ctx, appErr *AppError) error {
 	return c.Status(appErr.Status).JSON(ErrorResponse{Error: appErr})
 }
@@ -37,7 +37,7 @@ func testStore(t *testing.T) *store.Store {
 	if err != nil {
 		t.Fatalf("connect to test db: %v", err)
 	}
-	if err := s.Bootstrap(ctx); err != nil {
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
 		t.Fatalf("bootstrap: %v", err)
 	}
 	return s
@@ -90,10 +90,10 @@ func testAppWithAuth(t *testing.T, s *store.Store, reg *metadata.Registry) *fibe
 	})
 
 	// Auth routes — no middleware
-	authHandler := auth.NewAuthHandler(s, testJWTSecret)
+	authHandler := auth.NewAuthHandler(s, auth.TokenKeys{Secret: testJWTSecret})
 	auth.RegisterAuthRoutes(app, authHandler)
 
-	authMW := auth.AuthMiddleware(testJWTSecret)
+	authMW := auth.AuthMiddleware(auth.TokenKeys{Secret: testJWTSecret})
 	adminMW := auth.RequireAdmin()
 
 	migrator := store.NewMigrator(s)
@@ -427,6 +427,112 @@ func TestComputedFieldEnforcement(t *testing.T) {
 	}
 }
 
+func TestExpressionRuleEnforcement(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(t)
+	defer s.Close()
+
+	reg := metadata.NewRegistry()
+	_ = metadata.LoadAll(ctx, s.DB, reg)
+	app := testApp(t, s, reg)
+
+	const entityName = "_test_expr_rule_entity"
+
+	// Cleanup
+	defer func() {
+		store.Exec(ctx, s.DB, "DELETE FROM _rules WHERE entity = $1", entityName)
+		store.Exec(ctx, s.DB, "DROP TABLE IF EXISTS "+entityName)
+		store.Exec(ctx, s.DB, "DELETE FROM _entities WHERE name = $1", entityName)
+		_ = metadata.Reload(ctx, s.DB, reg)
+	}()
+
+	// 1. Create entity with a date range and a status
+	resp := doRequest(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name": entityName, "table": entityName,
+		"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+		"fields": []any{
+			map[string]any{"name": "id", "type": "uuid"},
+			map[string]any{"name": "start_day", "type": "int"},
+			map[string]any{"name": "end_day", "type": "int"},
+			map[string]any{"name": "status", "type": "string"},
+			map[string]any{"name": "name", "type": "string", "required": true},
+		},
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("create entity: expected 201, got %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+
+	// 2. Add expression rules: the range must not be reversed, and a closed
+	// record can't be changed (reads the stored row through old)
+	for _, def := range []map[string]any{
+		{"field": "end_day", "expression": "record.end_day < record.start_day", "message": "End day must not be before start day"},
+		{"expression": "action == 'update' && old.status == 'closed'", "message": "Closed records cannot be changed"},
+	} {
+		resp = doRequest(t, app, "POST", "/api/_admin/rules", map[string]any{
+			"entity":     entityName,
+			"hook":       "before_write",
+			"type":       "expression",
+			"definition": def,
+			"priority":   10,
+			"active":     true,
+		})
+		if resp.StatusCode != 201 {
+			t.Fatalf("create rule: expected 201, got %d: %s", resp.StatusCode, readBody(t, resp))
+		}
+	}
+
+	// 3. Insert a reversed range — should return 422 with the rule's message and field
+	resp = doRequest(t, app, "POST", "/api/"+entityName, map[string]any{
+		"start_day": 5, "end_day": 1, "status": "open", "name": "Bad Range",
+	})
+	body := readBody(t, resp)
+	if resp.StatusCode != 422 {
+		t.Fatalf("insert reversed range: expected 422, got %d: %s", resp.StatusCode, body)
+	}
+
+	var errResp engine.ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		t.Fatalf("parse error response: %v", err)
+	}
+	if errResp.Error.Code != "VALIDATION_FAILED" {
+		t.Fatalf("expected VALIDATION_FAILED, got %s", errResp.Error.Code)
+	}
+	if len(errResp.Error.Details) != 1 {
+		t.Fatalf("expected one expression rule violation, got %+v", errResp.Error.Details)
+	}
+	if d := errResp.Error.Details[0]; d.Field != "end_day" || d.Rule != "expression" || d.Message != "End day must not be before start day" {
+		t.Fatalf("expected the end_day rule's message, got %+v", d)
+	}
+
+	// 4. Insert a valid closed record — old is empty on create, so it passes
+	resp = doRequest(t, app, "POST", "/api/"+entityName, map[string]any{
+		"start_day": 1, "end_day": 5, "status": "closed", "name": "Closed Record",
+	})
+	body = readBody(t, resp)
+	if resp.StatusCode != 201 {
+		t.Fatalf("insert valid range: expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var createResp map[string]any
+	json.Unmarshal(body, &createResp)
+	recordID := createResp["data"].(map[string]any)["id"].(string)
+
+	// 5. Update the closed record — rejected by the rule reading old.status
+	resp = doRequest(t, app, "PUT", "/api/"+entityName+"/"+recordID, map[string]any{
+		"start_day": 1, "end_day": 5, "status": "closed", "name": "Renamed",
+	})
+	body = readBody(t, resp)
+	if resp.StatusCode != 422 {
+		t.Fatalf("update closed record: expected 422, got %d: %s", resp.StatusCode, body)
+	}
+	errResp = engine.ErrorResponse{}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		t.Fatalf("parse error response: %v", err)
+	}
+	if len(errResp.Error.Details) != 1 || errResp.Error.Details[0].Message != "Closed records cannot be changed" {
+		t.Fatalf("expected the closed-record rule's message, got %+v", errResp.Error.Details)
+	}
+}
+
 func TestStateMachineEnforcement(t *testing.T) {
 	ctx := context.Background()
 	s := testStore(t)
@@ -2273,3 +2379,131 @@ func TestDisabledUserCannotLogin(t *testing.T) {
 		t.Fatalf("disabled user login: expected 401, got %d", resp.StatusCode)
 	}
 }
+
+func TestPessimisticLocking_ParallelIncrements(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(t)
+	defer s.Close()
+
+	reg := metadata.NewRegistry()
+	_ = metadata.LoadAll(ctx, s.DB, reg)
+	app := testApp(t, s, reg)
+
+	const entityName = "_test_locking_entity"
+
+	defer func() {
+		store.Exec(ctx, s.DB, "DELETE FROM _rules WHERE entity = $1", entityName)
+		store.Exec(ctx, s.DB, "DROP TABLE IF EXISTS "+entityName)
+		store.Exec(ctx, s.DB, "DELETE FROM _entities WHERE name = $1", entityName)
+		_ = metadata.Reload(ctx, s.DB, reg)
+	}()
+
+	resp := doRequest(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name": entityName, "table": entityName, "locking": "pessimistic",
+		"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+		"fields": []any{
+			map[string]any{"name": "id", "type": "uuid"},
+			map[string]any{"name": "hits", "type": "int"},
+			map[string]any{"name": "note", "type": "string"},
+		},
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("create entity: expected 201, got %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	resp = doRequest(t, app, "POST", "/api/_admin/rules", map[string]any{
+		"entity": entityName, "hook": "before_update", "type": "computed", "active": true,
+		"definition": map[string]any{"field": "hits", "expression": "old.hits + 1"},
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("create rule: expected 201, got %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+
+	resp = doRequest(t, app, "POST", "/api/"+entityName, map[string]any{"hits": 0})
+	body := readBody(t, resp)
+	if resp.StatusCode != 201 {
+		t.Fatalf("insert: expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var created map[string]any
+	json.Unmarshal(body, &created)
+	id := created["data"].(map[string]any)["id"].(string)
+
+	// Every update reads old.hits; without the row lock increments get lost
+	const n = 8
+	done := make(chan int, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			resp := doRequest(t, app, "PUT", "/api/"+entityName+"/"+id, map[string]any{"note": "hit"})
+			done <- resp.StatusCode
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if status := <-done; status != 200 {
+			t.Fatalf("update: expected 200, got %d", status)
+		}
+	}
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT hits FROM "+entityName+" WHERE id = $1", id)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if fmt.Sprint(row["hits"]) != fmt.Sprint(n) {
+		t.Fatalf("expected %d increments, got %v", n, row["hits"])
+	}
+}
+
+func TestGeopoint_NearFilterUsesGreatCircleDistance(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(t)
+	defer s.Close()
+
+	reg := metadata.NewRegistry()
+	_ = metadata.LoadAll(ctx, s.DB, reg)
+	app := testApp(t, s, reg)
+
+	const entityName = "_test_geo_entity"
+
+	defer func() {
+		store.Exec(ctx, s.DB, "DROP TABLE IF EXISTS "+entityName)
+		store.Exec(ctx, s.DB, "DELETE FROM _entities WHERE name = $1", entityName)
+		_ = metadata.Reload(ctx, s.DB, reg)
+	}()
+
+	resp := doRequest(t, app, "POST", "/api/_admin/entities", map[string]any{
+		"name": entityName, "table": entityName,
+		"primary_key": map[string]any{"field": "id", "type": "uuid", "generated": true},
+		"fields": []any{
+			map[string]any{"name": "id", "type": "uuid"},
+			map[string]any{"name": "name", "type": "string"},
+			map[string]any{"name": "location", "type": "geopoint"},
+		},
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("create entity: expected 201, got %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	for name, loc := range map[string][2]float64{
+		"paris": {48.8566, 2.3522}, "versailles": {48.8049, 2.1204}, "london": {51.5074, -0.1278},
+	} {
+		resp = doRequest(t, app, "POST", "/api/"+entityName, map[string]any{
+			"name": name, "location": map[string]any{"lat": loc[0], "lng": loc[1]},
+		})
+		if resp.StatusCode != 201 {
+			t.Fatalf("create %s: expected 201, got %d: %s", name, resp.StatusCode, readBody(t, resp))
+		}
+	}
+
+	// Versailles is ~18 km from Paris, London ~344 km
+	for radius, want := range map[string]int{"5": 1, "25": 2, "340": 2, "350": 3} {
+		resp = doRequest(t, app, "GET", "/api/"+entityName+"?near[location]=48.8566,2.3522,"+radius, nil)
+		body := readBody(t, resp)
+		if resp.StatusCode != 200 {
+			t.Fatalf("near %s km: expected 200, got %d: %s", radius, resp.StatusCode, body)
+		}
+		var out struct {
+			Data []map[string]any `json:"data"`
+		}
+		_ = json.Unmarshal(body, &out)
+		if len(out.Data) != want {
+			t.Fatalf("near %s km: expected %d places, got %d: %s", radius, want, len(out.Data), body)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -9,7 +11,9 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	"rocket-backend/internal/config"
 	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
 )
 
 // TestResolveEntity_UnknownEntityReturnsError verifies that resolveEntity returns
@@ -99,3 +103,54 @@ func isAppError(err error, target **AppError) bool {
 		return true
 	})()
 }
+
+// testErrorHandler renders AppErrors the way the server's error handler does.
+func testErrorHandler(c *fiber.Ctx, err error) error {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return c.Status(appErr.Status).JSON(ErrorResponse{Error: appErr})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+// newSQLiteStore opens a bootstrapped SQLite store in a temp dir, closed when
+// the test ends.
+func newSQLiteStore(t *testing.T) *store.Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	return s
+}
+
+// newTestApp returns an app rendering errors with testErrorHandler whose
+// requests run as user; with a nil user no one is signed in.
+func newTestApp(t *testing.T, user *metadata.UserContext) *fiber.App {
+	t.Helper()
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	if user != nil {
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", user)
+			return c.Next()
+		})
+	}
+	return app
+}
+
+// testAdmin is the user most handler tests act as.
+func testAdmin() *metadata.UserContext {
+	return &metadata.UserContext{ID: "u1", Roles: []string{"admin"}}
+}
+
+// testUserFromHeaders signs each request in as X-Test-User (default u1) with
+// the role in X-Test-Role, for tests that switch users between requests.
+func testUserFromHeaders(c *fiber.Ctx) error {
+	c.Locals("user", &metadata.UserContext{ID: c.Get("X-Test-User", "u1"), Roles: []string{c.Get("X-Test-Role")}})
+	return c.Next()
+}
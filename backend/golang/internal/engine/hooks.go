@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"rocket-backend/internal/metadata"
+)
+
+// HookContext carries the data a Hook sees for a single write or delete.
+type HookContext struct {
+	Entity *metadata.Entity
+	Action string         // create, update, delete
+	Record map[string]any // fields being written, the saved record, or the record being deleted
+	Old    map[string]any // current record for update/delete; empty on create
+	User   *metadata.UserContext
+	DryRun bool // the write will be rolled back; skip side effects outside the transaction
+}
+
+// Hook runs custom Go logic in an entity's write pipeline. BeforeWrite runs inside
+// the transaction ahead of rules and may mutate hc.Record; returning an error vetoes
+// the write. AfterWrite runs after commit with the saved record and can only adjust
+// the response. BeforeDelete runs inside the delete transaction and may veto it.
+// Returning an *AppError controls the response; any other error becomes a 422.
+type Hook interface {
+	BeforeWrite(ctx context.Context, hc *HookContext) error
+	AfterWrite(ctx context.Context, hc *HookContext) error
+	BeforeDelete(ctx context.Context, hc *HookContext) error
+}
+
+// BaseHook implements Hook with no-ops; embed it to override only what you need.
+type BaseHook struct{}
+
+func (BaseHook) BeforeWrite(context.Context, *HookContext) error  { return nil }
+func (BaseHook) AfterWrite(context.Context, *HookContext) error   { return nil }
+func (BaseHook) BeforeDelete(context.Context, *HookContext) error { return nil }
+
+// HookRegistry holds Go hooks keyed by entity name, in registration order.
+type HookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string][]Hook
+}
+
+func NewHookRegistry() *HookRegistry {
+	return &HookRegistry{hooks: make(map[string][]Hook)}
+}
+
+// Register adds a hook for the entity. Hooks are meant to be registered at startup.
+func (r *HookRegistry) Register(entity string, h Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[entity] = append(r.hooks[entity], h)
+}
+
+// For returns the hooks registered for an entity.
+func (r *HookRegistry) For(entity string) []Hook {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.hooks[entity]
+}
+
+// DefaultHooks is the registry used by every Handler unless replaced with SetHooks.
+// Entity names are matched across all apps.
+var DefaultHooks = NewHookRegistry()
+
+// RegisterHook adds a hook for the entity to DefaultHooks.
+func RegisterHook(entity string, h Hook) {
+	DefaultHooks.Register(entity, h)
+}
+
+// runBeforeWriteHooks lets hooks mutate or veto the fields of a write plan.
+func runBeforeWriteHooks(ctx context.Context, plan *WritePlan, old map[string]any) error {
+	hc := &HookContext{Entity: plan.Entity, Action: plan.action(), Record: plan.Fields, Old: old, User: plan.User, DryRun: plan.DryRun}
+	for _, h := range plan.Hooks {
+		if err := h.BeforeWrite(ctx, hc); err != nil {
+			return hookError(err)
+		}
+	}
+	return nil
+}
+
+// runAfterWriteHooks runs after commit; failures are logged since the write is durable.
+func runAfterWriteHooks(ctx context.Context, plan *WritePlan, record, old map[string]any) {
+	hc := &HookContext{Entity: plan.Entity, Action: plan.action(), Record: record, Old: old, User: plan.User}
+	for _, h := range plan.Hooks {
+		if err := h.AfterWrite(ctx, hc); err != nil {
+			log.Printf("ERROR: after_write hook for %s: %v", plan.Entity.Name, err)
+		}
+	}
+}
+
+// runBeforeDeleteHooks lets hooks veto a delete.
+func runBeforeDeleteHooks(ctx context.Context, hooks []Hook, entity *metadata.Entity, record map[string]any, user *metadata.UserContext) error {
+	hc := &HookContext{Entity: entity, Action: "delete", Record: record, Old: record, User: user}
+	for _, h := range hooks {
+		if err := h.BeforeDelete(ctx, hc); err != nil {
+			return hookError(err)
+		}
+	}
+	return nil
+}
+
+func hookError(err error) error {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return NewAppError("HOOK_REJECTED", 422, err.Error())
+}
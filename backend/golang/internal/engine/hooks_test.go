@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+type stampHook struct {
+	BaseHook
+}
+
+func (stampHook) BeforeWrite(ctx context.Context, hc *HookContext) error {
+	if hc.Record["title"] == "forbidden" {
+		return errors.New("title is not allowed")
+	}
+	hc.Record["reviewed_by"] = hc.User.ID
+	return nil
+}
+
+func TestHandler_GoHookStampsFieldAndVetoes(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "article",
+		Table:      "articles",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string"},
+			{Name: "reviewed_by", Type: "string"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	hooks := NewHookRegistry()
+	hooks.Register("article", stampHook{})
+	h := NewHandler(s, reg)
+	h.SetHooks(hooks)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+
+	post := func(body map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/article", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		out, _ := io.ReadAll(resp.Body)
+		var decoded map[string]any
+		_ = json.Unmarshal(out, &decoded)
+		return resp.StatusCode, decoded
+	}
+
+	status, out := post(map[string]any{"title": "Hello"})
+	if status != 201 {
+		t.Fatalf("expected 201, got %d: %v", status, out)
+	}
+	if got := out["data"].(map[string]any)["reviewed_by"]; got != "u1" {
+		t.Fatalf("expected hook to stamp reviewed_by, got %v", got)
+	}
+
+	status, out = post(map[string]any{"title": "forbidden"})
+	if status != 422 || out["error"].(map[string]any)["code"] != "HOOK_REJECTED" {
+		t.Fatalf("expected hook veto as 422 HOOK_REJECTED, got %d: %v", status, out)
+	}
+	row, _ := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM articles")
+	if row["n"] != int64(1) {
+		t.Fatalf("vetoed write must not be stored, got %v rows", row["n"])
+	}
+}
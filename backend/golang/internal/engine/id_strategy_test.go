@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestExecuteWritePlan_IDStrategies(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	nanoID := regexp.MustCompile(`^[A-Za-z0-9_-]{21}$`)
+	tests := []struct {
+		strategy string
+		pkType   string
+		check    func(t *testing.T, first, second any)
+	}{
+		{"uuid", "uuid", func(t *testing.T, first, _ any) {
+			if id, err := uuid.Parse(first.(string)); err != nil || id.Version() != 4 {
+				t.Fatalf("expected a v4 uuid, got %v", first)
+			}
+		}},
+		{"uuidv7", "uuid", func(t *testing.T, first, second any) {
+			a, err := uuid.Parse(first.(string))
+			if err != nil || a.Version() != 7 {
+				t.Fatalf("expected a v7 uuid, got %v", first)
+			}
+			if b := second.(string); b <= first.(string) {
+				t.Fatalf("expected time-ordered ids, got %v then %v", first, b)
+			}
+		}},
+		{"nanoid", "string", func(t *testing.T, first, _ any) {
+			if s, _ := first.(string); !nanoID.MatchString(s) {
+				t.Fatalf("expected a 21-character nanoid, got %v", first)
+			}
+		}},
+		{"sequence", "bigint", func(t *testing.T, first, second any) {
+			if toInt(first) != 1 || toInt(second) != 2 {
+				t.Fatalf("expected sequential ids 1 and 2, got %v and %v", first, second)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			entity := &metadata.Entity{
+				Name:       "item_" + tt.strategy,
+				Table:      "items_" + tt.strategy,
+				PrimaryKey: metadata.PrimaryKey{Field: "id", Type: tt.pkType, Generated: true},
+				IDStrategy: tt.strategy,
+				Fields: []metadata.Field{
+					{Name: "id", Type: tt.pkType},
+					{Name: "title", Type: "string"},
+				},
+			}
+			if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			reg := metadata.NewRegistry()
+			reg.Load([]*metadata.Entity{entity}, nil)
+
+			var ids []any
+			for _, title := range []string{"first", "second"} {
+				plan, verrs := PlanWrite(entity, reg, map[string]any{"title": title}, nil)
+				if len(verrs) > 0 {
+					t.Fatalf("unexpected validation errors: %v", verrs)
+				}
+				rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+				if err != nil {
+					t.Fatalf("create: %v", err)
+				}
+				ids = append(ids, rec["id"])
+			}
+			tt.check(t, ids[0], ids[1])
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// OverrideImmutableHeader lets an admin change immutable fields through the
+// API. Without it admins are rejected like everyone else.
+const OverrideImmutableHeader = "X-Rocket-Override-Immutable"
+
+// checkImmutableFields returns a 422 naming each immutable field an update body
+// changes. Resending the current value is allowed. An admin sending
+// OverrideImmutableHeader: true is let through; anyone else sending it gets a
+// 403.
+func checkImmutableFields(c *fiber.Ctx, user *metadata.UserContext, entity *metadata.Entity, body, current map[string]any) error {
+	return immutableError(c, user, immutableChanges(entity, body, current, ""))
+}
+
+// checkImmutableChildFields applies the same check to the children a nested
+// write updates: rows of a one-to-many relation that name an existing child
+// of parentID by primary key. Violations are named relation.field.
+func checkImmutableChildFields(c *fiber.Ctx, user *metadata.UserContext, s *store.Store, reg *metadata.Registry, parentID any, ops []*RelationWrite) error {
+	var errs []ErrorDetail
+	for _, rw := range ops {
+		if rw.Relation.IsManyToMany() || rw.WriteMode == "append" {
+			continue
+		}
+		target := reg.GetEntity(rw.Relation.Target)
+		if target == nil || !hasImmutableFields(target) {
+			continue
+		}
+		existing, err := fetchCurrentChildren(c.Context(), s.DB, s.Dialect, target, rw.Relation, parentID)
+		if err != nil {
+			return err
+		}
+		byPK := indexByPK(existing, target.PrimaryKey.Field)
+		for _, row := range rw.Data {
+			pk := row[target.PrimaryKey.Field]
+			if pk == nil || row["_delete"] == true {
+				continue
+			}
+			if current, ok := byPK[fmt.Sprintf("%v", pk)]; ok {
+				errs = append(errs, immutableChanges(target, row, current, rw.Relation.Name+".")...)
+			}
+		}
+	}
+	return immutableError(c, user, errs)
+}
+
+func hasImmutableFields(entity *metadata.Entity) bool {
+	for _, f := range entity.Fields {
+		if f.Immutable {
+			return true
+		}
+	}
+	return false
+}
+
+// immutableChanges lists the immutable fields body changes from current,
+// each named with prefix.
+func immutableChanges(entity *metadata.Entity, body, current map[string]any, prefix string) []ErrorDetail {
+	var errs []ErrorDetail
+	for _, f := range entity.Fields {
+		if !f.Immutable {
+			continue
+		}
+		val, ok := body[f.Name]
+		if !ok || fmt.Sprintf("%v", val) == fmt.Sprintf("%v", current[f.Name]) {
+			continue
+		}
+		errs = append(errs, ErrorDetail{
+			Field:   prefix + f.Name,
+			Rule:    "immutable",
+			Message: fmt.Sprintf("%s cannot be changed after creation", f.Name),
+		})
+	}
+	return errs
+}
+
+// immutableError turns errs into the 422, unless an admin sent
+// OverrideImmutableHeader.
+func immutableError(c *fiber.Ctx, user *metadata.UserContext, errs []ErrorDetail) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if c.Get(OverrideImmutableHeader) == "true" {
+		if user != nil && user.IsAdmin() {
+			return nil
+		}
+		return ForbiddenError("Only admins can override immutable fields")
+	}
+	return ValidationError(errs)
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestUpdate_RejectsChangesToImmutableFields(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	order := &metadata.Entity{
+		Name:       "order",
+		Table:      "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "external_ref", Type: "string", Immutable: true},
+			{Name: "note", Type: "string"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, order); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{order}, nil)
+	var perms []*metadata.Permission
+	for _, action := range []string{"read", "create", "update"} {
+		perms = append(perms, &metadata.Permission{Entity: "order", Action: action, Roles: []string{"clerk"}})
+	}
+	reg.LoadPermissions(perms)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{c.Get("X-Test-Role")}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	do := func(method, path, role string, override bool, body any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Role", role)
+		if override {
+			req.Header.Set(OverrideImmutableHeader, "true")
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ = io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := do("POST", "/api/order", "clerk", false, map[string]any{"external_ref": "EXT-1", "note": "a"})
+	if status != 201 {
+		t.Fatalf("expected immutable field to be settable on create, got %d %v", status, out)
+	}
+	id := out["data"].(map[string]any)["id"].(string)
+
+	status, out = do("PUT", "/api/order/"+id, "clerk", false, map[string]any{"external_ref": "EXT-2", "note": "b"})
+	if status != 422 {
+		t.Fatalf("expected 422 when changing an immutable field, got %d %v", status, out)
+	}
+	details := out["error"].(map[string]any)["details"].([]any)
+	if d := details[0].(map[string]any); d["field"] != "external_ref" || d["code"] != "IMMUTABLE" {
+		t.Fatalf("expected an IMMUTABLE detail naming external_ref, got %v", d)
+	}
+
+	// Omitting the field, or resending its current value, lets the rest through
+	if status, out := do("PUT", "/api/order/"+id, "clerk", false, map[string]any{"note": "b"}); status != 200 {
+		t.Fatalf("expected update without the immutable field to succeed, got %d %v", status, out)
+	}
+	if status, out := do("PUT", "/api/order/"+id, "clerk", false, map[string]any{"external_ref": "EXT-1", "note": "c"}); status != 200 {
+		t.Fatalf("expected resending the current value to succeed, got %d %v", status, out)
+	}
+
+	// The override is explicit and admin-only
+	if status, out := do("PUT", "/api/order/"+id, "clerk", true, map[string]any{"external_ref": "EXT-2"}); status != 403 {
+		t.Fatalf("expected a non-admin override to be forbidden, got %d %v", status, out)
+	}
+	if status, out := do("PUT", "/api/order/"+id, "admin", false, map[string]any{"external_ref": "EXT-2"}); status != 422 {
+		t.Fatalf("expected an admin without the override to be rejected, got %d %v", status, out)
+	}
+	if status, out := do("PUT", "/api/order/"+id, "admin", true, map[string]any{"external_ref": "EXT-2"}); status != 200 {
+		t.Fatalf("expected an admin override to be allowed, got %d %v", status, out)
+	}
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT external_ref, note FROM orders WHERE id = ?1", id)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if row["external_ref"] != "EXT-2" || row["note"] != "c" {
+		t.Fatalf("unexpected row %v", row)
+	}
+}
+
+func TestUpdate_RejectsImmutableChangesOnNestedChildren(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	order := &metadata.Entity{
+		Name:       "order",
+		Table:      "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "number", Type: "string"}},
+	}
+	line := &metadata.Entity{
+		Name:       "line",
+		Table:      "order_lines",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "order_id", Type: "uuid"},
+			{Name: "sku", Type: "string", Immutable: true},
+			{Name: "qty", Type: "int"},
+		},
+	}
+	m := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{order, line} {
+		if err := m.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{order, line}, []*metadata.Relation{{
+		Name: "lines", Type: "one_to_many", Source: "order", Target: "line",
+		SourceKey: "id", TargetKey: "order_id", Ownership: "source", OnDelete: "cascade",
+	}})
+	h := NewHandler(s, reg)
+
+	app := newTestApp(t, nil)
+	app.Use(testUserFromHeaders)
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	do := func(method, path string, override bool, body any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Role", "admin")
+		if override {
+			req.Header.Set(OverrideImmutableHeader, "true")
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ = io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := do("POST", "/api/order", false, map[string]any{
+		"number": "A-1",
+		"lines":  map[string]any{"data": []any{map[string]any{"sku": "S-1", "qty": 1}}},
+	})
+	if status != 201 {
+		t.Fatalf("create order: %d %v", status, out)
+	}
+	orderID := out["data"].(map[string]any)["id"].(string)
+	row, err := store.QueryRow(ctx, s.DB, "SELECT id FROM order_lines WHERE order_id = ?1", orderID)
+	if err != nil {
+		t.Fatalf("find line: %v", err)
+	}
+	lineID := row["id"].(string)
+	lines := func(child map[string]any) map[string]any {
+		return map[string]any{"lines": map[string]any{"data": []any{child}}}
+	}
+
+	status, out = do("PUT", "/api/order/"+orderID, false, lines(map[string]any{"id": lineID, "sku": "S-2"}))
+	if status != 422 {
+		t.Fatalf("expected 422 when a nested update changes an immutable field, got %d %v", status, out)
+	}
+	details := out["error"].(map[string]any)["details"].([]any)
+	if d := details[0].(map[string]any); d["field"] != "lines.sku" || d["code"] != "IMMUTABLE" {
+		t.Fatalf("expected an IMMUTABLE detail naming lines.sku, got %v", d)
+	}
+
+	// Resending the current value, or changing other fields, goes through
+	if status, out := do("PUT", "/api/order/"+orderID, false, lines(map[string]any{"id": lineID, "sku": "S-1", "qty": 2})); status != 200 {
+		t.Fatalf("expected the nested update to succeed, got %d %v", status, out)
+	}
+	if status, out := do("PUT", "/api/order/"+orderID, true, lines(map[string]any{"id": lineID, "sku": "S-2"})); status != 200 {
+		t.Fatalf("expected an admin override to be allowed, got %d %v", status, out)
+	}
+
+	row, err = store.QueryRow(ctx, s.DB, "SELECT sku, qty FROM order_lines WHERE id = ?1", lineID)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if row["sku"] != "S-2" || row["qty"] != int64(2) {
+		t.Fatalf("unexpected line %v", row)
+	}
+}
@@ -9,26 +9,137 @@ import (
 	"rocket-backend/internal/store"
 )
 
+// MaxIncludeDepth caps the segments in a nested include path
+// (include=order.customer.account is 3). 0 disables the check.
+var MaxIncludeDepth = 3
+
+// MaxIncludeRecords caps the related records one request may load across all
+// of its includes. 0 disables the check.
+var MaxIncludeRecords = 5000
+
+// includeNode is one segment of a nested include; paths sharing a prefix
+// (items.product, items.warehouse) share the node so it is loaded once.
+type includeNode struct {
+	name     string
+	children []*includeNode
+}
+
+func buildIncludeTree(includes []string) []*includeNode {
+	var roots []*includeNode
+	for _, path := range includes {
+		level := &roots
+		for _, seg := range strings.Split(path, ".") {
+			var node *includeNode
+			for _, n := range *level {
+				if n.name == seg {
+					node = n
+					break
+				}
+			}
+			if node == nil {
+				node = &includeNode{name: seg}
+				*level = append(*level, node)
+			}
+			level = &node.children
+		}
+	}
+	return roots
+}
+
+// validateIncludes checks each include path against the relation graph and
+// MaxIncludeDepth.
+func validateIncludes(reg *metadata.Registry, entity *metadata.Entity, includes []string) error {
+	for _, path := range includes {
+		segs := strings.Split(path, ".")
+		if MaxIncludeDepth > 0 && len(segs) > MaxIncludeDepth {
+			return NewAppError("INCLUDE_LIMIT_EXCEEDED", 422,
+				fmt.Sprintf("Include %s is %d levels deep; the maximum is %d", path, len(segs), MaxIncludeDepth))
+		}
+		current := entity.Name
+		for _, seg := range segs {
+			rel := reg.FindRelationForEntity(seg, current)
+			if rel == nil {
+				return &AppError{
+					Code:    "UNKNOWN_FIELD",
+					Status:  400,
+					Message: fmt.Sprintf("Unknown include: %s", path),
+				}
+			}
+			if rel.Source == current {
+				current = rel.Target
+			} else {
+				current = rel.Source
+			}
+		}
+	}
+	return nil
+}
+
 // LoadIncludes fetches related data and attaches it to the parent rows.
+// Dotted paths load nested relations on the related rows.
 func LoadIncludes(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, rows []map[string]any, includes []string) error {
 	if len(rows) == 0 || len(includes) == 0 {
 		return nil
 	}
+	loaded := 0
+	return loadIncludeLevel(ctx, q, dialect, reg, entity, rows, buildIncludeTree(includes), nil, &loaded)
+}
 
-	for _, incName := range includes {
-		rel := reg.FindRelationForEntity(incName, entity.Name)
+// includeStep records a relation traversed on the current include path and
+// whether it was followed from source to target.
+type includeStep struct {
+	relation string
+	forward  bool
+}
+
+func loadIncludeLevel(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, rows []map[string]any, nodes []*includeNode, path []includeStep, loaded *int) error {
+	for _, node := range nodes {
+		rel := reg.FindRelationForEntity(node.name, entity.Name)
 		if rel == nil {
 			continue
 		}
+		forward := rel.Source == entity.Name
 
-		if rel.Source == entity.Name {
-			// Forward relation: load children by parent PK
-			if err := loadForwardRelation(ctx, q, dialect, reg, entity, rel, rows, incName); err != nil {
-				return err
+		// Following a relation back the way the path came (post.comments.post)
+		// would only reload the records above it, so the cycle is cut here.
+		// Repeating it in the same direction (employee.manager.manager) walks
+		// further along a hierarchy and is bounded by MaxIncludeDepth.
+		cycle := false
+		for _, st := range path {
+			if st.relation == rel.Name && st.forward != forward {
+				cycle = true
+				break
 			}
-		} else if rel.Target == entity.Name {
+		}
+		if cycle {
+			continue
+		}
+
+		var related []map[string]any
+		var relatedEntity *metadata.Entity
+		var err error
+		if forward {
+			// Forward relation: load children by parent PK
+			related, err = loadForwardRelation(ctx, q, dialect, reg, entity, rel, rows, node.name)
+			relatedEntity = reg.GetEntity(rel.Target)
+		} else {
 			// Reverse relation: load parents by FK on current entity
-			if err := loadReverseRelation(ctx, q, dialect, reg, entity, rel, rows, incName); err != nil {
+			related, err = loadReverseRelation(ctx, q, dialect, reg, entity, rel, rows, node.name)
+			relatedEntity = reg.GetEntity(rel.Source)
+		}
+		if err != nil {
+			return err
+		}
+
+		*loaded += len(related)
+		if MaxIncludeRecords > 0 && *loaded > MaxIncludeRecords {
+			return NewAppError("INCLUDE_LIMIT_EXCEEDED", 422,
+				fmt.Sprintf("Includes would load more than %d related records; request fewer includes or a smaller page", MaxIncludeRecords))
+		}
+
+		if len(node.children) > 0 && relatedEntity != nil {
+			next := append(path[:len(path):len(path)], includeStep{relation: rel.Name, forward: forward})
+			if err := loadIncludeLevel(ctx, q, dialect, reg, relatedEntity, related, node.children, next, loaded); err != nil {
 				return err
 			}
 		}
@@ -38,11 +149,11 @@ func LoadIncludes(ctx context.Context, q store.Querier, dialect store.Dialect, r
 }
 
 // loadForwardRelation loads children for one_to_many, one_to_one, or many_to_many.
-func loadForwardRelation(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, parentEntity *metadata.Entity, rel *metadata.Relation, rows []map[string]any, incName string) error {
+func loadForwardRelation(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, parentEntity *metadata.Entity, rel *metadata.Relation, rows []map[string]any, incName string) ([]map[string]any, error) {
 	parentPKField := parentEntity.PrimaryKey.Field
 	parentIDs := collectValues(rows, parentPKField)
 	if len(parentIDs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	if rel.IsManyToMany() {
@@ -51,7 +162,7 @@ func loadForwardRelation(ctx context.Context, q store.Querier, dialect store.Dia
 
 	targetEntity := reg.GetEntity(rel.Target)
 	if targetEntity == nil {
-		return fmt.Errorf("unknown target entity: %s", rel.Target)
+		return nil, fmt.Errorf("unknown target entity: %s", rel.Target)
 	}
 
 	// Query children
@@ -66,8 +177,9 @@ func loadForwardRelation(ctx context.Context, q store.Querier, dialect store.Dia
 
 	childRows, err := store.QueryRows(ctx, q, sql, pb.Params()...)
 	if err != nil {
-		return fmt.Errorf("load include %s: %w", incName, err)
+		return nil, fmt.Errorf("load include %s: %w", incName, err)
 	}
+	decodeStoredFields(targetEntity, childRows...)
 
 	// Group by FK
 	grouped := make(map[string][]map[string]any)
@@ -90,13 +202,13 @@ func loadForwardRelation(ctx context.Context, q store.Querier, dialect store.Dia
 		}
 	}
 
-	return nil
+	return childRows, nil
 }
 
-func loadManyToMany(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, rel *metadata.Relation, rows []map[string]any, parentPKField string, parentIDs []any, incName string) error {
+func loadManyToMany(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, rel *metadata.Relation, rows []map[string]any, parentPKField string, parentIDs []any, incName string) ([]map[string]any, error) {
 	targetEntity := reg.GetEntity(rel.Target)
 	if targetEntity == nil {
-		return fmt.Errorf("unknown target entity: %s", rel.Target)
+		return nil, fmt.Errorf("unknown target entity: %s", rel.Target)
 	}
 
 	// Query join table
@@ -106,14 +218,14 @@ func loadManyToMany(ctx context.Context, q store.Querier, dialect store.Dialect,
 		rel.SourceJoinKey, rel.TargetJoinKey, rel.JoinTable, inExpr)
 	joinRows, err := store.QueryRows(ctx, q, joinSQL, pb.Params()...)
 	if err != nil {
-		return fmt.Errorf("load join table %s: %w", rel.JoinTable, err)
+		return nil, fmt.Errorf("load join table %s: %w", rel.JoinTable, err)
 	}
 
 	if len(joinRows) == 0 {
 		for _, row := range rows {
 			row[incName] = []map[string]any{}
 		}
-		return nil
+		return nil, nil
 	}
 
 	// Collect target IDs
@@ -138,8 +250,9 @@ func loadManyToMany(ctx context.Context, q store.Querier, dialect store.Dialect,
 	}
 	targetRows, err := store.QueryRows(ctx, q, targetSQL, pb2.Params()...)
 	if err != nil {
-		return fmt.Errorf("load targets for %s: %w", incName, err)
+		return nil, fmt.Errorf("load targets for %s: %w", incName, err)
 	}
+	decodeStoredFields(targetEntity, targetRows...)
 
 	// Index targets by PK
 	targetByPK := make(map[string]map[string]any, len(targetRows))
@@ -168,20 +281,20 @@ func loadManyToMany(ctx context.Context, q store.Querier, dialect store.Dialect,
 		}
 	}
 
-	return nil
+	return targetRows, nil
 }
 
 // loadReverseRelation loads parent records referenced by FK on the current entity.
-func loadReverseRelation(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, rel *metadata.Relation, rows []map[string]any, incName string) error {
+func loadReverseRelation(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, rel *metadata.Relation, rows []map[string]any, incName string) ([]map[string]any, error) {
 	sourceEntity := reg.GetEntity(rel.Source)
 	if sourceEntity == nil {
-		return fmt.Errorf("unknown source entity: %s", rel.Source)
+		return nil, fmt.Errorf("unknown source entity: %s", rel.Source)
 	}
 
 	// Collect FK values from current rows
 	fkValues := collectValues(rows, rel.TargetKey)
 	if len(fkValues) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	columns := strings.Join(sourceEntity.FieldNames(), ", ")
@@ -195,8 +308,9 @@ func loadReverseRelation(ctx context.Context, q store.Querier, dialect store.Dia
 
 	parentRows, err := store.QueryRows(ctx, q, sql, pb.Params()...)
 	if err != nil {
-		return fmt.Errorf("load reverse include %s: %w", incName, err)
+		return nil, fmt.Errorf("load reverse include %s: %w", incName, err)
 	}
+	decodeStoredFields(sourceEntity, parentRows...)
 
 	// Index by PK
 	parentByPK := make(map[string]map[string]any, len(parentRows))
@@ -211,7 +325,7 @@ func loadReverseRelation(ctx context.Context, q store.Querier, dialect store.Dia
 		row[incName] = parentByPK[fk]
 	}
 
-	return nil
+	return parentRows, nil
 }
 
 func collectValues(rows []map[string]any, field string) []any {
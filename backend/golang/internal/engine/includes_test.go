@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestList_NestedIncludeLimits(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := func(name, table string, fields ...string) *metadata.Entity {
+		e := &metadata.Entity{
+			Name:       name,
+			Table:      table,
+			PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+			Fields:     []metadata.Field{{Name: "id", Type: "string"}, {Name: "name", Type: "string"}},
+		}
+		for _, f := range fields {
+			e.Fields = append(e.Fields, metadata.Field{Name: f, Type: "string"})
+		}
+		return e
+	}
+	entities := []*metadata.Entity{
+		entity("account", "accounts"),
+		entity("customer", "customers", "account_id"),
+		entity("order", "orders", "customer_id"),
+	}
+	for _, e := range entities {
+		if err := store.NewMigrator(s).Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	for _, stmt := range []string{
+		"INSERT INTO accounts (id, name) VALUES ('a1', 'Acme')",
+		"INSERT INTO customers (id, name, account_id) VALUES ('c1', 'Jo', 'a1')",
+		"INSERT INTO orders (id, name, customer_id) VALUES ('o1', 'first', 'c1'), ('o2', 'second', 'c1')",
+	} {
+		if _, err := store.Exec(ctx, s.DB, stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	reg := metadata.NewRegistry()
+	reg.Load(entities, []*metadata.Relation{
+		{Name: "customers", Type: "one_to_many", Source: "account", Target: "customer", SourceKey: "id", TargetKey: "account_id"},
+		{Name: "orders", Type: "one_to_many", Source: "customer", Target: "order", SourceKey: "id", TargetKey: "customer_id"},
+	})
+	h := NewHandler(s, reg)
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+
+	defer func(depth, records int) { MaxIncludeDepth, MaxIncludeRecords = depth, records }(MaxIncludeDepth, MaxIncludeRecords)
+	MaxIncludeDepth, MaxIncludeRecords = 2, 100
+
+	get := func(include string) (int, map[string]any) {
+		req, _ := http.NewRequest("GET", "/api/order?sort=id&include="+include, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := get("customer.account")
+	if status != 200 {
+		t.Fatalf("expected a within-limit include to succeed, got %d: %v", status, out)
+	}
+	first := out["data"].([]any)[0].(map[string]any)
+	customer := first["customer"].(map[string]any)
+	if account := customer["account"].(map[string]any); account["name"] != "Acme" {
+		t.Fatalf("expected the nested account to be loaded, got %v", customer)
+	}
+
+	status, out = get("customer.account.customers")
+	if status != 422 {
+		t.Fatalf("expected an over-deep include to be rejected with 422, got %d", status)
+	}
+	if code := out["error"].(map[string]any)["code"]; code != "INCLUDE_LIMIT_EXCEEDED" {
+		t.Fatalf("unexpected error code %v", code)
+	}
+
+	// order -> customer -> orders walks the same relation back, so it is cut
+	status, out = get("customer.orders")
+	if status != 200 {
+		t.Fatalf("expected the cyclic include to succeed, got %d", status)
+	}
+	customer = out["data"].([]any)[0].(map[string]any)["customer"].(map[string]any)
+	if _, ok := customer["orders"]; ok {
+		t.Fatalf("expected the relation cycle to be broken, got %v", customer["orders"])
+	}
+
+	// One shared customer plus its account is two related records
+	MaxIncludeRecords = 1
+	if status, _ := get("customer.account"); status != 422 {
+		t.Fatalf("expected the record cap to reject the request, got %d", status)
+	}
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestJSONFieldSchema_ValidatesAndFillsDefaults(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	var schema metadata.JSONSchema
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["zip"],
+		"additionalProperties": false,
+		"properties": {
+			"zip":     {"type": "string", "pattern": "^[0-9]{5}$"},
+			"country": {"type": "string", "enum": ["US", "CA"], "default": "US"},
+			"floor":   {"type": "integer", "minimum": 0}
+		}
+	}`), &schema); err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+	entity := &metadata.Entity{
+		Name:       "site",
+		Table:      "sites",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "address", Type: "json", Schema: &schema},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+
+	post := func(body map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/site", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(data, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := post(map[string]any{"address": map[string]any{"zip": "ABCDE", "floor": 1.5, "unit": "4B"}})
+	if status != 422 || out["error"].(map[string]any)["code"] != "VALIDATION_FAILED" {
+		t.Fatalf("expected 422 VALIDATION_FAILED, got %d %v", status, out)
+	}
+	got := map[string]string{}
+	for _, d := range out["error"].(map[string]any)["details"].([]any) {
+		detail := d.(map[string]any)
+		got[detail["field"].(string)] = detail["rule"].(string)
+	}
+	want := map[string]string{"address.zip": "pattern", "address.floor": "type", "address.unit": "additionalProperties"}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Fatalf("expected %s to fail %s, got details %v", field, rule, got)
+		}
+	}
+
+	if status, _ := post(map[string]any{"address": map[string]any{"country": "US"}}); status != 422 {
+		t.Fatalf("expected a missing required key to be rejected, got %d", status)
+	}
+	if status, _ := post(map[string]any{"address": "10001"}); status != 422 {
+		t.Fatalf("expected a non-object to be rejected, got %d", status)
+	}
+
+	status, out = post(map[string]any{"address": map[string]any{"zip": "10001", "floor": 3}})
+	if status != 201 {
+		t.Fatalf("expected a conforming value to be accepted, got %d %v", status, out)
+	}
+	address := out["data"].(map[string]any)["address"]
+	if text, ok := address.(string); ok {
+		// SQLite hands json columns back as text
+		_ = json.Unmarshal([]byte(text), &address)
+	}
+	if m, ok := address.(map[string]any); !ok || m["country"] != "US" {
+		t.Fatalf("expected the schema default to be filled in, got %v", address)
+	}
+}
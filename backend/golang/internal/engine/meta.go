@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/metadata"
+)
+
+var metaActions = []string{"read", "create", "update", "delete"}
+
+type metaEntity struct {
+	Name         string          `json:"name"`
+	PrimaryKey   string          `json:"primary_key"`
+	SoftDelete   bool            `json:"soft_delete"`
+	Actions      []string        `json:"actions"`
+	Conditional  []string        `json:"conditional_actions,omitempty"` // granted only under conditions; some records may be refused
+	Fields       []metaField     `json:"fields"`
+	Relations    []metaRelation  `json:"relations"`
+	Capabilities map[string]bool `json:"capabilities"`
+}
+
+type metaField struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Items     string   `json:"items,omitempty"` // element type of array fields
+	Required  bool     `json:"required,omitempty"`
+	Enum      []string `json:"enum,omitempty"`
+	Label     string   `json:"label,omitempty"`
+	HelpText  string   `json:"help_text,omitempty"`
+	Immutable bool     `json:"immutable,omitempty"`
+}
+
+type metaRelation struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+}
+
+// Meta handles GET /api/_meta: the entities the caller may use, with the
+// actions their roles allow, the relations they can include, and capability
+// flags. Entities the caller cannot act on at all are omitted.
+func (h *Handler) Meta(c *fiber.Ctx) error {
+	user := getUser(c)
+	if user == nil {
+		return UnauthorizedError("Authentication required")
+	}
+
+	entities := h.registry.AllEntities()
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Name < entities[j].Name })
+
+	visible := make(map[string]bool, len(entities))
+	out := make([]metaEntity, 0, len(entities))
+	for _, e := range entities {
+		actions, conditional := permittedActions(user, e.Name, h.registry)
+		if len(actions) == 0 {
+			continue
+		}
+		visible[e.Name] = true
+
+		fields := make([]metaField, 0, len(e.Fields))
+		for _, f := range e.Fields {
+			mf := metaField{Name: f.Name, Type: f.Type, Required: f.Required, Enum: f.Enum, Label: f.Label, HelpText: f.HelpText, Immutable: f.Immutable}
+			if f.Type == "array" {
+				mf.Items = f.ItemType()
+			}
+			fields = append(fields, mf)
+		}
+		out = append(out, metaEntity{
+			Name:        e.Name,
+			PrimaryKey:  e.PrimaryKey.Field,
+			SoftDelete:  e.SoftDelete,
+			Actions:     actions,
+			Conditional: conditional,
+			Fields:      fields,
+			Capabilities: map[string]bool{
+				"slug":          e.Slug != nil,
+				"cacheable":     e.Cacheable,
+				"state_machine": len(h.registry.GetStateMachinesForEntity(e.Name)) > 0,
+				"file_fields":   hasFileField(e),
+				"readonly":      e.ReadOnly,
+				"append_only":   e.AppendOnly,
+			},
+		})
+	}
+
+	// Relations are listed from both ends, but only when the far side is visible
+	for i := range out {
+		out[i].Relations = []metaRelation{}
+		for _, rel := range h.registry.AllRelations() {
+			var target string
+			switch out[i].Name {
+			case rel.Source:
+				target = rel.Target
+			case rel.Target:
+				target = rel.Source
+			default:
+				continue
+			}
+			if visible[target] {
+				out[i].Relations = append(out[i].Relations, metaRelation{Name: rel.Name, Type: rel.Type, Target: target})
+			}
+		}
+		sort.Slice(out[i].Relations, func(a, b int) bool { return out[i].Relations[a].Name < out[i].Relations[b].Name })
+	}
+
+	return c.JSON(fiber.Map{"data": fiber.Map{
+		"entities": out,
+		"capabilities": fiber.Map{
+			"inline_files":     h.fileStorage != nil,
+			"read_your_writes": h.store.ReadYourWrites() > 0,
+		},
+	}})
+}
+
+// EffectiveAction is what a user may do for one action on an entity.
+type EffectiveAction struct {
+	Allowed     bool `json:"allowed"`
+	Conditional bool `json:"conditional,omitempty"` // some records may be refused
+	// Grants lists the condition sets of the user's conditional grants; a record
+	// matching any one is allowed. Empty when some grant is unconditional.
+	Grants [][]metadata.PermissionCondition `json:"grants,omitempty"`
+	// Denies lists the condition sets of deny policies; matching records are
+	// refused even when granted.
+	Denies [][]metadata.PermissionCondition `json:"denies,omitempty"`
+}
+
+// effectiveActions resolves the user's policies for every action on an entity,
+// mirroring CheckPermission without a record: an unconditional deny removes the
+// action, conditional grants and denies are reported with their conditions.
+// Writes the entity's readonly or append_only mode forbids are removed for
+// everyone, admins included, since they need the override header.
+func effectiveActions(user *metadata.UserContext, entity string, reg *metadata.Registry) map[string]EffectiveAction {
+	out := make(map[string]EffectiveAction, len(metaActions))
+	e := reg.GetEntity(entity)
+	for _, action := range metaActions {
+		if e != nil && e.WriteBlocked(action) {
+			out[action] = EffectiveAction{}
+			continue
+		}
+		if user.IsAdmin() {
+			out[action] = EffectiveAction{Allowed: true}
+			continue
+		}
+		var ea EffectiveAction
+		unconditional, denied := false, false
+		for _, p := range reg.GetPermissions(entity, action) {
+			if !hasRoleIntersection(user.Roles, p.Roles) {
+				continue
+			}
+			switch {
+			case p.IsDeny() && len(p.Conditions) == 0:
+				denied = true
+			case p.IsDeny():
+				ea.Denies = append(ea.Denies, p.Conditions)
+			case len(p.Conditions) == 0:
+				ea.Allowed, unconditional = true, true
+			default:
+				ea.Allowed = true
+				ea.Grants = append(ea.Grants, p.Conditions)
+			}
+		}
+		if denied {
+			ea = EffectiveAction{}
+		} else if unconditional {
+			ea.Grants = nil
+		}
+		if !ea.Allowed {
+			ea.Denies = nil
+		}
+		ea.Conditional = len(ea.Grants) > 0 || len(ea.Denies) > 0
+		out[action] = ea
+	}
+	return out
+}
+
+// permittedActions lists the actions the user's roles are granted on an
+// entity, and which of those are conditional.
+func permittedActions(user *metadata.UserContext, entity string, reg *metadata.Registry) (actions, conditional []string) {
+	effective := effectiveActions(user, entity, reg)
+	actions = []string{}
+	for _, action := range metaActions {
+		ea := effective[action]
+		if !ea.Allowed {
+			continue
+		}
+		actions = append(actions, action)
+		if len(ea.Grants) > 0 {
+			conditional = append(conditional, action)
+		}
+	}
+	return actions, conditional
+}
+
+// EffectivePermissions handles GET /api/permissions/effective: for every
+// entity, what the caller may do per action and under which conditions.
+// Admins get full access; entities without any grant are listed as all-denied
+// so a client can tell "no access" from "unknown entity".
+func (h *Handler) EffectivePermissions(c *fiber.Ctx) error {
+	user := getUser(c)
+	if user == nil {
+		return UnauthorizedError("Authentication required")
+	}
+
+	entities := make(map[string]map[string]EffectiveAction)
+	for _, e := range h.registry.AllEntities() {
+		entities[e.Name] = effectiveActions(user, e.Name, h.registry)
+	}
+
+	return c.JSON(fiber.Map{"data": fiber.Map{
+		"user_id":  user.ID,
+		"roles":    user.Roles,
+		"admin":    user.IsAdmin(),
+		"entities": entities,
+	}})
+}
+
+func hasFileField(e *metadata.Entity) bool {
+	for _, f := range e.Fields {
+		if f.Type == "file" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestMeta_LimitedRoleSeesReducedEntityList(t *testing.T) {
+	reg := metadata.NewRegistry()
+	entity := func(name string) *metadata.Entity {
+		return &metadata.Entity{
+			Name:       name,
+			Table:      name + "s",
+			PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+			Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "owner_id", Type: "uuid"}},
+		}
+	}
+	reg.Load(
+		[]*metadata.Entity{entity("post"), entity("comment"), entity("invoice")},
+		[]*metadata.Relation{
+			{Name: "comments", Type: "one_to_many", Source: "post", Target: "comment", SourceKey: "id", TargetKey: "post_id"},
+			{Name: "post_invoices", Type: "one_to_many", Source: "post", Target: "invoice", SourceKey: "id", TargetKey: "post_id"},
+		},
+	)
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "post", Action: "read", Roles: []string{"reader"}},
+		{Entity: "post", Action: "update", Roles: []string{"reader"},
+			Conditions: []metadata.PermissionCondition{{Field: "owner_id", Operator: "eq", Value: "$user.id"}}},
+		{Entity: "comment", Action: "read", Roles: []string{"reader"}},
+		{Entity: "comment", Action: "create", Roles: []string{"reader"}},
+		{Entity: "comment", Action: "delete", Roles: []string{"reader"}},
+		{Entity: "comment", Action: "delete", Effect: "deny", Roles: []string{"reader"}},
+		{Entity: "invoice", Action: "read", Roles: []string{"billing"}},
+	})
+	s, err := store.New(context.Background(), config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	h := NewHandler(s, reg)
+
+	meta := func(user *metadata.UserContext) []any {
+		app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", user)
+			return c.Next()
+		})
+		app.Get("/api/_meta", h.Meta)
+		req, _ := http.NewRequest("GET", "/api/_meta", nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected 200, got %d: %s", resp.StatusCode, raw)
+		}
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return out["data"].(map[string]any)["entities"].([]any)
+	}
+	names := func(entities []any) []string {
+		var out []string
+		for _, e := range entities {
+			out = append(out, e.(map[string]any)["name"].(string))
+		}
+		return out
+	}
+
+	admin := meta(&metadata.UserContext{ID: "a1", Roles: []string{"admin"}})
+	if got := names(admin); !reflect.DeepEqual(got, []string{"comment", "invoice", "post"}) {
+		t.Fatalf("expected admin to see every entity, got %v", got)
+	}
+
+	limited := meta(&metadata.UserContext{ID: "u1", Roles: []string{"reader"}})
+	if got := names(limited); !reflect.DeepEqual(got, []string{"comment", "post"}) {
+		t.Fatalf("expected reader to see comment and post only, got %v", got)
+	}
+	comment := limited[0].(map[string]any)
+	if got := comment["actions"]; !reflect.DeepEqual(got, []any{"read", "create"}) {
+		t.Fatalf("expected the deny to remove delete from comment, got %v", got)
+	}
+	post := limited[1].(map[string]any)
+	if got := post["actions"]; !reflect.DeepEqual(got, []any{"read", "update"}) {
+		t.Fatalf("unexpected post actions: %v", got)
+	}
+	if got := post["conditional_actions"]; !reflect.DeepEqual(got, []any{"update"}) {
+		t.Fatalf("expected update to be conditional, got %v", got)
+	}
+	rels := post["relations"].([]any)
+	if len(rels) != 1 || rels[0].(map[string]any)["name"] != "comments" {
+		t.Fatalf("expected only the comments relation to be visible, got %v", rels)
+	}
+}
+
+func TestMeta_FieldsCarryLabelAndHelpText(t *testing.T) {
+	// Labels are read back from a stored entity definition
+	var entity metadata.Entity
+	if err := json.Unmarshal([]byte(`{
+		"name": "invoice", "table": "invoices",
+		"primary_key": {"field": "id", "type": "uuid", "generated": true},
+		"fields": [
+			{"name": "id", "type": "uuid"},
+			{"name": "due_on", "type": "date", "label": "Due date", "help_text": "Payment is late after this day"}
+		]}`), &entity); err != nil {
+		t.Fatalf("unmarshal entity: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{&entity}, nil)
+	s, err := store.New(context.Background(), config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "a1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/_meta", h.Meta)
+	req, _ := http.NewRequest("GET", "/api/_meta", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var out struct {
+		Data struct {
+			Entities []struct {
+				Fields []map[string]any `json:"fields"`
+			} `json:"entities"`
+		} `json:"data"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	if len(out.Data.Entities) != 1 {
+		t.Fatalf("expected one entity, got %+v", out.Data)
+	}
+	fields := out.Data.Entities[0].Fields
+	if fields[1]["label"] != "Due date" || fields[1]["help_text"] != "Payment is late after this day" {
+		t.Fatalf("expected due_on's label and help text, got %v", fields[1])
+	}
+	if _, ok := fields[0]["label"]; ok {
+		t.Fatalf("expected no label on an unlabeled field, got %v", fields[0])
+	}
+}
+
+func TestEffectivePermissions_ViewerVersusEditor(t *testing.T) {
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{
+		{Name: "doc", Table: "docs", PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+			Fields: []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "owner_id", Type: "uuid"}, {Name: "status", Type: "string"}}},
+		{Name: "audit", Table: "audits", PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+			Fields: []metadata.Field{{Name: "id", Type: "uuid"}}},
+		{Name: "ledger", Table: "ledgers", AppendOnly: true, PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+			Fields: []metadata.Field{{Name: "id", Type: "uuid"}}},
+	}, nil)
+	ownDoc := []metadata.PermissionCondition{{Field: "owner_id", Operator: "eq", Value: "$user.id"}}
+	published := []metadata.PermissionCondition{{Field: "status", Operator: "eq", Value: "published"}}
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "doc", Action: "read", Roles: []string{"viewer", "editor"}},
+		{Entity: "doc", Action: "create", Roles: []string{"editor"}},
+		{Entity: "doc", Action: "update", Roles: []string{"editor"}, Conditions: ownDoc},
+		{Entity: "doc", Action: "update", Effect: "deny", Roles: []string{"editor"}, Conditions: published},
+		{Entity: "doc", Action: "delete", Roles: []string{"editor"}},
+		{Entity: "doc", Action: "delete", Effect: "deny", Roles: []string{"editor"}},
+		{Entity: "ledger", Action: "read", Roles: []string{"editor"}},
+		{Entity: "ledger", Action: "create", Roles: []string{"editor"}},
+		{Entity: "ledger", Action: "update", Roles: []string{"editor"}},
+	})
+	s, err := store.New(context.Background(), config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	h := NewHandler(s, reg)
+
+	effective := func(roles ...string) map[string]any {
+		app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", &metadata.UserContext{ID: "u1", Roles: roles})
+			return c.Next()
+		})
+		RegisterDynamicRoutes(app, h)
+		req, _ := http.NewRequest("GET", "/api/permissions/effective", nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected 200, got %d: %s", resp.StatusCode, raw)
+		}
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return out["data"].(map[string]any)["entities"].(map[string]any)
+	}
+	allowed := func(entities map[string]any, entity string) []string {
+		var out []string
+		for _, action := range metaActions {
+			if entities[entity].(map[string]any)[action].(map[string]any)["allowed"] == true {
+				out = append(out, action)
+			}
+		}
+		return out
+	}
+
+	viewer, editor := effective("viewer"), effective("editor")
+	if got := allowed(viewer, "doc"); !reflect.DeepEqual(got, []string{"read"}) {
+		t.Fatalf("viewer doc actions: %v", got)
+	}
+	if got := allowed(editor, "doc"); !reflect.DeepEqual(got, []string{"read", "create", "update"}) {
+		t.Fatalf("expected the unconditional deny to remove delete for editors, got %v", got)
+	}
+	if got := allowed(viewer, "audit"); got != nil {
+		t.Fatalf("expected no audit access, got %v", got)
+	}
+
+	update := editor["doc"].(map[string]any)["update"].(map[string]any)
+	if update["conditional"] != true {
+		t.Fatalf("expected update to be conditional, got %v", update)
+	}
+	grants, _ := json.Marshal(update["grants"])
+	denies, _ := json.Marshal(update["denies"])
+	if string(grants) != `[[{"field":"owner_id","operator":"eq","value":"$user.id"}]]` ||
+		string(denies) != `[[{"field":"status","operator":"eq","value":"published"}]]` {
+		t.Fatalf("unexpected update conditions: grants=%s denies=%s", grants, denies)
+	}
+	if read := viewer["doc"].(map[string]any)["read"].(map[string]any); read["conditional"] != nil {
+		t.Fatalf("expected an unconditional read, got %v", read)
+	}
+
+	admin := effective("admin")
+	if got := allowed(admin, "audit"); len(got) != len(metaActions) {
+		t.Fatalf("expected admin to have full access, got %v", got)
+	}
+
+	// An append-only entity takes no updates or deletes, whatever the grants
+	if got := allowed(editor, "ledger"); !reflect.DeepEqual(got, []string{"read", "create"}) {
+		t.Fatalf("expected the write mode to remove update for editors, got %v", got)
+	}
+	if got := allowed(admin, "ledger"); !reflect.DeepEqual(got, []string{"read", "create"}) {
+		t.Fatalf("expected the write mode to apply to admins too, got %v", got)
+	}
+}
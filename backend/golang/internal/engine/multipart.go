@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/metadata"
+)
+
+// isMultipart reports whether the request carries a multipart/form-data body.
+func isMultipart(c *fiber.Ctx) bool {
+	return strings.HasPrefix(strings.ToLower(c.Get(fiber.HeaderContentType)), fiber.MIMEMultipartForm)
+}
+
+// parseMultipartBody converts a multipart/form-data request into a write body.
+// Plain form values are coerced to their field types; values for relations may be
+// sent as JSON strings. File parts must target file-type fields and are saved via the
+// configured FileStorage, with the field set to the new file id. The stored files are
+// returned so the caller can discard them if the write fails.
+func (h *Handler) parseMultipartBody(c *fiber.Ctx, entity *metadata.Entity) (map[string]any, []*storedFile, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, NewAppError("INVALID_PAYLOAD", 400, "Invalid multipart form data")
+	}
+
+	body := make(map[string]any, len(form.Value)+len(form.File))
+	for key, vals := range form.Value {
+		if len(vals) == 0 {
+			continue
+		}
+		body[key] = coerceFormValue(entity.GetField(key), vals[0])
+	}
+
+	if len(form.File) == 0 {
+		return body, nil, nil
+	}
+	if h.fileStorage == nil {
+		return nil, nil, NewAppError("INVALID_PAYLOAD", 400, "File storage is not configured")
+	}
+
+	// Validate every part before anything is written to storage
+	var errs []ErrorDetail
+	for key, files := range form.File {
+		f := entity.GetField(key)
+		if f == nil || f.Type != "file" {
+			errs = append(errs, ErrorDetail{Field: key, Rule: "type", Message: fmt.Sprintf("%s is not a file field", key)})
+			continue
+		}
+		if len(files) != 1 {
+			errs = append(errs, ErrorDetail{Field: key, Rule: "type", Message: fmt.Sprintf("%s accepts a single file", key)})
+			continue
+		}
+		errs = append(errs, h.validateUpload(f, files[0])...)
+	}
+	if len(errs) > 0 {
+		return nil, nil, ValidationError(errs)
+	}
+
+	var uploadedBy *string
+	if user := getUser(c); user != nil {
+		uploadedBy = &user.ID
+	}
+
+	var stored []*storedFile
+	for key, files := range form.File {
+		saved, err := saveUploadedFile(c.Context(), h.store, h.fileStorage, h.appName, files[0], uploadedBy)
+		if err != nil {
+			h.discardFiles(c.Context(), stored)
+			return nil, nil, err
+		}
+		stored = append(stored, saved)
+		body[key] = saved.ID
+	}
+	return body, stored, nil
+}
+
+// validateUpload checks a file part against the field's size and MIME constraints.
+func (h *Handler) validateUpload(f *metadata.Field, file *multipart.FileHeader) []ErrorDetail {
+	var errs []ErrorDetail
+
+	maxSize := h.maxFileSize
+	if f.File != nil && f.File.MaxSize > 0 && (maxSize <= 0 || f.File.MaxSize < maxSize) {
+		maxSize = f.File.MaxSize
+	}
+	if maxSize > 0 && file.Size > maxSize {
+		errs = append(errs, ErrorDetail{
+			Field:   f.Name,
+			Rule:    "max_size",
+			Message: fmt.Sprintf("%s is too large: %d bytes (max %d)", f.Name, file.Size, maxSize),
+		})
+	}
+
+	if mimeType := uploadMimeType(file); !f.File.AllowsMimeType(mimeType) {
+		errs = append(errs, ErrorDetail{
+			Field:   f.Name,
+			Rule:    "mime_type",
+			Message: fmt.Sprintf("%s must be one of: %s (got %s)", f.Name, strings.Join(f.File.AllowedTypes, ", "), mimeType),
+		})
+	}
+	return errs
+}
+
+func (h *Handler) discardFiles(ctx context.Context, files []*storedFile) {
+	for _, f := range files {
+		discardStoredFile(ctx, h.store, h.fileStorage, f)
+	}
+}
+
+// coerceFormValue converts a form string into the Go type expected by the field.
+// Values that cannot be converted are passed through so validation reports them.
+func coerceFormValue(f *metadata.Field, raw string) any {
+	if f == nil {
+		// Relation writes arrive as JSON objects
+		trimmed := strings.TrimSpace(raw)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var v any
+			if err := json.Unmarshal([]byte(trimmed), &v); err == nil {
+				return v
+			}
+		}
+		return raw
+	}
+
+	if raw == "" && f.Type != "string" && f.Type != "text" {
+		return nil
+	}
+	switch f.Type {
+	case "int", "integer", "bigint":
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n
+		}
+	case "float", "decimal":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case "json", "array", "geopoint":
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err == nil {
+			return v
+		}
+	}
+	return raw
+}
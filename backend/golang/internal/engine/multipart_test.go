@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/storage"
+	"rocket-backend/internal/store"
+)
+
+func TestCreate_MultipartInlineFile(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "document",
+		Table:      "documents",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string", Required: true},
+			{Name: "pages", Type: "int"},
+			{Name: "attachment", Type: "file", File: &metadata.FileConfig{MaxSize: 1024, AllowedTypes: []string{"text/*"}}},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	h := NewHandler(s, reg)
+	h.SetFileStorage(storage.NewLocalStorage(t.TempDir()), 1<<20, "test")
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+
+	post := func(contentType string, content []byte) (int, map[string]any) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		_ = w.WriteField("title", "Spec")
+		_ = w.WriteField("pages", "3")
+		hdr := textproto.MIMEHeader{}
+		hdr.Set("Content-Disposition", `form-data; name="attachment"; filename="spec.txt"`)
+		hdr.Set("Content-Type", contentType)
+		part, _ := w.CreatePart(hdr)
+		_, _ = part.Write(content)
+		_ = w.Close()
+
+		req, _ := http.NewRequest("POST", "/api/document", &buf)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := post("text/plain", []byte("hello"))
+	if status != 201 {
+		t.Fatalf("expected 201, got %d: %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+	if data["pages"] != float64(3) {
+		t.Fatalf("expected pages to be coerced to 3, got %v", data["pages"])
+	}
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT id, filename FROM _files")
+	if err != nil || row["filename"] != "spec.txt" {
+		t.Fatalf("expected a _files row for spec.txt, got %v (%v)", row, err)
+	}
+	doc, err := store.QueryRow(ctx, s.DB, "SELECT attachment FROM documents")
+	if err != nil {
+		t.Fatalf("fetch document: %v", err)
+	}
+	var attachment map[string]any
+	if err := json.Unmarshal([]byte(doc["attachment"].(string)), &attachment); err != nil || attachment["id"] != row["id"] {
+		t.Fatalf("expected attachment to reference file %v, got %v", row["id"], doc["attachment"])
+	}
+
+	status, out = post("image/png", []byte("png"))
+	if status != 422 {
+		t.Fatalf("expected 422 for disallowed MIME type, got %d: %v", status, out)
+	}
+	row, _ = store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM _files")
+	if row["n"] != int64(1) {
+		t.Fatalf("rejected upload must not be stored, got %v files", row["n"])
+	}
+}
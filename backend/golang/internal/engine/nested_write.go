@@ -2,9 +2,12 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
 
 	"rocket-backend/internal/instrument"
@@ -16,22 +19,51 @@ import (
 
 var uuidRE = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
+// StrictFields makes writes reject keys that are neither a field nor a writable
+// relation with a 422. When false (the default) they are dropped. Entities can
+// override it with strict_fields.
+var StrictFields = false
+
+func strictFields(entity *metadata.Entity) bool {
+	if entity.StrictFields != nil {
+		return *entity.StrictFields
+	}
+	return StrictFields
+}
+
 // WritePlan describes the full set of operations for a write request.
 type WritePlan struct {
-	IsCreate  bool
-	Entity    *metadata.Entity
-	Fields    map[string]any
-	ID        any // nil for create, set for update
-	ChildOps  []*RelationWrite
-	User      *metadata.UserContext
+	IsCreate bool
+	Entity   *metadata.Entity
+	Fields   map[string]any
+	ID       any // nil for create, set for update
+	ChildOps []*RelationWrite
+	User     *metadata.UserContext
+	Hooks    []Hook // Go hooks for the entity, set by the handler
+	DryRun   bool   // run the whole pipeline, then roll back instead of committing
+	Bypass   Bypass // side effects an admin asked to skip, set by the handler
+	Partial  bool   // PATCH: rules and state machines see the stored record with Fields applied
+
+	// FieldErrors are the field constraint violations found while planning.
+	// They are reported with the rule and state machine ones, in one 422.
+	FieldErrors []ErrorDetail
+}
+
+func (p *WritePlan) action() string {
+	if p.IsCreate {
+		return "create"
+	}
+	return "update"
 }
 
 // PlanWrite builds a WritePlan from the request body without executing any SQL.
+// Only unknown keys in strict mode fail here; field constraint violations are
+// kept on the plan and reported when it is executed.
 func PlanWrite(entity *metadata.Entity, reg *metadata.Registry, body map[string]any, existingID any) (*WritePlan, []ErrorDetail) {
 	fields, relWrites, unknownKeys := SeparateFieldsAndRelations(entity, reg, body)
 
-	// Reject unknown keys
-	if len(unknownKeys) > 0 {
+	// Reject unknown keys, or drop them in lenient mode
+	if len(unknownKeys) > 0 && strictFields(entity) {
 		var errs []ErrorDetail
 		for _, key := range unknownKeys {
 			errs = append(errs, ErrorDetail{
@@ -45,17 +77,14 @@ func PlanWrite(entity *metadata.Entity, reg *metadata.Registry, body map[string]
 
 	isCreate := existingID == nil
 
-	// Validate fields
-	validationErrs := ValidateFields(entity, fields, isCreate)
-	if len(validationErrs) > 0 {
-		return nil, validationErrs
-	}
+	ApplyFieldTransforms(entity, fields)
 
 	plan := &WritePlan{
-		IsCreate: isCreate,
-		Entity:   entity,
-		Fields:   fields,
-		ID:       existingID,
+		IsCreate:    isCreate,
+		Entity:      entity,
+		Fields:      fields,
+		ID:          existingID,
+		FieldErrors: ValidateFields(entity, fields, isCreate),
 	}
 
 	for _, rw := range relWrites {
@@ -66,7 +95,8 @@ func PlanWrite(entity *metadata.Entity, reg *metadata.Registry, body map[string]
 }
 
 // ExecuteWritePlan runs the planned operations inside a single transaction.
-// Returns the created/updated record.
+// Returns the created/updated record. A dry run returns the record as it would
+// be stored and rolls back, skipping sync webhooks and everything after commit.
 func ExecuteWritePlan(ctx context.Context, s *store.Store, reg *metadata.Registry, plan *WritePlan) (map[string]any, error) {
 	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "writer", "nested_write.execute")
 	defer span.End()
@@ -80,100 +110,210 @@ func ExecuteWritePlan(ctx context.Context, s *store.Store, reg *metadata.Registr
 	}
 	defer tx.Rollback() //nolint:errcheck
 
+	record, old, err := applyWritePlan(ctx, tx, s, reg, plan)
+	if err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return nil, err
+	}
+	if plan.DryRun {
+		span.SetMetadata("dry_run", true)
+		span.SetStatus("ok")
+		return record, nil // the deferred Rollback discards the write
+	}
+
+	// Commit
+	if err := tx.Commit(); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	invalidateEntityCache(reg, plan.Entity.Name)
+	finishWritePlan(ctx, s, reg, plan, record, old)
+
+	span.SetStatus("ok")
+	return record, nil
+}
+
+// applyWritePlan runs the part of a write that happens in tx: hooks, rules,
+// state machines, the SQL, child writes, rollups, audit entries and, unless
+// it is a dry run, sync webhooks. It returns the stored record and the record
+// as it was before the write (empty for a create).
+func applyWritePlan(ctx context.Context, tx store.Querier, s *store.Store, reg *metadata.Registry, plan *WritePlan) (record, old map[string]any, err error) {
 	// Evaluate rules (field -> expression -> computed)
-	var old map[string]any
 	if !plan.IsCreate {
+		// Pessimistic locking holds the row from here to commit, so rules and
+		// hooks see the state the update is applied to
+		if plan.Entity.Locking == "pessimistic" {
+			if err := store.LockRow(ctx, tx, s.Dialect, plan.Entity.Table, plan.Entity.PrimaryKey.Field, plan.ID); err != nil {
+				return nil, nil, err
+			}
+		}
 		old, _ = fetchRecord(ctx, tx, plan.Entity, plan.ID, s.Dialect)
 	}
 	if old == nil {
 		old = map[string]any{}
 	}
 
-	ruleErrs := EvaluateRules(ctx, reg, plan.Entity.Name, "before_write", plan.Fields, old, plan.IsCreate)
-	if len(ruleErrs) > 0 {
-		span.SetStatus("error")
-		return nil, ValidationError(ruleErrs)
+	// Snapshot the client's fields before hooks and rules add to them
+	audit := plan.Entity.Audit && !plan.IsCreate
+	var supplied map[string]any
+	if audit {
+		supplied = make(map[string]any, len(plan.Fields))
+		for k, v := range plan.Fields {
+			supplied[k] = v
+		}
 	}
 
-	// Evaluate state machines (after rules, before SQL write)
-	smErrs := EvaluateStateMachines(ctx, reg, plan.Entity.Name, plan.Fields, old, plan.IsCreate)
-	if len(smErrs) > 0 {
-		span.SetStatus("error")
-		return nil, ValidationError(smErrs)
+	if err := runBeforeWriteHooks(ctx, plan, old); err != nil {
+		return nil, nil, err
+	}
+
+	// Rules, then state machines (before SQL write); report every violation
+	// from them and the field checks together rather than stopping at the
+	// first failing stage
+	// A partial update evaluates them against the stored record with the
+	// submitted fields applied, so computed rules can read fields the client
+	// left out
+	fields := plan.Fields
+	if plan.Partial {
+		fields = mergeFields(old, plan.Fields)
+	}
+	var ruleErrs []ErrorDetail
+	if !plan.Bypass.Rules {
+		ruleErrs = EvaluateRules(ctx, reg, plan.Entity.Name, "before_write", fields, old, plan.IsCreate)
+	}
+	smErrs := EvaluateStateMachines(ctx, reg, plan.Entity.Name, fields, old, plan.IsCreate)
+	var errs []ErrorDetail
+	errs = append(errs, plan.FieldErrors...)
+	errs = append(errs, ruleErrs...)
+	errs = append(errs, smErrs...)
+	if len(errs) > 0 {
+		return nil, nil, ValidationError(errs)
+	}
+	if plan.Partial {
+		keepChangedFields(plan.Entity, plan.Fields, fields, old)
+	}
+
+	// Rules and state machines may have set values; normalize them too
+	ApplyFieldTransforms(plan.Entity, plan.Fields)
+
+	// Soft relations carry no FK constraint; check the referenced rows here
+	refErrs, err := checkSoftReferences(ctx, tx, s.Dialect, reg, plan.Entity, plan.Fields, old)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(refErrs) > 0 {
+		return nil, nil, ValidationError(refErrs)
 	}
 
 	// Auto-generate slug if configured
 	if err := autoGenerateSlug(ctx, tx, plan.Entity, s.Dialect, plan.Fields, plan.IsCreate, old, plan.ID); err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Resolve file fields: UUID string -> JSONB metadata object
 	if err := resolveFileFields(ctx, tx, plan.Entity, plan.Fields, s.Dialect); err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return nil, fmt.Errorf("resolve file fields: %w", err)
+		return nil, nil, fmt.Errorf("resolve file fields: %w", err)
 	}
 
+	stampAttribution(plan.Entity, plan.Fields, plan.User)
+
+	// record is the stored row as RETURNING reports it
 	var parentID any
 
 	if plan.IsCreate {
 		// INSERT parent
 		sql, params := BuildInsertSQL(plan.Entity, plan.Fields, s.Dialect)
-		row, err := store.QueryRow(ctx, tx, sql, params...)
+		record, err = store.QueryRow(ctx, tx, sql, params...)
 		if err != nil {
-			span.SetStatus("error")
-			span.SetMetadata("error", err.Error())
-			return nil, fmt.Errorf("insert %s: %w", plan.Entity.Table, err)
+			if err = store.MapError(s.Dialect, err); errors.Is(err, store.ErrUniqueViolation) {
+				return nil, nil, UniqueViolationError(plan.Entity, err)
+			}
+			return nil, nil, fmt.Errorf("insert %s: %w", plan.Entity.Table, err)
 		}
-		parentID = row[plan.Entity.PrimaryKey.Field]
+		parentID = record[plan.Entity.PrimaryKey.Field]
 	} else {
 		// UPDATE parent
 		parentID = plan.ID
 		sql, params := BuildUpdateSQL(plan.Entity, plan.ID, plan.Fields, s.Dialect)
 		if sql != "" {
-			if _, err := store.Exec(ctx, tx, sql, params...); err != nil {
-				span.SetStatus("error")
-				span.SetMetadata("error", err.Error())
-				return nil, fmt.Errorf("update %s: %w", plan.Entity.Table, err)
+			record, err = store.QueryRow(ctx, tx, sql, params...)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				if err = store.MapError(s.Dialect, err); errors.Is(err, store.ErrUniqueViolation) {
+					return nil, nil, UniqueViolationError(plan.Entity, err)
+				}
+				return nil, nil, fmt.Errorf("update %s: %w", plan.Entity.Table, err)
 			}
 		}
 	}
+	if record == nil {
+		// Nothing was set (or the row is gone); read it as it stands
+		if record, err = fetchRecord(ctx, tx, plan.Entity, parentID, s.Dialect); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		decodeStoredFields(plan.Entity, record)
+	}
 
 	// Execute child writes
 	for _, childOp := range plan.ChildOps {
+		if target := reg.GetEntity(childOp.Relation.Target); target != nil && !childOp.Relation.IsManyToMany() {
+			for _, row := range childOp.Data {
+				stampAttribution(target, row, plan.User)
+			}
+		}
 		if err := ExecuteChildWrite(ctx, tx, s.Dialect, reg, parentID, childOp); err != nil {
-			span.SetStatus("error")
-			span.SetMetadata("error", err.Error())
-			return nil, fmt.Errorf("child write for %s: %w", childOp.Relation.Name, err)
+			return nil, nil, fmt.Errorf("child write for %s: %w", childOp.Relation.Name, err)
 		}
 	}
 
-	// Pre-commit: fire sync (before_write) webhooks
-	action := "update"
-	if plan.IsCreate {
-		action = "create"
+	// Refresh rollups over this record on its old and new parents, and the
+	// record's own rollups when it is new or its children were written
+	if err := refreshRollupsOver(ctx, tx, s.Dialect, reg, plan.Entity, old, record); err != nil {
+		return nil, nil, err
 	}
-	if err := FireSyncWebhooks(ctx, tx, s.Dialect, reg, "before_write", plan.Entity.Name, action, plan.Fields, old, plan.User); err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return nil, fmt.Errorf("sync webhook: %w", err)
+	if (plan.IsCreate || len(plan.ChildOps) > 0) && len(rollupGroups(reg, plan.Entity)) > 0 {
+		if err := refreshOwnRollups(ctx, tx, s.Dialect, reg, plan.Entity, record); err != nil {
+			return nil, nil, err
+		}
+		if record, err = fetchRecord(ctx, tx, plan.Entity, parentID, s.Dialect); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// Commit
-	if err := tx.Commit(); err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return nil, fmt.Errorf("commit: %w", err)
+	if audit {
+		if changes := auditChanges(plan.Entity, old, record, supplied, plan.Fields, plan.User); len(changes) > 0 {
+			if err := WriteAuditEntry(ctx, tx, s.Dialect, plan.Entity, record[plan.Entity.PrimaryKey.Field], plan.action(), plan.User, changes); err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 
-	// Fetch the full record
-	record, err := fetchRecord(ctx, s.DB, plan.Entity, parentID, s.Dialect)
-	if err != nil {
-		span.SetStatus("error")
-		span.SetMetadata("error", err.Error())
-		return nil, err
+	if err := auditBypass(ctx, tx, s.Dialect, plan.Entity, parentID, plan.User, plan.Bypass); err != nil {
+		return nil, nil, err
+	}
+
+	if plan.DryRun {
+		return record, old, nil
+	}
+
+	// Pre-commit: fire sync (before_write) webhooks
+	if !plan.Bypass.Webhooks {
+		if err := FireSyncWebhooks(ctx, tx, s.Dialect, reg, "before_write", plan.Entity.Name, plan.action(), plan.Fields, old, plan.User); err != nil {
+			return nil, nil, fmt.Errorf("sync webhook: %w", err)
+		}
+	}
+
+	return record, old, nil
+}
+
+// finishWritePlan runs what follows a committed write: workflows triggered by
+// state transitions, after-write hooks and rules, and async webhooks.
+func finishWritePlan(ctx context.Context, s *store.Store, reg *metadata.Registry, plan *WritePlan, record, old map[string]any) {
+	recordID := plan.ID
+	if plan.IsCreate {
+		recordID = record[plan.Entity.PrimaryKey.Field]
 	}
 
 	// Post-commit: trigger workflows for state transitions
@@ -187,25 +327,116 @@ func ExecuteWritePlan(ctx context.Context, s *store.Store, reg *metadata.Registr
 			newState = fmt.Sprintf("%v", v)
 		}
 		if newState != "" && oldState != newState {
-			TriggerWorkflows(ctx, s, reg, plan.Entity.Name, sm.Field, newState, record, parentID)
+			TriggerWorkflows(ctx, s, reg, plan.Entity.Name, sm.Field, newState, record, recordID)
 		}
 	}
 
+	runAfterWriteHooks(ctx, plan, record, old)
+	if !plan.Bypass.Rules {
+		runAfterRules(ctx, s, reg, plan.Entity, "after_write", plan.action(), record, old)
+	}
+
 	// Post-commit: fire async (after_write) webhooks
-	FireAsyncWebhooks(ctx, s, reg, "after_write", plan.Entity.Name, action, record, old, plan.User)
+	if !plan.Bypass.Webhooks {
+		FireAsyncWebhooks(ctx, s, reg, "after_write", plan.Entity.Name, plan.action(), record, old, plan.User)
+	}
+}
 
-	span.SetStatus("ok")
-	return record, nil
+// mergeFields returns the stored record with the submitted fields applied.
+func mergeFields(old, fields map[string]any) map[string]any {
+	merged := make(map[string]any, len(old)+len(fields))
+	for k, v := range old {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
 }
 
-func fetchRecord(ctx context.Context, q store.Querier, entity *metadata.Entity, id any, dialect store.Dialect) (map[string]any, error) {
+// keepChangedFields copies into fields the values of merged that rules or
+// state machines changed from the stored record, so a partial update writes
+// the submitted fields and what was computed from them, and nothing else.
+func keepChangedFields(entity *metadata.Entity, fields, merged, old map[string]any) {
+	for k, v := range merged {
+		if _, ok := fields[k]; ok {
+			fields[k] = v
+			continue
+		}
+		if before, ok := old[k]; !ok || !sameFieldValue(entity.GetField(k), before, v) {
+			fields[k] = v
+		}
+	}
+}
+
+// sameFieldValue reports whether a stored value and a computed one are equal
+// as values of f: numbers by value whatever their Go type, booleans also from
+// SQLite's 0/1 and timestamps by instant. Anything else must be deeply equal,
+// so 1 and "1" differ on a string field.
+func sameFieldValue(f *metadata.Field, a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if f != nil {
+		switch f.StorageType() {
+		case "int", "integer", "bigint", "float", "decimal":
+			ra, aok := toRat(a)
+			rb, bok := toRat(b)
+			if aok && bok {
+				return ra.Cmp(rb) == 0
+			}
+		case "boolean":
+			a, b = storedBool(a), storedBool(b)
+		}
+	}
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	if raw, ok := a.([]byte); ok {
+		a = string(raw)
+	}
+	if raw, ok := b.([]byte); ok {
+		b = string(raw)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// storedBool turns SQLite's 0/1 into a bool and leaves other values alone.
+func storedBool(v any) any {
+	switch n := v.(type) {
+	case int64:
+		return n != 0
+	case int:
+		return n != 0
+	}
+	return v
+}
+
+// recordColumns lists the columns a record is read with.
+func recordColumns(entity *metadata.Entity) []string {
 	columns := entity.FieldNames()
 	if entity.SoftDelete && entity.GetField("deleted_at") == nil {
 		columns = append(columns, "deleted_at")
 	}
+	return columns
+}
+
+func fetchRecord(ctx context.Context, q store.Querier, entity *metadata.Entity, id any, dialect store.Dialect) (map[string]any, error) {
+	return loadRecord(ctx, q, entity, id, dialect, false)
+}
+
+// fetchRecordIncludingDeleted is fetchRecord that also finds soft-deleted
+// records, for ?include_deleted=true reads and restores.
+func fetchRecordIncludingDeleted(ctx context.Context, q store.Querier, entity *metadata.Entity, id any, dialect store.Dialect) (map[string]any, error) {
+	return loadRecord(ctx, q, entity, id, dialect, true)
+}
+
+func loadRecord(ctx context.Context, q store.Querier, entity *metadata.Entity, id any, dialect store.Dialect, includeDeleted bool) (map[string]any, error) {
+	columns := recordColumns(entity)
 
 	softDeleteClause := ""
-	if entity.SoftDelete {
+	if entity.SoftDelete && !includeDeleted {
 		softDeleteClause = " AND deleted_at IS NULL"
 	}
 
@@ -216,6 +447,7 @@ func fetchRecord(ctx context.Context, q store.Querier, entity *metadata.Entity,
 			joinColumns(columns), entity.Table, entity.Slug.Field, dialect.Placeholder(1), softDeleteClause)
 		row, err := store.QueryRow(ctx, q, slugSQL, idStr)
 		if err == nil {
+			decodeStoredFields(entity, row)
 			return row, nil
 		}
 		// slug lookup failed, fall through to PK lookup
@@ -224,7 +456,12 @@ func fetchRecord(ctx context.Context, q store.Querier, entity *metadata.Entity,
 	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s%s",
 		joinColumns(columns), entity.Table, entity.PrimaryKey.Field, dialect.Placeholder(1), softDeleteClause)
 
-	return store.QueryRow(ctx, q, sql, id)
+	row, err := store.QueryRow(ctx, q, sql, id)
+	if err != nil {
+		return nil, err
+	}
+	decodeStoredFields(entity, row)
+	return row, nil
 }
 
 var intRE = regexp.MustCompile(`^\d+$`)
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestPatch_ComputedRulesReadStoredFields(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "quote",
+		Table:      "quotes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "customer", Type: "string"},
+			{Name: "qty", Type: "int"},
+			{Name: "price", Type: "int"},
+			{Name: "total", Type: "int", Nullable: true},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadRules([]*metadata.Rule{
+		{
+			ID: "r1", Entity: "quote", Hook: "before_write", Type: "computed", Active: true, Priority: 1,
+			Definition: metadata.RuleDefinition{Field: "total", Expression: "record.qty * record.price"},
+		},
+		{
+			ID: "r2", Entity: "quote", Hook: "before_write", Type: "field", Active: true,
+			Definition: metadata.RuleDefinition{Field: "qty", Operator: "min", Value: float64(1), Message: "qty must be at least 1"},
+		},
+	})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Patch("/api/:entity/:id", h.Patch)
+
+	send := func(method, path string, body map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, out := send("POST", "/api/quote", map[string]any{"customer": "Acme", "qty": 2, "price": 7})
+	if status != 201 {
+		t.Fatalf("create: %d %v", status, out)
+	}
+	id := out["data"].(map[string]any)["id"].(string)
+
+	status, out = send("PATCH", "/api/quote/"+id, map[string]any{"qty": 5})
+	if status != 200 {
+		t.Fatalf("patch: %d %v", status, out)
+	}
+	rec := out["data"].(map[string]any)
+	if toInt(rec["total"]) != 35 || toInt(rec["price"]) != 7 || rec["customer"] != "Acme" {
+		t.Fatalf("expected the total from the stored price and other fields untouched, got %v", rec)
+	}
+
+	if status, out := send("PATCH", "/api/quote/"+id, map[string]any{"qty": 0}); status != 422 {
+		t.Fatalf("expected 422 for a rule violation, got %d %v", status, out)
+	}
+	if status, _ := send("PATCH", "/api/quote/00000000-0000-0000-0000-000000000000", map[string]any{"qty": 3}); status != 404 {
+		t.Fatalf("expected 404 for a missing record, got %d", status)
+	}
+	row, err := store.QueryRow(ctx, s.DB, "SELECT qty, price, total FROM quotes WHERE id = ?1", id)
+	if err != nil || toInt(row["qty"]) != 5 || toInt(row["price"]) != 7 || toInt(row["total"]) != 35 {
+		t.Fatalf("expected the stored record after the first patch only, got %v (%v)", row, err)
+	}
+}
+
+func TestKeepChangedFields_ComparesTypedValues(t *testing.T) {
+	entity := &metadata.Entity{Name: "quote", Fields: []metadata.Field{
+		{Name: "qty", Type: "int"},
+		{Name: "code", Type: "string"},
+		{Name: "active", Type: "boolean"},
+		{Name: "tags", Type: "array"},
+	}}
+	old := map[string]any{"qty": int64(2), "code": int64(1), "active": int64(1), "tags": []any{"a", "b"}}
+	merged := map[string]any{"qty": float64(2), "code": "1", "active": true, "tags": []any{"b", "a"}}
+
+	fields := map[string]any{}
+	keepChangedFields(entity, fields, merged, old)
+	if _, ok := fields["qty"]; ok {
+		t.Errorf("2 and 2.0 are the same int, got qty written")
+	}
+	if _, ok := fields["active"]; ok {
+		t.Errorf("stored 1 is true, got active written")
+	}
+	if fields["code"] != "1" {
+		t.Errorf("expected string code changed from 1 to \"1\", got %v", fields)
+	}
+	if _, ok := fields["tags"]; !ok {
+		t.Errorf("expected reordered tags to be written, got %v", fields)
+	}
+}
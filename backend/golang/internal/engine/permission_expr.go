@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+
+	"rocket-backend/internal/metadata"
+)
+
+// permissionPrograms caches compiled permission expressions by source text.
+var permissionPrograms sync.Map
+
+// permissionExprEnv builds the expression environment for permission conditions.
+func permissionExprEnv(user *metadata.UserContext, record map[string]any) map[string]any {
+	u := map[string]any{"id": "", "roles": []any{}}
+	if user != nil {
+		roles := make([]any, len(user.Roles))
+		for i, r := range user.Roles {
+			roles[i] = r
+		}
+		u = map[string]any{"id": user.ID, "roles": roles}
+	}
+	if record == nil {
+		record = map[string]any{}
+	}
+	return map[string]any{"record": record, "user": u}
+}
+
+// evaluatePermissionExpression runs an expression condition against a record.
+// Compile or runtime errors evaluate to false so a broken policy never grants access.
+func evaluatePermissionExpression(expression string, user *metadata.UserContext, record map[string]any) bool {
+	return evaluateExpressionEnv(expression, permissionExprEnv(user, record))
+}
+
+// evaluateExpressionEnv runs a boolean expression against env, failing closed.
+func evaluateExpressionEnv(expression string, env map[string]any) bool {
+	var prog *vm.Program
+	if cached, ok := permissionPrograms.Load(expression); ok {
+		prog = cached.(*vm.Program)
+	} else {
+		compiled, err := compileExpr(expression, expr.AsBool())
+		if err != nil {
+			return false
+		}
+		permissionPrograms.Store(expression, compiled)
+		prog = compiled
+	}
+	result, err := runExpr(context.Background(), prog, env)
+	if err != nil {
+		return false
+	}
+	b, _ := result.(bool)
+	return b
+}
+
+// expressionToClause translates a permission expression into a WhereClause for the
+// given user. Sub-expressions that don't reference `record` are evaluated up front,
+// so "record.owner_id == user.id || 'admin' in user.roles" becomes either
+// "owner_id = <user id>" or an always-true clause. Returns false when the expression
+// uses constructs that have no SQL equivalent; callers then filter rows in Go.
+func expressionToClause(expression string, user *metadata.UserContext, entity *metadata.Entity) (WhereClause, bool) {
+	tree, err := parser.Parse(expression)
+	if err != nil {
+		return WhereClause{}, false
+	}
+	t := exprTranslator{env: permissionExprEnv(user, nil), entity: entity}
+	return t.translate(tree.Node)
+}
+
+// ValidatePermissionExpression checks that an expression condition compiles
+// and has a SQL translation for entity, so reads can filter on it in the
+// query: record fields compared with ==, !=, <, >, <=, >= or in against
+// values that don't depend on the record, combined with &&, || and !.
+// Parts that only use user are allowed anywhere.
+func ValidatePermissionExpression(expression string, entity *metadata.Entity) error {
+	if _, err := CompileExpression(expression); err != nil {
+		return err
+	}
+	if _, ok := expressionToClause(expression, &metadata.UserContext{ID: "validate", Roles: []string{}}, entity); !ok {
+		return fmt.Errorf("expression has no SQL translation: compare record.<field> (a field of %s) with values that don't use record, combined with &&, || and !", entity.Name)
+	}
+	return nil
+}
+
+var (
+	clauseTrue  = WhereClause{Operator: "and", Value: []WhereClause{}}
+	clauseFalse = WhereClause{Operator: "or", Value: []WhereClause{}}
+)
+
+func isConstClause(wc WhereClause, want WhereClause) bool {
+	nested, ok := wc.Value.([]WhereClause)
+	return ok && len(nested) == 0 && wc.Operator == want.Operator
+}
+
+type exprTranslator struct {
+	env    map[string]any
+	entity *metadata.Entity
+}
+
+func (t exprTranslator) translate(node ast.Node) (WhereClause, bool) {
+	if !referencesRecord(node) {
+		val, err := expr.Eval(node.String(), t.env)
+		if err != nil {
+			return WhereClause{}, false
+		}
+		b, ok := val.(bool)
+		if !ok {
+			return WhereClause{}, false
+		}
+		if b {
+			return clauseTrue, true
+		}
+		return clauseFalse, true
+	}
+
+	switch n := node.(type) {
+	case *ast.UnaryNode:
+		if n.Operator != "!" && n.Operator != "not" {
+			return WhereClause{}, false
+		}
+		inner, ok := t.translate(n.Node)
+		if !ok {
+			return WhereClause{}, false
+		}
+		switch {
+		case isConstClause(inner, clauseTrue):
+			return clauseFalse, true
+		case isConstClause(inner, clauseFalse):
+			return clauseTrue, true
+		}
+		return WhereClause{Operator: "not_all", Value: []WhereClause{inner}}, true
+
+	case *ast.BinaryNode:
+		switch n.Operator {
+		case "&&", "and":
+			return t.combine("and", n.Left, n.Right)
+		case "||", "or":
+			return t.combine("or", n.Left, n.Right)
+		case "==", "!=", "<", ">", "<=", ">=", "in":
+			return t.comparison(n)
+		}
+	}
+	return WhereClause{}, false
+}
+
+// combine joins two sub-clauses, folding constant sides away.
+func (t exprTranslator) combine(op string, left, right ast.Node) (WhereClause, bool) {
+	l, ok := t.translate(left)
+	if !ok {
+		return WhereClause{}, false
+	}
+	r, ok := t.translate(right)
+	if !ok {
+		return WhereClause{}, false
+	}
+	absorbing, identity := clauseFalse, clauseTrue
+	if op == "or" {
+		absorbing, identity = clauseTrue, clauseFalse
+	}
+	switch {
+	case isConstClause(l, absorbing) || isConstClause(r, absorbing):
+		return absorbing, true
+	case isConstClause(l, identity):
+		return r, true
+	case isConstClause(r, identity):
+		return l, true
+	}
+	return WhereClause{Operator: op, Value: []WhereClause{l, r}}, true
+}
+
+var flippedOperators = map[string]string{"==": "==", "!=": "!=", "<": ">", ">": "<", "<=": ">=", ">=": "<="}
+
+var sqlOperators = map[string]string{"==": "eq", "!=": "neq", "<": "lt", ">": "gt", "<=": "lte", ">=": "gte", "in": "in"}
+
+// comparison translates `record.field <op> <value>` where the value side is record-free.
+func (t exprTranslator) comparison(n *ast.BinaryNode) (WhereClause, bool) {
+	op := n.Operator
+	fieldNode, valueNode := n.Left, n.Right
+	field, ok := t.recordField(fieldNode)
+	if !ok {
+		if op == "in" {
+			return WhereClause{}, false
+		}
+		fieldNode, valueNode = n.Right, n.Left
+		if field, ok = t.recordField(fieldNode); !ok {
+			return WhereClause{}, false
+		}
+		op = flippedOperators[op]
+	}
+	if referencesRecord(valueNode) {
+		return WhereClause{}, false
+	}
+
+	val, err := expr.Eval(valueNode.String(), t.env)
+	if err != nil || val == nil {
+		return WhereClause{}, false
+	}
+	if op == "in" {
+		list, ok := val.([]any)
+		if !ok {
+			return WhereClause{}, false
+		}
+		if len(list) == 0 {
+			return clauseFalse, true
+		}
+	}
+	return WhereClause{Field: field, Operator: sqlOperators[op], Value: val}, true
+}
+
+// recordField returns the column name for a `record.<field>` node on a known field.
+func (t exprTranslator) recordField(node ast.Node) (string, bool) {
+	m, ok := node.(*ast.MemberNode)
+	if !ok {
+		return "", false
+	}
+	ident, ok := m.Node.(*ast.IdentifierNode)
+	if !ok || ident.Value != "record" {
+		return "", false
+	}
+	prop, ok := m.Property.(*ast.StringNode)
+	if !ok || t.entity == nil || !t.entity.HasField(prop.Value) {
+		return "", false
+	}
+	return prop.Value, true
+}
+
+func referencesRecord(node ast.Node) bool {
+	return ast.Find(node, func(n ast.Node) bool {
+		ident, ok := n.(*ast.IdentifierNode)
+		return ok && ident.Value == "record"
+	}) != nil
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -36,9 +37,16 @@ func CheckPermission(ctx context.Context, user *metadata.UserContext, entity, ac
 		return ForbiddenError(fmt.Sprintf("No permission for %s on %s", action, entity))
 	}
 
+	// Deny policies take precedence over any grant
+	if err := checkDenyPolicies(user, entity, action, policies, currentRecord); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("denied", "policy")
+		return err
+	}
+
 	// Check each policy — if ANY passes, the action is allowed
 	for _, p := range policies {
-		if !hasRoleIntersection(user.Roles, p.Roles) {
+		if p.IsDeny() || !hasRoleIntersection(user.Roles, p.Roles) {
 			continue
 		}
 		// Role matches — now check conditions
@@ -46,7 +54,7 @@ func CheckPermission(ctx context.Context, user *metadata.UserContext, entity, ac
 			span.SetStatus("ok")
 			return nil // No conditions, role match is sufficient
 		}
-		if currentRecord != nil && evaluateConditions(p.Conditions, currentRecord) {
+		if currentRecord != nil && evaluateConditions(user, p.Conditions, currentRecord) {
 			span.SetStatus("ok")
 			return nil
 		}
@@ -63,6 +71,9 @@ func CheckPermission(ctx context.Context, user *metadata.UserContext, entity, ac
 
 // GetReadFilters returns extra WhereClause entries to inject into read queries
 // for row-level security. Admin users get no filters (full access).
+// Expression conditions are translated to SQL; a policy whose expression
+// can't be (see ValidatePermissionExpression) hides every row, allow or deny,
+// so a broken policy never grants access.
 func GetReadFilters(user *metadata.UserContext, entity string, reg *metadata.Registry) []WhereClause {
 	if user == nil || user.IsAdmin() {
 		return nil
@@ -72,23 +83,131 @@ func GetReadFilters(user *metadata.UserContext, entity string, reg *metadata.Reg
 	if len(policies) == 0 {
 		return nil
 	}
+	ent := reg.GetEntity(entity)
 
 	var filters []WhereClause
 	for _, p := range policies {
 		if !hasRoleIntersection(user.Roles, p.Roles) {
 			continue
 		}
-		for _, cond := range p.Conditions {
-			filters = append(filters, WhereClause{
-				Field:    cond.Field,
-				Operator: cond.Operator,
-				Value:    cond.Value,
-			})
+		clauses, ok := conditionsToClauses(user, ent, p.Conditions)
+		if !ok {
+			filters = append(filters, clauseFalse)
+			continue
+		}
+		if p.IsDeny() {
+			// Row-level deny: exclude rows matching all of the deny conditions.
+			// Unconditional denies are rejected earlier by CheckPermission.
+			if len(p.Conditions) > 0 {
+				filters = append(filters, WhereClause{
+					Operator: "not_all",
+					Value:    clauses,
+				})
+			}
+			continue
+		}
+		for _, wc := range clauses {
+			if !isConstClause(wc, clauseTrue) {
+				filters = append(filters, wc)
+			}
 		}
 	}
 	return filters
 }
 
+// FilterReadRows applies the user's read policy conditions to rows loaded
+// without GetReadFilters, such as a relation's linked records. Rows are dropped
+// when they fail an allow policy or match a deny policy.
+func FilterReadRows(user *metadata.UserContext, entity string, reg *metadata.Registry, rows []map[string]any) []map[string]any {
+	if len(rows) == 0 || user == nil || user.IsAdmin() {
+		return rows
+	}
+	var policies []*metadata.Permission
+	for _, p := range reg.GetPermissions(entity, "read") {
+		if len(p.Conditions) > 0 && hasRoleIntersection(user.Roles, p.Roles) {
+			policies = append(policies, p)
+		}
+	}
+	if len(policies) == 0 {
+		return rows
+	}
+
+	kept := rows[:0]
+	for _, row := range rows {
+		visible := true
+		for _, p := range policies {
+			matches := evaluateConditions(user, p.Conditions, row)
+			if matches == p.IsDeny() {
+				visible = false
+				break
+			}
+		}
+		if visible {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// CheckIncludeDeleted gates ?include_deleted=true: soft-deleted records are
+// only shown to admins and users who may delete (and so restore) them.
+func CheckIncludeDeleted(ctx context.Context, user *metadata.UserContext, entity string, reg *metadata.Registry) error {
+	if err := CheckPermission(ctx, user, entity, "delete", reg, nil); err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) && appErr.Status == 403 {
+			return ForbiddenError(fmt.Sprintf("include_deleted needs delete permission on %s", entity))
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckRecordDenied enforces row-level deny policies against a fetched record.
+// Used where a grant has already been checked without the record (e.g. get by ID).
+func CheckRecordDenied(user *metadata.UserContext, entity, action string, reg *metadata.Registry, record map[string]any) error {
+	if user == nil || user.IsAdmin() {
+		return nil
+	}
+	return checkDenyPolicies(user, entity, action, reg.GetPermissions(entity, action), record)
+}
+
+// checkDenyPolicies returns FORBIDDEN if any deny policy matching the user's roles
+// applies. A deny without conditions always applies; a deny with conditions applies
+// only when a record is available and matches all of them.
+func checkDenyPolicies(user *metadata.UserContext, entity, action string, policies []*metadata.Permission, record map[string]any) error {
+	for _, p := range policies {
+		if !p.IsDeny() || !hasRoleIntersection(user.Roles, p.Roles) {
+			continue
+		}
+		if len(p.Conditions) == 0 || (record != nil && evaluateConditions(user, p.Conditions, record)) {
+			return ForbiddenError(fmt.Sprintf("Permission denied for %s on %s", action, entity))
+		}
+	}
+	return nil
+}
+
+// conditionsToClauses converts policy conditions to WhereClauses. Returns false
+// if any expression condition has no SQL equivalent.
+func conditionsToClauses(user *metadata.UserContext, entity *metadata.Entity, conditions []metadata.PermissionCondition) ([]WhereClause, bool) {
+	clauses := make([]WhereClause, 0, len(conditions))
+	for _, cond := range conditions {
+		if cond.Expression != "" {
+			wc, ok := expressionToClause(cond.Expression, user, entity)
+			if !ok {
+				return nil, false
+			}
+			clauses = append(clauses, wc)
+			continue
+		}
+		clauses = append(clauses, WhereClause{
+			Field:    cond.Field,
+			Operator: cond.Operator,
+			Value:    cond.Value,
+		})
+	}
+	return clauses, true
+}
+
 func hasRoleIntersection(userRoles, policyRoles []string) bool {
 	for _, ur := range userRoles {
 		for _, pr := range policyRoles {
@@ -100,8 +219,14 @@ func hasRoleIntersection(userRoles, policyRoles []string) bool {
 	return false
 }
 
-func evaluateConditions(conditions []metadata.PermissionCondition, record map[string]any) bool {
+func evaluateConditions(user *metadata.UserContext, conditions []metadata.PermissionCondition, record map[string]any) bool {
 	for _, cond := range conditions {
+		if cond.Expression != "" {
+			if !evaluatePermissionExpression(cond.Expression, user, record) {
+				return false
+			}
+			continue
+		}
 		val, ok := record[cond.Field]
 		if !ok {
 			return false
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func permissionRegistry(perms ...*metadata.Permission) *metadata.Registry {
+	reg := metadata.NewRegistry()
+	reg.LoadPermissions(perms)
+	return reg
+}
+
+func TestCheckPermission_DenyOverridesGrant(t *testing.T) {
+	reg := permissionRegistry(
+		&metadata.Permission{Entity: "invoice", Action: "read", Effect: "allow", Roles: []string{"staff"}},
+		&metadata.Permission{Entity: "invoice", Action: "read", Effect: "deny", Roles: []string{"contractor"}},
+	)
+
+	staff := &metadata.UserContext{ID: "u1", Roles: []string{"staff"}}
+	if err := CheckPermission(context.Background(), staff, "invoice", "read", reg, nil); err != nil {
+		t.Fatalf("expected staff to be allowed, got %v", err)
+	}
+
+	// Contractor is also staff, so the grant alone would allow access
+	contractor := &metadata.UserContext{ID: "u2", Roles: []string{"staff", "contractor"}}
+	err := CheckPermission(context.Background(), contractor, "invoice", "read", reg, nil)
+	if err == nil {
+		t.Fatal("expected deny policy to block contractor")
+	}
+	if appErr, ok := err.(*AppError); !ok || appErr.Status != 403 {
+		t.Fatalf("expected 403 AppError, got %v", err)
+	}
+
+	admin := &metadata.UserContext{ID: "u3", Roles: []string{"admin", "contractor"}}
+	if err := CheckPermission(context.Background(), admin, "invoice", "read", reg, nil); err != nil {
+		t.Fatalf("expected admin to bypass deny, got %v", err)
+	}
+}
+
+func TestCheckPermission_RowLevelDeny(t *testing.T) {
+	reg := permissionRegistry(
+		&metadata.Permission{Entity: "invoice", Action: "update", Roles: []string{"staff"}},
+		&metadata.Permission{Entity: "invoice", Action: "update", Effect: "deny", Roles: []string{"staff"},
+			Conditions: []metadata.PermissionCondition{{Field: "status", Operator: "eq", Value: "paid"}}},
+	)
+	user := &metadata.UserContext{ID: "u1", Roles: []string{"staff"}}
+
+	if err := CheckPermission(context.Background(), user, "invoice", "update", reg, map[string]any{"status": "draft"}); err != nil {
+		t.Fatalf("expected update of draft invoice to be allowed, got %v", err)
+	}
+	if err := CheckPermission(context.Background(), user, "invoice", "update", reg, map[string]any{"status": "paid"}); err == nil {
+		t.Fatal("expected update of paid invoice to be denied")
+	}
+	if err := CheckRecordDenied(user, "invoice", "update", reg, map[string]any{"status": "paid"}); err == nil {
+		t.Fatal("expected CheckRecordDenied to reject paid invoice")
+	}
+}
+
+func TestGetReadFilters_RowLevelDeny(t *testing.T) {
+	reg := permissionRegistry(
+		&metadata.Permission{Entity: "invoice", Action: "read", Roles: []string{"staff"}},
+		&metadata.Permission{Entity: "invoice", Action: "read", Effect: "deny", Roles: []string{"staff"},
+			Conditions: []metadata.PermissionCondition{{Field: "confidential", Operator: "eq", Value: true}}},
+	)
+	user := &metadata.UserContext{ID: "u1", Roles: []string{"staff"}}
+
+	filters := GetReadFilters(user, "invoice", reg)
+	if len(filters) != 1 || filters[0].Operator != "not_all" {
+		t.Fatalf("expected a single not_all filter, got %+v", filters)
+	}
+
+	pb := store.NewDialect("postgres").NewParamBuilder()
+	clause := buildWhereClause(filters[0], pb, store.NewDialect("postgres"))
+	if !strings.HasPrefix(clause, "NOT COALESCE((confidential = $1)") {
+		t.Fatalf("unexpected clause: %s", clause)
+	}
+}
+
+func TestPermissionExpression_OwnershipOrRole(t *testing.T) {
+	reg := permissionRegistry(&metadata.Permission{Entity: "ticket", Action: "update", Roles: []string{"staff", "manager"},
+		Conditions: []metadata.PermissionCondition{{Expression: "record.owner_id == user.id || 'manager' in user.roles"}}})
+	reg.Load([]*metadata.Entity{{
+		Name: "ticket", Table: "tickets",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid"},
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "owner_id", Type: "string"}},
+	}}, nil)
+
+	staff := &metadata.UserContext{ID: "u1", Roles: []string{"staff"}}
+	manager := &metadata.UserContext{ID: "u2", Roles: []string{"manager"}}
+	ctx := context.Background()
+
+	if err := CheckPermission(ctx, staff, "ticket", "update", reg, map[string]any{"owner_id": "u1"}); err != nil {
+		t.Fatalf("expected owner to be allowed, got %v", err)
+	}
+	if err := CheckPermission(ctx, staff, "ticket", "update", reg, map[string]any{"owner_id": "u9"}); err == nil {
+		t.Fatal("expected non-owner staff to be denied")
+	}
+	if err := CheckPermission(ctx, manager, "ticket", "update", reg, map[string]any{"owner_id": "u9"}); err != nil {
+		t.Fatalf("expected manager to be allowed on any ticket, got %v", err)
+	}
+
+	// Read filters translate to SQL: ownership for staff, nothing for managers
+	reg.LoadPermissions([]*metadata.Permission{{Entity: "ticket", Action: "read", Roles: []string{"staff", "manager"},
+		Conditions: []metadata.PermissionCondition{{Expression: "record.owner_id == user.id || 'manager' in user.roles"}}}})
+	filters := GetReadFilters(staff, "ticket", reg)
+	if len(filters) != 1 {
+		t.Fatalf("expected one filter for staff, got %+v", filters)
+	}
+	pb := store.NewDialect("postgres").NewParamBuilder()
+	if clause := buildWhereClause(filters[0], pb, store.NewDialect("postgres")); clause != "owner_id = $1" || pb.Params()[0] != "u1" {
+		t.Fatalf("unexpected clause: %s %v", clause, pb.Params())
+	}
+	if filters := GetReadFilters(manager, "ticket", reg); len(filters) != 0 {
+		t.Fatalf("expected no filters for manager, got %+v", filters)
+	}
+}
+
+func TestReadPolicies_UntranslatableExpressionFailsClosed(t *testing.T) {
+	reg := permissionRegistry(&metadata.Permission{Entity: "ticket", Action: "read", Roles: []string{"staff"},
+		Conditions: []metadata.PermissionCondition{{Expression: "record.owner_id == user.id || user.id in record.watchers"}}})
+	reg.Load([]*metadata.Entity{{
+		Name: "ticket", Table: "tickets",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid"},
+		Fields: []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "owner_id", Type: "string"},
+			{Name: "watchers", Type: "json"}},
+	}}, nil)
+	staff := &metadata.UserContext{ID: "u1", Roles: []string{"staff"}}
+
+	// Queries can't filter on it, so it shows nothing rather than
+	// something to be filtered out of a page afterwards
+	if filters := GetReadFilters(staff, "ticket", reg); len(filters) != 1 || !isConstClause(filters[0], clauseFalse) {
+		t.Fatalf("expected untranslatable expression to hide every row, got %+v", filters)
+	}
+	ticket := reg.GetEntity("ticket")
+	if err := ValidatePermissionExpression("record.owner_id == user.id || user.id in record.watchers", ticket); err == nil {
+		t.Fatal("expected the expression to be rejected when saved")
+	}
+	if err := ValidatePermissionExpression("record.owner_id == user.id || 'manager' in user.roles", ticket); err != nil {
+		t.Fatalf("expected a translatable expression to be accepted, got %v", err)
+	}
+
+	// Rows loaded without the query filters are checked one by one
+	rows := FilterReadRows(staff, "ticket", reg, []map[string]any{
+		{"id": "1", "owner_id": "u1", "watchers": []any{}},
+		{"id": "2", "owner_id": "u2", "watchers": []any{"u1"}},
+		{"id": "3", "owner_id": "u2", "watchers": []any{"u3"}},
+	})
+	if len(rows) != 2 || rows[0]["id"] != "1" || rows[1]["id"] != "2" {
+		t.Fatalf("expected tickets 1 and 2, got %v", rows)
+	}
+}
+
+func TestCreate_RowLevelDenyMatchesNewRecord(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	entity := &metadata.Entity{
+		Name:       "invoice",
+		Table:      "invoices",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "status", Type: "string"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := permissionRegistry(
+		&metadata.Permission{Entity: "invoice", Action: "create", Roles: []string{"staff"}},
+		&metadata.Permission{Entity: "invoice", Action: "create", Effect: "deny", Roles: []string{"staff"},
+			Conditions: []metadata.PermissionCondition{{Field: "status", Operator: "eq", Value: "paid"}}},
+	)
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := newTestApp(t, &metadata.UserContext{ID: "u1", Roles: []string{"staff"}})
+	app.Post("/api/:entity", h.Create)
+	app.Post("/api/:entity/_bulk", h.BulkCreate)
+	post := func(path, body string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	if status, out := post("/api/invoice", `{"status": "draft"}`); status != 201 {
+		t.Fatalf("expected a draft invoice to be created, got %d %v", status, out)
+	}
+	if status, out := post("/api/invoice", `{"status": "paid"}`); status != 403 {
+		t.Fatalf("expected 403 for a create matching the deny, got %d %v", status, out)
+	}
+
+	status, out := post("/api/invoice/_bulk", `{"records": [{"status": "draft"}, {"status": "paid"}]}`)
+	data, _ := out["data"].(map[string]any)
+	failed, _ := data["failed"].([]any)
+	if status != 207 || len(failed) != 1 || toInt(failed[0].(map[string]any)["index"]) != 1 {
+		t.Fatalf("expected the paid record of the batch to be denied, got %d %v", status, out)
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestPreferReturnMinimal_OmitsWriteBodies(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "reading",
+		Table:      "readings",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "value", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	send := func(method, path, prefer, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if prefer != "" {
+			req.Header.Set("Prefer", prefer)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		return resp, string(raw)
+	}
+
+	// Default (and return=representation): the full record
+	resp, body := send("POST", "/api/reading", "return=representation", `{"value": 1}`)
+	if resp.StatusCode != 201 || !bytes.Contains([]byte(body), []byte(`"value":1`)) {
+		t.Fatalf("expected the created record, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = send("POST", "/api/reading", "handling=lenient, return=minimal", `{"value": 2}`)
+	if resp.StatusCode != 201 || body != "" {
+		t.Fatalf("expected 201 with no body, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Preference-Applied") != "return=minimal" {
+		t.Fatalf("expected Preference-Applied, got %q", resp.Header.Get("Preference-Applied"))
+	}
+	location := resp.Header.Get("Location")
+	row, err := store.QueryRow(ctx, s.DB, "SELECT id FROM readings WHERE value = 2")
+	if err != nil {
+		t.Fatalf("fetch reading: %v", err)
+	}
+	if location != "/api/reading/"+row["id"].(string) {
+		t.Fatalf("expected Location of the new record, got %q", location)
+	}
+
+	resp, body = send("PUT", location, "return=minimal", `{"value": 3}`)
+	if resp.StatusCode != 204 || body != "" || resp.Header.Get("Location") != location {
+		t.Fatalf("expected 204 with the Location and no body, got %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+	if row, _ := store.QueryRow(ctx, s.DB, "SELECT value FROM readings WHERE id = '"+row["id"].(string)+"'"); toInt(row["value"]) != 3 {
+		t.Fatalf("expected the update to be stored, got %v", row)
+	}
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"rocket-backend/internal/metadata"
+)
+
+// stripProtectedFields removes the entity's protected fields for action from a
+// client's write body, unless the entity lets admins through and user is one.
+// It runs before rules, defaults and hooks, which may still set the fields.
+func stripProtectedFields(entity *metadata.Entity, user *metadata.UserContext, action string, body map[string]any) {
+	if entity.Protected == nil || (entity.Protected.AdminBypass && user != nil && user.IsAdmin()) {
+		return
+	}
+	for _, name := range entity.Protected.For(action) {
+		delete(body, name)
+	}
+}
+
+// stripProtectedChildFields applies stripProtectedFields to the rows of nested
+// one-to-many writes: rows with a primary key are updates, the rest creates.
+func stripProtectedChildFields(reg *metadata.Registry, user *metadata.UserContext, ops []*RelationWrite) {
+	for _, rw := range ops {
+		if rw.Relation.IsManyToMany() {
+			continue
+		}
+		target := reg.GetEntity(rw.Relation.Target)
+		if target == nil {
+			continue
+		}
+		for _, row := range rw.Data {
+			action := "create"
+			if row[target.PrimaryKey.Field] != nil {
+				action = "update"
+			}
+			stripProtectedFields(target, user, action, row)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+type ownerHook struct {
+	BaseHook
+}
+
+func (ownerHook) BeforeWrite(ctx context.Context, hc *HookContext) error {
+	if hc.Action == "create" && hc.Record["owner_id"] == nil {
+		hc.Record["owner_id"] = hc.User.ID
+	}
+	return nil
+}
+
+func TestHandler_ProtectedFieldsIgnoreClientInput(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "ticket",
+		Table:      "tickets",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string"},
+			{Name: "owner_id", Type: "string"},
+		},
+		Protected: &metadata.ProtectedFields{Create: []string{"owner_id"}, Update: []string{"owner_id"}, AdminBypass: true},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "ticket", Action: "create", Roles: []string{"agent"}},
+		{Entity: "ticket", Action: "read", Roles: []string{"agent"}},
+		{Entity: "ticket", Action: "update", Roles: []string{"agent"}},
+	})
+
+	hooks := NewHookRegistry()
+	hooks.Register("ticket", ownerHook{})
+	h := NewHandler(s, reg)
+	h.SetHooks(hooks)
+
+	send := func(user *metadata.UserContext, method, path string, body map[string]any) map[string]any {
+		t.Helper()
+		app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", user)
+			return c.Next()
+		})
+		app.Post("/api/:entity", h.Create)
+		app.Put("/api/:entity/:id", h.Update)
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: %d %v", method, path, resp.StatusCode, out)
+		}
+		return out["data"].(map[string]any)
+	}
+	agent := &metadata.UserContext{ID: "u1", Roles: []string{"agent"}}
+	admin := &metadata.UserContext{ID: "a1", Roles: []string{"admin"}}
+
+	// The client's owner_id is dropped; the hook still assigns one server-side
+	created := send(agent, "POST", "/api/ticket", map[string]any{"title": "Printer", "owner_id": "u2"})
+	if created["owner_id"] != "u1" {
+		t.Fatalf("expected owner_id set by the hook, got %v", created["owner_id"])
+	}
+
+	updated := send(agent, "PUT", "/api/ticket/"+created["id"].(string), map[string]any{"title": "Printer jam", "owner_id": "u2"})
+	if updated["owner_id"] != "u1" || updated["title"] != "Printer jam" {
+		t.Fatalf("expected the title to change but not owner_id, got %v", updated)
+	}
+
+	// Admins bypass protection when the entity allows it
+	assigned := send(admin, "POST", "/api/ticket", map[string]any{"title": "Laptop", "owner_id": "u2"})
+	if assigned["owner_id"] != "u2" {
+		t.Fatalf("expected admin to set owner_id, got %v", assigned["owner_id"])
+	}
+
+	entity.Protected.AdminBypass = false
+	locked := send(admin, "POST", "/api/ticket", map[string]any{"title": "Monitor", "owner_id": "u2"})
+	if locked["owner_id"] != "a1" {
+		t.Fatalf("expected owner_id protected from admins too, got %v", locked["owner_id"])
+	}
+}
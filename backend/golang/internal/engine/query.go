@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -11,6 +12,13 @@ import (
 	"rocket-backend/internal/store"
 )
 
+// DefaultPerPage is the page size of list requests without per_page, and
+// MaxPerPage the largest per_page accepted; larger values are capped.
+var (
+	DefaultPerPage = 25
+	MaxPerPage     = 100
+)
+
 type QueryPlan struct {
 	Entity   *metadata.Entity
 	Filters  []WhereClause
@@ -18,6 +26,15 @@ type QueryPlan struct {
 	Page     int
 	PerPage  int
 	Includes []string
+	// IncludeDeleted (?include_deleted=true) lists soft-deleted records too
+	IncludeDeleted bool
+	// Keyset (?cursor=, empty for the first page) pages by cursor instead of
+	// offset: Sorts end with the primary key, PerPage is the limit and After
+	// holds the decoded cursor, one value per sort (nil for the first page).
+	// Filter identifies the filters a cursor is bound to.
+	Keyset bool
+	After  []any
+	Filter string
 }
 
 type WhereClause struct {
@@ -26,6 +43,13 @@ type WhereClause struct {
 	Value    any
 }
 
+// textSearch is the value of a "search" clause, parsed from ?q=.
+type textSearch struct {
+	Fields   []string
+	Term     string
+	FullText bool
+}
+
 type OrderClause struct {
 	Field string
 	Dir   string // ASC or DESC
@@ -38,14 +62,19 @@ type QueryResult struct {
 
 // ParseQueryParams parses Fiber query parameters into a QueryPlan.
 func ParseQueryParams(c *fiber.Ctx, entity *metadata.Entity, reg *metadata.Registry) (*QueryPlan, error) {
+	return parseListParams(c.Queries(), entity, reg)
+}
+
+// parseListParams builds a QueryPlan from list parameters in query-string
+// form. Both GET list and POST search go through it.
+func parseListParams(queries map[string]string, entity *metadata.Entity, reg *metadata.Registry) (*QueryPlan, error) {
 	plan := &QueryPlan{
 		Entity:  entity,
 		Page:    1,
-		PerPage: 25,
+		PerPage: DefaultPerPage,
 	}
 
 	// Parse filters: filter[field]=val or filter[field.op]=val
-	queries := c.Queries()
 	for key, val := range queries {
 		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
 			continue
@@ -61,7 +90,17 @@ func ParseQueryParams(c *fiber.Ctx, entity *metadata.Entity, reg *metadata.Regis
 			}
 		}
 
-		coerced, err := coerceValue(entity.GetField(field), val, op)
+		var coerced any
+		var err error
+		if f := entity.GetField(field); f.Type == "geopoint" {
+			err = fmt.Errorf("geopoint fields are filtered with near[%s]=lat,lng,radius_km", field)
+		} else if f.Type == "array" {
+			coerced, err = coerceArrayFilter(f, val, op)
+		} else if op == "contains" || op == "contains_any" {
+			err = fmt.Errorf("operator %q is only supported on array fields", op)
+		} else {
+			coerced, err = coerceValue(f, val, op)
+		}
 		if err != nil {
 			return nil, &AppError{
 				Code:    "INVALID_PAYLOAD",
@@ -70,15 +109,59 @@ func ParseQueryParams(c *fiber.Ctx, entity *metadata.Entity, reg *metadata.Regis
 			}
 		}
 
-		plan.Filters = append(plan.Filters, WhereClause{
+		plan.Filters = append(plan.Filters, caseInsensitiveClause(entity.GetField(field), WhereClause{
 			Field:    field,
 			Operator: op,
 			Value:    coerced,
-		})
+		}))
+	}
+
+	// Parse radius filters: near[field]=lat,lng,radius_km
+	for key, val := range queries {
+		if !strings.HasPrefix(key, "near[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		field := key[5 : len(key)-1]
+		f := entity.GetField(field)
+		if f == nil || f.Type != "geopoint" {
+			return nil, &AppError{
+				Code:    "UNKNOWN_FIELD",
+				Status:  400,
+				Message: fmt.Sprintf("Unknown geopoint field: %s", field),
+			}
+		}
+		near, err := parseNearFilter(val)
+		if err != nil {
+			return nil, &AppError{
+				Code:    "INVALID_PAYLOAD",
+				Status:  400,
+				Message: fmt.Sprintf("Invalid near value for %s: %v", field, err),
+			}
+		}
+		plan.Filters = append(plan.Filters, WhereClause{Field: field, Operator: "near", Value: near})
+	}
+
+	// Parse text search: q=acme
+	if term := strings.TrimSpace(queries["q"]); term != "" {
+		fields := entity.SearchFields()
+		if len(fields) == 0 {
+			return nil, &AppError{
+				Code:    "INVALID_PAYLOAD",
+				Status:  400,
+				Message: fmt.Sprintf("Entity %s has no string or text fields to search", entity.Name),
+			}
+		}
+		plan.Filters = append(plan.Filters, WhereClause{Operator: "search", Value: textSearch{
+			Fields: fields, Term: term, FullText: entity.Search == "fulltext",
+		}})
 	}
 
 	// Parse sort: sort=-created_at,name
-	if sortParam := c.Query("sort"); sortParam != "" {
+	sortParam := queries["sort"]
+	if sortParam == "" {
+		sortParam = entity.DefaultSort
+	}
+	if sortParam != "" {
 		parts := strings.Split(sortParam, ",")
 		for _, part := range parts {
 			part = strings.TrimSpace(part)
@@ -100,40 +183,157 @@ func ParseQueryParams(c *fiber.Ctx, entity *metadata.Entity, reg *metadata.Regis
 	}
 
 	// Parse pagination
-	if p := c.Query("page"); p != "" {
+	if p := queries["page"]; p != "" {
 		if v, err := strconv.Atoi(p); err == nil && v > 0 {
 			plan.Page = v
 		}
 	}
-	if pp := c.Query("per_page"); pp != "" {
+	if pp := queries["per_page"]; pp != "" {
 		if v, err := strconv.Atoi(pp); err == nil && v > 0 {
 			plan.PerPage = v
-			if plan.PerPage > 100 {
-				plan.PerPage = 100
+			if plan.PerPage > MaxPerPage {
+				plan.PerPage = MaxPerPage
+			}
+		}
+	}
+
+	// limit is the page size in either mode; a cursor= param, empty for the
+	// first page, switches from page/per_page to cursor pagination
+	if v, err := strconv.Atoi(queries["limit"]); err == nil && v > 0 {
+		plan.PerPage = min(v, MaxPerPage)
+	}
+	if cur, ok := queries["cursor"]; ok {
+		plan.Keyset = true
+		plan.Page = 1
+		plan.Filter = cursorFilterKey(queries)
+		if err := keysetSorts(plan); err != nil {
+			return nil, err
+		}
+		if cur != "" {
+			after, err := decodeCursor(cur, plan)
+			if err != nil {
+				return nil, err
 			}
+			plan.After = after
 		}
 	}
 
-	// Parse includes: include=items,customer
-	if inc := c.Query("include"); inc != "" {
+	// Parse includes: include=items,customer or nested include=items.product
+	if inc := queries["include"]; inc != "" {
 		parts := strings.Split(inc, ",")
 		for _, name := range parts {
-			name = strings.TrimSpace(name)
-			rel := reg.FindRelationForEntity(name, entity.Name)
-			if rel == nil {
-				return nil, &AppError{
-					Code:    "UNKNOWN_FIELD",
-					Status:  400,
-					Message: fmt.Sprintf("Unknown include: %s", name),
-				}
-			}
-			plan.Includes = append(plan.Includes, name)
+			plan.Includes = append(plan.Includes, strings.TrimSpace(name))
+		}
+		if err := validateIncludes(reg, entity, plan.Includes); err != nil {
+			return nil, err
 		}
 	}
 
+	plan.IncludeDeleted = queries["include_deleted"] == "true"
+
 	return plan, nil
 }
 
+// SearchQuery is the body of POST /api/:entity/search. It carries the same
+// parameters as a GET list, for queries too long for a URL:
+//
+//	{"filter": {"status.in": ["draft", "sent"], "total.gte": 1000},
+//	 "sort": "-created_at", "include": ["items"], "page": 1, "per_page": 50}
+//
+// q searches text fields. limit sets the page size, and cursor ("" for the
+// first page) pages by cursor instead of by page.
+//
+// sort and include take a comma-separated string or an array.
+type SearchQuery struct {
+	Filter         map[string]any `json:"filter"`
+	Q              string         `json:"q"`
+	Sort           any            `json:"sort"`
+	Include        any            `json:"include"`
+	Page           int            `json:"page"`
+	PerPage        int            `json:"per_page"`
+	Limit          int            `json:"limit"`
+	Cursor         *string        `json:"cursor"`
+	IncludeDeleted bool           `json:"include_deleted"`
+}
+
+// ParseSearchBody parses a SearchQuery body into a QueryPlan. The body is
+// converted to its query-string form so filters are coerced exactly as on GET.
+func ParseSearchBody(body []byte, entity *metadata.Entity, reg *metadata.Registry) (*QueryPlan, error) {
+	var q SearchQuery
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &q); err != nil {
+			return nil, &AppError{Code: "INVALID_PAYLOAD", Status: 400, Message: "Invalid search body: " + err.Error()}
+		}
+	}
+
+	queries := make(map[string]string, len(q.Filter)+4)
+	for key, val := range q.Filter {
+		s, err := searchParamString(val)
+		if err != nil {
+			return nil, &AppError{Code: "INVALID_PAYLOAD", Status: 400, Message: fmt.Sprintf("Invalid filter value for %s: %v", key, err)}
+		}
+		queries["filter["+key+"]"] = s
+	}
+	for name, val := range map[string]any{"sort": q.Sort, "include": q.Include} {
+		s, err := searchParamString(val)
+		if err != nil {
+			return nil, &AppError{Code: "INVALID_PAYLOAD", Status: 400, Message: fmt.Sprintf("Invalid %s: %v", name, err)}
+		}
+		if s != "" {
+			queries[name] = s
+		}
+	}
+	if q.Page > 0 {
+		queries["page"] = strconv.Itoa(q.Page)
+	}
+	if q.PerPage > 0 {
+		queries["per_page"] = strconv.Itoa(q.PerPage)
+	}
+	if q.Q != "" {
+		queries["q"] = q.Q
+	}
+	if q.Limit > 0 {
+		queries["limit"] = strconv.Itoa(q.Limit)
+	}
+	if q.Cursor != nil {
+		queries["cursor"] = *q.Cursor
+	}
+	if q.IncludeDeleted {
+		queries["include_deleted"] = "true"
+	}
+	return parseListParams(queries, entity, reg)
+}
+
+// searchParamString renders a JSON scalar or array of scalars the way it
+// would appear in a query string; arrays become comma-separated lists.
+func searchParamString(v any) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case []any:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			if _, nested := item.([]any); nested {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			s, err := searchParamString(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or array")
+	}
+}
+
 // BuildSelectSQL builds a parameterized SELECT statement from the query plan.
 func BuildSelectSQL(plan *QueryPlan, dialect store.Dialect) QueryResult {
 	pb := dialect.NewParamBuilder()
@@ -147,7 +347,7 @@ func BuildSelectSQL(plan *QueryPlan, dialect store.Dialect) QueryResult {
 	var where []string
 
 	// Soft delete filter
-	if entity.SoftDelete {
+	if entity.SoftDelete && !plan.IncludeDeleted {
 		where = append(where, "deleted_at IS NULL")
 	}
 
@@ -157,6 +357,11 @@ func BuildSelectSQL(plan *QueryPlan, dialect store.Dialect) QueryResult {
 		where = append(where, clause)
 	}
 
+	// Records after the cursor
+	if plan.Keyset && plan.After != nil {
+		where = append(where, keysetClause(plan, pb, dialect))
+	}
+
 	sql := fmt.Sprintf("SELECT %s FROM %s", columns, entity.Table)
 	if len(where) > 0 {
 		sql += " WHERE " + strings.Join(where, " AND ")
@@ -171,7 +376,12 @@ func BuildSelectSQL(plan *QueryPlan, dialect store.Dialect) QueryResult {
 		sql += " ORDER BY " + strings.Join(orderParts, ", ")
 	}
 
-	// Pagination
+	// Pagination; a cursor page fetches one extra record to tell whether
+	// another page follows
+	if plan.Keyset {
+		sql += fmt.Sprintf(" LIMIT %s", pb.Add(plan.PerPage+1))
+		return QueryResult{SQL: sql, Params: pb.Params()}
+	}
 	limit := pb.Add(plan.PerPage)
 	offset := pb.Add((plan.Page - 1) * plan.PerPage)
 	sql += fmt.Sprintf(" LIMIT %s OFFSET %s", limit, offset)
@@ -185,7 +395,7 @@ func BuildCountSQL(plan *QueryPlan, dialect store.Dialect) QueryResult {
 	entity := plan.Entity
 
 	var where []string
-	if entity.SoftDelete {
+	if entity.SoftDelete && !plan.IncludeDeleted {
 		where = append(where, "deleted_at IS NULL")
 	}
 	for _, f := range plan.Filters {
@@ -193,7 +403,7 @@ func BuildCountSQL(plan *QueryPlan, dialect store.Dialect) QueryResult {
 		where = append(where, clause)
 	}
 
-	sql := fmt.Sprintf("SELECT COUNT(*) FROM %s", entity.Table)
+	sql := fmt.Sprintf("SELECT COUNT(*) AS count FROM %s", entity.Table)
 	if len(where) > 0 {
 		sql += " WHERE " + strings.Join(where, " AND ")
 	}
@@ -229,11 +439,96 @@ func buildWhereClause(f WhereClause, pb store.ParamBuilder, dialect store.Dialec
 		return dialect.NotInExpr(f.Field, pb, values)
 	case "like":
 		return fmt.Sprintf("%s LIKE %s", f.Field, pb.Add(f.Value))
+	case "contains":
+		return dialect.ArrayContainsExpr(f.Field, pb, fmt.Sprintf("%v", f.Value))
+	case "near":
+		near, ok := f.Value.(geoNear)
+		if !ok {
+			return "1 = 0"
+		}
+		return dialect.GeoWithinExpr(f.Field, pb, near.Lat, near.Lng, near.RadiusKm)
+	case "search":
+		ts, ok := f.Value.(textSearch)
+		if !ok {
+			return "1 = 0"
+		}
+		if ts.FullText {
+			return dialect.FullTextMatchExpr(ts.Fields, pb, ts.Term)
+		}
+		return dialect.TextMatchExpr(ts.Fields, pb, ts.Term)
+	case "contains_any":
+		values, _ := f.Value.([]string)
+		return dialect.ArrayOverlapsExpr(f.Field, pb, values)
+	case "not_all":
+		// Negated conjunction of nested clauses, used by row-level deny policies.
+		// COALESCE keeps rows whose compared columns are NULL visible.
+		nested, _ := f.Value.([]WhereClause)
+		if len(nested) == 0 {
+			return "1 = 1"
+		}
+		parts := make([]string, 0, len(nested))
+		for _, n := range nested {
+			parts = append(parts, buildWhereClause(n, pb, dialect))
+		}
+		return fmt.Sprintf("NOT COALESCE((%s), FALSE)", strings.Join(parts, " AND "))
+	case "in_join":
+		// Membership through a many-to-many join table, used by the related
+		// records endpoint; never parsed from a request
+		j, ok := f.Value.(joinFilter)
+		if !ok {
+			return "1 = 0"
+		}
+		return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s = %s)",
+			f.Field, j.TargetJoinKey, j.JoinTable, j.SourceJoinKey, pb.Add(j.SourceID))
+	case "and", "or":
+		// Nested group produced by expression-based permission conditions.
+		// An empty "and" is always true, an empty "or" always false.
+		nested, _ := f.Value.([]WhereClause)
+		if len(nested) == 0 {
+			if f.Operator == "and" {
+				return "1 = 1"
+			}
+			return "1 = 0"
+		}
+		parts := make([]string, 0, len(nested))
+		for _, n := range nested {
+			parts = append(parts, buildWhereClause(n, pb, dialect))
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(f.Operator)+" ") + ")"
 	default:
 		return fmt.Sprintf("%s = %s", f.Field, pb.Add(f.Value))
 	}
 }
 
+// caseInsensitiveClause rewrites equality/like filters on case-insensitive fields
+// to compare LOWER(column) against the lowercased value, matching the LOWER() unique index.
+func caseInsensitiveClause(field *metadata.Field, wc WhereClause) WhereClause {
+	if field == nil || !field.IsCaseInsensitive() {
+		return wc
+	}
+	switch wc.Operator {
+	case "eq", "neq", "like", "in", "not_in":
+	default:
+		return wc
+	}
+	wc.Field = "LOWER(" + wc.Field + ")"
+	switch v := wc.Value.(type) {
+	case string:
+		wc.Value = strings.ToLower(v)
+	case []any:
+		lowered := make([]any, len(v))
+		for i, item := range v {
+			if str, ok := item.(string); ok {
+				lowered[i] = strings.ToLower(str)
+			} else {
+				lowered[i] = item
+			}
+		}
+		wc.Value = lowered
+	}
+	return wc
+}
+
 // parseFilterKey splits "total.gte" into ("total", "gte") or "status" into ("status", "eq").
 func parseFilterKey(key string) (string, string) {
 	parts := strings.SplitN(key, ".", 2)
@@ -263,7 +558,7 @@ func coerceValue(field *metadata.Field, val string, op string) (any, error) {
 }
 
 func coerceSingleValue(field *metadata.Field, val string) (any, error) {
-	switch field.Type {
+	switch field.StorageType() {
 	case "int":
 		return strconv.Atoi(val)
 	case "bigint":
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestSearch_MatchesEquivalentGetList(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "invoice",
+		Table:      "invoices",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "number", Type: "string"},
+			{Name: "status", Type: "string"},
+			{Name: "total", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+	app.Post("/api/:entity", h.Create)
+	app.Post("/api/:entity/search", h.Search)
+
+	do := func(method, path string, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	for i, inv := range []struct {
+		status string
+		total  int
+	}{{"draft", 500}, {"sent", 1500}, {"paid", 2500}, {"sent", 3000}, {"draft", 1200}, {"void", 9000}} {
+		body := map[string]any{"number": string(rune('A' + i)), "status": inv.status, "total": inv.total}
+		if status, out := do("POST", "/api/invoice", body); status != 201 {
+			t.Fatalf("create: %d %v", status, out)
+		}
+	}
+
+	status, get := do("GET", "/api/invoice?filter[status.in]=draft,sent&filter[total.gte]=1000&sort=-total&page=1&per_page=2", nil)
+	if status != 200 {
+		t.Fatalf("get list: %d %v", status, get)
+	}
+	status, post := do("POST", "/api/invoice/search", map[string]any{
+		"filter":   map[string]any{"status.in": []any{"draft", "sent"}, "total.gte": 1000},
+		"sort":     []any{"-total"},
+		"page":     1,
+		"per_page": 2,
+	})
+	if status != 200 {
+		t.Fatalf("search: %d %v", status, post)
+	}
+	if !reflect.DeepEqual(get, post) {
+		t.Fatalf("expected search to match the GET list\nGET:  %v\nPOST: %v", get, post)
+	}
+	if rows := post["data"].([]any); len(rows) != 2 || rows[0].(map[string]any)["number"] != "D" {
+		t.Fatalf("expected the two largest matching invoices, got %v", rows)
+	}
+	if total := toInt(post["meta"].(map[string]any)["total"]); total != 3 {
+		t.Fatalf("expected 3 matches in total, got %d", total)
+	}
+
+	// Errors are reported the same way as on GET
+	if status, out := do("POST", "/api/invoice/search", map[string]any{"filter": map[string]any{"missing": "x"}}); status != 400 {
+		t.Fatalf("expected an unknown filter field to be rejected, got %d %v", status, out)
+	}
+	if status, out := do("POST", "/api/invoice/search", map[string]any{"filter": map[string]any{"total": map[string]any{"gte": 1}}}); status != 400 {
+		t.Fatalf("expected an object filter value to be rejected, got %d %v", status, out)
+	}
+}
+
+func TestList_TextSearch(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "company",
+		Table:      "companies",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "name", Type: "string"},
+			{Name: "notes", Type: "text", Nullable: true},
+			{Name: "employees", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, row := range [][]any{
+		{"c1", "Acme Corp", nil, 10},
+		{"c2", "Globex", "acquired by ACME", 20},
+		{"c3", "Initech", "100% remote", 30},
+		{"c4", "Umbrella", "1000 staff", 40},
+	} {
+		if _, err := store.Exec(ctx, s.DB, "INSERT INTO companies (id, name, notes, employees) VALUES (?1, ?2, ?3, ?4)", row...); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+
+	ids := func(path string) (int, []any) {
+		req, _ := http.NewRequest("GET", path, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		var ids []any
+		if data, ok := out["data"].([]any); ok {
+			for _, row := range data {
+				ids = append(ids, row.(map[string]any)["id"])
+			}
+		}
+		return resp.StatusCode, ids
+	}
+
+	if _, got := ids("/api/company?q=acme&sort=id"); !reflect.DeepEqual(got, []any{"c1", "c2"}) {
+		t.Fatalf("expected a case-insensitive match in name or notes, got %v", got)
+	}
+	if _, got := ids("/api/company?q=100%25&sort=id"); !reflect.DeepEqual(got, []any{"c3"}) {
+		t.Fatalf("expected %% matched literally, got %v", got)
+	}
+	if _, got := ids("/api/company?q=acme&filter[employees.gt]=15"); !reflect.DeepEqual(got, []any{"c2"}) {
+		t.Fatalf("expected q combined with filters, got %v", got)
+	}
+
+	entity.SearchableFields = []string{"name"}
+	if _, got := ids("/api/company?q=acme"); !reflect.DeepEqual(got, []any{"c1"}) {
+		t.Fatalf("expected only searchable_fields searched, got %v", got)
+	}
+
+	// SQLite has no full-text search and matches substrings instead
+	entity.Search = "fulltext"
+	if _, got := ids("/api/company?q=acme"); !reflect.DeepEqual(got, []any{"c1"}) {
+		t.Fatalf("expected the fulltext fallback to match, got %v", got)
+	}
+}
+
+func TestBuildSelectSQL_TextSearchIsParameterized(t *testing.T) {
+	entity := &metadata.Entity{
+		Name:       "company",
+		Table:      "companies",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields:     []metadata.Field{{Name: "id", Type: "string"}, {Name: "name", Type: "string"}, {Name: "notes", Type: "text"}},
+	}
+	term := "x' OR 1=1 --"
+	plan, err := parseListParams(map[string]string{"q": term}, entity, metadata.NewRegistry())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	qr := BuildSelectSQL(plan, &store.PostgresDialect{})
+	want := `SELECT id, name, notes FROM companies WHERE (id ILIKE $1 ESCAPE '\' OR name ILIKE $1 ESCAPE '\' OR notes ILIKE $1 ESCAPE '\') LIMIT $2 OFFSET $3`
+	if qr.SQL != want || qr.Params[0] != "%"+term+"%" {
+		t.Fatalf("unexpected SQL %q with %v", qr.SQL, qr.Params)
+	}
+
+	entity.Search = "fulltext"
+	plan, _ = parseListParams(map[string]string{"q": term}, entity, metadata.NewRegistry())
+	qr = BuildSelectSQL(plan, &store.PostgresDialect{})
+	if !strings.Contains(qr.SQL, "plainto_tsquery('english', $1)") || qr.Params[0] != term {
+		t.Fatalf("expected the term as a parameter, got %v", qr.Params)
+	}
+}
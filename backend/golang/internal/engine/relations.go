@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// joinFilter is the value of an "in_join" WhereClause: the target keys linked
+// to SourceID in a many-to-many join table.
+type joinFilter struct {
+	JoinTable, SourceJoinKey, TargetJoinKey string
+	SourceID                                any
+}
+
+// ListRelated handles GET /api/:entity/:id/related/:relation. It lists the
+// records linked to one record through a relation of its entity, with the
+// usual list query parameters (filter, sort, page, per_page, include). The
+// source record must be readable; the related records go through the target
+// entity's read permissions and scope like any list.
+func (h *Handler) ListRelated(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", "record.related")
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	entity, err := h.resolveEntity(c)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	id := c.Params("id")
+	span.SetEntity(entity.Name, id)
+
+	relName := c.Params("relation")
+	rel := h.registry.FindRelationForEntity(relName, entity.Name)
+	if rel == nil || rel.Source != entity.Name {
+		span.SetStatus("error")
+		return NewAppError("UNKNOWN_RELATION", 404, fmt.Sprintf("Unknown relation %s on %s", relName, entity.Name))
+	}
+	target := h.registry.GetEntity(rel.Target)
+	if target == nil {
+		span.SetStatus("error")
+		return fmt.Errorf("unknown target entity: %s", rel.Target)
+	}
+
+	source, err := fetchRecord(c.Context(), h.reader(c), entity, id, h.store.Dialect)
+	if err != nil {
+		span.SetStatus("error")
+		if errors.Is(err, store.ErrNotFound) {
+			return respondError(c, NotFoundError(entity.Name, id))
+		}
+		return fmt.Errorf("fetch %s/%s: %w", entity.Name, id, err)
+	}
+	user := getUser(c)
+	if ok, err := h.inScope(c.Context(), entity, user, source); err != nil || !ok {
+		span.SetStatus("error")
+		if err != nil {
+			return err
+		}
+		return respondError(c, NotFoundError(entity.Name, id))
+	}
+	if err := CheckPermission(c.Context(), user, entity.Name, "read", h.registry, source); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+
+	var link WhereClause
+	if rel.IsManyToMany() {
+		link = WhereClause{Field: target.PrimaryKey.Field, Operator: "in_join", Value: joinFilter{
+			JoinTable: rel.JoinTable, SourceJoinKey: rel.SourceJoinKey, TargetJoinKey: rel.TargetJoinKey,
+			SourceID: source[entity.PrimaryKey.Field],
+		}}
+	} else {
+		link = WhereClause{Field: rel.TargetKey, Operator: "eq", Value: source[rel.SourceKey]}
+	}
+	return h.listRecords(c, span, target, func(target *metadata.Entity) (*QueryPlan, error) {
+		plan, err := ParseQueryParams(c, target, h.registry)
+		if err != nil {
+			return nil, err
+		}
+		plan.Filters = append(plan.Filters, link)
+		return plan, nil
+	}, entity.Name)
+}
+
+// AttachRelated handles POST /api/:entity/:id/relations/:relation with body
+// {"id": <target id>}. It links the target through the many-to-many join
+// table and returns the updated related list. Attaching an already linked
+// record is a no-op.
+func (h *Handler) AttachRelated(c *fiber.Ctx) error {
+	var body struct {
+		ID any `json:"id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return respondError(c, NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body"))
+	}
+	if body.ID == nil || body.ID == "" {
+		return respondError(c, ValidationError([]ErrorDetail{{Field: "id", Rule: "required", Message: "id is required"}}))
+	}
+	return h.writeRelation(c, "relation.attach", fmt.Sprintf("%v", body.ID), func(ctx context.Context, tx store.Querier, rel *metadata.Relation, sourceID, targetID any) error {
+		pb := h.store.Dialect.NewParamBuilder()
+		sql := fmt.Sprintf(
+			"INSERT INTO %s (%s, %s) VALUES (%s, %s) ON CONFLICT DO NOTHING",
+			rel.JoinTable, rel.SourceJoinKey, rel.TargetJoinKey, pb.Add(sourceID), pb.Add(targetID))
+		if _, err := store.Exec(ctx, tx, sql, pb.Params()...); err != nil {
+			return fmt.Errorf("insert join row in %s: %w", rel.JoinTable, err)
+		}
+		return nil
+	})
+}
+
+// DetachRelated handles DELETE /api/:entity/:id/relations/:relation/:targetId.
+// It removes the join row (the target record itself is kept) and returns the
+// updated related list.
+func (h *Handler) DetachRelated(c *fiber.Ctx) error {
+	return h.writeRelation(c, "relation.detach", c.Params("targetId"), func(ctx context.Context, tx store.Querier, rel *metadata.Relation, sourceID, targetID any) error {
+		pb := h.store.Dialect.NewParamBuilder()
+		sql := fmt.Sprintf("DELETE FROM %s WHERE %s = %s AND %s = %s",
+			rel.JoinTable, rel.SourceJoinKey, pb.Add(sourceID), rel.TargetJoinKey, pb.Add(targetID))
+		affected, err := store.Exec(ctx, tx, sql, pb.Params()...)
+		if err != nil {
+			return fmt.Errorf("delete join row in %s: %w", rel.JoinTable, err)
+		}
+		if affected == 0 {
+			return NewAppError("NOT_FOUND", 404, fmt.Sprintf("%s %v is not linked through %s", rel.Target, targetID, rel.Name))
+		}
+		return nil
+	})
+}
+
+// writeRelation resolves both ends of a many-to-many link, checks update
+// permission on the source record and read permission on the target record,
+// runs write in a transaction and responds with the related list.
+func (h *Handler) writeRelation(c *fiber.Ctx, spanName, targetParam string, write func(ctx context.Context, tx store.Querier, rel *metadata.Relation, sourceID, targetID any) error) error {
+	ctx := c.UserContext()
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "handler", spanName)
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	entity, err := h.resolveEntity(c)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	id := c.Params("id")
+	span.SetEntity(entity.Name, id)
+
+	relName := c.Params("relation")
+	rel := h.registry.GetRelation(relName)
+	if rel == nil || rel.Source != entity.Name {
+		span.SetStatus("error")
+		return NewAppError("UNKNOWN_RELATION", 404, fmt.Sprintf("Unknown relation %s on %s", relName, entity.Name))
+	}
+	if !rel.IsManyToMany() {
+		span.SetStatus("error")
+		return NewAppError("INVALID_RELATION", 400, fmt.Sprintf("Relation %s is %s; only many_to_many relations can be attached and detached", rel.Name, rel.Type))
+	}
+	target := h.registry.GetEntity(rel.Target)
+	if target == nil {
+		span.SetStatus("error")
+		return fmt.Errorf("unknown target entity: %s", rel.Target)
+	}
+
+	source, err := fetchRecord(c.Context(), h.store.DB, entity, id, h.store.Dialect)
+	if err != nil {
+		span.SetStatus("error")
+		if errors.Is(err, store.ErrNotFound) {
+			return respondError(c, NotFoundError(entity.Name, id))
+		}
+		return fmt.Errorf("fetch %s/%s: %w", entity.Name, id, err)
+	}
+	linked, err := fetchRecord(c.Context(), h.store.DB, target, targetParam, h.store.Dialect)
+	if err != nil {
+		span.SetStatus("error")
+		if errors.Is(err, store.ErrNotFound) {
+			return respondError(c, NotFoundError(target.Name, targetParam))
+		}
+		return fmt.Errorf("fetch %s/%s: %w", target.Name, targetParam, err)
+	}
+
+	user := getUser(c)
+	for _, end := range []struct {
+		entity *metadata.Entity
+		id     string
+		record map[string]any
+	}{{entity, id, source}, {target, targetParam, linked}} {
+		if ok, err := h.inScope(c.Context(), end.entity, user, end.record); err != nil || !ok {
+			span.SetStatus("error")
+			if err != nil {
+				return err
+			}
+			return respondError(c, NotFoundError(end.entity.Name, end.id))
+		}
+	}
+	if err := CheckPermission(c.Context(), user, entity.Name, "update", h.registry, source); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	if err := CheckPermission(c.Context(), user, target.Name, "read", h.registry, linked); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	if err := checkWriteMode(c, user, entity, "update"); err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	reservation, err := h.checkWriteLimit(c, entity, user, 1)
+	if err != nil {
+		span.SetStatus("error")
+		return err
+	}
+	defer reservation.release()
+
+	sourceID := source[entity.PrimaryKey.Field]
+	targetID := linked[target.PrimaryKey.Field]
+	err = h.store.Tx(c.Context(), func(tx store.Querier) error {
+		return write(c.Context(), tx, rel, sourceID, targetID)
+	})
+	if err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return handleWriteError(c, err)
+	}
+	invalidateEntityCache(h.registry, entity.Name)
+	invalidateEntityCache(h.registry, target.Name)
+	h.markWrite(c)
+	reservation.keep(1)
+
+	rows := []map[string]any{source}
+	if _, err := loadManyToMany(c.Context(), h.store.DB, h.store.Dialect, h.registry, rel, rows, entity.PrimaryKey.Field, []any{sourceID}, rel.Name); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return fmt.Errorf("load %s: %w", rel.Name, err)
+	}
+	related, _ := rows[0][rel.Name].([]map[string]any)
+	related = FilterReadRows(user, target.Name, h.registry, related)
+	scoped := related[:0]
+	for _, row := range related {
+		if ok, _ := h.inScope(c.Context(), target, user, row); ok {
+			scoped = append(scoped, row)
+		}
+	}
+	related = scoped
+	if related == nil {
+		related = []map[string]any{}
+	}
+
+	span.SetStatus("ok")
+	return c.JSON(fiber.Map{"data": related})
+}
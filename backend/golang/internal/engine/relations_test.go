@@ -0,0 +1,373 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestAttachDetachRelated_ManyToMany(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	post := &metadata.Entity{
+		Name:       "post",
+		Table:      "posts",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string"},
+		},
+	}
+	tag := &metadata.Entity{
+		Name:       "tag",
+		Table:      "tags",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "name", Type: "string"},
+		},
+	}
+	rel := &metadata.Relation{
+		Name: "tags", Type: "many_to_many", Source: "post", Target: "tag", SourceKey: "id",
+		JoinTable: "post_tags", SourceJoinKey: "post_id", TargetJoinKey: "tag_id", Ownership: "none", OnDelete: "detach",
+	}
+	migrator := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{post, tag} {
+		if err := migrator.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	if err := migrator.MigrateJoinTable(ctx, s.DB, rel, post, tag); err != nil {
+		t.Fatalf("migrate join table: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{post, tag}, []*metadata.Relation{rel})
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "post", Action: "update", Roles: []string{"editor"}},
+		{Entity: "tag", Action: "read", Roles: []string{"editor"}},
+		{Entity: "post", Action: "update", Roles: []string{"writer"}},
+	})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{c.Get("X-Test-Role")}})
+		return c.Next()
+	})
+	app.Post("/api/:entity/:id/relations/:relation", h.AttachRelated)
+	app.Delete("/api/:entity/:id/relations/:relation/:targetId", h.DetachRelated)
+
+	do := func(method, path, role string, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Role", role)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+	insert := func(e *metadata.Entity, fields map[string]any) string {
+		plan, verrs := PlanWrite(e, reg, fields, nil)
+		if len(verrs) > 0 {
+			t.Fatalf("plan %s: %v", e.Name, verrs)
+		}
+		rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+		if err != nil {
+			t.Fatalf("insert %s: %v", e.Name, err)
+		}
+		return rec["id"].(string)
+	}
+	postID := insert(post, map[string]any{"title": "Hello"})
+	goID := insert(tag, map[string]any{"name": "go"})
+
+	path := "/api/post/" + postID + "/relations/tags"
+	for i := 0; i < 2; i++ {
+		status, out := do("POST", path, "editor", map[string]any{"id": goID})
+		if status != 200 {
+			t.Fatalf("attach %d: expected 200, got %d %v", i, status, out)
+		}
+		if list := out["data"].([]any); len(list) != 1 || list[0].(map[string]any)["name"] != "go" {
+			t.Fatalf("attach %d: expected the tag to be linked once, got %v", i, list)
+		}
+	}
+
+	if status, out := do("POST", path, "writer", map[string]any{"id": goID}); status != 403 {
+		t.Fatalf("expected attach without read on the target to be forbidden, got %d %v", status, out)
+	}
+	if status, out := do("POST", path, "editor", map[string]any{"id": "00000000-0000-0000-0000-000000000000"}); status != 404 {
+		t.Fatalf("expected a missing target to be rejected, got %d %v", status, out)
+	}
+	if status, out := do("POST", "/api/post/"+postID+"/relations/missing", "editor", map[string]any{"id": goID}); status != 404 {
+		t.Fatalf("expected an unknown relation to be rejected, got %d %v", status, out)
+	}
+
+	status, out := do("DELETE", path+"/"+goID, "editor", nil)
+	if status != 200 || len(out["data"].([]any)) != 0 {
+		t.Fatalf("detach: expected an empty related list, got %d %v", status, out)
+	}
+	if status, out := do("DELETE", path+"/"+goID, "editor", nil); status != 404 {
+		t.Fatalf("expected detaching an unlinked tag to be 404, got %d %v", status, out)
+	}
+	if _, err := store.QueryRow(ctx, s.DB, "SELECT id FROM tags WHERE id = ?1", goID); err != nil {
+		t.Fatalf("expected the tag itself to be kept: %v", err)
+	}
+}
+
+func TestListRelated_PaginatesCustomerOrders(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	customer := &metadata.Entity{
+		Name:       "customer",
+		Table:      "customers",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "name", Type: "string"}},
+	}
+	order := &metadata.Entity{
+		Name:       "order",
+		Table:      "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "customer_id", Type: "uuid"},
+			{Name: "number", Type: "int"},
+			{Name: "status", Type: "string"},
+		},
+	}
+	segment := &metadata.Entity{
+		Name:       "segment",
+		Table:      "segments",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "name", Type: "string"}},
+	}
+	orders := &metadata.Relation{
+		Name: "orders", Type: "one_to_many", Source: "customer", Target: "order",
+		SourceKey: "id", TargetKey: "customer_id", Ownership: "source", OnDelete: "cascade",
+	}
+	segments := &metadata.Relation{
+		Name: "segments", Type: "many_to_many", Source: "customer", Target: "segment", SourceKey: "id",
+		JoinTable: "customer_segments", SourceJoinKey: "customer_id", TargetJoinKey: "segment_id", Ownership: "none", OnDelete: "detach",
+	}
+	migrator := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{customer, order, segment} {
+		if err := migrator.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	if err := migrator.MigrateJoinTable(ctx, s.DB, segments, customer, segment); err != nil {
+		t.Fatalf("migrate join table: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{customer, order, segment}, []*metadata.Relation{orders, segments})
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "customer", Action: "read", Roles: []string{"sales", "viewer"}},
+		{Entity: "order", Action: "read", Roles: []string{"sales"}},
+		{Entity: "segment", Action: "read", Roles: []string{"sales"}},
+	})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{c.Get("X-Test-Role")}})
+		return c.Next()
+	})
+	app.Get("/api/:entity/:id/related/:relation", h.ListRelated)
+
+	get := func(path, role string) (int, map[string]any) {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-Test-Role", role)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	insert := func(e *metadata.Entity, fields map[string]any) string {
+		plan, verrs := PlanWrite(e, reg, fields, nil)
+		if len(verrs) > 0 {
+			t.Fatalf("plan %s: %v", e.Name, verrs)
+		}
+		rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+		if err != nil {
+			t.Fatalf("insert %s: %v", e.Name, err)
+		}
+		return rec["id"].(string)
+	}
+	acme := insert(customer, map[string]any{"name": "Acme"})
+	other := insert(customer, map[string]any{"name": "Other"})
+	for i := 1; i <= 5; i++ {
+		status := "open"
+		if i%2 == 0 {
+			status = "shipped"
+		}
+		insert(order, map[string]any{"customer_id": acme, "number": i, "status": status})
+	}
+	insert(order, map[string]any{"customer_id": other, "number": 99, "status": "open"})
+
+	numbers := func(out map[string]any) []int {
+		var got []int
+		for _, row := range out["data"].([]any) {
+			got = append(got, toInt(row.(map[string]any)["number"]))
+		}
+		return got
+	}
+
+	status, out := get("/api/customer/"+acme+"/related/orders?sort=number&per_page=2&page=2", "sales")
+	if status != 200 {
+		t.Fatalf("expected 200, got %d %v", status, out)
+	}
+	meta := out["meta"].(map[string]any)
+	if got := numbers(out); len(got) != 2 || got[0] != 3 || got[1] != 4 || toInt(meta["total"]) != 5 {
+		t.Fatalf("expected orders 3 and 4 of 5, got %v (meta %v)", got, meta)
+	}
+	_, out = get("/api/customer/"+acme+"/related/orders?filter[status]=open&sort=-number", "sales")
+	if got := numbers(out); len(got) != 3 || got[0] != 5 || got[2] != 1 {
+		t.Fatalf("expected Acme's open orders only, got %v", got)
+	}
+
+	if status, _ := get("/api/customer/"+acme+"/related/orders", "viewer"); status != 403 {
+		t.Fatalf("expected read permission on order to be enforced, got %d", status)
+	}
+	if status, _ := get("/api/customer/missing/related/orders", "sales"); status != 404 {
+		t.Fatalf("expected 404 for an unknown customer, got %d", status)
+	}
+	if status, _ := get("/api/customer/"+acme+"/related/invoices", "sales"); status != 404 {
+		t.Fatalf("expected 404 for an unknown relation, got %d", status)
+	}
+
+	// many_to_many goes through the join table
+	vip := insert(segment, map[string]any{"name": "vip"})
+	insert(segment, map[string]any{"name": "churned"})
+	if _, err := store.Exec(ctx, s.DB, "INSERT INTO customer_segments (customer_id, segment_id) VALUES (?1, ?2)", acme, vip); err != nil {
+		t.Fatalf("link segment: %v", err)
+	}
+	_, out = get("/api/customer/"+acme+"/related/segments", "sales")
+	rows := out["data"].([]any)
+	if len(rows) != 1 || rows[0].(map[string]any)["name"] != "vip" {
+		t.Fatalf("expected only the linked segment, got %v", rows)
+	}
+}
+
+func TestCreate_SoftRelationChecksReference(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	customer := &metadata.Entity{
+		Name:       "customer",
+		Table:      "customers",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "name", Type: "string"},
+		},
+	}
+	order := &metadata.Entity{
+		Name:       "order",
+		Table:      "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "customer_id", Type: "uuid"},
+		},
+	}
+	rel := &metadata.Relation{
+		Name: "orders", Type: "one_to_many", Source: "customer", Target: "order", SourceKey: "id",
+		TargetKey: "customer_id", Ownership: "source", OnDelete: "restrict", Soft: true,
+	}
+	migrator := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{customer, order} {
+		if err := migrator.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{customer, order}, []*metadata.Relation{rel})
+	h := NewHandler(s, reg)
+
+	// No constraint at the database level: an orphan row goes straight in
+	if _, err := store.Exec(ctx, s.DB, "INSERT INTO orders (id, customer_id) VALUES (?1, ?2)",
+		store.GenerateUUID(), store.GenerateUUID()); err != nil {
+		t.Fatalf("expected no FK constraint on orders.customer_id: %v", err)
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	create := func(entity string, body map[string]any) (int, map[string]any) {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/"+entity, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("POST /api/%s: %v", entity, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	// Through the API the engine rejects a customer that doesn't exist
+	status, out := create("order", map[string]any{"customer_id": store.GenerateUUID()})
+	if status != 422 {
+		t.Fatalf("expected 422 for a missing customer, got %d %v", status, out)
+	}
+	details := out["error"].(map[string]any)["details"].([]any)
+	if d := details[0].(map[string]any); d["field"] != "customer_id" || d["rule"] != "reference" {
+		t.Fatalf("expected a reference error on customer_id, got %v", d)
+	}
+
+	status, out = create("customer", map[string]any{"name": "Acme"})
+	if status != 201 {
+		t.Fatalf("create customer: %d %v", status, out)
+	}
+	customerID := out["data"].(map[string]any)["id"]
+	if status, out = create("order", map[string]any{"customer_id": customerID}); status != 201 {
+		t.Fatalf("expected an order for an existing customer, got %d %v", status, out)
+	}
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestSoftDelete_IncludeDeletedAndRestore(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "note",
+		Table:      "notes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		SoftDelete: true,
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "title", Type: "string"}},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+	app.Get("/api/:entity/:id", h.GetByID)
+	app.Post("/api/:entity", h.Create)
+	app.Delete("/api/:entity/:id", h.Delete)
+	app.Post("/api/:entity/:id/restore", h.Restore)
+
+	send := func(method, path string, body map[string]any) (int, map[string]any) {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	listed := func(query string) int {
+		t.Helper()
+		status, out := send("GET", "/api/note"+query, nil)
+		if status != 200 {
+			t.Fatalf("list %s: %d %v", query, status, out)
+		}
+		return len(out["data"].([]any))
+	}
+
+	for _, title := range []string{"keep", "remove"} {
+		if status, out := send("POST", "/api/note", map[string]any{"title": title}); status != 201 {
+			t.Fatalf("create: %d %v", status, out)
+		}
+	}
+	_, out := send("GET", "/api/note?filter[title]=remove", nil)
+	id := out["data"].([]any)[0].(map[string]any)["id"].(string)
+	if status, out := send("DELETE", "/api/note/"+id, nil); status != 200 {
+		t.Fatalf("delete: %d %v", status, out)
+	}
+
+	if n := listed(""); n != 1 {
+		t.Fatalf("expected the deleted note hidden, got %d notes", n)
+	}
+	if n := listed("?include_deleted=true"); n != 2 {
+		t.Fatalf("expected include_deleted to list both notes, got %d", n)
+	}
+	if status, _ := send("GET", "/api/note/"+id, nil); status != 404 {
+		t.Fatalf("expected 404 for a deleted note, got %d", status)
+	}
+	status, out := send("GET", "/api/note/"+id+"?include_deleted=true", nil)
+	if status != 200 || out["data"].(map[string]any)["deleted_at"] == nil {
+		t.Fatalf("expected the deleted note with its deleted_at, got %d %v", status, out)
+	}
+
+	status, out = send("POST", "/api/note/"+id+"/restore", nil)
+	if status != 200 || out["data"].(map[string]any)["deleted_at"] != nil {
+		t.Fatalf("restore: expected the note back without deleted_at, got %d %v", status, out)
+	}
+	if n := listed(""); n != 2 {
+		t.Fatalf("expected the restored note listed, got %d notes", n)
+	}
+	if status, _ := send("POST", "/api/note/00000000-0000-0000-0000-000000000000/restore", nil); status != 404 {
+		t.Fatalf("expected 404 restoring a missing note, got %d", status)
+	}
+}
+
+func TestSoftDelete_IncludeDeletedNeedsDeletePermission(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	entity := &metadata.Entity{
+		Name:       "note",
+		Table:      "notes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		SoftDelete: true,
+		Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "title", Type: "string"}},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "note", Action: "read", Roles: []string{"reader", "editor"}},
+		{Entity: "note", Action: "delete", Roles: []string{"editor"}},
+	})
+	h := NewHandler(s, reg)
+
+	app := newTestApp(t, nil)
+	app.Use(testUserFromHeaders)
+	app.Get("/api/:entity", h.List)
+	app.Get("/api/:entity/distinct", h.Distinct)
+	app.Get("/api/:entity/:id", h.GetByID)
+
+	get := func(path, role string) int {
+		t.Helper()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-Test-Role", role)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp.StatusCode
+	}
+
+	id := "00000000-0000-0000-0000-000000000001"
+	for _, path := range []string{
+		"/api/note?include_deleted=true",
+		"/api/note/" + id + "?include_deleted=true",
+		"/api/note/distinct?field=title&include_deleted=true",
+	} {
+		if status := get(path, "reader"); status != 403 {
+			t.Errorf("%s: expected 403 for a reader, got %d", path, status)
+		}
+	}
+	if status := get("/api/note?include_deleted=true", "editor"); status != 200 {
+		t.Errorf("expected an editor to list deleted notes, got %d", status)
+	}
+	if status := get("/api/note", "reader"); status != 200 {
+		t.Errorf("expected a reader to list notes, got %d", status)
+	}
+}
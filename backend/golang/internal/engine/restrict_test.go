@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestDelete_RestrictedRelationReturnsConflict(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	customer := &metadata.Entity{
+		Name: "customer", Table: "customers",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields:     []metadata.Field{{Name: "id", Type: "string"}},
+	}
+	order := &metadata.Entity{
+		Name: "order", Table: "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields:     []metadata.Field{{Name: "id", Type: "string"}, {Name: "customer_id", Type: "string", Nullable: true}},
+	}
+	migrator := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{customer, order} {
+		if err := migrator.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	// No on_delete: restrict is the default
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{customer, order}, []*metadata.Relation{{
+		Name: "orders", Type: "one_to_many", Source: "customer", Target: "order",
+		SourceKey: "id", TargetKey: "customer_id", Ownership: "source",
+	}})
+	h := NewHandler(s, reg)
+
+	for _, q := range []string{
+		"INSERT INTO customers (id) VALUES ('c1'), ('c2')",
+		"INSERT INTO orders (id, customer_id) VALUES ('o1', 'c1'), ('o2', 'c1')",
+	} {
+		if _, err := store.Exec(ctx, s.DB, q); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Delete("/api/:entity/:id", h.Delete)
+	del := func(id string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest("DELETE", "/api/customer/"+id, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("DELETE: %v", err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, out := del("c1")
+	if status != 409 {
+		t.Fatalf("expected 409 deleting a customer with orders, got %d %v", status, out)
+	}
+	appErr := out["error"].(map[string]any)
+	details, _ := appErr["details"].([]any)
+	if appErr["code"] != "CONFLICT" || len(details) != 1 {
+		t.Fatalf("expected one blocking relation, got %v", appErr)
+	}
+	if d := details[0].(map[string]any); d["field"] != "orders" || d["message"] != "2 related order records exist" {
+		t.Fatalf("expected the orders relation and its count, got %v", d)
+	}
+	if row, _ := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM customers WHERE id = 'c1'"); toInt(row["n"]) != 1 {
+		t.Fatalf("expected the customer to survive a blocked delete")
+	}
+
+	if status, out := del("c2"); status != 200 {
+		t.Fatalf("expected a customer without orders to be deleted, got %d %v", status, out)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestExecuteWritePlan_ReturnsDatabaseDefaults(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	// The column defaults live only in the database, not in the metadata
+	if _, err := store.Exec(ctx, s.DB, `CREATE TABLE tickets (
+		id TEXT PRIMARY KEY,
+		title TEXT,
+		status TEXT DEFAULT 'open',
+		priority INTEGER DEFAULT 3,
+		created_at TEXT
+	)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	entity := &metadata.Entity{
+		Name:       "ticket",
+		Table:      "tickets",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "title", Type: "string"},
+			{Name: "status", Type: "string"},
+			{Name: "priority", Type: "int"},
+			{Name: "created_at", Type: "timestamp", Auto: "create"},
+		},
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	plan, verrs := PlanWrite(entity, reg, map[string]any{"title": "Printer on fire"}, nil)
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", verrs)
+	}
+	rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if rec["id"] == nil || rec["status"] != "open" || toInt(rec["priority"]) != 3 || rec["created_at"] == nil {
+		t.Fatalf("expected generated id, DB defaults and timestamp in the created record, got %v", rec)
+	}
+
+	plan, verrs = PlanWrite(entity, reg, map[string]any{"status": "closed"}, rec["id"])
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", verrs)
+	}
+	rec, err = ExecuteWritePlan(ctx, s, reg, plan)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if rec["status"] != "closed" || rec["title"] != "Printer on fire" || toInt(rec["priority"]) != 3 {
+		t.Fatalf("expected the full updated record, got %v", rec)
+	}
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// rollupGroup is the set of an entity's rollup fields that aggregate over the
+// same relation, so one UPDATE refreshes them together.
+type rollupGroup struct {
+	parent   *metadata.Entity
+	relation *metadata.Relation
+	target   *metadata.Entity
+	fields   []metadata.Field
+}
+
+// rollupGroups returns the entity's rollup fields grouped by relation, in field
+// order. Rollups over a relation that isn't loaded, isn't the entity's own, or
+// is many_to_many are skipped; the admin API rejects the latter two.
+func rollupGroups(reg *metadata.Registry, entity *metadata.Entity) []*rollupGroup {
+	var groups []*rollupGroup
+	byRelation := map[string]*rollupGroup{}
+	for _, f := range entity.Fields {
+		if !f.IsRollup() || f.Rollup == nil {
+			continue
+		}
+		g := byRelation[f.Rollup.Relation]
+		if g == nil {
+			rel := reg.GetRelation(f.Rollup.Relation)
+			if rel == nil || rel.Source != entity.Name || rel.IsManyToMany() {
+				continue
+			}
+			target := reg.GetEntity(rel.Target)
+			if target == nil {
+				continue
+			}
+			g = &rollupGroup{parent: entity, relation: rel, target: target}
+			byRelation[rel.Name] = g
+			groups = append(groups, g)
+		}
+		g.fields = append(g.fields, f)
+	}
+	return groups
+}
+
+// rollupGroupsOver returns the rollup groups on any entity that aggregate
+// records of entityName.
+func rollupGroupsOver(reg *metadata.Registry, entityName string) []*rollupGroup {
+	var groups []*rollupGroup
+	for _, rel := range reg.AllRelations() {
+		if rel.Target != entityName || rel.IsManyToMany() {
+			continue
+		}
+		parent := reg.GetEntity(rel.Source)
+		if parent == nil {
+			continue
+		}
+		for _, g := range rollupGroups(reg, parent) {
+			if g.relation.Name == rel.Name {
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
+}
+
+// setClause builds "field = (subquery), ..." computing each rollup from the
+// target records of the parent row being updated.
+func (g *rollupGroup) setClause() string {
+	match := fmt.Sprintf("_r.%s = %s.%s", g.relation.TargetKey, g.parent.Table, g.relation.SourceKey)
+	if g.target.SoftDelete {
+		match += " AND _r.deleted_at IS NULL"
+	}
+	sets := make([]string, 0, len(g.fields))
+	for _, f := range g.fields {
+		var agg string
+		switch f.Rollup.Aggregate {
+		case "count":
+			agg = "COUNT(*)"
+		case "sum":
+			agg = fmt.Sprintf("COALESCE(SUM(_r.%s), 0)", f.Rollup.Field)
+		default:
+			agg = fmt.Sprintf("%s(_r.%s)", strings.ToUpper(f.Rollup.Aggregate), f.Rollup.Field)
+		}
+		sets = append(sets, fmt.Sprintf("%s = (SELECT %s FROM %s _r WHERE %s)", f.Name, agg, g.target.Table, match))
+	}
+	return strings.Join(sets, ", ")
+}
+
+// recompute refreshes the group's rollups on the parent rows whose source key
+// is in keys. On Postgres each parent row is locked first, in a fixed order,
+// so the aggregate is read in a statement that starts after any concurrent
+// write to the same parent has committed; SQLite already serializes writers.
+func (g *rollupGroup) recompute(ctx context.Context, q store.Querier, dialect store.Dialect, keys []any) error {
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	for _, key := range keys {
+		if dialect.Name() == "postgres" {
+			pb := dialect.NewParamBuilder()
+			if _, err := store.Exec(ctx, q,
+				fmt.Sprintf("SELECT 1 FROM %s WHERE %s = %s FOR NO KEY UPDATE", g.parent.Table, g.relation.SourceKey, pb.Add(key)),
+				pb.Params()...); err != nil {
+				return fmt.Errorf("lock %s for rollups: %w", g.parent.Table, err)
+			}
+		}
+		pb := dialect.NewParamBuilder()
+		if _, err := store.Exec(ctx, q,
+			fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", g.parent.Table, g.setClause(), g.relation.SourceKey, pb.Add(key)),
+			pb.Params()...); err != nil {
+			return fmt.Errorf("update rollups on %s: %w", g.parent.Table, err)
+		}
+	}
+	return nil
+}
+
+// refreshRollupsOver recomputes the rollups that aggregate entity's records
+// for the parents the given records (before and after a write) belong to.
+func refreshRollupsOver(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, records ...map[string]any) error {
+	for _, g := range rollupGroupsOver(reg, entity.Name) {
+		var keys []any
+		seen := map[string]bool{}
+		for _, rec := range records {
+			key := rec[g.relation.TargetKey]
+			if key == nil || seen[fmt.Sprint(key)] {
+				continue
+			}
+			seen[fmt.Sprint(key)] = true
+			keys = append(keys, key)
+		}
+		if err := g.recompute(ctx, q, dialect, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshOwnRollups recomputes the entity's own rollups for one record.
+func refreshOwnRollups(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, record map[string]any) error {
+	for _, g := range rollupGroups(reg, entity) {
+		if key := record[g.relation.SourceKey]; key != nil {
+			if err := g.recompute(ctx, q, dialect, []any{key}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecomputeRollups recomputes every rollup field of the entity on all of its
+// rows. The admin API runs it after an entity or relation changes, so rollups
+// added to existing data start out correct.
+func RecomputeRollups(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity) error {
+	for _, g := range rollupGroups(reg, entity) {
+		if _, err := store.Exec(ctx, q, fmt.Sprintf("UPDATE %s SET %s", entity.Table, g.setClause())); err != nil {
+			return fmt.Errorf("recompute rollups on %s: %w", entity.Table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestRollup_ChildWritesUpdateParent(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	order := &metadata.Entity{
+		Name:       "order",
+		Table:      "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "number", Type: "string"},
+			{Name: "line_count", Type: "rollup", Rollup: &metadata.RollupConfig{Relation: "lines", Aggregate: "count"}},
+			{Name: "total", Type: "rollup", Rollup: &metadata.RollupConfig{Relation: "lines", Aggregate: "sum", Field: "amount"}},
+		},
+	}
+	line := &metadata.Entity{
+		Name:       "line",
+		Table:      "order_lines",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		SoftDelete: true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "order_id", Type: "uuid"},
+			{Name: "amount", Type: "float"},
+		},
+	}
+	m := store.NewMigrator(s)
+	for _, e := range []*metadata.Entity{order, line} {
+		if err := m.Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{order, line}, []*metadata.Relation{{
+		Name: "lines", Type: "one_to_many", Source: "order", Target: "line",
+		SourceKey: "id", TargetKey: "order_id", Ownership: "source", OnDelete: "cascade",
+	}})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Get("/api/:entity/:id", h.GetByID)
+	app.Put("/api/:entity/:id", h.Update)
+	app.Delete("/api/:entity/:id", h.Delete)
+
+	send := func(method, path string, body map[string]any) map[string]any {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: %d %v", method, path, resp.StatusCode, out)
+		}
+		return out["data"].(map[string]any)
+	}
+	expect := func(orderID string, count int, total float64) {
+		t.Helper()
+		rec := send("GET", "/api/order/"+orderID, nil)
+		if toInt(rec["line_count"]) != count || toFloat(rec["total"]) != total {
+			t.Fatalf("expected %d lines totalling %v, got %v and %v", count, total, rec["line_count"], rec["total"])
+		}
+	}
+
+	// Created with nested lines; the rollups come back in the response
+	created := send("POST", "/api/order", map[string]any{
+		"number": "A-1", "line_count": 99,
+		"lines": map[string]any{"data": []any{map[string]any{"amount": 10}, map[string]any{"amount": 5.5}}},
+	})
+	if toInt(created["line_count"]) != 2 || toFloat(created["total"]) != 15.5 {
+		t.Fatalf("expected the created order to carry its rollups, got %v", created)
+	}
+	orderID := created["id"].(string)
+
+	added := send("POST", "/api/line", map[string]any{"order_id": orderID, "amount": 4.5})
+	expect(orderID, 3, 20)
+
+	send("PUT", "/api/line/"+added["id"].(string), map[string]any{"amount": 14.5})
+	expect(orderID, 3, 30)
+
+	// Moving a line recomputes both its old and new order
+	other := send("POST", "/api/order", map[string]any{"number": "A-2"})["id"].(string)
+	expect(other, 0, 0)
+	send("PUT", "/api/line/"+added["id"].(string), map[string]any{"order_id": other})
+	expect(orderID, 2, 15.5)
+	expect(other, 1, 14.5)
+
+	// Soft-deleted lines drop out
+	send("DELETE", "/api/line/"+added["id"].(string), nil)
+	expect(other, 0, 0)
+
+	// Concurrent child writes don't lose updates
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			plan, verrs := PlanWrite(line, reg, map[string]any{"order_id": other, "amount": 1}, nil)
+			if len(verrs) > 0 {
+				errs <- fmt.Errorf("line %d: %v", i, verrs)
+				return
+			}
+			if _, err := ExecuteWritePlan(ctx, s, reg, plan); err != nil {
+				errs <- fmt.Errorf("line %d: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	expect(other, 10, 10)
+
+	// Rollups added to existing data are backfilled
+	if _, err := store.Exec(ctx, s.DB, "UPDATE orders SET line_count = NULL, total = NULL"); err != nil {
+		t.Fatalf("clear rollups: %v", err)
+	}
+	if err := RecomputeRollups(ctx, s.DB, s.Dialect, reg, order); err != nil {
+		t.Fatalf("recompute: %v", err)
+	}
+	expect(orderID, 2, 15.5)
+	expect(other, 10, 10)
+}
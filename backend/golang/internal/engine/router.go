@@ -14,9 +14,19 @@ func RegisterDynamicRoutes(app *fiber.App, h *Handler, middleware ...fiber.Handl
 		return all
 	}
 
+	app.Get("/api/_meta", wrap(h.Meta)...)
+	app.Get("/api/permissions/effective", wrap(h.EffectivePermissions)...)
 	app.Get("/api/:entity", wrap(h.List)...)
+	app.Get("/api/:entity/distinct", wrap(h.Distinct)...)
 	app.Get("/api/:entity/:id", wrap(h.GetByID)...)
 	app.Post("/api/:entity", wrap(h.Create)...)
+	app.Post("/api/:entity/search", wrap(h.Search)...)
+	app.Post("/api/:entity/_bulk", wrap(h.BulkCreate)...)
 	app.Put("/api/:entity/:id", wrap(h.Update)...)
+	app.Patch("/api/:entity/:id", wrap(h.Patch)...)
 	app.Delete("/api/:entity/:id", wrap(h.Delete)...)
+	app.Post("/api/:entity/:id/restore", wrap(h.Restore)...)
+	app.Get("/api/:entity/:id/related/:relation", wrap(h.ListRelated)...)
+	app.Post("/api/:entity/:id/relations/:relation", wrap(h.AttachRelated)...)
+	app.Delete("/api/:entity/:id/relations/:relation/:targetId", wrap(h.DetachRelated)...)
 }
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 
@@ -12,32 +13,83 @@ import (
 	"rocket-backend/internal/metadata"
 )
 
+// RuleOrder is the default rule evaluation order (writes.rule_order).
+// "phased" runs field rules, then expression rules, then — only when both
+// passed — computed rules, each group in priority order. "priority" runs
+// every rule in one pass in priority order, so a computed rule can feed a
+// later validation. Entities can override it with rule_order.
+var RuleOrder = "phased"
+
+func ruleOrder(entity *metadata.Entity) string {
+	if entity != nil && entity.RuleOrder != "" {
+		return entity.RuleOrder
+	}
+	return RuleOrder
+}
+
 // EvaluateRules runs all active rules for an entity/hook against the record.
 // It returns validation errors for field and expression rules, and mutates
-// the fields map for computed rules.
+// the fields map for computed rules. Rules are taken in priority order, ties
+// broken by created_at and then id.
 func EvaluateRules(ctx context.Context, reg *metadata.Registry, entityName string, hook string, fields map[string]any, old map[string]any, isCreate bool) []ErrorDetail {
 	_, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "rules", "rules.evaluate")
 	defer span.End()
 	span.SetEntity(entityName, "")
 
-	rules := reg.GetRulesForEntity(entityName, hook)
-	if len(rules) == 0 {
-		span.SetStatus("ok")
-		return nil
-	}
-
 	action := "update"
 	if isCreate {
 		action = "create"
 	}
 
+	// before_create/before_update rules join the before_write ones as a single
+	// set, so phases and priorities order them together
+	hooks := []string{hook}
+	if hook == "before_write" {
+		hooks = append(hooks, "before_"+action)
+	}
+	rules := reg.GetRulesForEntity(entityName, hooks...)
+	if len(rules) == 0 {
+		span.SetStatus("ok")
+		return nil
+	}
+
 	env := map[string]any{
 		"record": fields,
 		"old":    old,
 		"action": action,
 	}
+	entity := reg.GetEntity(entityName)
 
 	var errs []ErrorDetail
+	finish := func() []ErrorDetail {
+		if len(errs) > 0 {
+			span.SetStatus("error")
+		} else {
+			span.SetStatus("ok")
+		}
+		return errs
+	}
+
+	if ruleOrder(entity) == "priority" {
+		for _, r := range rules {
+			var detail *ErrorDetail
+			switch r.Type {
+			case "field":
+				detail = EvaluateFieldRule(r, fields)
+			case "expression":
+				detail = EvaluateExpressionRule(ctx, r, env)
+			case "computed":
+				detail = applyComputedRule(ctx, entity, r, env, fields)
+			}
+			if detail != nil {
+				errs = append(errs, *detail)
+				if r.Definition.StopOnFail {
+					break
+				}
+			}
+		}
+		return finish()
+	}
 
 	// 1. Field rules
 	for _, r := range rules {
@@ -47,8 +99,7 @@ func EvaluateRules(ctx context.Context, reg *metadata.Registry, entityName strin
 		if detail := EvaluateFieldRule(r, fields); detail != nil {
 			errs = append(errs, *detail)
 			if r.Definition.StopOnFail {
-				span.SetStatus("error")
-				return errs
+				return finish()
 			}
 		}
 	}
@@ -58,19 +109,17 @@ func EvaluateRules(ctx context.Context, reg *metadata.Registry, entityName strin
 		if r.Type != "expression" {
 			continue
 		}
-		if detail := EvaluateExpressionRule(r, env); detail != nil {
+		if detail := EvaluateExpressionRule(ctx, r, env); detail != nil {
 			errs = append(errs, *detail)
 			if r.Definition.StopOnFail {
-				span.SetStatus("error")
-				return errs
+				return finish()
 			}
 		}
 	}
 
 	// If there are validation errors, don't run computed fields
 	if len(errs) > 0 {
-		span.SetStatus("error")
-		return errs
+		return finish()
 	}
 
 	// 3. Computed fields
@@ -78,24 +127,58 @@ func EvaluateRules(ctx context.Context, reg *metadata.Registry, entityName strin
 		if r.Type != "computed" {
 			continue
 		}
-		val, err := EvaluateComputedField(r, env)
-		if err != nil {
-			errs = append(errs, ErrorDetail{
-				Field:   r.Definition.Field,
-				Rule:    "computed",
-				Message: err.Error(),
-			})
-			continue
+		if detail := applyComputedRule(ctx, entity, r, env, fields); detail != nil {
+			errs = append(errs, *detail)
 		}
-		fields[r.Definition.Field] = val
 	}
+	return finish()
+}
 
-	if len(errs) > 0 {
-		span.SetStatus("error")
+// applyComputedRule evaluates a computed rule and stores the result in
+// fields. Decimal targets use exact arithmetic and the field's precision.
+func applyComputedRule(ctx context.Context, entity *metadata.Entity, r *metadata.Rule, env map[string]any, fields map[string]any) *ErrorDetail {
+	ctx, span := startRuleSpan(ctx, r)
+	defer span.End()
+
+	var val any
+	var err error
+	if target := computedTarget(entity, r.Definition.Field); target != nil && target.Type == "decimal" {
+		val, err = EvaluateDecimalComputedField(ctx, r, env, target.Precision)
 	} else {
-		span.SetStatus("ok")
+		val, err = EvaluateComputedField(ctx, r, env)
+	}
+	if err != nil {
+		span.SetStatus(ruleSpanStatus(err))
+		code := "COMPUTED_ERROR"
+		if errors.Is(err, ErrExprTimeout) {
+			code = "EXPRESSION_TIMEOUT"
+		}
+		return &ErrorDetail{
+			Field:   r.Definition.Field,
+			Code:    code,
+			Rule:    "computed",
+			Message: err.Error(),
+		}
+	}
+	span.SetStatus("ok")
+	fields[r.Definition.Field] = val
+	return nil
+}
+
+// startRuleSpan times one expression or computed rule as its own event, so
+// slow rules can be found in _events.
+func startRuleSpan(ctx context.Context, r *metadata.Rule) (context.Context, instrument.Span) {
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "engine", "rules", "rules."+r.Type)
+	span.SetEntity(r.Entity, "")
+	span.SetMetadata("rule_id", r.ID)
+	return ctx, span
+}
+
+func ruleSpanStatus(err error) string {
+	if errors.Is(err, ErrExprTimeout) {
+		return "timeout"
 	}
-	return errs
+	return "error"
 }
 
 // EvaluateFieldRule evaluates a single field rule against a record.
@@ -186,7 +269,7 @@ func EvaluateFieldRule(rule *metadata.Rule, record map[string]any) *ErrorDetail
 
 // CompileExpression compiles an expression string into an expr-lang program.
 func CompileExpression(expression string) (*vm.Program, error) {
-	prog, err := expr.Compile(expression, expr.AsBool())
+	prog, err := compileExpr(expression, expr.AsBool())
 	if err != nil {
 		return nil, fmt.Errorf("compile expression: %w", err)
 	}
@@ -196,22 +279,32 @@ func CompileExpression(expression string) (*vm.Program, error) {
 // EvaluateExpressionRule evaluates a compiled expression rule against an environment.
 // The env should contain: record, old, action (and optionally related data).
 // Returns nil if the rule passes (expression is false), or an ErrorDetail if violated (expression is true).
-func EvaluateExpressionRule(rule *metadata.Rule, env map[string]any) *ErrorDetail {
+func EvaluateExpressionRule(ctx context.Context, rule *metadata.Rule, env map[string]any) *ErrorDetail {
+	ctx, span := startRuleSpan(ctx, rule)
+	defer span.End()
+
 	prog, ok := rule.Compiled.(*vm.Program)
 	if !ok || prog == nil {
 		// Lazy compile
 		compiled, err := CompileExpression(rule.Definition.Expression)
 		if err != nil {
-			return &ErrorDetail{Rule: "expression", Message: fmt.Sprintf("compile error: %v", err)}
+			span.SetStatus("error")
+			return &ErrorDetail{Field: rule.Definition.Field, Code: "EXPRESSION_ERROR", Rule: "expression", Message: fmt.Sprintf("compile error: %v", err)}
 		}
 		rule.Compiled = compiled
 		prog = compiled
 	}
 
-	result, err := expr.Run(prog, env)
+	result, err := runExpr(ctx, prog, env)
 	if err != nil {
-		return &ErrorDetail{Rule: "expression", Message: fmt.Sprintf("rule evaluation error: %v", err)}
+		span.SetStatus(ruleSpanStatus(err))
+		code := "EXPRESSION_ERROR"
+		if errors.Is(err, ErrExprTimeout) {
+			code = "EXPRESSION_TIMEOUT"
+		}
+		return &ErrorDetail{Field: rule.Definition.Field, Code: code, Rule: "expression", Message: fmt.Sprintf("rule evaluation error: %v", err)}
 	}
+	span.SetStatus("ok")
 
 	violated, ok := result.(bool)
 	if !ok {
@@ -223,7 +316,7 @@ func EvaluateExpressionRule(rule *metadata.Rule, env map[string]any) *ErrorDetai
 		if msg == "" {
 			msg = "Expression rule violated"
 		}
-		return &ErrorDetail{Rule: "expression", Message: msg}
+		return &ErrorDetail{Field: rule.Definition.Field, Code: "EXPRESSION", Rule: "expression", Message: msg}
 	}
 
 	return nil
@@ -231,7 +324,7 @@ func EvaluateExpressionRule(rule *metadata.Rule, env map[string]any) *ErrorDetai
 
 // CompileComputedExpression compiles an expression for a computed field (returns any value, not bool).
 func CompileComputedExpression(expression string) (*vm.Program, error) {
-	prog, err := expr.Compile(expression)
+	prog, err := compileExpr(expression)
 	if err != nil {
 		return nil, fmt.Errorf("compile computed expression: %w", err)
 	}
@@ -239,7 +332,7 @@ func CompileComputedExpression(expression string) (*vm.Program, error) {
 }
 
 // EvaluateComputedField evaluates a computed field rule and returns the computed value.
-func EvaluateComputedField(rule *metadata.Rule, env map[string]any) (any, error) {
+func EvaluateComputedField(ctx context.Context, rule *metadata.Rule, env map[string]any) (any, error) {
 	prog, ok := rule.Compiled.(*vm.Program)
 	if !ok || prog == nil {
 		// Lazy compile
@@ -251,7 +344,7 @@ func EvaluateComputedField(rule *metadata.Rule, env map[string]any) (any, error)
 		prog = compiled
 	}
 
-	result, err := expr.Run(prog, env)
+	result, err := runExpr(ctx, prog, env)
 	if err != nil {
 		return nil, fmt.Errorf("evaluate computed field %s: %w", rule.Definition.Field, err)
 	}
@@ -259,6 +352,13 @@ func EvaluateComputedField(rule *metadata.Rule, env map[string]any) (any, error)
 	return result, nil
 }
 
+func computedTarget(entity *metadata.Entity, field string) *metadata.Field {
+	if entity == nil {
+		return nil
+	}
+	return entity.GetField(field)
+}
+
 // toFloat64 converts numeric types to float64.
 func toFloat64(v any) (float64, bool) {
 	switch n := v.(type) {
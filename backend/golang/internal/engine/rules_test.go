@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"rocket-backend/internal/metadata"
 )
@@ -175,7 +177,7 @@ func TestEvaluateExpressionRule_Violated(t *testing.T) {
 		"old":    map[string]any{},
 		"action": "create",
 	}
-	detail := EvaluateExpressionRule(rule, env)
+	detail := EvaluateExpressionRule(context.Background(), rule, env)
 	if detail == nil {
 		t.Fatal("expected violation when status=paid and payment_date=nil")
 	}
@@ -184,6 +186,58 @@ func TestEvaluateExpressionRule_Violated(t *testing.T) {
 	}
 }
 
+func TestEvaluateExpressionRule_RunawayExpressionIsStopped(t *testing.T) {
+	defer func(timeout time.Duration, steps int) { ExprTimeout, ExprMaxSteps = timeout, steps }(ExprTimeout, ExprMaxSteps)
+
+	items := make([]any, 200)
+	for i := range items {
+		items[i] = i
+	}
+	env := map[string]any{
+		"record": map[string]any{"items": items},
+		"old":    map[string]any{},
+		"action": "create",
+	}
+	run := func() *ErrorDetail {
+		t.Helper()
+		rule := &metadata.Rule{
+			Type: "expression",
+			Definition: metadata.RuleDefinition{
+				Field:      "items",
+				Expression: "all(record.items, {all(record.items, {all(record.items, {# >= 0})})})",
+			},
+		}
+		start := time.Now()
+		detail := EvaluateExpressionRule(context.Background(), rule, env)
+		if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+			t.Fatalf("expected the evaluation to be cut off, took %s", elapsed)
+		}
+		return detail
+	}
+
+	// Stopped by the clock
+	ExprTimeout, ExprMaxSteps = 10*time.Millisecond, 1<<62
+	if detail := run(); detail == nil || detail.Code != "EXPRESSION_TIMEOUT" || detail.Field != "items" {
+		t.Fatalf("expected an EXPRESSION_TIMEOUT detail on items, got %+v", detail)
+	}
+
+	// Stopped by the step limit, with no time limit at all
+	ExprTimeout, ExprMaxSteps = 0, 1000
+	if detail := run(); detail == nil || detail.Code != "EXPRESSION_TIMEOUT" {
+		t.Fatalf("expected the step limit to stop the run, got %+v", detail)
+	}
+}
+
+func TestCompileExpression_RejectsOversizedExpressions(t *testing.T) {
+	huge := "record.a"
+	for i := 0; i < int(ExprMaxNodes); i++ {
+		huge += " + record.a"
+	}
+	if _, err := CompileExpression(huge + " > 0"); err == nil {
+		t.Fatal("expected an expression over ExprMaxNodes nodes to be rejected")
+	}
+}
+
 func TestEvaluateExpressionRule_Passes(t *testing.T) {
 	rule := &metadata.Rule{
 		Type: "expression",
@@ -200,7 +254,7 @@ func TestEvaluateExpressionRule_Passes(t *testing.T) {
 		"old":    map[string]any{},
 		"action": "create",
 	}
-	detail := EvaluateExpressionRule(rule, env)
+	detail := EvaluateExpressionRule(context.Background(), rule, env)
 	if detail != nil {
 		t.Fatalf("expected pass when payment_date is set, got %v", detail)
 	}
@@ -223,14 +277,14 @@ func TestEvaluateExpressionRule_WithOldRecord(t *testing.T) {
 		"old":    map[string]any{"status": "paid"},
 		"action": "update",
 	}
-	detail := EvaluateExpressionRule(rule, env)
+	detail := EvaluateExpressionRule(context.Background(), rule, env)
 	if detail == nil {
 		t.Fatal("expected violation when cancelling a paid order")
 	}
 
 	// Should pass (not an update)
 	env["action"] = "create"
-	detail = EvaluateExpressionRule(rule, env)
+	detail = EvaluateExpressionRule(context.Background(), rule, env)
 	if detail != nil {
 		t.Fatalf("expected pass on create, got %v", detail)
 	}
@@ -257,7 +311,7 @@ func TestEvaluateComputedField(t *testing.T) {
 		"old":    map[string]any{},
 		"action": "create",
 	}
-	val, err := EvaluateComputedField(rule, env)
+	val, err := EvaluateComputedField(context.Background(), rule, env)
 	if err != nil {
 		t.Fatalf("evaluate computed: %v", err)
 	}
@@ -270,6 +324,56 @@ func TestEvaluateComputedField(t *testing.T) {
 	}
 }
 
+func TestEvaluateRules_DecimalComputedFieldIsExact(t *testing.T) {
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{{
+		Name: "invoice", Table: "invoices", PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid"},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "subtotal", Type: "decimal", Precision: 2},
+			{Name: "tax_rate", Type: "decimal", Precision: 4},
+			{Name: "total", Type: "decimal", Precision: 2},
+		},
+	}}, nil)
+	reg.LoadRules([]*metadata.Rule{{
+		ID: "r1", Entity: "invoice", Hook: "before_write", Type: "computed", Active: true,
+		Definition: metadata.RuleDefinition{Field: "total", Expression: "record.subtotal * (1 + record.tax_rate)"},
+	}})
+
+	cases := []struct {
+		subtotal, taxRate, want float64
+	}{
+		{100, 0.1, 110.00},
+		{19.99, 0.0825, 21.64}, // 21.639175 rounds up
+		{0.1, 0.2, 0.12},
+	}
+	for _, tc := range cases {
+		fields := map[string]any{"subtotal": tc.subtotal, "tax_rate": tc.taxRate}
+		if errs := EvaluateRules(context.Background(), reg, "invoice", "before_write", fields, map[string]any{}, true); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		if fields["total"] != tc.want {
+			t.Fatalf("%v * (1 + %v): expected exactly %v, got %v", tc.subtotal, tc.taxRate, tc.want, fields["total"])
+		}
+	}
+}
+
+func TestEvaluateDecimalComputedField_RoundsOnlyOnce(t *testing.T) {
+	rule := &metadata.Rule{
+		Type:       "computed",
+		Definition: metadata.RuleDefinition{Field: "share", Expression: "-(record.amount / 3 * 3 / 8) + (record.amount > 0.5 ? 0.25 : 0)"},
+	}
+	// 1/3*3/8 is exactly 0.125, which rounds up; rounding each step through
+	// float64 gives 0.12499999999999999 and rounds down
+	result, err := EvaluateDecimalComputedField(context.Background(), rule, map[string]any{"record": map[string]any{"amount": 1.0}}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 0.13 {
+		t.Fatalf("expected 0.13, got %v", result)
+	}
+}
+
 func TestEvaluateComputedField_StringConcat(t *testing.T) {
 	rule := &metadata.Rule{
 		Type: "computed",
@@ -289,7 +393,7 @@ func TestEvaluateComputedField_StringConcat(t *testing.T) {
 		"old":    map[string]any{},
 		"action": "create",
 	}
-	val, err := EvaluateComputedField(rule, env)
+	val, err := EvaluateComputedField(context.Background(), rule, env)
 	if err != nil {
 		t.Fatalf("evaluate computed: %v", err)
 	}
@@ -318,3 +422,82 @@ func TestEvaluateFieldRule_IntegerValues(t *testing.T) {
 		t.Fatalf("expected pass for age=20 (int), got %v", detail)
 	}
 }
+
+func TestEvaluateRules_PriorityTiesAreStable(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rule := func(id, typ string, priority int, created time.Time, def metadata.RuleDefinition) *metadata.Rule {
+		return &metadata.Rule{ID: id, Entity: "invoice", Hook: "before_write", Type: typ, Priority: priority, CreatedAt: created, Active: true, Definition: def}
+	}
+	entity := &metadata.Entity{Name: "invoice", Fields: []metadata.Field{
+		{Name: "subtotal", Type: "int"}, {Name: "total", Type: "int"},
+	}}
+
+	// Same priority: created_at decides, then id. Loaded in reverse on purpose.
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadRules([]*metadata.Rule{
+		rule("c", "computed", 0, t0.Add(time.Minute), metadata.RuleDefinition{Field: "total", Expression: "record.total + 1"}),
+		rule("b", "computed", 0, t0, metadata.RuleDefinition{Field: "total", Expression: "record.total * 10"}),
+		rule("a", "computed", 0, t0, metadata.RuleDefinition{Field: "total", Expression: "record.subtotal * 2"}),
+	})
+	var ids []string
+	for _, r := range reg.GetRulesForEntity("invoice", "before_write") {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[1] != "b" || ids[2] != "c" {
+		t.Fatalf("expected rules ordered a, b, c, got %v", ids)
+	}
+	fields := map[string]any{"subtotal": 5}
+	if errs := EvaluateRules(context.Background(), reg, "invoice", "before_write", fields, map[string]any{}, true); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if fields["total"] != 101 {
+		t.Fatalf("expected (5*2)*10+1 = 101, got %v", fields["total"])
+	}
+
+	// A computed rule at a lower priority feeds a later validation only when
+	// rules run in a single priority pass.
+	validation := rule("v", "expression", 10, t0, metadata.RuleDefinition{Expression: "record.total != nil && record.total > 100", Message: "total too large"})
+	computed := rule("t", "computed", 0, t0, metadata.RuleDefinition{Field: "total", Expression: "record.subtotal * 2"})
+	reg.LoadRules([]*metadata.Rule{validation, computed})
+
+	fields = map[string]any{"subtotal": 60}
+	if errs := EvaluateRules(context.Background(), reg, "invoice", "before_write", fields, map[string]any{}, true); len(errs) > 0 {
+		t.Fatalf("phased: expected validation to run before the computed rule, got %v", errs)
+	}
+
+	entity.RuleOrder = "priority"
+	fields = map[string]any{"subtotal": 60}
+	errs := EvaluateRules(context.Background(), reg, "invoice", "before_write", fields, map[string]any{}, true)
+	if len(errs) != 1 || errs[0].Message != "total too large" {
+		t.Fatalf("priority: expected the computed total to be validated, got %v", errs)
+	}
+}
+
+func TestEvaluateRules_CreateOnlyRuleSkipsUpdates(t *testing.T) {
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{{Name: "invoice", Fields: []metadata.Field{{Name: "status", Type: "string"}, {Name: "total", Type: "int"}}}}, nil)
+	reg.LoadRules([]*metadata.Rule{
+		{ID: "draft", Entity: "invoice", Hook: "before_create", Type: "expression", Active: true,
+			Definition: metadata.RuleDefinition{Expression: "record.status != 'draft'", Message: "must start as draft"}},
+		{ID: "locked", Entity: "invoice", Hook: "before_update", Type: "expression", Active: true,
+			Definition: metadata.RuleDefinition{Expression: "old.status == 'paid'", Message: "paid invoices are locked"}},
+		{ID: "total", Entity: "invoice", Hook: "before_write", Type: "field", Active: true,
+			Definition: metadata.RuleDefinition{Field: "total", Operator: "min", Value: 0, Message: "total must not be negative"}},
+	})
+
+	errs := EvaluateRules(context.Background(), reg, "invoice", "before_write", map[string]any{"status": "sent", "total": -1}, nil, true)
+	if len(errs) != 2 || errs[0].Message != "total must not be negative" || errs[1].Message != "must start as draft" {
+		t.Fatalf("create: expected the before_write and before_create rules, got %v", errs)
+	}
+
+	old := map[string]any{"status": "draft", "total": 10}
+	if errs := EvaluateRules(context.Background(), reg, "invoice", "before_write", map[string]any{"status": "sent", "total": 10}, old, false); len(errs) != 0 {
+		t.Fatalf("update: expected the create-only rule not to fire, got %v", errs)
+	}
+	old["status"] = "paid"
+	errs = EvaluateRules(context.Background(), reg, "invoice", "before_write", map[string]any{"status": "sent", "total": 10}, old, false)
+	if len(errs) != 1 || errs[0].Message != "paid invoices are locked" {
+		t.Fatalf("update: expected only the before_update rule, got %v", errs)
+	}
+}
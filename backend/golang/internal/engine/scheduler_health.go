@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchedulerStatus reports a background scheduler's recent activity, as shown
+// by /health and GET /_admin/schedulers.
+type SchedulerStatus struct {
+	Name        string     `json:"name"`
+	Interval    string     `json:"interval"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	Runs        int64      `json:"runs"`
+	Processed   int64      `json:"processed"`
+	Healthy     bool       `json:"healthy"`
+}
+
+// SchedulerHealth tracks the ticks of one scheduler. A scheduler is unhealthy
+// once twice its interval passes without a tick, counted from when it started
+// tracking if it never ran.
+type SchedulerHealth struct {
+	mu          sync.Mutex
+	name        string
+	interval    time.Duration
+	startedAt   time.Time
+	lastRunAt   time.Time
+	lastError   string
+	lastErrorAt time.Time
+	runs        int64
+	processed   int64
+}
+
+var (
+	schedulersMu sync.Mutex
+	schedulers   = map[string]*SchedulerHealth{}
+)
+
+// TrackScheduler starts tracking a scheduler that ticks every interval,
+// replacing any earlier tracker with the same name.
+func TrackScheduler(name string, interval time.Duration) *SchedulerHealth {
+	h := &SchedulerHealth{name: name, interval: interval, startedAt: time.Now()}
+	schedulersMu.Lock()
+	schedulers[name] = h
+	schedulersMu.Unlock()
+	return h
+}
+
+// UntrackScheduler stops reporting a scheduler, e.g. after it was stopped.
+func UntrackScheduler(name string) {
+	schedulersMu.Lock()
+	delete(schedulers, name)
+	schedulersMu.Unlock()
+}
+
+// SchedulerStatuses returns the status of every tracked scheduler, by name.
+func SchedulerStatuses() []SchedulerStatus {
+	schedulersMu.Lock()
+	tracked := make([]*SchedulerHealth, 0, len(schedulers))
+	for _, h := range schedulers {
+		tracked = append(tracked, h)
+	}
+	schedulersMu.Unlock()
+
+	now := time.Now()
+	statuses := make([]SchedulerStatus, 0, len(tracked))
+	for _, h := range tracked {
+		statuses = append(statuses, h.Status(now))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Record notes a completed tick that processed n items. A non-nil err becomes
+// the last error; it is kept after later successful ticks, with its time.
+func (h *SchedulerHealth) Record(n int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastRunAt = time.Now()
+	h.runs++
+	h.processed += int64(n)
+	if err != nil {
+		h.lastError = err.Error()
+		h.lastErrorAt = h.lastRunAt
+	}
+}
+
+// Status reports the scheduler as of now.
+func (h *SchedulerHealth) Status(now time.Time) SchedulerStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := SchedulerStatus{
+		Name:      h.name,
+		Interval:  h.interval.String(),
+		LastError: h.lastError,
+		Runs:      h.runs,
+		Processed: h.processed,
+	}
+	since := h.startedAt
+	if !h.lastRunAt.IsZero() {
+		t := h.lastRunAt
+		st.LastRunAt = &t
+		since = t
+	}
+	if !h.lastErrorAt.IsZero() {
+		t := h.lastErrorAt
+		st.LastErrorAt = &t
+	}
+	st.Healthy = now.Sub(since) <= 2*h.interval
+	return st
+}
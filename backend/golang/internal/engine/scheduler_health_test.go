@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/store"
+)
+
+func TestSchedulerHealth_ReflectsRecentRun(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	webhookID := store.GenerateUUID()
+	if _, err := store.Exec(ctx, s.DB,
+		"INSERT INTO _webhooks (id, entity, hook, url) VALUES (?1, 'order', 'after_write', ?2)", webhookID, srv.URL); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	logID := store.GenerateUUID()
+	if _, err := store.Exec(ctx, s.DB,
+		`INSERT INTO _webhook_logs (id, webhook_id, entity, hook, url, method, status, attempt, max_attempts, idempotency_key, next_retry_at)
+		 VALUES (?1, ?2, 'order', 'after_write', ?3, 'POST', 'retrying', 1, 3, ?1, datetime('now', '-1 minutes'))`,
+		logID, webhookID, srv.URL); err != nil {
+		t.Fatalf("insert log: %v", err)
+	}
+
+	health := TrackScheduler("webhooks-test", 30*time.Second)
+	defer UntrackScheduler("webhooks-test")
+
+	status := func() SchedulerStatus {
+		t.Helper()
+		for _, st := range SchedulerStatuses() {
+			if st.Name == "webhooks-test" {
+				return st
+			}
+		}
+		t.Fatal("expected the scheduler to be tracked")
+		return SchedulerStatus{}
+	}
+	if st := status(); st.LastRunAt != nil || st.Runs != 0 || !st.Healthy {
+		t.Fatalf("expected a healthy scheduler that hasn't run yet, got %+v", st)
+	}
+
+	health.Record(ProcessWebhookRetries(s, nil))
+	st := status()
+	if st.LastRunAt == nil || time.Since(*st.LastRunAt) > 5*time.Second {
+		t.Fatalf("expected a recent last run, got %v", st.LastRunAt)
+	}
+	if st.Runs != 1 || st.Processed != 1 || st.LastError != "" || !st.Healthy {
+		t.Fatalf("expected one run that retried one delivery, got %+v", st)
+	}
+
+	// A scheduler that misses its ticks turns unhealthy
+	if health.Status(time.Now().Add(2 * time.Minute)).Healthy {
+		t.Fatal("expected a stale scheduler to be unhealthy")
+	}
+}
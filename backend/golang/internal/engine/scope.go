@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/expr-lang/expr/parser"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// scopeExprEnv is the permission environment with the user's attributes
+// flattened into `user`, so a scope can say record.org_id == user.org_id.
+// Attributes never shadow id and roles.
+func scopeExprEnv(user *metadata.UserContext, record map[string]any) map[string]any {
+	env := permissionExprEnv(user, record)
+	if user == nil {
+		return env
+	}
+	u := env["user"].(map[string]any)
+	for k, v := range user.Attributes {
+		if _, taken := u[k]; !taken {
+			u[k] = v
+		}
+	}
+	return env
+}
+
+// scopeApplies reports whether the entity's scope limits this user.
+func scopeApplies(entity *metadata.Entity, user *metadata.UserContext) bool {
+	if entity.Scope == "" {
+		return false
+	}
+	return !(entity.ScopeBypass && user != nil && user.IsAdmin())
+}
+
+// loadUserAttributes fills user.Attributes from _users once per request.
+// Users without a row (e.g. external tokens) get no attributes, so scopes
+// comparing against them match nothing.
+func (h *Handler) loadUserAttributes(ctx context.Context, user *metadata.UserContext) error {
+	if user == nil || user.Attributes != nil {
+		return nil
+	}
+	user.Attributes = map[string]any{}
+	row, err := store.QueryRow(ctx, h.store.DB,
+		fmt.Sprintf("SELECT attributes FROM _users WHERE id = %s", h.store.Dialect.Placeholder(1)), user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("load user attributes: %w", err)
+	}
+	switch v := row["attributes"].(type) {
+	case string:
+		_ = json.Unmarshal([]byte(v), &user.Attributes)
+	case []byte:
+		_ = json.Unmarshal(v, &user.Attributes)
+	case map[string]any:
+		user.Attributes = v
+	}
+	return nil
+}
+
+// scopeFilter translates the entity's scope into a mandatory read filter.
+// A scope that can't be expressed in SQL matches nothing rather than
+// leaking rows.
+func (h *Handler) scopeFilter(ctx context.Context, entity *metadata.Entity, user *metadata.UserContext) ([]WhereClause, error) {
+	if !scopeApplies(entity, user) {
+		return nil, nil
+	}
+	if err := h.loadUserAttributes(ctx, user); err != nil {
+		return nil, err
+	}
+	tree, err := parser.Parse(entity.Scope)
+	if err != nil {
+		return []WhereClause{clauseFalse}, nil
+	}
+	clause, ok := exprTranslator{env: scopeExprEnv(user, nil), entity: entity}.translate(tree.Node)
+	if !ok {
+		return []WhereClause{clauseFalse}, nil
+	}
+	return []WhereClause{clause}, nil
+}
+
+// inScope reports whether a record satisfies the entity's scope for user.
+func (h *Handler) inScope(ctx context.Context, entity *metadata.Entity, user *metadata.UserContext, record map[string]any) (bool, error) {
+	if !scopeApplies(entity, user) {
+		return true, nil
+	}
+	if err := h.loadUserAttributes(ctx, user); err != nil {
+		return false, err
+	}
+	return evaluateExpressionEnv(entity.Scope, scopeExprEnv(user, record)), nil
+}
+
+// applyScopeDefaults fills fields the scope pins to a value (the
+// record.field == <value> terms joined by "and") when a new record leaves
+// them out, so creating an org-scoped record needs no org_id in the body.
+func (h *Handler) applyScopeDefaults(ctx context.Context, entity *metadata.Entity, user *metadata.UserContext, body map[string]any) error {
+	filters, err := h.scopeFilter(ctx, entity, user)
+	if err != nil || len(filters) == 0 {
+		return err
+	}
+	var fill func(wc WhereClause)
+	fill = func(wc WhereClause) {
+		switch wc.Operator {
+		case "and":
+			nested, _ := wc.Value.([]WhereClause)
+			for _, n := range nested {
+				fill(n)
+			}
+		case "eq":
+			if _, set := body[wc.Field]; !set {
+				body[wc.Field] = wc.Value
+			}
+		}
+	}
+	fill(filters[0])
+	return nil
+}
+
+// checkScopedWrite rejects a write whose resulting record would fall
+// outside the entity's scope.
+func (h *Handler) checkScopedWrite(ctx context.Context, entity *metadata.Entity, user *metadata.UserContext, record map[string]any) error {
+	ok, err := h.inScope(ctx, entity, user, record)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ForbiddenError(fmt.Sprintf("%s record is outside your scope", entity.Name))
+	}
+	return nil
+}
+
+// ValidateScope checks that an entity scope parses and references record.
+func ValidateScope(scope string) error {
+	tree, err := parser.Parse(scope)
+	if err != nil {
+		return err
+	}
+	if !referencesRecord(tree.Node) {
+		return errors.New("scope must reference record")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestEntityScope_UsersInDifferentOrgsNeverSeeEachOther(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:        "project",
+		Table:       "projects",
+		PrimaryKey:  metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Scope:       "record.org_id == user.org_id",
+		DefaultSort: "name",
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "org_id", Type: "string"},
+			{Name: "name", Type: "string"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for id, org := range map[string]string{"alice": "acme", "bob": "globex"} {
+		if _, err := store.Exec(ctx, s.DB, "INSERT INTO _users (id, email, password_hash, attributes) VALUES (?1, ?2, 'x', ?3)",
+			id, id+"@example.com", `{"org_id":"`+org+`"}`); err != nil {
+			t.Fatalf("insert user %s: %v", id, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadPermissions([]*metadata.Permission{
+		{Entity: "project", Action: "read", Roles: []string{"member"}},
+		{Entity: "project", Action: "create", Roles: []string{"member"}},
+		{Entity: "project", Action: "update", Roles: []string{"member"}},
+	})
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: c.Get("X-Test-User"), Roles: []string{"member"}})
+		return c.Next()
+	})
+	app.Get("/api/:entity", h.List)
+	app.Get("/api/:entity/:id", h.GetByID)
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	do := func(method, path, user string, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	// org_id is filled from the creator's attributes
+	ids := map[string]string{}
+	for _, w := range []struct{ user, name string }{{"alice", "b-rocket"}, {"alice", "a-anvil"}, {"bob", "c-widget"}} {
+		status, out := do("POST", "/api/project", w.user, map[string]any{"name": w.name})
+		if status != 201 {
+			t.Fatalf("create %s as %s: %d %v", w.name, w.user, status, out)
+		}
+		ids[w.name] = out["data"].(map[string]any)["id"].(string)
+	}
+	if status, out := do("POST", "/api/project", "alice", map[string]any{"name": "spy", "org_id": "globex"}); status != 403 {
+		t.Fatalf("expected creating into another org to be forbidden, got %d %v", status, out)
+	}
+
+	status, out := do("GET", "/api/project", "alice", nil)
+	if status != 200 {
+		t.Fatalf("list as alice: %d %v", status, out)
+	}
+	rows := out["data"].([]any)
+	if len(rows) != 2 || rows[0].(map[string]any)["name"] != "a-anvil" || rows[1].(map[string]any)["name"] != "b-rocket" {
+		t.Fatalf("expected alice's two projects in default sort order, got %v", rows)
+	}
+	if total := out["meta"].(map[string]any)["total"]; toInt(total) != 2 {
+		t.Fatalf("expected the total to count only alice's org, got %v", total)
+	}
+	if _, out := do("GET", "/api/project", "bob", nil); len(out["data"].([]any)) != 1 {
+		t.Fatalf("expected bob to see only his project, got %v", out["data"])
+	}
+
+	if status, _ := do("GET", "/api/project/"+ids["c-widget"], "alice", nil); status != 404 {
+		t.Fatalf("expected bob's project to be invisible to alice, got %d", status)
+	}
+	if status, _ := do("PUT", "/api/project/"+ids["c-widget"], "alice", map[string]any{"name": "taken"}); status != 404 {
+		t.Fatalf("expected alice not to update bob's project, got %d", status)
+	}
+	if status, _ := do("PUT", "/api/project/"+ids["a-anvil"], "alice", map[string]any{"org_id": "globex"}); status != 403 {
+		t.Fatalf("expected moving a project out of scope to be forbidden, got %d", status)
+	}
+	if _, out := do("GET", "/api/project", "mallory", nil); len(out["data"].([]any)) != 0 {
+		t.Fatalf("expected a user without attributes to see nothing, got %v", out["data"])
+	}
+}
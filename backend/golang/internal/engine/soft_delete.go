@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"rocket-backend/internal/metadata"
 	"rocket-backend/internal/store"
@@ -11,80 +12,124 @@ import (
 // HandleCascadeDelete processes on_delete policies for all relations
 // where the deleted entity is the source.
 func HandleCascadeDelete(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, recordID any) error {
-	relations := reg.GetRelationsForSource(entity.Name)
-	for _, rel := range relations {
-		if err := executeCascade(ctx, q, dialect, reg, rel, recordID); err != nil {
-			return fmt.Errorf("cascade delete for relation %s: %w", rel.Name, err)
+	_, err := cascadeDelete(ctx, q, dialect, reg, entity, recordID)
+	return err
+}
+
+// CascadeEffect is what deleting a record did, or would do, to the records of
+// one relation.
+type CascadeEffect struct {
+	Relation string `json:"relation"`
+	Entity   string `json:"entity"`
+	Action   string `json:"action"`          // delete, soft_delete, set_null or detach
+	Count    *int64 `json:"count,omitempty"` // left out of previews when the caller can't read the entity
+}
+
+// cascadeDelete runs HandleCascadeDelete and reports the rows each relation's
+// policy touched. Restricting relations are checked first, so a blocked
+// delete changes nothing.
+func cascadeDelete(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, recordID any) ([]CascadeEffect, error) {
+	if err := checkRestrictedDelete(ctx, q, dialect, reg, entity, recordID); err != nil {
+		return nil, err
+	}
+	var effects []CascadeEffect
+	for _, rel := range reg.GetRelationsForSource(entity.Name) {
+		effect, err := executeCascade(ctx, q, dialect, reg, rel, recordID)
+		if err != nil {
+			return nil, fmt.Errorf("cascade delete for relation %s: %w", rel.Name, err)
+		}
+		if effect != nil {
+			effects = append(effects, *effect)
 		}
 	}
-	return nil
+	return effects, nil
 }
 
-func executeCascade(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, rel *metadata.Relation, parentID any) error {
-	switch rel.OnDelete {
+func executeCascade(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, rel *metadata.Relation, parentID any) (*CascadeEffect, error) {
+	exec := func(action, sql string) (*CascadeEffect, error) {
+		n, err := store.Exec(ctx, q, sql, parentID)
+		if err != nil {
+			return nil, err
+		}
+		return &CascadeEffect{Relation: rel.Name, Entity: rel.Target, Action: action, Count: &n}, nil
+	}
+
+	switch rel.DefaultOnDelete() {
 	case "cascade":
 		if rel.IsManyToMany() {
 			// Hard-delete join table rows
-			sql := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", rel.JoinTable, rel.SourceJoinKey, dialect.Placeholder(1))
-			if _, err := store.Exec(ctx, q, sql, parentID); err != nil {
-				return err
-			}
-		} else {
-			targetEntity := reg.GetEntity(rel.Target)
-			if targetEntity != nil && targetEntity.SoftDelete {
-				sql := fmt.Sprintf("UPDATE %s SET deleted_at = %s WHERE %s = %s AND deleted_at IS NULL",
-					targetEntity.Table, dialect.NowExpr(), rel.TargetKey, dialect.Placeholder(1))
-				if _, err := store.Exec(ctx, q, sql, parentID); err != nil {
-					return err
-				}
-			} else if targetEntity != nil {
-				sql := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", targetEntity.Table, rel.TargetKey, dialect.Placeholder(1))
-				if _, err := store.Exec(ctx, q, sql, parentID); err != nil {
-					return err
-				}
-			}
+			return exec("detach", fmt.Sprintf("DELETE FROM %s WHERE %s = %s", rel.JoinTable, rel.SourceJoinKey, dialect.Placeholder(1)))
 		}
-
-	case "set_null":
 		targetEntity := reg.GetEntity(rel.Target)
-		if targetEntity != nil {
-			sql := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = %s",
-				targetEntity.Table, rel.TargetKey, rel.TargetKey, dialect.Placeholder(1))
-			if _, err := store.Exec(ctx, q, sql, parentID); err != nil {
-				return err
-			}
+		if targetEntity != nil && targetEntity.SoftDelete {
+			return exec("soft_delete", fmt.Sprintf("UPDATE %s SET deleted_at = %s WHERE %s = %s AND deleted_at IS NULL",
+				targetEntity.Table, dialect.NowExpr(), rel.TargetKey, dialect.Placeholder(1)))
+		} else if targetEntity != nil {
+			return exec("delete", fmt.Sprintf("DELETE FROM %s WHERE %s = %s", targetEntity.Table, rel.TargetKey, dialect.Placeholder(1)))
 		}
 
-	case "restrict":
+	case "set_null":
 		targetEntity := reg.GetEntity(rel.Target)
 		if targetEntity != nil {
-			countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = %s", targetEntity.Table, rel.TargetKey, dialect.Placeholder(1))
-			if targetEntity.SoftDelete {
-				countSQL += " AND deleted_at IS NULL"
-			}
-			rows, err := store.QueryRows(ctx, q, countSQL, parentID)
-			if err != nil {
-				return err
-			}
-			if len(rows) > 0 {
-				if count, ok := rows[0]["count"].(int64); ok && count > 0 {
-					return &AppError{
-						Code:    "CONFLICT",
-						Status:  409,
-						Message: fmt.Sprintf("Cannot delete: %d related %s records exist", count, rel.Target),
-					}
-				}
-			}
+			return exec("set_null", fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = %s",
+				targetEntity.Table, rel.TargetKey, rel.TargetKey, dialect.Placeholder(1)))
 		}
 
 	case "detach":
 		if rel.IsManyToMany() {
-			sql := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", rel.JoinTable, rel.SourceJoinKey, dialect.Placeholder(1))
-			if _, err := store.Exec(ctx, q, sql, parentID); err != nil {
-				return err
-			}
+			return exec("detach", fmt.Sprintf("DELETE FROM %s WHERE %s = %s", rel.JoinTable, rel.SourceJoinKey, dialect.Placeholder(1)))
 		}
 	}
 
-	return nil
+	return nil, nil
+}
+
+// checkRestrictedDelete returns a 409 CONFLICT when relations with on_delete
+// restrict (the default) still have dependent rows, with one detail per
+// blocking relation. Many-to-many relations count the record's join rows.
+func checkRestrictedDelete(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, recordID any) error {
+	var details []ErrorDetail
+	for _, rel := range reg.GetRelationsForSource(entity.Name) {
+		if rel.DefaultOnDelete() != "restrict" {
+			continue
+		}
+		var countSQL string
+		if rel.IsManyToMany() {
+			countSQL = fmt.Sprintf("SELECT COUNT(*) AS count FROM %s WHERE %s = %s", rel.JoinTable, rel.SourceJoinKey, dialect.Placeholder(1))
+		} else {
+			target := reg.GetEntity(rel.Target)
+			if target == nil || rel.TargetKey == "" {
+				continue
+			}
+			countSQL = fmt.Sprintf("SELECT COUNT(*) AS count FROM %s WHERE %s = %s", target.Table, rel.TargetKey, dialect.Placeholder(1))
+			if target.SoftDelete {
+				countSQL += " AND deleted_at IS NULL"
+			}
+		}
+		row, err := store.QueryRow(ctx, q, countSQL, recordID)
+		if err != nil {
+			return fmt.Errorf("count %s records for relation %s: %w", rel.Target, rel.Name, err)
+		}
+		if count := toInt(row["count"]); count > 0 {
+			details = append(details, ErrorDetail{
+				Field:   rel.Name,
+				Code:    "RESTRICTED",
+				Rule:    "on_delete",
+				Message: fmt.Sprintf("%d related %s records exist", count, rel.Target),
+			})
+		}
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	names := make([]string, len(details))
+	for i, d := range details {
+		names[i] = d.Field
+	}
+	return &AppError{
+		Code:    "CONFLICT",
+		Status:  409,
+		Message: fmt.Sprintf("Cannot delete %s: related records exist (%s)", entity.Name, strings.Join(names, ", ")),
+		Details: details,
+	}
 }
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// checkSoftReferences verifies, for each soft relation pointing at entity, that
+// a written foreign key names an existing (not soft-deleted) source row. Soft
+// relations have no database constraint, so this is their only integrity check.
+// Keys left unchanged on update aren't re-checked.
+func checkSoftReferences(ctx context.Context, q store.Querier, dialect store.Dialect, reg *metadata.Registry, entity *metadata.Entity, fields, old map[string]any) ([]ErrorDetail, error) {
+	var errs []ErrorDetail
+	for _, rel := range reg.AllRelations() {
+		if !rel.Soft || rel.IsManyToMany() || rel.Target != entity.Name || rel.TargetKey == "" {
+			continue
+		}
+		val, ok := fields[rel.TargetKey]
+		if !ok || val == nil {
+			continue
+		}
+		if prev, had := old[rel.TargetKey]; had && fmt.Sprint(prev) == fmt.Sprint(val) {
+			continue
+		}
+		source := reg.GetEntity(rel.Source)
+		if source == nil {
+			continue
+		}
+
+		sql := fmt.Sprintf("SELECT 1 AS found FROM %s WHERE %s = %s", source.Table, rel.SourceKey, dialect.Placeholder(1))
+		if source.SoftDelete {
+			sql += " AND deleted_at IS NULL"
+		}
+		_, err := store.QueryRow(ctx, q, sql, val)
+		if errors.Is(err, store.ErrNotFound) {
+			errs = append(errs, ErrorDetail{
+				Field:   rel.TargetKey,
+				Rule:    "reference",
+				Message: fmt.Sprintf("%s %v does not exist", source.Name, val),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check %s reference: %w", rel.Name, err)
+		}
+	}
+	return errs, nil
+}
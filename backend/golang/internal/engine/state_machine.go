@@ -56,6 +56,7 @@ func evaluateStateMachine(sm *metadata.StateMachine, fields map[string]any, old
 		if sm.Definition.Initial != "" && newStateStr != sm.Definition.Initial {
 			return []ErrorDetail{{
 				Field:   sm.Field,
+				Code:    "INVALID_INITIAL_STATE",
 				Rule:    "state_machine",
 				Message: fmt.Sprintf("Initial state must be '%s', got '%s'", sm.Definition.Initial, newStateStr),
 			}}
@@ -78,6 +79,7 @@ func evaluateStateMachine(sm *metadata.StateMachine, fields map[string]any, old
 	if transition == nil {
 		return []ErrorDetail{{
 			Field:   sm.Field,
+			Code:    "INVALID_TRANSITION",
 			Rule:    "state_machine",
 			Message: fmt.Sprintf("Invalid transition from '%s' to '%s'", oldState, newStateStr),
 		}}
@@ -94,6 +96,7 @@ func evaluateStateMachine(sm *metadata.StateMachine, fields map[string]any, old
 		if err != nil {
 			return []ErrorDetail{{
 				Field:   sm.Field,
+				Code:    "GUARD_ERROR",
 				Rule:    "state_machine",
 				Message: fmt.Sprintf("Guard evaluation error: %v", err),
 			}}
@@ -102,6 +105,7 @@ func evaluateStateMachine(sm *metadata.StateMachine, fields map[string]any, old
 			msg := fmt.Sprintf("Transition from '%s' to '%s' blocked by guard", oldState, newStateStr)
 			return []ErrorDetail{{
 				Field:   sm.Field,
+				Code:    "GUARD_BLOCKED",
 				Rule:    "state_machine",
 				Message: msg,
 			}}
@@ -136,7 +140,7 @@ func FindTransition(sm *metadata.StateMachine, oldState, newState string) *metad
 func EvaluateGuard(transition *metadata.Transition, env map[string]any) (bool, error) {
 	prog, ok := transition.CompiledGuard.(*vm.Program)
 	if !ok || prog == nil {
-		compiled, err := expr.Compile(transition.Guard, expr.AsBool())
+		compiled, err := compileExpr(transition.Guard, expr.AsBool())
 		if err != nil {
 			return false, fmt.Errorf("compile guard: %w", err)
 		}
@@ -144,7 +148,7 @@ func EvaluateGuard(transition *metadata.Transition, env map[string]any) (bool, e
 		prog = compiled
 	}
 
-	result, err := expr.Run(prog, env)
+	result, err := runExpr(context.Background(), prog, env)
 	if err != nil {
 		return false, fmt.Errorf("evaluate guard: %w", err)
 	}
@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestWrite_StrictAndLenientUnknownFields(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	strict := true
+	entities := []*metadata.Entity{
+		{Name: "invoice", Table: "invoices", StrictFields: &strict},
+		{Name: "note", Table: "notes"},
+	}
+	for _, e := range entities {
+		e.PrimaryKey = metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true}
+		e.Fields = []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "title", Type: "string"}}
+		if err := store.NewMigrator(s).Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load(entities, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	do := func(method, path string, body any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(data, &out)
+		return resp.StatusCode, out
+	}
+	typo := map[string]any{"title": "Q3", "titel": "Q3"}
+
+	// The entity opts in: the typo is named in the 422
+	status, out := do("POST", "/api/invoice", typo)
+	if status != 422 {
+		t.Fatalf("expected strict create to fail with 422, got %d", status)
+	}
+	details := out["error"].(map[string]any)["details"].([]any)
+	if len(details) != 1 || details[0].(map[string]any)["field"] != "titel" || details[0].(map[string]any)["rule"] != "unknown" {
+		t.Fatalf("expected the unknown field to be listed, got %v", details)
+	}
+	status, out = do("POST", "/api/invoice", map[string]any{"title": "Q3"})
+	if status != 201 {
+		t.Fatalf("create: %d %v", status, out)
+	}
+	id := out["data"].(map[string]any)["id"].(string)
+	if status, _ := do("PUT", "/api/invoice/"+id, typo); status != 422 {
+		t.Fatalf("expected strict update to fail with 422, got %d", status)
+	}
+
+	// Lenient by default; the global switch applies to entities that don't say
+	status, out = do("POST", "/api/note", typo)
+	if status != 201 {
+		t.Fatalf("expected lenient create to succeed, got %d: %v", status, out)
+	}
+	note := out["data"].(map[string]any)
+	if _, ok := note["titel"]; ok || note["title"] != "Q3" {
+		t.Fatalf("expected the unknown key to be dropped, got %v", note)
+	}
+	if status, _ := do("PUT", "/api/note/"+note["id"].(string), typo); status != 200 {
+		t.Fatalf("expected lenient update to succeed, got %d", status)
+	}
+
+	defer func(v bool) { StrictFields = v }(StrictFields)
+	StrictFields = true
+	if status, _ := do("PUT", "/api/note/"+note["id"].(string), typo); status != 422 {
+		t.Fatalf("expected the global strict mode to apply, got %d", status)
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestTablePrefix_SeparatesAppsInOneDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Both apps share the entity and relation names; only the prefix differs
+	definitions := func() ([]*metadata.Entity, []*metadata.Relation) {
+		order := &metadata.Entity{
+			Name:       "order",
+			Table:      "orders",
+			PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+			Fields: []metadata.Field{
+				{Name: "id", Type: "uuid"},
+				{Name: "number", Type: "string", Unique: true},
+			},
+		}
+		tag := &metadata.Entity{
+			Name:       "tag",
+			Table:      "tags",
+			PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+			Fields:     []metadata.Field{{Name: "id", Type: "uuid"}, {Name: "label", Type: "string"}},
+		}
+		rel := &metadata.Relation{
+			Name: "order_tags", Type: "many_to_many", Source: "order", Target: "tag", SourceKey: "id",
+			JoinTable: "order_tags", SourceJoinKey: "order_id", TargetJoinKey: "tag_id", Ownership: "none",
+		}
+		return []*metadata.Entity{order, tag}, []*metadata.Relation{rel}
+	}
+
+	for _, prefix := range []string{"alpha_", "beta_"} {
+		s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: dir, Name: "shared", TablePrefix: prefix})
+		if err != nil {
+			t.Fatalf("open sqlite store: %v", err)
+		}
+		defer s.Close()
+
+		entities, relations := definitions()
+		m := store.NewMigrator(s)
+		for _, e := range entities {
+			if err := m.Migrate(ctx, e); err != nil {
+				t.Fatalf("%s: migrate %s: %v", prefix, e.Name, err)
+			}
+		}
+		if err := m.MigrateJoinTable(ctx, s.DB, relations[0], entities[0], entities[1]); err != nil {
+			t.Fatalf("%s: migrate join table: %v", prefix, err)
+		}
+
+		reg := metadata.NewRegistry()
+		reg.SetTablePrefix(s.TablePrefix)
+		entities, relations = definitions()
+		reg.Load(entities, relations)
+		order := reg.GetEntity("order")
+		if order.Table != prefix+"orders" || reg.GetRelation("order_tags").JoinTable != prefix+"order_tags" {
+			t.Fatalf("%s: expected prefixed names in the registry, got %s and %s",
+				prefix, order.Table, reg.GetRelation("order_tags").JoinTable)
+		}
+
+		// The same order number in both apps doesn't collide
+		plan, verrs := PlanWrite(order, reg, map[string]any{"number": "A-1"}, nil)
+		if len(verrs) > 0 {
+			t.Fatalf("%s: unexpected validation errors: %v", prefix, verrs)
+		}
+		if _, err := ExecuteWritePlan(ctx, s, reg, plan); err != nil {
+			t.Fatalf("%s: create: %v", prefix, err)
+		}
+	}
+
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: dir, Name: "shared"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	for _, table := range []string{"alpha_orders", "beta_orders", "alpha_order_tags", "beta_order_tags"} {
+		if ok, err := s.Dialect.TableExists(ctx, s.DB, table); err != nil || !ok {
+			t.Fatalf("expected table %s to exist (%v)", table, err)
+		}
+	}
+	for _, table := range []string{"orders", "order_tags"} {
+		if ok, _ := s.Dialect.TableExists(ctx, s.DB, table); ok {
+			t.Fatalf("expected no unprefixed %s table", table)
+		}
+	}
+	for _, table := range []string{"alpha_orders", "beta_orders"} {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM "+table)
+		if err != nil || toInt(row["n"]) != 1 {
+			t.Fatalf("expected one order in %s, got %v (%v)", table, row, err)
+		}
+		names, err := s.Dialect.IndexNames(ctx, s.DB, table)
+		if err != nil {
+			t.Fatalf("list indexes on %s: %v", table, err)
+		}
+		found := false
+		for _, n := range names {
+			found = found || n == store.UniqueIndexName(table, "number", false)
+		}
+		if !found {
+			t.Fatalf("expected the unique index on %s to be named after it, got %v", table, names)
+		}
+	}
+}
+
+func TestTablePrefix_AppliedOnceByRecord(t *testing.T) {
+	// An entity whose own table starts with the prefix still gets it, so it
+	// can't collide with one the prefix maps onto the same name
+	orders := &metadata.Entity{Name: "order", Table: "orders"}
+	shopOrders := &metadata.Entity{Name: "shop_order", Table: "shop_orders"}
+
+	reg := metadata.NewRegistry()
+	reg.SetTablePrefix("shop_")
+	reg.Load([]*metadata.Entity{orders, shopOrders}, nil)
+	// Reloading the same definitions doesn't prefix them again
+	reg.Load([]*metadata.Entity{orders, shopOrders}, nil)
+
+	if got := reg.GetEntity("order").Table; got != "shop_orders" {
+		t.Fatalf("expected shop_orders, got %s", got)
+	}
+	if got := reg.GetEntity("shop_order").Table; got != "shop_shop_orders" {
+		t.Fatalf("expected shop_shop_orders, got %s", got)
+	}
+}
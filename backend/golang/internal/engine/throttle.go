@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/metadata"
+)
+
+// writeThrottle counts each user's successful (and in-flight) writes per
+// entity for entities with a write_limit. Counts live in memory, so with several instances each
+// one enforces the limit on its own share of the traffic.
+type writeThrottle struct {
+	mu     sync.Mutex
+	writes map[string][]time.Time // entity + user -> write times within the window
+}
+
+func newWriteThrottle() *writeThrottle {
+	return &writeThrottle{writes: map[string][]time.Time{}}
+}
+
+func throttleKey(entity *metadata.Entity, user *metadata.UserContext) string {
+	if user == nil {
+		return entity.Name + "\x00"
+	}
+	return entity.Name + "\x00" + user.ID
+}
+
+// reserve takes n slots from the user's write limit on the entity, in the
+// same locked step that checks them, so concurrent writes can't all pass the
+// check. It returns how long until n slots are free when they aren't, or the
+// reservation; both are zero without a limit. n must not exceed the limit's
+// max.
+func (t *writeThrottle) reserve(entity *metadata.Entity, user *metadata.UserContext, n int, now time.Time) (time.Duration, *writeReservation) {
+	limit := entity.WriteLimit
+	if limit == nil || limit.Max <= 0 || limit.WindowSeconds <= 0 {
+		return 0, nil
+	}
+	window := time.Duration(limit.WindowSeconds) * time.Second
+	key := throttleKey(entity, user)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	recent := t.prune(key, now.Add(-window))
+	if len(recent)+n > limit.Max {
+		// The oldest writes that have to leave the window first
+		return recent[len(recent)+n-limit.Max-1].Add(window).Sub(now), nil
+	}
+	for i := 0; i < n; i++ {
+		recent = append(recent, now)
+	}
+	t.writes[key] = recent
+	return 0, &writeReservation{throttle: t, key: key, at: now, held: n}
+}
+
+// writeReservation is the slots a write took from the limit. Slots its writes
+// used are kept; release gives back the rest, so only successful writes count.
+type writeReservation struct {
+	throttle *writeThrottle
+	key      string
+	at       time.Time
+	held     int
+}
+
+// keep marks n of the reserved slots as used by successful writes.
+func (r *writeReservation) keep(n int) {
+	if r != nil {
+		r.held = max(r.held-n, 0)
+	}
+}
+
+// release returns the slots not kept. Safe to call more than once, and on nil.
+func (r *writeReservation) release() {
+	if r == nil || r.held == 0 {
+		return
+	}
+	t := r.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	recent := t.writes[r.key]
+	for i := len(recent) - 1; i >= 0 && r.held > 0; i-- {
+		if recent[i].Equal(r.at) {
+			recent = append(recent[:i], recent[i+1:]...)
+			r.held--
+		}
+	}
+	r.held = 0
+	if len(recent) == 0 {
+		delete(t.writes, r.key)
+		return
+	}
+	t.writes[r.key] = recent
+}
+
+// prune drops writes older than since. Callers hold mu.
+func (t *writeThrottle) prune(key string, since time.Time) []time.Time {
+	recent := t.writes[key]
+	i := 0
+	for i < len(recent) && !recent[i].After(since) {
+		i++
+	}
+	recent = recent[i:]
+	if len(recent) == 0 {
+		delete(t.writes, key)
+		return nil
+	}
+	t.writes[key] = recent
+	return recent
+}
+
+// checkWriteLimit reserves n writes against the entity's write_limit and
+// rejects them with 429 when they would take the user past it, setting
+// Retry-After to when enough counted writes have left the window. More writes
+// than the limit allows at all are rejected with 422. Callers keep the slots
+// their successful writes used and release the reservation when done.
+func (h *Handler) checkWriteLimit(c *fiber.Ctx, entity *metadata.Entity, user *metadata.UserContext, n int) (*writeReservation, error) {
+	if limit := entity.WriteLimit; limit != nil && limit.Max > 0 && limit.WindowSeconds > 0 && n > limit.Max {
+		return nil, ValidationError([]ErrorDetail{{Field: "records", Rule: "max", Message: fmt.Sprintf(
+			"at most %d records per request: %s allows %d writes per %d seconds", limit.Max, entity.Name, limit.Max, limit.WindowSeconds)}})
+	}
+	wait, reservation := h.throttle.reserve(entity, user, n, time.Now())
+	if wait <= 0 {
+		return reservation, nil
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return nil, NewAppError("TOO_MANY_WRITES", 429, fmt.Sprintf(
+		"Write limit for %s reached: %d writes per %d seconds", entity.Name, entity.WriteLimit.Max, entity.WriteLimit.WindowSeconds))
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestWriteLimit_ThrottlesWritesBeyondTheLimit(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "comment",
+		Table:      "comments",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		WriteLimit: &metadata.WriteLimit{Max: 2, WindowSeconds: 60},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "body", Type: "string", Required: true},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: c.Get("X-Test-User"), Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Post("/api/:entity/_bulk", h.BulkCreate)
+
+	send := func(path, user string, body map[string]any) *http.Response {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		return resp
+	}
+	post := func(user string, body map[string]any) *http.Response {
+		return send("/api/comment", user, body)
+	}
+	bulk := func(user string, n int) *http.Response {
+		records := make([]map[string]any, n)
+		for i := range records {
+			records[i] = map[string]any{"body": "bulk"}
+		}
+		return send("/api/comment/_bulk", user, map[string]any{"records": records})
+	}
+
+	// A batch has to fit in the remaining limit as a whole
+	if resp := bulk("u3", 3); resp.StatusCode != 422 {
+		t.Fatalf("expected a batch larger than the limit to be rejected, got %d", resp.StatusCode)
+	}
+	if resp := post("u3", map[string]any{"body": "first"}); resp.StatusCode != 201 {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	if resp := bulk("u3", 2); resp.StatusCode != 429 {
+		t.Fatalf("expected a batch past the remaining limit to be throttled, got %d", resp.StatusCode)
+	}
+	if resp := bulk("u3", 1); resp.StatusCode != 200 {
+		t.Fatalf("expected a batch within the remaining limit to succeed, got %d", resp.StatusCode)
+	}
+
+	// Failed writes don't count against the limit
+	if resp := post("u1", map[string]any{}); resp.StatusCode != 422 {
+		t.Fatalf("expected a validation error, got %d", resp.StatusCode)
+	}
+	for i := 0; i < 2; i++ {
+		if resp := post("u1", map[string]any{"body": "hi"}); resp.StatusCode != 201 {
+			t.Fatalf("write %d: expected 201, got %d", i+1, resp.StatusCode)
+		}
+	}
+	resp := post("u1", map[string]any{"body": "spam"})
+	if resp.StatusCode != 429 {
+		t.Fatalf("expected the third write in the window to be throttled, got %d", resp.StatusCode)
+	}
+	if ra := resp.Header.Get("Retry-After"); ra == "" || ra == "0" {
+		t.Fatalf("expected a Retry-After header, got %q", ra)
+	}
+	if resp := post("u2", map[string]any{"body": "hello"}); resp.StatusCode != 201 {
+		t.Fatalf("expected another user to be unaffected, got %d", resp.StatusCode)
+	}
+}
+
+func TestWriteThrottle_ConcurrentWritesCannotOvershoot(t *testing.T) {
+	entity := &metadata.Entity{Name: "comment", WriteLimit: &metadata.WriteLimit{Max: 3, WindowSeconds: 60}}
+	user := &metadata.UserContext{ID: "u1"}
+	throttle := newWriteThrottle()
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var granted []*writeReservation
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if wait, r := throttle.reserve(entity, user, 1, now); wait == 0 {
+				mu.Lock()
+				granted = append(granted, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(granted) != 3 {
+		t.Fatalf("expected exactly 3 of 20 concurrent writes let through, got %d", len(granted))
+	}
+
+	// A write that fails gives its slot back; one that succeeded keeps it
+	granted[0].keep(1)
+	granted[0].release()
+	granted[1].release()
+	if wait, _ := throttle.reserve(entity, user, 1, now); wait != 0 {
+		t.Fatal("expected the released slot to be free again")
+	}
+	if wait, _ := throttle.reserve(entity, user, 1, now); wait == 0 {
+		t.Fatal("expected the kept slot to still count")
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestEntityTimestamps_UpdateAdvancesOnlyUpdatedAt(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "note",
+		Table:      "notes",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Timestamps: true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "body", Type: "string"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	send := func(method, path string, body map[string]any) map[string]any {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: %d %v", method, path, resp.StatusCode, out)
+		}
+		return out["data"].(map[string]any)
+	}
+
+	// Client-supplied timestamps are ignored
+	created := send("POST", "/api/note", map[string]any{"body": "draft", "created_at": "1999-01-01T00:00:00Z"})
+	id := created["id"].(string)
+	stamps, err := store.QueryRow(ctx, s.DB, "SELECT created_at, updated_at FROM notes WHERE id = ?1", id)
+	if err != nil || stamps["created_at"] == nil || stamps["updated_at"] == nil {
+		t.Fatalf("expected both timestamps to be stamped on insert, got %v (%v)", stamps, err)
+	}
+	if stamps["created_at"] == "1999-01-01T00:00:00Z" {
+		t.Fatal("expected a client-supplied created_at to be ignored")
+	}
+
+	// Backdate both so the update is visible at SQLite's one-second resolution
+	const past = "2000-01-01 00:00:00"
+	if _, err := store.Exec(ctx, s.DB, "UPDATE notes SET created_at = ?1, updated_at = ?1 WHERE id = ?2", past, id); err != nil {
+		t.Fatalf("backdate: %v", err)
+	}
+	send("PUT", "/api/note/"+id, map[string]any{"body": "final", "updated_at": past})
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT created_at, updated_at FROM notes WHERE id = ?1", id)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if row["created_at"] != past {
+		t.Fatalf("expected created_at to be unchanged, got %v", row["created_at"])
+	}
+	if row["updated_at"] == past || row["updated_at"] == nil {
+		t.Fatalf("expected updated_at to advance, got %v", row["updated_at"])
+	}
+}
+
+func TestEntityAttribution_RecordsActingUser(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:        "note",
+		Table:       "notes",
+		PrimaryKey:  metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Attribution: true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "body", Type: "string"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	h := NewHandler(s, reg)
+
+	actor := "alice"
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: actor, Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+
+	send := func(method, path string, body map[string]any) map[string]any {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: %d %v", method, path, resp.StatusCode, out)
+		}
+		return out["data"].(map[string]any)
+	}
+
+	// Client-supplied values are replaced by the acting user
+	created := send("POST", "/api/note", map[string]any{"body": "draft", "created_by": "mallory"})
+	if created["created_by"] != "alice" || created["updated_by"] != "alice" {
+		t.Fatalf("expected alice as creator and updater, got %v", created)
+	}
+
+	actor = "bob"
+	updated := send("PUT", "/api/note/"+created["id"].(string), map[string]any{"body": "final", "updated_by": "mallory"})
+	if updated["created_by"] != "alice" || updated["updated_by"] != "bob" {
+		t.Fatalf("expected alice as creator and bob as updater, got %v", updated)
+	}
+
+	// System writes have no user and leave updated_by null
+	plan, verrs := PlanWrite(entity, reg, map[string]any{"body": "by a job"}, created["id"])
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", verrs)
+	}
+	rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+	if err != nil {
+		t.Fatalf("system update: %v", err)
+	}
+	if rec["created_by"] != "alice" || rec["updated_by"] != nil {
+		t.Fatalf("expected a null updated_by after a system write, got %v", rec)
+	}
+}
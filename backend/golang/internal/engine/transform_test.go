@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestExecuteWritePlan_LowerTransformNormalizesValue(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "member",
+		Table:      "members",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "email", Type: "string", Required: true, Transform: []string{"trim", "lower"}},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	plan, verrs := PlanWrite(entity, reg, map[string]any{"email": "  Alice@Example.COM "}, nil)
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", verrs)
+	}
+	if _, err := ExecuteWritePlan(ctx, s, reg, plan); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT email FROM members")
+	if err != nil {
+		t.Fatalf("fetch member: %v", err)
+	}
+	if row["email"] != "alice@example.com" {
+		t.Fatalf("expected normalized email, got %q", row["email"])
+	}
+
+	// Transforms are idempotent
+	f := entity.Fields[1]
+	if got := f.ApplyTransforms(f.ApplyTransforms("  Bob@X.io")); got != "bob@x.io" {
+		t.Fatalf("expected idempotent transform, got %q", got)
+	}
+
+	// Whitespace-only input trims to empty and fails the required check
+	if plan, _ := PlanWrite(entity, reg, map[string]any{"email": "   "}, nil); len(plan.FieldErrors) != 1 || plan.FieldErrors[0].Rule != "required" {
+		t.Fatalf("expected required error after trim, got %v", plan.FieldErrors)
+	}
+}
+
+func TestExecuteWritePlan_EmptyStringsStoredAsNull(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	EmptyStrings = "null"
+	defer func() { EmptyStrings = "keep" }()
+
+	entity := &metadata.Entity{
+		Name:       "member",
+		Table:      "members",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "handle", Type: "string", Nullable: true, Unique: true},
+			{Name: "note", Type: "text", Nullable: true, EmptyStrings: "keep"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	// Two blank handles are both null, so they don't collide on the unique index
+	var ids []any
+	for range 2 {
+		plan, verrs := PlanWrite(entity, reg, map[string]any{"handle": "", "note": ""}, nil)
+		if len(verrs) > 0 {
+			t.Fatalf("unexpected validation errors: %v", verrs)
+		}
+		rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if rec["handle"] != nil || rec["note"] != "" {
+			t.Fatalf("expected a null handle and the note kept as \"\", got %v", rec)
+		}
+		ids = append(ids, rec["id"])
+	}
+
+	plan, verrs := PlanWrite(entity, reg, map[string]any{"handle": "ada"}, ids[0])
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", verrs)
+	}
+	if _, err := ExecuteWritePlan(ctx, s, reg, plan); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	plan, verrs = PlanWrite(entity, reg, map[string]any{"handle": ""}, ids[0])
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", verrs)
+	}
+	rec, err := ExecuteWritePlan(ctx, s, reg, plan)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if rec["handle"] != nil {
+		t.Fatalf("expected clearing the handle with \"\" to store null, got %v", rec["handle"])
+	}
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestExecuteWritePlan_CaseInsensitiveUniqueConflict(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	entity := &metadata.Entity{
+		Name:       "member",
+		Table:      "members",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "email", Type: "string", Required: true, Unique: true, CaseInsensitive: true},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	write := func(email string) error {
+		plan, verrs := PlanWrite(entity, reg, map[string]any{"email": email}, nil)
+		if len(verrs) > 0 {
+			t.Fatalf("unexpected validation errors: %v", verrs)
+		}
+		_, err := ExecuteWritePlan(ctx, s, reg, plan)
+		return err
+	}
+
+	if err := write("A@x.com"); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	err := write("a@x.com")
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError, got %v", err)
+	}
+	if appErr.Status != 409 {
+		t.Fatalf("expected 409, got %d", appErr.Status)
+	}
+	if len(appErr.Details) != 1 || appErr.Details[0].Field != "email" {
+		t.Fatalf("expected conflict on email, got %+v", appErr.Details)
+	}
+}
+
+func TestCaseInsensitiveClause(t *testing.T) {
+	field := &metadata.Field{Name: "email", Type: "string", CaseInsensitive: true}
+	wc := caseInsensitiveClause(field, WhereClause{Field: "email", Operator: "eq", Value: "A@X.com"})
+	if wc.Field != "LOWER(email)" || wc.Value != "a@x.com" {
+		t.Fatalf("unexpected clause: %+v", wc)
+	}
+
+	wc = caseInsensitiveClause(field, WhereClause{Field: "email", Operator: "gt", Value: "A"})
+	if wc.Field != "email" {
+		t.Fatalf("expected gt to be left untouched, got %+v", wc)
+	}
+}
+
+func TestExecuteWritePlan_PartialUniqueOnlyAppliesToMatchingRows(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	entity := &metadata.Entity{
+		Name:       "plan",
+		Table:      "plans",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "code", Type: "string", Required: true, Unique: true, UniqueWhere: map[string]any{"active": true}},
+			{Name: "active", Type: "boolean"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+
+	write := func(code string, active bool) error {
+		plan, verrs := PlanWrite(entity, reg, map[string]any{"code": code, "active": active}, nil)
+		if len(verrs) > 0 {
+			t.Fatalf("unexpected validation errors: %v", verrs)
+		}
+		_, err := ExecuteWritePlan(ctx, s, reg, plan)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := write("basic", false); err != nil {
+			t.Fatalf("inactive row %d: expected duplicates outside the condition to be allowed: %v", i, err)
+		}
+	}
+	if err := write("basic", true); err != nil {
+		t.Fatalf("first active row: %v", err)
+	}
+	err := write("basic", true)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Status != 409 {
+		t.Fatalf("expected 409 for a second active row, got %v", err)
+	}
+	if len(appErr.Details) != 1 || appErr.Details[0].Field != "code" {
+		t.Fatalf("expected conflict on code, got %+v", appErr.Details)
+	}
+
+	// Changing the condition replaces the index rather than adding a second one
+	entity.Fields[1].UniqueWhere = map[string]any{"active.neq": false}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("re-migrate: %v", err)
+	}
+	indexes, err := s.Dialect.IndexNames(ctx, s.DB, "plans")
+	if err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+	var codeIndexes []string
+	for _, name := range indexes {
+		if strings.HasPrefix(name, "idx_plans_code") {
+			codeIndexes = append(codeIndexes, name)
+		}
+	}
+	if len(codeIndexes) != 1 || codeIndexes[0] != store.FieldUniqueIndexName("plans", entity.Fields[1]) {
+		t.Fatalf("expected only the new partial index, got %v", codeIndexes)
+	}
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestValidation_ReportsEveryViolationWithFieldAndCode(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	entity := &metadata.Entity{
+		Name:       "order",
+		Table:      "orders",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "customer", Type: "string", Required: true},
+			{Name: "channel", Type: "string", Enum: []string{"web", "store"}},
+			{Name: "status", Type: "string"},
+			{Name: "qty", Type: "int"},
+			{Name: "total", Type: "int"},
+			{Name: "discount", Type: "int"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	reg.LoadRules([]*metadata.Rule{
+		{ID: "r1", Entity: "order", Hook: "before_write", Type: "field", Active: true,
+			Definition: metadata.RuleDefinition{Field: "qty", Operator: "min", Value: 1}},
+		{ID: "r2", Entity: "order", Hook: "before_write", Type: "expression", Active: true,
+			Definition: metadata.RuleDefinition{Field: "discount", Expression: "record.discount > record.total", Message: "Discount exceeds total"}},
+	})
+	reg.LoadStateMachines([]*metadata.StateMachine{{
+		ID: "sm1", Entity: "order", Field: "status", Active: true,
+		Definition: metadata.StateMachineDefinition{Initial: "draft", Transitions: []metadata.Transition{
+			{From: metadata.TransitionFrom{"draft"}, To: "placed"},
+		}},
+	}})
+
+	codes := func(details []ErrorDetail) map[string]string {
+		t.Helper()
+		got := map[string]string{}
+		for _, d := range details {
+			if d.Field == "" || d.Code == "" || d.Message == "" {
+				t.Fatalf("expected field, code and message on every detail, got %+v", d)
+			}
+			got[d.Field] = d.Code
+		}
+		return got
+	}
+
+	// Field constraints, a field rule, an expression rule and a state machine
+	// broken in one write
+	plan, verrs := PlanWrite(entity, reg, map[string]any{
+		"channel": "fax", "status": "shipped", "qty": 0, "total": 10, "discount": 50,
+	}, nil)
+	if len(verrs) > 0 {
+		t.Fatalf("unexpected planning errors: %v", verrs)
+	}
+	_, err = ExecuteWritePlan(ctx, s, reg, plan)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Status != 422 {
+		t.Fatalf("expected a 422, got %v", err)
+	}
+	got := codes(appErr.Details)
+	if len(got) != 5 || got["customer"] != "REQUIRED" || got["channel"] != "ENUM" ||
+		got["qty"] != "MIN" || got["discount"] != "EXPRESSION" || got["status"] != "INVALID_INITIAL_STATE" {
+		t.Fatalf("expected the field, rule, expression and state violations together, got %+v", appErr.Details)
+	}
+}
+
+func TestExpressionRule_ReportsOnlyItsOwnField(t *testing.T) {
+	rule := &metadata.Rule{ID: "r1", Entity: "order", Hook: "before_write", Type: "expression", Active: true,
+		Definition: metadata.RuleDefinition{Expression: "record.discount > record.total"}}
+	detail := EvaluateExpressionRule(context.Background(), rule, map[string]any{"record": map[string]any{"discount": 5, "total": 1}})
+	if detail == nil || detail.Field != "" || detail.Code != "EXPRESSION" {
+		t.Fatalf("expected a violation with no field, got %+v", detail)
+	}
+}
@@ -3,24 +3,39 @@ package engine
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/expr-lang/expr"
-	"github.com/google/uuid"
 
 	"rocket-backend/internal/instrument"
 	"rocket-backend/internal/metadata"
 	"rocket-backend/internal/store"
 )
 
-var webhookHTTPClient = &http.Client{Timeout: 30 * time.Second}
+var webhookHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+	// No proxy: every connection must go through the target check
+	Transport: &http.Transport{DialContext: dialWebhookTarget, ForceAttemptHTTP2: true, TLSHandshakeTimeout: 10 * time.Second},
+}
+
+// WebhookDedupWindow is how long a delivered event suppresses an identical one
+// (same webhook and idempotency key). Zero disables deduplication.
+var WebhookDedupWindow = 60 * time.Second
+
+// inflightWebhooks holds webhook id + idempotency key for async deliveries that
+// have not been logged yet, so duplicates fired back to back are dropped too.
+var inflightWebhooks sync.Map
 
 // WebhookPayload is the JSON body sent to webhook endpoints.
 type WebhookPayload struct {
@@ -43,7 +58,7 @@ func BuildWebhookPayload(hook, entity, action string, record, old map[string]any
 		Action:         action,
 		Record:         record,
 		Timestamp:      time.Now().UTC().Format(time.RFC3339),
-		IdempotencyKey: "wh_" + uuid.New().String(),
+		IdempotencyKey: WebhookIdempotencyKey(entity, webhookRecordID(record, old), hook, action, record, old),
 	}
 	if old != nil {
 		p.Old = old
@@ -55,6 +70,29 @@ func BuildWebhookPayload(hook, entity, action string, record, old map[string]any
 	return p
 }
 
+// WebhookIdempotencyKey derives the key identifying an entity event from the
+// entity, record id, hook, action and a hash of the changed fields (the whole
+// record when there is no previous version). Identical events get the same
+// key, whichever write raised them, so a retried bulk operation is delivered
+// once. After-hooks of a create carry the inserted record, id included.
+func WebhookIdempotencyKey(entity string, recordID any, hook, action string, record, old map[string]any) string {
+	changed := record
+	if old != nil {
+		changed = computeChanges(record, old)
+	}
+	changedJSON, _ := json.Marshal(changed)
+	changeHash := sha256.Sum256(changedJSON)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%v|%s|%s|%x", entity, recordID, hook, action, changeHash)))
+	return "wh_" + hex.EncodeToString(sum[:16])
+}
+
+func webhookRecordID(record, old map[string]any) any {
+	if id, ok := record["id"]; ok && id != nil {
+		return id
+	}
+	return old["id"]
+}
+
 // computeChanges returns a map of field -> {old, new} for changed fields.
 func computeChanges(record, old map[string]any) map[string]any {
 	changes := map[string]any{}
@@ -114,13 +152,13 @@ func EvaluateWebhookCondition(wh *metadata.Webhook, payload *WebhookPayload) (bo
 
 	// Lazy-compile and cache the condition program
 	if wh.CompiledCondition == nil {
-		prog, err := expr.Compile(wh.Condition, expr.AsBool())
+		prog, err := compileExpr(wh.Condition, expr.AsBool())
 		if err != nil {
 			return false, fmt.Errorf("compile webhook condition: %w", err)
 		}
 		wh.CompiledCondition = prog
 	}
-	result, err := expr.Run(wh.CompiledCondition, env)
+	result, err := runExpr(context.Background(), wh.CompiledCondition, env)
 	if err != nil {
 		return false, fmt.Errorf("evaluate webhook condition: %w", err)
 	}
@@ -145,6 +183,12 @@ func DispatchWebhook(ctx context.Context, url, method string, headers map[string
 	span.SetMetadata("url", url)
 	span.SetMetadata("method", method)
 
+	if err := WebhookTargets.CheckURL(ctx, url, false); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return &DispatchResult{Error: err.Error()}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(bodyJSON))
 	if err != nil {
 		span.SetStatus("error")
@@ -182,6 +226,12 @@ func DispatchWebhook(ctx context.Context, url, method string, headers map[string
 
 // LogWebhookDelivery inserts a row into _webhook_logs.
 func LogWebhookDelivery(ctx context.Context, q store.Querier, dialect store.Dialect, wh *metadata.Webhook, payload *WebhookPayload, headers map[string]string, bodyJSON []byte, result *DispatchResult) {
+	logWebhookDelivery(ctx, q, dialect, wh, payload.IdempotencyKey, headers, bodyJSON, result)
+}
+
+// logWebhookDelivery inserts the log row and returns its summary (see
+// webhookLogEntry), or nil if it couldn't be written.
+func logWebhookDelivery(ctx context.Context, q store.Querier, dialect store.Dialect, wh *metadata.Webhook, idempotencyKey string, headers map[string]string, bodyJSON []byte, result *DispatchResult) map[string]any {
 	status := "delivered"
 	errMsg := result.Error
 	if errMsg != "" || result.StatusCode < 200 || result.StatusCode >= 300 {
@@ -211,11 +261,36 @@ func LogWebhookDelivery(ctx context.Context, q store.Querier, dialect store.Dial
 			pb.Add(id), pb.Add(wh.ID), pb.Add(wh.Entity), pb.Add(wh.Hook), pb.Add(wh.URL), pb.Add(wh.Method),
 			pb.Add(string(headersJSON)), pb.Add(string(bodyJSON)),
 			pb.Add(result.StatusCode), pb.Add(result.ResponseBody),
-			pb.Add(status), pb.Add(1), pb.Add(wh.Retry.MaxAttempts), pb.Add(nextRetry), pb.Add(errMsg), pb.Add(payload.IdempotencyKey)),
+			pb.Add(status), pb.Add(1), pb.Add(wh.Retry.MaxAttempts), pb.Add(nextRetry), pb.Add(errMsg), pb.Add(idempotencyKey)),
 		pb.Params()...)
 	if err != nil {
 		log.Printf("ERROR: failed to log webhook delivery for %s: %v", wh.ID, err)
+		return nil
+	}
+	return webhookLogEntry(id, wh.ID, wh.Entity, wh.Hook, wh.URL, status, 1, wh.Retry.MaxAttempts, result.StatusCode, errMsg)
+}
+
+// recentDelivery returns the stored result of a delivery of the same event by wh
+// within WebhookDedupWindow, or nil if there is none.
+func recentDelivery(ctx context.Context, q store.Querier, dialect store.Dialect, wh *metadata.Webhook, idempotencyKey string) *DispatchResult {
+	if WebhookDedupWindow <= 0 {
+		return nil
 	}
+	pb := dialect.NewParamBuilder()
+	whereWindow := dialect.IntervalSinceExpr("created_at", pb, fmt.Sprintf("%d", int(WebhookDedupWindow.Seconds())))
+	row, err := store.QueryRow(ctx, q,
+		fmt.Sprintf(`SELECT response_status, response_body FROM _webhook_logs
+		 WHERE webhook_id = %s AND idempotency_key = %s AND status = 'delivered' AND %s
+		 ORDER BY created_at DESC LIMIT 1`, pb.Add(wh.ID), pb.Add(idempotencyKey), whereWindow),
+		pb.Params()...)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("ERROR: webhook %s dedup lookup: %v", wh.ID, err)
+		}
+		return nil
+	}
+	body, _ := row["response_body"].(string)
+	return &DispatchResult{StatusCode: toInt(row["response_status"]), ResponseBody: body}
 }
 
 // FireAsyncWebhooks dispatches async webhooks for an entity hook after commit.
@@ -244,12 +319,29 @@ func FireAsyncWebhooks(ctx context.Context, s *store.Store, reg *metadata.Regist
 			continue
 		}
 
+		if wh.Batch != nil {
+			QueueBatchedWebhook(ctx, s, reg, wh, payload)
+			continue
+		}
+
+		inflightKey := wh.ID + ":" + payload.IdempotencyKey
+		if _, busy := inflightWebhooks.LoadOrStore(inflightKey, struct{}{}); busy {
+			log.Printf("Webhook %s: skipping duplicate event %s", wh.ID, payload.IdempotencyKey)
+			continue
+		}
+
 		// Dispatch in background goroutine
 		go func(wh *metadata.Webhook) {
+			defer inflightWebhooks.Delete(inflightKey)
+			if recentDelivery(context.Background(), s.DB, s.Dialect, wh, payload.IdempotencyKey) != nil {
+				log.Printf("Webhook %s: event %s already delivered, skipping", wh.ID, payload.IdempotencyKey)
+				return
+			}
 			headers := ResolveHeaders(wh.Headers)
 			bodyJSON, _ := json.Marshal(payload)
-			result := DispatchWebhook(context.Background(), wh.URL, wh.Method, headers, bodyJSON)
-			LogWebhookDelivery(context.Background(), s.DB, s.Dialect, wh, payload, headers, bodyJSON, result)
+			result := deliverWebhook(context.Background(), wh, headers, bodyJSON)
+			entry := logWebhookDelivery(context.Background(), s.DB, s.Dialect, wh, payload.IdempotencyKey, headers, bodyJSON, result)
+			fireWebhookFailed(context.Background(), s, reg, entry)
 		}(wh)
 	}
 }
@@ -279,12 +371,22 @@ func FireSyncWebhooks(ctx context.Context, tx store.Querier, dialect store.Diale
 			continue
 		}
 
-		headers := ResolveHeaders(wh.Headers)
-		bodyJSON, _ := json.Marshal(payload)
-		result := DispatchWebhook(ctx, wh.URL, wh.Method, headers, bodyJSON)
+		// An identical event delivered recently reuses that result instead of
+		// re-sending. A before-hook gates the write it precedes, so it is always
+		// asked afresh.
+		var result *DispatchResult
+		if !strings.HasPrefix(hook, "before_") {
+			result = recentDelivery(ctx, tx, dialect, wh, payload.IdempotencyKey)
+		}
+		if result == nil {
+			headers := ResolveHeaders(wh.Headers)
+			bodyJSON, _ := json.Marshal(payload)
+			result = deliverWebhook(ctx, wh, headers, bodyJSON)
 
-		// Log delivery (inside the transaction)
-		LogWebhookDelivery(ctx, tx, dialect, wh, payload, headers, bodyJSON, result)
+			// Log delivery (inside the transaction). A failure rolls the write
+			// back along with its log, so meta-webhooks aren't told about it.
+			LogWebhookDelivery(ctx, tx, dialect, wh, payload, headers, bodyJSON, result)
+		}
 
 		if result.Error != "" {
 			return fmt.Errorf("webhook %s failed: %s", wh.ID, result.Error)
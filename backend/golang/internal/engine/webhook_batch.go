@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+var (
+	// batchFlushLocks serialises flushes per webhook within this process. Events
+	// are also claimed in the database, so other processes don't send them too.
+	batchFlushLocks sync.Map // webhook ID -> *sync.Mutex
+	// batchTimers marks webhooks with an interval flush already scheduled.
+	batchTimers sync.Map // webhook ID -> struct{}
+)
+
+// staleBatchClaim is how long events may stay claimed ('sending') before they
+// are queued again, e.g. after the claiming process died mid-delivery.
+const staleBatchClaim = 5 * time.Minute
+
+// QueueBatchedWebhook stores an event for a batched webhook as a 'queued' log row.
+// The batch is flushed in the background once it is full; the first queued event
+// schedules a flush after the batch interval for whatever has accumulated by then.
+func QueueBatchedWebhook(ctx context.Context, s *store.Store, reg *metadata.Registry, wh *metadata.Webhook, payload *WebhookPayload) {
+	bodyJSON, _ := json.Marshal(payload)
+	pb := s.Dialect.NewParamBuilder()
+	_, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf(`INSERT INTO _webhook_logs (id, webhook_id, entity, hook, url, method, request_body, status, attempt, max_attempts, idempotency_key)
+		 VALUES (%s, %s, %s, %s, %s, %s, %s, 'queued', 0, %s, %s)`,
+			pb.Add(store.GenerateUUID()), pb.Add(wh.ID), pb.Add(wh.Entity), pb.Add(wh.Hook), pb.Add(wh.URL), pb.Add(wh.Method),
+			pb.Add(string(bodyJSON)), pb.Add(wh.Retry.MaxAttempts), pb.Add(payload.IdempotencyKey)),
+		pb.Params()...)
+	if err != nil {
+		log.Printf("ERROR: failed to queue batched webhook %s: %v", wh.ID, err)
+		return
+	}
+
+	pb = s.Dialect.NewParamBuilder()
+	row, err := store.QueryRow(ctx, s.DB,
+		fmt.Sprintf("SELECT COUNT(*) AS n FROM _webhook_logs WHERE webhook_id = %s AND status = 'queued'", pb.Add(wh.ID)),
+		pb.Params()...)
+	if err == nil && toInt(row["n"]) >= batchSize(wh) {
+		go flushWebhookBatch(context.Background(), s, reg, wh, false)
+		return
+	}
+
+	if _, pending := batchTimers.LoadOrStore(wh.ID, struct{}{}); !pending {
+		time.AfterFunc(batchInterval(wh), func() {
+			batchTimers.Delete(wh.ID)
+			flushWebhookBatch(context.Background(), s, reg, wh, true)
+		})
+	}
+}
+
+// ProcessWebhookBatches flushes queued events of batched webhooks that have no
+// interval flush scheduled in this process, e.g. events queued before a restart.
+// Events left claimed by a flush that never finished are queued again first.
+func ProcessWebhookBatches(s *store.Store, reg *metadata.Registry) {
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(context.Background(), s.DB,
+		fmt.Sprintf("UPDATE _webhook_logs SET status = 'queued' WHERE status = 'sending' AND updated_at < %s",
+			pb.Add(s.Dialect.TimeParam(time.Now().Add(-staleBatchClaim)))),
+		pb.Params()...); err != nil {
+		log.Printf("ERROR: requeue stale webhook batch events: %v", err)
+	}
+	for _, wh := range reg.AllWebhooks() {
+		if wh.Batch == nil || !wh.Active || !wh.Async {
+			continue
+		}
+		if _, pending := batchTimers.Load(wh.ID); pending {
+			continue
+		}
+		flushWebhookBatch(context.Background(), s, reg, wh, true)
+	}
+}
+
+// flushWebhookBatch delivers queued events as array payloads of up to the batch
+// size. A trailing partial batch is only sent when partial is true. Each delivery
+// is logged as a single _webhook_logs row, so retries resend the whole batch.
+func flushWebhookBatch(ctx context.Context, s *store.Store, reg *metadata.Registry, wh *metadata.Webhook, partial bool) {
+	mu, _ := batchFlushLocks.LoadOrStore(wh.ID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	size := batchSize(wh)
+	for {
+		pb := s.Dialect.NewParamBuilder()
+		rows, err := store.QueryRows(ctx, s.DB,
+			fmt.Sprintf(`SELECT id, request_body FROM _webhook_logs
+			 WHERE webhook_id = %s AND status = 'queued'
+			   AND NOT EXISTS (SELECT 1 FROM _webhooks w WHERE w.id = _webhook_logs.webhook_id AND NOT w.active)
+			 ORDER BY created_at ASC, id ASC
+			 LIMIT %d`, pb.Add(wh.ID), size),
+			pb.Params()...)
+		if err != nil {
+			log.Printf("ERROR: webhook batch query for %s: %v", wh.ID, err)
+			return
+		}
+		if len(rows) == 0 || (len(rows) < size && !partial) {
+			return
+		}
+
+		ids, events, err := claimBatchEvents(ctx, s, rows)
+		if err != nil {
+			log.Printf("ERROR: claim queued webhook events for %s: %v", wh.ID, err)
+			releaseBatchEvents(ctx, s, wh, ids)
+			return
+		}
+		if len(ids) == 0 || (len(ids) < size && !partial) {
+			// Another process took some of them; hand back the rest for a later flush
+			releaseBatchEvents(ctx, s, wh, ids)
+			return
+		}
+
+		headers := ResolveHeaders(wh.Headers)
+		bodyJSON, _ := json.Marshal(events)
+		result := deliverWebhook(ctx, wh, headers, bodyJSON)
+		entry := logWebhookDelivery(ctx, s.DB, s.Dialect, wh, "whb_"+uuid.New().String(), headers, bodyJSON, result)
+		fireWebhookFailed(ctx, s, reg, entry)
+
+		pb = s.Dialect.NewParamBuilder()
+		if _, err := store.Exec(ctx, s.DB,
+			"DELETE FROM _webhook_logs WHERE "+s.Dialect.InExpr("id", pb, ids), pb.Params()...); err != nil {
+			log.Printf("ERROR: clear queued webhook events for %s: %v", wh.ID, err)
+			return
+		}
+		if len(rows) < size {
+			return
+		}
+	}
+}
+
+// claimBatchEvents moves queued events to 'sending' with one conditional update
+// per row, so when several processes flush the same webhook each event is
+// claimed, and delivered, by only one of them. It returns the claimed events.
+func claimBatchEvents(ctx context.Context, s *store.Store, rows []map[string]any) ([]any, []json.RawMessage, error) {
+	var ids []any
+	var events []json.RawMessage
+	for _, row := range rows {
+		pb := s.Dialect.NewParamBuilder()
+		n, err := store.Exec(ctx, s.DB,
+			fmt.Sprintf("UPDATE _webhook_logs SET status = 'sending', updated_at = %s WHERE id = %s AND status = 'queued'",
+				s.Dialect.NowExpr(), pb.Add(row["id"])),
+			pb.Params()...)
+		if err != nil {
+			return ids, events, err
+		}
+		if n != 1 {
+			continue
+		}
+		ids = append(ids, row["id"])
+		switch v := row["request_body"].(type) {
+		case string:
+			events = append(events, json.RawMessage(v))
+		case []byte:
+			events = append(events, json.RawMessage(v))
+		default:
+			b, _ := json.Marshal(v)
+			events = append(events, b)
+		}
+	}
+	return ids, events, nil
+}
+
+// releaseBatchEvents puts claimed events that weren't sent back in the queue.
+func releaseBatchEvents(ctx context.Context, s *store.Store, wh *metadata.Webhook, ids []any) {
+	if len(ids) == 0 {
+		return
+	}
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, s.DB,
+		"UPDATE _webhook_logs SET status = 'queued' WHERE status = 'sending' AND "+s.Dialect.InExpr("id", pb, ids),
+		pb.Params()...); err != nil {
+		log.Printf("ERROR: release webhook batch events for %s: %v", wh.ID, err)
+	}
+}
+
+func batchSize(wh *metadata.Webhook) int {
+	if wh.Batch.Size < 1 {
+		return 1
+	}
+	return wh.Batch.Size
+}
+
+func batchInterval(wh *metadata.Webhook) time.Duration {
+	if wh.Batch.IntervalMs <= 0 {
+		return time.Second
+	}
+	return time.Duration(wh.Batch.IntervalMs) * time.Millisecond
+}
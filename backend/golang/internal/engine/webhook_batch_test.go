@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestFireAsyncWebhooks_BatchesRapidChanges(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	received := make(chan []byte, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	wh := &metadata.Webhook{
+		ID: store.GenerateUUID(), Entity: "order", Hook: "after_write", URL: srv.URL, Method: "POST",
+		Async: true, Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 3},
+		Batch: &metadata.WebhookBatch{Size: 2, IntervalMs: 60000},
+	}
+	if _, err := store.Exec(ctx, s.DB,
+		"INSERT INTO _webhooks (id, entity, hook, url, batch) VALUES (?1, 'order', 'after_write', ?2, '{\"size\": 2, \"interval_ms\": 60000}')",
+		wh.ID, srv.URL); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWebhooks([]*metadata.Webhook{wh})
+
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "create", map[string]any{"id": "o1"}, nil, nil)
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "update", map[string]any{"id": "o1", "status": "paid"}, map[string]any{"id": "o1"}, nil)
+
+	var body []byte
+	select {
+	case body = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a batched delivery once the batch was full")
+	}
+	var events []WebhookPayload
+	if err := json.Unmarshal(body, &events); err != nil || len(events) != 2 {
+		t.Fatalf("expected an array of 2 events, got %s (%v)", body, err)
+	}
+	select {
+	case extra := <-received:
+		t.Fatalf("expected a single request, got another: %s", extra)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// One log row for the batch, no events left in the queue
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		row, err := store.QueryRow(ctx, s.DB,
+			"SELECT COUNT(*) AS n, SUM(CASE WHEN status = 'queued' THEN 1 ELSE 0 END) AS queued FROM _webhook_logs WHERE webhook_id = ?1", wh.ID)
+		if err == nil && row["n"] == int64(1) && toInt(row["queued"]) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected one delivered batch log, got %v (%v)", row, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFlushWebhookBatch_ClaimsEventsOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	whID := store.GenerateUUID()
+	if _, err := store.Exec(ctx, s.DB,
+		"INSERT INTO _webhooks (id, entity, hook, url) VALUES (?1, 'order', 'after_write', 'http://example.com')", whID); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	queue := func(id string) {
+		t.Helper()
+		if _, err := store.Exec(ctx, s.DB,
+			`INSERT INTO _webhook_logs (id, webhook_id, entity, hook, url, method, request_body, status, idempotency_key)
+			 VALUES (?1, ?2, 'order', 'after_write', 'http://example.com', 'POST', '{}', 'queued', ?1)`, id, whID); err != nil {
+			t.Fatalf("queue event: %v", err)
+		}
+	}
+	queue("e1")
+	queue("e2")
+	rows, err := store.QueryRows(ctx, s.DB, "SELECT id, request_body FROM _webhook_logs WHERE status = 'queued' ORDER BY id")
+	if err != nil {
+		t.Fatalf("select queued: %v", err)
+	}
+
+	// Two processes that selected the same queued rows: only the first claims them
+	first, _, err := claimBatchEvents(ctx, s, rows)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("expected the first flush to claim both events, got %v (%v)", first, err)
+	}
+	second, _, err := claimBatchEvents(ctx, s, rows)
+	if err != nil || len(second) != 0 {
+		t.Fatalf("expected the second flush to claim nothing, got %v (%v)", second, err)
+	}
+
+	// A claim left behind by a process that died is queued again once stale
+	if _, err := store.Exec(ctx, s.DB,
+		"UPDATE _webhook_logs SET updated_at = datetime('now', '-10 minutes') WHERE id = 'e1'"); err != nil {
+		t.Fatalf("age claim: %v", err)
+	}
+	ProcessWebhookBatches(s, metadata.NewRegistry())
+	got, err := store.QueryRows(ctx, s.DB, "SELECT id, status FROM _webhook_logs ORDER BY id")
+	if err != nil || len(got) != 2 || got[0]["status"] != "queued" || got[1]["status"] != "sending" {
+		t.Fatalf("expected only the stale claim to be requeued, got %v (%v)", got, err)
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+// MetaWebhookEntity is the entity meta-webhooks subscribe to (with the
+// after_write hook). They fire when a delivery of another webhook fails for
+// good, i.e. its log turns "failed", e.g. to alert Slack or a pager.
+const MetaWebhookEntity = "_webhook"
+
+// webhookLogEntry is the record a meta-webhook receives for a delivery log.
+func webhookLogEntry(id, webhookID, entity, hook, url, status string, attempt, maxAttempts, responseStatus int, errMsg string) map[string]any {
+	return map[string]any{
+		"id":              id,
+		"webhook_id":      webhookID,
+		"entity":          entity,
+		"hook":            hook,
+		"url":             url,
+		"status":          status,
+		"attempt":         attempt,
+		"max_attempts":    maxAttempts,
+		"response_status": responseStatus,
+		"error":           errMsg,
+	}
+}
+
+// fireWebhookFailed delivers entry to the meta-webhooks if it records a failed
+// delivery. Failures of meta-webhooks themselves are only logged, so an
+// alerting endpoint that is down can't set off more alerts about itself.
+func fireWebhookFailed(ctx context.Context, s *store.Store, reg *metadata.Registry, entry map[string]any) {
+	if reg == nil || entry == nil || entry["status"] != "failed" || entry["entity"] == MetaWebhookEntity {
+		return
+	}
+	webhooks := reg.GetWebhooksForEntityHook(MetaWebhookEntity, "after_write")
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload := BuildWebhookPayload("webhook.failed", MetaWebhookEntity, "failed", entry, nil, nil)
+	for _, wh := range webhooks {
+		fire, err := EvaluateWebhookCondition(wh, payload)
+		if err != nil {
+			log.Printf("ERROR: webhook %s condition evaluation: %v", wh.ID, err)
+			continue
+		}
+		if !fire {
+			continue
+		}
+		headers := ResolveHeaders(wh.Headers)
+		bodyJSON, _ := json.Marshal(payload)
+		result := deliverWebhook(ctx, wh, headers, bodyJSON)
+		logWebhookDelivery(ctx, s.DB, s.Dialect, wh, payload.IdempotencyKey, headers, bodyJSON, result)
+	}
+}
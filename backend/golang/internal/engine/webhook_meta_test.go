@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestWebhookFailure_FiresMetaWebhookOnce(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	// The alerting endpoint is down too; its failures must not alert again
+	var alerts atomic.Int32
+	var alert WebhookPayload
+	alerting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if alerts.Add(1) == 1 {
+			json.NewDecoder(r.Body).Decode(&alert)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer alerting.Close()
+
+	wh := &metadata.Webhook{
+		ID: store.GenerateUUID(), Entity: "order", Hook: "after_write", URL: failing.URL, Method: "POST",
+		Async: true, Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 2},
+	}
+	meta := &metadata.Webhook{
+		ID: store.GenerateUUID(), Entity: MetaWebhookEntity, Hook: "after_write", URL: alerting.URL, Method: "POST",
+		Async: true, Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 1},
+	}
+	for _, w := range []*metadata.Webhook{wh, meta} {
+		if _, err := store.Exec(ctx, s.DB,
+			"INSERT INTO _webhooks (id, entity, hook, url) VALUES (?1, ?2, 'after_write', ?3)", w.ID, w.Entity, w.URL); err != nil {
+			t.Fatalf("insert webhook: %v", err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWebhooks([]*metadata.Webhook{wh, meta})
+
+	logStatus := func(webhookID string) string {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT status FROM _webhook_logs WHERE webhook_id = ?1", webhookID)
+		if err != nil {
+			return ""
+		}
+		return row["status"].(string)
+	}
+
+	// The first attempt fails but will be retried: no alert yet
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "create", map[string]any{"id": "o1"}, nil, nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for logStatus(wh.ID) != "retrying" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the failed delivery to be scheduled for retry")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := alerts.Load(); n != 0 {
+		t.Fatalf("expected no alert while retries remain, got %d", n)
+	}
+
+	// The last retry fails: the delivery is failed and the meta-webhook fires
+	if _, err := store.Exec(ctx, s.DB, "UPDATE _webhook_logs SET next_retry_at = datetime('now', '-1 minute')"); err != nil {
+		t.Fatalf("make retry due: %v", err)
+	}
+	if _, err := ProcessWebhookRetries(s, reg); err != nil {
+		t.Fatalf("process retries: %v", err)
+	}
+	if st := logStatus(wh.ID); st != "failed" {
+		t.Fatalf("expected the delivery to be failed, got %q", st)
+	}
+	if st := logStatus(meta.ID); st != "failed" {
+		t.Fatalf("expected the meta-webhook delivery to be logged as failed, got %q", st)
+	}
+	if _, err := ProcessWebhookRetries(s, reg); err != nil {
+		t.Fatalf("process retries: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if n := alerts.Load(); n != 1 {
+		t.Fatalf("expected exactly 1 alert, got %d", n)
+	}
+	if alert.Event != "webhook.failed" || alert.Entity != MetaWebhookEntity ||
+		alert.Record["webhook_id"] != wh.ID || alert.Record["error"] != "HTTP 500" {
+		t.Fatalf("unexpected alert payload: %+v", alert)
+	}
+	row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM _webhook_logs")
+	if err != nil {
+		t.Fatalf("count logs: %v", err)
+	}
+	if n := toInt(row["n"]); n != 2 {
+		t.Fatalf("expected 2 log rows (delivery and alert), got %d", n)
+	}
+}
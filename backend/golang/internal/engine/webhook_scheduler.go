@@ -8,23 +8,27 @@ import (
 	"math"
 	"time"
 
+	"rocket-backend/internal/metadata"
 	"rocket-backend/internal/store"
 )
 
 // WebhookScheduler retries failed webhook deliveries on a background interval.
 type WebhookScheduler struct {
-	store  *store.Store
-	ticker *time.Ticker
-	done   chan struct{}
+	store    *store.Store
+	registry *metadata.Registry // meta-webhooks to tell about exhausted retries
+	health   *SchedulerHealth
+	ticker   *time.Ticker
+	done     chan struct{}
 }
 
-func NewWebhookScheduler(s *store.Store) *WebhookScheduler {
-	return &WebhookScheduler{store: s}
+func NewWebhookScheduler(s *store.Store, reg *metadata.Registry) *WebhookScheduler {
+	return &WebhookScheduler{store: s, registry: reg}
 }
 
 // Start begins the background ticker for retrying webhook deliveries.
 func (ws *WebhookScheduler) Start() {
 	ws.ticker = time.NewTicker(30 * time.Second)
+	ws.health = TrackScheduler("webhooks", 30*time.Second)
 	ws.done = make(chan struct{})
 	go ws.run()
 	log.Println("Webhook scheduler started (30s interval)")
@@ -38,6 +42,9 @@ func (ws *WebhookScheduler) Stop() {
 	if ws.done != nil {
 		close(ws.done)
 	}
+	if ws.health != nil {
+		UntrackScheduler(ws.health.name)
+	}
 }
 
 func (ws *WebhookScheduler) run() {
@@ -46,35 +53,41 @@ func (ws *WebhookScheduler) run() {
 		case <-ws.done:
 			return
 		case <-ws.ticker.C:
-			ws.processRetries()
+			ws.health.Record(ws.processRetries())
 		}
 	}
 }
 
 // ProcessWebhookRetries retries failed webhook deliveries for a given store.
-func ProcessWebhookRetries(s *store.Store) {
-	tmp := &WebhookScheduler{store: s}
-	tmp.processRetries()
+// Returns the number of deliveries retried and whether the lookup failed.
+func ProcessWebhookRetries(s *store.Store, reg *metadata.Registry) (int, error) {
+	tmp := &WebhookScheduler{store: s, registry: reg}
+	return tmp.processRetries()
 }
 
-func (ws *WebhookScheduler) processRetries() {
+func (ws *WebhookScheduler) processRetries() (int, error) {
 	ctx := context.Background()
 
 	rows, err := store.QueryRows(ctx, ws.store.DB,
 		fmt.Sprintf(`SELECT id, webhook_id, entity, hook, url, method, request_headers, request_body,
-		        status, attempt, max_attempts, idempotency_key
+		        status, attempt, max_attempts, idempotency_key,
+		        (SELECT w.transport FROM _webhooks w WHERE w.id = _webhook_logs.webhook_id) AS transport,
+		        (SELECT w.transport_config FROM _webhooks w WHERE w.id = _webhook_logs.webhook_id) AS transport_config,
+		        (SELECT w.secret FROM _webhooks w WHERE w.id = _webhook_logs.webhook_id) AS secret
 		 FROM _webhook_logs
 		 WHERE status = 'retrying' AND next_retry_at < %s
+		   AND NOT EXISTS (SELECT 1 FROM _webhooks w WHERE w.id = _webhook_logs.webhook_id AND NOT w.active)
 		 ORDER BY next_retry_at ASC
 		 LIMIT 50`, ws.store.Dialect.NowExpr()))
 	if err != nil {
 		log.Printf("ERROR: webhook scheduler query failed: %v", err)
-		return
+		return 0, fmt.Errorf("find webhook retries: %w", err)
 	}
 
 	for _, row := range rows {
 		ws.retryDelivery(ctx, row)
 	}
+	return len(rows), nil
 }
 
 func (ws *WebhookScheduler) retryDelivery(ctx context.Context, row map[string]any) {
@@ -108,9 +121,26 @@ func (ws *WebhookScheduler) retryDelivery(ctx context.Context, row map[string]an
 		}
 	}
 
+	// Queue transports and the signing secret are resolved from the webhook;
+	// logs only keep the target and the unsigned headers
+	wh := &metadata.Webhook{URL: url, Method: method}
+	wh.Transport, _ = row["transport"].(string)
+	wh.Secret, _ = row["secret"].(string)
+	var configErr error
+	switch v := row["transport_config"].(type) {
+	case string:
+		configErr = json.Unmarshal([]byte(v), &wh.TransportConfig)
+	case []byte:
+		configErr = json.Unmarshal(v, &wh.TransportConfig)
+	}
+
 	// Dispatch
-	resolved := ResolveHeaders(headers)
-	result := DispatchWebhook(ctx, url, method, resolved, bodyJSON)
+	var result *DispatchResult
+	if configErr != nil {
+		result = &DispatchResult{Error: fmt.Sprintf("invalid transport_config: %v", configErr)}
+	} else {
+		result = deliverWebhook(ctx, wh, ResolveHeaders(headers), bodyJSON)
+	}
 
 	// Determine new status
 	newStatus := "delivered"
@@ -152,7 +182,48 @@ func (ws *WebhookScheduler) retryDelivery(ctx context.Context, row map[string]an
 		log.Printf("Webhook retry delivered: log=%s attempt=%d", logID, attempt)
 	} else if newStatus == "failed" {
 		log.Printf("Webhook retry exhausted: log=%s attempt=%d/%d", logID, attempt, maxAttempts)
+		entity, _ := row["entity"].(string)
+		hook, _ := row["hook"].(string)
+		fireWebhookFailed(ctx, ws.store, ws.registry, webhookLogEntry(logID, fmt.Sprintf("%v", row["webhook_id"]),
+			entity, hook, url, newStatus, attempt, maxAttempts, result.StatusCode, errMsg))
+	}
+}
+
+// CleanupWebhookLogs purges delivered webhook logs older than retentionDays and
+// failed logs older than failedRetentionDays. Pending and retrying logs are never
+// purged. Failed logs are always kept at least as long as delivered ones so recent
+// failures survive for investigation. Returns the number of rows deleted.
+func CleanupWebhookLogs(ctx context.Context, s *store.Store, retentionDays, failedRetentionDays int) int64 {
+	if failedRetentionDays < retentionDays {
+		failedRetentionDays = retentionDays
+	}
+
+	var total int64
+	for _, p := range []struct {
+		status string
+		days   int
+	}{
+		{"delivered", retentionDays},
+		{"failed", failedRetentionDays},
+	} {
+		if p.days <= 0 {
+			continue
+		}
+		pb := s.Dialect.NewParamBuilder()
+		whereExpr := s.Dialect.IntervalDeleteExpr("created_at", pb, fmt.Sprintf("%d", p.days))
+		n, err := store.Exec(ctx, s.DB,
+			fmt.Sprintf("DELETE FROM _webhook_logs WHERE status = %s AND %s", pb.Add(p.status), whereExpr),
+			pb.Params()...)
+		if err != nil {
+			log.Printf("ERROR: webhook log cleanup (%s): %v", p.status, err)
+			continue
+		}
+		total += n
+	}
+	if total > 0 {
+		log.Printf("Webhook log cleanup: deleted %d old logs", total)
 	}
+	return total
 }
 
 func toInt(v any) int {
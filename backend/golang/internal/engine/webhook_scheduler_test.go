@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/store"
+)
+
+func TestCleanupWebhookLogs_PurgesOldDeliveredKeepsRecentFailures(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	webhookID := store.GenerateUUID()
+	if _, err := store.Exec(ctx, s.DB,
+		"INSERT INTO _webhooks (id, entity, hook, url) VALUES (?1, 'order', 'after_write', 'http://example.com')", webhookID); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+
+	insertLog := func(status string, ageDays int) string {
+		id := store.GenerateUUID()
+		_, err := store.Exec(ctx, s.DB,
+			fmt.Sprintf(`INSERT INTO _webhook_logs (id, webhook_id, entity, hook, url, method, status, idempotency_key, created_at)
+			 VALUES (?1, ?2, 'order', 'after_write', 'http://example.com', 'POST', ?3, ?4, datetime('now', '-%d days'))`, ageDays),
+			id, webhookID, status, id)
+		if err != nil {
+			t.Fatalf("insert log: %v", err)
+		}
+		return id
+	}
+
+	oldDelivered := insertLog("delivered", 40)
+	recentDelivered := insertLog("delivered", 1)
+	recentFailed := insertLog("failed", 40) // past delivered retention, within failed retention
+	oldFailed := insertLog("failed", 120)
+	oldRetrying := insertLog("retrying", 120)
+
+	deleted := CleanupWebhookLogs(ctx, s, 30, 90)
+	if deleted != 2 {
+		t.Fatalf("expected 2 logs purged, got %d", deleted)
+	}
+
+	exists := func(id string) bool {
+		_, err := store.QueryRow(ctx, s.DB, "SELECT id FROM _webhook_logs WHERE id = ?1", id)
+		return err == nil
+	}
+	for _, id := range []string{oldDelivered, oldFailed} {
+		if exists(id) {
+			t.Fatalf("expected log %s to be purged", id)
+		}
+	}
+	for _, id := range []string{recentDelivered, recentFailed, oldRetrying} {
+		if !exists(id) {
+			t.Fatalf("expected log %s to survive", id)
+		}
+	}
+}
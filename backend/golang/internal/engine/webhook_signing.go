@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"rocket-backend/internal/metadata"
+)
+
+// WebhookSignatureHeader carries "sha256=<hex HMAC-SHA256 of the body>" on
+// every delivery of a webhook that has a secret.
+const WebhookSignatureHeader = "X-Rocket-Signature"
+
+// WebhookSecretKey encrypts webhook secrets in _webhooks (webhooks.secret_key).
+// Empty stores new secrets as plaintext. Changing it makes secrets stored
+// under the old key unreadable, so their deliveries fail until they are set
+// again.
+var WebhookSecretKey string
+
+// encryptedSecretPrefix marks a stored secret as AES-GCM ciphertext.
+const encryptedSecretPrefix = "enc:"
+
+// SignWebhookBody returns the X-Rocket-Signature value for body.
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptWebhookSecret returns the value to store for a webhook secret:
+// AES-256-GCM ciphertext under WebhookSecretKey, or the secret itself when no
+// key is configured.
+func EncryptWebhookSecret(secret string) (string, error) {
+	if secret == "" || WebhookSecretKey == "" {
+		return secret, nil
+	}
+	gcm, err := webhookSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptWebhookSecret reverses EncryptWebhookSecret. Secrets stored as
+// plaintext are returned unchanged.
+func DecryptWebhookSecret(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedSecretPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if WebhookSecretKey == "" {
+		return "", errors.New("webhook secret is encrypted but webhooks.secret_key is not set")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decode webhook secret: %w", err)
+	}
+	gcm, err := webhookSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("decrypt webhook secret: ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt webhook secret: %w", err)
+	}
+	return string(plain), nil
+}
+
+// webhookSecretCipher derives the AES-256 key from WebhookSecretKey, so any
+// string can be configured.
+func webhookSecretCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(WebhookSecretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("webhook secret cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// signWebhookHeaders returns headers with the signature of bodyJSON added
+// when the webhook has a secret. The signature is computed on every attempt
+// and never stored in _webhook_logs, so retries sign with the current secret.
+func signWebhookHeaders(wh *metadata.Webhook, headers map[string]string, bodyJSON []byte) (map[string]string, error) {
+	if wh.Secret == "" {
+		return headers, nil
+	}
+	secret, err := DecryptWebhookSecret(wh.Secret)
+	if err != nil {
+		return nil, err
+	}
+	signed := maps.Clone(headers)
+	if signed == nil {
+		signed = map[string]string{}
+	}
+	signed[WebhookSignatureHeader] = SignWebhookBody(secret, bodyJSON)
+	return signed, nil
+}
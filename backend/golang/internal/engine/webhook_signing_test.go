@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestWebhookSignature_VerifiesOnDeliveryAndRetry(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	defer func(key string) { WebhookSecretKey = key }(WebhookSecretKey)
+	WebhookSecretKey = "test-key"
+	const secret = "whsec_receiver_shared"
+	stored, err := EncryptWebhookSecret(secret)
+	if err != nil {
+		t.Fatalf("encrypt secret: %v", err)
+	}
+	if !strings.HasPrefix(stored, "enc:") || strings.Contains(stored, secret) {
+		t.Fatalf("expected the stored secret to be encrypted, got %q", stored)
+	}
+
+	type delivery struct {
+		signature string
+		body      []byte
+	}
+	var mu sync.Mutex
+	var deliveries []delivery
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		deliveries = append(deliveries, delivery{r.Header.Get(WebhookSignatureHeader), body})
+		first := len(deliveries) == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusInternalServerError) // fail once so the scheduler retries
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	wh := &metadata.Webhook{
+		ID: store.GenerateUUID(), Entity: "order", Hook: "after_write", URL: srv.URL, Method: "POST",
+		Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 3}, Secret: stored,
+	}
+	if _, err := store.Exec(ctx, s.DB,
+		"INSERT INTO _webhooks (id, entity, hook, url, async, secret) VALUES (?1, 'order', 'after_write', ?2, 0, ?3)", wh.ID, srv.URL, stored); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWebhooks([]*metadata.Webhook{wh})
+
+	record := map[string]any{"id": "o1", "status": "paid"}
+	if err := FireSyncWebhooks(ctx, s.DB, s.Dialect, reg, "after_write", "order", "create", record, nil, nil); err == nil {
+		t.Fatal("expected the failed first delivery to be reported")
+	}
+	if _, err := store.Exec(ctx, s.DB,
+		"UPDATE _webhook_logs SET next_retry_at = datetime('now', '-1 minute') WHERE webhook_id = ?1", wh.ID); err != nil {
+		t.Fatalf("make retry due: %v", err)
+	}
+	if n, err := ProcessWebhookRetries(s, reg); err != nil || n != 1 {
+		t.Fatalf("expected one retry, got %d (%v)", n, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deliveries) != 2 {
+		t.Fatalf("expected a delivery and a retry, got %d", len(deliveries))
+	}
+	for i, d := range deliveries {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(d.body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); d.signature != want {
+			t.Fatalf("delivery %d: expected signature %s, got %q", i, want, d.signature)
+		}
+	}
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT request_headers FROM _webhook_logs WHERE webhook_id = ?1", wh.ID)
+	if err != nil {
+		t.Fatalf("load log: %v", err)
+	}
+	if h, _ := row["request_headers"].(string); strings.Contains(h, WebhookSignatureHeader) {
+		t.Fatalf("expected the signature to stay out of the delivery log, got %s", h)
+	}
+}
+
+func TestDecryptWebhookSecret(t *testing.T) {
+	defer func(key string) { WebhookSecretKey = key }(WebhookSecretKey)
+
+	WebhookSecretKey = ""
+	if got, err := DecryptWebhookSecret("plain"); err != nil || got != "plain" {
+		t.Fatalf("expected a plaintext secret to pass through, got %q (%v)", got, err)
+	}
+	if stored, _ := EncryptWebhookSecret("plain"); stored != "plain" {
+		t.Fatalf("expected secrets to be stored as plaintext without a key, got %q", stored)
+	}
+
+	WebhookSecretKey = "old-key"
+	stored, err := EncryptWebhookSecret("s3cret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if got, err := DecryptWebhookSecret(stored); err != nil || got != "s3cret" {
+		t.Fatalf("expected the secret back, got %q (%v)", got, err)
+	}
+	WebhookSecretKey = "new-key"
+	if _, err := DecryptWebhookSecret(stored); err == nil {
+		t.Fatal("expected a secret stored under another key to fail")
+	}
+	WebhookSecretKey = ""
+	if _, err := DecryptWebhookSecret(stored); err == nil {
+		t.Fatal("expected an encrypted secret to fail without a key")
+	}
+}
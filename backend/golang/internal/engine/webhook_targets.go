@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// WebhookTargets restricts the hosts webhooks may call. nil allows every
+// target; the server installs one built from the webhooks config.
+var WebhookTargets *WebhookTargetPolicy
+
+// WebhookTargetPolicy is an allow/deny list of webhook targets. Entries are
+// CIDRs ("10.0.0.0/8"), IPs, hostnames ("hooks.example.com") or wildcard
+// hostnames ("*.example.com"). Loopback, link-local (which includes cloud
+// metadata endpoints such as 169.254.169.254) and unspecified addresses are
+// denied unless AllowInternal is set or an allowed CIDR covers them.
+type WebhookTargetPolicy struct {
+	AllowInternal bool
+
+	allowHosts, denyHosts []string
+	allowNets, denyNets   []*net.IPNet
+}
+
+// NewWebhookTargetPolicy parses the allow and deny entries.
+func NewWebhookTargetPolicy(allowed, denied []string, allowInternal bool) (*WebhookTargetPolicy, error) {
+	p := &WebhookTargetPolicy{AllowInternal: allowInternal}
+	var err error
+	if p.allowHosts, p.allowNets, err = parseTargetPatterns(allowed); err != nil {
+		return nil, fmt.Errorf("allowed_targets: %w", err)
+	}
+	if p.denyHosts, p.denyNets, err = parseTargetPatterns(denied); err != nil {
+		return nil, fmt.Errorf("denied_targets: %w", err)
+	}
+	return p, nil
+}
+
+func parseTargetPatterns(entries []string) (hosts []string, nets []*net.IPNet, err error) {
+	for _, e := range entries {
+		e = strings.ToLower(strings.TrimSpace(e))
+		switch {
+		case e == "":
+			continue
+		case strings.Contains(e, "/"):
+			_, n, err := net.ParseCIDR(e)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid CIDR %q", e)
+			}
+			nets = append(nets, n)
+		case net.ParseIP(e) != nil:
+			ip := net.ParseIP(e)
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			hosts = append(hosts, e)
+		}
+	}
+	return hosts, nets, nil
+}
+
+// CheckURL reports whether a webhook URL may be called. With resolve set the
+// hostname is looked up and every address it resolves to is checked; a name
+// that doesn't resolve yet is left to the dispatch-time check.
+func (p *WebhookTargetPolicy) CheckURL(ctx context.Context, rawURL string, resolve bool) error {
+	if p == nil {
+		return nil
+	}
+	// Bare broker addresses (kafka) have no scheme
+	if !strings.Contains(rawURL, "//") {
+		rawURL = "//" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("webhook target %q has no host", rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip, false)
+	}
+	if matchHostPattern(p.denyHosts, host) {
+		return fmt.Errorf("webhook target %s is denied", host)
+	}
+	hostAllowed := matchHostPattern(p.allowHosts, host)
+	var ips []net.IP
+	if resolve {
+		if addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host); err == nil {
+			for _, a := range addrs {
+				ips = append(ips, a.IP)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		if p.restricted() && !hostAllowed && len(p.allowNets) == 0 {
+			return fmt.Errorf("webhook target %s is not in the allowed targets", host)
+		}
+		return nil
+	}
+	for _, ip := range ips {
+		if err := p.checkIP(ip, hostAllowed); err != nil {
+			return fmt.Errorf("%s resolves to %s: %w", host, ip, err)
+		}
+	}
+	return nil
+}
+
+// restricted reports whether an allow list is configured.
+func (p *WebhookTargetPolicy) restricted() bool {
+	return len(p.allowHosts)+len(p.allowNets) > 0
+}
+
+// checkIP decides one resolved address. hostAllowed is whether the name it
+// came from matched an allowed hostname; that never lifts the internal
+// address default, only an allowed CIDR or AllowInternal does.
+func (p *WebhookTargetPolicy) checkIP(ip net.IP, hostAllowed bool) error {
+	for _, n := range p.denyNets {
+		if n.Contains(ip) {
+			return fmt.Errorf("webhook target %s is denied", ip)
+		}
+	}
+	for _, n := range p.allowNets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if !p.AllowInternal && (ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()) {
+		return fmt.Errorf("webhook target %s is a loopback or link-local address", ip)
+	}
+	if p.restricted() && !hostAllowed {
+		return fmt.Errorf("webhook target %s is not in the allowed targets", ip)
+	}
+	return nil
+}
+
+func matchHostPattern(patterns []string, host string) bool {
+	for _, p := range patterns {
+		if p == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(p, "*"); ok && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialWebhookTarget resolves the host itself, checks every address against
+// WebhookTargets and connects to a checked address, so a name that
+// re-resolves to an internal address after validation (DNS rebinding) is
+// refused. Redirects go through here too.
+func dialWebhookTarget(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	p := WebhookTargets
+	if p == nil {
+		return d.DialContext(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.CheckURL(ctx, "//"+net.JoinHostPort(host, port), false); err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		hostAllowed := matchHostPattern(p.allowHosts, strings.ToLower(host))
+		for _, a := range addrs {
+			if err := p.checkIP(a.IP, hostAllowed); err != nil {
+				return nil, fmt.Errorf("%s resolves to %s: %w", host, a.IP, err)
+			}
+			ips = append(ips, a.IP)
+		}
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
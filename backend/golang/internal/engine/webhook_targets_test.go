@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookTargets_RejectsMetadataEndpoint(t *testing.T) {
+	ctx := context.Background()
+	policy, err := NewWebhookTargetPolicy(nil, []string{"*.internal.example"}, false)
+	if err != nil {
+		t.Fatalf("new policy: %v", err)
+	}
+
+	const metadataURL = "http://169.254.169.254/latest/meta-data/iam/security-credentials/"
+	if err := policy.CheckURL(ctx, metadataURL, true); err == nil || !strings.Contains(err.Error(), "link-local") {
+		t.Fatalf("expected the metadata endpoint to be rejected as link-local, got %v", err)
+	}
+	for _, target := range []string{"http://localhost:8080/hook", "http://[::1]/hook", "https://db.internal.example/hook"} {
+		if err := policy.CheckURL(ctx, target, true); err == nil {
+			t.Fatalf("expected %s to be rejected", target)
+		}
+	}
+	if err := policy.CheckURL(ctx, "https://hooks.example.com/in", false); err != nil {
+		t.Fatalf("expected a public host to be allowed, got %v", err)
+	}
+
+	// Dispatch refuses the request before it is sent, and the dialer refuses
+	// names resolving to loopback even when the URL check is skipped
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer srv.Close()
+	WebhookTargets = policy
+	defer func() { WebhookTargets = nil }()
+
+	if res := DispatchWebhook(ctx, metadataURL, "GET", nil, nil); !strings.Contains(res.Error, "link-local") {
+		t.Fatalf("expected dispatch to the metadata endpoint to fail, got %+v", res)
+	}
+	local := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	if res := DispatchWebhook(ctx, local, "POST", nil, []byte("{}")); res.Error == "" || hit {
+		t.Fatalf("expected the dialer to refuse localhost, got %+v (hit=%v)", res, hit)
+	}
+
+	allowLocal, _ := NewWebhookTargetPolicy([]string{"127.0.0.1/32"}, nil, false)
+	WebhookTargets = allowLocal
+	if res := DispatchWebhook(ctx, srv.URL, "POST", nil, []byte("{}")); res.Error != "" || !hit {
+		t.Fatalf("expected an explicitly allowed CIDR to be reachable, got %+v", res)
+	}
+	if err := allowLocal.CheckURL(ctx, "https://93.184.216.34/in", false); err == nil {
+		t.Fatal("expected addresses outside the allow list to be rejected")
+	}
+
+	// Queue transports dial through the same policy
+	WebhookTargets = policy
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	accepted := make(chan struct{}, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- struct{}{}
+			conn.Close()
+		}
+	}()
+	err = (&NATSTransport{}).Publish(ctx, "nats://"+ln.Addr().String(), map[string]string{"subject": "orders"}, nil, []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "loopback") {
+		t.Fatalf("expected the nats dial to a loopback address to be refused, got %v", err)
+	}
+	select {
+	case <-accepted:
+		t.Fatal("expected no connection to reach the nats server")
+	default:
+	}
+}
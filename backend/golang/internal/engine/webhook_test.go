@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestFireAsyncWebhooks_DedupsIdenticalEvents(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	wh := &metadata.Webhook{
+		ID: store.GenerateUUID(), Entity: "order", Hook: "after_write", URL: srv.URL, Method: "POST",
+		Async: true, Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 3},
+	}
+	if _, err := store.Exec(ctx, s.DB,
+		"INSERT INTO _webhooks (id, entity, hook, url) VALUES (?1, 'order', 'after_write', ?2)", wh.ID, srv.URL); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWebhooks([]*metadata.Webhook{wh})
+
+	record := map[string]any{"id": "o1", "status": "paid"}
+	old := map[string]any{"id": "o1", "status": "pending"}
+	logCount := func() int {
+		row, err := store.QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM _webhook_logs WHERE webhook_id = ?1", wh.ID)
+		if err != nil {
+			t.Fatalf("count logs: %v", err)
+		}
+		return toInt(row["n"])
+	}
+
+	// Back-to-back duplicates, then one more after the first delivery is logged
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "update", record, old, nil)
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "update", record, old, nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for logCount() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the first event to be delivered and logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "update", record, old, nil)
+	time.Sleep(200 * time.Millisecond)
+
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected 1 delivery for identical events, got %d", n)
+	}
+	if n := logCount(); n != 1 {
+		t.Fatalf("expected 1 log row, got %d", n)
+	}
+
+	// A different change is a different event
+	FireAsyncWebhooks(ctx, s, reg, "after_write", "order", "update",
+		map[string]any{"id": "o1", "status": "shipped"}, record, nil)
+	deadline = time.Now().Add(5 * time.Second)
+	for hits.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a distinct event to be delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A retried bulk operation raises the same change from a separate write,
+	// inside the window, and is not delivered again
+	FireAsyncWebhooks(context.Background(), s, reg, "after_write", "order", "update", record, old, nil)
+	time.Sleep(200 * time.Millisecond)
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("expected the replayed change to be skipped, got %d deliveries", n)
+	}
+}
+
+func TestFireSyncWebhooks_BeforeHookIsNeverReused(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	wh := &metadata.Webhook{
+		ID: store.GenerateUUID(), Entity: "order", Hook: "before_write", URL: srv.URL, Method: "POST",
+		Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 1},
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWebhooks([]*metadata.Webhook{wh})
+
+	// Two creates with the same payload: neither has a record id yet
+	record := map[string]any{"status": "pending"}
+	for i := 0; i < 2; i++ {
+		if err := FireSyncWebhooks(ctx, s.DB, s.Dialect, reg, "before_write", "order", "create", record, nil, nil); err != nil {
+			t.Fatalf("sync webhook: %v", err)
+		}
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("expected every before_write to be dispatched, got %d deliveries", n)
+	}
+}
+
+type scrubHook struct {
+	BaseHook
+}
+
+func (scrubHook) BeforeDelete(ctx context.Context, hc *HookContext) error {
+	hc.Record["total"] = nil
+	return nil
+}
+
+func TestDelete_WebhooksCarryDeletedRecord(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	entity := &metadata.Entity{
+		Name:       "invoice",
+		Table:      "invoices",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "number", Type: "string"},
+			{Name: "total", Type: "float"},
+		},
+	}
+	if err := store.NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := store.Exec(ctx, s.DB, "INSERT INTO invoices (id, number, total) VALUES ('inv-1', 'A-100', 42.5)"); err != nil {
+		t.Fatalf("insert invoice: %v", err)
+	}
+
+	payloads := make(chan WebhookPayload, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p WebhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		payloads <- p
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{entity}, nil)
+	var webhooks []*metadata.Webhook
+	for _, hook := range []string{"before_delete", "after_delete"} {
+		wh := &metadata.Webhook{
+			ID: store.GenerateUUID(), Entity: "invoice", Hook: hook, URL: srv.URL, Method: "POST",
+			Async: hook == "after_delete", Active: true, Retry: metadata.WebhookRetry{MaxAttempts: 1},
+		}
+		if _, err := store.Exec(ctx, s.DB,
+			"INSERT INTO _webhooks (id, entity, hook, url, async) VALUES (?1, 'invoice', ?2, ?3, ?4)", wh.ID, hook, srv.URL, wh.Async); err != nil {
+			t.Fatalf("insert webhook: %v", err)
+		}
+		webhooks = append(webhooks, wh)
+	}
+	reg.LoadWebhooks(webhooks)
+
+	hooks := NewHookRegistry()
+	hooks.Register("invoice", scrubHook{})
+	h := NewHandler(s, reg)
+	h.SetHooks(hooks)
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u-7", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	app.Delete("/api/:entity/:id", h.Delete)
+
+	req, _ := http.NewRequest("DELETE", "/api/invoice/inv-1", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("delete: %v %v", err, resp.StatusCode)
+	}
+
+	seen := map[string]bool{}
+	for len(seen) < 2 {
+		select {
+		case p := <-payloads:
+			seen[p.Event] = true
+			if p.Action != "delete" || p.Record["id"] != "inv-1" || p.Record["number"] != "A-100" || p.Record["total"] != 42.5 {
+				t.Fatalf("%s: expected the deleted invoice in the payload, got %+v", p.Event, p.Record)
+			}
+			if p.User["id"] != "u-7" {
+				t.Fatalf("%s: expected the deleting user, got %v", p.Event, p.User)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected before_delete and after_delete deliveries, got %v", seen)
+		}
+	}
+}
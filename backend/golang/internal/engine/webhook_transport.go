@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rocket-backend/internal/instrument"
+	"rocket-backend/internal/metadata"
+)
+
+// WebhookTransport publishes a webhook body to a non-HTTP target such as a
+// message queue. target is the webhook URL; config is its transport_config.
+type WebhookTransport interface {
+	Publish(ctx context.Context, target string, config map[string]string, headers map[string]string, body []byte) error
+}
+
+var (
+	webhookTransportsMu sync.RWMutex
+	webhookTransports   = map[string]WebhookTransport{
+		"nats": &NATSTransport{},
+	}
+)
+
+// HasWebhookTransport reports whether a transport is registered under name.
+func HasWebhookTransport(name string) bool {
+	return getWebhookTransport(name) != nil
+}
+
+// RegisterWebhookTransport installs the transport used for webhooks with the
+// given transport name, replacing any existing one. Kafka and SQS have no
+// built-in client; register one at startup to enable them.
+func RegisterWebhookTransport(name string, t WebhookTransport) {
+	webhookTransportsMu.Lock()
+	defer webhookTransportsMu.Unlock()
+	webhookTransports[name] = t
+}
+
+func getWebhookTransport(name string) WebhookTransport {
+	webhookTransportsMu.RLock()
+	defer webhookTransportsMu.RUnlock()
+	return webhookTransports[name]
+}
+
+// deliverWebhook sends bodyJSON to the webhook's target over its transport.
+// headers are resolved values; a webhook with a secret also gets its
+// signature header.
+func deliverWebhook(ctx context.Context, wh *metadata.Webhook, headers map[string]string, bodyJSON []byte) *DispatchResult {
+	headers, err := signWebhookHeaders(wh, headers, bodyJSON)
+	if err != nil {
+		return &DispatchResult{Error: fmt.Sprintf("sign webhook: %v", err)}
+	}
+	if wh.Transport == "" || wh.Transport == "http" {
+		return DispatchWebhook(ctx, wh.URL, wh.Method, headers, bodyJSON)
+	}
+
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "webhook", "dispatcher", "webhook.publish")
+	defer span.End()
+	span.SetMetadata("transport", wh.Transport)
+	span.SetMetadata("url", wh.URL)
+
+	if err := WebhookTargets.CheckURL(ctx, wh.URL, true); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return &DispatchResult{Error: err.Error()}
+	}
+	t := getWebhookTransport(wh.Transport)
+	if t == nil {
+		span.SetStatus("error")
+		return &DispatchResult{Error: fmt.Sprintf("no %s transport registered", wh.Transport)}
+	}
+	if err := t.Publish(ctx, wh.URL, wh.TransportConfig, headers, bodyJSON); err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		return &DispatchResult{Error: fmt.Sprintf("%s publish: %v", wh.Transport, err)}
+	}
+	span.SetStatus("ok")
+	// Queues have no response; an acknowledged publish is logged as 200 so
+	// delivery status and retries work the same as for HTTP
+	return &DispatchResult{StatusCode: 200}
+}
+
+// NATSTransport publishes to a NATS subject (transport_config.subject) on the
+// server at tls://[user:pass@]host[:port], or nats:// for a plaintext
+// connection. It opens a connection per publish and waits for the server to
+// acknowledge it with PONG.
+type NATSTransport struct {
+	// Dial opens the connection; nil dials through the WebhookTargets policy,
+	// like HTTP deliveries. Replaceable in tests.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig is cloned for tls:// servers; nil verifies against the
+	// system roots.
+	TLSConfig *tls.Config
+}
+
+func (t *NATSTransport) Publish(ctx context.Context, target string, config map[string]string, headers map[string]string, body []byte) error {
+	subject := config["subject"]
+	if subject == "" {
+		return fmt.Errorf("transport_config.subject is required")
+	}
+	// Resolved header values are only known now, so check again before they
+	// reach the wire
+	if err := ValidateNATSMessage(subject, headers); err != nil {
+		return err
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" {
+		return fmt.Errorf("invalid nats url %q", target)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	dial := t.Dial
+	if dial == nil {
+		dial = dialWebhookTarget
+	}
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	r := bufio.NewReader(conn)
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("expected INFO from server, got %q (%v)", strings.TrimSpace(line), err)
+	}
+	// The server sends INFO in the clear, then expects the TLS handshake
+	if u.Scheme == "tls" {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if t.TLSConfig != nil {
+			cfg = t.TLSConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("tls handshake: %w", err)
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+
+	opts := map[string]any{"verbose": false, "pedantic": false, "name": "rocket-backend", "headers": true, "lang": "go"}
+	if u.User != nil {
+		opts["user"] = u.User.Username()
+		if pass, ok := u.User.Password(); ok {
+			opts["pass"] = pass
+		}
+	}
+	connectJSON, _ := json.Marshal(opts)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "CONNECT %s\r\n", connectJSON)
+	if len(headers) > 0 {
+		hdr := natsHeaderBlock(headers)
+		fmt.Fprintf(&msg, "HPUB %s %d %d\r\n%s%s\r\n", subject, len(hdr), len(hdr)+len(body), hdr, body)
+	} else {
+		fmt.Fprintf(&msg, "PUB %s %d\r\n%s\r\n", subject, len(body), body)
+	}
+	msg.WriteString("PING\r\n")
+	if _, err := conn.Write([]byte(msg.String())); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	// The server handles commands in order, so PONG means the publish was accepted
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("write pong: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// ValidateNATSMessage checks a subject and header set before they are written
+// into the NATS protocol. The protocol is line-based and space-separated, so a
+// space, control character or CR/LF in a subject, or in a header name or
+// value, could end the command early and inject another one. Header names
+// can't contain a colon either.
+func ValidateNATSMessage(subject string, headers map[string]string) error {
+	if subject == "" || strings.IndexFunc(subject, natsUnsafe) >= 0 {
+		return fmt.Errorf("invalid nats subject %q: must be non-empty, without spaces or control characters", subject)
+	}
+	for k, v := range headers {
+		if k == "" || strings.ContainsRune(k, ':') || strings.IndexFunc(k, natsUnsafe) >= 0 {
+			return fmt.Errorf("invalid nats header name %q", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("nats header %s: value must not contain CR or LF", k)
+		}
+	}
+	return nil
+}
+
+func natsUnsafe(r rune) bool {
+	return r == ' ' || r == '\t' || r < 0x20 || r == 0x7f
+}
+
+// natsHeaderBlock encodes headers in the NATS/1.0 format used by HPUB.
+func natsHeaderBlock(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("NATS/1.0\r\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, headers[k])
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
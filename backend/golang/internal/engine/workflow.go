@@ -2,6 +2,9 @@ package engine
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -12,6 +15,26 @@ import (
 	"rocket-backend/internal/store"
 )
 
+// ErrInvalidCallbackToken is returned when a workflow callback carries a token
+// that doesn't match the one issued for the paused step.
+var ErrInvalidCallbackToken = errors.New("invalid callback token")
+
+// MaxWorkflowSteps is the default number of steps an instance may execute in a
+// single run before it is failed. It stops goto loops from hanging the request.
+var MaxWorkflowSteps = 100
+
+// WorkflowRetryMaxAttempts is how many times a failed instance is retried
+// automatically after a transient step failure (workflows.retry_max_attempts).
+// It is recorded on each instance when it is created; 0 disables retries.
+var WorkflowRetryMaxAttempts = 0
+
+// WorkflowRetryBackoff is the delay before the first automatic retry; it
+// doubles with each further attempt, up to maxWorkflowRetryDelay.
+var WorkflowRetryBackoff = 30 * time.Second
+
+// maxWorkflowRetryDelay caps the doubled delay between automatic retries.
+const maxWorkflowRetryDelay = 24 * time.Hour
+
 // WFEngine orchestrates workflow lifecycle: triggering, step advancement,
 // approval resolution, and timeout handling. All dependencies are injected.
 type WFEngine struct {
@@ -22,6 +45,7 @@ type WFEngine struct {
 	stepExecutors   map[string]StepExecutor
 	actionExecutors map[string]ActionExecutor
 	evaluator       ExpressionEvaluator
+	maxSteps        int
 }
 
 // NewWFEngine creates a WFEngine with the given dependencies.
@@ -42,9 +66,15 @@ func NewWFEngine(
 		stepExecutors:   stepExecutors,
 		actionExecutors: actionExecutors,
 		evaluator:       evaluator,
+		maxSteps:        MaxWorkflowSteps,
 	}
 }
 
+// SetMaxSteps overrides the per-run step limit. Values <= 0 disable the guard.
+func (e *WFEngine) SetMaxSteps(n int) {
+	e.maxSteps = n
+}
+
 // NewDefaultWFEngine creates a WFEngine with default executors and Postgres store.
 func NewDefaultWFEngine(s *store.Store, reg *metadata.Registry) *WFEngine {
 	return NewWFEngine(
@@ -122,6 +152,7 @@ func (e *WFEngine) ResolveAction(ctx context.Context,
 		By:     userID,
 		At:     time.Now().UTC().Format(time.RFC3339),
 	})
+	setStepOutput(instance, step.ID, map[string]any{"status": action, "by": userID})
 	instance.CurrentStepDeadline = nil
 
 	var nextGoto string
@@ -155,19 +186,131 @@ func (e *WFEngine) ResolveAction(ctx context.Context,
 	return e.wfStore.LoadInstance(ctx, e.pool, e.dialect, instance.ID)
 }
 
-// ProcessTimeouts finds and handles timed-out workflow instances.
-func (e *WFEngine) ProcessTimeouts(ctx context.Context) {
+// ResolveCallback resumes an instance paused on a callback step. The token must
+// match the one sent with the step's webhook; it is single-use.
+func (e *WFEngine) ResolveCallback(ctx context.Context,
+	instanceID, token string, success bool, data any) (*metadata.WorkflowInstance, error) {
+
+	instance, err := e.wfStore.LoadInstance(ctx, e.pool, e.dialect, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.Status != "running" {
+		return nil, fmt.Errorf("workflow instance is not running (status: %s)", instance.Status)
+	}
+
+	wf := e.registry.GetWorkflow(instance.WorkflowName)
+	if wf == nil {
+		return nil, fmt.Errorf("workflow definition not found: %s", instance.WorkflowName)
+	}
+
+	step := wf.FindStep(instance.CurrentStep)
+	if step == nil || step.Type != "callback" {
+		return nil, fmt.Errorf("current step is not a callback step")
+	}
+	if instance.CallbackToken == "" ||
+		subtle.ConstantTimeCompare([]byte(hashCallbackToken(token)), []byte(instance.CallbackToken)) != 1 {
+		return nil, ErrInvalidCallbackToken
+	}
+	// Only the caller that clears the token resumes the step
+	consumed, err := e.wfStore.ConsumeCallbackToken(ctx, e.pool, e.dialect, instance.ID, instance.CallbackToken)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidCallbackToken
+	}
+
+	status := "failure"
+	next := step.OnFailure
+	if success {
+		status = "success"
+		next = step.OnSuccess
+	}
+	instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
+		Step:   step.ID,
+		Status: "callback_" + status,
+		At:     time.Now().UTC().Format(time.RFC3339),
+	})
+	setStepOutput(instance, step.ID, map[string]any{"status": status, "data": data})
+	instance.CallbackToken = ""
+	instance.CurrentStepDeadline = nil
+
+	// A reported failure with nowhere to go fails the instance
+	if next == nil && !success {
+		instance.Status = "failed"
+		instance.CurrentStep = ""
+		if err := e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	}
+	if next == nil || next.Goto == "" || next.Goto == "end" {
+		instance.Status = "completed"
+		instance.CurrentStep = ""
+		if err := e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	}
+
+	instance.CurrentStep = next.Goto
+	if err := e.advanceWorkflow(ctx, instance, wf); err != nil {
+		return nil, err
+	}
+
+	return e.wfStore.LoadInstance(ctx, e.pool, e.dialect, instance.ID)
+}
+
+// ProcessTimeouts finds and handles timed-out workflow instances. It returns
+// how many were handled and whether the lookup failed.
+func (e *WFEngine) ProcessTimeouts(ctx context.Context) (int, error) {
 	instances, err := e.wfStore.FindTimedOut(ctx, e.pool, e.dialect)
 	if err != nil {
 		log.Printf("ERROR: workflow timeout query failed: %v", err)
-		return
+		return 0, fmt.Errorf("find timed-out workflows: %w", err)
 	}
 
+	handled := 0
 	for _, instance := range instances {
 		if err := e.handleTimeout(ctx, instance); err != nil {
 			log.Printf("ERROR: processing timeout for instance %s: %v", instance.ID, err)
+			continue
 		}
+		handled++
 	}
+	return handled, nil
+}
+
+// ProcessRetries resumes failed instances whose automatic retry is due, at
+// the step that failed. It returns how many were retried and whether the
+// lookup failed.
+func (e *WFEngine) ProcessRetries(ctx context.Context) (int, error) {
+	instances, err := e.wfStore.FindRetryDue(ctx, e.pool, e.dialect)
+	if err != nil {
+		log.Printf("ERROR: workflow retry query failed: %v", err)
+		return 0, fmt.Errorf("find workflow retries: %w", err)
+	}
+
+	retried := 0
+	for _, instance := range instances {
+		// Another scheduler may have picked the instance up since the lookup
+		claimed, err := e.wfStore.ClaimRetry(ctx, e.pool, e.dialect, instance.ID)
+		if err != nil {
+			log.Printf("ERROR: claiming workflow retry %s: %v", instance.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := e.retryInstance(ctx, instance); err != nil {
+			log.Printf("ERROR: retrying workflow instance %s: %v", instance.ID, err)
+			continue
+		}
+		retried++
+	}
+	return retried, nil
 }
 
 // ── Internal ──
@@ -186,8 +329,11 @@ func (e *WFEngine) createInstance(ctx context.Context,
 	instanceID, err := e.wfStore.CreateInstance(ctx, e.pool, e.dialect, WorkflowInstanceData{
 		WorkflowID:   wf.ID,
 		WorkflowName: wf.Name,
+		Entity:       wf.Trigger.Entity,
+		RecordID:     recordIDString(recordID),
 		CurrentStep:  firstStepID,
 		Context:      wfCtx,
+		MaxAttempts:  WorkflowRetryMaxAttempts,
 	})
 	if err != nil {
 		return err
@@ -197,10 +343,13 @@ func (e *WFEngine) createInstance(ctx context.Context,
 		ID:           instanceID,
 		WorkflowID:   wf.ID,
 		WorkflowName: wf.Name,
+		Entity:       wf.Trigger.Entity,
+		RecordID:     recordIDString(recordID),
 		Status:       "running",
 		CurrentStep:  firstStepID,
 		Context:      wfCtx,
 		History:      []metadata.WorkflowHistoryEntry{},
+		MaxAttempts:  WorkflowRetryMaxAttempts,
 	}
 
 	log.Printf("Created workflow instance %s for workflow %s", instance.ID, wf.Name)
@@ -220,14 +369,34 @@ func (e *WFEngine) advanceWorkflow(ctx context.Context,
 		ActionExecutors: e.actionExecutors,
 		Evaluator:       e.evaluator,
 		Registry:        e.registry,
+		Persist: func(ctx context.Context, instance *metadata.WorkflowInstance) error {
+			return e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance)
+		},
 	}
 
+	executed := 0
 	for {
 		if instance.Status != "running" {
 			span.SetStatus("ok")
 			return nil
 		}
 
+		if e.maxSteps > 0 && executed >= e.maxSteps {
+			msg := fmt.Sprintf("exceeded %d step executions in one run, possible goto loop", e.maxSteps)
+			log.Printf("ERROR: workflow %s instance %s at step %s: %s", wf.Name, instance.ID, instance.CurrentStep, msg)
+			instance.Status = "failed"
+			instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
+				Step:   instance.CurrentStep,
+				Status: "step_limit_exceeded",
+				Error:  msg,
+				At:     time.Now().UTC().Format(time.RFC3339),
+			})
+			span.SetStatus("error")
+			span.SetMetadata("error", msg)
+			return e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance)
+		}
+		executed++
+
 		step := wf.FindStep(instance.CurrentStep)
 		if step == nil {
 			instance.Status = "failed"
@@ -247,14 +416,27 @@ func (e *WFEngine) advanceWorkflow(ctx context.Context,
 		if err != nil {
 			log.Printf("ERROR: workflow %s step %s failed: %v", wf.Name, step.ID, err)
 			instance.Status = "failed"
+			instance.LastError = err.Error()
+			instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
+				Step:   step.ID,
+				Status: "failed",
+				Error:  err.Error(),
+				At:     time.Now().UTC().Format(time.RFC3339),
+			})
 			span.SetStatus("error")
 			span.SetMetadata("error", err.Error())
-			return e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance)
+			if err := e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance); err != nil {
+				return err
+			}
+			return e.scheduleRetry(ctx, instance, err)
 		}
 
 		if result.Paused {
 			span.SetStatus("ok")
 			span.SetMetadata("paused_at", instance.CurrentStep)
+			if result.Persisted {
+				return nil
+			}
 			return e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance)
 		}
 
@@ -269,6 +451,56 @@ func (e *WFEngine) advanceWorkflow(ctx context.Context,
 	}
 }
 
+// scheduleRetry schedules a failed instance for another attempt when its step
+// failed with a transient error and it has attempts left. Other failures,
+// such as validation errors or guard rejections, stay failed.
+func (e *WFEngine) scheduleRetry(ctx context.Context, instance *metadata.WorkflowInstance, stepErr error) error {
+	if !isTransientWorkflowError(stepErr) || instance.Attempts >= instance.MaxAttempts {
+		return nil
+	}
+	delay := workflowRetryDelay(instance.Attempts)
+	log.Printf("Workflow instance %s will be retried in %s (attempt %d/%d)", instance.ID, delay, instance.Attempts+1, instance.MaxAttempts)
+	return e.wfStore.ScheduleRetry(ctx, e.pool, e.dialect, instance.ID, time.Now().Add(delay))
+}
+
+// workflowRetryDelay is the delay before the retry that follows attempts
+// earlier ones: WorkflowRetryBackoff doubled once per attempt, without going
+// past maxWorkflowRetryDelay.
+func workflowRetryDelay(attempts int) time.Duration {
+	delay := WorkflowRetryBackoff
+	for range attempts {
+		if delay >= maxWorkflowRetryDelay {
+			break
+		}
+		delay = min(delay*2, maxWorkflowRetryDelay)
+	}
+	return delay
+}
+
+// retryInstance re-runs a failed instance, claimed with ClaimRetry, from its
+// current step. The instance is persisted as running first, so a retry that
+// can't start isn't picked up again on every tick.
+func (e *WFEngine) retryInstance(ctx context.Context, instance *metadata.WorkflowInstance) error {
+	wf := e.registry.GetWorkflow(instance.WorkflowName)
+	if wf == nil {
+		return fmt.Errorf("workflow definition not found: %s", instance.WorkflowName)
+	}
+
+	instance.Attempts++
+	instance.Status = "running"
+	instance.NextRetryAt = nil
+	instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
+		Step:   instance.CurrentStep,
+		Status: "retried",
+		At:     time.Now().UTC().Format(time.RFC3339),
+	})
+	if err := e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance); err != nil {
+		return err
+	}
+	log.Printf("Retrying workflow instance %s at step %s (attempt %d/%d)", instance.ID, instance.CurrentStep, instance.Attempts, instance.MaxAttempts)
+	return e.advanceWorkflow(ctx, instance, wf)
+}
+
 func (e *WFEngine) handleTimeout(ctx context.Context, instance *metadata.WorkflowInstance) error {
 	wf := e.registry.GetWorkflow(instance.WorkflowName)
 	if wf == nil {
@@ -277,10 +509,21 @@ func (e *WFEngine) handleTimeout(ctx context.Context, instance *metadata.Workflo
 	}
 
 	step := wf.FindStep(instance.CurrentStep)
-	if step == nil || step.Type != "approval" {
+	if step == nil || (step.Type != "approval" && step.Type != "callback") {
 		return nil
 	}
 
+	// A callback that arrived first has consumed the token and resumed the step
+	if step.Type == "callback" && instance.CallbackToken != "" {
+		consumed, err := e.wfStore.ConsumeCallbackToken(ctx, e.pool, e.dialect, instance.ID, instance.CallbackToken)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return nil
+		}
+	}
+
 	log.Printf("Workflow instance %s step %s timed out", instance.ID, step.ID)
 
 	instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
@@ -288,7 +531,9 @@ func (e *WFEngine) handleTimeout(ctx context.Context, instance *metadata.Workflo
 		Status: "timed_out",
 		At:     time.Now().UTC().Format(time.RFC3339),
 	})
+	setStepOutput(instance, step.ID, map[string]any{"status": "timed_out"})
 	instance.CurrentStepDeadline = nil
+	instance.CallbackToken = ""
 
 	nextGoto := ""
 	if step.OnTimeout != nil {
@@ -328,10 +573,71 @@ func ResolveWorkflowAction(ctx context.Context, s *store.Store, reg *metadata.Re
 	return engine.ResolveAction(ctx, instanceID, action, userID)
 }
 
-// ListPendingInstances returns workflow instances that are running (awaiting approval).
-func ListPendingInstances(ctx context.Context, s *store.Store) ([]*metadata.WorkflowInstance, error) {
+// ResolveWorkflowCallback resumes an instance paused on a callback step.
+func ResolveWorkflowCallback(ctx context.Context, s *store.Store, reg *metadata.Registry,
+	instanceID, token string, success bool, data any) (*metadata.WorkflowInstance, error) {
+	engine := NewDefaultWFEngine(s, reg)
+	return engine.ResolveCallback(ctx, instanceID, token, success, data)
+}
+
+// ListPendingInstances returns a page of running instances waiting on a step,
+// plus the total count. assignee narrows them to steps currently assigned to
+// that role or fixed user (see currentAssignee).
+func ListPendingInstances(ctx context.Context, s *store.Store, reg *metadata.Registry, filter PendingInstanceFilter, assignee metadata.WorkflowAssignee) ([]*metadata.WorkflowInstance, int, error) {
+	if assignee.Role != "" || assignee.User != "" {
+		filter.Assignee = assignee
+		filter.AssignedSteps = assignedSteps(reg, assignee)
+	}
+	wfStore := &PgWorkflowStore{}
+	return wfStore.ListPending(ctx, s.DB, s.Dialect, filter)
+}
+
+// assignedSteps maps each workflow to the IDs of its steps whose own
+// assignee is the given role or fixed user.
+func assignedSteps(reg *metadata.Registry, assignee metadata.WorkflowAssignee) map[string][]string {
+	out := map[string][]string{}
+	for _, wf := range reg.AllWorkflows() {
+		for _, step := range wf.Steps {
+			a := step.Assignee
+			if a == nil {
+				continue
+			}
+			if (assignee.Role != "" && a.Type == "role" && a.Role == assignee.Role) ||
+				(assignee.User != "" && a.Type == "fixed" && a.User == assignee.User) {
+				out[wf.Name] = append(out[wf.Name], step.ID)
+			}
+		}
+	}
+	return out
+}
+
+// currentAssignee returns who an instance's current step is assigned to: the
+// assignee an escalation handed it to, else the step's own. Nil when the
+// workflow or step is gone or the step has no assignee.
+func currentAssignee(reg *metadata.Registry, instance *metadata.WorkflowInstance) *metadata.WorkflowAssignee {
+	if out := stepOutput(instance, instance.CurrentStep); out != nil && out["assignee"] != nil {
+		// Escalations store the assignee in the context, which is JSON once reloaded
+		raw, _ := json.Marshal(out["assignee"])
+		var a metadata.WorkflowAssignee
+		if json.Unmarshal(raw, &a) == nil {
+			return &a
+		}
+	}
+	wf := reg.GetWorkflow(instance.WorkflowName)
+	if wf == nil {
+		return nil
+	}
+	step := wf.FindStep(instance.CurrentStep)
+	if step == nil {
+		return nil
+	}
+	return step.Assignee
+}
+
+// ListWorkflowInstances returns a page of workflow instances matching the filter, plus the total count.
+func ListWorkflowInstances(ctx context.Context, s *store.Store, filter WorkflowInstanceFilter) ([]*metadata.WorkflowInstance, int, error) {
 	wfStore := &PgWorkflowStore{}
-	return wfStore.ListPending(ctx, s.DB, s.Dialect)
+	return wfStore.List(ctx, s.DB, s.Dialect, filter)
 }
 
 // DeleteWorkflowInstance removes a workflow instance by ID.
@@ -342,6 +648,13 @@ func DeleteWorkflowInstance(ctx context.Context, s *store.Store, id string) erro
 
 // ── Context helpers ──
 
+func recordIDString(recordID any) string {
+	if recordID == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", recordID)
+}
+
 func buildWorkflowContext(mappings map[string]string, record map[string]any, recordID any) map[string]any {
 	ctx := make(map[string]any, len(mappings))
 	for key, path := range mappings {
@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -11,52 +12,59 @@ import (
 	"rocket-backend/internal/store"
 )
 
-// ActionExecutor handles execution of a single workflow action type.
+// ActionExecutor handles execution of a single workflow action type. The
+// returned output (may be nil) is stored under context.steps.<step id> so later
+// steps can read it.
 type ActionExecutor interface {
-	Execute(ctx context.Context, q store.Querier, reg *metadata.Registry, instance *metadata.WorkflowInstance, action *metadata.WorkflowAction) error
+	Execute(ctx context.Context, q store.Querier, reg *metadata.Registry, instance *metadata.WorkflowInstance, action *metadata.WorkflowAction) (map[string]any, error)
 }
 
 // SetFieldActionExecutor performs a field update on a target entity record.
 type SetFieldActionExecutor struct{}
 
 func (e *SetFieldActionExecutor) Execute(ctx context.Context, q store.Querier, reg *metadata.Registry,
-	instance *metadata.WorkflowInstance, action *metadata.WorkflowAction) error {
+	instance *metadata.WorkflowInstance, action *metadata.WorkflowAction) (map[string]any, error) {
 
 	entityName := action.Entity
 	if entityName == "" {
-		return fmt.Errorf("set_field action missing entity")
+		return nil, fmt.Errorf("set_field action missing entity")
 	}
 
 	entity := reg.GetEntity(entityName)
 	if entity == nil {
-		return fmt.Errorf("entity not found: %s", entityName)
+		return nil, fmt.Errorf("entity not found: %s", entityName)
 	}
 
 	env := map[string]any{"context": instance.Context}
 	recordID := resolveContextPath(env, action.RecordID)
 	if recordID == nil {
-		return fmt.Errorf("could not resolve record_id: %s", action.RecordID)
+		return nil, fmt.Errorf("could not resolve record_id: %s", action.RecordID)
 	}
 
 	val := action.Value
 	if s, ok := val.(string); ok && s == "now" {
 		val = time.Now().UTC().Format(time.RFC3339)
 	}
+	if f := entity.GetField(action.Field); f != nil {
+		val = f.ApplyTransforms(val)
+	}
 
 	sql := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = $2",
 		entity.Table, action.Field, entity.PrimaryKey.Field)
-	if _, err := store.Exec(ctx, q, sql, val, recordID); err != nil {
-		return fmt.Errorf("set_field UPDATE: %w", err)
+	affected, err := store.Exec(ctx, q, sql, val, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("set_field UPDATE: %w", err)
 	}
+	invalidateEntityCache(reg, entity.Name)
 
-	return nil
+	return map[string]any{"record_id": recordID, "field": action.Field, "value": val, "affected": affected}, nil
 }
 
 // WebhookActionExecutor dispatches an HTTP request as a workflow action.
 type WebhookActionExecutor struct{}
 
 func (e *WebhookActionExecutor) Execute(ctx context.Context, _ store.Querier, _ *metadata.Registry,
-	instance *metadata.WorkflowInstance, action *metadata.WorkflowAction) error {
+	instance *metadata.WorkflowInstance, action *metadata.WorkflowAction) (map[string]any, error) {
 
 	body, _ := json.Marshal(instance.Context)
 	method := action.Method
@@ -65,31 +73,70 @@ func (e *WebhookActionExecutor) Execute(ctx context.Context, _ store.Querier, _
 	}
 
 	result := DispatchWebhookDirect(ctx, action.URL, method, nil, body)
+	ok := result.Error == "" && result.StatusCode >= 200 && result.StatusCode < 300
+	output := map[string]any{"status_code": result.StatusCode, "ok": ok}
+	// A JSON response is exposed as a value so conditions can read into it
+	var parsed any
+	if json.Unmarshal([]byte(result.ResponseBody), &parsed) == nil {
+		output["body"] = parsed
+	} else {
+		output["body"] = result.ResponseBody
+	}
+
 	if result.Error != "" {
-		return fmt.Errorf("workflow webhook %s %s failed: %s", method, action.URL, result.Error)
+		return output, markTransient(fmt.Errorf("workflow webhook %s %s failed: %s", method, action.URL, result.Error), true)
 	}
-	if result.StatusCode < 200 || result.StatusCode >= 300 {
-		return fmt.Errorf("workflow webhook %s %s returned HTTP %d", method, action.URL, result.StatusCode)
+	if !ok {
+		return output, markTransient(fmt.Errorf("workflow webhook %s %s returned HTTP %d", method, action.URL, result.StatusCode),
+			transientDispatch(result))
+	}
+	return output, nil
+}
+
+// transientError marks a step failure that may succeed if retried, such as a
+// webhook target that is down.
+type transientError struct{ err error }
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// markTransient wraps err as a transientError when transient is true.
+func markTransient(err error, transient bool) error {
+	if !transient {
+		return err
 	}
-	return nil
+	return &transientError{err: err}
+}
+
+// transientDispatch reports whether a failed HTTP dispatch is worth retrying:
+// a network error, a timeout, rate limiting or a server error.
+func transientDispatch(result *DispatchResult) bool {
+	return result.Error != "" || result.StatusCode == 408 || result.StatusCode == 429 || result.StatusCode >= 500
+}
+
+// isTransientWorkflowError reports whether a step failure may be retried
+// automatically: a marked transientError or a transient database error.
+func isTransientWorkflowError(err error) bool {
+	var te *transientError
+	return errors.As(err, &te) || store.IsTransientError(err)
 }
 
 // CreateRecordActionExecutor creates a new record in a target entity (stub).
 type CreateRecordActionExecutor struct{}
 
 func (e *CreateRecordActionExecutor) Execute(_ context.Context, _ store.Querier, _ *metadata.Registry,
-	_ *metadata.WorkflowInstance, action *metadata.WorkflowAction) error {
+	_ *metadata.WorkflowInstance, action *metadata.WorkflowAction) (map[string]any, error) {
 	log.Printf("STUB: workflow create_record action for entity %s (not yet implemented)", action.Entity)
-	return nil
+	return nil, nil
 }
 
 // SendEventActionExecutor emits a named event (stub).
 type SendEventActionExecutor struct{}
 
 func (e *SendEventActionExecutor) Execute(_ context.Context, _ store.Querier, _ *metadata.Registry,
-	_ *metadata.WorkflowInstance, action *metadata.WorkflowAction) error {
+	_ *metadata.WorkflowInstance, action *metadata.WorkflowAction) (map[string]any, error) {
 	log.Printf("STUB: workflow send_event action '%s' (not yet implemented)", action.Event)
-	return nil
+	return nil, nil
 }
 
 // DefaultActionExecutors returns the built-in set of action executors.
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"rocket-backend/internal/metadata"
+)
+
+// ProcessEscalations fires the escalations of waiting approval steps whose
+// share of the timeout has elapsed. Runs before ProcessTimeouts so an
+// escalation at the deadline still notifies, or reassigns, before the step
+// times out. It returns how many instances were checked without error and
+// whether the lookup failed.
+func (e *WFEngine) ProcessEscalations(ctx context.Context) (int, error) {
+	instances, err := e.wfStore.FindWithDeadline(ctx, e.pool, e.dialect)
+	if err != nil {
+		log.Printf("ERROR: workflow escalation query failed: %v", err)
+		return 0, fmt.Errorf("find workflows with deadlines: %w", err)
+	}
+
+	checked := 0
+	for _, instance := range instances {
+		if err := e.escalate(ctx, instance, time.Now()); err != nil {
+			log.Printf("ERROR: processing escalations for instance %s: %v", instance.ID, err)
+			continue
+		}
+		checked++
+	}
+	return checked, nil
+}
+
+// escalate fires every escalation of the instance's current step that is due
+// at now and hasn't fired yet. The number fired is kept in the step output
+// (context.steps.<id>.escalations), which approving, rejecting or timing out
+// replaces, so re-entering the step starts over.
+func (e *WFEngine) escalate(ctx context.Context, instance *metadata.WorkflowInstance, now time.Time) error {
+	wf := e.registry.GetWorkflow(instance.WorkflowName)
+	if wf == nil || instance.CurrentStepDeadline == nil {
+		return nil
+	}
+	step := wf.FindStep(instance.CurrentStep)
+	if step == nil || step.Type != "approval" || len(step.Escalations) == 0 {
+		return nil
+	}
+	timeout, err := time.ParseDuration(step.Timeout)
+	if err != nil || timeout <= 0 {
+		return nil
+	}
+	deadline, ok := parseDeadline(*instance.CurrentStepDeadline)
+	if !ok {
+		return fmt.Errorf("unparseable deadline %q", *instance.CurrentStepDeadline)
+	}
+
+	output := stepOutput(instance, step.ID)
+	if output == nil || output["status"] != "waiting" {
+		output = map[string]any{"status": "waiting"}
+	}
+	fired := toInt(output["escalations"])
+	elapsed := float64(now.Sub(deadline.Add(-timeout))) / float64(timeout)
+	if fired >= len(step.Escalations) || elapsed < step.Escalations[fired].At {
+		return nil
+	}
+
+	for ; fired < len(step.Escalations) && elapsed >= step.Escalations[fired].At; fired++ {
+		esc := &step.Escalations[fired]
+		status := "escalated"
+		if esc.Reassign != nil {
+			status = "reassigned"
+			output["assignee"] = esc.Reassign
+		}
+		output["escalations"] = fired + 1
+		output["deadline"] = deadline.UTC().Format(time.RFC3339)
+		setStepOutput(instance, step.ID, output)
+
+		// Notify actions see the updated context; a failed one is recorded
+		// but doesn't stop the escalation
+		var errMsg string
+		for i := range esc.Notify {
+			executor, ok := e.actionExecutors[esc.Notify[i].Type]
+			if !ok {
+				log.Printf("WARN: unknown workflow action type: %s", esc.Notify[i].Type)
+				continue
+			}
+			if _, err := executor.Execute(ctx, e.pool, e.registry, instance, &esc.Notify[i]); err != nil && errMsg == "" {
+				errMsg = err.Error()
+			}
+		}
+		instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
+			Step:   step.ID,
+			Status: status,
+			Error:  errMsg,
+			At:     now.UTC().Format(time.RFC3339),
+		})
+		log.Printf("Workflow instance %s step %s %s at %.0f%% of its timeout", instance.ID, step.ID, status, esc.At*100)
+
+		// Reassigning at the deadline gives the new assignee a full timeout
+		if esc.Reassign != nil && esc.At >= 1 {
+			deadline = now.Add(timeout)
+			elapsed = 0
+		}
+	}
+
+	d := deadline.UTC().Format(time.RFC3339)
+	instance.CurrentStepDeadline = &d
+	return e.wfStore.PersistInstance(ctx, e.pool, e.dialect, instance)
+}
+
+// stepOutput returns context.steps.<id>, or nil if the step hasn't recorded one.
+func stepOutput(instance *metadata.WorkflowInstance, stepID string) map[string]any {
+	steps, _ := instance.Context["steps"].(map[string]any)
+	out, _ := steps[stepID].(map[string]any)
+	return out
+}
+
+// parseDeadline reads current_step_deadline as written by the step executors
+// (RFC 3339) or as loaded back from the database.
+func parseDeadline(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
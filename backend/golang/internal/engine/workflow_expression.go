@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/expr-lang/expr"
@@ -28,14 +29,14 @@ func (e *ExprLangEvaluator) EvaluateBool(expression string, env map[string]any)
 	prog, ok := e.cache[expression]
 	if !ok {
 		var err error
-		prog, err = expr.Compile(expression, expr.AsBool())
+		prog, err = compileExpr(expression, expr.AsBool())
 		if err != nil {
 			return false, fmt.Errorf("compile condition: %w", err)
 		}
 		e.cache[expression] = prog
 	}
 
-	result, err := expr.Run(prog, env)
+	result, err := runExpr(context.Background(), prog, env)
 	if err != nil {
 		return false, fmt.Errorf("evaluate condition: %w", err)
 	}
@@ -1,6 +1,9 @@
 package engine
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
 	"rocket-backend/internal/instrument"
@@ -21,7 +24,12 @@ func NewWorkflowHandler(s *store.Store, reg *metadata.Registry) *WorkflowHandler
 // RegisterWorkflowRoutes adds workflow runtime routes.
 // Must be registered AFTER admin routes but BEFORE dynamic entity routes.
 func RegisterWorkflowRoutes(app *fiber.App, h *WorkflowHandler, middleware ...fiber.Handler) {
+	// Called by external systems, which authenticate with the step's token
+	// instead of a user session, so it sits ahead of the group middleware.
+	app.Post("/api/_workflows/:id/callback", h.Callback)
+
 	wf := app.Group("/api/_workflows", middleware...)
+	wf.Get("/", h.List)
 	wf.Get("/pending", h.ListPending)
 	wf.Get("/:id", h.GetInstance)
 	wf.Post("/:id/approve", h.Approve)
@@ -38,15 +46,112 @@ func (h *WorkflowHandler) GetInstance(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"data": instance})
 }
 
+// List handles GET /api/_workflows with optional filters:
+// ?status, ?workflow, ?entity, ?record_id, ?from, ?to (created_at range), ?page, ?per_page.
+func (h *WorkflowHandler) List(c *fiber.Ctx) error {
+	filter := WorkflowInstanceFilter{
+		Status:        c.Query("status"),
+		WorkflowName:  c.Query("workflow"),
+		Entity:        c.Query("entity"),
+		RecordID:      c.Query("record_id"),
+		CreatedAfter:  c.Query("from"),
+		CreatedBefore: c.Query("to"),
+		Page:          c.QueryInt("page", 1),
+		PerPage:       c.QueryInt("per_page", DefaultPerPage),
+	}
+	if filter.Status != "" && !validInstanceStatuses[filter.Status] {
+		return NewAppError("VALIDATION_FAILED", 422, "status must be one of: running, completed, failed, cancelled")
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = DefaultPerPage
+	}
+	if filter.PerPage > MaxPerPage {
+		filter.PerPage = MaxPerPage
+	}
+
+	instances, total, err := ListWorkflowInstances(c.Context(), h.store, filter)
+	if err != nil {
+		return NewAppError("INTERNAL_ERROR", 500, "Failed to list workflow instances")
+	}
+	if instances == nil {
+		instances = []*metadata.WorkflowInstance{}
+	}
+	return c.JSON(fiber.Map{
+		"data": instances,
+		"meta": fiber.Map{
+			"page":     filter.Page,
+			"per_page": filter.PerPage,
+			"total":    total,
+		},
+	})
+}
+
+var validInstanceStatuses = map[string]bool{
+	"running":   true,
+	"completed": true,
+	"failed":    true,
+	"cancelled": true,
+}
+
+// ListPending handles GET /api/_workflows/pending: instances waiting on a
+// step, most urgent deadline first. Filters: ?workflow, ?assignee_role,
+// ?assignee_user, ?deadline_from, ?deadline_to (RFC 3339); paging: ?limit,
+// ?offset.
 func (h *WorkflowHandler) ListPending(c *fiber.Ctx) error {
-	instances, err := ListPendingInstances(c.Context(), h.store)
+	filter := PendingInstanceFilter{
+		WorkflowName: c.Query("workflow"),
+		Limit:        c.QueryInt("limit", DefaultPerPage),
+		Offset:       c.QueryInt("offset", 0),
+	}
+	var err error
+	if filter.DeadlineAfter, err = queryTime(c, "deadline_from"); err != nil {
+		return err
+	}
+	if filter.DeadlineBefore, err = queryTime(c, "deadline_to"); err != nil {
+		return err
+	}
+	if filter.Limit < 1 {
+		filter.Limit = DefaultPerPage
+	}
+	if filter.Limit > MaxPerPage {
+		filter.Limit = MaxPerPage
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	assignee := metadata.WorkflowAssignee{Role: c.Query("assignee_role"), User: c.Query("assignee_user")}
+
+	instances, total, err := ListPendingInstances(c.Context(), h.store, h.registry, filter, assignee)
 	if err != nil {
 		return NewAppError("INTERNAL_ERROR", 500, "Failed to list pending instances")
 	}
 	if instances == nil {
 		instances = []*metadata.WorkflowInstance{}
 	}
-	return c.JSON(fiber.Map{"data": instances})
+	return c.JSON(fiber.Map{
+		"data": instances,
+		"meta": fiber.Map{
+			"limit":  filter.Limit,
+			"offset": filter.Offset,
+			"total":  total,
+		},
+	})
+}
+
+// queryTime parses an optional RFC 3339 query parameter.
+func queryTime(c *fiber.Ctx, param string) (*time.Time, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, NewAppError("INVALID_PAYLOAD", 400, param+" must be an RFC 3339 timestamp")
+	}
+	return &t, nil
 }
 
 func (h *WorkflowHandler) Approve(c *fiber.Ctx) error {
@@ -98,3 +203,52 @@ func (h *WorkflowHandler) Reject(c *fiber.Ctx) error {
 	span.SetStatus("ok")
 	return c.JSON(fiber.Map{"data": instance})
 }
+
+// Callback handles POST /api/_workflows/:id/callback from the external system a
+// callback step notified. Body: {"token": "...", "status": "success"|"failure", "data": {...}}.
+// The token may also be sent in the X-Callback-Token header.
+func (h *WorkflowHandler) Callback(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	ctx, span := instrument.GetInstrumenter(ctx).StartSpan(ctx, "workflow", "handler", "workflow.callback")
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	id := c.Params("id")
+	span.SetMetadata("instance_id", id)
+
+	var body struct {
+		Token  string `json:"token"`
+		Status string `json:"status"`
+		Data   any    `json:"data"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return NewAppError("INVALID_PAYLOAD", 400, "Invalid JSON body")
+		}
+	}
+	if body.Token == "" {
+		body.Token = c.Get("X-Callback-Token")
+	}
+	if body.Token == "" {
+		return UnauthorizedError("Callback token required")
+	}
+	if body.Status == "" {
+		body.Status = "success"
+	}
+	if body.Status != "success" && body.Status != "failure" {
+		return NewAppError("VALIDATION_FAILED", 422, "status must be success or failure")
+	}
+
+	instance, err := ResolveWorkflowCallback(c.Context(), h.store, h.registry, id, body.Token, body.Status == "success", body.Data)
+	if err != nil {
+		span.SetStatus("error")
+		span.SetMetadata("error", err.Error())
+		if errors.Is(err, ErrInvalidCallbackToken) {
+			return ForbiddenError(err.Error())
+		}
+		return NewAppError("VALIDATION_FAILED", 422, err.Error())
+	}
+
+	span.SetStatus("ok")
+	return c.JSON(fiber.Map{"data": instance})
+}
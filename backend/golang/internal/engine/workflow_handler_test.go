@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestWorkflowHandlerList_FilterByStatus(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-1', 'order_approval', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+
+	wfStore := &PgWorkflowStore{}
+	for i, status := range []string{"running", "failed", "completed", "failed"} {
+		id, err := wfStore.CreateInstance(ctx, s.DB, s.Dialect, WorkflowInstanceData{
+			WorkflowID:   "wf-1",
+			WorkflowName: "order_approval",
+			Entity:       "order",
+			RecordID:     string(rune('a' + i)),
+			CurrentStep:  "review",
+			Context:      map[string]any{},
+		})
+		if err != nil {
+			t.Fatalf("create instance: %v", err)
+		}
+		inst, err := wfStore.LoadInstance(ctx, s.DB, s.Dialect, id)
+		if err != nil {
+			t.Fatalf("load instance: %v", err)
+		}
+		inst.Status = status
+		if err := wfStore.PersistInstance(ctx, s.DB, s.Dialect, inst); err != nil {
+			t.Fatalf("persist instance: %v", err)
+		}
+	}
+
+	h := NewWorkflowHandler(s, metadata.NewRegistry())
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Get("/api/_workflows", h.List)
+
+	get := func(query string) (int, map[string]any) {
+		req, _ := http.NewRequest("GET", "/api/_workflows"+query, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := get("?status=failed")
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %v", status, out)
+	}
+	data := out["data"].([]any)
+	if len(data) != 2 {
+		t.Fatalf("expected 2 failed instances, got %d", len(data))
+	}
+	for _, d := range data {
+		inst := d.(map[string]any)
+		if inst["status"] != "failed" || inst["entity"] != "order" || inst["current_step"] != "review" {
+			t.Fatalf("unexpected instance: %v", inst)
+		}
+	}
+	if total := out["meta"].(map[string]any)["total"]; total != float64(2) {
+		t.Fatalf("expected total 2, got %v", total)
+	}
+
+	_, out = get("?status=failed&record_id=b")
+	if data := out["data"].([]any); len(data) != 1 {
+		t.Fatalf("expected 1 instance for record b, got %d", len(data))
+	}
+
+	if status, _ := get("?status=bogus"); status != 422 {
+		t.Fatalf("expected 422 for unknown status, got %d", status)
+	}
+}
+
+func TestWorkflowHandlerListPending_PagesByDeadlineAndFiltersByAssignee(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-1', 'order_approval', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWorkflows([]*metadata.Workflow{{
+		ID: "wf-1", Name: "order_approval", Active: true,
+		Steps: []metadata.WorkflowStep{{ID: "review", Type: "approval",
+			Assignee: &metadata.WorkflowAssignee{Type: "role", Role: "manager"}}},
+	}})
+
+	// Deadlines out of creation order; "c" was escalated to a director
+	wfStore := &PgWorkflowStore{}
+	for _, inst := range []struct{ record, deadline string }{
+		{"a", "2026-03-04T00:00:00Z"}, {"b", "2026-03-01T00:00:00Z"}, {"c", "2026-03-02T00:00:00Z"},
+		{"d", ""}, {"e", "2026-03-03T00:00:00Z"},
+	} {
+		wfCtx := map[string]any{}
+		if inst.record == "c" {
+			wfCtx["steps"] = map[string]any{"review": map[string]any{"status": "waiting",
+				"assignee": map[string]any{"type": "role", "role": "director"}}}
+		}
+		id, err := wfStore.CreateInstance(ctx, s.DB, s.Dialect, WorkflowInstanceData{
+			WorkflowID: "wf-1", WorkflowName: "order_approval", Entity: "order",
+			RecordID: inst.record, CurrentStep: "review", Context: wfCtx,
+		})
+		if err != nil {
+			t.Fatalf("create instance: %v", err)
+		}
+		loaded, err := wfStore.LoadInstance(ctx, s.DB, s.Dialect, id)
+		if err != nil {
+			t.Fatalf("load instance: %v", err)
+		}
+		if inst.deadline != "" {
+			loaded.CurrentStepDeadline = &inst.deadline
+		}
+		if err := wfStore.PersistInstance(ctx, s.DB, s.Dialect, loaded); err != nil {
+			t.Fatalf("persist instance: %v", err)
+		}
+	}
+
+	h := NewWorkflowHandler(s, reg)
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Get("/api/_workflows/pending", h.ListPending)
+
+	get := func(query string) ([]string, float64) {
+		req, _ := http.NewRequest("GET", "/api/_workflows/pending"+query, nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out struct {
+			Data []struct {
+				RecordID string `json:"record_id"`
+			} `json:"data"`
+			Meta struct {
+				Total float64 `json:"total"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(raw, &out); err != nil || resp.StatusCode != 200 {
+			t.Fatalf("GET pending%s: %d %s", query, resp.StatusCode, raw)
+		}
+		var records []string
+		for _, d := range out.Data {
+			records = append(records, d.RecordID)
+		}
+		return records, out.Meta.Total
+	}
+
+	if records, total := get("?limit=2"); total != 5 || len(records) != 2 || records[0] != "b" || records[1] != "c" {
+		t.Fatalf("expected the 2 most urgent of 5 (b, c), got %v of %v", records, total)
+	}
+	if records, _ := get("?limit=2&offset=2"); len(records) != 2 || records[0] != "e" || records[1] != "a" {
+		t.Fatalf("expected page 2 to be e, a, got %v", records)
+	}
+	if records, _ := get("?limit=2&offset=4"); len(records) != 1 || records[0] != "d" {
+		t.Fatalf("expected the instance without a deadline last, got %v", records)
+	}
+
+	if records, total := get("?assignee_role=manager&limit=2"); total != 4 || len(records) != 2 || records[0] != "b" || records[1] != "e" {
+		t.Fatalf("expected managers' b, e of 4, got %v of %v", records, total)
+	}
+	if records, total := get("?assignee_role=director"); total != 1 || len(records) != 1 || records[0] != "c" {
+		t.Fatalf("expected the escalated instance for directors, got %v of %v", records, total)
+	}
+	if records, total := get("?deadline_to=2026-03-02T23:59:59Z"); total != 2 || len(records) != 2 {
+		t.Fatalf("expected 2 instances due by March 2, got %v of %v", records, total)
+	}
+	if records, total := get("?deadline_to=2026-03-03T01:00:00%2B02:00"); total != 2 || len(records) != 2 {
+		t.Fatalf("expected the bound compared as a time, not as text, got %v of %v", records, total)
+	}
+	req, _ := http.NewRequest("GET", "/api/_workflows/pending?deadline_from=yesterday", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 for a malformed deadline, got %d", resp.StatusCode)
+	}
+}
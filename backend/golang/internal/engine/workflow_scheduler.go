@@ -9,12 +9,14 @@ import (
 	"rocket-backend/internal/store"
 )
 
-// WorkflowScheduler runs background tasks for workflow timeouts.
+// WorkflowScheduler runs background tasks for workflow escalations, timeouts
+// and automatic retries.
 // Delegates all logic to WFEngine — no direct SQL or instance parsing.
 type WorkflowScheduler struct {
 	store    *store.Store
 	registry *metadata.Registry
 	engine   *WFEngine
+	health   *SchedulerHealth
 	ticker   *time.Ticker
 	done     chan struct{}
 }
@@ -30,6 +32,7 @@ func NewWorkflowScheduler(s *store.Store, reg *metadata.Registry) *WorkflowSched
 // Start begins the background ticker for processing timeouts.
 func (ws *WorkflowScheduler) Start() {
 	ws.ticker = time.NewTicker(60 * time.Second)
+	ws.health = TrackScheduler("workflows", 60*time.Second)
 	ws.done = make(chan struct{})
 	go ws.run()
 	log.Println("Workflow scheduler started (60s interval)")
@@ -43,6 +46,9 @@ func (ws *WorkflowScheduler) Stop() {
 	if ws.done != nil {
 		close(ws.done)
 	}
+	if ws.health != nil {
+		UntrackScheduler(ws.health.name)
+	}
 }
 
 func (ws *WorkflowScheduler) run() {
@@ -51,16 +57,34 @@ func (ws *WorkflowScheduler) run() {
 		case <-ws.done:
 			return
 		case <-ws.ticker.C:
-			ws.engine.ProcessTimeouts(context.Background())
+			ws.health.Record(processWorkflowTimeouts(ws.engine))
 		}
 	}
 }
 
-// ProcessWorkflowTimeouts processes timed-out workflow instances for a given store and registry.
-// Used by the multi-app scheduler.
-func ProcessWorkflowTimeouts(s *store.Store, reg *metadata.Registry) {
-	engine := NewDefaultWFEngine(s, reg)
-	engine.ProcessTimeouts(context.Background())
+// ProcessWorkflowTimeouts processes due escalations, timed-out workflow
+// instances and, when enabled, due automatic retries for a given store and
+// registry. Used by the multi-app scheduler. Returns the number of instances
+// processed and the first lookup error.
+func ProcessWorkflowTimeouts(s *store.Store, reg *metadata.Registry) (int, error) {
+	return processWorkflowTimeouts(NewDefaultWFEngine(s, reg))
+}
+
+func processWorkflowTimeouts(engine *WFEngine) (int, error) {
+	escalated, escErr := engine.ProcessEscalations(context.Background())
+	timedOut, err := engine.ProcessTimeouts(context.Background())
+	if escErr != nil {
+		err = escErr
+	}
+	retried := 0
+	if WorkflowRetryMaxAttempts > 0 {
+		n, retryErr := engine.ProcessRetries(context.Background())
+		retried = n
+		if err == nil {
+			err = retryErr
+		}
+	}
+	return escalated + timedOut + retried, err
 }
 
 // Ensure WorkflowHandler still has a function it needs — loadWorkflowInstance backward compat.
@@ -2,6 +2,10 @@ package engine
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -14,6 +18,10 @@ import (
 type StepResult struct {
 	Paused   bool
 	NextGoto string
+	// Persisted means the executor already saved the paused instance, so the
+	// engine must not write its in-memory copy over a resume that may have
+	// happened in the meantime.
+	Persisted bool
 }
 
 // StepExecutorContext provides dependencies that step executors need.
@@ -21,6 +29,9 @@ type StepExecutorContext struct {
 	ActionExecutors map[string]ActionExecutor
 	Evaluator       ExpressionEvaluator
 	Registry        *metadata.Registry
+	// Persist saves the instance mid-step, for executors that must record
+	// state before handing control to an external system.
+	Persist func(ctx context.Context, instance *metadata.WorkflowInstance) error
 }
 
 // StepExecutor handles execution of a single workflow step type.
@@ -28,22 +39,55 @@ type StepExecutor interface {
 	Execute(ctx context.Context, q store.Querier, ectx *StepExecutorContext, instance *metadata.WorkflowInstance, step *metadata.WorkflowStep) (*StepResult, error)
 }
 
-// ActionStepExecutor runs all actions in an action step sequentially.
+// ActionStepExecutor runs all actions in an action step sequentially. When
+// the step failed before, it resumes at the action that failed, so a retry
+// doesn't repeat the actions that already succeeded.
 type ActionStepExecutor struct{}
 
 func (e *ActionStepExecutor) Execute(ctx context.Context, q store.Querier, ectx *StepExecutorContext,
 	instance *metadata.WorkflowInstance, step *metadata.WorkflowStep) (*StepResult, error) {
 
-	for _, action := range step.Actions {
+	start, outputs := failedStepActions(instance, step.ID)
+	stepOutput := map[string]any{}
+	for _, out := range outputs {
+		for k, v := range out.(map[string]any) {
+			if k != "type" {
+				stepOutput[k] = v
+			}
+		}
+	}
+	for i := start; i < len(step.Actions); i++ {
+		action := step.Actions[i]
 		executor, ok := ectx.ActionExecutors[action.Type]
 		if !ok {
 			log.Printf("WARN: unknown workflow action type: %s", action.Type)
 			continue
 		}
-		if err := executor.Execute(ctx, q, ectx.Registry, instance, &action); err != nil {
+		out, err := executor.Execute(ctx, q, ectx.Registry, instance, &action)
+		if out == nil {
+			out = map[string]any{}
+		}
+		if err != nil {
+			out["error"] = err.Error()
+		}
+		// Later actions win on key clashes; each one stays reachable via actions[i]
+		for k, v := range out {
+			stepOutput[k] = v
+		}
+		out["type"] = action.Type
+		outputs = append(outputs, out)
+
+		if err != nil && !action.ContinueOnError {
+			stepOutput["status"] = "failed"
+			stepOutput["actions"] = outputs
+			stepOutput["failed_action"] = i
+			setStepOutput(instance, step.ID, stepOutput)
 			return nil, fmt.Errorf("action %s: %w", action.Type, err)
 		}
 	}
+	stepOutput["status"] = "completed"
+	stepOutput["actions"] = outputs
+	setStepOutput(instance, step.ID, stepOutput)
 
 	instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
 		Step:   step.ID,
@@ -95,6 +139,7 @@ func (e *ConditionStepExecutor) Execute(_ context.Context, _ store.Querier, ectx
 		Status: status,
 		At:     time.Now().UTC().Format(time.RFC3339),
 	})
+	setStepOutput(instance, step.ID, map[string]any{"status": status, "result": isTrue})
 
 	return &StepResult{Paused: false, NextGoto: next}, nil
 }
@@ -116,11 +161,141 @@ func (e *ApprovalStepExecutor) Execute(_ context.Context, _ store.Querier, _ *St
 	return &StepResult{Paused: true, NextGoto: ""}, nil
 }
 
+// CallbackStepExecutor dispatches a webhook carrying a one-time token, then
+// pauses until the external system posts the token back to
+// /api/_workflows/:id/callback or the step times out. The hashed token and
+// deadline are saved before the dispatch, so a receiver that calls back
+// immediately finds the instance already waiting.
+type CallbackStepExecutor struct{}
+
+func (e *CallbackStepExecutor) Execute(ctx context.Context, _ store.Querier, ectx *StepExecutorContext,
+	instance *metadata.WorkflowInstance, step *metadata.WorkflowStep) (*StepResult, error) {
+
+	if step.URL == "" {
+		return nil, fmt.Errorf("callback step %s has no url", step.ID)
+	}
+	token, err := newCallbackToken()
+	if err != nil {
+		return nil, err
+	}
+	instance.CallbackToken = hashCallbackToken(token)
+	if step.Timeout != "" {
+		duration, err := time.ParseDuration(step.Timeout)
+		if err == nil {
+			deadline := time.Now().UTC().Add(duration).Format(time.RFC3339)
+			instance.CurrentStepDeadline = &deadline
+		}
+	}
+	if ectx != nil && ectx.Persist != nil {
+		if err := ectx.Persist(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"instance_id":   instance.ID,
+		"workflow":      instance.WorkflowName,
+		"step":          step.ID,
+		"token":         token,
+		"callback_path": "/_workflows/" + instance.ID + "/callback",
+		"context":       instance.Context,
+	})
+	method := step.Method
+	if method == "" {
+		method = "POST"
+	}
+	result := DispatchWebhookDirect(ctx, step.URL, method, nil, body)
+	if result.Error != "" || result.StatusCode < 200 || result.StatusCode >= 300 {
+		msg := result.Error
+		if msg == "" {
+			msg = fmt.Sprintf("HTTP %d", result.StatusCode)
+		}
+		instance.CallbackToken = ""
+		instance.CurrentStepDeadline = nil
+		instance.History = append(instance.History, metadata.WorkflowHistoryEntry{
+			Step:   step.ID,
+			Status: "dispatch_failed",
+			Error:  msg,
+			At:     time.Now().UTC().Format(time.RFC3339),
+		})
+		setStepOutput(instance, step.ID, map[string]any{"status": "dispatch_failed", "status_code": result.StatusCode, "error": msg})
+		if step.OnFailure != nil {
+			return &StepResult{Paused: false, NextGoto: step.OnFailure.Goto}, nil
+		}
+		return nil, markTransient(fmt.Errorf("callback step %s: dispatch to %s failed: %s", step.ID, step.URL, msg), transientDispatch(result))
+	}
+
+	return &StepResult{Paused: true, NextGoto: "", Persisted: ectx != nil && ectx.Persist != nil}, nil
+}
+
+func newCallbackToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate callback token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashCallbackToken is what gets persisted, so a leaked instance row can't be
+// used to resume the workflow.
+func hashCallbackToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// failedStepActions reads back an action step that failed: the index of the
+// action that failed and the outputs of the actions before it. A step that
+// didn't fail starts over at 0.
+func failedStepActions(instance *metadata.WorkflowInstance, stepID string) (int, []any) {
+	steps, _ := instance.Context["steps"].(map[string]any)
+	prev, _ := steps[stepID].(map[string]any)
+	if prev == nil || prev["status"] != "failed" {
+		return 0, []any{}
+	}
+	var failed int
+	switch v := prev["failed_action"].(type) {
+	case int:
+		failed = v
+	case float64: // read back from JSON
+		failed = int(v)
+	default:
+		return 0, []any{}
+	}
+	actions, _ := prev["actions"].([]any)
+	if failed <= 0 || len(actions) == 0 {
+		return 0, []any{}
+	}
+	// The last output is the failed action's own
+	done := actions[:len(actions)-1]
+	for _, out := range done {
+		if _, ok := out.(map[string]any); !ok {
+			return 0, []any{}
+		}
+	}
+	return failed, append([]any{}, done...)
+}
+
+// setStepOutput stores a step's result under context.steps.<id>, where later
+// conditions and actions can read it. It lives in the instance context so it
+// is persisted with the instance and survives a restart or an approval pause.
+func setStepOutput(instance *metadata.WorkflowInstance, stepID string, output map[string]any) {
+	if instance.Context == nil {
+		instance.Context = map[string]any{}
+	}
+	steps, ok := instance.Context["steps"].(map[string]any)
+	if !ok {
+		steps = map[string]any{}
+		instance.Context["steps"] = steps
+	}
+	steps[stepID] = output
+}
+
 // DefaultStepExecutors returns the built-in set of step executors.
 func DefaultStepExecutors() map[string]StepExecutor {
 	return map[string]StepExecutor{
 		"action":    &ActionStepExecutor{},
 		"condition": &ConditionStepExecutor{},
 		"approval":  &ApprovalStepExecutor{},
+		"callback":  &CallbackStepExecutor{},
 	}
 }
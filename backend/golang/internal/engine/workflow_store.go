@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"rocket-backend/internal/metadata"
 	"rocket-backend/internal/store"
@@ -15,8 +18,14 @@ type WorkflowStore interface {
 	CreateInstance(ctx context.Context, q store.Querier, dialect store.Dialect, data WorkflowInstanceData) (string, error)
 	LoadInstance(ctx context.Context, q store.Querier, dialect store.Dialect, id string) (*metadata.WorkflowInstance, error)
 	PersistInstance(ctx context.Context, q store.Querier, dialect store.Dialect, instance *metadata.WorkflowInstance) error
-	ListPending(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error)
+	ListPending(ctx context.Context, q store.Querier, dialect store.Dialect, filter PendingInstanceFilter) ([]*metadata.WorkflowInstance, int, error)
+	List(ctx context.Context, q store.Querier, dialect store.Dialect, filter WorkflowInstanceFilter) ([]*metadata.WorkflowInstance, int, error)
 	FindTimedOut(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error)
+	FindWithDeadline(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error)
+	FindRetryDue(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error)
+	ScheduleRetry(ctx context.Context, q store.Querier, dialect store.Dialect, id string, at time.Time) error
+	ClaimRetry(ctx context.Context, q store.Querier, dialect store.Dialect, id string) (bool, error)
+	ConsumeCallbackToken(ctx context.Context, q store.Querier, dialect store.Dialect, id, tokenHash string) (bool, error)
 	DeleteInstance(ctx context.Context, q store.Querier, dialect store.Dialect, id string) error
 }
 
@@ -24,10 +33,42 @@ type WorkflowStore interface {
 type WorkflowInstanceData struct {
 	WorkflowID   string
 	WorkflowName string
+	Entity       string
+	RecordID     string
 	CurrentStep  string
 	Context      map[string]any
+	MaxAttempts  int // automatic retries allowed after transient failures
 }
 
+// WorkflowInstanceFilter narrows List results. Empty fields are ignored.
+type WorkflowInstanceFilter struct {
+	Status        string
+	WorkflowName  string
+	Entity        string
+	RecordID      string
+	CreatedAfter  string
+	CreatedBefore string
+	Page          int
+	PerPage       int
+}
+
+// PendingInstanceFilter narrows ListPending results. Empty fields are ignored;
+// a Limit of 0 returns every match.
+type PendingInstanceFilter struct {
+	WorkflowName   string
+	DeadlineAfter  *time.Time
+	DeadlineBefore *time.Time
+	// Assignee keeps instances whose current step an escalation reassigned
+	// to that role or fixed user, or, if none did, whose current step is one
+	// of AssignedSteps (workflow name -> step IDs assigned to it).
+	Assignee      metadata.WorkflowAssignee
+	AssignedSteps map[string][]string
+	Limit         int
+	Offset        int
+}
+
+const workflowInstanceColumns = "id, workflow_id, workflow_name, entity, record_id, status, current_step, current_step_deadline, callback_token, attempts, max_attempts, last_error, next_retry_at, context, history, created_at, updated_at"
+
 // PgWorkflowStore implements WorkflowStore against Postgres _workflow_instances.
 type PgWorkflowStore struct{}
 
@@ -43,10 +84,11 @@ func (s *PgWorkflowStore) CreateInstance(ctx context.Context, q store.Querier, d
 		// SQLite: generate UUID in application code
 		id := store.GenerateUUID()
 		_, err = store.Exec(ctx, q,
-			fmt.Sprintf(`INSERT INTO _workflow_instances (id, workflow_id, workflow_name, status, current_step, context, history)
-			 VALUES (%s, %s, %s, 'running', %s, %s, %s)`,
+			fmt.Sprintf(`INSERT INTO _workflow_instances (id, workflow_id, workflow_name, entity, record_id, status, current_step, max_attempts, context, history)
+			 VALUES (%s, %s, %s, %s, %s, 'running', %s, %s, %s, %s)`,
 				pb.Add(id), pb.Add(data.WorkflowID), pb.Add(data.WorkflowName),
-				pb.Add(data.CurrentStep), pb.Add(string(ctxJSON)), pb.Add(string(historyJSON))),
+				pb.Add(nilIfEmpty(data.Entity)), pb.Add(nilIfEmpty(data.RecordID)), pb.Add(data.CurrentStep), pb.Add(data.MaxAttempts),
+				pb.Add(string(ctxJSON)), pb.Add(string(historyJSON))),
 			pb.Params()...)
 		if err != nil {
 			return "", fmt.Errorf("insert workflow instance: %w", err)
@@ -56,11 +98,12 @@ func (s *PgWorkflowStore) CreateInstance(ctx context.Context, q store.Querier, d
 
 	// PostgreSQL: use RETURNING id with gen_random_uuid() default
 	row, err := store.QueryRow(ctx, q,
-		fmt.Sprintf(`INSERT INTO _workflow_instances (workflow_id, workflow_name, status, current_step, context, history)
-		 VALUES (%s, %s, 'running', %s, %s, %s)
+		fmt.Sprintf(`INSERT INTO _workflow_instances (workflow_id, workflow_name, entity, record_id, status, current_step, max_attempts, context, history)
+		 VALUES (%s, %s, %s, %s, 'running', %s, %s, %s, %s)
 		 RETURNING id`,
 			pb.Add(data.WorkflowID), pb.Add(data.WorkflowName),
-			pb.Add(data.CurrentStep), pb.Add(ctxJSON), pb.Add(historyJSON)),
+			pb.Add(nilIfEmpty(data.Entity)), pb.Add(nilIfEmpty(data.RecordID)), pb.Add(data.CurrentStep), pb.Add(data.MaxAttempts),
+			pb.Add(ctxJSON), pb.Add(historyJSON)),
 		pb.Params()...)
 	if err != nil {
 		return "", fmt.Errorf("insert workflow instance: %w", err)
@@ -71,8 +114,8 @@ func (s *PgWorkflowStore) CreateInstance(ctx context.Context, q store.Querier, d
 
 func (s *PgWorkflowStore) LoadInstance(ctx context.Context, q store.Querier, dialect store.Dialect, id string) (*metadata.WorkflowInstance, error) {
 	row, err := store.QueryRow(ctx, q,
-		fmt.Sprintf(`SELECT id, workflow_id, workflow_name, status, current_step, current_step_deadline, context, history, created_at, updated_at
-		 FROM _workflow_instances WHERE id = %s`, dialect.Placeholder(1)), id)
+		fmt.Sprintf(`SELECT %s
+		 FROM _workflow_instances WHERE id = %s`, workflowInstanceColumns, dialect.Placeholder(1)), id)
 	if err != nil {
 		return nil, fmt.Errorf("workflow instance not found: %s", id)
 	}
@@ -93,19 +136,189 @@ func (s *PgWorkflowStore) PersistInstance(ctx context.Context, q store.Querier,
 	pb := dialect.NewParamBuilder()
 	_, err = store.Exec(ctx, q,
 		fmt.Sprintf(`UPDATE _workflow_instances
-		 SET status = %s, current_step = %s, current_step_deadline = %s, context = %s, history = %s, updated_at = %s
+		 SET status = %s, current_step = %s, current_step_deadline = %s, callback_token = %s, attempts = %s, last_error = %s,
+		     next_retry_at = NULL, context = %s, history = %s, updated_at = %s
 		 WHERE id = %s`,
 			pb.Add(instance.Status), pb.Add(nilIfEmpty(instance.CurrentStep)), pb.Add(instance.CurrentStepDeadline),
+			pb.Add(nilIfEmpty(instance.CallbackToken)), pb.Add(instance.Attempts), pb.Add(nilIfEmpty(instance.LastError)),
 			pb.Add(ctxJSON), pb.Add(historyJSON), dialect.NowExpr(), pb.Add(instance.ID)),
 		pb.Params()...)
 	return err
 }
 
-func (s *PgWorkflowStore) ListPending(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error) {
+// ListPending returns running instances waiting on a step, most urgent
+// deadline first (instances without one last), plus the total count.
+func (s *PgWorkflowStore) ListPending(ctx context.Context, q store.Querier, dialect store.Dialect, filter PendingInstanceFilter) ([]*metadata.WorkflowInstance, int, error) {
+	pb := dialect.NewParamBuilder()
+	conditions := []string{"status = 'running'", "current_step IS NOT NULL"}
+	if filter.WorkflowName != "" {
+		conditions = append(conditions, "workflow_name = "+pb.Add(filter.WorkflowName))
+	}
+	if filter.DeadlineAfter != nil {
+		conditions = append(conditions, "current_step_deadline >= "+pb.Add(deadlineParam(dialect, *filter.DeadlineAfter)))
+	}
+	if filter.DeadlineBefore != nil {
+		conditions = append(conditions, "current_step_deadline <= "+pb.Add(deadlineParam(dialect, *filter.DeadlineBefore)))
+	}
+	if filter.Assignee.Role != "" || filter.Assignee.User != "" {
+		conditions = append(conditions, pendingAssigneeCondition(dialect, pb, filter))
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	countRow, err := store.QueryRow(ctx, q, "SELECT COUNT(*) AS count FROM _workflow_instances"+where, pb.Params()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count pending workflow instances: %w", err)
+	}
+	total := toInt(countRow["count"])
+
+	dataSQL := fmt.Sprintf(`SELECT %s FROM _workflow_instances%s
+		 ORDER BY current_step_deadline IS NULL, current_step_deadline ASC, created_at ASC`, workflowInstanceColumns, where)
+	if filter.Limit > 0 {
+		dataSQL += fmt.Sprintf(" LIMIT %s OFFSET %s", pb.Add(filter.Limit), pb.Add(filter.Offset))
+	}
+	rows, err := store.QueryRows(ctx, q, dataSQL, pb.Params()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list pending workflow instances: %w", err)
+	}
+
+	var instances []*metadata.WorkflowInstance
+	for _, row := range rows {
+		inst, err := ParseWorkflowInstanceRow(row)
+		if err != nil {
+			log.Printf("WARN: skipping workflow instance: %v", err)
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	return instances, total, nil
+}
+
+// deadlineParam binds t for a comparison with current_step_deadline, which
+// SQLite keeps as the RFC 3339 text the step executors write.
+func deadlineParam(dialect store.Dialect, t time.Time) any {
+	if dialect.Name() == "sqlite" {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return t
+}
+
+// pendingAssigneeCondition matches filter.Assignee against the assignee an
+// escalation stored in the current step's context output, else against the
+// steps assigned to it in the workflow definitions (see currentAssignee).
+func pendingAssigneeCondition(dialect store.Dialect, pb store.ParamBuilder, filter PendingInstanceFilter) string {
+	escalated := func(key string) string {
+		if dialect.Name() == "sqlite" {
+			path := `'$.steps."' || current_step || '".assignee`
+			if key != "" {
+				path += "." + key
+			}
+			return fmt.Sprintf("json_extract(context, %s')", path)
+		}
+		if key == "" {
+			return "(context->'steps'->current_step->'assignee')"
+		}
+		return fmt.Sprintf("(context->'steps'->current_step->'assignee'->>'%s')", key)
+	}
+
+	var byEscalation []string
+	if filter.Assignee.Role != "" {
+		byEscalation = append(byEscalation, fmt.Sprintf("(%s = 'role' AND %s = %s)", escalated("type"), escalated("role"), pb.Add(filter.Assignee.Role)))
+	}
+	if filter.Assignee.User != "" {
+		byEscalation = append(byEscalation, fmt.Sprintf("(%s = 'fixed' AND %s = %s)", escalated("type"), escalated("user"), pb.Add(filter.Assignee.User)))
+	}
+
+	names := make([]string, 0, len(filter.AssignedSteps))
+	for name := range filter.AssignedSteps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var byDefinition []string
+	for _, name := range names {
+		steps := make([]any, len(filter.AssignedSteps[name]))
+		for i, id := range filter.AssignedSteps[name] {
+			steps[i] = id
+		}
+		byDefinition = append(byDefinition, fmt.Sprintf("(workflow_name = %s AND %s)", pb.Add(name), dialect.InExpr("current_step", pb, steps)))
+	}
+	definition := "1=0" // always false
+	if len(byDefinition) > 0 {
+		definition = strings.Join(byDefinition, " OR ")
+	}
+
+	return fmt.Sprintf("((%s IS NOT NULL AND (%s)) OR (%s IS NULL AND (%s)))",
+		escalated(""), strings.Join(byEscalation, " OR "), escalated(""), definition)
+}
+
+func (s *PgWorkflowStore) List(ctx context.Context, q store.Querier, dialect store.Dialect, filter WorkflowInstanceFilter) ([]*metadata.WorkflowInstance, int, error) {
+	pb := dialect.NewParamBuilder()
+	var conditions []string
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+pb.Add(filter.Status))
+	}
+	if filter.WorkflowName != "" {
+		conditions = append(conditions, "workflow_name = "+pb.Add(filter.WorkflowName))
+	}
+	if filter.Entity != "" {
+		conditions = append(conditions, "entity = "+pb.Add(filter.Entity))
+	}
+	if filter.RecordID != "" {
+		conditions = append(conditions, "record_id = "+pb.Add(filter.RecordID))
+	}
+	if filter.CreatedAfter != "" {
+		conditions = append(conditions, "created_at >= "+pb.Add(filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != "" {
+		conditions = append(conditions, "created_at <= "+pb.Add(filter.CreatedBefore))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	countRow, err := store.QueryRow(ctx, q, "SELECT COUNT(*) AS count FROM _workflow_instances"+where, pb.Params()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count workflow instances: %w", err)
+	}
+	total := toInt(countRow["count"])
+
+	offset := (filter.Page - 1) * filter.PerPage
+	dataSQL := fmt.Sprintf("SELECT %s FROM _workflow_instances%s ORDER BY created_at DESC LIMIT %s OFFSET %s",
+		workflowInstanceColumns, where, pb.Add(filter.PerPage), pb.Add(offset))
+	rows, err := store.QueryRows(ctx, q, dataSQL, pb.Params()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list workflow instances: %w", err)
+	}
+
+	var instances []*metadata.WorkflowInstance
+	for _, row := range rows {
+		inst, err := ParseWorkflowInstanceRow(row)
+		if err != nil {
+			log.Printf("WARN: skipping workflow instance: %v", err)
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	return instances, total, nil
+}
+
+func (s *PgWorkflowStore) FindTimedOut(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error) {
+	return s.findRunning(ctx, q, "AND current_step_deadline < "+dialect.NowExpr())
+}
+
+// FindWithDeadline returns running instances waiting on a step with a
+// deadline, due or not; the scheduler checks them for escalations.
+func (s *PgWorkflowStore) FindWithDeadline(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error) {
+	return s.findRunning(ctx, q, "")
+}
+
+func (s *PgWorkflowStore) findRunning(ctx context.Context, q store.Querier, cond string) ([]*metadata.WorkflowInstance, error) {
 	rows, err := store.QueryRows(ctx, q,
-		`SELECT id, workflow_id, workflow_name, status, current_step, current_step_deadline, context, history, created_at, updated_at
-		 FROM _workflow_instances WHERE status = 'running' AND current_step IS NOT NULL
-		 ORDER BY created_at DESC`)
+		fmt.Sprintf(`SELECT %s
+		 FROM _workflow_instances
+		 WHERE status = 'running'
+		   AND current_step_deadline IS NOT NULL %s`, workflowInstanceColumns, cond))
 	if err != nil {
 		return nil, err
 	}
@@ -122,13 +335,15 @@ func (s *PgWorkflowStore) ListPending(ctx context.Context, q store.Querier, dial
 	return instances, nil
 }
 
-func (s *PgWorkflowStore) FindTimedOut(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error) {
+// FindRetryDue returns failed instances whose scheduled retry is due.
+func (s *PgWorkflowStore) FindRetryDue(ctx context.Context, q store.Querier, dialect store.Dialect) ([]*metadata.WorkflowInstance, error) {
 	rows, err := store.QueryRows(ctx, q,
-		fmt.Sprintf(`SELECT id, workflow_id, workflow_name, status, current_step, current_step_deadline, context, history, created_at, updated_at
+		fmt.Sprintf(`SELECT %s
 		 FROM _workflow_instances
-		 WHERE status = 'running'
-		   AND current_step_deadline IS NOT NULL
-		   AND current_step_deadline < %s`, dialect.NowExpr()))
+		 WHERE status = 'failed' AND next_retry_at IS NOT NULL AND next_retry_at <= %s
+		 ORDER BY next_retry_at ASC
+		 LIMIT 50`, workflowInstanceColumns, dialect.Placeholder(1)),
+		dialect.TimeParam(time.Now()))
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +352,7 @@ func (s *PgWorkflowStore) FindTimedOut(ctx context.Context, q store.Querier, dia
 	for _, row := range rows {
 		inst, err := ParseWorkflowInstanceRow(row)
 		if err != nil {
-			log.Printf("WARN: skipping timed-out instance: %v", err)
+			log.Printf("WARN: skipping workflow instance: %v", err)
 			continue
 		}
 		instances = append(instances, inst)
@@ -145,6 +360,50 @@ func (s *PgWorkflowStore) FindTimedOut(ctx context.Context, q store.Querier, dia
 	return instances, nil
 }
 
+// ScheduleRetry marks a failed instance to be retried at the given time.
+// PersistInstance clears the schedule.
+func (s *PgWorkflowStore) ScheduleRetry(ctx context.Context, q store.Querier, dialect store.Dialect, id string, at time.Time) error {
+	pb := dialect.NewParamBuilder()
+	_, err := store.Exec(ctx, q,
+		fmt.Sprintf(`UPDATE _workflow_instances SET next_retry_at = %s WHERE id = %s`, pb.Add(dialect.TimeParam(at)), pb.Add(id)),
+		pb.Params()...)
+	if err != nil {
+		return fmt.Errorf("schedule workflow retry: %w", err)
+	}
+	return nil
+}
+
+// ClaimRetry takes a failed instance with a scheduled retry for this
+// scheduler: it marks it running and clears the schedule in one conditional
+// update, so of two schedulers that found the same instance only one gets
+// true.
+func (s *PgWorkflowStore) ClaimRetry(ctx context.Context, q store.Querier, dialect store.Dialect, id string) (bool, error) {
+	pb := dialect.NewParamBuilder()
+	n, err := store.Exec(ctx, q,
+		fmt.Sprintf(`UPDATE _workflow_instances SET status = 'running', next_retry_at = NULL
+		 WHERE id = %s AND status = 'failed' AND next_retry_at IS NOT NULL`, pb.Add(id)),
+		pb.Params()...)
+	if err != nil {
+		return false, fmt.Errorf("claim workflow retry: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ConsumeCallbackToken clears an instance's callback token if it still is
+// tokenHash, in one conditional update. Of two callbacks (or a callback and
+// the timeout scheduler) racing on the same token only one gets true.
+func (s *PgWorkflowStore) ConsumeCallbackToken(ctx context.Context, q store.Querier, dialect store.Dialect, id, tokenHash string) (bool, error) {
+	pb := dialect.NewParamBuilder()
+	n, err := store.Exec(ctx, q,
+		fmt.Sprintf(`UPDATE _workflow_instances SET callback_token = NULL WHERE id = %s AND callback_token = %s`,
+			pb.Add(id), pb.Add(tokenHash)),
+		pb.Params()...)
+	if err != nil {
+		return false, fmt.Errorf("consume workflow callback token: %w", err)
+	}
+	return n == 1, nil
+}
+
 func (s *PgWorkflowStore) DeleteInstance(ctx context.Context, q store.Querier, dialect store.Dialect, id string) error {
 	pb := dialect.NewParamBuilder()
 	_, err := store.Exec(ctx, q,
@@ -164,6 +423,12 @@ func ParseWorkflowInstanceRow(row map[string]any) (*metadata.WorkflowInstance, e
 		Status:       fmt.Sprintf("%v", row["status"]),
 	}
 
+	if e, ok := row["entity"]; ok && e != nil {
+		instance.Entity = fmt.Sprintf("%v", e)
+	}
+	if rid, ok := row["record_id"]; ok && rid != nil {
+		instance.RecordID = fmt.Sprintf("%v", rid)
+	}
 	if cs, ok := row["current_step"]; ok && cs != nil {
 		instance.CurrentStep = fmt.Sprintf("%v", cs)
 	}
@@ -171,6 +436,18 @@ func ParseWorkflowInstanceRow(row map[string]any) (*metadata.WorkflowInstance, e
 		s := fmt.Sprintf("%v", d)
 		instance.CurrentStepDeadline = &s
 	}
+	if ct, ok := row["callback_token"]; ok && ct != nil {
+		instance.CallbackToken = fmt.Sprintf("%v", ct)
+	}
+	instance.Attempts = toInt(row["attempts"])
+	instance.MaxAttempts = toInt(row["max_attempts"])
+	if le, ok := row["last_error"]; ok && le != nil {
+		instance.LastError = fmt.Sprintf("%v", le)
+	}
+	if nr, ok := row["next_retry_at"]; ok && nr != nil {
+		s := fmt.Sprintf("%v", nr)
+		instance.NextRetryAt = &s
+	}
 	if ca, ok := row["created_at"]; ok && ca != nil {
 		instance.CreatedAt = fmt.Sprintf("%v", ca)
 	}
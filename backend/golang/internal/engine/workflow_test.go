@@ -0,0 +1,558 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestAdvanceWorkflow_StepLimitStopsGotoLoop(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-1', 'spin', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+
+	wf := &metadata.Workflow{
+		ID:      "wf-1",
+		Name:    "spin",
+		Trigger: metadata.WorkflowTrigger{Type: "state_change", Entity: "order"},
+		Steps: []metadata.WorkflowStep{
+			{ID: "check", Type: "condition", Expression: "true", OnTrue: &metadata.StepGoto{Goto: "check"}},
+		},
+	}
+
+	e := NewDefaultWFEngine(s, metadata.NewRegistry())
+	e.SetMaxSteps(5)
+	if err := e.createInstance(ctx, wf, map[string]any{"id": "o1"}, "o1"); err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+
+	row, err := store.QueryRow(ctx, s.DB, "SELECT id FROM _workflow_instances WHERE workflow_id = 'wf-1'")
+	if err != nil {
+		t.Fatalf("find instance: %v", err)
+	}
+	inst, err := e.wfStore.LoadInstance(ctx, s.DB, s.Dialect, row["id"].(string))
+	if err != nil {
+		t.Fatalf("load instance: %v", err)
+	}
+	if inst.Status != "failed" {
+		t.Fatalf("expected status failed, got %q", inst.Status)
+	}
+	if len(inst.History) != 6 {
+		t.Fatalf("expected 5 executed steps plus the loop entry, got %d entries", len(inst.History))
+	}
+	last := inst.History[len(inst.History)-1]
+	if last.Status != "step_limit_exceeded" || last.Step != "check" || !strings.Contains(last.Error, "exceeded 5") {
+		t.Fatalf("unexpected loop history entry: %+v", last)
+	}
+}
+
+func TestWorkflow_ConditionBranchesOnPriorStepOutput(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"retry": true}`))
+	}))
+	defer srv.Close()
+
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-2', 'charge', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+	wf := &metadata.Workflow{
+		ID:      "wf-2",
+		Name:    "charge",
+		Trigger: metadata.WorkflowTrigger{Type: "state_change", Entity: "order"},
+		Steps: []metadata.WorkflowStep{
+			{ID: "call", Type: "action", Then: &metadata.StepGoto{Goto: "review"},
+				Actions: []metadata.WorkflowAction{{Type: "webhook", URL: srv.URL, ContinueOnError: true}}},
+			{ID: "review", Type: "approval", OnApprove: &metadata.StepGoto{Goto: "check"}},
+			{ID: "check", Type: "condition",
+				Expression: "context.steps.call.status_code == 503 && context.steps.call.body.retry == true",
+				OnTrue:     &metadata.StepGoto{Goto: "retry_later"}, OnFalse: &metadata.StepGoto{Goto: "end"}},
+			{ID: "retry_later", Type: "action", Actions: []metadata.WorkflowAction{{Type: "send_event", Event: "charge.retry"}}},
+		},
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWorkflows([]*metadata.Workflow{wf})
+
+	if err := NewDefaultWFEngine(s, reg).createInstance(ctx, wf, map[string]any{"id": "o1"}, "o1"); err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	row, err := store.QueryRow(ctx, s.DB, "SELECT id FROM _workflow_instances WHERE workflow_id = 'wf-2'")
+	if err != nil {
+		t.Fatalf("find instance: %v", err)
+	}
+
+	// A fresh engine resumes from the persisted context, as after a restart
+	inst, err := NewDefaultWFEngine(s, reg).ResolveAction(ctx, row["id"].(string), "approved", "u1")
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if inst.Status != "completed" {
+		t.Fatalf("expected completed, got %q", inst.Status)
+	}
+	var statuses []string
+	for _, h := range inst.History {
+		statuses = append(statuses, h.Step+":"+h.Status)
+	}
+	if got := strings.Join(statuses, ","); got != "call:completed,review:approved,check:on_true,retry_later:completed" {
+		t.Fatalf("unexpected history: %s", got)
+	}
+
+	steps := inst.Context["steps"].(map[string]any)
+	call := steps["call"].(map[string]any)
+	if call["ok"] != false || call["error"] == nil {
+		t.Fatalf("expected the failed call to be recorded, got %v", call)
+	}
+	if review := steps["review"].(map[string]any); review["by"] != "u1" {
+		t.Fatalf("expected the approval decision in context, got %v", review)
+	}
+}
+
+func TestWorkflow_CallbackStepResumesOrTimesOut(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	// The external system receives the token with the dispatch, by which time
+	// the instance must already be waiting on it
+	tokens := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		id, token := payload["instance_id"].(string), payload["token"].(string)
+		tokens[id] = token
+		row, err := store.QueryRow(ctx, s.DB, "SELECT callback_token FROM _workflow_instances WHERE id = ?1", id)
+		if err != nil || row["callback_token"] != hashCallbackToken(token) {
+			t.Errorf("expected the hashed token to be saved before dispatch, got %v (%v)", row["callback_token"], err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-3', 'ship', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+	wf := &metadata.Workflow{
+		ID:      "wf-3",
+		Name:    "ship",
+		Trigger: metadata.WorkflowTrigger{Type: "state_change", Entity: "order"},
+		Steps: []metadata.WorkflowStep{
+			{ID: "warehouse", Type: "callback", URL: srv.URL, Timeout: "1h",
+				OnSuccess: &metadata.StepGoto{Goto: "end"}, OnTimeout: &metadata.StepGoto{Goto: "escalate"}},
+			{ID: "escalate", Type: "action", Actions: []metadata.WorkflowAction{{Type: "send_event", Event: "ship.late"}}},
+		},
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWorkflows([]*metadata.Workflow{wf})
+	e := NewDefaultWFEngine(s, reg)
+
+	start := func(recordID string) string {
+		if err := e.createInstance(ctx, wf, map[string]any{"id": recordID}, recordID); err != nil {
+			t.Fatalf("create instance: %v", err)
+		}
+		row, err := store.QueryRow(ctx, s.DB, "SELECT id, callback_token FROM _workflow_instances WHERE record_id = ?1", recordID)
+		if err != nil {
+			t.Fatalf("find instance: %v", err)
+		}
+		id := row["id"].(string)
+		if row["callback_token"] == nil || row["callback_token"] == tokens[id] {
+			t.Fatalf("expected a hashed callback token to be persisted, got %v", row["callback_token"])
+		}
+		return id
+	}
+
+	h := NewWorkflowHandler(s, reg)
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	RegisterWorkflowRoutes(app, h, func(c *fiber.Ctx) error { return UnauthorizedError("no session") })
+	callback := func(id string, body map[string]any) (int, map[string]any) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/_workflows/"+id+"/callback", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("callback: %v", err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	acked := start("o1")
+	if status, _ := callback(acked, map[string]any{"token": "wrong"}); status != 403 {
+		t.Fatalf("expected a wrong token to be refused, got %d", status)
+	}
+	status, out := callback(acked, map[string]any{"token": tokens[acked], "data": map[string]any{"tracking": "T1"}})
+	if status != 200 {
+		t.Fatalf("expected the callback to resume the instance, got %d: %v", status, out)
+	}
+	inst := out["data"].(map[string]any)
+	if inst["status"] != "completed" {
+		t.Fatalf("expected completed, got %v", inst["status"])
+	}
+	output := inst["context"].(map[string]any)["steps"].(map[string]any)["warehouse"].(map[string]any)
+	if output["status"] != "success" || output["data"].(map[string]any)["tracking"] != "T1" {
+		t.Fatalf("unexpected callback output: %v", output)
+	}
+	if status, _ := callback(acked, map[string]any{"token": tokens[acked]}); status == 200 {
+		t.Fatal("expected the token to be single-use")
+	}
+
+	late := start("o2")
+	if _, err := store.Exec(ctx, s.DB,
+		"UPDATE _workflow_instances SET current_step_deadline = '2000-01-01 00:00:00' WHERE id = ?1", late); err != nil {
+		t.Fatalf("expire deadline: %v", err)
+	}
+	e.ProcessTimeouts(ctx)
+	timedOut, err := e.wfStore.LoadInstance(ctx, s.DB, s.Dialect, late)
+	if err != nil {
+		t.Fatalf("load instance: %v", err)
+	}
+	var statuses []string
+	for _, h := range timedOut.History {
+		statuses = append(statuses, h.Step+":"+h.Status)
+	}
+	if got := strings.Join(statuses, ","); timedOut.Status != "completed" || got != "warehouse:timed_out,escalate:completed" {
+		t.Fatalf("expected the timeout to route to on_timeout, got %s (%s)", got, timedOut.Status)
+	}
+	if timedOut.CallbackToken != "" {
+		t.Fatal("expected the timed-out token to be cleared")
+	}
+	if status, _ := callback(late, map[string]any{"token": tokens[late]}); status == 200 {
+		t.Fatal("expected a callback after the timeout to be refused")
+	}
+
+	// A callback that loaded the instance just before another one consumed
+	// the token doesn't resume the step a second time
+	raced := start("o3")
+	e.wfStore = &tokenRaceStore{WorkflowStore: e.wfStore}
+	if _, err := e.ResolveCallback(ctx, raced, tokens[raced], true, nil); !errors.Is(err, ErrInvalidCallbackToken) {
+		t.Fatalf("expected the consumed token to be refused, got %v", err)
+	}
+	if inst, _ := e.wfStore.LoadInstance(ctx, s.DB, s.Dialect, raced); inst.Status != "running" || len(inst.History) != 0 {
+		t.Fatalf("expected the instance left to the other callback, got %s %v", inst.Status, inst.History)
+	}
+}
+
+// tokenRaceStore consumes an instance's callback token right after the first
+// load, as a concurrent callback would.
+type tokenRaceStore struct {
+	WorkflowStore
+	once sync.Once
+}
+
+func (r *tokenRaceStore) LoadInstance(ctx context.Context, q store.Querier, dialect store.Dialect, id string) (*metadata.WorkflowInstance, error) {
+	inst, err := r.WorkflowStore.LoadInstance(ctx, q, dialect, id)
+	if err == nil {
+		r.once.Do(func() { _, _ = r.WorkflowStore.ConsumeCallbackToken(ctx, q, dialect, id, inst.CallbackToken) })
+	}
+	return inst, err
+}
+
+func TestWorkflow_ApprovalEscalatesNearDeadline(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	var notified []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		notified = append(notified, payload)
+	}))
+	defer srv.Close()
+
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-4', 'refund', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+	wf := &metadata.Workflow{
+		ID:      "wf-4",
+		Name:    "refund",
+		Trigger: metadata.WorkflowTrigger{Type: "state_change", Entity: "order"},
+		Steps: []metadata.WorkflowStep{
+			{ID: "review", Type: "approval", Timeout: "10h",
+				Assignee:  &metadata.WorkflowAssignee{Type: "role", Role: "manager"},
+				OnApprove: &metadata.StepGoto{Goto: "end"}, OnTimeout: &metadata.StepGoto{Goto: "end"},
+				Escalations: []metadata.WorkflowEscalation{
+					{At: 0.8, Notify: []metadata.WorkflowAction{{Type: "webhook", URL: srv.URL}}},
+					{At: 1, Reassign: &metadata.WorkflowAssignee{Type: "role", Role: "director"}},
+				}},
+		},
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWorkflows([]*metadata.Workflow{wf})
+	e := NewDefaultWFEngine(s, reg)
+	if err := e.createInstance(ctx, wf, map[string]any{"id": "o1"}, "o1"); err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	row, err := store.QueryRow(ctx, s.DB, "SELECT id FROM _workflow_instances WHERE workflow_id = 'wf-4'")
+	if err != nil {
+		t.Fatalf("find instance: %v", err)
+	}
+	id := row["id"].(string)
+	setDeadline := func(in time.Duration) {
+		t.Helper()
+		if _, err := store.Exec(ctx, s.DB, "UPDATE _workflow_instances SET current_step_deadline = ?1 WHERE id = ?2",
+			time.Now().Add(in).UTC().Format(time.RFC3339), id); err != nil {
+			t.Fatalf("set deadline: %v", err)
+		}
+	}
+	history := func() (string, *metadata.WorkflowInstance) {
+		t.Helper()
+		inst, err := e.wfStore.LoadInstance(ctx, s.DB, s.Dialect, id)
+		if err != nil {
+			t.Fatalf("load instance: %v", err)
+		}
+		var statuses []string
+		for _, h := range inst.History {
+			statuses = append(statuses, h.Step+":"+h.Status)
+		}
+		return strings.Join(statuses, ","), inst
+	}
+
+	// Half way through nothing happens
+	setDeadline(5 * time.Hour)
+	e.ProcessEscalations(ctx)
+	if got, _ := history(); len(notified) != 0 || got != "" {
+		t.Fatalf("expected no escalation at 50%%, got %d notifications and history %q", len(notified), got)
+	}
+
+	// 90% of the timeout has elapsed: the fallback is notified once
+	setDeadline(time.Hour)
+	e.ProcessEscalations(ctx)
+	e.ProcessEscalations(ctx)
+	if len(notified) != 1 {
+		t.Fatalf("expected one escalation notification, got %d", len(notified))
+	}
+	review := notified[0]["steps"].(map[string]any)["review"].(map[string]any)
+	if review["status"] != "waiting" || toInt(review["escalations"]) != 1 {
+		t.Fatalf("expected the escalation in the notified context, got %v", review)
+	}
+	if got, inst := history(); got != "review:escalated" || inst.Status != "running" {
+		t.Fatalf("expected the escalation in history, got %q (%s)", got, inst.Status)
+	}
+
+	// At the deadline the step is reassigned and restarted instead of timing out
+	setDeadline(-time.Minute)
+	e.ProcessEscalations(ctx)
+	e.ProcessTimeouts(ctx)
+	got, inst := history()
+	if got != "review:escalated,review:reassigned" || inst.Status != "running" {
+		t.Fatalf("expected a reassignment instead of a timeout, got %q (%s)", got, inst.Status)
+	}
+	deadline, ok := parseDeadline(*inst.CurrentStepDeadline)
+	if !ok || time.Until(deadline) < 9*time.Hour {
+		t.Fatalf("expected the new assignee to get a full timeout, got %v", *inst.CurrentStepDeadline)
+	}
+	assignee := inst.Context["steps"].(map[string]any)["review"].(map[string]any)["assignee"].(map[string]any)
+	if assignee["role"] != "director" {
+		t.Fatalf("expected the step to be reassigned to director, got %v", assignee)
+	}
+}
+
+func TestWorkflow_TransientFailureIsRetried(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	prevMax, prevBackoff := WorkflowRetryMaxAttempts, WorkflowRetryBackoff
+	WorkflowRetryMaxAttempts, WorkflowRetryBackoff = 2, 0
+	t.Cleanup(func() { WorkflowRetryMaxAttempts, WorkflowRetryBackoff = prevMax, prevBackoff })
+
+	// The target is down for the first call, then recovers; /reject always
+	// refuses and /first always succeeds
+	var calls, firstCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/first" {
+			firstCalls.Add(1)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.URL.Path == "/reject" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-5', 'notify', '{"type":"state_change","entity":"order"}'),
+		 ('wf-6', 'reject', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflows: %v", err)
+	}
+	notify := &metadata.Workflow{
+		ID: "wf-5", Name: "notify",
+		Trigger: metadata.WorkflowTrigger{Type: "state_change", Entity: "order"},
+		Steps: []metadata.WorkflowStep{
+			{ID: "call", Type: "action", Actions: []metadata.WorkflowAction{
+				{Type: "webhook", URL: srv.URL + "/first"},
+				{Type: "webhook", URL: srv.URL},
+			}},
+		},
+	}
+	reject := &metadata.Workflow{
+		ID: "wf-6", Name: "reject",
+		Trigger: metadata.WorkflowTrigger{Type: "state_change", Entity: "order"},
+		Steps: []metadata.WorkflowStep{
+			{ID: "call", Type: "action", Actions: []metadata.WorkflowAction{{Type: "webhook", URL: srv.URL + "/reject"}}},
+		},
+	}
+	reg := metadata.NewRegistry()
+	reg.LoadWorkflows([]*metadata.Workflow{notify, reject})
+	e := NewDefaultWFEngine(s, reg)
+
+	load := func(workflowID string) *metadata.WorkflowInstance {
+		t.Helper()
+		row, err := store.QueryRow(ctx, s.DB, "SELECT id FROM _workflow_instances WHERE workflow_id = ?1", workflowID)
+		if err != nil {
+			t.Fatalf("find instance: %v", err)
+		}
+		inst, err := e.wfStore.LoadInstance(ctx, s.DB, s.Dialect, row["id"].(string))
+		if err != nil {
+			t.Fatalf("load instance: %v", err)
+		}
+		return inst
+	}
+
+	for _, wf := range []*metadata.Workflow{notify, reject} {
+		if err := e.createInstance(ctx, wf, map[string]any{"id": "o1"}, "o1"); err != nil {
+			t.Fatalf("create %s instance: %v", wf.Name, err)
+		}
+	}
+	inst := load("wf-5")
+	if inst.Status != "failed" || inst.NextRetryAt == nil || inst.MaxAttempts != 2 || !strings.Contains(inst.LastError, "HTTP 503") {
+		t.Fatalf("expected a failed instance scheduled for retry, got %+v", inst)
+	}
+	if rejected := load("wf-6"); rejected.Status != "failed" || rejected.NextRetryAt != nil {
+		t.Fatalf("expected a 400 not to be retried, got %+v", rejected)
+	}
+
+	retried, err := e.ProcessRetries(ctx)
+	if err != nil || retried != 1 {
+		t.Fatalf("expected one instance retried, got %d, %v", retried, err)
+	}
+	inst = load("wf-5")
+	if inst.Status != "completed" || inst.Attempts != 1 || inst.NextRetryAt != nil {
+		t.Fatalf("expected the retry to complete the instance, got %+v", inst)
+	}
+	var statuses []string
+	for _, h := range inst.History {
+		statuses = append(statuses, h.Step+":"+h.Status)
+	}
+	if got := strings.Join(statuses, ","); got != "call:failed,call:retried,call:completed" {
+		t.Fatalf("unexpected history: %s", got)
+	}
+	// The retry resumed at the failed action
+	if n := firstCalls.Load(); n != 1 {
+		t.Fatalf("expected the action that succeeded to run once, ran %d times", n)
+	}
+	output := inst.Context["steps"].(map[string]any)["call"].(map[string]any)
+	if actions := output["actions"].([]any); len(actions) != 2 || output["status"] != "completed" {
+		t.Fatalf("expected both actions in the step output, got %v", output)
+	}
+	if retried, _ := e.ProcessRetries(ctx); retried != 0 {
+		t.Fatalf("expected nothing left to retry, got %d", retried)
+	}
+}
+
+func TestWorkflow_RetryClaimAndDelay(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+	wfStore := &PgWorkflowStore{}
+	if _, err := s.DB.ExecContext(ctx,
+		`INSERT INTO _workflows (id, name, trigger) VALUES ('wf-1', 'notify', '{"type":"state_change","entity":"order"}')`); err != nil {
+		t.Fatalf("insert workflow: %v", err)
+	}
+
+	id, err := wfStore.CreateInstance(ctx, s.DB, s.Dialect, WorkflowInstanceData{
+		WorkflowID: "wf-1", WorkflowName: "notify", CurrentStep: "call", MaxAttempts: 3,
+	})
+	if err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	if _, err := s.DB.ExecContext(ctx, "UPDATE _workflow_instances SET status = 'failed' WHERE id = ?1", id); err != nil {
+		t.Fatalf("fail instance: %v", err)
+	}
+	if err := wfStore.ScheduleRetry(ctx, s.DB, s.Dialect, id, time.Now()); err != nil {
+		t.Fatalf("schedule retry: %v", err)
+	}
+
+	// Two schedulers found the same instance; only the first claim wins
+	if claimed, err := wfStore.ClaimRetry(ctx, s.DB, s.Dialect, id); err != nil || !claimed {
+		t.Fatalf("expected the first claim to win, got %v, %v", claimed, err)
+	}
+	if claimed, err := wfStore.ClaimRetry(ctx, s.DB, s.Dialect, id); err != nil || claimed {
+		t.Fatalf("expected the second claim to lose, got %v, %v", claimed, err)
+	}
+
+	prev := WorkflowRetryBackoff
+	WorkflowRetryBackoff = 30 * time.Second
+	t.Cleanup(func() { WorkflowRetryBackoff = prev })
+	if d := workflowRetryDelay(2); d != 2*time.Minute {
+		t.Fatalf("expected the backoff doubled twice, got %s", d)
+	}
+	// Shifting by the attempt count would overflow long before this
+	if d := workflowRetryDelay(100); d != maxWorkflowRetryDelay {
+		t.Fatalf("expected the delay capped at %s, got %s", maxWorkflowRetryDelay, d)
+	}
+}
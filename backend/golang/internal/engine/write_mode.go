@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/metadata"
+)
+
+// OverrideWriteModeHeader lets an admin write to a readonly or append_only
+// entity through the API. Without it admins are blocked like everyone else.
+const OverrideWriteModeHeader = "X-Rocket-Override-Write-Mode"
+
+// checkWriteMode returns a 405 when the entity's mode forbids action. An
+// admin sending OverrideWriteModeHeader: true is let through; anyone else
+// sending it gets a 403.
+func checkWriteMode(c *fiber.Ctx, user *metadata.UserContext, entity *metadata.Entity, action string) error {
+	if !entity.WriteBlocked(action) {
+		return nil
+	}
+	if c.Get(OverrideWriteModeHeader) == "true" {
+		if user != nil && user.IsAdmin() {
+			return nil
+		}
+		return ForbiddenError("Only admins can override an entity's write mode")
+	}
+	mode := "readonly"
+	if !entity.ReadOnly {
+		mode = "append-only"
+	}
+	return NewAppError("METHOD_NOT_ALLOWED", 405, fmt.Sprintf("%s is %s: %s is not allowed", entity.Name, mode, action))
+}
+
+// checkChildWriteModes applies checkWriteMode to nested one-to-many writes.
+// An append-only child accepts only the append write mode, since diff and
+// replace can update or delete existing rows.
+func checkChildWriteModes(c *fiber.Ctx, user *metadata.UserContext, reg *metadata.Registry, ops []*RelationWrite) error {
+	for _, rw := range ops {
+		if rw.Relation.IsManyToMany() {
+			continue
+		}
+		target := reg.GetEntity(rw.Relation.Target)
+		if target == nil {
+			continue
+		}
+		action := "create"
+		if rw.WriteMode != "append" {
+			action = "update"
+		}
+		if err := checkWriteMode(c, user, target, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCascadeWriteModes blocks a delete whose on_delete cascade would delete
+// or null out rows of a readonly or append_only entity.
+func checkCascadeWriteModes(c *fiber.Ctx, user *metadata.UserContext, reg *metadata.Registry, entity *metadata.Entity) error {
+	for _, rel := range reg.GetRelationsForSource(entity.Name) {
+		if rel.IsManyToMany() {
+			continue
+		}
+		var action string
+		switch rel.OnDelete {
+		case "cascade":
+			action = "delete"
+		case "set_null":
+			action = "update"
+		default:
+			continue
+		}
+		target := reg.GetEntity(rel.Target)
+		if target == nil {
+			continue
+		}
+		if err := checkWriteMode(c, user, target, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+	"rocket-backend/internal/store"
+)
+
+func TestAppendOnlyEntity_BlocksUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	ledger := &metadata.Entity{
+		Name:       "ledger_entry",
+		Table:      "ledger_entries",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		AppendOnly: true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "amount", Type: "int"},
+		},
+	}
+	audit := &metadata.Entity{
+		Name:       "audit_entry",
+		Table:      "audit_entries",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "uuid", Generated: true},
+		ReadOnly:   true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "uuid"},
+			{Name: "note", Type: "string"},
+		},
+	}
+	for _, e := range []*metadata.Entity{ledger, audit} {
+		if err := store.NewMigrator(s).Migrate(ctx, e); err != nil {
+			t.Fatalf("migrate %s: %v", e.Name, err)
+		}
+	}
+	reg := metadata.NewRegistry()
+	reg.Load([]*metadata.Entity{ledger, audit}, nil)
+	var perms []*metadata.Permission
+	for _, action := range []string{"read", "create", "update", "delete"} {
+		perms = append(perms, &metadata.Permission{Entity: "ledger_entry", Action: action, Roles: []string{"clerk"}})
+	}
+	reg.LoadPermissions(perms)
+	h := NewHandler(s, reg)
+
+	app := fiber.New(fiber.Config{ErrorHandler: testErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &metadata.UserContext{ID: "u1", Roles: []string{c.Get("X-Test-Role")}})
+		return c.Next()
+	})
+	app.Post("/api/:entity", h.Create)
+	app.Put("/api/:entity/:id", h.Update)
+	app.Delete("/api/:entity/:id", h.Delete)
+
+	do := func(method, path, role string, override bool, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Role", role)
+		if override {
+			req.Header.Set(OverrideWriteModeHeader, "true")
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return resp.StatusCode, out
+	}
+
+	status, out := do("POST", "/api/ledger_entry", "clerk", false, map[string]any{"amount": 100})
+	if status != 201 {
+		t.Fatalf("expected create to work on an append-only entity, got %d %v", status, out)
+	}
+	id := out["data"].(map[string]any)["id"].(string)
+
+	for _, role := range []string{"clerk", "admin"} {
+		if status, out := do("PUT", "/api/ledger_entry/"+id, role, false, map[string]any{"amount": 200}); status != 405 {
+			t.Fatalf("%s: expected update to be blocked with 405, got %d %v", role, status, out)
+		}
+		if status, out := do("DELETE", "/api/ledger_entry/"+id, role, false, nil); status != 405 {
+			t.Fatalf("%s: expected delete to be blocked with 405, got %d %v", role, status, out)
+		}
+	}
+	if status, out := do("POST", "/api/audit_entry", "admin", false, map[string]any{"note": "x"}); status != 405 {
+		t.Fatalf("expected create to be blocked on a readonly entity, got %d %v", status, out)
+	}
+
+	// The override is explicit and admin-only
+	if status, out := do("PUT", "/api/ledger_entry/"+id, "clerk", true, map[string]any{"amount": 200}); status != 403 {
+		t.Fatalf("expected a non-admin override to be forbidden, got %d %v", status, out)
+	}
+	if status, out := do("PUT", "/api/ledger_entry/"+id, "admin", true, map[string]any{"amount": 200}); status != 200 {
+		t.Fatalf("expected an admin override to be allowed, got %d %v", status, out)
+	}
+	row, err := store.QueryRow(ctx, s.DB, "SELECT amount FROM ledger_entries WHERE id = ?1", id)
+	if err != nil || toInt(row["amount"]) != 200 {
+		t.Fatalf("expected the overridden update to be written, got %v (%v)", row, err)
+	}
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -15,8 +16,43 @@ type RelationWrite struct {
 	Data      []map[string]any
 }
 
-// BuildInsertSQL builds a parameterized INSERT statement.
-// Returns SQL, params, and the PK field name (for RETURNING).
+// generatedID returns the value to insert for entity's generated primary key
+// under its id_strategy, or "" when the database assigns it.
+func generatedID(entity *metadata.Entity, dialect store.Dialect) string {
+	switch entity.IDStrategy {
+	case "uuidv7":
+		return store.GenerateUUIDv7()
+	case "nanoid":
+		return store.GenerateNanoID()
+	case "sequence":
+		return ""
+	}
+	// For SQLite: generate UUID PK in Go since there's no gen_random_uuid()
+	if dialect.UUIDDefault() == "" {
+		return store.GenerateUUID()
+	}
+	return ""
+}
+
+// stampAttribution sets the entity's created_by/updated_by fields to the acting
+// user's id, or null for system and anonymous writes, replacing any value the
+// client, hooks or rules put there. Inserts store both, updates only
+// updated_by (see BuildUpdateSQL).
+func stampAttribution(entity *metadata.Entity, fields map[string]any, user *metadata.UserContext) {
+	var by any
+	if user != nil && user.ID != "" {
+		by = user.ID
+	}
+	for _, f := range entity.Fields {
+		if f.IsAutoUser() {
+			fields[f.Name] = by
+		}
+	}
+}
+
+// BuildInsertSQL builds a parameterized INSERT statement. It returns the
+// whole stored row, so DB defaults, generated keys and timestamps come back
+// without a second read.
 func BuildInsertSQL(entity *metadata.Entity, fields map[string]any, dialect store.Dialect) (string, []any) {
 	pb := dialect.NewParamBuilder()
 	var cols []string
@@ -24,18 +60,17 @@ func BuildInsertSQL(entity *metadata.Entity, fields map[string]any, dialect stor
 
 	for _, f := range entity.Fields {
 		if f.Name == entity.PrimaryKey.Field && entity.PrimaryKey.Generated {
-			// For SQLite: generate UUID PK in Go since there's no gen_random_uuid()
-			if dialect.UUIDDefault() == "" {
+			if id := generatedID(entity, dialect); id != "" {
 				cols = append(cols, f.Name)
-				vals = append(vals, pb.Add(store.GenerateUUID()))
+				vals = append(vals, pb.Add(id))
 			}
-			continue // PK generated by DB (PostgreSQL)
+			continue // PK assigned by the DB default or identity
 		}
 		if f.Auto == "create" || f.Auto == "update" {
 			// Auto-timestamp fields handled below
 			continue
 		}
-		if f.Name == "deleted_at" {
+		if f.IsRollup() || f.Name == "deleted_at" {
 			continue
 		}
 
@@ -50,7 +85,7 @@ func BuildInsertSQL(entity *metadata.Entity, fields map[string]any, dialect stor
 			}
 		}
 		cols = append(cols, f.Name)
-		vals = append(vals, pb.Add(val))
+		vals = append(vals, pb.Add(columnValue(f, val, dialect)))
 	}
 
 	// Add auto-timestamp fields
@@ -65,13 +100,14 @@ func BuildInsertSQL(entity *metadata.Entity, fields map[string]any, dialect stor
 		entity.Table,
 		strings.Join(cols, ", "),
 		strings.Join(vals, ", "),
-		entity.PrimaryKey.Field,
+		joinColumns(recordColumns(entity)),
 	)
 
 	return sql, pb.Params()
 }
 
-// BuildUpdateSQL builds a parameterized UPDATE statement.
+// BuildUpdateSQL builds a parameterized UPDATE statement that returns the
+// updated row. Returns "" when there is nothing to set.
 func BuildUpdateSQL(entity *metadata.Entity, id any, fields map[string]any, dialect store.Dialect) (string, []any) {
 	pb := dialect.NewParamBuilder()
 	var sets []string
@@ -81,13 +117,18 @@ func BuildUpdateSQL(entity *metadata.Entity, id any, fields map[string]any, dial
 		if !ok {
 			continue
 		}
-		sets = append(sets, fmt.Sprintf("%s = %s", f.Name, pb.Add(val)))
+		sets = append(sets, fmt.Sprintf("%s = %s", f.Name, pb.Add(columnValue(f, val, dialect))))
 	}
 
-	// Auto-update timestamp
+	// Auto-update timestamp, and the updating user as stamped by stampAttribution
 	for _, f := range entity.Fields {
-		if f.Auto == "update" {
+		switch f.Auto {
+		case "update":
 			sets = append(sets, fmt.Sprintf("%s = %s", f.Name, dialect.NowExpr()))
+		case "update_user":
+			if val, ok := fields[f.Name]; ok {
+				sets = append(sets, fmt.Sprintf("%s = %s", f.Name, pb.Add(val)))
+			}
 		}
 	}
 
@@ -100,15 +141,38 @@ func BuildUpdateSQL(entity *metadata.Entity, id any, fields map[string]any, dial
 		where += " AND deleted_at IS NULL"
 	}
 
-	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING %s",
 		entity.Table,
 		strings.Join(sets, ", "),
 		where,
+		joinColumns(recordColumns(entity)),
 	)
 
 	return sql, pb.Params()
 }
 
+// columnValue encodes structured values for json/file/geopoint columns as JSON text so
+// they bind on every driver (SQLite has no native map/slice support), and
+// array values through the dialect's array encoding.
+func columnValue(f metadata.Field, val any, dialect store.Dialect) any {
+	if f.Type == "array" {
+		if items, err := encodeArray(&f, val); err == nil && items != nil {
+			return dialect.ArrayParam(items)
+		}
+		return val
+	}
+	if f.Type != "json" && f.Type != "file" && f.Type != "geopoint" {
+		return val
+	}
+	switch val.(type) {
+	case map[string]any, []any:
+		if b, err := json.Marshal(val); err == nil {
+			return string(b)
+		}
+	}
+	return val
+}
+
 // BuildSoftDeleteSQL builds a soft-delete UPDATE statement.
 func BuildSoftDeleteSQL(entity *metadata.Entity, id any, dialect store.Dialect) (string, []any) {
 	pb := dialect.NewParamBuilder()
@@ -118,6 +182,15 @@ func BuildSoftDeleteSQL(entity *metadata.Entity, id any, dialect store.Dialect)
 	return sql, pb.Params()
 }
 
+// BuildRestoreSQL builds an UPDATE statement that undoes a soft delete.
+func BuildRestoreSQL(entity *metadata.Entity, id any, dialect store.Dialect) (string, []any) {
+	pb := dialect.NewParamBuilder()
+	idPlaceholder := pb.Add(id)
+	sql := fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE %s = %s AND deleted_at IS NOT NULL",
+		entity.Table, entity.PrimaryKey.Field, idPlaceholder)
+	return sql, pb.Params()
+}
+
 // BuildHardDeleteSQL builds a DELETE statement.
 func BuildHardDeleteSQL(entity *metadata.Entity, id any, dialect store.Dialect) (string, []any) {
 	pb := dialect.NewParamBuilder()
@@ -127,6 +200,47 @@ func BuildHardDeleteSQL(entity *metadata.Entity, id any, dialect store.Dialect)
 	return sql, pb.Params()
 }
 
+// EmptyStrings is how writes treat "" and null (writes.empty_strings): "keep"
+// stores them as sent, "null" turns "" into null, and "empty" turns null into
+// "" on string and text fields. Fields can override it with empty_strings.
+var EmptyStrings = "keep"
+
+// coerceEmpty applies the field's empty_strings mode to val.
+func coerceEmpty(f *metadata.Field, val any) any {
+	mode := f.EmptyStrings
+	if mode == "" {
+		mode = EmptyStrings
+	}
+	switch {
+	case mode == "null" && val == "":
+		return nil
+	case mode == "empty" && val == nil && (f.Type == "string" || f.Type == "text"):
+		return ""
+	}
+	return val
+}
+
+// ApplyFieldTransforms normalizes incoming values in place using each field's
+// transform directives and empty_strings mode, and fills defaults declared by
+// json field schemas. Safe to call more than once on the same map.
+func ApplyFieldTransforms(entity *metadata.Entity, fields map[string]any) {
+	for _, f := range entity.Fields {
+		val, ok := fields[f.Name]
+		if !ok {
+			continue
+		}
+		if len(f.Transform) > 0 {
+			val = f.ApplyTransforms(val)
+		}
+		// After transforms, so a value trimmed down to "" counts as empty
+		val = coerceEmpty(&f, val)
+		fields[f.Name] = val
+		if f.Schema != nil {
+			f.Schema.ApplyDefaults(val)
+		}
+	}
+}
+
 // ValidateFields validates incoming fields against entity metadata.
 func ValidateFields(entity *metadata.Entity, fields map[string]any, isCreate bool) []ErrorDetail {
 	var errs []ErrorDetail
@@ -168,9 +282,53 @@ func ValidateFields(entity *metadata.Entity, fields map[string]any, isCreate boo
 		}
 	}
 
+	// Check array element types (and per-element enums)
+	for i := range entity.Fields {
+		f := &entity.Fields[i]
+		if f.Type != "array" {
+			continue
+		}
+		if val, ok := fields[f.Name]; ok {
+			if detail := validateArrayField(f, val); detail != nil {
+				errs = append(errs, *detail)
+			}
+		}
+	}
+
+	// Check geopoint coordinates
+	for i := range entity.Fields {
+		f := &entity.Fields[i]
+		if f.Type != "geopoint" {
+			continue
+		}
+		if val, ok := fields[f.Name]; ok {
+			if detail := validateGeopointField(f, val); detail != nil {
+				errs = append(errs, *detail)
+			}
+		}
+	}
+
+	// Check json values against their field's schema
+	for _, f := range entity.Fields {
+		if f.Schema == nil {
+			continue
+		}
+		val, ok := fields[f.Name]
+		if !ok || val == nil {
+			continue
+		}
+		for _, v := range f.Schema.Validate(val) {
+			errs = append(errs, ErrorDetail{
+				Field:   f.Name + strings.TrimPrefix(v.Path, "$"),
+				Rule:    v.Rule,
+				Message: f.Name + strings.TrimPrefix(v.Message, "$"),
+			})
+		}
+	}
+
 	// Check enum constraints
 	for _, f := range entity.Fields {
-		if len(f.Enum) == 0 {
+		if len(f.Enum) == 0 || f.Type == "array" {
 			continue
 		}
 		val, ok := fields[f.Name]
@@ -2,26 +2,71 @@ package instrument
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"rocket-backend/internal/store"
 )
 
+// defaultEventsMaxPerPage caps per_page on GET /_events unless overridden.
+const defaultEventsMaxPerPage = 100
+
 // EventHandler exposes REST endpoints for querying and emitting events.
 type EventHandler struct {
-	db      *sql.DB
-	dialect store.Dialect
+	db         *sql.DB
+	dialect    store.Dialect
+	maxPerPage int
 }
 
 // NewEventHandler creates an EventHandler backed by the given db and dialect.
 func NewEventHandler(db *sql.DB, dialect store.Dialect) *EventHandler {
-	return &EventHandler{db: db, dialect: dialect}
+	return &EventHandler{db: db, dialect: dialect, maxPerPage: defaultEventsMaxPerPage}
+}
+
+// SetMaxPerPage overrides the largest page GET /_events will return.
+func (h *EventHandler) SetMaxPerPage(n int) {
+	h.maxPerPage = n
+}
+
+// eventCursor is the keyset position of the last event on a page.
+type eventCursor struct {
+	CreatedAt string `json:"t"`
+	ID        string `json:"id"`
+}
+
+func encodeEventCursor(row map[string]any) string {
+	cur := eventCursor{ID: fmt.Sprintf("%v", row["id"])}
+	switch v := row["created_at"].(type) {
+	case time.Time:
+		cur.CreatedAt = v.UTC().Format(time.RFC3339Nano)
+	default:
+		cur.CreatedAt = fmt.Sprintf("%v", v)
+	}
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeEventCursor(s string) (*eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur eventCursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return nil, err
+	}
+	if cur.CreatedAt == "" || cur.ID == "" {
+		return nil, fmt.Errorf("incomplete cursor")
+	}
+	return &cur, nil
 }
 
 // Emit handles POST /_events — emit a custom business event (any authenticated user).
@@ -47,6 +92,8 @@ func (h *EventHandler) Emit(c *fiber.Ctx) error {
 }
 
 // List handles GET /_events — list events with filters (admin only).
+// Pages by offset (page/per_page) or, for high-volume tables, by keyset: pass the
+// returned next_cursor as ?after= to continue without OFFSET scans or a COUNT.
 func (h *EventHandler) List(c *fiber.Ctx) error {
 	ctx := c.UserContext()
 
@@ -105,25 +152,31 @@ func (h *EventHandler) List(c *fiber.Ctx) error {
 		argIdx++
 	}
 
-	// Pagination
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	if page < 1 {
-		page = 1
+	// Sort (id breaks ties between events created in the same instant)
+	sortParam := c.Query("sort", "-created_at")
+	orderBy := "created_at DESC, id DESC"
+	keysetOp := "<"
+	if sortParam == "created_at" {
+		orderBy = "created_at ASC, id ASC"
+		keysetOp = ">"
 	}
+
+	// Pagination
 	perPage, _ := strconv.Atoi(c.Query("per_page", "50"))
 	if perPage < 1 {
 		perPage = 50
 	}
-	if perPage > 100 {
-		perPage = 100
+	if perPage > h.maxPerPage {
+		perPage = h.maxPerPage
 	}
-	offset := (page - 1) * perPage
 
-	// Sort
-	sortParam := c.Query("sort", "-created_at")
-	orderBy := "created_at DESC"
-	if sortParam == "created_at" {
-		orderBy = "created_at ASC"
+	var cursor *eventCursor
+	if v := c.Query("after"); v != "" {
+		cur, err := decodeEventCursor(v)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": fiber.Map{"code": "VALIDATION_FAILED", "message": "invalid after cursor"}})
+		}
+		cursor = cur
 	}
 
 	whereClause := ""
@@ -131,20 +184,40 @@ func (h *EventHandler) List(c *fiber.Ctx) error {
 		whereClause = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	// Count query
-	countSQL := "SELECT COUNT(*) as count FROM _events" + whereClause
-	countRow, err := store.QueryRow(ctx, h.db, countSQL, args...)
-	if err != nil {
-		return fmt.Errorf("count events: %w", err)
+	pagination := fiber.Map{"per_page": perPage}
+	page := 1
+	if cursor == nil {
+		page, _ = strconv.Atoi(c.Query("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+
+		countSQL := "SELECT COUNT(*) as count FROM _events" + whereClause
+		countRow, err := store.QueryRow(ctx, h.db, countSQL, args...)
+		if err != nil {
+			return fmt.Errorf("count events: %w", err)
+		}
+		pagination["page"] = page
+		pagination["total"] = toInt(countRow["count"])
+	} else {
+		keyset := fmt.Sprintf("(created_at %s %s OR (created_at = %s AND id %s %s))",
+			keysetOp, h.dialect.Placeholder(argIdx), h.dialect.Placeholder(argIdx+1), keysetOp, h.dialect.Placeholder(argIdx+2))
+		args = append(args, cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		argIdx += 3
+		if whereClause == "" {
+			whereClause = " WHERE " + keyset
+		} else {
+			whereClause += " AND " + keyset
+		}
 	}
-	total := toInt(countRow["count"])
+	offset := (page - 1) * perPage
 
-	// Data query
+	// Fetch one extra row to know whether another page follows
 	dataSQL := fmt.Sprintf(
 		"SELECT id, trace_id, span_id, parent_span_id, event_type, source, component, action, entity, record_id, user_id, duration_ms, status, metadata, created_at FROM _events%s ORDER BY %s LIMIT %s OFFSET %s",
 		whereClause, orderBy, h.dialect.Placeholder(argIdx), h.dialect.Placeholder(argIdx+1),
 	)
-	dataArgs := append(args, perPage, offset)
+	dataArgs := append(args, perPage+1, offset)
 	rows, err := store.QueryRows(ctx, h.db, dataSQL, dataArgs...)
 	if err != nil {
 		return fmt.Errorf("list events: %w", err)
@@ -153,13 +226,16 @@ func (h *EventHandler) List(c *fiber.Ctx) error {
 		rows = []map[string]any{}
 	}
 
+	hasMore := len(rows) > perPage
+	if hasMore {
+		rows = rows[:perPage]
+		pagination["next_cursor"] = encodeEventCursor(rows[len(rows)-1])
+	}
+	pagination["has_more"] = hasMore
+
 	return c.JSON(fiber.Map{
-		"data": rows,
-		"pagination": fiber.Map{
-			"page":     page,
-			"per_page": perPage,
-			"total":    total,
-		},
+		"data":       rows,
+		"pagination": pagination,
 	})
 }
 
@@ -0,0 +1,109 @@
+package instrument
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/store"
+)
+
+func TestEventList_KeysetPagesAreStableAndDisjoint(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	// Several events share a timestamp so ordering relies on the id tie-breaker
+	inRange := map[string]bool{}
+	for i := 0; i < 12; i++ {
+		id := store.GenerateUUID()
+		createdAt := fmt.Sprintf("2025-01-0%d 10:00:00", 1+i/4)
+		if _, err := store.Exec(ctx, s.DB,
+			`INSERT INTO _events (id, trace_id, span_id, event_type, source, component, action, created_at)
+			 VALUES (?1, ?2, ?3, 'system', 'engine', 'handler', 'request', ?4)`,
+			id, store.GenerateUUID(), store.GenerateUUID(), createdAt); err != nil {
+			t.Fatalf("insert event: %v", err)
+		}
+		if i >= 4 {
+			inRange[id] = true
+		}
+	}
+
+	h := NewEventHandler(s.DB, s.Dialect)
+	h.SetMaxPerPage(3)
+	app := fiber.New()
+	app.Get("/_events", h.List)
+
+	get := func(query url.Values) map[string]any {
+		req, _ := http.NewRequest("GET", "/_events?"+query.Encode(), nil)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected 200, got %d: %s", resp.StatusCode, raw)
+		}
+		var out map[string]any
+		_ = json.Unmarshal(raw, &out)
+		return out
+	}
+
+	seen := map[string]bool{}
+	query := url.Values{"from": {"2025-01-02 00:00:00"}, "per_page": {"50"}}
+	var lastCreated string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+		out := get(query)
+		pagination := out["pagination"].(map[string]any)
+		if pagination["per_page"] != float64(3) {
+			t.Fatalf("expected per_page capped at 3, got %v", pagination["per_page"])
+		}
+		if pages > 0 {
+			if _, ok := pagination["total"]; ok {
+				t.Fatal("expected keyset pages to skip the count")
+			}
+		}
+		for _, r := range out["data"].([]any) {
+			row := r.(map[string]any)
+			id := row["id"].(string)
+			if seen[id] {
+				t.Fatalf("event %s returned on more than one page", id)
+			}
+			seen[id] = true
+			created := row["created_at"].(string)
+			if lastCreated != "" && created > lastCreated {
+				t.Fatalf("expected newest first, got %s after %s", created, lastCreated)
+			}
+			lastCreated = created
+		}
+		if pagination["has_more"] != true {
+			break
+		}
+		query.Set("after", pagination["next_cursor"].(string))
+	}
+
+	if len(seen) != len(inRange) {
+		t.Fatalf("expected %d events in range, got %d", len(inRange), len(seen))
+	}
+	for id := range seen {
+		if !inRange[id] {
+			t.Fatalf("event %s is outside the requested range", id)
+		}
+	}
+}
@@ -6,13 +6,70 @@ type SlugConfig struct {
 	RegenerateOnUpdate bool   `json:"regenerate_on_update,omitempty"` // re-generate slug on update when source changes
 }
 
+// WriteLimit caps the successful writes one user makes to an entity within
+// a rolling window.
+type WriteLimit struct {
+	Max           int `json:"max"`
+	WindowSeconds int `json:"window_seconds"`
+}
+
+// ProtectedFields lists fields clients may not assign. The engine strips them
+// from create and update bodies; rules, defaults and hooks can still set them.
+type ProtectedFields struct {
+	Create      []string `json:"create,omitempty"`
+	Update      []string `json:"update,omitempty"`
+	AdminBypass bool     `json:"admin_bypass,omitempty"` // admins may assign them
+}
+
+// For returns the fields protected on action ("create" or "update").
+func (p *ProtectedFields) For(action string) []string {
+	if p == nil {
+		return nil
+	}
+	if action == "create" {
+		return p.Create
+	}
+	return p.Update
+}
+
 type Entity struct {
-	Name       string      `json:"name"`
-	Table      string      `json:"table"`
-	PrimaryKey PrimaryKey  `json:"primary_key"`
-	SoftDelete bool        `json:"soft_delete"`
-	Slug       *SlugConfig `json:"slug,omitempty"`
-	Fields     []Field     `json:"fields"`
+	Name         string      `json:"name"`
+	Table        string      `json:"table"`
+	PrimaryKey   PrimaryKey  `json:"primary_key"`
+	IDStrategy   string      `json:"id_strategy,omitempty"`   // generated primary key: "uuid" (default), "uuidv7", "nanoid" or "sequence"
+	SoftDelete   bool        `json:"soft_delete"`
+	Slug         *SlugConfig `json:"slug,omitempty"`
+	Fields       []Field     `json:"fields"`
+	Cacheable    bool        `json:"cacheable,omitempty"`     // cache list/get reads in memory until a write or TTL expiry
+	CacheTTL     int         `json:"cache_ttl,omitempty"`     // seconds; defaults to 60 when cacheable
+	StrictFields *bool       `json:"strict_fields,omitempty"` // reject (true) or drop (false) unknown write keys; unset follows writes.strict_fields
+	RuleOrder    string      `json:"rule_order,omitempty"`    // "phased" or "priority"; unset follows writes.rule_order
+	Locking      string      `json:"locking,omitempty"`       // "none" (default, last write wins) or "pessimistic" (updates lock the row with SELECT ... FOR UPDATE)
+	ReadOnly     bool        `json:"readonly,omitempty"`      // no create, update or delete through the API
+	AppendOnly   bool        `json:"append_only,omitempty"`   // create only; update and delete are blocked
+	Timestamps   bool        `json:"timestamps,omitempty"`    // engine-managed created_at/updated_at fields
+	Attribution  bool        `json:"attribution,omitempty"`   // engine-managed created_by/updated_by fields (acting user's id)
+	Audit        bool        `json:"audit,omitempty"`         // record changed fields of every update in _audit_log
+	Scope        string      `json:"scope,omitempty"`         // expression every read and write must satisfy, e.g. record.org_id == user.org_id
+	ScopeBypass  bool        `json:"scope_admin_bypass,omitempty"` // admins are not limited by scope
+	DefaultSort  string      `json:"default_sort,omitempty"`  // list sort when the request has none, e.g. "-created_at"
+	SearchableFields []string `json:"searchable_fields,omitempty"` // string/text fields ?q= searches; empty means all of them
+	Search       string      `json:"search,omitempty"`        // "like" (default, case-insensitive substring) or "fulltext" (Postgres text search)
+	WriteLimit   *WriteLimit `json:"write_limit,omitempty"`   // per-user throttle on creates, updates and deletes (429 when exceeded)
+	Protected    *ProtectedFields `json:"protected_fields,omitempty"` // fields clients can't assign on create/update
+	// TablePrefixed is set once Registry.Load has put Table under the app's
+	// table prefix. Definitions parsed from a request or a stored row don't
+	// carry it yet.
+	TablePrefixed bool `json:"-"`
+}
+
+// PhysicalTable returns the entity's table name under prefix, prepending it
+// unless Registry.Load already has.
+func (e *Entity) PhysicalTable(prefix string) string {
+	if e.TablePrefixed {
+		return e.Table
+	}
+	return prefix + e.Table
 }
 
 type PrimaryKey struct {
@@ -31,6 +88,67 @@ func (e *Entity) GetField(name string) *Field {
 	return nil
 }
 
+// SearchFields returns the fields a ?q= search looks in: searchable_fields
+// when set, otherwise every string and text field.
+func (e *Entity) SearchFields() []string {
+	if len(e.SearchableFields) > 0 {
+		return e.SearchableFields
+	}
+	var names []string
+	for _, f := range e.Fields {
+		if f.Type == "string" || f.Type == "text" {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// WriteBlocked reports whether the entity's readonly or append_only mode
+// forbids action ("create", "update" or "delete") through the API.
+func (e *Entity) WriteBlocked(action string) bool {
+	switch {
+	case e.ReadOnly:
+		return action == "create" || action == "update" || action == "delete"
+	case e.AppendOnly:
+		return action == "update" || action == "delete"
+	}
+	return false
+}
+
+// ApplyTimestamps adds the created_at and updated_at fields of an entity with
+// timestamps enabled, or marks declared ones as engine-managed. Safe to call
+// more than once.
+func (e *Entity) ApplyTimestamps() {
+	if !e.Timestamps {
+		return
+	}
+	for _, ts := range []struct{ name, auto string }{{"created_at", "create"}, {"updated_at", "update"}} {
+		if f := e.GetField(ts.name); f != nil {
+			f.Auto = ts.auto
+			continue
+		}
+		e.Fields = append(e.Fields, Field{Name: ts.name, Type: "timestamp", Auto: ts.auto})
+	}
+}
+
+// ApplyAttribution adds the created_by and updated_by fields of an entity with
+// attribution enabled, or marks declared ones as engine-managed. They are
+// nullable, since system and anonymous writes have no user. Safe to call more
+// than once.
+func (e *Entity) ApplyAttribution() {
+	if !e.Attribution {
+		return
+	}
+	for _, by := range []struct{ name, auto string }{{"created_by", "create_user"}, {"updated_by", "update_user"}} {
+		if f := e.GetField(by.name); f != nil {
+			f.Auto = by.auto
+			f.Nullable = true
+			continue
+		}
+		e.Fields = append(e.Fields, Field{Name: by.name, Type: "string", Nullable: true, Auto: by.auto})
+	}
+}
+
 // HasField returns true if the entity has a field with the given name.
 func (e *Entity) HasField(name string) bool {
 	return e.GetField(name) != nil
@@ -46,14 +164,14 @@ func (e *Entity) FieldNames() []string {
 }
 
 // WritableFields returns fields that can be set by the client.
-// Excludes auto-generated PKs and auto-timestamp fields.
+// Excludes auto-generated PKs, auto-timestamp and rollup fields.
 func (e *Entity) WritableFields() []Field {
 	var fields []Field
 	for _, f := range e.Fields {
 		if f.Name == e.PrimaryKey.Field && e.PrimaryKey.Generated {
 			continue
 		}
-		if f.IsAuto() {
+		if f.IsAuto() || f.IsRollup() {
 			continue
 		}
 		fields = append(fields, f)
@@ -69,7 +187,7 @@ func (e *Entity) UpdatableFields() []Field {
 		if f.Name == e.PrimaryKey.Field {
 			continue
 		}
-		if f.IsAuto() {
+		if f.IsAuto() || f.IsRollup() {
 			continue
 		}
 		if f.Name == "deleted_at" {
@@ -1,22 +1,122 @@
 package metadata
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileConfig constrains uploads accepted by a file-type field.
+type FileConfig struct {
+	MaxSize      int64    `json:"max_size,omitempty"`      // bytes; 0 means the app-wide limit
+	AllowedTypes []string `json:"allowed_types,omitempty"` // MIME types, "image/*" wildcards allowed
+}
+
+// AllowsMimeType reports whether the MIME type is permitted. An empty list allows everything.
+func (fc *FileConfig) AllowsMimeType(mimeType string) bool {
+	if fc == nil || len(fc.AllowedTypes) == 0 {
+		return true
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	for _, t := range fc.AllowedTypes {
+		t = strings.ToLower(t)
+		if t == mimeType || t == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// RollupConfig declares a rollup field: an aggregate over the records a
+// one_to_many or one_to_one relation of the entity points to, kept up to date
+// by the engine whenever those records are written.
+type RollupConfig struct {
+	Relation  string `json:"relation"`        // relation whose source is this entity
+	Aggregate string `json:"aggregate"`       // count, sum, avg, min, max
+	Field     string `json:"field,omitempty"` // target field to aggregate; not used by count
+}
+
+// ValidRollupAggregates lists the aggregates a rollup field may use.
+var ValidRollupAggregates = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
 
 type Field struct {
-	Name      string   `json:"name"`
-	Type      string   `json:"type"`
-	Required  bool     `json:"required,omitempty"`
-	Unique    bool     `json:"unique,omitempty"`
-	Default   any      `json:"default,omitempty"`
-	Nullable  bool     `json:"nullable,omitempty"`
-	Enum      []string `json:"enum,omitempty"`
-	Precision int      `json:"precision,omitempty"`
-	Auto      string   `json:"auto,omitempty"` // "create" or "update"
+	Name            string         `json:"name"`
+	Type            string         `json:"type"`
+	Required        bool           `json:"required,omitempty"`
+	Unique          bool           `json:"unique,omitempty"`
+	CaseInsensitive bool           `json:"case_insensitive,omitempty"` // unique/filter comparisons ignore case (string/text only)
+	Default         any            `json:"default,omitempty"`
+	Nullable        bool           `json:"nullable,omitempty"`
+	Enum            []string       `json:"enum,omitempty"`
+	Precision       int            `json:"precision,omitempty"`
+	Auto            string         `json:"auto,omitempty"`          // timestamps: "create" or "update"; acting user: "create_user" or "update_user"
+	File            *FileConfig    `json:"file,omitempty"`          // upload constraints for file fields
+	Transform       []string       `json:"transform,omitempty"`     // applied in order before validation: trim, lower, upper, normalize_email
+	RenamedFrom     string         `json:"renamed_from,omitempty"`  // previous column name; the migrator renames it instead of adding a new column
+	Items           string         `json:"items,omitempty"`         // element type of an array field: string (default), int, float, boolean, uuid
+	Backfill        any            `json:"backfill,omitempty"`      // value written into existing NULLs when the field becomes required
+	Schema          *JSONSchema    `json:"schema,omitempty"`        // json fields only: structure enforced on write
+	UniqueWhere     map[string]any `json:"unique_where,omitempty"`  // unique fields only: uniqueness applies to rows matching these conditions
+	Rollup          *RollupConfig  `json:"rollup,omitempty"`        // rollup fields only: the aggregate the engine maintains
+	Label           string         `json:"label,omitempty"`         // display name for generated UIs; descriptive only
+	HelpText        string         `json:"help_text,omitempty"`     // hint shown next to the input in generated UIs; descriptive only
+	Immutable       bool           `json:"immutable,omitempty"`     // set on create, then rejected on update unless an admin overrides
+	EmptyStrings    string         `json:"empty_strings,omitempty"` // "keep", "null" ("" is stored as null) or "empty" (null is stored as ""); unset follows writes.empty_strings
+}
+
+// ValidArrayItems lists the element types an array field may hold.
+var ValidArrayItems = map[string]bool{
+	"string":  true,
+	"int":     true,
+	"float":   true,
+	"boolean": true,
+	"uuid":    true,
+}
+
+// ItemType returns the element type of an array field, defaulting to string.
+func (f Field) ItemType() string {
+	if f.Items == "" {
+		return "string"
+	}
+	return f.Items
+}
+
+// ValidTransforms lists the supported field transform directives.
+var ValidTransforms = map[string]bool{
+	"trim":            true,
+	"lower":           true,
+	"upper":           true,
+	"normalize_email": true,
+}
+
+// StorageType returns the type the field's column is created with. Rollups
+// store counts as bigint, and other aggregates as decimal when a precision is
+// set or float otherwise.
+func (f Field) StorageType() string {
+	if f.Type != "rollup" {
+		return f.Type
+	}
+	switch {
+	case f.Rollup != nil && f.Rollup.Aggregate == "count":
+		return "bigint"
+	case f.Precision > 0:
+		return "decimal"
+	}
+	return "float"
 }
 
 // PostgresType returns the Postgres DDL type for this field.
 func (f Field) PostgresType() string {
-	switch f.Type {
+	switch f.StorageType() {
 	case "string", "text":
 		return "TEXT"
 	case "int", "integer":
@@ -38,8 +138,10 @@ func (f Field) PostgresType() string {
 		return "TIMESTAMPTZ"
 	case "date":
 		return "DATE"
-	case "json", "file":
+	case "json", "file", "geopoint":
 		return "JSONB"
+	case "array":
+		return "TEXT[]"
 	default:
 		return "TEXT"
 	}
@@ -47,5 +149,69 @@ func (f Field) PostgresType() string {
 
 // IsAuto returns true if the field is auto-managed by the engine.
 func (f Field) IsAuto() bool {
-	return f.Auto == "create" || f.Auto == "update"
+	return f.Auto == "create" || f.Auto == "update" || f.IsAutoUser()
+}
+
+// IsAutoUser returns true if the engine sets the field to the acting user's id.
+func (f Field) IsAutoUser() bool {
+	return f.Auto == "create_user" || f.Auto == "update_user"
+}
+
+// IsRollup returns true if the field is a rollup maintained by the engine.
+func (f Field) IsRollup() bool {
+	return f.Type == "rollup"
+}
+
+// IsCaseInsensitive returns true if comparisons on this string field should ignore case.
+func (f Field) IsCaseInsensitive() bool {
+	return f.CaseInsensitive && (f.Type == "string" || f.Type == "text")
+}
+
+// UniqueCondition is one term of a field's unique_where: Field equals Value,
+// or differs from it when Op is "neq". A nil Value compares with IS [NOT] NULL.
+type UniqueCondition struct {
+	Field string
+	Op    string
+	Value any
+}
+
+// UniqueConditions parses unique_where ("field" or "field.neq" keys) in key
+// order, so the same conditions always produce the same index.
+func (f Field) UniqueConditions() []UniqueCondition {
+	keys := make([]string, 0, len(f.UniqueWhere))
+	for k := range f.UniqueWhere {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	conds := make([]UniqueCondition, 0, len(keys))
+	for _, k := range keys {
+		name, op := k, "eq"
+		if i := strings.LastIndex(k, "."); i >= 0 {
+			name, op = k[:i], k[i+1:]
+		}
+		conds = append(conds, UniqueCondition{Field: name, Op: op, Value: f.UniqueWhere[k]})
+	}
+	return conds
+}
+
+// ApplyTransforms runs the field's transform directives over a string value.
+// Non-string values pass through unchanged. Transforms are idempotent.
+func (f Field) ApplyTransforms(val any) any {
+	str, ok := val.(string)
+	if !ok || len(f.Transform) == 0 {
+		return val
+	}
+	for _, t := range f.Transform {
+		switch t {
+		case "trim":
+			str = strings.TrimSpace(str)
+		case "lower":
+			str = strings.ToLower(str)
+		case "upper":
+			str = strings.ToUpper(str)
+		case "normalize_email":
+			str = strings.ToLower(strings.TrimSpace(str))
+		}
+	}
+	return str
 }
@@ -0,0 +1,225 @@
+package metadata
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// JSONSchema is the subset of JSON Schema accepted on json fields. It gives
+// structure to semi-structured columns: types, object properties, required
+// keys, array items, enums, numeric and length bounds, patterns and defaults.
+type JSONSchema struct {
+	Type                 string                 `json:"type,omitempty"` // object, array, string, number, integer, boolean, null
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Default              any                    `json:"default,omitempty"` // filled in when an object property is missing
+
+	pattern *regexp.Regexp
+}
+
+var validSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// Compile checks the schema and compiles its patterns. The registry calls it
+// on load so writes don't re-parse patterns.
+func (s *JSONSchema) Compile() error {
+	return s.compile("$")
+}
+
+func (s *JSONSchema) compile(path string) error {
+	if s.Type != "" && !validSchemaTypes[s.Type] {
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", path, err)
+		}
+		s.pattern = re
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("%s.%s: schema must be an object", path, name)
+		}
+		if err := prop.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaViolation is one failed constraint. Path locates the value from the
+// field's root, e.g. "$.address.zip" or "$.tags[2]"; Rule is the keyword.
+type SchemaViolation struct {
+	Path    string
+	Rule    string
+	Message string
+}
+
+// Validate returns the constraints v breaks, or nil when it conforms.
+func (s *JSONSchema) Validate(v any) []SchemaViolation {
+	var out []SchemaViolation
+	s.validate(v, "$", &out)
+	return out
+}
+
+func (s *JSONSchema) validate(v any, path string, out *[]SchemaViolation) {
+	fail := func(rule, format string, args ...any) {
+		*out = append(*out, SchemaViolation{Path: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !schemaTypeMatches(s.Type, v) {
+		fail("type", "%s must be of type %s", path, s.Type)
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "%s must be one of the allowed values", path)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				*out = append(*out, SchemaViolation{Path: path + "." + name, Rule: "required", Message: fmt.Sprintf("%s.%s is required", path, name)})
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				prop.validate(val[k], path+"."+k, out)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*out = append(*out, SchemaViolation{Path: path + "." + k, Rule: "additionalProperties", Message: fmt.Sprintf("%s.%s is not allowed", path, k)})
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			fail("minItems", "%s must have at least %d items", path, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			fail("maxItems", "%s must have at most %d items", path, *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), out)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("minLength", "%s must be at least %d characters", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("maxLength", "%s must be at most %d characters", path, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			fail("pattern", "%s must match %s", path, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			fail("minimum", "%s must be >= %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			fail("maximum", "%s must be <= %v", path, *s.Maximum)
+		}
+	}
+}
+
+// ApplyDefaults fills missing object properties that declare a default,
+// descending into nested objects and array items. Maps are updated in place.
+func (s *JSONSchema) ApplyDefaults(v any) {
+	switch val := v.(type) {
+	case map[string]any:
+		for name, prop := range s.Properties {
+			if _, ok := val[name]; !ok && prop.Default != nil {
+				val[name] = cloneJSON(prop.Default)
+			}
+			if child, ok := val[name]; ok {
+				prop.ApplyDefaults(child)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for _, item := range val {
+				s.Items.ApplyDefaults(item)
+			}
+		}
+	}
+}
+
+func schemaTypeMatches(t string, v any) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+// cloneJSON copies a decoded JSON value so defaults aren't shared between records.
+func cloneJSON(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = cloneJSON(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = cloneJSON(item)
+		}
+		return out
+	}
+	return v
+}
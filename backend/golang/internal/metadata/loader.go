@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 )
 
 // LoadAll reads all entities and relations from the database and populates the registry.
@@ -115,7 +116,7 @@ func loadRelations(ctx context.Context, db *sql.DB) ([]*Relation, error) {
 
 func loadRules(ctx context.Context, db *sql.DB) ([]*Rule, error) {
 	rows, err := db.QueryContext(ctx,
-		"SELECT id, entity, hook, type, definition, priority, active FROM _rules ORDER BY entity, priority")
+		"SELECT id, entity, hook, type, definition, priority, active, created_at, tags FROM _rules ORDER BY entity, priority, created_at, id")
 	if err != nil {
 		return nil, err
 	}
@@ -125,11 +126,13 @@ func loadRules(ctx context.Context, db *sql.DB) ([]*Rule, error) {
 	for rows.Next() {
 		var r Rule
 		var defJSON []byte
-		var active any
-		if err := rows.Scan(&r.ID, &r.Entity, &r.Hook, &r.Type, &defJSON, &r.Priority, &active); err != nil {
+		var active, createdAt, tags any
+		if err := rows.Scan(&r.ID, &r.Entity, &r.Hook, &r.Type, &defJSON, &r.Priority, &active, &createdAt, &tags); err != nil {
 			return nil, fmt.Errorf("scan rule row: %w", err)
 		}
 		r.Active = toBool(active)
+		r.Tags = ParseStringArray(tags)
+		r.CreatedAt = toTime(createdAt)
 		if err := json.Unmarshal(defJSON, &r.Definition); err != nil {
 			log.Printf("WARN: skipping rule %s (invalid JSON): %v", r.ID, err)
 			continue
@@ -167,7 +170,7 @@ func loadStateMachines(ctx context.Context, db *sql.DB) ([]*StateMachine, error)
 
 func loadWorkflows(ctx context.Context, db *sql.DB) ([]*Workflow, error) {
 	rows, err := db.QueryContext(ctx,
-		"SELECT id, name, trigger, context, steps, active FROM _workflows ORDER BY name")
+		"SELECT id, name, trigger, context, steps, active, tags FROM _workflows ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -177,11 +180,12 @@ func loadWorkflows(ctx context.Context, db *sql.DB) ([]*Workflow, error) {
 	for rows.Next() {
 		var wf Workflow
 		var triggerJSON, contextJSON, stepsJSON []byte
-		var active any
-		if err := rows.Scan(&wf.ID, &wf.Name, &triggerJSON, &contextJSON, &stepsJSON, &active); err != nil {
+		var active, tags any
+		if err := rows.Scan(&wf.ID, &wf.Name, &triggerJSON, &contextJSON, &stepsJSON, &active, &tags); err != nil {
 			return nil, fmt.Errorf("scan workflow row: %w", err)
 		}
 		wf.Active = toBool(active)
+		wf.Tags = ParseStringArray(tags)
 		if err := json.Unmarshal(triggerJSON, &wf.Trigger); err != nil {
 			log.Printf("WARN: skipping workflow %s (invalid trigger JSON): %v", wf.Name, err)
 			continue
@@ -201,7 +205,7 @@ func loadWorkflows(ctx context.Context, db *sql.DB) ([]*Workflow, error) {
 
 func loadWebhooks(ctx context.Context, db *sql.DB) ([]*Webhook, error) {
 	rows, err := db.QueryContext(ctx,
-		"SELECT id, entity, hook, url, method, headers, condition, async, retry, active FROM _webhooks ORDER BY entity, hook")
+		"SELECT id, entity, hook, url, method, headers, condition, async, retry, active, batch, transport, transport_config, tags, secret FROM _webhooks ORDER BY entity, hook")
 	if err != nil {
 		return nil, err
 	}
@@ -210,13 +214,16 @@ func loadWebhooks(ctx context.Context, db *sql.DB) ([]*Webhook, error) {
 	var webhooks []*Webhook
 	for rows.Next() {
 		var wh Webhook
-		var headersJSON, retryJSON []byte
-		var asyncVal, activeVal any
-		if err := rows.Scan(&wh.ID, &wh.Entity, &wh.Hook, &wh.URL, &wh.Method, &headersJSON, &wh.Condition, &asyncVal, &retryJSON, &activeVal); err != nil {
+		var headersJSON, retryJSON, batchJSON, transportConfigJSON []byte
+		var asyncVal, activeVal, tags any
+		var transport, secret sql.NullString
+		if err := rows.Scan(&wh.ID, &wh.Entity, &wh.Hook, &wh.URL, &wh.Method, &headersJSON, &wh.Condition, &asyncVal, &retryJSON, &activeVal, &batchJSON,
+			&transport, &transportConfigJSON, &tags, &secret); err != nil {
 			return nil, fmt.Errorf("scan webhook row: %w", err)
 		}
 		wh.Async = toBool(asyncVal)
 		wh.Active = toBool(activeVal)
+		wh.Tags = ParseStringArray(tags)
 		if headersJSON != nil && len(headersJSON) > 0 {
 			if err := json.Unmarshal(headersJSON, &wh.Headers); err != nil {
 				log.Printf("WARN: skipping webhook %s (invalid headers JSON): %v", wh.ID, err)
@@ -232,6 +239,20 @@ func loadWebhooks(ctx context.Context, db *sql.DB) ([]*Webhook, error) {
 				continue
 			}
 		}
+		if len(batchJSON) > 0 {
+			if err := json.Unmarshal(batchJSON, &wh.Batch); err != nil {
+				log.Printf("WARN: skipping webhook %s (invalid batch JSON): %v", wh.ID, err)
+				continue
+			}
+		}
+		wh.Transport = transport.String
+		wh.Secret = secret.String
+		if len(transportConfigJSON) > 0 {
+			if err := json.Unmarshal(transportConfigJSON, &wh.TransportConfig); err != nil {
+				log.Printf("WARN: skipping webhook %s (invalid transport_config JSON): %v", wh.ID, err)
+				continue
+			}
+		}
 		webhooks = append(webhooks, &wh)
 	}
 	return webhooks, rows.Err()
@@ -239,7 +260,7 @@ func loadWebhooks(ctx context.Context, db *sql.DB) ([]*Webhook, error) {
 
 func loadPermissions(ctx context.Context, db *sql.DB) ([]*Permission, error) {
 	rows, err := db.QueryContext(ctx,
-		"SELECT id, entity, action, roles, conditions FROM _permissions ORDER BY entity, action")
+		"SELECT id, entity, action, effect, roles, conditions FROM _permissions ORDER BY entity, action")
 	if err != nil {
 		return nil, err
 	}
@@ -250,9 +271,14 @@ func loadPermissions(ctx context.Context, db *sql.DB) ([]*Permission, error) {
 		var p Permission
 		var condJSON []byte
 		var rolesRaw any
-		if err := rows.Scan(&p.ID, &p.Entity, &p.Action, &rolesRaw, &condJSON); err != nil {
+		var effect sql.NullString
+		if err := rows.Scan(&p.ID, &p.Entity, &p.Action, &effect, &rolesRaw, &condJSON); err != nil {
 			return nil, fmt.Errorf("scan permission row: %w", err)
 		}
+		p.Effect = effect.String
+		if p.Effect == "" {
+			p.Effect = "allow"
+		}
 		p.Roles = ParseStringArray(rolesRaw)
 		if condJSON != nil && len(condJSON) > 0 {
 			if err := json.Unmarshal(condJSON, &p.Conditions); err != nil {
@@ -284,6 +310,22 @@ func toBool(v any) bool {
 	}
 }
 
+// toTime converts a scanned timestamp: time.Time from PostgreSQL, text from
+// SQLite. Unparseable values give the zero time.
+func toTime(v any) time.Time {
+	switch val := v.(type) {
+	case time.Time:
+		return val
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
 // ParseStringArray decodes TEXT[] (PostgreSQL) or JSON string (SQLite) into []string.
 func ParseStringArray(v any) []string {
 	if v == nil {
@@ -1,17 +1,27 @@
 package metadata
 
 // Permission represents a metadata-driven permission policy.
+// Effect is "allow" (default) or "deny"; deny policies take precedence over grants.
 type Permission struct {
-	ID         string              `json:"id,omitempty"`
-	Entity     string              `json:"entity"`
-	Action     string              `json:"action"`
-	Roles      []string            `json:"roles"`
+	ID         string                `json:"id,omitempty"`
+	Entity     string                `json:"entity"`
+	Action     string                `json:"action"`
+	Effect     string                `json:"effect,omitempty"`
+	Roles      []string              `json:"roles"`
 	Conditions []PermissionCondition `json:"conditions,omitempty"`
 }
 
+// IsDeny returns true if the policy carves out an exception instead of granting access.
+func (p *Permission) IsDeny() bool {
+	return p.Effect == "deny"
+}
+
 // PermissionCondition is a field-level condition for a permission policy.
+// Alternatively, Expression holds an expr-lang boolean evaluated against
+// `record` and `user` (id, roles), e.g. "record.owner_id == user.id".
 type PermissionCondition struct {
-	Field    string `json:"field"`
-	Operator string `json:"operator"`
-	Value    any    `json:"value"`
+	Field      string `json:"field"`
+	Operator   string `json:"operator"`
+	Value      any    `json:"value"`
+	Expression string `json:"expression,omitempty"`
 }
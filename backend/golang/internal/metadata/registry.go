@@ -1,6 +1,8 @@
 package metadata
 
 import (
+	"log"
+	"slices"
 	"sort"
 	"sync"
 )
@@ -16,6 +18,8 @@ type Registry struct {
 	workflowsByName           map[string]*Workflow         // keyed by workflow name
 	permissionsByEntityAction map[string][]*Permission     // keyed by "entity:action"
 	webhooksByEntityHook     map[string][]*Webhook        // keyed by "entity:hook"
+	version                  uint64                       // bumped on every Load
+	tablePrefix              string                       // prepended to entity and join table names on Load
 }
 
 func NewRegistry() *Registry {
@@ -97,14 +101,15 @@ func (r *Registry) AllRelations() []*Relation {
 	return relations
 }
 
-// GetRulesForEntity returns active rules for an entity and hook, sorted by priority.
-func (r *Registry) GetRulesForEntity(entityName, hook string) []*Rule {
+// GetRulesForEntity returns active rules for an entity and any of hooks,
+// sorted by priority, then created_at, then id.
+func (r *Registry) GetRulesForEntity(entityName string, hooks ...string) []*Rule {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	all := r.rulesByEntity[entityName]
 	var result []*Rule
 	for _, rule := range all {
-		if rule.Active && rule.Hook == hook {
+		if rule.Active && slices.Contains(hooks, rule.Hook) {
 			result = append(result, rule)
 		}
 	}
@@ -117,19 +122,52 @@ func (r *Registry) Load(entities []*Entity, relations []*Relation) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	r.version++
+
 	r.entities = make(map[string]*Entity, len(entities))
 	for _, e := range entities {
 		r.entities[e.Name] = e
+		e.Table = e.PhysicalTable(r.tablePrefix)
+		e.TablePrefixed = true
+		e.ApplyTimestamps()
+		e.ApplyAttribution()
+		for i := range e.Fields {
+			if f := &e.Fields[i]; f.Schema != nil {
+				if err := f.Schema.Compile(); err != nil {
+					log.Printf("WARN: entity %s field %s: invalid schema: %v", e.Name, f.Name, err)
+				}
+			}
+		}
 	}
 
 	r.relationsBySource = make(map[string][]*Relation)
 	r.relationsByName = make(map[string]*Relation, len(relations))
 	for _, rel := range relations {
+		if rel.JoinTable != "" {
+			rel.JoinTable = rel.PhysicalJoinTable(r.tablePrefix)
+			rel.JoinTablePrefixed = true
+		}
 		r.relationsByName[rel.Name] = rel
 		r.relationsBySource[rel.Source] = append(r.relationsBySource[rel.Source], rel)
 	}
 }
 
+// SetTablePrefix sets the prefix applied to entity and join table names by
+// later Loads, so apps sharing one database keep their tables apart. Stored
+// definitions keep the unprefixed names.
+func (r *Registry) SetTablePrefix(prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tablePrefix = prefix
+}
+
+// Version identifies the loaded entity/relation set; it changes on every Load.
+func (r *Registry) Version() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
 // GetStateMachinesForEntity returns active state machines for an entity.
 func (r *Registry) GetStateMachinesForEntity(entityName string) []*StateMachine {
 	r.mu.RLock()
@@ -177,6 +215,17 @@ func (r *Registry) GetWorkflow(name string) *Workflow {
 	return r.workflowsByName[name]
 }
 
+// AllWorkflows returns every workflow in the registry, including inactive ones.
+func (r *Registry) AllWorkflows() []*Workflow {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	workflows := make([]*Workflow, 0, len(r.workflowsByName))
+	for _, wf := range r.workflowsByName {
+		workflows = append(workflows, wf)
+	}
+	return workflows
+}
+
 // LoadWorkflows replaces all workflows in the registry.
 func (r *Registry) LoadWorkflows(workflows []*Workflow) {
 	r.mu.Lock()
@@ -228,6 +277,17 @@ func (r *Registry) GetWebhooksForEntityHook(entity, hook string) []*Webhook {
 	return result
 }
 
+// AllWebhooks returns every webhook in the registry, including inactive ones.
+func (r *Registry) AllWebhooks() []*Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var result []*Webhook
+	for _, whs := range r.webhooksByEntityHook {
+		result = append(result, whs...)
+	}
+	return result
+}
+
 // LoadWebhooks replaces all webhooks in the registry.
 func (r *Registry) LoadWebhooks(webhooks []*Webhook) {
 	r.mu.Lock()
@@ -240,7 +300,8 @@ func (r *Registry) LoadWebhooks(webhooks []*Webhook) {
 	}
 }
 
-// LoadRules replaces all rules in the registry, sorted by priority.
+// LoadRules replaces all rules in the registry, sorted by priority, then
+// created_at, then id.
 func (r *Registry) LoadRules(rules []*Rule) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -249,10 +310,9 @@ func (r *Registry) LoadRules(rules []*Rule) {
 	for _, rule := range rules {
 		r.rulesByEntity[rule.Entity] = append(r.rulesByEntity[rule.Entity], rule)
 	}
-	// Sort each entity's rules by priority
 	for _, entityRules := range r.rulesByEntity {
 		sort.Slice(entityRules, func(i, j int) bool {
-			return entityRules[i].Priority < entityRules[j].Priority
+			return ruleLess(entityRules[i], entityRules[j])
 		})
 	}
 }
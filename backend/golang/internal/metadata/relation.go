@@ -11,9 +11,25 @@ type Relation struct {
 	SourceJoinKey string `json:"source_join_key,omitempty"`
 	TargetJoinKey string `json:"target_join_key,omitempty"`
 	Ownership     string `json:"ownership"`  // source, target, none
-	OnDelete      string `json:"on_delete"`  // cascade, set_null, restrict, detach
+	OnDelete      string `json:"on_delete"`  // cascade, set_null, restrict (default), detach
 	Fetch         string `json:"fetch,omitempty"`      // lazy (default), eager
 	WriteMode     string `json:"write_mode,omitempty"` // diff (default), replace, append
+	// Soft makes the engine check that written target keys reference an
+	// existing, non-deleted source row. The migrator declares no FK for any
+	// relation, so without it dangling keys are stored as written.
+	Soft bool `json:"soft,omitempty"`
+	// JoinTablePrefixed is set once Registry.Load has put JoinTable under the
+	// app's table prefix.
+	JoinTablePrefixed bool `json:"-"`
+}
+
+// PhysicalJoinTable returns the join table name under prefix, prepending it
+// unless Registry.Load already has.
+func (r *Relation) PhysicalJoinTable(prefix string) string {
+	if r.JoinTablePrefixed {
+		return r.JoinTable
+	}
+	return prefix + r.JoinTable
 }
 
 func (r *Relation) IsManyToMany() bool {
@@ -43,3 +59,11 @@ func (r *Relation) DefaultFetch() string {
 	}
 	return "lazy"
 }
+
+// DefaultOnDelete returns the on_delete policy, defaulting to "restrict".
+func (r *Relation) DefaultOnDelete() string {
+	if r.OnDelete != "" {
+		return r.OnDelete
+	}
+	return "restrict"
+}
@@ -1,5 +1,7 @@
 package metadata
 
+import "time"
+
 // RelatedLoadSpec tells the engine which relation to pre-fetch before evaluating an expression.
 type RelatedLoadSpec struct {
 	Relation string         `json:"relation"`
@@ -28,12 +30,26 @@ type RuleDefinition struct {
 type Rule struct {
 	ID         string         `json:"id"`
 	Entity     string         `json:"entity"`
-	Hook       string         `json:"hook"`
+	Hook       string         `json:"hook"` // "before_write" (creates and updates), "before_create", "before_update", "before_delete", "after_write", "after_delete"
 	Type       string         `json:"type"` // "field", "expression", "computed"
 	Definition RuleDefinition `json:"definition"`
 	Priority   int            `json:"priority"`
 	Active     bool           `json:"active"`
+	Tags       []string       `json:"tags,omitempty"` // for grouping and filtering in the admin API
+	CreatedAt  time.Time      `json:"-"`              // breaks priority ties (older first), then ID
 
 	// Compiled holds the compiled expression program (set at load time, not serialized).
 	Compiled any `json:"-"`
 }
+
+// ruleLess orders rules by priority, then created_at, then id, so rules that
+// share a priority still evaluate in a stable order.
+func ruleLess(a, b *Rule) bool {
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
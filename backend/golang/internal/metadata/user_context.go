@@ -4,6 +4,10 @@ package metadata
 type UserContext struct {
 	ID    string   `json:"id"`
 	Roles []string `json:"roles"`
+
+	// Attributes are the admin-managed _users.attributes (e.g. org_id) that
+	// entity scopes compare against. Loaded on demand; nil until then.
+	Attributes map[string]any `json:"-"`
 }
 
 // HasRole checks whether the user has a specific role.
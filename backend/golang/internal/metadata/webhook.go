@@ -8,18 +8,37 @@ type WebhookRetry struct {
 	Backoff     string `json:"backoff"` // "exponential" or "linear"
 }
 
-// Webhook defines an HTTP callout triggered by entity writes.
+// WebhookBatch collects async deliveries and sends them as a single array payload,
+// flushing once Size events are queued or IntervalMs after the first one.
+type WebhookBatch struct {
+	Size       int `json:"size"`
+	IntervalMs int `json:"interval_ms"`
+}
+
+// Webhook defines a callout triggered by entity writes. Transport selects the
+// delivery target: "http" (default) calls URL; "nats", "kafka" and "sqs"
+// publish the payload to the broker or queue at URL.
 type Webhook struct {
 	ID        string            `json:"id"`
 	Entity    string            `json:"entity"`
-	Hook      string            `json:"hook"`   // after_write, before_write, after_delete, before_delete
+	Hook      string            `json:"hook"` // after_write, before_write, after_delete, before_delete
 	URL       string            `json:"url"`
 	Method    string            `json:"method"` // POST, PUT, PATCH, GET, DELETE
 	Headers   map[string]string `json:"headers"`
 	Condition string            `json:"condition"` // expression; empty = always fire
 	Async     bool              `json:"async"`
 	Retry     WebhookRetry      `json:"retry"`
+	Batch     *WebhookBatch     `json:"batch,omitempty"` // nil = deliver each event individually
 	Active    bool              `json:"active"`
+	Tags      []string          `json:"tags,omitempty"` // for grouping and filtering in the admin API
+
+	Transport       string            `json:"transport,omitempty"`        // http (default), nats, kafka, sqs
+	TransportConfig map[string]string `json:"transport_config,omitempty"` // e.g. {"subject": "orders"} for nats, {"topic": "orders"} for kafka
+
+	// Secret signs each delivery with an X-Rocket-Signature HMAC. It holds
+	// the stored value, encrypted when webhooks.secret_key is set, and is
+	// never serialized.
+	Secret string `json:"-"`
 
 	// CompiledCondition caches the compiled condition program (lazy-initialized).
 	CompiledCondition *vm.Program `json:"-"`
@@ -54,6 +54,16 @@ type WorkflowAssignee struct {
 	User string `json:"user,omitempty"`  // for type=fixed
 }
 
+// WorkflowEscalation fires once while an approval step waits, when the given
+// share of its timeout has elapsed: it runs the Notify actions and, if set,
+// hands the step to a new assignee. An escalation at 1 that reassigns also
+// restarts the timeout for the new assignee instead of timing out.
+type WorkflowEscalation struct {
+	At       float64           `json:"at"`                 // share of the timeout elapsed, 0 < at <= 1 (0.8 is 80%)
+	Notify   []WorkflowAction  `json:"notify,omitempty"`   // e.g. a webhook paging the fallback role
+	Reassign *WorkflowAssignee `json:"reassign,omitempty"`
+}
+
 // WorkflowAction defines an action to execute within a workflow step.
 type WorkflowAction struct {
 	Type     string `json:"type"`                // "set_field", "webhook", "send_event", "create_record"
@@ -64,12 +74,15 @@ type WorkflowAction struct {
 	URL      string `json:"url,omitempty"`
 	Method   string `json:"method,omitempty"`
 	Event    string `json:"event,omitempty"`
+	// ContinueOnError records a failed action's output and moves on, so a later
+	// condition can branch on context.steps.<id> instead of the workflow failing.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
 }
 
 // WorkflowStep represents a single step in the workflow.
 type WorkflowStep struct {
 	ID string `json:"id"`
-	// Type is "action", "condition", or "approval".
+	// Type is "action", "condition", "approval", or "callback".
 	Type string `json:"type"`
 
 	// Action step fields
@@ -88,6 +101,14 @@ type WorkflowStep struct {
 	OnApprove *StepGoto         `json:"on_approve,omitempty"`
 	OnReject  *StepGoto         `json:"on_reject,omitempty"`
 	OnTimeout *StepGoto         `json:"on_timeout,omitempty"`
+	// Escalations run in order of At; they need a Timeout
+	Escalations []WorkflowEscalation `json:"escalations,omitempty"`
+
+	// Callback step fields (Timeout and OnTimeout above also apply)
+	URL       string    `json:"url,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	OnSuccess *StepGoto `json:"on_success,omitempty"`
+	OnFailure *StepGoto `json:"on_failure,omitempty"`
 }
 
 // Workflow represents a workflow definition from the _workflows table.
@@ -98,13 +119,15 @@ type Workflow struct {
 	Context map[string]string `json:"context"`
 	Steps   []WorkflowStep    `json:"steps"`
 	Active  bool              `json:"active"`
+	Tags    []string          `json:"tags,omitempty"` // for grouping and filtering in the admin API
 }
 
 // WorkflowHistoryEntry records what happened at each step.
 type WorkflowHistoryEntry struct {
 	Step   string `json:"step"`
-	Status string `json:"status"` // "completed", "approved", "rejected", "timed_out"
+	Status string `json:"status"` // "completed", "approved", "rejected", "timed_out", "escalated", "reassigned", "step_limit_exceeded"
 	By     string `json:"by,omitempty"`
+	Error  string `json:"error,omitempty"`
 	At     string `json:"at"`
 }
 
@@ -113,11 +136,18 @@ type WorkflowInstance struct {
 	ID                  string                 `json:"id"`
 	WorkflowID          string                 `json:"workflow_id"`
 	WorkflowName        string                 `json:"workflow_name"`
+	Entity              string                 `json:"entity,omitempty"`    // trigger entity
+	RecordID            string                 `json:"record_id,omitempty"` // trigger record
 	Status              string                 `json:"status"` // "running", "completed", "failed", "cancelled"
 	CurrentStep         string                 `json:"current_step"`
 	CurrentStepDeadline *string                `json:"current_step_deadline,omitempty"`
 	Context             map[string]any         `json:"context"`
 	History             []WorkflowHistoryEntry `json:"history"`
+	CallbackToken       string                 `json:"-"` // sha256 of the token a paused callback step waits for
+	Attempts            int                    `json:"attempts,omitempty"`      // automatic retries made after transient failures
+	MaxAttempts         int                    `json:"max_attempts,omitempty"`  // retry cap, fixed when the instance is created
+	LastError           string                 `json:"last_error,omitempty"`    // error of the most recent failed step
+	NextRetryAt         *string                `json:"next_retry_at,omitempty"` // when a failed instance will be retried
 	CreatedAt           string                 `json:"created_at,omitempty"`
 	UpdatedAt           string                 `json:"updated_at,omitempty"`
 }
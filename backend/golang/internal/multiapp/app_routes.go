@@ -49,6 +49,15 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	appAuth.Post("/refresh", dispatch(func(ac *AppContext) fiber.Handler { return ac.AuthHandler.Refresh }))
 	appAuth.Post("/logout", dispatch(func(ac *AppContext) fiber.Handler { return ac.AuthHandler.Logout }))
 	appAuth.Post("/accept-invite", dispatch(func(ac *AppContext) fiber.Handler { return ac.AuthHandler.AcceptInvite }))
+	appAuth.Post("/change-password", appAuthMW, dispatch(func(ac *AppContext) fiber.Handler { return ac.AuthHandler.ChangePassword }))
+
+	// Workflow callbacks from external systems (authenticated by the step's token)
+	app.Post("/api/:app/_workflows/:id/callback", resolverMW, instrMW,
+		dispatch(func(ac *AppContext) fiber.Handler { return ac.WorkflowHandler.Callback }))
+
+	// Public app settings (no auth required, only app resolver)
+	app.Get("/api/:app/settings/public", resolverMW, instrMW,
+		dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.PublicSettings }))
 
 	// All other routes require app resolver + auth + instrumentation
 	protected := app.Group("/api/:app", resolverMW, appAuthMW, instrMW)
@@ -62,9 +71,13 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	adm.Post("/entities", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CreateEntity }))
 	adm.Put("/entities/:name", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.UpdateEntity }))
 	adm.Delete("/entities/:name", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.DeleteEntity }))
+	adm.Post("/entities/:name/reindex", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ReindexEntity }))
+	adm.Post("/entities/:name/truncate", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.TruncateEntity }))
+	adm.Get("/entities/:name/records/count-by/:field", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CountBy }))
 
 	// Relations
 	adm.Get("/relations", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListRelations }))
+	adm.Get("/relations/graph", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.RelationGraph }))
 	adm.Get("/relations/:name", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.GetRelation }))
 	adm.Post("/relations", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CreateRelation }))
 	adm.Put("/relations/:name", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.UpdateRelation }))
@@ -75,6 +88,7 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	adm.Get("/rules/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.GetRule }))
 	adm.Post("/rules", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CreateRule }))
 	adm.Put("/rules/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.UpdateRule }))
+	adm.Post("/rules/:id/toggle", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ToggleRule }))
 	adm.Delete("/rules/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.DeleteRule }))
 
 	// State Machines
@@ -108,6 +122,7 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	adm.Get("/permissions", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListPermissions }))
 	adm.Get("/permissions/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.GetPermission }))
 	adm.Post("/permissions", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CreatePermission }))
+	adm.Post("/permissions/bulk", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.BulkCreatePermissions }))
 	adm.Put("/permissions/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.UpdatePermission }))
 	adm.Delete("/permissions/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.DeletePermission }))
 
@@ -116,12 +131,15 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	adm.Get("/webhooks/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.GetWebhook }))
 	adm.Post("/webhooks", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CreateWebhook }))
 	adm.Put("/webhooks/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.UpdateWebhook }))
+	adm.Post("/webhooks/:id/toggle", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ToggleWebhook }))
 	adm.Delete("/webhooks/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.DeleteWebhook }))
 
 	// Webhook Logs
 	adm.Get("/webhook-logs", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListWebhookLogs }))
+	adm.Get("/webhook-logs/export.csv", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ExportWebhookLogs }))
 	adm.Get("/webhook-logs/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.GetWebhookLog }))
 	adm.Post("/webhook-logs/:id/retry", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.RetryWebhookLog }))
+	adm.Get("/audit-log", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListAuditLog }))
 
 	// UI Configs
 	adm.Get("/ui-configs", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListUIConfigs }))
@@ -134,6 +152,16 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	adm.Get("/export", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.Export }))
 	adm.Post("/import", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.Import }))
 
+	// Settings
+	adm.Get("/settings", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListSettings }))
+	adm.Get("/settings/:key", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.GetSetting }))
+	adm.Put("/settings/:key", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.PutSetting }))
+	adm.Delete("/settings/:key", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.DeleteSetting }))
+
+	// Read cache
+	adm.Get("/cache", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.CacheStats }))
+	adm.Get("/schedulers", dispatch(func(ac *AppContext) fiber.Handler { return ac.AdminHandler.ListSchedulers }))
+
 	// AI Schema Generator
 	adm.Get("/ai/status", dispatch(func(ac *AppContext) fiber.Handler {
 		if ac.AIHandler == nil {
@@ -155,6 +183,7 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 
 	// Workflow runtime routes
 	wf := protected.Group("/_workflows")
+	wf.Get("/", dispatch(func(ac *AppContext) fiber.Handler { return ac.WorkflowHandler.List }))
 	wf.Get("/pending", dispatch(func(ac *AppContext) fiber.Handler { return ac.WorkflowHandler.ListPending }))
 	wf.Get("/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.WorkflowHandler.GetInstance }))
 	wf.Post("/:id/approve", dispatch(func(ac *AppContext) fiber.Handler { return ac.WorkflowHandler.Approve }))
@@ -175,10 +204,26 @@ func RegisterAppRoutes(app *fiber.App, manager *AppManager, platformJWTSecret st
 	events.Get("/stats", adminMW, dispatch(func(ac *AppContext) fiber.Handler { return ac.EventHandler.GetStats }))
 	events.Get("/", adminMW, dispatch(func(ac *AppContext) fiber.Handler { return ac.EventHandler.List }))
 
+	// Self-service profile routes (auth required, no admin)
+	protected.Get("/me", dispatch(func(ac *AppContext) fiber.Handler { return ac.AuthHandler.GetMe }))
+	protected.Put("/me", dispatch(func(ac *AppContext) fiber.Handler { return ac.AuthHandler.UpdateMe }))
+
+	// API discovery (auth required, filtered by the caller's permissions)
+	protected.Get("/_meta", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Meta }))
+	protected.Get("/permissions/effective", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.EffectivePermissions }))
+
 	// Dynamic entity routes (must be last — catch-all pattern)
 	protected.Get("/:entity", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.List }))
+	protected.Get("/:entity/distinct", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Distinct }))
 	protected.Get("/:entity/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.GetByID }))
 	protected.Post("/:entity", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Create }))
+	protected.Post("/:entity/search", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Search }))
+	protected.Post("/:entity/_bulk", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.BulkCreate }))
 	protected.Put("/:entity/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Update }))
+	protected.Patch("/:entity/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Patch }))
 	protected.Delete("/:entity/:id", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Delete }))
+	protected.Post("/:entity/:id/restore", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.Restore }))
+	protected.Get("/:entity/:id/related/:relation", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.ListRelated }))
+	protected.Post("/:entity/:id/relations/:relation", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.AttachRelated }))
+	protected.Delete("/:entity/:id/relations/:relation/:targetId", dispatch(func(ac *AppContext) fiber.Handler { return ac.EngineHandler.DetachRelated }))
 }
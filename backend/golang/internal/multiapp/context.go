@@ -36,6 +36,14 @@ type AppContext struct {
 
 	// Injected by manager for building AIHandler
 	aiProvider *ai.Provider
+
+	// Injected by manager for building EventHandler
+	eventsPage int
+}
+
+// TokenKeys returns the keys that sign and verify this app's access tokens.
+func (ac *AppContext) TokenKeys() auth.TokenKeys {
+	return auth.TokenKeys{Secret: ac.JWTSecret, Audience: ac.Name}
 }
 
 // BuildHandlers creates all handler instances for this app context.
@@ -43,12 +51,16 @@ func (ac *AppContext) BuildHandlers() {
 	ac.Migrator = store.NewMigrator(ac.Store)
 	ac.EngineHandler = engine.NewHandler(ac.Store, ac.Registry)
 	ac.AdminHandler = admin.NewHandler(ac.Store, ac.Registry, ac.Migrator)
-	ac.AuthHandler = auth.NewAuthHandler(ac.Store, ac.JWTSecret)
+	ac.AuthHandler = auth.NewAuthHandler(ac.Store, ac.TokenKeys())
 	ac.WorkflowHandler = engine.NewWorkflowHandler(ac.Store, ac.Registry)
 	if ac.fileStorage != nil {
 		ac.FileHandler = engine.NewFileHandler(ac.Store, ac.fileStorage, ac.maxFileSize, ac.Name)
+		ac.EngineHandler.SetFileStorage(ac.fileStorage, ac.maxFileSize, ac.Name)
 	}
 	ac.EventHandler = instrument.NewEventHandler(ac.Store.DB, ac.Store.Dialect)
+	if ac.eventsPage > 0 {
+		ac.EventHandler.SetMaxPerPage(ac.eventsPage)
+	}
 	if ac.aiProvider != nil {
 		ac.AIHandler = ai.NewHandler(ac.aiProvider, ac.Registry)
 	}
@@ -6,10 +6,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 
 	"rocket-backend/internal/ai"
 	"rocket-backend/internal/config"
+	"rocket-backend/internal/engine"
 	"rocket-backend/internal/instrument"
 	"rocket-backend/internal/metadata"
 	"rocket-backend/internal/storage"
@@ -27,10 +29,11 @@ type AppManager struct {
 	maxFileSize int64
 	instrConfig config.InstrumentationConfig
 	aiProvider  *ai.Provider
+	bootstrap   config.BootstrapConfig
 }
 
 // NewAppManager creates an AppManager backed by the management database.
-func NewAppManager(mgmtStore *store.Store, dbCfg config.DatabaseConfig, appPoolSize int, fs storage.FileStorage, maxFileSize int64, instrCfg config.InstrumentationConfig, aiCfg config.AIConfig) *AppManager {
+func NewAppManager(mgmtStore *store.Store, dbCfg config.DatabaseConfig, appPoolSize int, fs storage.FileStorage, maxFileSize int64, instrCfg config.InstrumentationConfig, aiCfg config.AIConfig, bootstrapCfg config.BootstrapConfig) *AppManager {
 	return &AppManager{
 		apps:        make(map[string]*AppContext),
 		mgmtStore:   mgmtStore,
@@ -40,6 +43,7 @@ func NewAppManager(mgmtStore *store.Store, dbCfg config.DatabaseConfig, appPoolS
 		maxFileSize: maxFileSize,
 		instrConfig: instrCfg,
 		aiProvider:  ai.NewProvider(aiCfg.BaseURL, aiCfg.APIKey, aiCfg.Model),
+		bootstrap:   bootstrapCfg,
 	}
 }
 
@@ -87,20 +91,21 @@ func (m *AppManager) Create(ctx context.Context, name, displayName, dbDriver str
 	}
 
 	// Connect to the new database using the app's driver
-	appCfg := m.appDBConfig(dbDriver, dbName)
+	appCfg := m.appDBConfig(name, dbDriver, dbName)
 	appStore, err := store.NewWithPoolSize(ctx, appCfg, m.poolSize)
 	if err != nil {
 		return nil, fmt.Errorf("connect to app database %s: %w", dbName, err)
 	}
 
 	// Bootstrap system tables + seed admin user
-	if err := appStore.Bootstrap(ctx); err != nil {
+	if err := appStore.Bootstrap(ctx, m.bootstrap); err != nil {
 		appStore.Close()
 		return nil, fmt.Errorf("bootstrap app %s: %w", name, err)
 	}
 
 	// Build app context
 	reg := metadata.NewRegistry()
+	reg.SetTablePrefix(appStore.TablePrefix)
 	if err := metadata.LoadAll(ctx, appStore.DB, reg); err != nil {
 		log.Printf("WARN: Failed to load metadata for app %s: %v", name, err)
 	}
@@ -114,6 +119,7 @@ func (m *AppManager) Create(ctx context.Context, name, displayName, dbDriver str
 		fileStorage: m.fileStorage,
 		maxFileSize: m.maxFileSize,
 		aiProvider:  m.aiProvider,
+		eventsPage:  m.instrConfig.MaxPageSize,
 	}
 	if m.instrConfig.Enabled {
 		ac.EventBuffer = instrument.NewEventBuffer(appStore.DB, appStore.Dialect, m.instrConfig.BufferSize, m.instrConfig.FlushIntervalMs)
@@ -239,7 +245,7 @@ func (m *AppManager) LoadAll(ctx context.Context) error {
 		}
 		jwtSecret := row["jwt_secret"].(string)
 
-		appCfg := m.appDBConfig(dbDriver, dbName)
+		appCfg := m.appDBConfig(name, dbDriver, dbName)
 		appStore, err := store.NewWithPoolSize(ctx, appCfg, m.poolSize)
 		if err != nil {
 			log.Printf("WARN: Failed to connect to app %s (db: %s): %v", name, dbName, err)
@@ -247,16 +253,18 @@ func (m *AppManager) LoadAll(ctx context.Context) error {
 		}
 
 		// Bootstrap is idempotent
-		if err := appStore.Bootstrap(ctx); err != nil {
+		if err := appStore.Bootstrap(ctx, m.bootstrap); err != nil {
 			log.Printf("WARN: Failed to bootstrap app %s: %v", name, err)
 			appStore.Close()
 			continue
 		}
 
 		reg := metadata.NewRegistry()
+		reg.SetTablePrefix(appStore.TablePrefix)
 		if err := metadata.LoadAll(ctx, appStore.DB, reg); err != nil {
 			log.Printf("WARN: Failed to load metadata for app %s: %v", name, err)
 		}
+		warnPlaintextWebhookSecrets(name, reg)
 
 		ac := &AppContext{
 			Name:        name,
@@ -267,6 +275,7 @@ func (m *AppManager) LoadAll(ctx context.Context) error {
 			fileStorage: m.fileStorage,
 			maxFileSize: m.maxFileSize,
 			aiProvider:  m.aiProvider,
+			eventsPage:  m.instrConfig.MaxPageSize,
 		}
 		if m.instrConfig.Enabled {
 			ac.EventBuffer = instrument.NewEventBuffer(appStore.DB, appStore.Dialect, m.instrConfig.BufferSize, m.instrConfig.FlushIntervalMs)
@@ -329,16 +338,18 @@ func (m *AppManager) initApp(ctx context.Context, appName string) (*AppContext,
 		return nil, fmt.Errorf("app %s is %s", appName, status)
 	}
 
-	appCfg := m.appDBConfig(dbDriver, dbName)
+	appCfg := m.appDBConfig(appName, dbDriver, dbName)
 	appStore, err := store.NewWithPoolSize(ctx, appCfg, m.poolSize)
 	if err != nil {
 		return nil, fmt.Errorf("connect to app %s: %w", appName, err)
 	}
 
 	reg := metadata.NewRegistry()
+	reg.SetTablePrefix(appStore.TablePrefix)
 	if err := metadata.LoadAll(ctx, appStore.DB, reg); err != nil {
 		log.Printf("WARN: Failed to load metadata for app %s: %v", appName, err)
 	}
+	warnPlaintextWebhookSecrets(appName, reg)
 
 	ac := &AppContext{
 		Name:        appName,
@@ -349,6 +360,7 @@ func (m *AppManager) initApp(ctx context.Context, appName string) (*AppContext,
 		fileStorage: m.fileStorage,
 		maxFileSize: m.maxFileSize,
 		aiProvider:  m.aiProvider,
+		eventsPage:  m.instrConfig.MaxPageSize,
 	}
 	if m.instrConfig.Enabled {
 		ac.EventBuffer = instrument.NewEventBuffer(appStore.DB, appStore.Dialect, m.instrConfig.BufferSize, m.instrConfig.FlushIntervalMs)
@@ -359,11 +371,30 @@ func (m *AppManager) initApp(ctx context.Context, appName string) (*AppContext,
 	return ac, nil
 }
 
-// appDBConfig builds a DatabaseConfig for an app database with the given driver.
-func (m *AppManager) appDBConfig(driver, dbName string) config.DatabaseConfig {
+// warnPlaintextWebhookSecrets logs the app's signed webhooks when
+// webhooks.secret_key is empty, since their secrets are stored as plaintext.
+func warnPlaintextWebhookSecrets(appName string, reg *metadata.Registry) {
+	if engine.WebhookSecretKey != "" {
+		return
+	}
+	n := 0
+	for _, wh := range reg.AllWebhooks() {
+		if wh.Secret != "" {
+			n++
+		}
+	}
+	if n > 0 {
+		log.Printf("WARN: App %s has %d signed webhook(s) but webhooks.secret_key is not set; their secrets are stored as plaintext", appName, n)
+	}
+}
+
+// appDBConfig builds a DatabaseConfig for an app database with the given
+// driver, with {app} in the table prefix replaced by the app name.
+func (m *AppManager) appDBConfig(appName, driver, dbName string) config.DatabaseConfig {
 	cfg := m.dbConfig
 	cfg.Driver = driver
 	cfg.Name = dbName
+	cfg.TablePrefix = strings.ReplaceAll(cfg.TablePrefix, "{app}", appName)
 	return cfg
 }
 
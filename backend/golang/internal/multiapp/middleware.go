@@ -54,7 +54,7 @@ func AppAuthMiddleware(platformJWTSecret string) fiber.Handler {
 
 		// Try app-scoped JWT secret first
 		if ac != nil {
-			claims, err := auth.ParseAccessToken(token, ac.JWTSecret)
+			claims, err := ac.TokenKeys().Parse(token)
 			if err == nil {
 				c.Locals("user", &metadata.UserContext{
 					ID:    claims.Subject,
@@ -65,7 +65,7 @@ func AppAuthMiddleware(platformJWTSecret string) fiber.Handler {
 		}
 
 		// Fall back to platform JWT secret
-		claims, err := auth.ParseAccessToken(token, platformJWTSecret)
+		claims, err := platformTokenKeys(platformJWTSecret).Parse(token)
 		if err != nil {
 			return engine.UnauthorizedError("Invalid or expired token")
 		}
@@ -93,7 +93,7 @@ func PlatformAuthMiddleware(platformJWTSecret string) fiber.Handler {
 			return engine.UnauthorizedError("Invalid auth header format")
 		}
 
-		claims, err := auth.ParseAccessToken(parts[1], platformJWTSecret)
+		claims, err := platformTokenKeys(platformJWTSecret).Parse(parts[1])
 		if err != nil {
 			return engine.UnauthorizedError("Invalid or expired token")
 		}
@@ -106,3 +106,28 @@ func PlatformAuthMiddleware(platformJWTSecret string) fiber.Handler {
 		return c.Next()
 	}
 }
+
+func platformTokenKeys(secret string) auth.TokenKeys {
+	return auth.TokenKeys{Secret: secret, Audience: auth.PlatformAudience}
+}
+
+// PlatformAdminRole is the platform user role allowed to manage apps.
+const PlatformAdminRole = "platform_admin"
+
+// RequirePlatformAdmin checks the platform user set by PlatformAuthMiddleware
+// has the platform_admin role. It is separate from the app-level RequireAdmin,
+// whose "admin" role is granted per app and means nothing on the platform.
+func RequirePlatformAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*metadata.UserContext)
+		if !ok || user == nil {
+			return engine.UnauthorizedError("Missing auth token")
+		}
+		for _, r := range user.Roles {
+			if r == PlatformAdminRole {
+				return c.Next()
+			}
+		}
+		return engine.ForbiddenError("Platform admin access required")
+	}
+}
@@ -60,7 +60,7 @@ func seedPlatformAdmin(ctx context.Context, s *store.Store) error {
 	hash := string(hashBytes)
 
 	pb := s.Dialect.NewParamBuilder()
-	rolesParam := s.Dialect.ArrayParam([]string{"platform_admin"})
+	rolesParam := s.Dialect.ArrayParam([]string{PlatformAdminRole})
 	if s.Dialect.UUIDDefault() == "" {
 		// SQLite: generate UUID in Go since there's no gen_random_uuid()
 		id := store.GenerateUUID()
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
-	"time"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
@@ -37,8 +37,8 @@ func RegisterPlatformRoutes(app *fiber.App, h *PlatformHandler, platformAuthMW f
 	pAuth.Post("/refresh", h.Refresh)
 	pAuth.Post("/logout", h.Logout)
 
-	// Platform admin (auth required)
-	pAdmin := app.Group("/api/_platform", platformAuthMW)
+	// Platform admin (auth + platform_admin role required)
+	pAdmin := app.Group("/api/_platform", platformAuthMW, RequirePlatformAdmin())
 	pAdmin.Get("/apps", h.ListApps)
 	pAdmin.Post("/apps", h.CreateApp)
 	pAdmin.Get("/apps/:name", h.GetApp)
@@ -64,7 +64,7 @@ func (h *PlatformHandler) Login(c *fiber.Ctx) error {
 
 	pb := h.store.Dialect.NewParamBuilder()
 	user, err := store.QueryRow(ctx, h.store.DB,
-		fmt.Sprintf("SELECT id, email, password_hash, roles, active FROM _platform_users WHERE email = %s", pb.Add(body.Email)),
+		fmt.Sprintf("SELECT id, email, password_hash, roles, active FROM _platform_users WHERE LOWER(email) = LOWER(%s)", pb.Add(strings.TrimSpace(body.Email))),
 		pb.Params()...)
 	if err != nil {
 		return engine.UnauthorizedError("Invalid email or password")
@@ -104,40 +104,11 @@ func (h *PlatformHandler) Refresh(c *fiber.Ctx) error {
 
 	ctx := c.Context()
 
-	pb := h.store.Dialect.NewParamBuilder()
-	row, err := store.QueryRow(ctx, h.store.DB,
-		fmt.Sprintf(`SELECT rt.id, rt.user_id, rt.expires_at, u.roles, u.active
-		 FROM _platform_refresh_tokens rt
-		 JOIN _platform_users u ON u.id = rt.user_id
-		 WHERE rt.token = %s`, pb.Add(body.RefreshToken)),
-		pb.Params()...)
+	userID, roles, err := auth.RotateRefreshToken(ctx, h.store, auth.PlatformRefreshTables, body.RefreshToken)
 	if err != nil {
-		return engine.UnauthorizedError("Invalid refresh token")
-	}
-
-	expiresAt, _ := row["expires_at"].(time.Time)
-	if time.Now().After(expiresAt) {
-		pb2 := h.store.Dialect.NewParamBuilder()
-		_, _ = store.Exec(ctx, h.store.DB,
-			fmt.Sprintf("DELETE FROM _platform_refresh_tokens WHERE token = %s", pb2.Add(body.RefreshToken)),
-			pb2.Params()...)
-		return engine.UnauthorizedError("Refresh token expired")
-	}
-
-	active := toBool(row["active"])
-	if !active {
-		return engine.UnauthorizedError("Account is disabled")
+		return err
 	}
 
-	tokenID, _ := row["id"].(string)
-	pb3 := h.store.Dialect.NewParamBuilder()
-	_, _ = store.Exec(ctx, h.store.DB,
-		fmt.Sprintf("DELETE FROM _platform_refresh_tokens WHERE id = %s", pb3.Add(tokenID)),
-		pb3.Params()...)
-
-	userID, _ := row["user_id"].(string)
-	roles := extractRoles(row["roles"])
-
 	pair, err := h.generateTokenPair(ctx, userID, roles)
 	if err != nil {
 		return err
@@ -157,10 +128,7 @@ func (h *PlatformHandler) Logout(c *fiber.Ctx) error {
 		return engine.UnauthorizedError("Refresh token is required")
 	}
 
-	pb := h.store.Dialect.NewParamBuilder()
-	_, _ = store.Exec(c.Context(), h.store.DB,
-		fmt.Sprintf("DELETE FROM _platform_refresh_tokens WHERE token = %s", pb.Add(body.RefreshToken)),
-		pb.Params()...)
+	auth.RevokeRefreshToken(c.Context(), h.store, auth.PlatformRefreshTables, body.RefreshToken)
 
 	return c.JSON(fiber.Map{"message": "Logged out"})
 }
@@ -248,27 +216,7 @@ func (h *PlatformHandler) DeleteApp(c *fiber.Ctx) error {
 // --- helpers ---
 
 func (h *PlatformHandler) generateTokenPair(ctx context.Context, userID string, roles []string) (*auth.TokenPair, error) {
-	accessToken, err := auth.GenerateAccessToken(userID, roles, h.jwtSecret)
-	if err != nil {
-		return nil, engine.NewAppError("INTERNAL_ERROR", 500, "Failed to generate access token")
-	}
-
-	refreshToken := auth.GenerateRefreshToken()
-	expiresAt := time.Now().Add(auth.RefreshTokenTTL)
-
-	pb := h.store.Dialect.NewParamBuilder()
-	_, err = store.Exec(ctx, h.store.DB,
-		fmt.Sprintf(`INSERT INTO _platform_refresh_tokens (user_id, token, expires_at) VALUES (%s, %s, %s)`,
-			pb.Add(userID), pb.Add(refreshToken), pb.Add(expiresAt)),
-		pb.Params()...)
-	if err != nil {
-		return nil, engine.NewAppError("INTERNAL_ERROR", 500, "Failed to store refresh token")
-	}
-
-	return &auth.TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	return auth.IssueTokenPair(ctx, h.store, auth.PlatformRefreshTables, platformTokenKeys(h.jwtSecret), userID, roles)
 }
 
 func extractRoles(v any) []string {
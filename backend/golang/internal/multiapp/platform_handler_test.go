@@ -0,0 +1,120 @@
+package multiapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/auth"
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/store"
+)
+
+func TestPlatformAuth_AdminLoginGuardsAppRoutes(t *testing.T) {
+	ctx := context.Background()
+	dbCfg := config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "platform"}
+	s, err := store.New(ctx, dbCfg)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	// Seeds platform@localhost / changeme with platform_admin
+	if err := PlatformBootstrap(ctx, s); err != nil {
+		t.Fatalf("platform bootstrap: %v", err)
+	}
+
+	hash, err := auth.HashPassword("viewerpass1")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	pb := s.Dialect.NewParamBuilder()
+	if _, err := store.Exec(ctx, s.DB,
+		fmt.Sprintf("INSERT INTO _platform_users (id, email, password_hash, roles) VALUES (%s, %s, %s, %s)",
+			pb.Add(store.GenerateUUID()), pb.Add("viewer@localhost"), pb.Add(hash), pb.Add(s.Dialect.ArrayParam([]string{"viewer"}))),
+		pb.Params()...); err != nil {
+		t.Fatalf("insert viewer: %v", err)
+	}
+
+	const secret = "platform-secret"
+	mgr := NewAppManager(s, dbCfg, 1, nil, 0, config.InstrumentationConfig{}, config.AIConfig{}, config.BootstrapConfig{})
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if appErr, ok := err.(*engine.AppError); ok {
+				return c.Status(appErr.Status).JSON(engine.ErrorResponse{Error: appErr})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		},
+	})
+	RegisterPlatformRoutes(app, NewPlatformHandler(s, secret, mgr, config.AIConfig{}), PlatformAuthMiddleware(secret))
+
+	do := func(method, path, token string, body any) (int, map[string]any) {
+		var r io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			r = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	login := func(email, password string) map[string]any {
+		status, out := do("POST", "/api/_platform/auth/login", "", map[string]any{"email": email, "password": password})
+		if status != 200 {
+			t.Fatalf("login %s: %d %v", email, status, out)
+		}
+		return out["data"].(map[string]any)
+	}
+
+	pair := login("platform@localhost", "changeme")
+	if status, out := do("GET", "/api/_platform/apps", pair["access_token"].(string), nil); status != 200 {
+		t.Fatalf("expected the platform admin to list apps, got %d: %v", status, out)
+	}
+	if status, _ := do("GET", "/api/_platform/apps", "", nil); status != 401 {
+		t.Fatalf("expected 401 without a token, got %d", status)
+	}
+
+	viewer := login("viewer@localhost", "viewerpass1")
+	if status, _ := do("POST", "/api/_platform/apps", viewer["access_token"].(string), map[string]any{"name": "shop"}); status != 403 {
+		t.Fatalf("expected a non-admin platform user to be refused, got %d", status)
+	}
+
+	// App-level admin tokens are signed with another secret and never pass
+	appToken, _ := auth.GenerateAccessToken("u1", []string{"admin"}, "app-secret")
+	if status, _ := do("GET", "/api/_platform/apps", appToken, nil); status != 401 {
+		t.Fatalf("expected an app token to be rejected, got %d", status)
+	}
+
+	// Refresh rotates: the old refresh token stops working
+	status, out := do("POST", "/api/_platform/auth/refresh", "", map[string]any{"refresh_token": pair["refresh_token"]})
+	if status != 200 {
+		t.Fatalf("refresh: %d %v", status, out)
+	}
+	rotated := out["data"].(map[string]any)
+	if status, _ := do("POST", "/api/_platform/auth/refresh", "", map[string]any{"refresh_token": pair["refresh_token"]}); status != 401 {
+		t.Fatalf("expected a used refresh token to be refused, got %d", status)
+	}
+	if status, _ := do("GET", "/api/_platform/apps", rotated["access_token"].(string), nil); status != 200 {
+		t.Fatalf("expected the refreshed token to work, got %d", status)
+	}
+
+	do("POST", "/api/_platform/auth/logout", "", map[string]any{"refresh_token": rotated["refresh_token"]})
+	if status, _ := do("POST", "/api/_platform/auth/refresh", "", map[string]any{"refresh_token": rotated["refresh_token"]}); status != 401 {
+		t.Fatalf("expected logout to revoke the refresh token, got %d", status)
+	}
+}
@@ -0,0 +1,84 @@
+package multiapp
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"rocket-backend/internal/store"
+)
+
+// QueryTagMiddleware tags the request's queries with its method, route pattern
+// and trace ID (database.query_tags) so they can be matched up in
+// pg_stat_activity. A missing or malformed X-Trace-ID (anything beyond
+// letters, digits and dashes) is replaced with a generated one and written back
+// to the request, so the instrumentation trace uses the same ID. The raw path
+// never reaches the tag; only the matched route pattern does.
+func QueryTagMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		traceID := c.Get("X-Trace-ID")
+		if !validTraceID(traceID) {
+			traceID = uuid.New().String()
+			c.Request().Header.Set("X-Trace-ID", traceID)
+		}
+		tag := &routeQueryTag{c: c, method: c.Method(), traceID: traceID}
+
+		c.Context().SetUserValue(store.QueryTagKey, tag)
+		c.SetUserContext(store.WithQueryTag(c.UserContext(), tag))
+		err := c.Next()
+		// The route is final now; pin the tag before the Fiber context is
+		// recycled, in case a background goroutine still holds the request's
+		// context.
+		tag.pin()
+		return err
+	}
+}
+
+// routeQueryTag resolves the route pattern each time it is used while the
+// request runs: inside a Use middleware c.Route() is still the middleware's own
+// prefix, the final route is only known once the router has dispatched to the
+// handler. A query run from an earlier middleware therefore doesn't fix the
+// route for later ones.
+type routeQueryTag struct {
+	mu      sync.Mutex
+	c       *fiber.Ctx
+	method  string
+	traceID string
+	tag     string
+}
+
+func (t *routeQueryTag) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.c == nil {
+		return t.tag
+	}
+	return t.format()
+}
+
+// pin fixes the tag at the final route and drops the Fiber context.
+func (t *routeQueryTag) pin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.c != nil {
+		t.tag = t.format()
+		t.c = nil
+	}
+}
+
+func (t *routeQueryTag) format() string {
+	return "rocket " + t.method + " " + t.c.Route().Path + " trace=" + t.traceID
+}
+
+func validTraceID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
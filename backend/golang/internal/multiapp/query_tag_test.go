@@ -0,0 +1,92 @@
+package multiapp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/store"
+)
+
+func queryTagApp(t *testing.T) *fiber.App {
+	app := fiber.New()
+	app.Use(QueryTagMiddleware())
+	app.Get("/api/:app/orders", func(c *fiber.Ctx) error {
+		user, _ := c.UserContext().Value(store.QueryTagKey).(fmt.Stringer)
+		fast, _ := c.Context().Value(store.QueryTagKey).(fmt.Stringer)
+		if user == nil || fast == nil || user.String() != fast.String() {
+			t.Errorf("user context tag %v differs from request context tag %v", user, fast)
+			return c.SendString("")
+		}
+		return c.SendString(user.String())
+	})
+	return app
+}
+
+func TestQueryTagMiddleware_TagsBothContexts(t *testing.T) {
+	app := queryTagApp(t)
+
+	req, _ := http.NewRequest("GET", "/api/shop/orders", nil)
+	req.Header.Set("X-Trace-ID", "abc")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if got := string(body); got != "rocket GET /api/:app/orders trace=abc" {
+		t.Fatalf("unexpected tag %q", got)
+	}
+}
+
+func TestQueryTagMiddleware_ReplacesMalformedTraceID(t *testing.T) {
+	app := queryTagApp(t)
+
+	req, _ := http.NewRequest("GET", "/api/shop/orders", nil)
+	req.Header.Set("X-Trace-ID", "x **//; DROP TABLE _users; --")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	got := string(body)
+	if !strings.HasPrefix(got, "rocket GET /api/:app/orders trace=") || strings.Contains(got, "DROP") {
+		t.Fatalf("malformed trace id reached the tag: %q", got)
+	}
+}
+
+func TestQueryTagMiddleware_EarlyUseDoesNotFixTheRoute(t *testing.T) {
+	app := fiber.New()
+	app.Use(QueryTagMiddleware())
+	var early string
+	// Like an auth middleware that queries before the route is dispatched
+	app.Use(func(c *fiber.Ctx) error {
+		early = c.UserContext().Value(store.QueryTagKey).(fmt.Stringer).String()
+		return c.Next()
+	})
+	var tag fmt.Stringer
+	app.Get("/api/:app/orders", func(c *fiber.Ctx) error {
+		tag = c.UserContext().Value(store.QueryTagKey).(fmt.Stringer)
+		return c.SendString(tag.String())
+	})
+
+	req, _ := http.NewRequest("GET", "/api/shop/orders", nil)
+	req.Header.Set("X-Trace-ID", "abc")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(early, "/api/:app/orders") {
+		t.Fatalf("expected the middleware's own route before dispatch, got %q", early)
+	}
+	if got := string(body); got != "rocket GET /api/:app/orders trace=abc" {
+		t.Fatalf("expected the handler's queries to carry the final route, got %q", got)
+	}
+	if got := tag.String(); got != "rocket GET /api/:app/orders trace=abc" {
+		t.Fatalf("expected the tag to stay pinned after the request, got %q", got)
+	}
+}
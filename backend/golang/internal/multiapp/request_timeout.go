@@ -0,0 +1,53 @@
+package multiapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/store"
+)
+
+// RequestTimeoutMiddleware gives each request an overall deadline
+// (server.request_timeout_ms). The deadline is set on the Fiber user context
+// and, for handlers that pass the fasthttp request context down, stored under
+// store.DeadlineKey so their queries are cancelled too. A handler that fails
+// after the deadline gets a 503; one that finished its work is left alone.
+// Paths containing one of exclude (streams, file transfers) run without a
+// deadline; what the client asks for doesn't matter.
+//
+// The budget is cooperative: it cancels contexts, it doesn't stop handlers. A
+// handler that never checks its context runs to completion, and its response
+// is sent even if that is after the deadline.
+func RequestTimeoutMiddleware(timeout time.Duration, exclude []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if isExcludedRoute(c, exclude) {
+			return c.Next()
+		}
+		deadline := time.Now().Add(timeout)
+		ctx, cancel := context.WithDeadline(c.UserContext(), deadline)
+		defer cancel()
+		c.SetUserContext(ctx)
+		c.Context().SetUserValue(store.DeadlineKey, deadline)
+
+		err := c.Next()
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return engine.NewAppError("REQUEST_TIMEOUT", fiber.StatusServiceUnavailable, "Request timed out")
+		}
+		return err
+	}
+}
+
+func isExcludedRoute(c *fiber.Ctx, exclude []string) bool {
+	path := c.Path()
+	for _, fragment := range exclude {
+		if fragment != "" && strings.Contains(path, fragment) {
+			return true
+		}
+	}
+	return false
+}
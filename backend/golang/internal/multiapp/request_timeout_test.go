@@ -0,0 +1,82 @@
+package multiapp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/engine"
+	"rocket-backend/internal/store"
+)
+
+func TestRequestTimeoutMiddleware_CutsOffSlowHandlers(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if appErr, ok := err.(*engine.AppError); ok {
+				return c.Status(appErr.Status).JSON(engine.ErrorResponse{Error: appErr})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		},
+	})
+	app.Use(RequestTimeoutMiddleware(50*time.Millisecond, []string{"/_files/"}))
+	// Waits on the user context, as handlers calling out to other services do
+	app.Get("/api/shop/wait", func(c *fiber.Ctx) error {
+		select {
+		case <-time.After(5 * time.Second):
+			return c.SendString("done")
+		case <-c.UserContext().Done():
+			return c.UserContext().Err()
+		}
+	})
+	// A query that never finishes on its own, run on the fasthttp request context
+	app.Get("/api/shop/query", func(c *fiber.Ctx) error {
+		_, err := store.QueryRows(c.Context(), s.DB,
+			"WITH RECURSIVE r(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM r) SELECT COUNT(*) FROM r")
+		return err
+	})
+	app.Get("/api/shop/_files/:id", func(c *fiber.Ctx) error {
+		time.Sleep(100 * time.Millisecond)
+		return c.SendString("file")
+	})
+
+	get := func(path string, headers ...string) int {
+		t.Helper()
+		req, _ := http.NewRequest("GET", path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		start := time.Now()
+		resp, err := app.Test(req, 5000)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("GET %s took %v, expected it cut off", path, elapsed)
+		}
+		return resp.StatusCode
+	}
+
+	if status := get("/api/shop/wait"); status != 503 {
+		t.Fatalf("expected a slow handler to get 503, got %d", status)
+	}
+	if status := get("/api/shop/wait", "Accept", "text/event-stream"); status != 503 {
+		t.Fatalf("expected an event-stream Accept header not to lift the deadline, got %d", status)
+	}
+	if status := get("/api/shop/query"); status != 503 {
+		t.Fatalf("expected a slow query to be cancelled with 503, got %d", status)
+	}
+	if status := get("/api/shop/_files/abc"); status != 200 {
+		t.Fatalf("expected excluded paths to run past the deadline, got %d", status)
+	}
+}
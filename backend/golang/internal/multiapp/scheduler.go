@@ -2,6 +2,8 @@ package multiapp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -10,18 +12,21 @@ import (
 	"rocket-backend/internal/instrument"
 )
 
-// MultiAppScheduler runs workflow timeouts, webhook retries, and event cleanup across all apps.
+// MultiAppScheduler runs workflow timeouts, webhook retries, and event/webhook log cleanup across all apps.
 type MultiAppScheduler struct {
 	manager        *AppManager
 	instrConfig    config.InstrumentationConfig
+	webhookConfig  config.WebhookConfig
 	workflowTicker *time.Ticker
 	webhookTicker  *time.Ticker
 	cleanupTicker  *time.Ticker
+	workflowHealth *engine.SchedulerHealth
+	webhookHealth  *engine.SchedulerHealth
 	done           chan struct{}
 }
 
-func NewMultiAppScheduler(manager *AppManager, instrCfg config.InstrumentationConfig) *MultiAppScheduler {
-	return &MultiAppScheduler{manager: manager, instrConfig: instrCfg}
+func NewMultiAppScheduler(manager *AppManager, instrCfg config.InstrumentationConfig, webhookCfg config.WebhookConfig) *MultiAppScheduler {
+	return &MultiAppScheduler{manager: manager, instrConfig: instrCfg, webhookConfig: webhookCfg}
 }
 
 // Start begins background tickers for all apps.
@@ -29,11 +34,11 @@ func (s *MultiAppScheduler) Start() {
 	s.done = make(chan struct{})
 	s.workflowTicker = time.NewTicker(60 * time.Second)
 	s.webhookTicker = time.NewTicker(30 * time.Second)
-	if s.instrConfig.Enabled {
-		s.cleanupTicker = time.NewTicker(1 * time.Hour)
-	}
+	s.cleanupTicker = time.NewTicker(1 * time.Hour)
+	s.workflowHealth = engine.TrackScheduler("workflows", 60*time.Second)
+	s.webhookHealth = engine.TrackScheduler("webhooks", 30*time.Second)
 	go s.run()
-	log.Println("Multi-app scheduler started (workflows: 60s, webhooks: 30s, event cleanup: 1h)")
+	log.Println("Multi-app scheduler started (workflows: 60s, webhooks: 30s, event/webhook log cleanup: 1h)")
 }
 
 // Stop halts all background tickers.
@@ -50,39 +55,53 @@ func (s *MultiAppScheduler) Stop() {
 	if s.done != nil {
 		close(s.done)
 	}
+	engine.UntrackScheduler("workflows")
+	engine.UntrackScheduler("webhooks")
 }
 
 func (s *MultiAppScheduler) run() {
-	// If cleanup ticker is nil (instrumentation disabled), use a stopped channel
-	var cleanupCh <-chan time.Time
-	if s.cleanupTicker != nil {
-		cleanupCh = s.cleanupTicker.C
-	}
-
 	for {
 		select {
 		case <-s.done:
 			return
 		case <-s.workflowTicker.C:
-			s.processAllWorkflowTimeouts()
+			s.workflowHealth.Record(s.processAllWorkflowTimeouts())
 		case <-s.webhookTicker.C:
-			s.processAllWebhookRetries()
-		case <-cleanupCh:
-			s.processAllEventCleanup()
+			s.webhookHealth.Record(s.processAllWebhookRetries())
+		case <-s.cleanupTicker.C:
+			if s.instrConfig.Enabled {
+				s.processAllEventCleanup()
+			}
+			s.processAllWebhookLogCleanup()
 		}
 	}
 }
 
-func (s *MultiAppScheduler) processAllWorkflowTimeouts() {
+func (s *MultiAppScheduler) processAllWorkflowTimeouts() (int, error) {
+	total := 0
+	var errs []error
 	for _, ac := range s.manager.AllContexts() {
-		engine.ProcessWorkflowTimeouts(ac.Store, ac.Registry)
+		n, err := engine.ProcessWorkflowTimeouts(ac.Store, ac.Registry)
+		total += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("app %s: %w", ac.Name, err))
+		}
 	}
+	return total, errors.Join(errs...)
 }
 
-func (s *MultiAppScheduler) processAllWebhookRetries() {
+func (s *MultiAppScheduler) processAllWebhookRetries() (int, error) {
+	total := 0
+	var errs []error
 	for _, ac := range s.manager.AllContexts() {
-		engine.ProcessWebhookRetries(ac.Store)
+		n, err := engine.ProcessWebhookRetries(ac.Store, ac.Registry)
+		total += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("app %s: %w", ac.Name, err))
+		}
+		engine.ProcessWebhookBatches(ac.Store, ac.Registry)
 	}
+	return total, errors.Join(errs...)
 }
 
 func (s *MultiAppScheduler) processAllEventCleanup() {
@@ -91,3 +110,10 @@ func (s *MultiAppScheduler) processAllEventCleanup() {
 		instrument.CleanupOldEvents(ctx, ac.Store.DB, ac.Store.Dialect, s.instrConfig.RetentionDays)
 	}
 }
+
+func (s *MultiAppScheduler) processAllWebhookLogCleanup() {
+	ctx := context.Background()
+	for _, ac := range s.manager.AllContexts() {
+		engine.CleanupWebhookLogs(ctx, ac.Store, s.webhookConfig.LogRetentionDays, s.webhookConfig.FailedLogRetentionDays)
+	}
+}
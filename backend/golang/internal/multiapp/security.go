@@ -0,0 +1,47 @@
+package multiapp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+)
+
+// SecurityHeadersMiddleware adds the configured security headers once the
+// handler has run, keeping any the handler set itself. File downloads and
+// event streams skip X-Frame-Options and the CSP so they can still be
+// embedded; HSTS is only sent on HTTPS requests.
+func SecurityHeadersMiddleware(cfg config.SecurityConfig) fiber.Handler {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d; includeSubDomains", cfg.HSTSMaxAge)
+	}
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		setDefault := func(key, value string) {
+			if value != "" && len(c.Response().Header.Peek(key)) == 0 {
+				c.Set(key, value)
+			}
+		}
+		setDefault("X-Content-Type-Options", "nosniff")
+		setDefault("Referrer-Policy", cfg.ReferrerPolicy)
+		if !isFileOrStream(c) {
+			setDefault("X-Frame-Options", cfg.FrameOptions)
+			setDefault("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if c.Protocol() == "https" {
+			setDefault("Strict-Transport-Security", hsts)
+		}
+		return err
+	}
+}
+
+func isFileOrStream(c *fiber.Ctx) bool {
+	if len(c.Response().Header.Peek(fiber.HeaderContentDisposition)) > 0 {
+		return true
+	}
+	return strings.HasPrefix(string(c.Response().Header.ContentType()), "text/event-stream")
+}
@@ -0,0 +1,69 @@
+package multiapp
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"rocket-backend/internal/config"
+)
+
+func TestSecurityHeadersMiddleware_SetsHeaders(t *testing.T) {
+	app := fiber.New()
+	app.Use(SecurityHeadersMiddleware(config.SecurityConfig{
+		Headers:               true,
+		FrameOptions:          "DENY",
+		ContentSecurityPolicy: "default-src 'none'",
+		ReferrerPolicy:        "no-referrer",
+		HSTSMaxAge:            3600,
+	}))
+	app.Get("/data", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"data": "ok"})
+	})
+	app.Get("/file", func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "application/pdf")
+		c.Set("Content-Disposition", `inline; filename="a.pdf"`)
+		return c.SendString("%PDF")
+	})
+
+	get := func(path, proto string) http.Header {
+		t.Helper()
+		req, _ := http.NewRequest("GET", path, nil)
+		if proto != "" {
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp.Header
+	}
+
+	h := get("/data", "")
+	for key, want := range map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Content-Security-Policy": "default-src 'none'",
+		"Referrer-Policy":         "no-referrer",
+	} {
+		if got := h.Get(key); got != want {
+			t.Fatalf("expected %s %q, got %q", key, want, got)
+		}
+	}
+	if h.Get("Strict-Transport-Security") != "" {
+		t.Fatal("expected no HSTS over plain HTTP")
+	}
+	if got := get("/data", "https").Get("Strict-Transport-Security"); got != "max-age=3600; includeSubDomains" {
+		t.Fatalf("expected HSTS over HTTPS, got %q", got)
+	}
+
+	// Files can still be framed, and keep their content type
+	h = get("/file", "")
+	if h.Get("X-Frame-Options") != "" || h.Get("Content-Security-Policy") != "" {
+		t.Fatalf("expected no framing headers on a file download, got %v", h)
+	}
+	if h.Get("X-Content-Type-Options") != "nosniff" || h.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("expected nosniff with the file's own content type, got %v", h)
+	}
+}
@@ -7,21 +7,119 @@ import (
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+)
+
+// Insecure fallbacks used when the bootstrap config leaves the admin unset.
+const (
+	defaultAdminEmail    = "admin@localhost"
+	defaultAdminPassword = "changeme"
 )
 
-// Bootstrap creates all system tables and seeds the admin user.
-func (s *Store) Bootstrap(ctx context.Context) error {
+// Bootstrap creates all system tables, seeds _roles and, if no users exist yet,
+// creates the bootstrap admin user described by seed.
+func (s *Store) Bootstrap(ctx context.Context, seed config.BootstrapConfig) error {
 	ddl := s.Dialect.SystemTablesSQL()
 	if _, err := s.DB.ExecContext(ctx, ddl); err != nil {
 		return fmt.Errorf("bootstrap system tables: %w", err)
 	}
-	if err := s.seedAdminUser(ctx); err != nil {
+	if err := s.migrateSystemTables(ctx); err != nil {
+		return fmt.Errorf("migrate system tables: %w", err)
+	}
+	s.ensureEmailIndex(ctx)
+	if len(seed.AdminRoles) == 0 {
+		seed.AdminRoles = []string{"admin"}
+	}
+	if err := s.seedRoles(ctx, append(append([]string{}, seed.AdminRoles...), seed.Roles...)); err != nil {
+		return fmt.Errorf("seed roles: %w", err)
+	}
+	if err := s.seedRolesInUse(ctx); err != nil {
+		return fmt.Errorf("seed roles in use: %w", err)
+	}
+	if err := s.seedAdminUser(ctx, seed); err != nil {
 		return fmt.Errorf("seed admin user: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) seedAdminUser(ctx context.Context) error {
+// migrateSystemTables adds columns that were introduced after the initial schema.
+func (s *Store) migrateSystemTables(ctx context.Context) error {
+	additions := []struct {
+		table, column, def string
+	}{
+		{"_permissions", "effect", "TEXT NOT NULL DEFAULT 'allow'"},
+		{"_users", "metadata", s.Dialect.ColumnType("json", 0) + " DEFAULT '{}'"},
+		{"_users", "last_login_at", s.Dialect.ColumnType("timestamp", 0)},
+		{"_users", "attributes", s.Dialect.ColumnType("json", 0) + " DEFAULT '{}'"},
+		{"_users", "password_reset_required", s.Dialect.ColumnType("boolean", 0) + " NOT NULL DEFAULT false"},
+		{"_workflow_instances", "entity", "TEXT"},
+		{"_workflow_instances", "record_id", "TEXT"},
+		{"_workflow_instances", "callback_token", "TEXT"},
+		{"_workflow_instances", "attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"_workflow_instances", "max_attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"_workflow_instances", "last_error", "TEXT"},
+		{"_workflow_instances", "next_retry_at", s.Dialect.ColumnType("timestamp", 0)},
+		{"_webhooks", "batch", s.Dialect.ColumnType("json", 0)},
+		{"_webhooks", "transport", "TEXT NOT NULL DEFAULT 'http'"},
+		{"_webhooks", "transport_config", s.Dialect.ColumnType("json", 0)},
+		{"_rules", "tags", s.Dialect.ColumnType("array", 0)},
+		{"_workflows", "tags", s.Dialect.ColumnType("array", 0)},
+		{"_webhooks", "tags", s.Dialect.ColumnType("array", 0)},
+		{"_webhooks", "secret", "TEXT"},
+	}
+	for _, a := range additions {
+		cols, err := s.Dialect.GetColumns(ctx, s.DB, a.table)
+		if err != nil {
+			continue // table may not exist yet, CREATE TABLE IF NOT EXISTS handles it
+		}
+		if _, ok := cols[a.column]; ok {
+			continue
+		}
+		if _, err := s.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", a.table, a.column, a.def)); err != nil {
+			return fmt.Errorf("add %s.%s column: %w", a.table, a.column, err)
+		}
+		log.Printf("Migrated %s: added %s column", a.table, a.column)
+	}
+	return nil
+}
+
+// seedRoles inserts any of the given roles missing from _roles.
+func (s *Store) seedRoles(ctx context.Context, roles []string) error {
+	for _, role := range roles {
+		if role == "" {
+			continue
+		}
+		pb := s.Dialect.NewParamBuilder()
+		if _, err := s.DB.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO _roles (name) VALUES (%s) ON CONFLICT (name) DO NOTHING", pb.Add(role)),
+			pb.Params()...); err != nil {
+			return fmt.Errorf("insert role %s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// seedRolesInUse adds the roles already given to users, invites and
+// permissions to _roles, so databases created before role names were checked
+// against it keep working.
+func (s *Store) seedRolesInUse(ctx context.Context) error {
+	for _, table := range []string{"_users", "_invites", "_permissions"} {
+		rows, err := QueryRows(ctx, s.DB, "SELECT DISTINCT roles FROM "+table)
+		if err != nil {
+			return fmt.Errorf("read %s roles: %w", table, err)
+		}
+		for _, row := range rows {
+			if err := s.seedRoles(ctx, metadata.ParseStringArray(row["roles"])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) seedAdminUser(ctx context.Context, seed config.BootstrapConfig) error {
 	var count int
 	err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM _users").Scan(&count)
 	if err != nil {
@@ -31,28 +129,37 @@ func (s *Store) seedAdminUser(ctx context.Context) error {
 		return nil
 	}
 
-	hashBytes, err := bcrypt.GenerateFromPassword([]byte("changeme"), bcrypt.DefaultCost)
+	email := NormalizeEmail(seed.AdminEmail)
+	if email == "" {
+		email = defaultAdminEmail
+	}
+	password := seed.AdminPassword
+	if password == "" {
+		password = defaultAdminPassword
+	}
+
+	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	hash := string(hashBytes)
 
 	pb := s.Dialect.NewParamBuilder()
-	rolesParam := s.Dialect.ArrayParam([]string{"admin"})
+	rolesParam := s.Dialect.ArrayParam(seed.AdminRoles)
 
 	if s.Dialect.Name() == "sqlite" {
 		// SQLite: generate UUID in Go, roles as JSON string
 		id := uuid.New().String()
 		sqlStr := fmt.Sprintf(
 			"INSERT INTO _users (id, email, password_hash, roles) VALUES (%s, %s, %s, %s)",
-			pb.Add(id), pb.Add("admin@localhost"), pb.Add(hash), pb.Add(rolesParam),
+			pb.Add(id), pb.Add(email), pb.Add(hash), pb.Add(rolesParam),
 		)
 		_, err = s.DB.ExecContext(ctx, sqlStr, pb.Params()...)
 	} else {
 		// PostgreSQL: let gen_random_uuid() handle the ID
 		sqlStr := fmt.Sprintf(
 			"INSERT INTO _users (email, password_hash, roles) VALUES (%s, %s, %s)",
-			pb.Add("admin@localhost"), pb.Add(hash), pb.Add(rolesParam),
+			pb.Add(email), pb.Add(hash), pb.Add(rolesParam),
 		)
 		_, err = s.DB.ExecContext(ctx, sqlStr, pb.Params()...)
 	}
@@ -61,6 +168,10 @@ func (s *Store) seedAdminUser(ctx context.Context) error {
 		return err
 	}
 
-	log.Println("WARNING: Default admin user created (admin@localhost / changeme) — change the password immediately.")
+	if password == defaultAdminPassword {
+		log.Printf("WARNING: Bootstrap admin user created (%s / %s) — set bootstrap.admin_password or change the password immediately.", email, defaultAdminPassword)
+	} else {
+		log.Printf("Bootstrap admin user created (%s)", email)
+	}
 	return nil
 }
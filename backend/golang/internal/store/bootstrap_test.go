@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"rocket-backend/internal/config"
+)
+
+func TestBootstrap_CreatesConfiguredAdminOnEmptyDatabase(t *testing.T) {
+	ctx := context.Background()
+	s := testSQLiteStore(t)
+	seed := config.BootstrapConfig{
+		AdminEmail:    "ops@example.com",
+		AdminPassword: "s3cret-pass",
+		AdminRoles:    []string{"admin", "ops"},
+		Roles:         []string{"viewer"},
+	}
+	if err := s.Bootstrap(ctx, seed); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	rows, err := QueryRows(ctx, s.DB, "SELECT email, password_hash, roles FROM _users")
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("expected exactly one user, got %v (%v)", rows, err)
+	}
+	if rows[0]["email"] != "ops@example.com" {
+		t.Fatalf("expected configured admin email, got %v", rows[0]["email"])
+	}
+	if bcrypt.CompareHashAndPassword([]byte(rows[0]["password_hash"].(string)), []byte("s3cret-pass")) != nil {
+		t.Fatal("expected configured admin password")
+	}
+	if rows[0]["roles"] != `["admin","ops"]` {
+		t.Fatalf("expected configured admin roles, got %v", rows[0]["roles"])
+	}
+
+	row, err := QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM _roles WHERE name IN ('admin', 'ops', 'viewer')")
+	if err != nil || row["n"] != int64(3) {
+		t.Fatalf("expected seeded roles, got %v (%v)", row, err)
+	}
+
+	// Re-bootstrapping with users present leaves them alone
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{AdminEmail: "other@example.com"}); err != nil {
+		t.Fatalf("re-bootstrap: %v", err)
+	}
+	row, _ = QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM _users")
+	if row["n"] != int64(1) {
+		t.Fatalf("expected no additional admin, got %v users", row["n"])
+	}
+}
+
+func TestBootstrap_SeedsRolesAlreadyInUse(t *testing.T) {
+	ctx := context.Background()
+	s := testSQLiteStore(t)
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	// A permission saved before role names were checked against _roles
+	if _, err := Exec(ctx, s.DB,
+		`INSERT INTO _permissions (id, entity, action, roles) VALUES ('p1', 'post', 'read', '["legacy"]')`); err != nil {
+		t.Fatalf("insert permission: %v", err)
+	}
+	if err := s.Bootstrap(ctx, config.BootstrapConfig{}); err != nil {
+		t.Fatalf("re-bootstrap: %v", err)
+	}
+	row, err := QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM _roles WHERE name = 'legacy'")
+	if err != nil || row["n"] != int64(1) {
+		t.Fatalf("expected the role in use to be seeded, got %v (%v)", row, err)
+	}
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type deadlineKey struct{}
+
+// DeadlineKey is the context key holding a request's deadline (a time.Time).
+// The fasthttp request context handlers often pass down never reports a
+// deadline of its own, so the timeout middleware stores it here and
+// QueryRows, QueryRow and Exec apply it to each statement.
+var DeadlineKey = deadlineKey{}
+
+// withRequestDeadline bounds ctx by the request deadline stored under
+// DeadlineKey, unless ctx already carries a deadline.
+func withRequestDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	deadline, ok := ctx.Value(DeadlineKey).(time.Time)
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // Dialect abstracts database-specific SQL generation and behavior.
@@ -23,10 +25,19 @@ type Dialect interface {
 	// NowExpr returns the SQL expression for the current timestamp.
 	NowExpr() string
 
+	// TimeParam encodes a time for comparison with timestamp columns.
+	// PostgreSQL binds the time itself; SQLite compares text, so it's
+	// formatted like datetime('now') in UTC.
+	TimeParam(t time.Time) any
+
 	// UUIDDefault returns the DDL DEFAULT clause for auto-generated UUIDs,
 	// or empty string if UUIDs must be generated in application code.
 	UUIDDefault() string
 
+	// IdentityDefault returns the DDL clause that makes an integer primary key
+	// auto-increment, or empty string if INTEGER PRIMARY KEY already does.
+	IdentityDefault() string
+
 	// ColumnType maps a metadata field type to the database DDL type.
 	ColumnType(fieldType string, precision int) string
 
@@ -37,14 +48,33 @@ type Dialect interface {
 	PlatformTablesSQL() string
 
 	// TableExists checks whether a table exists.
-	TableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error)
+	TableExists(ctx context.Context, db Querier, tableName string) (bool, error)
 
 	// GetColumns returns existing column names and types for a table.
 	GetColumns(ctx context.Context, db *sql.DB, tableName string) (map[string]string, error)
 
+	// IndexNames returns the names of the indexes on a table.
+	IndexNames(ctx context.Context, db Querier, tableName string) ([]string, error)
+
 	// SoftDeleteIndexSQL returns the CREATE INDEX statement for soft-delete filtering.
 	SoftDeleteIndexSQL(table string) string
 
+	// MaintenanceSQL returns the statements that refresh a table's planner
+	// statistics and, when vacuum is set, reclaim its dead space.
+	// PostgreSQL: ANALYZE or VACUUM ANALYZE. SQLite: none.
+	MaintenanceSQL(table string, vacuum bool) []string
+
+	// ForUpdateClause returns the suffix that makes a SELECT lock the rows it
+	// reads until the transaction ends.
+	// PostgreSQL: " FOR UPDATE". SQLite: "" (see LockRow).
+	ForUpdateClause() string
+
+	// TruncateSQL returns the statements that remove every row of tables.
+	// PostgreSQL: one TRUNCATE, with RESTART IDENTITY when restartIdentity is
+	// set. SQLite: a DELETE per table; an emptied INTEGER PRIMARY KEY restarts
+	// at 1 on its own.
+	TruncateSQL(tables []string, restartIdentity bool) []string
+
 	// InExpr builds a SQL expression for the IN operator.
 	// PostgreSQL: "field = ANY($n)" with single array param.
 	// SQLite: "field IN (?n, ?n+1, ...)" expanding the slice.
@@ -57,6 +87,9 @@ type Dialect interface {
 	// IntervalDeleteExpr returns SQL for deleting rows older than N days.
 	IntervalDeleteExpr(createdAtCol string, pb ParamBuilder, days string) string
 
+	// IntervalSinceExpr returns SQL matching rows created within the last N seconds.
+	IntervalSinceExpr(createdAtCol string, pb ParamBuilder, seconds string) string
+
 	// ArrayParam encodes a string slice for storage.
 	// PostgreSQL: returns the slice as-is (pgx handles TEXT[]).
 	// SQLite: JSON-encodes to string.
@@ -65,6 +98,35 @@ type Dialect interface {
 	// ScanArray decodes a TEXT[] (PostgreSQL) or JSON string (SQLite) into []string.
 	ScanArray(src any) ([]string, error)
 
+	// ArrayContainsExpr matches rows whose array column holds value.
+	// PostgreSQL: "field @> ARRAY[$n]::text[]"
+	// SQLite: EXISTS over json_each(field).
+	ArrayContainsExpr(field string, pb ParamBuilder, value string) string
+
+	// ArrayOverlapsExpr matches rows whose array column holds any of values.
+	// PostgreSQL: "field && $n::text[]"
+	// SQLite: EXISTS over json_each(field) with IN.
+	ArrayOverlapsExpr(field string, pb ParamBuilder, values []string) string
+
+	// GeoWithinExpr matches rows whose geopoint column ({"lat", "lng"} JSON)
+	// lies within radiusKm of lat/lng.
+	// PostgreSQL: haversine great-circle distance.
+	// SQLite: a bounding box around the circle, so points just outside the
+	// radius near its corners also match.
+	GeoWithinExpr(field string, pb ParamBuilder, lat, lng, radiusKm float64) string
+
+	// TextMatchExpr matches rows where any of fields contains term, ignoring
+	// case. term is matched literally (% and _ are escaped).
+	// PostgreSQL: "(a ILIKE $n OR b ILIKE $n)"
+	// SQLite: LIKE, which ignores case for ASCII letters only.
+	TextMatchExpr(fields []string, pb ParamBuilder, term string) string
+
+	// FullTextMatchExpr matches rows whose fields, taken as one document,
+	// contain the words of term.
+	// PostgreSQL: to_tsvector('english', ...) @@ plainto_tsquery('english', $n).
+	// SQLite has no built-in equivalent and uses TextMatchExpr.
+	FullTextMatchExpr(fields []string, pb ParamBuilder, term string) string
+
 	// FilterCountExpr returns SQL for conditional counting.
 	// PostgreSQL: "COUNT(*) FILTER (WHERE condition)"
 	// SQLite: "SUM(CASE WHEN condition THEN 1 ELSE 0 END)"
@@ -86,6 +148,15 @@ type Dialect interface {
 	// DropDatabase drops a database (PostgreSQL) or deletes the file (SQLite).
 	DropDatabase(ctx context.Context, db *sql.DB, name string, dataDir string) error
 
+	// SetNotNullSQL returns the statements that forbid NULL in an existing column.
+	// PostgreSQL: ALTER COLUMN ... SET NOT NULL.
+	// SQLite can't alter column constraints, so it installs insert/update
+	// triggers that abort with SQLite's own NOT NULL message.
+	SetNotNullSQL(table, column string) []string
+
+	// DropNotNullSQL reverses SetNotNullSQL.
+	DropNotNullSQL(table, column string) []string
+
 	// MapError inspects a driver error and returns a well-known sentinel error if applicable.
 	MapError(err error) error
 
@@ -105,6 +176,9 @@ type ParamBuilder interface {
 	Count() int
 }
 
+// EarthRadiusKm is the mean Earth radius GeoWithinExpr measures distances with.
+const EarthRadiusKm = 6371.0
+
 // NewDialect creates a Dialect for the given driver name ("postgres" or "sqlite").
 func NewDialect(driver string) Dialect {
 	switch driver {
@@ -146,3 +220,11 @@ func (p *sqliteParamBuilder) Add(v any) string {
 
 func (p *sqliteParamBuilder) Params() []any { return p.params }
 func (p *sqliteParamBuilder) Count() int    { return p.n }
+
+// LikePattern returns the LIKE pattern matching values that contain term,
+// with LIKE's wildcards and the escape character in term escaped by a
+// backslash (use with ESCAPE '\').
+func LikePattern(term string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return "%" + r.Replace(term) + "%"
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // PostgresDialect implements Dialect for PostgreSQL via pgx/stdlib.
@@ -25,6 +26,7 @@ func (d *PostgresDialect) NewParamBuilder() ParamBuilder {
 
 func (d *PostgresDialect) NowExpr() string      { return "NOW()" }
 func (d *PostgresDialect) UUIDDefault() string   { return "DEFAULT gen_random_uuid()" }
+func (d *PostgresDialect) IdentityDefault() string { return "GENERATED BY DEFAULT AS IDENTITY" }
 func (d *PostgresDialect) NeedsBoolFix() bool    { return false }
 func (d *PostgresDialect) SupportsPercentile() bool { return true }
 
@@ -51,8 +53,10 @@ func (d *PostgresDialect) ColumnType(fieldType string, precision int) string {
 		return "TIMESTAMPTZ"
 	case "date":
 		return "DATE"
-	case "json", "file":
+	case "json", "file", "geopoint":
 		return "JSONB"
+	case "array":
+		return "TEXT[]"
 	default:
 		return "TEXT"
 	}
@@ -66,7 +70,7 @@ func (d *PostgresDialect) PlatformTablesSQL() string {
 	return pgPlatformTablesSQL
 }
 
-func (d *PostgresDialect) TableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error) {
+func (d *PostgresDialect) TableExists(ctx context.Context, db Querier, tableName string) (bool, error) {
 	var exists bool
 	err := db.QueryRowContext(ctx,
 		`SELECT EXISTS(SELECT 1 FROM information_schema.tables WHERE table_name = $1 AND table_schema = 'public')`,
@@ -96,10 +100,50 @@ func (d *PostgresDialect) GetColumns(ctx context.Context, db *sql.DB, tableName
 	return cols, rows.Err()
 }
 
+func (d *PostgresDialect) IndexNames(ctx context.Context, db Querier, tableName string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT indexname FROM pg_indexes WHERE tablename = $1 AND schemaname = 'public'`,
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 func (d *PostgresDialect) SoftDeleteIndexSQL(table string) string {
 	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_deleted_at ON %s (deleted_at) WHERE deleted_at IS NULL", table, table)
 }
 
+func (d *PostgresDialect) MaintenanceSQL(table string, vacuum bool) []string {
+	if vacuum {
+		return []string{"VACUUM ANALYZE " + table}
+	}
+	return []string{"ANALYZE " + table}
+}
+
+func (d *PostgresDialect) ForUpdateClause() string {
+	return " FOR UPDATE"
+}
+
+func (d *PostgresDialect) TruncateSQL(tables []string, restartIdentity bool) []string {
+	stmt := "TRUNCATE " + strings.Join(tables, ", ")
+	if restartIdentity {
+		stmt += " RESTART IDENTITY"
+	}
+	return []string{stmt}
+}
+
 func (d *PostgresDialect) InExpr(field string, pb ParamBuilder, values []any) string {
 	ph := pb.Add(values)
 	return fmt.Sprintf("%s = ANY(%s)", field, ph)
@@ -115,6 +159,15 @@ func (d *PostgresDialect) IntervalDeleteExpr(createdAtCol string, pb ParamBuilde
 	return fmt.Sprintf("%s < now() - (%s || ' days')::interval", createdAtCol, ph)
 }
 
+func (d *PostgresDialect) TimeParam(t time.Time) any {
+	return t
+}
+
+func (d *PostgresDialect) IntervalSinceExpr(createdAtCol string, pb ParamBuilder, seconds string) string {
+	ph := pb.Add(seconds)
+	return fmt.Sprintf("%s >= now() - (%s || ' seconds')::interval", createdAtCol, ph)
+}
+
 func (d *PostgresDialect) ArrayParam(values []string) any {
 	return values
 }
@@ -142,6 +195,48 @@ func (d *PostgresDialect) ScanArray(src any) ([]string, error) {
 	}
 }
 
+func (d *PostgresDialect) ArrayContainsExpr(field string, pb ParamBuilder, value string) string {
+	return fmt.Sprintf("%s @> ARRAY[%s]::text[]", field, pb.Add(value))
+}
+
+func (d *PostgresDialect) ArrayOverlapsExpr(field string, pb ParamBuilder, values []string) string {
+	return fmt.Sprintf("%s && %s::text[]", field, pb.Add(values))
+}
+
+func (d *PostgresDialect) GeoWithinExpr(field string, pb ParamBuilder, lat, lng, radiusKm float64) string {
+	pLat, pLng := pb.Add(lat), pb.Add(lng)
+	rowLat := fmt.Sprintf("(%s->>'lat')::float8", field)
+	rowLng := fmt.Sprintf("(%s->>'lng')::float8", field)
+	return fmt.Sprintf("%v * 2 * ASIN(LEAST(1.0, SQRT(POWER(SIN(RADIANS(%s - %s::float8) / 2), 2) + COS(RADIANS(%s::float8)) * COS(RADIANS(%s)) * POWER(SIN(RADIANS(%s - %s::float8) / 2), 2)))) <= %s",
+		EarthRadiusKm, rowLat, pLat, pLat, rowLat, rowLng, pLng, pb.Add(radiusKm))
+}
+
+func (d *PostgresDialect) TextMatchExpr(fields []string, pb ParamBuilder, term string) string {
+	ph := pb.Add(LikePattern(term))
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = fmt.Sprintf(`%s ILIKE %s ESCAPE '\'`, f, ph)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func (d *PostgresDialect) FullTextMatchExpr(fields []string, pb ParamBuilder, term string) string {
+	doc := make([]string, len(fields))
+	for i, f := range fields {
+		doc[i] = fmt.Sprintf("coalesce(%s, '')", f)
+	}
+	return fmt.Sprintf("to_tsvector('english', %s) @@ plainto_tsquery('english', %s)",
+		strings.Join(doc, " || ' ' || "), pb.Add(term))
+}
+
+func (d *PostgresDialect) SetNotNullSQL(table, column string) []string {
+	return []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column)}
+}
+
+func (d *PostgresDialect) DropNotNullSQL(table, column string) []string {
+	return []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, column)}
+}
+
 // parsePgArray parses a PostgreSQL array literal like {admin,user} into []string.
 func parsePgArray(s string) ([]string, error) {
 	s = strings.TrimSpace(s)
@@ -253,6 +348,7 @@ CREATE TABLE IF NOT EXISTS _rules (
     definition  JSONB NOT NULL,
     priority    INT NOT NULL DEFAULT 0,
     active      BOOLEAN NOT NULL DEFAULT true,
+    tags        TEXT[] DEFAULT '{}',
     created_at  TIMESTAMPTZ DEFAULT NOW(),
     updated_at  TIMESTAMPTZ DEFAULT NOW()
 );
@@ -274,6 +370,7 @@ CREATE TABLE IF NOT EXISTS _workflows (
     context     JSONB NOT NULL DEFAULT '{}',
     steps       JSONB NOT NULL DEFAULT '[]',
     active      BOOLEAN NOT NULL DEFAULT true,
+    tags        TEXT[] DEFAULT '{}',
     created_at  TIMESTAMPTZ DEFAULT NOW(),
     updated_at  TIMESTAMPTZ DEFAULT NOW()
 );
@@ -282,9 +379,16 @@ CREATE TABLE IF NOT EXISTS _workflow_instances (
     id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     workflow_id           UUID NOT NULL REFERENCES _workflows(id) ON DELETE CASCADE,
     workflow_name         TEXT NOT NULL,
+    entity                TEXT,
+    record_id             TEXT,
     status                TEXT NOT NULL DEFAULT 'running',
     current_step          TEXT,
     current_step_deadline TIMESTAMPTZ,
+    callback_token        TEXT,
+    attempts              INTEGER NOT NULL DEFAULT 0,
+    max_attempts          INTEGER NOT NULL DEFAULT 0,
+    last_error            TEXT,
+    next_retry_at         TIMESTAMPTZ,
     context               JSONB NOT NULL DEFAULT '{}',
     history               JSONB NOT NULL DEFAULT '[]',
     created_at            TIMESTAMPTZ DEFAULT NOW(),
@@ -292,13 +396,22 @@ CREATE TABLE IF NOT EXISTS _workflow_instances (
 );
 
 CREATE TABLE IF NOT EXISTS _users (
-    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    email         TEXT NOT NULL UNIQUE,
-    password_hash TEXT NOT NULL,
-    roles         TEXT[] DEFAULT '{}',
-    active        BOOLEAN DEFAULT true,
-    created_at    TIMESTAMPTZ DEFAULT NOW(),
-    updated_at    TIMESTAMPTZ DEFAULT NOW()
+    id                      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email                   TEXT NOT NULL UNIQUE,
+    password_hash           TEXT NOT NULL,
+    roles                   TEXT[] DEFAULT '{}',
+    active                  BOOLEAN DEFAULT true,
+    metadata                JSONB DEFAULT '{}',
+    attributes              JSONB DEFAULT '{}',
+    last_login_at           TIMESTAMPTZ,
+    password_reset_required BOOLEAN NOT NULL DEFAULT false,
+    created_at              TIMESTAMPTZ DEFAULT NOW(),
+    updated_at              TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS _roles (
+    name       TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
 CREATE TABLE IF NOT EXISTS _refresh_tokens (
@@ -315,6 +428,7 @@ CREATE TABLE IF NOT EXISTS _permissions (
     id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     entity     TEXT NOT NULL,
     action     TEXT NOT NULL,
+    effect     TEXT NOT NULL DEFAULT 'allow',
     roles      TEXT[] NOT NULL DEFAULT '{}',
     conditions JSONB DEFAULT '[]',
     created_at TIMESTAMPTZ DEFAULT NOW(),
@@ -332,6 +446,11 @@ CREATE TABLE IF NOT EXISTS _webhooks (
     async      BOOLEAN NOT NULL DEFAULT true,
     retry      JSONB DEFAULT '{"max_attempts": 3, "backoff": "exponential"}',
     active     BOOLEAN NOT NULL DEFAULT true,
+    batch      JSONB,
+    transport  TEXT NOT NULL DEFAULT 'http',
+    transport_config JSONB,
+    secret     TEXT,
+    tags       TEXT[] DEFAULT '{}',
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
@@ -358,6 +477,19 @@ CREATE TABLE IF NOT EXISTS _webhook_logs (
 );
 CREATE INDEX IF NOT EXISTS idx_webhook_logs_status ON _webhook_logs(status);
 CREATE INDEX IF NOT EXISTS idx_webhook_logs_retry ON _webhook_logs(next_retry_at) WHERE status = 'retrying';
+CREATE INDEX IF NOT EXISTS idx_webhook_logs_idempotency ON _webhook_logs(webhook_id, idempotency_key);
+
+CREATE TABLE IF NOT EXISTS _audit_log (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity     TEXT NOT NULL,
+    record_id  TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    user_id    TEXT,
+    changes    JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity_record ON _audit_log(entity, record_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON _audit_log(created_at);
 
 CREATE TABLE IF NOT EXISTS _files (
     id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -413,6 +545,14 @@ CREATE TABLE IF NOT EXISTS _invites (
 );
 CREATE INDEX IF NOT EXISTS idx_invites_token ON _invites(token);
 CREATE INDEX IF NOT EXISTS idx_invites_email ON _invites(email);
+
+CREATE TABLE IF NOT EXISTS _settings (
+    key        TEXT PRIMARY KEY,
+    value      JSONB NOT NULL DEFAULT 'null',
+    public     BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
 `
 
 const pgPlatformTablesSQL = `
@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // SQLiteDialect implements Dialect for SQLite via modernc.org/sqlite.
@@ -26,6 +28,7 @@ func (d *SQLiteDialect) NewParamBuilder() ParamBuilder {
 
 func (d *SQLiteDialect) NowExpr() string      { return "datetime('now')" }
 func (d *SQLiteDialect) UUIDDefault() string   { return "" }
+func (d *SQLiteDialect) IdentityDefault() string { return "" }
 func (d *SQLiteDialect) NeedsBoolFix() bool    { return true }
 func (d *SQLiteDialect) SupportsPercentile() bool { return false }
 
@@ -49,8 +52,10 @@ func (d *SQLiteDialect) ColumnType(fieldType string, precision int) string {
 		return "TEXT"
 	case "date":
 		return "TEXT"
-	case "json", "file":
+	case "json", "file", "geopoint":
 		return "TEXT"
+	case "array":
+		return "TEXT" // JSON array text
 	default:
 		return "TEXT"
 	}
@@ -64,7 +69,7 @@ func (d *SQLiteDialect) PlatformTablesSQL() string {
 	return sqlitePlatformTablesSQL
 }
 
-func (d *SQLiteDialect) TableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error) {
+func (d *SQLiteDialect) TableExists(ctx context.Context, db Querier, tableName string) (bool, error) {
 	var name string
 	err := db.QueryRowContext(ctx,
 		"SELECT name FROM sqlite_master WHERE type='table' AND name=?1",
@@ -101,11 +106,54 @@ func (d *SQLiteDialect) GetColumns(ctx context.Context, db *sql.DB, tableName st
 	return cols, rows.Err()
 }
 
+func (d *SQLiteDialect) IndexNames(ctx context.Context, db Querier, tableName string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT name FROM sqlite_master WHERE type='index' AND tbl_name=?1",
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 func (d *SQLiteDialect) SoftDeleteIndexSQL(table string) string {
 	// SQLite supports partial indexes (3.8.0+)
 	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_deleted_at ON %s (deleted_at) WHERE deleted_at IS NULL", table, table)
 }
 
+func (d *SQLiteDialect) MaintenanceSQL(table string, vacuum bool) []string {
+	// VACUUM rebuilds the whole database file and ANALYZE stats are rarely
+	// worth it at SQLite's scale, so there is no per-table maintenance.
+	return nil
+}
+
+func (d *SQLiteDialect) ForUpdateClause() string {
+	// No row locks; the store's single connection already runs one
+	// transaction at a time.
+	return ""
+}
+
+func (d *SQLiteDialect) TruncateSQL(tables []string, restartIdentity bool) []string {
+	// Keys are plain INTEGER PRIMARY KEYs (no AUTOINCREMENT), so an empty
+	// table hands out 1 again and there is no sequence to reset.
+	stmts := make([]string, len(tables))
+	for i, t := range tables {
+		stmts[i] = "DELETE FROM " + t
+	}
+	return stmts
+}
+
 func (d *SQLiteDialect) InExpr(field string, pb ParamBuilder, values []any) string {
 	if len(values) == 0 {
 		return "1=0" // always false
@@ -133,6 +181,15 @@ func (d *SQLiteDialect) IntervalDeleteExpr(createdAtCol string, pb ParamBuilder,
 	return fmt.Sprintf("%s < datetime('now', '-' || %s || ' days')", createdAtCol, ph)
 }
 
+func (d *SQLiteDialect) TimeParam(t time.Time) any {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+func (d *SQLiteDialect) IntervalSinceExpr(createdAtCol string, pb ParamBuilder, seconds string) string {
+	ph := pb.Add(seconds)
+	return fmt.Sprintf("%s >= datetime('now', '-' || %s || ' seconds')", createdAtCol, ph)
+}
+
 func (d *SQLiteDialect) ArrayParam(values []string) any {
 	if values == nil {
 		return "[]"
@@ -165,6 +222,84 @@ func (d *SQLiteDialect) ScanArray(src any) ([]string, error) {
 	return result, nil
 }
 
+func (d *SQLiteDialect) ArrayContainsExpr(field string, pb ParamBuilder, value string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value = %s)", field, pb.Add(value))
+}
+
+func (d *SQLiteDialect) ArrayOverlapsExpr(field string, pb ParamBuilder, values []string) string {
+	if len(values) == 0 {
+		return "1=0" // always false
+	}
+	phs := make([]string, len(values))
+	for i, v := range values {
+		phs[i] = pb.Add(v)
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value IN (%s))", field, strings.Join(phs, ", "))
+}
+
+func (d *SQLiteDialect) GeoWithinExpr(field string, pb ParamBuilder, lat, lng, radiusKm float64) string {
+	rowLat := fmt.Sprintf("json_extract(%s, '$.lat')", field)
+	rowLng := fmt.Sprintf("json_extract(%s, '$.lng')", field)
+	dLat := radiusKm / (EarthRadiusKm * math.Pi / 180)
+	expr := fmt.Sprintf("%s BETWEEN %s AND %s", rowLat, pb.Add(lat-dLat), pb.Add(lat+dLat))
+
+	// Degrees of longitude shrink towards the poles; close enough to one, or
+	// with a radius spanning the globe, every longitude is in range
+	cosLat := math.Cos(lat * math.Pi / 180)
+	if cosLat < 1e-9 || lat+dLat >= 90 || lat-dLat <= -90 {
+		return expr
+	}
+	dLng := dLat / cosLat
+	if dLng >= 180 {
+		return expr
+	}
+	minLng, maxLng := lng-dLng, lng+dLng
+	switch {
+	case minLng < -180: // box crosses the antimeridian
+		expr += fmt.Sprintf(" AND (%s >= %s OR %s <= %s)", rowLng, pb.Add(minLng+360), rowLng, pb.Add(maxLng))
+	case maxLng > 180:
+		expr += fmt.Sprintf(" AND (%s >= %s OR %s <= %s)", rowLng, pb.Add(minLng), rowLng, pb.Add(maxLng-360))
+	default:
+		expr += fmt.Sprintf(" AND %s BETWEEN %s AND %s", rowLng, pb.Add(minLng), pb.Add(maxLng))
+	}
+	return expr
+}
+
+func (d *SQLiteDialect) TextMatchExpr(fields []string, pb ParamBuilder, term string) string {
+	ph := pb.Add(LikePattern(term))
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, f, ph)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func (d *SQLiteDialect) FullTextMatchExpr(fields []string, pb ParamBuilder, term string) string {
+	return d.TextMatchExpr(fields, pb, term)
+}
+
+func (d *SQLiteDialect) SetNotNullSQL(table, column string) []string {
+	var stmts []string
+	for _, op := range []string{"insert", "update"} {
+		stmts = append(stmts, fmt.Sprintf(
+			`CREATE TRIGGER IF NOT EXISTS %s BEFORE %s ON %s FOR EACH ROW WHEN NEW.%s IS NULL
+BEGIN SELECT RAISE(ABORT, 'NOT NULL constraint failed: %s.%s'); END`,
+			notNullTriggerName(table, column, op), strings.ToUpper(op), table, column, table, column))
+	}
+	return stmts
+}
+
+func (d *SQLiteDialect) DropNotNullSQL(table, column string) []string {
+	return []string{
+		"DROP TRIGGER IF EXISTS " + notNullTriggerName(table, column, "insert"),
+		"DROP TRIGGER IF EXISTS " + notNullTriggerName(table, column, "update"),
+	}
+}
+
+func notNullTriggerName(table, column, op string) string {
+	return fmt.Sprintf("trg_%s_%s_not_null_%s", table, column, op)
+}
+
 func (d *SQLiteDialect) FilterCountExpr(condition string) string {
 	return fmt.Sprintf("SUM(CASE WHEN %s THEN 1 ELSE 0 END)", condition)
 }
@@ -242,6 +377,7 @@ CREATE TABLE IF NOT EXISTS _rules (
     definition  TEXT NOT NULL,
     priority    INTEGER NOT NULL DEFAULT 0,
     active      INTEGER NOT NULL DEFAULT 1,
+    tags        TEXT DEFAULT '[]',
     created_at  TEXT DEFAULT (datetime('now')),
     updated_at  TEXT DEFAULT (datetime('now'))
 );
@@ -263,6 +399,7 @@ CREATE TABLE IF NOT EXISTS _workflows (
     context     TEXT NOT NULL DEFAULT '{}',
     steps       TEXT NOT NULL DEFAULT '[]',
     active      INTEGER NOT NULL DEFAULT 1,
+    tags        TEXT DEFAULT '[]',
     created_at  TEXT DEFAULT (datetime('now')),
     updated_at  TEXT DEFAULT (datetime('now'))
 );
@@ -271,9 +408,16 @@ CREATE TABLE IF NOT EXISTS _workflow_instances (
     id                    TEXT PRIMARY KEY,
     workflow_id           TEXT NOT NULL REFERENCES _workflows(id) ON DELETE CASCADE,
     workflow_name         TEXT NOT NULL,
+    entity                TEXT,
+    record_id             TEXT,
     status                TEXT NOT NULL DEFAULT 'running',
     current_step          TEXT,
     current_step_deadline TEXT,
+    callback_token        TEXT,
+    attempts              INTEGER NOT NULL DEFAULT 0,
+    max_attempts          INTEGER NOT NULL DEFAULT 0,
+    last_error            TEXT,
+    next_retry_at         TEXT,
     context               TEXT NOT NULL DEFAULT '{}',
     history               TEXT NOT NULL DEFAULT '[]',
     created_at            TEXT DEFAULT (datetime('now')),
@@ -281,13 +425,22 @@ CREATE TABLE IF NOT EXISTS _workflow_instances (
 );
 
 CREATE TABLE IF NOT EXISTS _users (
-    id            TEXT PRIMARY KEY,
-    email         TEXT NOT NULL UNIQUE,
-    password_hash TEXT NOT NULL,
-    roles         TEXT DEFAULT '[]',
-    active        INTEGER DEFAULT 1,
-    created_at    TEXT DEFAULT (datetime('now')),
-    updated_at    TEXT DEFAULT (datetime('now'))
+    id                      TEXT PRIMARY KEY,
+    email                   TEXT NOT NULL UNIQUE,
+    password_hash           TEXT NOT NULL,
+    roles                   TEXT DEFAULT '[]',
+    active                  INTEGER DEFAULT 1,
+    metadata                TEXT DEFAULT '{}',
+    attributes              TEXT DEFAULT '{}',
+    last_login_at           TEXT,
+    password_reset_required INTEGER NOT NULL DEFAULT 0,
+    created_at              TEXT DEFAULT (datetime('now')),
+    updated_at              TEXT DEFAULT (datetime('now'))
+);
+
+CREATE TABLE IF NOT EXISTS _roles (
+    name       TEXT PRIMARY KEY,
+    created_at TEXT DEFAULT (datetime('now'))
 );
 
 CREATE TABLE IF NOT EXISTS _refresh_tokens (
@@ -304,6 +457,7 @@ CREATE TABLE IF NOT EXISTS _permissions (
     id         TEXT PRIMARY KEY,
     entity     TEXT NOT NULL,
     action     TEXT NOT NULL,
+    effect     TEXT NOT NULL DEFAULT 'allow',
     roles      TEXT NOT NULL DEFAULT '[]',
     conditions TEXT DEFAULT '[]',
     created_at TEXT DEFAULT (datetime('now')),
@@ -321,6 +475,11 @@ CREATE TABLE IF NOT EXISTS _webhooks (
     async      INTEGER NOT NULL DEFAULT 1,
     retry      TEXT DEFAULT '{"max_attempts": 3, "backoff": "exponential"}',
     active     INTEGER NOT NULL DEFAULT 1,
+    batch      TEXT,
+    transport  TEXT NOT NULL DEFAULT 'http',
+    transport_config TEXT,
+    secret     TEXT,
+    tags       TEXT DEFAULT '[]',
     created_at TEXT DEFAULT (datetime('now')),
     updated_at TEXT DEFAULT (datetime('now'))
 );
@@ -347,6 +506,19 @@ CREATE TABLE IF NOT EXISTS _webhook_logs (
 );
 CREATE INDEX IF NOT EXISTS idx_webhook_logs_status ON _webhook_logs(status);
 CREATE INDEX IF NOT EXISTS idx_webhook_logs_retry ON _webhook_logs(next_retry_at) WHERE status = 'retrying';
+CREATE INDEX IF NOT EXISTS idx_webhook_logs_idempotency ON _webhook_logs(webhook_id, idempotency_key);
+
+CREATE TABLE IF NOT EXISTS _audit_log (
+    id         TEXT PRIMARY KEY,
+    entity     TEXT NOT NULL,
+    record_id  TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    user_id    TEXT,
+    changes    TEXT NOT NULL DEFAULT '{}',
+    created_at TEXT DEFAULT (datetime('now'))
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity_record ON _audit_log(entity, record_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON _audit_log(created_at);
 
 CREATE TABLE IF NOT EXISTS _files (
     id            TEXT PRIMARY KEY,
@@ -402,6 +574,14 @@ CREATE TABLE IF NOT EXISTS _invites (
 );
 CREATE INDEX IF NOT EXISTS idx_invites_token ON _invites(token);
 CREATE INDEX IF NOT EXISTS idx_invites_email ON _invites(email);
+
+CREATE TABLE IF NOT EXISTS _settings (
+    key        TEXT PRIMARY KEY,
+    value      TEXT NOT NULL DEFAULT 'null',
+    public     INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT DEFAULT (datetime('now')),
+    updated_at TEXT DEFAULT (datetime('now'))
+);
 `
 
 const sqlitePlatformTablesSQL = `
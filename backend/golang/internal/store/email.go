@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// NormalizeEmails lowercases emails before they are stored. Set from
+// auth.normalize_emails. Either way lookups and the unique index on _users
+// ignore case, so turning it off only preserves the casing users typed.
+var NormalizeEmails = true
+
+// NormalizeEmail trims an email and, when NormalizeEmails is on, lowercases it.
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if NormalizeEmails {
+		email = strings.ToLower(email)
+	}
+	return email
+}
+
+// ensureEmailIndex makes _users.email unique regardless of case. Databases
+// that already hold addresses differing only in case keep working; the index
+// is skipped with a warning until the duplicates are merged.
+func (s *Store) ensureEmailIndex(ctx context.Context) {
+	if _, err := s.DB.ExecContext(ctx,
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON _users (LOWER(email))"); err != nil {
+		log.Printf("WARN: case-insensitive email index on _users not created (duplicate emails differing only in case?): %v", err)
+	}
+}
@@ -2,7 +2,11 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -10,6 +14,9 @@ import (
 	"rocket-backend/internal/metadata"
 )
 
+// ErrInvalidRename is returned when a field's renamed_from hint can't be applied.
+var ErrInvalidRename = errors.New("invalid field rename")
+
 type Migrator struct {
 	store *Store
 }
@@ -21,6 +28,10 @@ func NewMigrator(store *Store) *Migrator {
 // Migrate ensures the database table matches the entity metadata.
 // Creates the table if it doesn't exist, or adds missing columns.
 func (m *Migrator) Migrate(ctx context.Context, entity *metadata.Entity) error {
+	entity.ApplyTimestamps()
+	entity.ApplyAttribution()
+	entity = m.physical(entity)
+
 	exists, err := m.store.Dialect.TableExists(ctx, m.store.DB, entity.Table)
 	if err != nil {
 		return fmt.Errorf("check table exists: %w", err)
@@ -33,9 +44,14 @@ func (m *Migrator) Migrate(ctx context.Context, entity *metadata.Entity) error {
 	return m.alterTable(ctx, entity)
 }
 
-// MigrateJoinTable creates a join table for a many-to-many relation if it doesn't exist.
-func (m *Migrator) MigrateJoinTable(ctx context.Context, rel *metadata.Relation, sourceEntity, targetEntity *metadata.Entity) error {
-	exists, err := m.store.Dialect.TableExists(ctx, m.store.DB, rel.JoinTable)
+// MigrateJoinTable creates a join table for a many-to-many relation if it doesn't
+// exist. q may be a transaction so the DDL commits or rolls back with the caller.
+func (m *Migrator) MigrateJoinTable(ctx context.Context, q Querier, rel *metadata.Relation, sourceEntity, targetEntity *metadata.Entity) error {
+	prefixed := *rel
+	prefixed.JoinTable = rel.PhysicalJoinTable(m.store.TablePrefix)
+	prefixed.JoinTablePrefixed = true
+	rel = &prefixed
+	exists, err := m.store.Dialect.TableExists(ctx, q, rel.JoinTable)
 	if err != nil {
 		return fmt.Errorf("check join table exists: %w", err)
 	}
@@ -61,12 +77,25 @@ func (m *Migrator) MigrateJoinTable(ctx context.Context, rel *metadata.Relation,
 		rel.SourceJoinKey, rel.TargetJoinKey,
 	)
 
-	if _, err := m.store.DB.ExecContext(ctx, sqlStr); err != nil {
+	if _, err := q.ExecContext(ctx, sqlStr); err != nil {
 		return fmt.Errorf("create join table %s: %w", rel.JoinTable, err)
 	}
 	return nil
 }
 
+// physical returns entity with its table name under the store's prefix.
+// Entities from the registry already carry it; ones parsed from an admin
+// request don't.
+func (m *Migrator) physical(entity *metadata.Entity) *metadata.Entity {
+	if entity.TablePrefixed {
+		return entity
+	}
+	e := *entity
+	e.Table = entity.PhysicalTable(m.store.TablePrefix)
+	e.TablePrefixed = true
+	return &e
+}
+
 func (m *Migrator) createTable(ctx context.Context, entity *metadata.Entity) error {
 	var cols []string
 	for _, f := range entity.Fields {
@@ -99,12 +128,40 @@ func (m *Migrator) alterTable(ctx context.Context, entity *metadata.Entity) erro
 		return fmt.Errorf("get columns for %s: %w", entity.Table, err)
 	}
 
+	renames, err := pendingRenames(entity, existing)
+	if err != nil {
+		return err
+	}
+	for _, f := range renames {
+		sqlStr := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", entity.Table, f.RenamedFrom, f.Name)
+		if _, err := m.store.DB.ExecContext(ctx, sqlStr); err != nil {
+			return fmt.Errorf("rename column %s.%s to %s: %w", entity.Table, f.RenamedFrom, f.Name, err)
+		}
+		// Indexes follow the column; drop the ones named after the old field so
+		// createIndexes recreates them under the new name instead of duplicating.
+		indexes, err := m.store.Dialect.IndexNames(ctx, m.store.DB, entity.Table)
+		if err != nil {
+			return fmt.Errorf("list indexes on %s: %w", entity.Table, err)
+		}
+		if err := m.dropUniqueIndexes(ctx, entity.Table, f.RenamedFrom, indexes, ""); err != nil {
+			return err
+		}
+		existing[f.Name] = existing[f.RenamedFrom]
+		delete(existing, f.RenamedFrom)
+	}
+
 	for _, f := range entity.Fields {
 		if _, ok := existing[f.Name]; !ok {
-			colType := m.store.Dialect.ColumnType(f.Type, f.Precision)
+			colType := m.store.Dialect.ColumnType(f.StorageType(), f.Precision)
 			notNull := ""
 			if f.Required && !f.Nullable {
 				notNull = " NOT NULL DEFAULT ''" // safe default for existing rows
+				if f.Type == "array" {
+					notNull = " NOT NULL DEFAULT '[]'"
+					if m.store.Dialect.Name() == "postgres" {
+						notNull = " NOT NULL DEFAULT '{}'"
+					}
+				}
 			}
 			sqlStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s%s", entity.Table, f.Name, colType, notNull)
 			if _, err := m.store.DB.ExecContext(ctx, sqlStr); err != nil {
@@ -132,14 +189,195 @@ func (m *Migrator) alterTable(ctx context.Context, entity *metadata.Entity) erro
 	return nil
 }
 
+// ValidateRenames checks the entity's renamed_from hints against the live table
+// without changing it. Returns nil if the table doesn't exist yet.
+func (m *Migrator) ValidateRenames(ctx context.Context, entity *metadata.Entity) error {
+	entity = m.physical(entity)
+	exists, err := m.store.Dialect.TableExists(ctx, m.store.DB, entity.Table)
+	if err != nil || !exists {
+		return err
+	}
+	existing, err := m.store.Dialect.GetColumns(ctx, m.store.DB, entity.Table)
+	if err != nil {
+		return fmt.Errorf("get columns for %s: %w", entity.Table, err)
+	}
+	_, err = pendingRenames(entity, existing)
+	return err
+}
+
+// RequiredChanges compares an entity's stored definition with its update and
+// returns the fields whose column becomes NOT NULL and those that stop being
+// NOT NULL. Fields are matched by name or renamed_from; new fields are left to
+// alterTable.
+func RequiredChanges(old, updated *metadata.Entity) (tightened, loosened []*metadata.Field) {
+	notNull := func(e *metadata.Entity, f *metadata.Field) bool {
+		return f.Required && !f.Nullable && f.Name != e.PrimaryKey.Field
+	}
+	for i := range updated.Fields {
+		f := &updated.Fields[i]
+		prev := old.GetField(f.Name)
+		if prev == nil && f.RenamedFrom != "" {
+			prev = old.GetField(f.RenamedFrom)
+		}
+		if prev == nil {
+			continue
+		}
+		switch was, is := notNull(old, prev), notNull(updated, f); {
+		case is && !was:
+			tightened = append(tightened, f)
+		case was && !is:
+			loosened = append(loosened, f)
+		}
+	}
+	return tightened, loosened
+}
+
+// NullViolation reports a field that can't become required because existing
+// rows hold NULL and the field has no backfill value.
+type NullViolation struct {
+	Field string `json:"field"`
+	Rows  int64  `json:"rows"`
+}
+
+// CheckRequired counts existing NULLs in each field of fields that has no
+// backfill value. Columns are looked up under their old name if a rename is
+// still pending. Returns nil if the table doesn't exist yet.
+func (m *Migrator) CheckRequired(ctx context.Context, entity *metadata.Entity, fields []*metadata.Field) ([]NullViolation, error) {
+	entity = m.physical(entity)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	exists, err := m.store.Dialect.TableExists(ctx, m.store.DB, entity.Table)
+	if err != nil || !exists {
+		return nil, err
+	}
+	existing, err := m.store.Dialect.GetColumns(ctx, m.store.DB, entity.Table)
+	if err != nil {
+		return nil, fmt.Errorf("get columns for %s: %w", entity.Table, err)
+	}
+
+	var violations []NullViolation
+	for _, f := range fields {
+		if f.Backfill != nil {
+			continue
+		}
+		col := f.Name
+		if _, ok := existing[col]; !ok && f.RenamedFrom != "" {
+			col = f.RenamedFrom
+		}
+		if _, ok := existing[col]; !ok {
+			continue
+		}
+		var n int64
+		err := m.store.DB.QueryRowContext(ctx,
+			fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", entity.Table, col)).Scan(&n)
+		if err != nil {
+			return nil, fmt.Errorf("count nulls in %s.%s: %w", entity.Table, col, err)
+		}
+		if n > 0 {
+			violations = append(violations, NullViolation{Field: f.Name, Rows: n})
+		}
+	}
+	return violations, nil
+}
+
+// ApplyRequiredChanges writes each tightened field's backfill value into its
+// NULL rows and then enforces NOT NULL; loosened fields have the constraint
+// dropped. Runs before Migrate, in one transaction, so a failure leaves the
+// table as it was. Columns are looked up under their old name if a rename is
+// still pending. Does nothing if the table doesn't exist yet.
+func (m *Migrator) ApplyRequiredChanges(ctx context.Context, entity *metadata.Entity, tightened, loosened []*metadata.Field) error {
+	entity = m.physical(entity)
+	if len(tightened) == 0 && len(loosened) == 0 {
+		return nil
+	}
+	exists, err := m.store.Dialect.TableExists(ctx, m.store.DB, entity.Table)
+	if err != nil || !exists {
+		return err
+	}
+	existing, err := m.store.Dialect.GetColumns(ctx, m.store.DB, entity.Table)
+	if err != nil {
+		return fmt.Errorf("get columns for %s: %w", entity.Table, err)
+	}
+	column := func(f *metadata.Field) string {
+		if _, ok := existing[f.Name]; !ok && f.RenamedFrom != "" {
+			return f.RenamedFrom
+		}
+		return f.Name
+	}
+	return m.store.Tx(ctx, func(tx Querier) error {
+		for _, f := range tightened {
+			col := column(f)
+			if f.Backfill != nil {
+				pb := m.store.Dialect.NewParamBuilder()
+				if _, err := Exec(ctx, tx,
+					fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", entity.Table, col, pb.Add(f.Backfill), col),
+					pb.Params()...); err != nil {
+					return fmt.Errorf("backfill %s.%s: %w", entity.Table, col, err)
+				}
+			}
+			for _, stmt := range m.store.Dialect.SetNotNullSQL(entity.Table, col) {
+				if _, err := Exec(ctx, tx, stmt); err != nil {
+					return fmt.Errorf("set %s.%s not null: %w", entity.Table, col, err)
+				}
+			}
+		}
+		for _, f := range loosened {
+			col := column(f)
+			for _, stmt := range m.store.Dialect.DropNotNullSQL(entity.Table, col) {
+				if _, err := Exec(ctx, tx, stmt); err != nil {
+					return fmt.Errorf("drop %s.%s not null: %w", entity.Table, col, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// pendingRenames returns the fields whose renamed_from column still has to be renamed.
+// A hint is already applied when the new column exists and the old one doesn't.
+func pendingRenames(entity *metadata.Entity, existing map[string]string) ([]*metadata.Field, error) {
+	var renames []*metadata.Field
+	for i := range entity.Fields {
+		f := &entity.Fields[i]
+		if f.RenamedFrom == "" || f.RenamedFrom == f.Name {
+			continue
+		}
+		_, hasOld := existing[f.RenamedFrom]
+		_, hasNew := existing[f.Name]
+		switch {
+		case hasOld && hasNew:
+			return nil, fmt.Errorf("%w: cannot rename %s.%s to %s, column already exists", ErrInvalidRename, entity.Table, f.RenamedFrom, f.Name)
+		case hasNew:
+			continue
+		case !hasOld:
+			return nil, fmt.Errorf("%w: column %s.%s does not exist", ErrInvalidRename, entity.Table, f.RenamedFrom)
+		}
+		renames = append(renames, f)
+	}
+	return renames, nil
+}
+
 func (m *Migrator) buildColumnDef(entity *metadata.Entity, f *metadata.Field) string {
-	col := f.Name + " " + m.store.Dialect.ColumnType(f.Type, f.Precision)
+	col := f.Name + " " + m.store.Dialect.ColumnType(f.StorageType(), f.Precision)
 
 	if f.Name == entity.PrimaryKey.Field {
 		col += " PRIMARY KEY"
-		uuidDefault := m.store.Dialect.UUIDDefault()
-		if entity.PrimaryKey.Generated && entity.PrimaryKey.Type == "uuid" && uuidDefault != "" {
-			col += " " + uuidDefault
+		// The key column's type is the field's, which validation checks
+		// id_strategy against, not primary_key.type
+		if entity.PrimaryKey.Generated {
+			switch entity.IDStrategy {
+			case "", "uuid":
+				uuidDefault := m.store.Dialect.UUIDDefault()
+				if f.Type == "uuid" && uuidDefault != "" {
+					col += " " + uuidDefault
+				}
+			case "sequence":
+				if identity := m.store.Dialect.IdentityDefault(); identity != "" {
+					col += " " + identity
+				}
+			}
+			// uuidv7 and nanoid keys are generated by the engine on insert
 		}
 	}
 
@@ -147,7 +385,8 @@ func (m *Migrator) buildColumnDef(entity *metadata.Entity, f *metadata.Field) st
 		col += " NOT NULL"
 	}
 
-	if f.Default != nil && f.Name != entity.PrimaryKey.Field {
+	// Array defaults have no portable DDL literal; the engine applies them on insert
+	if f.Default != nil && f.Name != entity.PrimaryKey.Field && f.Type != "array" {
 		switch v := f.Default.(type) {
 		case string:
 			col += fmt.Sprintf(" DEFAULT '%s'", v)
@@ -172,28 +411,253 @@ func (m *Migrator) buildColumnDef(entity *metadata.Entity, f *metadata.Field) st
 }
 
 func (m *Migrator) createIndexes(ctx context.Context, entity *metadata.Entity) error {
+	existing, err := m.store.Dialect.IndexNames(ctx, m.store.DB, entity.Table)
+	if err != nil {
+		return fmt.Errorf("list indexes on %s: %w", entity.Table, err)
+	}
 	for _, f := range entity.Fields {
-		if f.Unique {
-			sqlStr := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s)",
-				entity.Table, f.Name, entity.Table, f.Name)
-			if _, err := m.store.DB.ExecContext(ctx, sqlStr); err != nil {
-				return fmt.Errorf("create unique index on %s.%s: %w", entity.Table, f.Name, err)
-			}
+		if !f.Unique {
+			continue
+		}
+		// Replace the field's index if case sensitivity or unique_where changed
+		if err := m.dropUniqueIndexes(ctx, entity.Table, f.Name, existing, FieldUniqueIndexName(entity.Table, f)); err != nil {
+			return err
 		}
 	}
+	for _, idx := range m.declaredIndexes(entity) {
+		if _, err := m.store.DB.ExecContext(ctx, idx.sql); err != nil {
+			return fmt.Errorf("create index %s: %w", idx.name, err)
+		}
+	}
+	return nil
+}
 
+type declaredIndex struct {
+	name string
+	sql  string
+}
+
+// declaredIndexes lists the indexes the entity's metadata asks for: one per
+// unique field and the soft-delete index.
+func (m *Migrator) declaredIndexes(entity *metadata.Entity) []declaredIndex {
+	var out []declaredIndex
+	for _, f := range entity.Fields {
+		if !f.Unique {
+			continue
+		}
+		name := FieldUniqueIndexName(entity.Table, f)
+		target := f.Name
+		if f.IsCaseInsensitive() {
+			target = "LOWER(" + f.Name + ")"
+		}
+		sqlStr := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)", name, entity.Table, target)
+		if len(f.UniqueWhere) > 0 {
+			sqlStr += " WHERE " + m.uniqueWhereSQL(f)
+		}
+		out = append(out, declaredIndex{name: name, sql: sqlStr})
+	}
 	if entity.SoftDelete {
-		sqlStr := m.store.Dialect.SoftDeleteIndexSQL(entity.Table)
-		if _, err := m.store.DB.ExecContext(ctx, sqlStr); err != nil {
-			return fmt.Errorf("create soft delete index on %s: %w", entity.Table, err)
+		out = append(out, declaredIndex{
+			name: fmt.Sprintf("idx_%s_deleted_at", entity.Table),
+			sql:  m.store.Dialect.SoftDeleteIndexSQL(entity.Table),
+		})
+	}
+	return out
+}
+
+// Reindex drops and recreates the entity's declared indexes in one
+// transaction and returns their names. Missing indexes are created.
+func (m *Migrator) Reindex(ctx context.Context, entity *metadata.Entity) ([]string, error) {
+	entity = m.physical(entity)
+	indexes := m.declaredIndexes(entity)
+	rebuilt := make([]string, 0, len(indexes))
+	err := m.store.Tx(ctx, func(tx Querier) error {
+		for _, idx := range indexes {
+			if _, err := Exec(ctx, tx, "DROP INDEX IF EXISTS "+idx.name); err != nil {
+				return fmt.Errorf("drop index %s: %w", idx.name, err)
+			}
+			if _, err := Exec(ctx, tx, idx.sql); err != nil {
+				return fmt.Errorf("create index %s: %w", idx.name, err)
+			}
+			rebuilt = append(rebuilt, idx.name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rebuilt, nil
+}
+
+// Maintain runs the dialect's table maintenance (statistics refresh, and
+// space reclamation when vacuum is set). Returns the statements it ran.
+func (m *Migrator) Maintain(ctx context.Context, entity *metadata.Entity, vacuum bool) ([]string, error) {
+	entity = m.physical(entity)
+	stmts := m.store.Dialect.MaintenanceSQL(entity.Table, vacuum)
+	for _, stmt := range stmts {
+		if _, err := m.store.DB.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return stmts, nil
+}
+
+// Truncate removes every row of the entity's table and of joinTables (physical
+// names, see Relation.PhysicalJoinTable), keeping the schema. restartIdentity
+// resets sequence keys. It runs on q so the caller can record the truncate in
+// the same transaction. Returns the number of rows the entity's table held.
+func (m *Migrator) Truncate(ctx context.Context, q Querier, entity *metadata.Entity, joinTables []string, restartIdentity bool) (int64, error) {
+	entity = m.physical(entity)
+	tables := append([]string{entity.Table}, joinTables...)
+	var removed int64
+	if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+entity.Table).Scan(&removed); err != nil {
+		return 0, fmt.Errorf("count %s: %w", entity.Table, err)
+	}
+	for _, stmt := range m.store.Dialect.TruncateSQL(tables, restartIdentity) {
+		if _, err := Exec(ctx, q, stmt); err != nil {
+			return 0, fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return removed, nil
+}
+
+// Drop drops the entity's table and joinTables (physical names), for an
+// entity being deleted. It runs on q so the caller can drop them in the
+// transaction that removes the entity's metadata.
+func (m *Migrator) Drop(ctx context.Context, q Querier, entity *metadata.Entity, joinTables []string) error {
+	return m.DropTables(ctx, q, append([]string{m.physical(entity).Table}, joinTables...))
+}
+
+// DropTables drops the given physical entity or join tables on q. Tables that
+// don't exist are skipped.
+func (m *Migrator) DropTables(ctx context.Context, q Querier, tables []string) error {
+	for _, table := range tables {
+		if _, err := Exec(ctx, q, "DROP TABLE IF EXISTS "+table); err != nil {
+			return fmt.Errorf("drop table %s: %w", table, err)
 		}
 	}
+	return nil
+}
+
+// UniqueIndexName returns the name of the unique index the migrator creates for a field.
+// Case-insensitive indexes get a "_ci" suffix so they replace, rather than collide with,
+// an existing case-sensitive index.
+func UniqueIndexName(table, field string, caseInsensitive bool) string {
+	if caseInsensitive {
+		return fmt.Sprintf("idx_%s_%s_ci", table, field)
+	}
+	return fmt.Sprintf("idx_%s_%s", table, field)
+}
+
+// FieldUniqueIndexName returns the name of the index backing a unique field.
+// A unique_where index gets a hash of its conditions appended, so changing
+// them builds a new index instead of keeping the old predicate.
+func FieldUniqueIndexName(table string, f metadata.Field) string {
+	name := UniqueIndexName(table, f.Name, f.IsCaseInsensitive())
+	if len(f.UniqueWhere) == 0 {
+		return name
+	}
+	h := fnv.New32a()
+	for _, c := range f.UniqueConditions() {
+		fmt.Fprintf(h, "%s %s %#v;", c.Field, c.Op, c.Value)
+	}
+	return fmt.Sprintf("%s_w%08x", name, h.Sum32())
+}
 
+// isUniqueIndexFor reports whether an index is one the migrator built for the field.
+func isUniqueIndexFor(table, field, index string) bool {
+	for _, ci := range []bool{false, true} {
+		base := UniqueIndexName(table, field, ci)
+		if index == base {
+			return true
+		}
+		if hash, ok := strings.CutPrefix(index, base+"_w"); ok && len(hash) == 8 {
+			if _, err := strconv.ParseUint(hash, 16, 32); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dropUniqueIndexes drops the field's unique indexes other than keep.
+func (m *Migrator) dropUniqueIndexes(ctx context.Context, table, field string, existing []string, keep string) error {
+	for _, index := range existing {
+		if index == keep || !isUniqueIndexFor(table, field, index) {
+			continue
+		}
+		if _, err := m.store.DB.ExecContext(ctx, "DROP INDEX IF EXISTS "+index); err != nil {
+			return fmt.Errorf("drop unique index %s on %s.%s: %w", index, table, field, err)
+		}
+	}
 	return nil
 }
 
+// uniqueWhereSQL renders a field's unique_where as the predicate of a partial
+// index. Index predicates can't take bind parameters, so values are inlined as
+// literals; admin validation limits them to strings, numbers, booleans and null.
+func (m *Migrator) uniqueWhereSQL(f metadata.Field) string {
+	terms := make([]string, 0, len(f.UniqueWhere))
+	for _, c := range f.UniqueConditions() {
+		if c.Value == nil {
+			if c.Op == "neq" {
+				terms = append(terms, c.Field+" IS NOT NULL")
+			} else {
+				terms = append(terms, c.Field+" IS NULL")
+			}
+			continue
+		}
+		op := "="
+		if c.Op == "neq" {
+			op = "<>"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s", c.Field, op, m.sqlLiteral(c.Value)))
+	}
+	return strings.Join(terms, " AND ")
+}
+
+func (m *Migrator) sqlLiteral(v any) string {
+	switch val := v.(type) {
+	case bool:
+		if m.store.Dialect.NeedsBoolFix() {
+			if val {
+				return "1"
+			}
+			return "0"
+		}
+		return strconv.FormatBool(val)
+	case string:
+		return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
 // GenerateUUID generates a new UUID string. Used when the database dialect
 // does not support gen_random_uuid() (e.g., SQLite).
 func GenerateUUID() string {
 	return uuid.New().String()
 }
+
+// GenerateUUIDv7 generates a time-ordered UUID (version 7), so keys created
+// close together sort and index close together.
+func GenerateUUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+const nanoIDAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-"
+
+// GenerateNanoID generates a 21-character URL-safe random ID.
+func GenerateNanoID() string {
+	b := make([]byte, 21)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("generate nanoid: %v", err))
+	}
+	// 64 symbols, so masking each byte keeps the distribution uniform
+	for i := range b {
+		b[i] = nanoIDAlphabet[b[i]&63]
+	}
+	return string(b)
+}
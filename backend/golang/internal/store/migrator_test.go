@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"rocket-backend/internal/config"
+	"rocket-backend/internal/metadata"
+)
+
+func testSQLiteStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := New(context.Background(), config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestMigrate_CaseInsensitiveUnique(t *testing.T) {
+	ctx := context.Background()
+	s := testSQLiteStore(t)
+	entity := &metadata.Entity{
+		Name:       "member",
+		Table:      "members",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "email", Type: "string", Unique: true, CaseInsensitive: true},
+		},
+	}
+	if err := NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if _, err := Exec(ctx, s.DB, "INSERT INTO members (id, email) VALUES (?1, ?2)", "1", "A@x.com"); err != nil {
+		t.Fatalf("insert first: %v", err)
+	}
+	_, err := Exec(ctx, s.DB, "INSERT INTO members (id, email) VALUES (?1, ?2)", "2", "a@x.com")
+	if err == nil {
+		t.Fatal("expected A@x.com and a@x.com to collide")
+	}
+	if !errors.Is(MapError(s.Dialect, err), ErrUniqueViolation) {
+		t.Fatalf("expected ErrUniqueViolation, got: %v", err)
+	}
+
+	// Re-running the migration is idempotent
+	if err := NewMigrator(s).Migrate(ctx, entity); err != nil {
+		t.Fatalf("re-migrate: %v", err)
+	}
+}
+
+func TestMigrate_RenamedFieldKeepsData(t *testing.T) {
+	ctx := context.Background()
+	s := testSQLiteStore(t)
+	m := NewMigrator(s)
+	entity := &metadata.Entity{
+		Name:       "member",
+		Table:      "members",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "mail", Type: "string", Unique: true},
+		},
+	}
+	if err := m.Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := Exec(ctx, s.DB, "INSERT INTO members (id, mail) VALUES ('1', 'a@x.com')"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	entity.Fields[1] = metadata.Field{Name: "email", Type: "string", Unique: true, RenamedFrom: "mail"}
+	if err := m.Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate rename: %v", err)
+	}
+	row, err := QueryRow(ctx, s.DB, "SELECT email FROM members WHERE id = '1'")
+	if err != nil || row["email"] != "a@x.com" {
+		t.Fatalf("expected data to survive the rename, got %v (%v)", row, err)
+	}
+	cols, _ := s.Dialect.GetColumns(ctx, s.DB, "members")
+	if _, ok := cols["mail"]; ok {
+		t.Fatal("expected old column to be gone")
+	}
+	if _, err := Exec(ctx, s.DB, "INSERT INTO members (id, email) VALUES ('2', 'a@x.com')"); !errors.Is(MapError(s.Dialect, err), ErrUniqueViolation) {
+		t.Fatalf("expected unique index to carry over, got %v", err)
+	}
+
+	// The hint is a no-op once applied
+	if err := m.Migrate(ctx, entity); err != nil {
+		t.Fatalf("re-migrate: %v", err)
+	}
+
+	// Old column missing or new name taken are rejected
+	entity.Fields = append(entity.Fields, metadata.Field{Name: "phone", Type: "string", RenamedFrom: "tel"})
+	if err := m.ValidateRenames(ctx, entity); !errors.Is(err, ErrInvalidRename) {
+		t.Fatalf("expected ErrInvalidRename for missing column, got %v", err)
+	}
+	entity.Fields[2] = metadata.Field{Name: "email", Type: "string", RenamedFrom: "id"}
+	if err := m.ValidateRenames(ctx, entity); !errors.Is(err, ErrInvalidRename) {
+		t.Fatalf("expected ErrInvalidRename for collision, got %v", err)
+	}
+}
+
+func TestMigrate_RequiredFieldBackfillsNulls(t *testing.T) {
+	ctx := context.Background()
+	s := testSQLiteStore(t)
+	m := NewMigrator(s)
+	old := &metadata.Entity{
+		Name:       "member",
+		Table:      "members",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "status", Type: "string"},
+		},
+	}
+	if err := m.Migrate(ctx, old); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, q := range []string{
+		"INSERT INTO members (id, status) VALUES ('1', 'active')",
+		"INSERT INTO members (id) VALUES ('2')",
+		"INSERT INTO members (id) VALUES ('3')",
+	} {
+		if _, err := Exec(ctx, s.DB, q); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	updated := &metadata.Entity{Name: old.Name, Table: old.Table, PrimaryKey: old.PrimaryKey, Fields: []metadata.Field{
+		{Name: "id", Type: "string"},
+		{Name: "status", Type: "string", Required: true},
+	}}
+	tightened, loosened := RequiredChanges(old, updated)
+	if len(tightened) != 1 || tightened[0].Name != "status" || len(loosened) != 0 {
+		t.Fatalf("expected status to tighten, got %v / %v", tightened, loosened)
+	}
+
+	// Without a backfill the offending rows are counted
+	violations, err := m.CheckRequired(ctx, updated, tightened)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(violations) != 1 || violations[0] != (NullViolation{Field: "status", Rows: 2}) {
+		t.Fatalf("expected 2 null rows in status, got %v", violations)
+	}
+
+	updated.Fields[1].Backfill = "pending"
+	if violations, _ := m.CheckRequired(ctx, updated, tightened); len(violations) != 0 {
+		t.Fatalf("expected a backfill to clear the violation, got %v", violations)
+	}
+	if err := m.ApplyRequiredChanges(ctx, updated, tightened, loosened); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if err := m.Migrate(ctx, updated); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	row, err := QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM members WHERE status = 'pending'")
+	if err != nil || row["n"] != int64(2) {
+		t.Fatalf("expected both nulls backfilled, got %v (%v)", row, err)
+	}
+	if _, err := Exec(ctx, s.DB, "INSERT INTO members (id) VALUES ('4')"); err == nil {
+		t.Fatal("expected NOT NULL to be enforced on insert")
+	}
+	if _, err := Exec(ctx, s.DB, "UPDATE members SET status = NULL WHERE id = '1'"); err == nil {
+		t.Fatal("expected NOT NULL to be enforced on update")
+	}
+
+	// Making the field optional again lifts the constraint
+	tightened, loosened = RequiredChanges(updated, old)
+	if err := m.ApplyRequiredChanges(ctx, old, tightened, loosened); err != nil {
+		t.Fatalf("apply loosen: %v", err)
+	}
+	if _, err := Exec(ctx, s.DB, "INSERT INTO members (id) VALUES ('4')"); err != nil {
+		t.Fatalf("expected NULL to be allowed again: %v", err)
+	}
+
+	// A field renamed and made required in one update is backfilled under its
+	// old name, before the migration renames it
+	renamed := &metadata.Entity{Name: old.Name, Table: old.Table, PrimaryKey: old.PrimaryKey, Fields: []metadata.Field{
+		{Name: "id", Type: "string"},
+		{Name: "state", Type: "string", Required: true, RenamedFrom: "status", Backfill: "pending"},
+	}}
+	tightened, loosened = RequiredChanges(old, renamed)
+	if err := m.ApplyRequiredChanges(ctx, renamed, tightened, loosened); err != nil {
+		t.Fatalf("apply before rename: %v", err)
+	}
+	if err := m.Migrate(ctx, renamed); err != nil {
+		t.Fatalf("migrate rename: %v", err)
+	}
+	if row, err := QueryRow(ctx, s.DB, "SELECT state FROM members WHERE id = '4'"); err != nil || row["state"] != "pending" {
+		t.Fatalf("expected the renamed column backfilled, got %v (%v)", row, err)
+	}
+	if _, err := Exec(ctx, s.DB, "INSERT INTO members (id) VALUES ('5')"); err == nil {
+		t.Fatal("expected NOT NULL to follow the renamed column")
+	}
+}
+
+func TestReindex_RecreatesMissingDeclaredIndex(t *testing.T) {
+	ctx := context.Background()
+	s := testSQLiteStore(t)
+	entity := &metadata.Entity{
+		Name:       "account",
+		Table:      "accounts",
+		PrimaryKey: metadata.PrimaryKey{Field: "id", Type: "string"},
+		SoftDelete: true,
+		Fields: []metadata.Field{
+			{Name: "id", Type: "string"},
+			{Name: "code", Type: "string", Unique: true},
+		},
+	}
+	m := NewMigrator(s)
+	if err := m.Migrate(ctx, entity); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// Simulate an index lost during a bulk load
+	if _, err := Exec(ctx, s.DB, "DROP INDEX idx_accounts_code"); err != nil {
+		t.Fatalf("drop index: %v", err)
+	}
+
+	rebuilt, err := m.Reindex(ctx, entity)
+	if err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if len(rebuilt) != 2 || rebuilt[0] != "idx_accounts_code" || rebuilt[1] != "idx_accounts_deleted_at" {
+		t.Fatalf("expected both declared indexes to be rebuilt, got %v", rebuilt)
+	}
+	names, err := s.Dialect.IndexNames(ctx, s.DB, "accounts")
+	if err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+	found := false
+	for _, n := range names {
+		found = found || n == "idx_accounts_code"
+	}
+	if !found {
+		t.Fatalf("expected idx_accounts_code to exist again, got %v", names)
+	}
+	if _, err := Exec(ctx, s.DB, "INSERT INTO accounts (id, code) VALUES ('1', 'A'), ('2', 'A')"); err == nil {
+		t.Fatal("expected the recreated index to enforce uniqueness")
+	}
+}
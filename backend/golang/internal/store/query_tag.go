@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type queryTagKey struct{}
+
+// QueryTagKey is the context key holding a request's query tag. Handlers pass
+// either the Fiber user context or the fasthttp request context down to the
+// store, so the tagging middleware sets it on both.
+var QueryTagKey = queryTagKey{}
+
+// WithQueryTag returns a context whose queries (through QueryRows, QueryRow and
+// Exec) are prefixed with a /* tag */ comment. The tag is either a string or a
+// fmt.Stringer resolved when the first query runs.
+func WithQueryTag(ctx context.Context, tag any) context.Context {
+	return context.WithValue(ctx, QueryTagKey, tag)
+}
+
+// tagQuery prefixes sqlStr with ctx's query tag, if any. Only a conservative
+// set of characters survives, so the tag can never open or close a comment
+// ("*" is dropped) or carry quotes into the statement.
+func tagQuery(ctx context.Context, sqlStr string) string {
+	var tag string
+	switch v := ctx.Value(QueryTagKey).(type) {
+	case string:
+		tag = v
+	case fmt.Stringer:
+		tag = v.String()
+	}
+	tag = strings.Map(queryTagRune, tag)
+	if tag == "" {
+		return sqlStr
+	}
+	return "/* " + tag + " */ " + sqlStr
+}
+
+func queryTagRune(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return r
+	case strings.ContainsRune(" _-/:.=", r):
+		return r
+	}
+	return -1
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SetReplica attaches a read replica. Reads that can tolerate replication lag
+// should go through Reader.
+func (s *Store) SetReplica(db *sql.DB) {
+	s.replica = db
+}
+
+// Reader returns the read replica, or the primary when none is configured.
+func (s *Store) Reader() Querier {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.DB
+}
+
+// ReadYourWrites is how long after a client's write its reads are sent to the
+// primary instead of the replica. Zero disables it.
+func (s *Store) ReadYourWrites() time.Duration {
+	return s.readYourWrites
+}
+
+// SetReadYourWrites overrides the read-your-writes window.
+func (s *Store) SetReadYourWrites(d time.Duration) {
+	s.readYourWrites = d
+}
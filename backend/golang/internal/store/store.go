@@ -3,11 +3,16 @@ package store
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log"
+	"net"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+
 	_ "github.com/jackc/pgx/v5/stdlib"  // Register pgx as database/sql driver
 	_ "modernc.org/sqlite"               // Register sqlite as database/sql driver
 
@@ -26,13 +31,20 @@ type Querier interface {
 
 // Store wraps a database connection and dialect.
 type Store struct {
-	DB      *sql.DB
-	Dialect Dialect
-	driver  string
-	dataDir string // for SQLite: directory holding .db files
+	DB          *sql.DB
+	Dialect     Dialect
+	TablePrefix string // prepended to entity and join table names (see metadata.Entity.PhysicalTable)
+	driver      string
+	dataDir     string // for SQLite: directory holding .db files
+
+	replica        *sql.DB       // optional read replica; nil means reads use DB
+	readYourWrites time.Duration // see ReadYourWrites
 }
 
-// New creates a Store from config.
+// New creates a Store from config. If the database is not reachable yet, it retries
+// up to cfg.ConnectRetries times, doubling the delay between attempts. This is
+// meant for the platform store at startup, while the database may still be
+// coming up.
 func New(ctx context.Context, cfg config.DatabaseConfig) (*Store, error) {
 	driver := cfg.Driver
 	if driver == "" {
@@ -40,10 +52,56 @@ func New(ctx context.Context, cfg config.DatabaseConfig) (*Store, error) {
 	}
 
 	dialect := NewDialect(driver)
-	driverName := dialect.DriverName()
-	dsn := cfg.DSN()
 
-	db, err := sql.Open(driverName, dsn)
+	var db *sql.DB
+	delay := time.Duration(cfg.ConnectRetryDelayMs) * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		var err error
+		db, err = connect(ctx, cfg, driver, dialect.DriverName())
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetries || ctx.Err() != nil {
+			return nil, fmt.Errorf("connect after %d attempt(s): %w", attempt+1, err)
+		}
+		log.Printf("Database not reachable (attempt %d/%d): %v — retrying in %s", attempt+1, cfg.ConnectRetries+1, err, delay)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connect after %d attempt(s): %w", attempt+1, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxConnectRetryDelay)
+	}
+
+	s := &Store{
+		DB:             db,
+		Dialect:        dialect,
+		TablePrefix:    cfg.TablePrefix,
+		driver:         driver,
+		dataDir:        cfg.Path,
+		readYourWrites: time.Duration(cfg.ReadYourWritesMs) * time.Millisecond,
+	}
+	if cfg.ReplicaHost != "" && driver == "postgres" {
+		rcfg := cfg
+		rcfg.Host = cfg.ReplicaHost
+		if cfg.ReplicaPort > 0 {
+			rcfg.Port = cfg.ReplicaPort
+		}
+		replica, err := connect(ctx, rcfg, driver, dialect.DriverName())
+		if err != nil {
+			log.Printf("WARN: read replica %s unreachable, reads will use the primary: %v", cfg.ReplicaHost, err)
+		} else {
+			s.replica = replica
+		}
+	}
+	return s, nil
+}
+
+const maxConnectRetryDelay = 30 * time.Second
+
+// connect opens and pings a database. It is a variable so tests can simulate outages.
+var connect = func(ctx context.Context, cfg config.DatabaseConfig, driver, driverName string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
@@ -69,19 +127,16 @@ func New(ctx context.Context, cfg config.DatabaseConfig) (*Store, error) {
 		db.Close()
 		return nil, fmt.Errorf("ping: %w", err)
 	}
-
-	return &Store{
-		DB:      db,
-		Dialect: dialect,
-		driver:  driver,
-		dataDir: cfg.Path,
-	}, nil
+	return db, nil
 }
 
 // NewWithPoolSize connects to a database using the given config but overrides pool size.
+// It is used for per-app pools, opened on the request path, so it fails fast
+// instead of retrying.
 func NewWithPoolSize(ctx context.Context, cfg config.DatabaseConfig, poolSize int) (*Store, error) {
 	override := cfg
 	override.PoolSize = poolSize
+	override.ConnectRetries = 0
 	return New(ctx, override)
 }
 
@@ -100,6 +155,9 @@ func (s *Store) DataDir() string {
 // Close closes the database connection.
 func (s *Store) Close() {
 	s.DB.Close()
+	if s.replica != nil {
+		s.replica.Close()
+	}
 }
 
 // BeginTx starts a new transaction.
@@ -107,9 +165,54 @@ func (s *Store) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return s.DB.BeginTx(ctx, nil)
 }
 
+// Tx runs fn in a transaction, committing when it returns nil and rolling back
+// when it returns an error or panics. The querier passed to fn works with
+// QueryRows, QueryRow and Exec just like s.DB does.
+func (s *Store) Tx(ctx context.Context, fn func(tx Querier) error) error {
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// LockRow locks the row of table whose keyColumn is id until tx ends, so
+// concurrent transactions that lock it too wait instead of racing on it. Use
+// it in a Tx callback before reading a row that the transaction then updates.
+// A missing row is not an error. On SQLite it is a no-op: the store's single
+// connection already serializes transactions.
+func LockRow(ctx context.Context, tx Querier, dialect Dialect, table, keyColumn string, id any) error {
+	clause := dialect.ForUpdateClause()
+	if clause == "" {
+		return nil
+	}
+	var discard any
+	err := tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s%s", keyColumn, table, keyColumn, dialect.Placeholder(1), clause), id).Scan(&discard)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("lock %s row: %w", table, err)
+	}
+	return nil
+}
+
 // QueryRows executes a query and returns results as []map[string]any.
 func QueryRows(ctx context.Context, q Querier, sqlStr string, args ...any) ([]map[string]any, error) {
-	rows, err := q.QueryContext(ctx, sqlStr, args...)
+	ctx, cancel := withRequestDeadline(ctx)
+	defer cancel()
+	rows, err := q.QueryContext(ctx, tagQuery(ctx, sqlStr), args...)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -157,7 +260,9 @@ func QueryRow(ctx context.Context, q Querier, sqlStr string, args ...any) (map[s
 
 // Exec executes a statement and returns the number of rows affected.
 func Exec(ctx context.Context, q Querier, sqlStr string, args ...any) (int64, error) {
-	result, err := q.ExecContext(ctx, sqlStr, args...)
+	ctx, cancel := withRequestDeadline(ctx)
+	defer cancel()
+	result, err := q.ExecContext(ctx, tagQuery(ctx, sqlStr), args...)
 	if err != nil {
 		return 0, fmt.Errorf("exec: %w", err)
 	}
@@ -176,6 +281,37 @@ func MapError(dialect Dialect, err error) error {
 	return dialect.MapError(err)
 }
 
+// IsTransientError reports whether err is a database failure that may succeed
+// if retried: a lost or refused connection, a timeout, a serialization
+// failure or deadlock, a server out of resources or shutting down, or a busy
+// SQLite database.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"): // connection, resources
+			return true
+		case pgErr.Code == "40001", pgErr.Code == "40P01": // serialization failure, deadlock
+			return true
+		case pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03": // shutdown, cannot connect now
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
+
 // normalizeValue converts database-specific types to JSON-serializable Go types.
 func normalizeValue(v any) any {
 	if v == nil {
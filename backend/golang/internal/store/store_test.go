@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"rocket-backend/internal/config"
+)
+
+func TestNew_RetriesUntilDatabaseIsReachable(t *testing.T) {
+	realConnect := connect
+	t.Cleanup(func() { connect = realConnect })
+
+	attempts := 0
+	connect = func(ctx context.Context, cfg config.DatabaseConfig, driver, driverName string) (*sql.DB, error) {
+		attempts++
+		if attempts <= 2 {
+			return nil, errors.New("connection refused")
+		}
+		return realConnect(ctx, cfg, driver, driverName)
+	}
+
+	cfg := config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test", ConnectRetries: 3, ConnectRetryDelayMs: 1}
+	s, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("expected connection after retries, got %v", err)
+	}
+	defer s.Close()
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+
+	// Gives up once the retry budget is exhausted
+	attempts = 0
+	cfg.ConnectRetries = 1
+	if _, err := New(context.Background(), cfg); err == nil {
+		t.Fatal("expected error when retries are exhausted")
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+
+	// Per-app pools fail on the first attempt
+	attempts = 0
+	cfg.ConnectRetries = 3
+	if _, err := NewWithPoolSize(context.Background(), cfg, 2); err == nil {
+		t.Fatal("expected error from an unreachable per-app database")
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt for a per-app pool, got %d", attempts)
+	}
+}
+
+func TestTx_RollsBackOnMidSequenceFailure(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(ctx, config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	defer s.Close()
+	if _, err := Exec(ctx, s.DB, "CREATE TABLE items (id TEXT PRIMARY KEY)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	count := func() int {
+		row, err := QueryRow(ctx, s.DB, "SELECT COUNT(*) AS n FROM items")
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return int(row["n"].(int64))
+	}
+
+	// The second insert violates the primary key after the first succeeded
+	err = s.Tx(ctx, func(tx Querier) error {
+		if _, err := Exec(ctx, tx, "INSERT INTO items (id) VALUES ('a')"); err != nil {
+			return err
+		}
+		_, err := Exec(ctx, tx, "INSERT INTO items (id) VALUES ('a')")
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected the failing statement's error")
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected the first insert to be rolled back, found %d rows", n)
+	}
+
+	func() {
+		defer func() { _ = recover() }()
+		_ = s.Tx(ctx, func(tx Querier) error {
+			if _, err := Exec(ctx, tx, "INSERT INTO items (id) VALUES ('b')"); err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+			panic("boom")
+		})
+	}()
+	if n := count(); n != 0 {
+		t.Fatalf("expected a panic to roll back, found %d rows", n)
+	}
+
+	if err := s.Tx(ctx, func(tx Querier) error {
+		_, err := Exec(ctx, tx, "INSERT INTO items (id) VALUES ('c')")
+		return err
+	}); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expected the committed row, found %d rows", n)
+	}
+}
+
+func TestDSN_SetsApplicationName(t *testing.T) {
+	cfg := config.DatabaseConfig{Driver: "postgres", User: "u", Password: "p", Host: "db", Port: 5432, Name: "app", ApplicationName: "rocket api"}
+	if dsn := cfg.DSN(); !strings.Contains(dsn, "&application_name=rocket+api") {
+		t.Fatalf("expected application_name in DSN, got %s", dsn)
+	}
+	cfg.ApplicationName = ""
+	if dsn := cfg.DSN(); strings.Contains(dsn, "application_name") {
+		t.Fatalf("expected no application_name, got %s", dsn)
+	}
+}
+
+// recordingQuerier captures the SQL sent to the underlying Querier.
+type recordingQuerier struct {
+	Querier
+	sql []string
+}
+
+func (r *recordingQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	r.sql = append(r.sql, query)
+	return r.Querier.QueryContext(ctx, query, args...)
+}
+
+func (r *recordingQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.sql = append(r.sql, query)
+	return r.Querier.ExecContext(ctx, query, args...)
+}
+
+func TestQueryTag_PrefixesTaggedQueries(t *testing.T) {
+	s, err := New(context.Background(), config.DatabaseConfig{Driver: "sqlite", Path: t.TempDir(), Name: "test"})
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer s.Close()
+	q := &recordingQuerier{Querier: s.DB}
+
+	if _, err := QueryRow(context.Background(), q, "SELECT 1 AS one"); err != nil {
+		t.Fatalf("untagged query: %v", err)
+	}
+	ctx := WithQueryTag(context.Background(), "rocket GET /api/shop/orders trace=t1 **//'; DROP /*")
+	if _, err := QueryRow(ctx, q, "SELECT 1 AS one"); err != nil {
+		t.Fatalf("tagged query: %v", err)
+	}
+	if _, err := Exec(ctx, q, "CREATE TABLE tagged (id INTEGER)"); err != nil {
+		t.Fatalf("tagged exec: %v", err)
+	}
+
+	want := []string{
+		"SELECT 1 AS one",
+		"/* rocket GET /api/shop/orders trace=t1 // DROP / */ SELECT 1 AS one",
+		"/* rocket GET /api/shop/orders trace=t1 // DROP / */ CREATE TABLE tagged (id INTEGER)",
+	}
+	if len(q.sql) != len(want) {
+		t.Fatalf("expected %d statements, got %v", len(want), q.sql)
+	}
+	for i := range want {
+		if q.sql[i] != want[i] {
+			t.Errorf("statement %d: expected %q, got %q", i, want[i], q.sql[i])
+		}
+	}
+}
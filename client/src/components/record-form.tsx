@@ -46,29 +46,30 @@ export default function RecordForm(props: RecordFormProps) {
     return props.fkFields?.find((fk) => fk.fieldName === fieldName);
   }
 
-  function getFieldLabel(fieldName: string): string {
-    return props.formConfig?.field_overrides?.[fieldName]?.label || fieldName;
+  function getFieldLabel(field: Field): string {
+    return props.formConfig?.field_overrides?.[field.name]?.label || field.label || field.name;
   }
 
-  function isFieldReadonly(fieldName: string): boolean {
-    if (props.formConfig?.readonly_fields?.includes(fieldName)) return true;
-    if (props.formConfig?.field_overrides?.[fieldName]?.readonly) return true;
+  function isFieldReadonly(field: Field): boolean {
+    if (field.immutable && !props.isNew) return true;
+    if (props.formConfig?.readonly_fields?.includes(field.name)) return true;
+    if (props.formConfig?.field_overrides?.[field.name]?.readonly) return true;
     return false;
   }
 
-  function getFieldHelp(fieldName: string): string | undefined {
-    return props.formConfig?.field_overrides?.[fieldName]?.help;
+  function getFieldHelp(field: Field): string | undefined {
+    return props.formConfig?.field_overrides?.[field.name]?.help || field.help_text;
   }
 
   function renderField(field: Field) {
     const value = getFieldValue(field.name);
     const errorMsg = props.errors?.[field.name];
-    const label = getFieldLabel(field.name);
+    const label = getFieldLabel(field);
     const slugCfg = props.slugConfig;
     const isAutoSlug = slugCfg?.field === field.name && !!slugCfg?.source;
     const labelClass = (field.required && !isAutoSlug) ? "form-label form-label-required" : "form-label";
-    const readonly = isFieldReadonly(field.name);
-    const helpText = getFieldHelp(field.name) ?? (isAutoSlug ? `Auto-generated from ${slugCfg!.source}` : undefined);
+    const readonly = isFieldReadonly(field);
+    const helpText = getFieldHelp(field) ?? (isAutoSlug ? `Auto-generated from ${slugCfg!.source}` : undefined);
 
     // Check if this field is a FK and should render as a dropdown
     const fkInfo = getFkInfo(field.name);
@@ -313,7 +313,8 @@ export default function EntityListPage() {
   }
 
   function getColumnLabel(fieldName: string): string {
-    return uiConfig()?.form?.field_overrides?.[fieldName]?.label || fieldName;
+    const field = entityDef()?.fields.find((f) => f.name === fieldName);
+    return uiConfig()?.form?.field_overrides?.[fieldName]?.label || field?.label || fieldName;
   }
 
   function getColumns(): Column<Record<string, unknown>>[] {
@@ -21,6 +21,9 @@ export interface Field {
   enum?: string[];
   precision?: number;
   auto?: "create" | "update";
+  label?: string;
+  help_text?: string;
+  immutable?: boolean;
 }
 
 export interface PrimaryKey {